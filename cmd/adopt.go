@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+type adoptCmd struct {
+	cmd  *cobra.Command
+	opts adoptOpts
+}
+
+type adoptOpts struct {
+	provider string
+	version  string
+	kind     string
+}
+
+func newAdoptCmd() *adoptCmd {
+	root := &adoptCmd{}
+
+	cmd := &cobra.Command{
+		Use:         "adopt <path> <url | owner/repo>",
+		Annotations: map[string]string{writesConfig: "true"},
+		Short:       "Manages a binary installed without bin",
+		Long: `Manages a binary installed without bin, i.e. by hand before using bin.
+
+The file is recorded as installed from the URL, without downloading
+anything, so bin update keeps it up to date from then on. It's
+recorded with the latest version of the URL unless --version gives
+the one actually installed.`,
+		SilenceUsage:  true,
+		Args:          cobra.ExactArgs(2),
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := filepath.Abs(os.ExpandEnv(args[0]))
+			if err != nil {
+				return err
+			}
+			fi, err := os.Stat(p)
+			if err != nil {
+				return err
+			}
+			if fi.IsDir() {
+				return fmt.Errorf("%s is a directory, adopt the binaries it contains one by one", p)
+			}
+			if err := config.ValidateKind(root.opts.kind); err != nil {
+				return err
+			}
+			if b := config.Owner(p); b != nil {
+				return fmt.Errorf("%s is already managed by bin, it's installed from %s", p, b.URL)
+			}
+
+			u, err := installURL(args[1], root.opts.provider)
+			if err != nil {
+				return err
+			}
+			hash, err := fileSHA256(p)
+			if err != nil {
+				return err
+			}
+
+			b := &config.Binary{
+				Path:       p,
+				RemoteName: filepath.Base(p),
+				URL:        u,
+				Provider:   root.opts.provider,
+				Kind:       root.opts.kind,
+				Hash:       hash,
+			}
+			pv, err := newProvider(b)
+			if err != nil {
+				return err
+			}
+			b.Provider = pv.GetID()
+
+			if v := root.opts.version; v != "" {
+				b.Version = v
+				if unavailable(b) {
+					binLog(b).WithField("version", v).Warn("The version isn't published at the URL, the next update replaces it with the latest one")
+				}
+			} else {
+				if b.Version, _, err = pv.GetLatestVersion(); err != nil {
+					return fmt.Errorf("error getting the latest version of %s, pass the installed one with --version: %w", u, err)
+				}
+			}
+
+			if err := config.UpsertBinary(b); err != nil {
+				return err
+			}
+			binLog(b).WithField("url", u).Info("Adopted")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().StringVarP(&root.opts.provider, "provider", "p", "", "Forces to use a specific provider")
+	root.cmd.Flags().StringVar(&root.opts.version, "version", "", "Version of the file, the latest one of the URL by default")
+	root.cmd.Flags().StringVar(&root.opts.kind, "kind", config.KindBinary, "Kind of the artifact, binary or file for data files and fonts")
+	return root
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestAdopt(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	binDir := versionedDemo(t, forge)
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(binDir, "tool")
+	if err := os.WriteFile(p, []byte("installed by hand"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("adopt exited with %d", code) }, []string{"adopt", p, "http://" + demoHost + "/acme/tool", "--version", "v1.0.0"})
+	b := config.Get().Bins[p]
+	if b == nil || b.Version != "v1.0.0" || b.Provider != "github" {
+		t.Fatalf("expected the file to be managed at v1.0.0, got %+v", b)
+	}
+	if sum, _ := fileSHA256(p); b.Hash != sum {
+		t.Errorf("expected the hash of the file to be recorded, got %s", b.Hash)
+	}
+	if b, _ := os.ReadFile(p); string(b) != "installed by hand" {
+		t.Errorf("expected the file to be left as is, got %q", b)
+	}
+
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"adopt", p, "http://" + demoHost + "/acme/tool"})
+	if code == 0 {
+		t.Fatal("expected adopting a managed file to fail")
+	}
+
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", p})
+	if b, _ := os.ReadFile(p); string(b) != string(fakeforge.Script("tool", "v1.1.0")) {
+		t.Errorf("expected the adopted file to be updated, got %q", b)
+	}
+
+	// without a version, the latest one is recorded
+	other := filepath.Join(binDir, "other")
+	if err := os.WriteFile(other, []byte("installed by hand"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("adopt exited with %d", code) }, []string{"adopt", other, "http://" + demoHost + "/acme/tool"})
+	if b := config.Get().Bins[other]; b == nil || b.Version != "v1.1.0" {
+		t.Fatalf("expected the latest version to be recorded, got %+v", b)
+	}
+}
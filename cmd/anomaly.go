@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/docker/go-units"
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/prompt"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+// anomalySizeFactor is how much smaller or bigger than the previous
+// ones an asset has to be to be flagged
+const anomalySizeFactor = 4
+
+// assetAnomalies explains how the downloaded asset differs from the
+// ones the binary was previously installed from
+func assetAnomalies(p *config.AssetProfile, f *providers.File) []string {
+	if p == nil || f.Asset == "" {
+		return nil
+	}
+	anomalies := []string{}
+	if pattern := assets.SelectionPattern(f.Asset, f.Version); pattern != p.Pattern {
+		anomalies = append(anomalies, fmt.Sprintf("the asset is named %s, previous ones matched %s", f.Asset, p.Pattern))
+	}
+	if format := assets.Format(f.Asset); format != p.Format {
+		anomalies = append(anomalies, fmt.Sprintf("the asset is a %s, previous ones were %s", format, p.Format))
+	}
+	if p.MinSize > 0 && f.AssetSize > 0 {
+		switch {
+		case f.AssetSize*anomalySizeFactor < p.MinSize:
+			anomalies = append(anomalies, fmt.Sprintf("the asset weighs %s, previous ones weighed at least %s", units.HumanSize(float64(f.AssetSize)), units.HumanSize(float64(p.MinSize))))
+		case f.AssetSize > p.MaxSize*anomalySizeFactor:
+			anomalies = append(anomalies, fmt.Sprintf("the asset weighs %s, previous ones weighed at most %s", units.HumanSize(float64(f.AssetSize)), units.HumanSize(float64(p.MaxSize))))
+		}
+	}
+	return anomalies
+}
+
+// recordAsset returns the profile updated with the installed asset.
+// Assets which don't match it become the new normal.
+func recordAsset(p *config.AssetProfile, f *providers.File) *config.AssetProfile {
+	if f.Asset == "" {
+		return p
+	}
+	pattern, format := assets.SelectionPattern(f.Asset, f.Version), assets.Format(f.Asset)
+	if p == nil || p.Pattern != pattern || p.Format != format {
+		return &config.AssetProfile{Pattern: pattern, Format: format, MinSize: f.AssetSize, MaxSize: f.AssetSize, Installs: 1}
+	}
+	np := *p
+	np.MinSize, np.MaxSize = min(np.MinSize, f.AssetSize), max(np.MaxSize, f.AssetSize)
+	np.Installs++
+	return &np
+}
+
+// guardAnomalous asks to confirm the update of a binary whose asset
+// doesn't look like the previous ones, which might be the sign of a
+// compromised release. Non-interactive updates fail unless allowed.
+func guardAnomalous(b *config.Binary, f *providers.File, allow bool) error {
+	anomalies := assetAnomalies(b.AssetProfile, f)
+	if len(anomalies) == 0 {
+		return nil
+	}
+
+	p := os.ExpandEnv(b.Path)
+	binLog(b).WithField("version", f.Version).WithField("asset", f.Asset).WithField("anomalies", anomalies).Warn("The asset doesn't look like the previous ones")
+	if allow {
+		return nil
+	}
+	if !prompt.IsInteractive() {
+		return fmt.Errorf("the %s asset of %s is unusual, use --allow-anomalous to install it anyway", f.Version, p)
+	}
+	return prompt.Confirm(fmt.Sprintf("Install it anyway? It'll be the new normal for %s", p))
+}
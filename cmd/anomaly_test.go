@@ -0,0 +1,77 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+func TestAssetAnomalies(t *testing.T) {
+	var p *config.AssetProfile
+	for _, f := range []*providers.File{
+		{Asset: "tool_1.0.0_linux_amd64.tar.gz", Version: "v1.0.0", AssetSize: 5_000_000},
+		{Asset: "tool_1.1.0_linux_amd64.tar.gz", Version: "v1.1.0", AssetSize: 6_000_000},
+	} {
+		if a := assetAnomalies(p, f); len(a) > 0 {
+			t.Fatalf("expected %s not to be flagged, got %v", f.Asset, a)
+		}
+		p = recordAsset(p, f)
+	}
+	if p.Pattern != "tool_*_linux_amd64.tar.gz" || p.Format != "tar.gz" || p.MinSize != 5_000_000 || p.MaxSize != 6_000_000 || p.Installs != 2 {
+		t.Fatalf("unexpected profile %+v", p)
+	}
+
+	cases := []struct {
+		desc      string
+		f         *providers.File
+		anomalies []string
+	}{
+		{"usual", &providers.File{Asset: "tool_1.2.0_linux_amd64.tar.gz", Version: "v1.2.0", AssetSize: 7_000_000}, nil},
+		{"renamed", &providers.File{Asset: "tool-setup.exe.tar.gz", Version: "v1.2.0", AssetSize: 5_500_000}, []string{"named tool-setup.exe.tar.gz"}},
+		{"format", &providers.File{Asset: "tool_1.2.0_linux_amd64.zip", Version: "v1.2.0", AssetSize: 5_500_000}, []string{"named", "is a zip"}},
+		{"tiny", &providers.File{Asset: "tool_1.2.0_linux_amd64.tar.gz", Version: "v1.2.0", AssetSize: 20_000}, []string{"weighed at least"}},
+		{"huge", &providers.File{Asset: "tool_1.2.0_linux_amd64.tar.gz", Version: "v1.2.0", AssetSize: 200_000_000}, []string{"weighed at most"}},
+		{"unknown asset", &providers.File{Version: "v1.2.0"}, nil},
+	}
+	for _, c := range cases {
+		got := assetAnomalies(p, c.f)
+		if len(got) != len(c.anomalies) {
+			t.Errorf("%s: expected %d anomalies, got %v", c.desc, len(c.anomalies), got)
+			continue
+		}
+		for i, a := range c.anomalies {
+			if !strings.Contains(got[i], a) {
+				t.Errorf("%s: expected %q in %q", c.desc, a, got[i])
+			}
+		}
+	}
+}
+
+func TestRecordAssetNewNormal(t *testing.T) {
+	p := recordAsset(nil, &providers.File{Asset: "tool_1.0.0_linux_amd64.tar.gz", Version: "1.0.0", AssetSize: 100})
+	p = recordAsset(p, &providers.File{Asset: "tool-1.1.0-x86_64-linux.zip", Version: "1.1.0", AssetSize: 300})
+	if p.Pattern != "tool-*-x86_64-linux.zip" || p.Format != "zip" || p.MinSize != 300 || p.Installs != 1 {
+		t.Fatalf("expected the new asset to become the profile, got %+v", p)
+	}
+	if a := assetAnomalies(p, &providers.File{Asset: "tool-1.2.0-x86_64-linux.zip", Version: "1.2.0", AssetSize: 310}); len(a) > 0 {
+		t.Fatalf("expected the new normal not to be flagged, got %v", a)
+	}
+}
+
+func TestGuardAnomalous(t *testing.T) {
+	b := &config.Binary{Path: "/usr/local/bin/tool", AssetProfile: &config.AssetProfile{Pattern: "tool_*_linux_amd64.tar.gz", Format: "tar.gz", MinSize: 100, MaxSize: 100, Installs: 3}}
+	f := &providers.File{Asset: "tool-setup.exe", Version: "v2.0.0", AssetSize: 100}
+
+	// tests aren't interactive
+	if err := guardAnomalous(b, f, false); err == nil || !strings.Contains(err.Error(), "--allow-anomalous") {
+		t.Fatalf("expected the anomalous asset to be refused, got %v", err)
+	}
+	if err := guardAnomalous(b, f, true); err != nil {
+		t.Fatalf("expected the anomalous asset to be allowed, got %v", err)
+	}
+	if err := guardAnomalous(&config.Binary{Path: b.Path}, f, false); err != nil {
+		t.Fatalf("expected binaries without profile to be installed, got %v", err)
+	}
+}
@@ -0,0 +1,339 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+const (
+	auditVerified   = "verified"
+	auditUnverified = "unverified"
+)
+
+// sarifVersion is the version of the SARIF logs of --sarif
+const sarifVersion = "2.1.0"
+
+type auditCmd struct {
+	cmd  *cobra.Command
+	opts auditOpts
+}
+
+type auditOpts struct {
+	json  bool
+	sarif bool
+	fix   bool
+}
+
+// auditOutput is the JSON output of `bin audit`, see pkg/schema/schemas/audit.json
+type auditOutput struct {
+	SchemaVersion int          `json:"schema_version"`
+	Bins          []*auditItem `json:"bins"`
+}
+
+// auditItem is where a binary came from and how it was verified
+type auditItem struct {
+	Path     string `json:"path"`
+	Provider string `json:"provider"`
+	URL      string `json:"url"`
+	Version  string `json:"version"`
+	// Asset, AssetURL and SHA256 are the name, download URL and
+	// digest of the asset the binary was installed from, Hash
+	// the digest of the installed file
+	Asset    string `json:"asset,omitempty"`
+	AssetURL string `json:"asset_url,omitempty"`
+	SHA256   string `json:"sha256,omitempty"`
+	Hash     string `json:"hash,omitempty"`
+	// VerifiedBy are the verifications the asset passed when it was
+	// installed: checksum, signature and attestation
+	VerifiedBy []string `json:"verified_by"`
+	Checksum   string   `json:"checksum,omitempty"`
+	SignedBy   string   `json:"signed_by,omitempty"`
+	// InstalledAt is missing for the binaries installed
+	// before their provenance was recorded
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+	Status      string     `json:"status"`
+	Hint        string     `json:"hint,omitempty"`
+
+	b *config.Binary
+}
+
+func newAuditCmd() *auditCmd {
+	root := &auditCmd{}
+
+	cmd := &cobra.Command{
+		Use:               "audit [binary]...",
+		ValidArgsFunction: completeBinaries(0),
+		Short:             "Reports where the binaries came from and how they were verified",
+		Long: `Reports where the binaries came from and how they were verified: their
+provider, source URL, the URL and sha256 of the downloaded asset, the
+installed version and when it was installed, and whether a checksum,
+signature or release attestation verified the asset. It's read from what
+was recorded when the binaries were installed, nothing is downloaded.
+
+The binaries installed before their provenance was recorded are reported
+as unverified, --fix re-installs them at their recorded version.
+
+--json prints the report for scripts (see 'bin schema audit') and --sarif
+as a SARIF log, the unverified binaries being warnings.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if root.opts.json && root.opts.sarif {
+				return fmt.Errorf("--json and --sarif can't be used together")
+			}
+			if root.opts.fix && config.ReadOnly() {
+				return fmt.Errorf("'%s --fix' needs to write the configuration, which is read-only", cmd.CommandPath())
+			}
+			binsToAudit, err := selectBins(args, nil)
+			if err != nil {
+				return err
+			}
+			bins := make([]*config.Binary, 0, len(binsToAudit))
+			for _, b := range binsToAudit {
+				bins = append(bins, b)
+			}
+			sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
+
+			items := make([]*auditItem, len(bins))
+			for i, b := range bins {
+				items[i] = auditBinary(b)
+			}
+
+			var errs []error
+			if root.opts.fix {
+				errs = fixAudit(items)
+			}
+			switch {
+			case root.opts.json:
+				if err := writeJSON(os.Stdout, auditOutput{SchemaVersion: schema.Version, Bins: items}); err != nil {
+					return err
+				}
+			case root.opts.sarif:
+				if err := writeJSON(os.Stdout, newSarifLog(cmd.Root().Version, items)); err != nil {
+					return err
+				}
+			default:
+				printAudit(os.Stdout, items)
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print the report as JSON, see `bin schema audit`")
+	root.cmd.Flags().BoolVar(&root.opts.sarif, "sarif", false, "Print the report as a SARIF log, i.e. to attach it to compliance tickets")
+	root.cmd.Flags().BoolVar(&root.opts.fix, "fix", false, "Re-install the binaries installed before their provenance was recorded, at their recorded version")
+	return root
+}
+
+// installedNow is the install time recorded in the entries
+func installedNow() *time.Time {
+	now := time.Now().UTC().Truncate(time.Second)
+	return &now
+}
+
+// auditBinary returns the provenance recorded for the binary
+func auditBinary(b *config.Binary) *auditItem {
+	a := &auditItem{
+		Path:        os.ExpandEnv(b.Path),
+		Provider:    b.Provider,
+		URL:         b.URL,
+		Version:     b.Version,
+		Asset:       b.InstalledAsset,
+		AssetURL:    b.AssetURL,
+		SHA256:      b.AssetSHA256,
+		Hash:        b.Hash,
+		VerifiedBy:  []string{},
+		Checksum:    b.VerifiedWith,
+		SignedBy:    b.SignedBy,
+		InstalledAt: b.InstalledAt,
+		Status:      auditUnverified,
+		b:           b,
+	}
+	if b.VerifiedWith != "" {
+		a.VerifiedBy = append(a.VerifiedBy, "checksum")
+	}
+	if b.SignedBy != "" {
+		a.VerifiedBy = append(a.VerifiedBy, "signature")
+	}
+	if len(a.VerifiedBy) > 0 {
+		a.Status = auditVerified
+	}
+	if b.InstalledAt == nil {
+		// whatever was verified back then wasn't recorded
+		a.Status = auditUnverified
+		a.Hint = "installed before its provenance was recorded, re-install it with `bin audit --fix`"
+	}
+	return a
+}
+
+// fixAudit re-installs the binaries whose provenance wasn't
+// recorded, the items are updated with the new one
+func fixAudit(items []*auditItem) []error {
+	cache := assets.NewDownloadCache()
+	defer cache.Close()
+	var errs []error
+	for _, a := range items {
+		if a.InstalledAt != nil {
+			continue
+		}
+		nb, err := fixBinary(a.b, cache)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("Error re-installing %s: %w", a.Path, err))
+			continue
+		}
+		binLog(nb).Info("Re-installed")
+		*a = *auditBinary(nb)
+	}
+	if len(errs) > 0 {
+		log.WithField("binaries", len(errs)).Warn("Some binaries couldn't be re-installed")
+	}
+	return errs
+}
+
+// printAudit prints the provenance of every binary, the
+// digests being shortened like the ones of git
+func printAudit(w io.Writer, items []*auditItem) {
+	header := []tableColumn{{header: "Path", truncate: true}, {header: "Provider"}, {header: "Version"}, {header: "Asset URL", truncate: true}, {header: "SHA256"}, {header: "Verified by"}, {header: "Installed"}, {header: "Status"}}
+	rows := make([][]tableCell, 0, len(items))
+	for _, a := range items {
+		status := tableCell{text: strings.ToUpper(a.Status), color: color.New(color.FgGreen).Sprint}
+		if a.Status == auditUnverified {
+			status.color = color.New(color.FgYellow).Sprint
+		}
+		installed := ""
+		if a.InstalledAt != nil {
+			installed = a.InstalledAt.Local().Format("2006-01-02 15:04")
+		}
+		sha := a.SHA256
+		if len(sha) > 12 {
+			sha = sha[:12]
+		}
+		rows = append(rows, []tableCell{{text: a.Path}, {text: a.Provider}, {text: a.Version}, {text: displayValue(a.AssetURL)}, {text: displayValue(sha)}, {text: displayValue(strings.Join(a.VerifiedBy, ", "))}, {text: displayValue(installed)}, status})
+	}
+	printTable(w, header, rows, terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+
+	var hints []string
+	for _, a := range items {
+		if a.Hint != "" {
+			hints = append(hints, a.Path+": "+a.Hint)
+		}
+	}
+	if len(hints) > 0 {
+		fmt.Fprintf(w, "\n%s\n", strings.Join(hints, "\n"))
+	}
+}
+
+// sarifLog is the subset of SARIF 2.1.0 bin writes: one result
+// per binary, the unverified ones being warnings
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifRule struct {
+	ID               string       `json:"id"`
+	ShortDescription sarifMessage `json:"shortDescription"`
+}
+
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+type sarifResult struct {
+	RuleID     string          `json:"ruleId"`
+	Level      string          `json:"level"`
+	Message    sarifMessage    `json:"message"`
+	Locations  []sarifLocation `json:"locations"`
+	Properties *auditItem      `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+}
+
+type sarifArtifactLocation struct {
+	URI string `json:"uri"`
+}
+
+func newSarifLog(version string, items []*auditItem) *sarifLog {
+	version, _, _ = strings.Cut(version, "\n")
+	results := make([]sarifResult, 0, len(items))
+	for _, a := range items {
+		r := sarifResult{
+			RuleID:     "provenance",
+			Level:      "note",
+			Message:    sarifMessage{Text: fmt.Sprintf("%s %s from %s, verified by %s", filepath.Base(a.Path), a.Version, a.URL, strings.Join(a.VerifiedBy, ", "))},
+			Locations:  []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: fileURI(a.Path)}}}},
+			Properties: a,
+		}
+		if a.Status == auditUnverified {
+			r.RuleID, r.Level = "unverified", "warning"
+			r.Message.Text = fmt.Sprintf("%s %s from %s wasn't verified", filepath.Base(a.Path), a.Version, a.URL)
+			if a.Hint != "" {
+				r.Message.Text += ": " + a.Hint
+			}
+		}
+		results = append(results, r)
+	}
+	return &sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: sarifVersion,
+		Runs: []sarifRun{{
+			Tool: sarifTool{Driver: sarifDriver{
+				Name:           "bin",
+				Version:        version,
+				InformationURI: "https://" + selfRepo,
+				Rules: []sarifRule{
+					{ID: "provenance", ShortDescription: sarifMessage{Text: "Provenance of a binary verified when it was installed"}},
+					{ID: "unverified", ShortDescription: sarifMessage{Text: "Binary installed without a checksum, signature or attestation verifying it"}},
+				},
+			}},
+			Results: results,
+		}},
+	}
+}
+
+// fileURI returns the file URI of the path
+func fileURI(p string) string {
+	p = filepath.ToSlash(p)
+	if !strings.HasPrefix(p, "/") {
+		p = "/" + p
+	}
+	return (&url.URL{Scheme: "file", Path: p}).String()
+}
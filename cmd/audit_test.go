@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestAudit(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	binDir := versionedDemo(t, forge)
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool"})
+	p := executablePath(filepath.Join(binDir, "tool"))
+
+	// installed by hand, before the provenance was recorded
+	hand := filepath.Join(binDir, "other")
+	if err := os.WriteFile(hand, []byte("installed by hand"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("adopt exited with %d", code) }, []string{"adopt", hand, "http://" + demoHost + "/acme/tool"})
+
+	var out auditOutput
+	b := captureStdout(t, func() {
+		Execute("test", func(code int) { t.Fatalf("audit exited with %d", code) }, []string{"audit", "--json"})
+	})
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	validateOutput(t, "audit", out)
+	if len(out.Bins) != 2 {
+		t.Fatalf("expected both binaries to be audited, got %+v", out.Bins)
+	}
+	other, tool := out.Bins[0], out.Bins[1]
+	if tool.Path != p || tool.Status != auditVerified || tool.Checksum != "sha256" || tool.InstalledAt == nil || tool.Version != "v1.0.0" {
+		t.Fatalf("expected the installed binary to be verified, got %+v", tool)
+	}
+	if !strings.HasPrefix(tool.AssetURL, "http://"+demoHost+"/") || tool.SHA256 != config.Get().Bins[p].AssetSHA256 {
+		t.Fatalf("expected the asset to be recorded, got %s %s", tool.AssetURL, tool.SHA256)
+	}
+	if other.Status != auditUnverified || other.Hint == "" {
+		t.Fatalf("expected the adopted binary to be unverified, got %+v", other)
+	}
+
+	b = captureStdout(t, func() {
+		Execute("test", func(code int) { t.Fatalf("audit exited with %d", code) }, []string{"audit", "--sarif"})
+	})
+	var sl sarifLog
+	if err := json.Unmarshal(b, &sl); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	if sl.Version != sarifVersion || len(sl.Runs) != 1 || len(sl.Runs[0].Results) != 2 {
+		t.Fatalf("unexpected SARIF log %s", b)
+	}
+	if r := sl.Runs[0].Results[0]; r.RuleID != "unverified" || r.Level != "warning" {
+		t.Fatalf("expected the unverified binary to be a warning, got %+v", r)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("audit exited with %d", code) }, []string{"audit", "--fix", hand})
+	if a := auditBinary(config.Get().Bins[hand]); a.Status != auditVerified || a.InstalledAt == nil {
+		t.Fatalf("expected the re-installed binary to be verified, got %+v", a)
+	}
+}
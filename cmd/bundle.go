@@ -0,0 +1,293 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/bundle"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// offlineBundle is the bundle given with --from-bundle, the
+// binaries are installed from it instead of their provider
+// nolint: gochecknoglobals
+var offlineBundle *bundle.Bundle
+
+// openBundle makes the installs of the run use the bundle at p,
+// closeBundle must be called once they're done
+func openBundle(p string) error {
+	b, err := bundle.Open(p)
+	if err != nil {
+		return err
+	}
+	if b.Platform != config.Platform() {
+		return fmt.Errorf("the bundle %s holds the assets of %s, not %s, download it with --os and --arch", p, b.Platform, config.Platform())
+	}
+	offlineBundle = b
+	return nil
+}
+
+func closeBundle() {
+	offlineBundle = nil
+}
+
+// bundledProvider returns the provider of the asset
+// of the binary in the offline bundle
+func bundledProvider(b *config.Binary) (providers.Provider, error) {
+	e, ok := offlineBundle.Lookup(b.URL, b.Path)
+	if !ok {
+		return nil, fmt.Errorf("%s isn't in the bundle", b.URL)
+	}
+	data, err := offlineBundle.Asset(e)
+	if err != nil {
+		return nil, err
+	}
+	return providers.NewBundled(e.Provider, e.Version, e.Asset, e.AssetURL, e.PackagePath, data), nil
+}
+
+// checkBundled fails with the binaries missing from the
+// offline bundle, before installing any of them
+func checkBundled(bins []*config.Binary) error {
+	var missing []string
+	for _, b := range bins {
+		if _, ok := offlineBundle.Lookup(b.URL, b.Path); !ok {
+			missing = append(missing, fmt.Sprintf("%s (%s)", os.ExpandEnv(b.Path), b.URL))
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+	if len(missing) == 1 {
+		return fmt.Errorf("%s isn't in the bundle", missing[0])
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%d binaries aren't in the bundle: %s", len(missing), strings.Join(missing, ", "))
+}
+
+// missingBins returns the binaries whose file is missing, as they're
+// installed: from their locked artifact when they're locked
+func missingBins(bins []*config.Binary, locked map[*config.Binary]*config.LockedBinary) []*config.Binary {
+	var missing []*config.Binary
+	for _, b := range bins {
+		if _, err := os.Stat(os.ExpandEnv(b.Path)); err == nil {
+			continue
+		}
+		if lb := locked[b]; lb != nil {
+			b = lockedBinary(b, lb)
+		}
+		missing = append(missing, b)
+	}
+	return missing
+}
+
+// installBundle installs every binary of the offline bundle under the
+// name it had, into the default path or default_files_path
+func installBundle() error {
+	failures := map[*config.Binary]error{}
+	for _, e := range offlineBundle.Entries {
+		c := newInstallCmd()
+		args := []string{e.URL}
+		if e.Kind == config.KindFile {
+			c.opts.kind = config.KindFile
+		} else {
+			args = append(args, filepath.Base(os.ExpandEnv(e.Path)))
+		}
+		if err := c.cmd.RunE(c.cmd, args); err != nil {
+			failures[&config.Binary{Path: e.Path, URL: e.URL}] = fmt.Errorf("error installing %s: %w", e.URL, err)
+		}
+	}
+	if n := warnFailures(failures); n > 0 {
+		return failuresError("install", n)
+	}
+	return nil
+}
+
+type downloadCmd struct {
+	cmd  *cobra.Command
+	opts downloadOpts
+}
+
+type downloadOpts struct {
+	output string
+	// os picks the assets of another system,
+	// along with the global --arch flag
+	os       string
+	locked   bool
+	manifest string
+}
+
+func newDownloadCmd() *downloadCmd {
+	root := &downloadCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "download [binary...] --output <bundle.tar>",
+		Short: "Downloads the assets of the binaries into a bundle for offline installs",
+		Long: `Downloads the assets of the binaries into a bundle for offline installs.
+
+The assets of the installed versions of the binaries of the configuration,
+the ones pinned in the lockfile with --locked or the ones of a manifest of
+'bin export' with --manifest, are packed into a single archive along with
+their digests. 'bin install --from-bundle' and 'bin ensure --from-bundle'
+install them on machines without network access.
+
+The assets are picked for the host, or for another machine with --os and
+--arch.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if root.opts.locked && root.opts.manifest != "" {
+				return fmt.Errorf("--locked and --manifest can't be used together")
+			}
+			if err := config.SetOS(root.opts.os); err != nil {
+				return err
+			}
+			defer config.SetOS("") //nolint: errcheck
+			goos, _ := config.ResolveOS()
+			goarch, _ := config.ResolveArch()
+			providers.SetPlatform(goos, goarch)
+			defer providers.SetPlatform(runtime.GOOS, runtime.GOARCH)
+
+			bins, locked, err := root.bins(args)
+			if err != nil {
+				return err
+			}
+			sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
+
+			out, err := filepath.Abs(root.opts.output)
+			if err != nil {
+				return err
+			}
+			// the bundle is only in place once complete
+			f, err := os.CreateTemp(filepath.Dir(out), "."+filepath.Base(out)+"-*")
+			if err != nil {
+				return err
+			}
+			defer os.Remove(f.Name())
+			defer f.Close()
+
+			w := bundle.NewWriter(f, config.Platform())
+			failures := map[*config.Binary]error{}
+			for _, b := range bins {
+				e, data, err := downloadAsset(b, locked[b])
+				if err == nil {
+					err = w.Add(e, data)
+				}
+				if err != nil {
+					failures[b] = fmt.Errorf("error downloading %s: %w", os.ExpandEnv(b.Path), err)
+					continue
+				}
+				binLog(b).WithField("version", e.Version).WithField("asset", e.Asset).Info("Downloaded")
+			}
+			if n := warnFailures(failures); n > 0 {
+				return failuresError("download", n)
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+			if err := f.Close(); err != nil {
+				return err
+			}
+			if err := os.Rename(f.Name(), out); err != nil {
+				return err
+			}
+			log.WithField("binaries", len(bins)).WithField("platform", config.Platform()).WithField("path", out).Info("Bundled")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().StringVarP(&root.opts.output, "output", "o", "", "Path of the bundle to write")
+	root.cmd.Flags().StringVar(&root.opts.os, "os", "", "Pick the assets built for the given operating system (i.e. linux or windows) instead of the host's one, see --arch")
+	root.cmd.Flags().BoolVar(&root.opts.locked, "locked", false, "Download exactly the artifacts pinned in the lockfile")
+	root.cmd.Flags().StringVar(&root.opts.manifest, "manifest", "", "Download the binaries of a manifest written by `bin export` instead of the ones of the configuration")
+	_ = root.cmd.MarkFlagRequired("output")
+	return root
+}
+
+// bins returns the binaries to download along with their locked
+// artifact, when downloading the ones of the lockfile
+func (r *downloadCmd) bins(args []string) ([]*config.Binary, map[*config.Binary]*config.LockedBinary, error) {
+	var selected map[string]*config.Binary
+	var locked map[*config.Binary]*config.LockedBinary
+	var err error
+	switch {
+	case r.opts.manifest != "":
+		data, err := os.ReadFile(r.opts.manifest)
+		if err != nil {
+			return nil, nil, err
+		}
+		m, err := config.ReadManifest(data)
+		if err != nil {
+			return nil, nil, err
+		}
+		selected = map[string]*config.Binary{}
+		for _, mb := range m.Bins {
+			b := mb.Binary(config.Get().DefaultPath)
+			b.Version = mb.Version
+			selected[b.Path] = b
+		}
+		if len(args) > 0 {
+			return nil, nil, fmt.Errorf("the binaries of a manifest can't be picked, all of them are downloaded")
+		}
+	case r.opts.locked:
+		selected, locked, err = lockedBinaries(args)
+	default:
+		selected, err = selectBins(args, nil)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	bins := make([]*config.Binary, 0, len(selected))
+	for _, b := range selected {
+		bins = append(bins, b)
+	}
+	return bins, locked, nil
+}
+
+// downloadAsset downloads the asset of the installed version of the
+// binary, or its locked one, for the platform of the bundle
+func downloadAsset(b *config.Binary, lb *config.LockedBinary) (bundle.Entry, []byte, error) {
+	ep := os.ExpandEnv(b.Path)
+	// the choices made on another platform don't apply
+	host := runtime.GOOS+"/"+runtime.GOARCH == config.Platform()
+	if lb != nil && lb.Platform != config.Platform() {
+		b, lb = lockedBinary(b, lb), nil
+	}
+	if lb != nil {
+		b = lockedBinary(b, lb)
+	}
+	selected, packagePath := selectedAsset(b, lb), b.PackagePath
+	if !host && lb == nil {
+		selected, packagePath = "", ""
+	}
+
+	p, err := newProvider(b)
+	if err != nil {
+		return bundle.Entry{}, nil, err
+	}
+	cache := assets.NewDownloadCache()
+	defer cache.Close()
+	f, err := p.Fetch(&providers.FetchOpts{Version: b.Version, PackagePath: packagePath, Cache: cache, Formats: b.PreferFormat, SelectedAsset: selected, RequireChecksum: config.Get().RequireChecksum, Files: b.IsFile(), Mirrors: b.Mirrors, MirrorFirst: b.MirrorFirst, SigningKeys: config.SigningKeys(b), RequireSignature: config.Get().RequireSignature, AssetRewrites: b.AssetRewrites})
+	if err != nil {
+		return bundle.Entry{}, nil, err
+	}
+	if err := checkLocked(ep, lb, f); err != nil {
+		return bundle.Entry{}, nil, err
+	}
+	if f.AssetSHA256 == "" {
+		return bundle.Entry{}, nil, fmt.Errorf("the %s provider doesn't download a release asset, it can't be bundled", p.GetID())
+	}
+	_, data, ok := cache.Asset(f.AssetSHA256)
+	if !ok {
+		return bundle.Entry{}, nil, fmt.Errorf("the asset %s wasn't kept, it can't be bundled", f.Asset)
+	}
+	return bundle.Entry{Path: b.Path, URL: b.URL, Provider: p.GetID(), Version: f.Version, PackagePath: f.PackagePath, Kind: b.Kind, Asset: f.Asset, SHA256: f.AssetSHA256, AssetURL: f.AssetURL}, data, nil
+}
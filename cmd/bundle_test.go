@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/bundle"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestBundle(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := forge.AddTool("acme/other", "v2.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	binDir := versionedDemo(t, forge)
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool/releases/tag/v1.0.0"})
+	p := filepath.Join(binDir, "tool")
+
+	out := filepath.Join(t.TempDir(), "bundle.tar")
+	Execute("test", func(code int) { t.Fatalf("download exited with %d", code) }, []string{"download", "--output", out})
+	b, err := bundle.Open(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Entries) != 1 || b.Entries[0].Version != "v1.0.0" || b.Platform != config.Platform() {
+		t.Fatalf("expected the installed version to be bundled, got %+v", b.Index)
+	}
+
+	// offline: nothing is published anymore
+	demoForge = fakeforge.New()
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"ensure"})
+	if code == 0 {
+		t.Fatal("expected ensure to fail without network access")
+	}
+	Execute("test", func(code int) { t.Fatalf("ensure exited with %d", code) }, []string{"ensure", "--from-bundle", out})
+	if got, _ := os.ReadFile(p); string(got) != string(fakeforge.Script("tool", "v1.0.0")) {
+		t.Errorf("expected the bundled version to be installed, got %q", got)
+	}
+	if cb := config.Get().Bins[p]; cb == nil || cb.Provider != "github" {
+		t.Errorf("expected the binary to keep its provider, got %+v", cb)
+	}
+
+	// installed on another machine
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "--from-bundle", out})
+	if cb := config.Get().Bins[p]; cb == nil || cb.Version != "v1.0.0" {
+		t.Fatalf("expected the bundle to be installed, got %+v", cb)
+	}
+
+	// the binaries missing from the bundle are listed up front
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`", "bins": {"`+filepath.Join(binDir, "other")+`": {"path": "`+filepath.Join(binDir, "other")+`", "url": "http://`+demoHost+`/acme/other", "version": "v2.0.0", "provider": "github"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	code = 0
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--from-bundle", out})
+	if code == 0 {
+		t.Fatal("expected ensure to fail when a binary isn't in the bundle")
+	}
+
+	// the assets of another machine
+	demoForge = forge
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(p); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool/releases/tag/v1.0.0"})
+	cross := filepath.Join(t.TempDir(), "darwin.tar")
+	Execute("test", func(code int) { t.Fatalf("download exited with %d", code) }, []string{"download", "--os", "darwin", "--arch", "arm64", "--output", cross})
+	if b, err = bundle.Open(cross); err != nil {
+		t.Fatal(err)
+	}
+	if b.Platform != "darwin/arm64" || len(b.Entries) != 1 || b.Entries[0].Asset != fakeforge.AssetName("tool", "v1.0.0", "darwin/arm64") {
+		t.Fatalf("expected the darwin/arm64 asset to be bundled, got %+v", b.Index)
+	}
+	if config.Platform() == "darwin/arm64" {
+		return
+	}
+	code = 0
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--from-bundle", cross})
+	if code == 0 {
+		t.Fatal("expected the bundle of another platform to be refused")
+	}
+}
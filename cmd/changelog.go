@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/markdown"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+// the release notes shown by `bin update --changelog`
+const (
+	changelogTarget = "target"
+	changelogAll    = "all"
+)
+
+const (
+	// maxNotesLines truncates the notes of a release
+	maxNotesLines = 30
+	// maxNotesReleases limits the releases shown in between
+	maxNotesReleases = 10
+)
+
+func validateChangelog(mode string) error {
+	switch mode {
+	case "", changelogTarget, changelogAll:
+		return nil
+	}
+	return fmt.Errorf("invalid changelog %q, use %s or %s", mode, changelogTarget, changelogAll)
+}
+
+// showReleaseNotes prints the release notes of the binary updated from
+// the given version. It's quietly skipped when the provider doesn't
+// publish any, failing to get them doesn't fail the update
+func showReleaseNotes(w io.Writer, b *config.Binary, from, mode string) {
+	if mode == "" || b == nil || from == b.Version {
+		return
+	}
+	p, err := newProvider(b)
+	if err != nil {
+		log.Debugf("Unable to get the release notes of %s: %v", b.Path, err)
+		return
+	}
+	n, ok := p.(providers.ReleaseNoter)
+	if !ok || !p.Capabilities().Has(providers.CapReleaseNotes) {
+		return
+	}
+	if mode == changelogTarget {
+		from = ""
+	}
+	notes, err := n.ReleaseNotes(from, b.Version)
+	if err != nil {
+		binLog(b).WithField("version", b.Version).WithError(err).Warn("Unable to get the release notes")
+		return
+	}
+	writeReleaseNotes(w, b, notes)
+}
+
+// writeReleaseNotes prints the notes, newest first, truncating the
+// long ones with a pointer to the release page
+func writeReleaseNotes(w io.Writer, b *config.Binary, notes []*providers.ReleaseNotes) {
+	for i, n := range notes {
+		if i == maxNotesReleases {
+			fmt.Fprintf(w, "... and %d older releases\n\n", len(notes)-i)
+			return
+		}
+		fmt.Fprintf(w, "%s\n", color.New(color.Bold).Sprintf("Release notes of %s %s", os.ExpandEnv(b.Path), n.Version))
+		lines := markdown.Render(n.Body)
+		if len(lines) == 0 {
+			lines = []string{"No release notes"}
+		}
+		truncated := len(lines) > maxNotesLines
+		if truncated {
+			lines = lines[:maxNotesLines]
+		}
+		for _, l := range lines {
+			if l == "" {
+				fmt.Fprintln(w)
+				continue
+			}
+			fmt.Fprintf(w, "  %s\n", l)
+		}
+		if truncated && n.URL != "" {
+			fmt.Fprintf(w, "  ... view full notes at %s\n", n.URL)
+		}
+		fmt.Fprintln(w)
+	}
+}
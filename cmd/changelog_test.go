@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+func TestWriteReleaseNotes(t *testing.T) {
+	color.NoColor = true
+	long := []string{}
+	for i := 0; i < maxNotesLines+5; i++ {
+		long = append(long, fmt.Sprintf("- change %d", i))
+	}
+	notes := []*providers.ReleaseNotes{
+		{Version: "v1.2.0", Body: strings.Join(long, "\n"), URL: "https://github.com/o/r/releases/v1.2.0"},
+		{Version: "v1.1.0"},
+	}
+	var out bytes.Buffer
+	writeReleaseNotes(&out, &config.Binary{Path: "/bin/noted"}, notes)
+
+	got := out.String()
+	for _, want := range []string{
+		"Release notes of /bin/noted v1.2.0\n  • change 0\n",
+		fmt.Sprintf("  • change %d\n  ... view full notes at https://github.com/o/r/releases/v1.2.0\n", maxNotesLines-1),
+		"Release notes of /bin/noted v1.1.0\n  No release notes\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected the notes to contain %q, got\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, fmt.Sprintf("change %d\n", maxNotesLines)) {
+		t.Errorf("expected the notes to be truncated, got\n%s", got)
+	}
+}
+
+func TestValidateChangelog(t *testing.T) {
+	for _, mode := range []string{"", changelogTarget, changelogAll} {
+		if err := validateChangelog(mode); err != nil {
+			t.Errorf("expected %q to be valid, got %v", mode, err)
+		}
+	}
+	if err := validateChangelog("latest"); err == nil {
+		t.Error("expected an unknown changelog to be refused")
+	}
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// completionTimeout bounds the completions reaching the providers,
+// nothing is completed when they don't answer in time (i.e. offline)
+var completionTimeout = 2 * time.Second
+
+// completionVersions is the number of recent versions completed
+const completionVersions = 20
+
+// completeBinaries completes the names of the managed binaries, up to
+// n arguments (0 for any number) and not the ones already given
+func completeBinaries(n int) cobra.CompletionFunc {
+	return func(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+		if n > 0 && len(args) >= n {
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		}
+		given := map[string]bool{}
+		for _, a := range args {
+			given[filepath.Base(a)] = true
+		}
+		res := []cobra.Completion{}
+		for _, b := range config.Get().Bins {
+			name := filepath.Base(os.ExpandEnv(b.Path))
+			if given[name] || !strings.HasPrefix(name, toComplete) {
+				continue
+			}
+			res = append(res, cobra.CompletionWithDesc(name, b.Version))
+		}
+		sort.Strings(res)
+		return res, cobra.ShellCompDirectiveNoFileComp
+	}
+}
+
+// completeInstall completes the recent versions of the
+// `owner/repo@` shorthands, and paths otherwise
+func completeInstall(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	repo, prefix, ok := strings.Cut(toComplete, "@")
+	if len(args) > 0 || !ok {
+		return nil, cobra.ShellCompDirectiveDefault
+	}
+	u, err := providers.ExpandShorthand(repo, config.Get().DefaultForge)
+	if err != nil || u == repo {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	releases, ok := withinTimeout(func() ([]*providers.Release, error) {
+		p, err := newProvider(&config.Binary{URL: u})
+		if err != nil {
+			return nil, err
+		}
+		if !p.Capabilities().Has(providers.CapListVersions) {
+			return nil, nil
+		}
+		return p.ListVersions(completionVersions)
+	})
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	res := []cobra.Completion{}
+	for _, r := range releases {
+		if strings.HasPrefix(r.Version, prefix) {
+			res = append(res, repo+"@"+r.Version)
+		}
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp | cobra.ShellCompDirectiveKeepOrder
+}
+
+// completeAssets completes the assets of the release
+// of the URL being installed, the latest one by default
+func completeAssets(cmd *cobra.Command, args []string, toComplete string) ([]cobra.Completion, cobra.ShellCompDirective) {
+	if len(args) == 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	u, err := providers.ExpandShorthand(args[0], config.Get().DefaultForge)
+	if err != nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	names, ok := withinTimeout(func() ([]string, error) {
+		p, err := newProvider(&config.Binary{URL: u})
+		if err != nil {
+			return nil, err
+		}
+		if l, ok := p.(providers.AssetLister); ok {
+			return l.ListAssets("")
+		}
+		return nil, nil
+	})
+	if !ok {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	res := []cobra.Completion{}
+	for _, n := range names {
+		if strings.HasPrefix(n, toComplete) {
+			res = append(res, n)
+		}
+	}
+	return res, cobra.ShellCompDirectiveNoFileComp
+}
+
+// withinTimeout returns the result of fn unless it fails or
+// takes longer than completionTimeout, it's left running then
+// since the completions exit right after
+func withinTimeout[T any](fn func() (T, error)) (T, bool) {
+	type result struct {
+		v   T
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := fn()
+		done <- result{v, err}
+	}()
+	select {
+	case r := <-done:
+		if r.err != nil {
+			log.Debugf("Completing nothing: %v", r.err)
+		}
+		return r.v, r.err == nil
+	case <-time.After(completionTimeout):
+		log.Debugf("Completing nothing, no answer within %s", completionTimeout)
+		var zero T
+		return zero, false
+	}
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestCompletion(t *testing.T) {
+	forge := fakeforge.New()
+	for _, r := range []string{"acme/tool", "acme/other"} {
+		if err := forge.AddTool(r, "v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { demoForge = nil }()
+	demoForge = forge
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	t.Setenv("BIN_DEMO", "1")
+	for _, k := range []string{"BIN_CONFIG", "BIN_STATE", "XDG_DATA_HOME"} {
+		t.Setenv(k, "")
+	}
+	for _, r := range []string{"acme/tool", "acme/other"} {
+		Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/" + r})
+	}
+
+	complete := func(args ...string) []string {
+		t.Helper()
+		out := captureStdout(t, func() {
+			Execute("test", func(code int) { t.Fatalf("completion exited with %d", code) }, append([]string{"__completeNoDesc"}, args...))
+		})
+		// the last line is the directive
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+		return lines[:len(lines)-1]
+	}
+
+	if c := complete("update", ""); strings.Join(c, " ") != "other tool" {
+		t.Fatalf("expected the managed binaries, got %v", c)
+	}
+	if c := complete("remove", "tool", ""); strings.Join(c, " ") != "other" {
+		t.Fatalf("expected the binaries not given yet, got %v", c)
+	}
+	if c := complete("info", "tool", ""); len(c) != 0 {
+		t.Fatalf("expected a single binary to be completed, got %v", c)
+	}
+	if c := complete("install", "http://"+demoHost+"/acme/tool", "--asset", "tool_"); len(c) == 0 || !strings.HasPrefix(c[0], "tool_1.0.0_") {
+		t.Fatalf("expected the assets of the latest release, got %v", c)
+	}
+
+	defer func(d time.Duration) { completionTimeout = d }(completionTimeout)
+	completionTimeout = 10 * time.Millisecond
+	if _, ok := withinTimeout(func() (int, error) { time.Sleep(time.Second); return 1, nil }); ok {
+		t.Fatal("expected nothing to be completed after the timeout")
+	}
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+type configCmd struct {
+	cmd *cobra.Command
+}
+
+func newConfigCmd() *configCmd {
+	root := &configCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "config",
+		Short:         "Manages the configuration file",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+	}
+
+	fmtCmd := &cobra.Command{
+		Use:           "fmt [file]",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Rewrites the configuration file in its canonical form",
+		Long:          "Rewrites the configuration file, or the given one, in the form bin writes it in: the binaries sorted by name, their fields in a fixed order and an indentation of 4 spaces. The configuration isn't changed, the fields bin doesn't know are refused instead of being dropped.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var p string
+			if len(args) > 0 {
+				p = args[0]
+			} else {
+				var err error
+				if p, err = config.GetConfigPath(); err != nil {
+					return err
+				}
+			}
+			changed, err := config.FormatFile(p)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				log.WithField("path", p).Info("Already formatted")
+				return nil
+			}
+			log.WithField("path", p).Info("Formatted")
+			return nil
+		},
+	}
+
+	cmd.AddCommand(fmtCmd)
+	root.cmd = cmd
+	return root
+}
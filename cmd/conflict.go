@@ -0,0 +1,213 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/options"
+	"github.com/marcosnils/bin/pkg/prompt"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/stats"
+)
+
+// resolution is how ensure resolves a binary diverging
+// from the state recorded in the configuration
+type resolution string
+
+const (
+	// resolveKeepLocal records the installed file, pinned
+	// to the version it reports
+	resolveKeepLocal resolution = "keep-local"
+	// resolveRestore re-installs the recorded version
+	resolveRestore resolution = "restore-pinned"
+	// resolveUpgrade installs the latest version
+	resolveUpgrade resolution = "upgrade-latest"
+)
+
+var resolutions = []resolution{resolveKeepLocal, resolveRestore, resolveUpgrade}
+
+// versionTimeout bounds the run of a binary to detect its version
+const versionTimeout = 5 * time.Second
+
+var versionRe = regexp.MustCompile(`v?\d+\.\d+(\.\d+)?([-+][0-9A-Za-z.-]+)?`)
+
+// conflict is a binary whose state diverges from the configuration:
+// it was changed outside of bin, or its recorded version isn't
+// available anymore (i.e. the release was yanked)
+type conflict struct {
+	b *config.Binary
+	// modified is set when the installed file changed, unavailable
+	// when the recorded version can't be installed anymore
+	modified, unavailable bool
+	// localHash and localVersion describe the installed file,
+	// the version is empty when it can't be detected
+	localHash, localVersion string
+	// latest and latestURL are the latest version available,
+	// empty when it couldn't be checked
+	latest, latestURL string
+}
+
+// choices returns the resolutions which make sense for the conflict
+func (c *conflict) choices() []resolution {
+	var rs []resolution
+	if c.modified {
+		rs = append(rs, resolveKeepLocal)
+	}
+	if !c.unavailable {
+		rs = append(rs, resolveRestore)
+	}
+	// pinned binaries are never upgraded by ensure
+	if c.newer() && !c.b.Pinned {
+		rs = append(rs, resolveUpgrade)
+	}
+	return rs
+}
+
+// newer reports whether the latest version is newer than the
+// recorded one, or different when they can't be compared
+func (c *conflict) newer() bool {
+	if c.latest == "" || c.latest == c.b.Version {
+		return false
+	}
+	tags, err := providers.NewTagFilter(c.b.TagPrefix, c.b.TagPattern)
+	if err != nil {
+		return true
+	}
+	latest, lErr := version.NewVersion(tags.Version(c.latest))
+	pinned, pErr := version.NewVersion(tags.Version(c.b.Version))
+	return lErr != nil || pErr != nil || latest.GreaterThan(pinned)
+}
+
+func (c *conflict) describe(r resolution) string {
+	switch r {
+	case resolveKeepLocal:
+		v := c.localVersion
+		if v == "" {
+			v = c.b.Version
+		}
+		return fmt.Sprintf("%s: keep the installed file, pinned to %s", r, v)
+	case resolveRestore:
+		return fmt.Sprintf("%s: re-install %s", r, c.b.Version)
+	default:
+		return fmt.Sprintf("%s: install %s", r, c.latest)
+	}
+}
+
+// print shows the local, pinned and latest states of the binary
+func (c *conflict) print() {
+	p := os.ExpandEnv(c.b.Path)
+	reason := "was modified since it was installed"
+	if c.unavailable {
+		reason = fmt.Sprintf("is missing and its version %s isn't available anymore", c.b.Version)
+	}
+	fmt.Printf("\n%s %s\n", color.YellowString(p), reason)
+	if c.modified {
+		local := c.localVersion
+		if local == "" {
+			local = "unknown version"
+		}
+		fmt.Printf("  %s  %s (sha256:%.12s)\n", _rPad("local", 6), local, c.localHash)
+	}
+	fmt.Printf("  %s  %s\n", _rPad("pinned", 6), c.b.Version)
+	latest := c.latest
+	if latest == "" {
+		latest = "unknown"
+	}
+	fmt.Printf("  %s  %s\n", _rPad("latest", 6), latest)
+}
+
+// resolve picks the resolution of the conflict, asking the user
+// unless a strategy is given. Non interactive runs without one
+// re-install the recorded version as ensure always did.
+func (c *conflict) resolve(strategy resolution) (resolution, error) {
+	choices := c.choices()
+	if strategy == "" && !prompt.IsInteractive() {
+		strategy = resolveRestore
+	}
+	if strategy == resolveUpgrade && c.b.Pinned {
+		return "", fmt.Errorf("%s is pinned to %s, unpin it to upgrade it", os.ExpandEnv(c.b.Path), c.b.Version)
+	}
+	if strategy != "" {
+		for _, r := range choices {
+			if r == strategy {
+				return r, nil
+			}
+		}
+		return "", fmt.Errorf("%s can't be resolved with %s, use --strategy with one of: %s", os.ExpandEnv(c.b.Path), strategy, joinResolutions(choices))
+	}
+	if len(choices) == 0 {
+		return "", fmt.Errorf("%s can't be resolved, neither its version %s nor a newer one is available", os.ExpandEnv(c.b.Path), c.b.Version)
+	}
+
+	c.print()
+	opts := make([]fmt.Stringer, 0, len(choices))
+	for _, r := range choices {
+		opts = append(opts, options.LiteralStringer(c.describe(r)))
+	}
+	choice, err := options.Select("How do you want to resolve it?", opts)
+	if err != nil {
+		return "", err
+	}
+	for i, o := range opts {
+		if o == choice {
+			return choices[i], nil
+		}
+	}
+	return "", fmt.Errorf("invalid choice %v", choice)
+}
+
+func joinResolutions(rs []resolution) string {
+	s := make([]string, len(rs))
+	for i, r := range rs {
+		s[i] = string(r)
+	}
+	return strings.Join(s, ", ")
+}
+
+// parseResolution validates the --strategy flag
+func parseResolution(s string) (resolution, error) {
+	if s == "" {
+		return "", nil
+	}
+	for _, r := range resolutions {
+		if string(r) == s {
+			return r, nil
+		}
+	}
+	return "", fmt.Errorf("invalid strategy %q, use one of %s", s, joinResolutions(resolutions))
+}
+
+// reportedVersion runs the executable with --version
+// and returns the first version it prints
+func reportedVersion(path string) string {
+	ctx, cancel := context.WithTimeout(context.Background(), versionTimeout)
+	defer cancel()
+	out, _ := exec.CommandContext(ctx, path, "--version").CombinedOutput()
+	return versionRe.FindString(string(out))
+}
+
+// detectVersion runs the binary with --version and returns the first
+// version it prints, with the v prefix of the recorded one if any
+func detectVersion(b *config.Binary) string {
+	if b.IsFile() {
+		return ""
+	}
+	v := reportedVersion(stats.Resolve(os.ExpandEnv(b.Path)))
+	if v == "" {
+		return ""
+	}
+	if strings.HasPrefix(b.Version, "v") && !strings.HasPrefix(v, "v") {
+		v = "v" + v
+	} else if !strings.HasPrefix(b.Version, "v") {
+		v = strings.TrimPrefix(v, "v")
+	}
+	return v
+}
@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestEnsureStrategy(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binaries are shell scripts")
+	}
+	dir, binDir := newTestConfig(t, "")
+
+	src := filepath.Join(dir, "tool-1.0.0")
+	original := writeScript(t, src, "tool 1.0.0")
+	installed := filepath.Join(binDir, "tool")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src, installed})
+
+	// upgraded outside of bin
+	writeScript(t, installed, "tool 1.2.0")
+	Execute("test", func(code int) { t.Fatalf("ensure exited with %d", code) }, []string{"ensure", "--strategy", "keep-local"})
+	b := config.Get().Bins[installed]
+	if b.Version != "1.2.0" || !b.Pinned {
+		t.Fatalf("expected the binary to be pinned to the detected version, got %s (pinned %t)", b.Version, b.Pinned)
+	}
+	if r, err := ensureBinary(b, nil, false); err != nil || r != nil {
+		t.Fatalf("expected the kept binary to be ensured, got %+v (%v)", r, err)
+	}
+
+	writeScript(t, installed, "tool 1.3.0")
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--strategy", "upgrade-latest"})
+	if code == 0 {
+		t.Error("expected upgrade-latest to be refused without a newer version")
+	}
+	Execute("test", func(code int) { t.Fatalf("ensure exited with %d", code) }, []string{"ensure", "--strategy", "restore-pinned"})
+	if got, _ := os.ReadFile(installed); string(got) != string(original) {
+		t.Errorf("expected the recorded version to be restored, got %q", got)
+	}
+
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--strategy", "newest"})
+	if code == 0 {
+		t.Error("expected an invalid strategy to be refused")
+	}
+}
+
+func TestConflictChoices(t *testing.T) {
+	b := &config.Binary{Path: "/bin/tool", Version: "v1.2.0"}
+	cases := []struct {
+		c    *conflict
+		want []resolution
+	}{
+		{&conflict{b: b, modified: true, latest: "v1.3.0"}, []resolution{resolveKeepLocal, resolveRestore, resolveUpgrade}},
+		{&conflict{b: b, modified: true, latest: "v1.1.0"}, []resolution{resolveKeepLocal, resolveRestore}},
+		{&conflict{b: b, modified: true}, []resolution{resolveKeepLocal, resolveRestore}},
+		// the release of the recorded version was yanked
+		{&conflict{b: b, unavailable: true, latest: "v1.2.1"}, []resolution{resolveUpgrade}},
+		{&conflict{b: b, unavailable: true, latest: "v1.2.0"}, nil},
+	}
+	for i, c := range cases {
+		if got := c.c.choices(); !slices.Equal(got, c.want) {
+			t.Errorf("%d: expected %v, got %v", i, c.want, got)
+		}
+	}
+
+	if _, err := cases[4].c.resolve(resolveUpgrade); err == nil {
+		t.Error("expected the conflict not to be resolved without a newer version")
+	}
+}
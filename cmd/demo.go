@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// demoHost is the host of the forge of the demo mode, .test
+// is reserved so nothing ever leaves the process for it
+const demoHost = "forge.bin.test"
+
+// demoTools are the repositories of the demo forge
+// with their versions, the last one is the latest
+var demoTools = []struct {
+	repo     string
+	versions []string
+}{
+	{"demo/hello", []string{"v1.0.0", "v1.1.0", "v1.2.0"}},
+	{"demo/jqlite", []string{"v0.9.0", "v1.0.0"}},
+	{"demo/tiny", []string{"2.0.0", "2.1.0"}},
+}
+
+// demoForge serves the releases of the demo mode, the built-in tools
+// when nil. The tests set their own to change it along the scenario
+// nolint: gochecknoglobals
+var demoForge http.Handler
+
+// demoEnabled reports whether BIN_DEMO is set
+func demoEnabled() bool {
+	v, _ := strconv.ParseBool(os.Getenv("BIN_DEMO"))
+	return v
+}
+
+// demoDir is the profile of the demo mode: the configuration,
+// the state, the binaries and everything bin writes
+func demoDir() string {
+	return filepath.Join(os.TempDir(), "bin-demo")
+}
+
+// newDemoForge returns a forge with the releases of demoTools, they're
+// synthesized identically on every run so the installs of the
+// previous runs stay consistent
+func newDemoForge() (http.Handler, error) {
+	f := fakeforge.New()
+	for _, t := range demoTools {
+		if err := f.AddTool(t.repo, t.versions...); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// startDemo points bin to the demo profile, creating it if needed,
+// and serves the demo forge in-process through the settings
+func startDemo(s *providers.Settings) error {
+	dir := demoDir()
+	conf := filepath.Join(dir, "config.json")
+	if _, err := os.Stat(conf); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Join(dir, "bin"), 0o755); err != nil {
+			return err
+		}
+		c := fmt.Sprintf(`{"default_path": %q, "default_files_path": %q}`, filepath.Join(dir, "bin"), filepath.Join(dir, "files"))
+		if err := os.WriteFile(conf, []byte(c), 0o644); err != nil {
+			return err
+		}
+	}
+	for k, v := range map[string]string{"BIN_CONFIG": conf, "BIN_STATE": filepath.Join(dir, "state.json"), "XDG_DATA_HOME": filepath.Join(dir, "share")} {
+		if err := os.Setenv(k, v); err != nil {
+			return err
+		}
+	}
+
+	forge := demoForge
+	if forge == nil {
+		var err error
+		if forge, err = newDemoForge(); err != nil {
+			return err
+		}
+	}
+	s.Intercept(demoHost, forge)
+	s.Set("GHES_BASE_URL", "http://"+demoHost+fakeforge.APIPath)
+	log.Debugf("demo mode enabled, using the profile at %s", dir)
+	return nil
+}
+
+type demoCmd struct {
+	cmd  *cobra.Command
+	opts demoOpts
+}
+
+type demoOpts struct {
+	remove bool
+}
+
+func newDemoCmd() *demoCmd {
+	root := &demoCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "demo",
+		Short:         "Prints how to try bin offline against a fake forge",
+		Long:          "Prints how to try bin offline: with BIN_DEMO=1 every command uses a temporary profile and installs synthetic tools from a fake forge served in-process",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dir := demoDir()
+			if root.opts.remove {
+				if err := os.RemoveAll(dir); err != nil {
+					return err
+				}
+				log.WithField("path", dir).Info("Removed the demo profile")
+				return nil
+			}
+
+			fmt.Printf("Run the commands with BIN_DEMO=1 to use the demo profile at %s,\n", dir)
+			fmt.Printf("its binaries are installed in %s. The tools of the demo forge are:\n\n", filepath.Join(dir, "bin"))
+			for _, t := range demoTools {
+				fmt.Printf("  http://%s/%s  %v\n", demoHost, t.repo, t.versions)
+			}
+			fmt.Printf("\nFor instance:\n\n")
+			fmt.Printf("  export BIN_DEMO=1\n")
+			fmt.Printf("  bin install http://%s/%s/releases/tag/%s\n", demoHost, demoTools[0].repo, demoTools[0].versions[0])
+			fmt.Printf("  bin outdated\n  bin update --yes\n  bin list\n\n")
+			fmt.Printf("`bin demo --remove` deletes the profile.\n")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.remove, "remove", false, "Delete the demo profile and everything installed in it")
+	return root
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+// TestDemo runs the commands in demo mode against a forge
+// whose releases change along the scenario
+func TestDemo(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { demoForge = nil }()
+	demoForge = forge
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	t.Setenv("BIN_DEMO", "1")
+	for _, k := range []string{"BIN_CONFIG", "BIN_STATE", "XDG_DATA_HOME"} {
+		t.Setenv(k, "")
+	}
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool"})
+	installed := executablePath(filepath.Join(demoDir(), "bin", "tool"))
+	if b, _ := os.ReadFile(installed); string(b) != string(fakeforge.Script("tool", "v1.0.0")) {
+		t.Fatalf("expected the tool to be installed in the demo profile, got %q", b)
+	}
+
+	// a release is added upstream
+	if err := forge.AddTool("acme/tool", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", installed})
+	if b := config.Get().Bins[installed]; b == nil || b.Version != "v1.1.0" {
+		t.Fatalf("expected the tool to be updated to v1.1.0, got %+v", b)
+	}
+
+	// the installed version is re-published and doesn't
+	// match its checksum anymore, ensure refuses it
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	if err := forge.ReplaceAsset("acme/tool", "v1.1.0", fakeforge.AssetName("tool", "v1.1.0", host), []byte("tampered")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(installed); err != nil {
+		t.Fatal(err)
+	}
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"ensure", installed})
+	if code == 0 {
+		t.Fatal("expected ensure to refuse the tampered asset")
+	}
+
+	Execute("test", func(code int) { t.Fatalf("demo exited with %d", code) }, []string{"demo", "--remove"})
+	if _, err := os.Stat(demoDir()); !os.IsNotExist(err) {
+		t.Fatalf("expected the demo profile to be removed, got %v", err)
+	}
+}
@@ -0,0 +1,211 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+type doctorCmd struct {
+	cmd  *cobra.Command
+	opts doctorOpts
+}
+
+type doctorOpts struct {
+	// providers prints the provider of every
+	// binary along with its capabilities
+	providers bool
+	// fixPath writes the shell profile putting the
+	// managed binaries first in PATH, see fixPath
+	fixPath bool
+	shell   string
+}
+
+// doctorCheck inspects the managed binaries and
+// returns the problems it found, if any
+type doctorCheck struct {
+	name string
+	run  func() []string
+}
+
+// doctorChecks are run by doctor in order
+var doctorChecks = []doctorCheck{
+	{"directories", checkDirectories},
+	{"ownership", checkOwnership},
+	{"capabilities", checkCapabilities},
+	{"path", checkPath},
+}
+
+func newDoctorCmd() *doctorCmd {
+	root := &doctorCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "doctor",
+		Short:         "Checks the managed binaries for problems and suggests how to fix them",
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if root.opts.providers {
+				printProviders()
+			}
+			if root.opts.fixPath {
+				return fixPath(root.opts.shell)
+			}
+			problems := 0
+			for _, c := range doctorChecks {
+				found := c.run()
+				if len(found) == 0 {
+					log.WithField("check", c.name).Info("ok")
+					continue
+				}
+				for _, p := range found {
+					log.WithField("check", c.name).Warn(p)
+				}
+				problems += len(found)
+			}
+			if problems > 0 {
+				return fmt.Errorf("found %d problems", problems)
+			}
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.providers, "providers", false, "Print the provider of every binary along with what it supports")
+	root.cmd.Flags().BoolVar(&root.opts.fixPath, "fix-path", false, "Put the directories of the managed binaries first in PATH in the profile of the shell")
+	root.cmd.Flags().StringVar(&root.opts.shell, "shell", "", "Shell whose profile is written by --fix-path: bash, zsh, fish or powershell (default from $SHELL)")
+	return root
+}
+
+// checkDirectories reports the binaries recorded in directories which
+// don't exist anymore, i.e. because they were moved
+func checkDirectories() []string {
+	missing := map[string]int{}
+	for p := range config.Get().Bins {
+		dir := filepath.Dir(os.ExpandEnv(p))
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			missing[dir]++
+		}
+	}
+	dirs := make([]string, 0, len(missing))
+	for d := range missing {
+		dirs = append(dirs, d)
+	}
+	sort.Strings(dirs)
+
+	problems := make([]string, 0, len(dirs)+1)
+	for _, d := range dirs {
+		problems = append(problems, fmt.Sprintf("%d binaries are recorded in %s which doesn't exist anymore, if it was moved run `bin migrate-path --from %s --to <directory>`", missing[d], d, d))
+	}
+	if dp := os.ExpandEnv(config.Get().DefaultPath); dp != "" && missing[dp] == 0 {
+		if _, err := os.Stat(dp); os.IsNotExist(err) {
+			problems = append(problems, fmt.Sprintf("the default path %s doesn't exist", dp))
+		}
+	}
+	return problems
+}
+
+// checkOwnership reports the recorded files replaced by other installs
+// and the files labeled by bin, in the managed directories, which
+// aren't recorded anymore
+func checkOwnership() []string {
+	cfg := config.Get()
+	dirs := map[string]bool{}
+	if cfg.DefaultPath != "" {
+		dirs[os.ExpandEnv(cfg.DefaultPath)] = true
+	}
+	var problems []string
+	paths := make([]string, 0, len(cfg.Bins))
+	for p := range cfg.Bins {
+		paths = append(paths, os.ExpandEnv(p))
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		dirs[filepath.Dir(p)] = true
+		if o, err := ownerOf(p); err == nil && o.replaced() {
+			problems = append(problems, fmt.Sprintf("%s was replaced by a file installed from %s, `bin prune` forgets it", p, o.label.Source))
+		}
+	}
+
+	sorted := make([]string, 0, len(dirs))
+	for d := range dirs {
+		sorted = append(sorted, d)
+	}
+	sort.Strings(sorted)
+	for _, d := range sorted {
+		entries, err := os.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			p := filepath.Join(d, e.Name())
+			if o, err := ownerOf(p); err == nil && o.orphaned() {
+				problems = append(problems, fmt.Sprintf("%s was installed by bin from %s but isn't in the configuration, install it again or remove it", p, o.label.Source))
+			}
+		}
+	}
+	return problems
+}
+
+// managedProviders returns the provider of each binary by path, sorted
+func managedProviders() ([]string, map[string]providers.Provider, map[string]error) {
+	cfg := config.Get()
+	paths := make([]string, 0, len(cfg.Bins))
+	ps, errs := map[string]providers.Provider{}, map[string]error{}
+	for k, b := range cfg.Bins {
+		paths = append(paths, k)
+		if ps[k], errs[k] = newProvider(b); errs[k] != nil {
+			delete(ps, k)
+		}
+	}
+	sort.Strings(paths)
+	return paths, ps, errs
+}
+
+// checkCapabilities reports the binaries whose settings rely on
+// something their provider doesn't support
+func checkCapabilities() []string {
+	cfg := config.Get()
+	paths, ps, errs := managedProviders()
+	var problems []string
+	for _, k := range paths {
+		b, p := cfg.Bins[k], ps[k]
+		if p == nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", os.ExpandEnv(k), errs[k]))
+			continue
+		}
+		c := p.Capabilities()
+		if cfg.RequireChecksum && !c.Has(providers.CapChecksums) {
+			problems = append(problems, fmt.Sprintf("%s is installed with the %s provider which can't verify checksums, but require_checksum is set", os.ExpandEnv(k), p.GetID()))
+		}
+		if b.TrackLatest > 0 && !c.Has(providers.CapListVersions) {
+			problems = append(problems, fmt.Sprintf("%s tracks the latest series but the %s provider can't list the versions of %s", os.ExpandEnv(k), p.GetID(), b.URL))
+		}
+	}
+	return problems
+}
+
+// printProviders prints the provider of every binary and its
+// capabilities, the binaries whose provider can't be built are
+// reported by checkCapabilities
+func printProviders() {
+	paths, ps, _ := managedProviders()
+	header := []tableColumn{{header: "Path", truncate: true}, {header: "Provider"}, {header: "Capabilities"}}
+	rows := make([][]tableCell, 0, len(paths))
+	for _, k := range paths {
+		id, caps := "-", "-"
+		if p := ps[k]; p != nil {
+			id, caps = p.GetID(), p.Capabilities().String()
+		}
+		rows = append(rows, []tableCell{{text: os.ExpandEnv(k)}, {text: id}, {text: caps}})
+	}
+	printTable(os.Stdout, header, rows, terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+}
@@ -0,0 +1,257 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/spf13/cobra"
+)
+
+// quotaTop is the number of items listed when the quota is exceeded
+const quotaTop = 5
+
+type duCmd struct {
+	cmd  *cobra.Command
+	opts duOpts
+}
+
+type duOpts struct {
+	top int
+}
+
+// usageItem is an artifact managed by bin and the
+// disk space it takes
+type usageItem struct {
+	Path string
+	// Kind is binary or file for the installed entries, previous
+	// for the copies kept of modified binaries, or the name of the
+	// directory of bin (cache, downloads, journal, stats)
+	Kind string
+	Size int64
+}
+
+func newDuCmd() *duCmd {
+	root := &duCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "du",
+		Short: "Shows the disk space taken by the binaries and files managed by bin",
+		Long: `Shows the disk space taken by the binaries and files managed by bin,
+largest first, along with their completions, man pages, the copies kept
+of modified binaries and the cache of bin.
+
+The total is checked against the 'quota' of the configuration on
+install and update, see 'bin prune' to free space.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			items, total, err := managedUsage()
+			if err != nil {
+				return err
+			}
+			quota, err := getQuota()
+			if err != nil {
+				return err
+			}
+			if root.opts.top > 0 && len(items) > root.opts.top {
+				items = items[:root.opts.top]
+			}
+			printUsage(os.Stdout, items, total, quota)
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().IntVar(&root.opts.top, "top", 0, "Only show the given number of largest items")
+	return root
+}
+
+func printUsage(w io.Writer, items []usageItem, total, quota int64) {
+	sizes := make([]string, len(items))
+	sL, kL := len("Size"), len("Kind")
+	for i, it := range items {
+		sizes[i] = assets.FormatSize(it.Size)
+		sL, kL = max(sL, len(sizes[i])), max(kL, len(it.Kind))
+	}
+	fmt.Fprintf(w, "%s  %s  %s\n", _rPad("Size", sL), _rPad("Kind", kL), "Path")
+	for i, it := range items {
+		fmt.Fprintf(w, "%s  %s  %s\n", _rPad(sizes[i], sL), _rPad(it.Kind, kL), it.Path)
+	}
+	summary := fmt.Sprintf("\nTotal: %s", assets.FormatSize(total))
+	if quota > 0 {
+		summary += fmt.Sprintf(" of a %s quota", assets.FormatSize(quota))
+	}
+	fmt.Fprintln(w, summary)
+}
+
+// managedUsage returns the artifacts managed by bin, largest first,
+// and the disk space they take altogether. The binaries account for
+// their real binary when they're shims, and their completions and
+// man pages. The versions of the store are accounted for as a whole.
+func managedUsage() ([]usageItem, int64, error) {
+	items := []usageItem{}
+	for _, b := range config.Get().Bins {
+		p := os.ExpandEnv(b.Path)
+		size := diskUsage(p)
+		if stats.IsShim(p) {
+			size += diskUsage(stats.RealPath(p))
+		}
+		for _, e := range b.Extras {
+			size += diskUsage(os.ExpandEnv(e))
+		}
+		kind := config.KindBinary
+		if b.IsFile() {
+			kind = config.KindFile
+		}
+		items = append(items, usageItem{Path: p, Kind: kind, Size: size})
+
+		// the copy kept when a modified binary was overwritten
+		if size := diskUsage(p + ".local"); size > 0 {
+			items = append(items, usageItem{Path: p + ".local", Kind: "previous", Size: size})
+		}
+	}
+
+	for kind, dir := range map[string]func() (string, error){
+		"cache":     config.GetCacheDir,
+		"downloads": config.GetDownloadsDir,
+		"journal":   config.GetJournalDir,
+		"stats":     config.GetStatsDir,
+		"store":     config.GetStoreDir,
+	} {
+		d, err := dir()
+		if err != nil {
+			return nil, 0, err
+		}
+		if size := diskUsage(d); size > 0 {
+			items = append(items, usageItem{Path: d, Kind: kind, Size: size})
+		}
+	}
+
+	var total int64
+	for _, it := range items {
+		total += it.Size
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Size != items[j].Size {
+			return items[i].Size > items[j].Size
+		}
+		return items[i].Path < items[j].Path
+	})
+	return items, total, nil
+}
+
+// diskUsage returns the size of the file at path or, for directories,
+// of every file in it. Symlinks aren't followed and missing paths
+// take no space.
+func diskUsage(path string) int64 {
+	var size int64
+	_ = filepath.WalkDir(path, func(_ string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.Type().IsRegular() {
+			if fi, err := d.Info(); err == nil {
+				size += fi.Size()
+			}
+		}
+		return nil
+	})
+	return size
+}
+
+// getQuota returns the quota of the configuration, 0 when unset
+func getQuota() (int64, error) {
+	q := config.Get().Quota
+	if q == "" {
+		return 0, nil
+	}
+	size, err := parseSize(q)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quota: %w", err)
+	}
+	return size, nil
+}
+
+// guardQuota warns when saving f at path would bring the disk space
+// taken by the managed artifacts over the quota, listing the largest
+// ones. It fails instead when enforce is set.
+func guardQuota(path string, f *providers.File, enforce bool) error {
+	quota, err := getQuota()
+	if err != nil || quota == 0 {
+		return err
+	}
+	items, total, err := managedUsage()
+	if err != nil {
+		return err
+	}
+
+	size, err := fileSize(f)
+	if err != nil {
+		return err
+	}
+	// the new file replaces the one at path, if any
+	after := total + size - diskUsage(os.ExpandEnv(path))
+	if after <= quota {
+		return nil
+	}
+
+	largest := []string{}
+	for _, it := range items[:min(len(items), quotaTop)] {
+		largest = append(largest, fmt.Sprintf("%s (%s)", it.Path, assets.FormatSize(it.Size)))
+	}
+	if enforce {
+		return fmt.Errorf("installing %s %s brings the disk usage of bin to %s, over its quota of %s. The largest items are %s, see 'bin du' and 'bin prune'", f.Name, f.Version, assets.FormatSize(after), assets.FormatSize(quota), strings.Join(largest, ", "))
+	}
+	log.WithField("binary", f.Name).WithField("version", f.Version).WithField("usage", assets.FormatSize(after)).WithField("quota", assets.FormatSize(quota)).WithField("largest", largest).Warn("The install brings the disk usage of bin over its quota, see 'bin du' and 'bin prune'")
+	return nil
+}
+
+// fileSize returns the size of the fetched file, its data is
+// buffered so the size is known before it's saved
+func fileSize(f *providers.File) (int64, error) {
+	b, err := io.ReadAll(f.Data)
+	if err != nil {
+		return 0, err
+	}
+	f.Data = bytes.NewReader(b)
+	return int64(len(b)), nil
+}
+
+// parseSize parses a size in bytes with an optional unit, either
+// decimal (KB, MB, GB, TB) or binary (K, KiB, M, MiB, ...)
+func parseSize(s string) (int64, error) {
+	units := []struct {
+		suffix string
+		mult   int64
+	}{
+		{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+		{"KB", 1e3}, {"MB", 1e6}, {"GB", 1e9}, {"TB", 1e12},
+		{"K", 1 << 10}, {"M", 1 << 20}, {"G", 1 << 30}, {"T", 1 << 40},
+		{"B", 1},
+	}
+	v, mult := strings.TrimSpace(s), int64(1)
+	for _, u := range units {
+		if len(v) >= len(u.suffix) && strings.EqualFold(v[len(v)-len(u.suffix):], u.suffix) {
+			v, mult = strings.TrimSpace(v[:len(v)-len(u.suffix)]), u.mult
+			break
+		}
+	}
+	n, err := strconv.ParseFloat(v, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return int64(n * float64(mult)), nil
+}
@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	for s, want := range map[string]int64{
+		"1024":    1024,
+		"10B":     10,
+		"2K":      2 << 10,
+		"1.5 MiB": 3 << 19,
+		"2gib":    2 << 30,
+		"1GB":     1e9,
+		"500MB":   500e6,
+	} {
+		got, err := parseSize(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if got != want {
+			t.Errorf("%s: expected %d, got %d", s, want, got)
+		}
+	}
+	for _, s := range []string{"", "GiB", "-1M", "1 PB"} {
+		if _, err := parseSize(s); err == nil {
+			t.Errorf("%s: expected an error", s)
+		}
+	}
+}
+
+func TestQuota(t *testing.T) {
+	dir, binDir := newTestConfig(t, `"quota": "1K"`)
+	src := filepath.Join(dir, "tool")
+	if err := os.WriteFile(src, bytes.Repeat([]byte("x"), 2048), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"install", src, "--enforce-quota"})
+	if code == 0 {
+		t.Fatal("expected the install to be refused over the quota")
+	}
+	if _, err := os.Stat(filepath.Join(binDir, "tool")); err == nil {
+		t.Fatal("expected nothing to be installed over the quota")
+	}
+
+	// without --enforce-quota, going over it is only reported
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src})
+	items, total, err := managedUsage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) == 0 || items[0].Path != filepath.Join(binDir, "tool") || items[0].Size != 2048 {
+		t.Fatalf("expected the installed binary to be the largest item, got %+v", items)
+	}
+
+	var out bytes.Buffer
+	printUsage(&out, items[:1], total, 1024)
+	if !strings.Contains(out.String(), "2.0 KiB  binary") || !strings.Contains(out.String(), "of a 1.0 KiB quota") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
@@ -2,19 +2,64 @@ package cmd
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"sort"
 
-	"github.com/caarlos0/log"
-	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/assets"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/prompt"
 	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/stats"
 	"github.com/spf13/cobra"
 )
 
 type ensureCmd struct {
-	cmd *cobra.Command
+	cmd  *cobra.Command
+	opts ensureOpts
+}
+
+type ensureOpts struct {
+	concurrency int
+	failFast    bool
+	// strategy resolves the divergences without asking
+	strategy string
+	// locked installs the artifacts pinned in the lockfile
+	locked bool
+	json   bool
+	// exclude are the globs of the binaries not to ensure
+	exclude []string
+	// relaxed retries the asset selection of every binary with
+	// relaxed requirements, like relaxed_fallback in their entry
+	relaxed bool
+	// check only verifies the binaries, remote
+	// compares them with the latest versions too
+	check  bool
+	remote bool
+	// yes creates the missing directories without asking
+	yes bool
+	// fromBundle installs the binaries from a bundle
+	// of bin download instead of their provider
+	fromBundle string
+}
+
+// ensureResult is what ensure did, or has to decide, about a binary
+type ensureResult struct {
+	// bin and file are the binary installed and the file it
+	// was installed from, bin is nil when nothing was done
+	bin  *config.Binary
+	file *providers.File
+	// conflict is set when the binary diverges from the
+	// configuration, it's applied once resolved
+	conflict   *conflict
+	resolution resolution
+	// relaxed is the relaxation which found the asset
+	// of the installed binary, if one was needed
+	relaxed assets.Relaxation
 }
 
 func newEnsureCmd() *ensureCmd {
@@ -22,87 +67,418 @@ func newEnsureCmd() *ensureCmd {
 	// nolint: dupl
 	cmd := &cobra.Command{
 		Use:           "ensure [binary_path]...",
+		Annotations:   map[string]string{writesConfig: "true"},
 		Aliases:       []string{"e"},
 		Short:         "Ensures that all binaries listed in the configuration are present",
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cfg := config.Get()
-			binsToProcess := map[string]*config.Binary{}
-
-			// Update specific binaries
-			if len(args) > 0 {
-				for _, a := range args {
-					bin, err := getBinPath(a)
-					if err != nil {
-						return err
-					}
-					binsToProcess[bin] = cfg.Bins[bin]
+			if root.opts.remote && !root.opts.check {
+				return fmt.Errorf("--remote can only be used with --check")
+			}
+			if root.opts.check && (root.opts.locked || root.opts.strategy != "" || root.opts.relaxed || root.opts.fromBundle != "") {
+				return fmt.Errorf("--check can't be used with --locked, --strategy, --relaxed or --from-bundle")
+			}
+
+			var binsToProcess map[string]*config.Binary
+			var locked map[*config.Binary]*config.LockedBinary
+			if root.opts.locked {
+				config.FreezeLock(true)
+				defer config.FreezeLock(false)
+				var err error
+				if binsToProcess, locked, err = lockedBinaries(args); err != nil {
+					return err
+				}
+				if err := excludeBins(binsToProcess, root.opts.exclude); err != nil {
+					return err
 				}
 			} else {
-				binsToProcess = cfg.Bins
+				var err error
+				if binsToProcess, err = selectBins(args, root.opts.exclude); err != nil {
+					return err
+				}
 			}
 
-			// TODO: code smell here, this pretty much does
-			// the same thing as install logic. Refactor to
-			// use the same code in both places
-			for _, binCfg := range binsToProcess {
-				ep := os.ExpandEnv(binCfg.Path)
-				_, err := os.Stat(ep)
+			if err := checkConcurrency(root.opts.concurrency); err != nil {
+				return err
+			}
+			strategy, err := parseResolution(root.opts.strategy)
+			if err != nil {
+				return err
+			}
 
-				if err == nil {
-					f, err := os.Open(ep)
-					if err != nil {
-						return err
-					}
+			bins := make([]*config.Binary, 0, len(binsToProcess))
+			for _, b := range binsToProcess {
+				bins = append(bins, b)
+			}
+			sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
 
-					h := sha256.New()
-					if _, err := io.Copy(h, f); err != nil {
-						return err
-					}
+			if root.opts.fromBundle != "" {
+				if err := openBundle(root.opts.fromBundle); err != nil {
+					return err
+				}
+				defer closeBundle()
+				if err := checkBundled(missingBins(bins, locked)); err != nil {
+					return err
+				}
+			}
 
-					if fmt.Sprintf("%x", h.Sum(nil)) == binCfg.Hash {
-						continue
+			if root.opts.check {
+				items, err := verifyBins(bins, root.opts.concurrency, root.opts.remote)
+				if root.opts.json {
+					if jerr := writeJSON(os.Stdout, newVerifyOutput(items)); jerr != nil {
+						return jerr
 					}
+				} else {
+					printVerified(os.Stdout, items)
+				}
+				return err
+			}
 
-					log.Infof("%s hash does not match with config's, re-installing", ep)
+			if err := ensureDirs(bins, root.opts.yes); err != nil {
+				return err
+			}
 
-				} else if !os.IsNotExist(err) {
-					continue
+			// binaries are inspected and fetched concurrently, what's
+			// done is reported at the end in a stable order
+			cache := assets.NewDownloadCache()
+			defer cache.Close()
+			results := make([]*ensureResult, len(bins))
+			errs := forEach(len(bins), root.opts.concurrency, root.opts.failFast, func(i int) error {
+				var err error
+				if locked != nil {
+					results[i], err = ensureLockedBinary(bins[i], locked[bins[i]], cache)
+				} else {
+					results[i], err = ensureBinary(bins[i], cache, root.opts.relaxed || bins[i].RelaxedFallback)
 				}
+				return err
+			})
 
-				p, err := providers.New(binCfg.URL, binCfg.Provider, binCfg.VersionURL)
-				if err != nil {
-					return err
+			// the divergences are resolved one at a time, then
+			// the resolutions are applied concurrently
+			var conflicts []int
+			for i, r := range results {
+				if errs[i] != nil || r == nil || r.conflict == nil {
+					continue
 				}
-				log.Debugf("Using provider '%s' for '%s'", p.GetID(), binCfg.URL)
-
-				pResult, err := p.Fetch(&providers.FetchOpts{Version: binCfg.Version})
-				if err != nil {
-					return err
+				if root.opts.failFast && hasFailure(errs) {
+					errs[i] = errSkipped
+					continue
 				}
-
-				hash, err := saveToDisk(pResult, ep, true)
-				if err != nil {
-					return fmt.Errorf("error installing binary: %w", err)
+				if r.resolution, errs[i] = r.conflict.resolve(strategy); errs[i] == nil {
+					conflicts = append(conflicts, i)
 				}
+			}
+			resolved := forEach(len(conflicts), root.opts.concurrency, root.opts.failFast, func(i int) error {
+				return results[conflicts[i]].apply(cache)
+			})
+			for i, err := range resolved {
+				errs[conflicts[i]] = err
+			}
 
-				err = config.UpsertBinary(&config.Binary{
-					RemoteName: pResult.Name,
-					Path:       binCfg.Path,
-					Version:    pResult.Version,
-					Hash:       fmt.Sprintf("%x", hash),
-					URL:        binCfg.URL,
-				})
-				if err != nil {
+			failures := map[*config.Binary]error{}
+			report := newActionReport()
+			var relaxedBins []*ensureResult
+			for i, b := range bins {
+				if errs[i] != nil {
+					failures[b] = errs[i]
+					continue
+				}
+				r := results[i]
+				if r == nil {
+					report.record(b, actionPresent, b.Version, "")
+					continue
+				}
+				if r.bin != nil {
+					e := withDownload(binLog(r.bin), r.file)
+					if r.relaxed != assets.RelaxNone {
+						e = e.WithField("relaxed", r.relaxed.String())
+					}
+					e.Info("Done ensuring")
+					warnHintBypassed(r.bin)
+					item := report.record(b, actionInstalled, "", r.bin.Version)
+					if r.relaxed != assets.RelaxNone {
+						item.Relaxed = r.relaxed.String()
+						relaxedBins = append(relaxedBins, r)
+					}
+				}
+			}
+			for _, i := range conflicts {
+				if r := results[i]; errs[i] == nil {
+					binLog(r.conflict.b).WithField("resolution", string(r.resolution)).Info("Resolved")
+					report.record(r.conflict.b, actionResolved, r.conflict.b.Version, r.version()).Resolution = string(r.resolution)
+				}
+			}
+			for _, r := range relaxedBins {
+				binLog(r.bin).WithField("asset", r.file.Asset).WithField("relaxed", r.relaxed.String()).Warn("No asset matched strictly, adjust the asset hints to match the installed one")
+			}
+			report.fail(failures)
+			if root.opts.json {
+				if err := writeJSON(os.Stdout, report.output()); err != nil {
 					return err
 				}
-				log.Infof("Done ensuring %s to %s", os.ExpandEnv(binCfg.Path), color.GreenString(binCfg.Version))
+			}
+			if n := warnFailures(failures); n > 0 {
+				return failuresError("ensure", n)
 			}
 			return nil
 		},
 	}
 
 	root.cmd = cmd
+	root.cmd.Flags().IntVar(&root.opts.concurrency, "concurrency", defaultConcurrency, "Number of binaries fetched at once")
+	root.cmd.Flags().BoolVar(&root.opts.failFast, "fail-fast", false, "Stop at the first binary failing to be ensured")
+	root.cmd.Flags().StringVar(&root.opts.strategy, "strategy", "", "Resolve the binaries diverging from the configuration without asking: keep-local, restore-pinned or upgrade-latest")
+	root.cmd.Flags().BoolVar(&root.opts.locked, "locked", false, "Install exactly the artifacts pinned in the lockfile, failing if any of them changed upstream")
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print a summary of what was done to each binary as JSON, see `bin schema ensure`")
+	root.cmd.Flags().StringSliceVar(&root.opts.exclude, "exclude", nil, "Don't ensure the binaries whose name matches one of these globs (i.e. 'kube*')")
+	root.cmd.Flags().BoolVar(&root.opts.check, "check", false, "Only verify that the binaries are present, executable and match the configuration, failing otherwise. Nothing is fetched unless --remote is set")
+	root.cmd.Flags().BoolVar(&root.opts.remote, "remote", false, "With --check, also fail when a binary which isn't pinned is behind its latest version upstream")
+	root.cmd.Flags().BoolVarP(&root.opts.yes, "yes", "y", false, "Create the missing directories of the binaries without asking")
+	root.cmd.Flags().StringVar(&root.opts.fromBundle, "from-bundle", "", "Install the binaries from a bundle written by `bin download`, without network access")
+	root.cmd.Flags().BoolVar(&root.opts.relaxed, "relaxed", false, "When no asset of a binary matches, retry with relaxed requirements (libc of the asset hint, emulated arch, raw binaries), like relaxed_fallback in its entry")
 	return root
 }
+
+// ensureBinary re-installs the binary when it's missing. When it was
+// modified, or its version isn't available anymore, the conflict is
+// returned to be resolved. It returns nil when it's present.
+func ensureBinary(binCfg *config.Binary, cache *assets.DownloadCache, relaxed bool) (*ensureResult, error) {
+	ep := os.ExpandEnv(binCfg.Path)
+	_, err := os.Stat(ep)
+
+	if err == nil {
+		f, err := os.Open(stats.Resolve(ep))
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		h := sha256.New()
+		if _, err := io.Copy(h, f); err != nil {
+			return nil, err
+		}
+
+		hash := fmt.Sprintf("%x", h.Sum(nil))
+		if hash == binCfg.Hash {
+			return nil, nil
+		}
+
+		binLog(binCfg).Info("The hash doesn't match the configuration")
+		c := &conflict{b: binCfg, modified: true, localHash: hash, localVersion: detectVersion(binCfg)}
+		c.latest, c.latestURL = latestVersion(binCfg)
+		return &ensureResult{conflict: c}, nil
+
+	} else if !os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	nb, file, err := installPinned(binCfg, nil, cache)
+	if err != nil && relaxed && errors.Is(err, assets.ErrNoMatch) {
+		return installRelaxed(binCfg, cache, err)
+	}
+	if err != nil {
+		if unavailable(binCfg) {
+			c := &conflict{b: binCfg, unavailable: true}
+			c.latest, c.latestURL = latestVersion(binCfg)
+			return &ensureResult{conflict: c}, nil
+		}
+		return nil, err
+	}
+	return &ensureResult{bin: nb, file: file}, nil
+}
+
+// ensureDirs creates the missing directories the missing binaries
+// are installed in, once confirmed, and warns about the ones which
+// aren't in PATH
+func ensureDirs(bins []*config.Binary, yes bool) error {
+	missing, outside := []string{}, []string{}
+	seen := map[string]bool{}
+	for _, b := range bins {
+		p := os.ExpandEnv(b.Path)
+		dir := filepath.Dir(p)
+		if _, err := os.Lstat(p); err == nil || seen[dir] {
+			continue
+		}
+		seen[dir] = true
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			missing = append(missing, dir)
+		}
+		if !b.IsFile() && !dirInPath(dir) {
+			outside = append(outside, dir)
+		}
+	}
+	sort.Strings(missing)
+	sort.Strings(outside)
+
+	if len(missing) > 0 {
+		fmt.Printf("\nThe following directories are missing:\n")
+		for _, d := range missing {
+			fmt.Printf("  %s\n", d)
+		}
+		if !yes {
+			if err := prompt.Confirm("Create them?"); err != nil {
+				return err
+			}
+		}
+		for _, d := range missing {
+			if err := os.MkdirAll(d, 0o755); err != nil {
+				return err
+			}
+		}
+	}
+	for _, d := range outside {
+		log.WithField("path", d).Warn("The directory isn't in PATH, the binaries installed in it can't be run by their name (see `bin doctor --fix-path`)")
+	}
+	return nil
+}
+
+// installRelaxed installs the binary whose strict asset selection
+// failed with err, retrying with progressively relaxed requirements
+func installRelaxed(binCfg *config.Binary, cache *assets.DownloadCache, err error) (*ensureResult, error) {
+	ep := os.ExpandEnv(binCfg.Path)
+	for _, r := range assets.Relaxations {
+		log.Debugf("No asset of %s matches, retrying relaxing %s", ep, r)
+		nb, file, rerr := installPinnedWith(binCfg, nil, cache, r)
+		if rerr == nil {
+			return &ensureResult{bin: nb, file: file, relaxed: r}, nil
+		}
+		if !errors.Is(rerr, assets.ErrNoMatch) {
+			return nil, rerr
+		}
+	}
+	return nil, err
+}
+
+// apply applies the resolution of the conflict
+func (r *ensureResult) apply(cache *assets.DownloadCache) error {
+	var err error
+	b := r.conflict.b
+	switch r.resolution {
+	case resolveKeepLocal:
+		nb := *b
+		nb.Hash = r.conflict.localHash
+		if r.conflict.localVersion != "" {
+			nb.Version = r.conflict.localVersion
+		}
+		// the file doesn't come from the recorded asset anymore
+		nb.AssetDigest = ""
+		nb.Pinned = true
+		if err := upsertBinary(&nb); err != nil {
+			return err
+		}
+		binLog(b).WithField("version", nb.Version).Info("Pinned, unpin it to get updates again")
+	case resolveRestore:
+		r.bin, r.file, err = installPinned(b, nil, cache)
+	case resolveUpgrade:
+		r.bin, r.file, err = updateBinary(b, &updateInfo{version: r.conflict.latest, url: r.conflict.latestURL}, updateOpts{}, cache)
+	}
+	return err
+}
+
+// version returns the version of the binary once its conflict is resolved
+func (r *ensureResult) version() string {
+	switch {
+	case r.bin != nil:
+		return r.bin.Version
+	case r.resolution == resolveKeepLocal && r.conflict.localVersion != "":
+		return r.conflict.localVersion
+	}
+	return r.conflict.b.Version
+}
+
+// latestVersion returns the latest version of the binary and its
+// URL, empty when it can't be checked
+func latestVersion(b *config.Binary) (string, string) {
+	p, err := newProvider(b)
+	if err != nil {
+		return "", ""
+	}
+	v, u, err := p.GetLatestVersion()
+	if err != nil {
+		log.Debugf("Error checking the latest version of %s: %v", b.Path, err)
+		return "", ""
+	}
+	return v, u
+}
+
+// unavailable reports whether the recorded version of the binary
+// isn't published anymore (i.e. the release was yanked)
+func unavailable(b *config.Binary) bool {
+	p, err := newProvider(b)
+	if err != nil || !p.Capabilities().Has(providers.CapListVersions) {
+		return false
+	}
+	releases, err := p.ListVersions(0)
+	if err != nil || len(releases) == 0 {
+		return false
+	}
+	for _, r := range releases {
+		if r.Version == b.Version {
+			return false
+		}
+	}
+	return true
+}
+
+// installPinned installs the recorded version of the binary, or
+// its locked artifact when lb is set, which it must match exactly.
+//
+// TODO: code smell here, this pretty much does
+// the same thing as install logic. Refactor to
+// use the same code in both places
+func installPinned(binCfg *config.Binary, lb *config.LockedBinary, cache *assets.DownloadCache) (*config.Binary, *providers.File, error) {
+	return installPinnedWith(binCfg, lb, cache, assets.RelaxNone)
+}
+
+// installPinnedWith is installPinned with the asset selection relaxed
+func installPinnedWith(binCfg *config.Binary, lb *config.LockedBinary, cache *assets.DownloadCache, relax assets.Relaxation) (*config.Binary, *providers.File, error) {
+	ep := os.ExpandEnv(binCfg.Path)
+	if lb != nil {
+		binCfg = lockedBinary(binCfg, lb)
+	}
+	p, err := newProvider(binCfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("Using provider '%s' for '%s'", p.GetID(), binCfg.URL)
+	pResult, err := p.Fetch(&providers.FetchOpts{Version: binCfg.Version, PackagePath: binCfg.PackagePath, Cache: cache, Formats: binCfg.PreferFormat, SelectedAsset: selectedAsset(binCfg, lb), RequireChecksum: config.Get().RequireChecksum, Files: binCfg.IsFile(), Completions: binCfg.InstallCompletions, Manpages: binCfg.InstallManpages, Mirrors: binCfg.Mirrors, MirrorFirst: binCfg.MirrorFirst, SigningKeys: config.SigningKeys(binCfg), RequireSignature: config.Get().RequireSignature, Relax: relax, AssetRewrites: binCfg.AssetRewrites, BuildFromSource: binCfg.BuildFromSource, MainPackage: binCfg.MainPackage})
+	if err != nil {
+		return nil, nil, fmt.Errorf("error fetching %s: %w", ep, err)
+	}
+	if err := checkLocked(ep, lb, pResult); err != nil {
+		return nil, nil, err
+	}
+
+	hash, err := saveToDisk(pResult, ep, binCfg, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error installing binary: %w", err)
+	}
+
+	nb := *binCfg
+	nb.RemoteName = pResult.Name
+	nb.Version = pResult.Version
+	nb.Hash = fmt.Sprintf("%x", hash)
+	nb.AssetDigest = pResult.AssetDigest
+	nb.VerifiedWith = pResult.VerifiedWith
+	nb.SignedBy, nb.SignedFile = pResult.SignedBy, pResult.SignedFile
+	nb.InstalledAsset, nb.AssetSHA256 = pResult.Asset, pResult.AssetSHA256
+	nb.AssetURL, nb.InstalledAt = pResult.AssetURL, installedNow()
+	nb.Emulated = pResult.Emulated
+	nb.AssetProfile = recordAsset(nb.AssetProfile, pResult)
+	nb.AssetHintBypassed = pResult.AssetHintBypassed
+	if pResult.SelectedAsset != "" {
+		nb.SelectedAsset = pResult.SelectedAsset
+	}
+	nb.Source = pResult.Source
+	if err := installExtras(&nb, pResult.Extras); err != nil {
+		return nil, nil, err
+	}
+	if err := upsertBinary(&nb); err != nil {
+		return nil, nil, err
+	}
+	if err := runPostInstall(&nb); err != nil {
+		return nil, nil, err
+	}
+	return &nb, pResult, nil
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// defaultPathExt is the value of PATHEXT when it isn't set
+const defaultPathExt = ".COM;.EXE;.BAT;.CMD"
+
+// executablePath returns the path binaries are installed at. Windows
+// only runs the files with an extension of PATHEXT, the others get
+// the .exe suffix
+func executablePath(p string) string {
+	if runtime.GOOS != "windows" {
+		return p
+	}
+	pathExt := os.Getenv("PATHEXT")
+	if pathExt == "" {
+		pathExt = defaultPathExt
+	}
+	return withExeSuffix(p, pathExt)
+}
+
+// withExeSuffix appends .exe to the path unless its
+// extension is one of the ; separated extensions
+func withExeSuffix(p, pathExt string) string {
+	ext := filepath.Ext(p)
+	if ext != "" {
+		for _, e := range strings.Split(pathExt, ";") {
+			if strings.EqualFold(strings.TrimSpace(e), ext) {
+				return p
+			}
+		}
+	}
+	return p + ".exe"
+}
@@ -0,0 +1,24 @@
+package cmd
+
+import "testing"
+
+func TestWithExeSuffix(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{`C:\bin\tool`, `C:\bin\tool.exe`},
+		{`C:\bin\tool.exe`, `C:\bin\tool.exe`},
+		{`C:\bin\TOOL.EXE`, `C:\bin\TOOL.EXE`},
+		{`C:\bin\tool.cmd`, `C:\bin\tool.cmd`},
+		{`C:\bin\tool-1.2`, `C:\bin\tool-1.2.exe`},
+		{`C:\bin\tool.ps1`, `C:\bin\tool.ps1.exe`},
+	}
+	for _, c := range cases {
+		if got := withExeSuffix(c.in, defaultPathExt); got != c.out {
+			t.Errorf("%s: expected %s, got %s", c.in, c.out, got)
+		}
+	}
+	if got := withExeSuffix(`C:\bin\tool.ps1`, ".EXE; .PS1"); got != `C:\bin\tool.ps1` {
+		t.Errorf("expected the extensions of PATHEXT to be kept, got %s", got)
+	}
+}
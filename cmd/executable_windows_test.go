@@ -0,0 +1,17 @@
+package cmd
+
+import "testing"
+
+func TestExecutablePath(t *testing.T) {
+	t.Setenv("PATHEXT", "")
+	if got := executablePath(`C:\bin\tool`); got != `C:\bin\tool.exe` {
+		t.Errorf("expected the .exe suffix to be appended, got %s", got)
+	}
+	t.Setenv("PATHEXT", ".EXE;.PY")
+	if got := executablePath(`C:\bin\tool.py`); got != `C:\bin\tool.py` {
+		t.Errorf("expected the extensions of PATHEXT to be kept, got %s", got)
+	}
+	if got := executablePath(`C:\bin\tool.cmd`); got != `C:\bin\tool.cmd.exe` {
+		t.Errorf("expected the extensions missing from PATHEXT to get the suffix, got %s", got)
+	}
+}
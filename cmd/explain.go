@@ -0,0 +1,331 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/marcosnils/bin/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+type explainConfigCmd struct {
+	cmd  *cobra.Command
+	opts explainConfigOpts
+}
+
+type explainConfigOpts struct {
+	json bool
+}
+
+// effectiveSetting is the value of a setting used by the commands.
+// Key is the name of the environment variable or provider setting
+// it comes from, when it isn't the name of the setting itself.
+type effectiveSetting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Origin string `json:"origin"`
+	Key    string `json:"key,omitempty"`
+}
+
+// explainConfigOutput is the JSON output of `bin explain-config`, see pkg/schema/schemas/explain-config.json
+type explainConfigOutput struct {
+	SchemaVersion int                `json:"schema_version"`
+	Path          string             `json:"path,omitempty"`
+	Settings      []effectiveSetting `json:"settings"`
+}
+
+func newExplainConfigCmd() *explainConfigCmd {
+	root := &explainConfigCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "explain-config [binary]",
+		Short:         "Shows the effective settings of a binary, or the global ones, along with where each value comes from",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// the minimum TLS version of a binary accounts for the global one
+			out := explainConfigOutput{Settings: []effectiveSetting{minTLSSetting(nil)}}
+			if len(args) == 1 {
+				p, err := getBinPath(args[0])
+				if err != nil {
+					return err
+				}
+				b := config.Get().Bins[p]
+				bs, err := binarySettings(b)
+				if err != nil {
+					return err
+				}
+				out.Path = os.ExpandEnv(b.Path)
+				out.Settings = bs
+			}
+			out.Settings = append(out.Settings, globalSettings(cmd.Flags().Changed("read-only"))...)
+
+			if root.opts.json {
+				out.SchemaVersion = schema.Version
+				return writeJSON(os.Stdout, out)
+			}
+			printSettings(os.Stdout, out.Settings)
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print the settings as JSON, see `bin schema explain-config`")
+	return root
+}
+
+// globalSettings resolves the settings shared by every binary
+// with the same resolvers the commands use
+func globalSettings(readOnlyFlag bool) []effectiveSetting {
+	cfg := config.Get()
+	var s []effectiveSetting
+
+	p, _ := config.GetConfigPath()
+	s = append(s, effectiveSetting{Name: "config_file", Value: p, Origin: envOrDefault("BIN_CONFIG"), Key: envKey("BIN_CONFIG")})
+
+	ro := config.OriginDefault
+	switch {
+	case readOnlyFlag:
+		ro = config.OriginFlag
+	case config.ReadOnly():
+		ro = config.OriginDetected
+	}
+	s = append(s, effectiveSetting{Name: "read_only", Value: strconv.FormatBool(config.ReadOnly()), Origin: ro})
+	s = append(s, effectiveSetting{Name: "pure", Value: strconv.FormatBool(settings.Pure()), Origin: flagOrDefault(settings.Pure())})
+
+	s = append(s, configString("default_path", cfg.DefaultPath, ""))
+	s = append(s, configString("default_files_path", cfg.DefaultFilesPath, ""))
+	s = append(s, configString("default_forge", cfg.DefaultForge, "github"))
+
+	libc, origin := config.ResolveLibc()
+	if libc == "" {
+		libc = config.LibcAny
+	}
+	s = append(s, effectiveSetting{Name: "libc", Value: libc, Origin: origin})
+	arch, origin := config.ResolveArch()
+	s = append(s, effectiveSetting{Name: "arch", Value: arch, Origin: origin})
+	fallback, origin := "none", config.OriginDetected
+	if archs, enabled := config.GetFallbackArch(); len(archs) > 0 {
+		fallback, origin = archs[0], config.FallbackArchOrigin()
+		if !enabled {
+			fallback = "disabled"
+		}
+	}
+	s = append(s, effectiveSetting{Name: "fallback_arch", Value: fallback, Origin: origin})
+
+	s = append(s, configBool("require_checksum", cfg.RequireChecksum))
+	s = append(s, configBool("stats", cfg.Stats))
+	s = append(s, configBool("keep_appimage_extension", cfg.KeepAppImageExtension))
+	s = append(s, configBool("split_state", cfg.SplitState))
+	s = append(s, configBool("versioned_installs", cfg.VersionedInstalls))
+	keep := ""
+	if cfg.KeepVersions > 0 {
+		keep = strconv.Itoa(cfg.KeepVersions)
+	}
+	s = append(s, configString("keep_versions", keep, strconv.Itoa(store.DefaultKeep)))
+	s = append(s, configString("quota", cfg.Quota, ""))
+
+	for _, e := range []struct{ name, kind, configured string }{
+		{"bash_completions_path", assets.ExtraBash, cfg.BashCompletionsPath},
+		{"zsh_completions_path", assets.ExtraZsh, cfg.ZshCompletionsPath},
+		{"fish_completions_path", assets.ExtraFish, cfg.FishCompletionsPath},
+		{"man_path", assets.ExtraMan, cfg.ManPath},
+	} {
+		dir, _ := config.GetExtrasDir(e.kind, "")
+		if e.kind == assets.ExtraMan {
+			// the pages go to the directory of their section
+			dir = filepath.Dir(dir)
+		}
+		origin := config.OriginDefault
+		if e.configured != "" {
+			origin = config.OriginConfig
+		}
+		s = append(s, effectiveSetting{Name: e.name, Value: dir, Origin: origin})
+	}
+
+	for _, keys := range [][]string{{"HTTPS_PROXY", "https_proxy"}, {"HTTP_PROXY", "http_proxy"}, {"NO_PROXY", "no_proxy"}} {
+		v, k, origin := settings.LookupFirst(keys...)
+		s = append(s, effectiveSetting{Name: strings.ToLower(keys[0]), Value: redactProxy(v), Origin: origin, Key: k})
+	}
+	return s
+}
+
+// binarySettings resolves the settings of the binary
+// with the same resolvers the commands use
+func binarySettings(b *config.Binary) ([]effectiveSetting, error) {
+	u, err := platformURL(b, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return nil, err
+	}
+
+	s := []effectiveSetting{
+		{Name: "path", Value: os.ExpandEnv(b.Path), Origin: config.OriginEntry},
+		{Name: "url", Value: u, Origin: config.OriginEntry},
+		entryString("provider", b.Provider, ""),
+		entryString("kind", b.Kind, config.KindBinary),
+		entryString("version", b.Version, ""),
+		{Name: "pinned", Value: strconv.FormatBool(b.Pinned), Origin: entryOrDefault(b.Pinned)},
+		entryString("package_path", b.PackagePath, ""),
+		entryString("tag_prefix", b.TagPrefix, ""),
+		entryString("tag_pattern", b.TagPattern, ""),
+		entryString("asset", b.Asset, ""),
+		entryString("selected_asset", b.SelectedAsset, ""),
+	}
+
+	policy, origin := providers.ResolveAssetHintPolicy(b.AssetHintPolicy)
+	s = append(s, effectiveSetting{Name: "asset_hint_policy", Value: policy, Origin: origin})
+	formats, origin := assets.ResolveFormats(b.PreferFormat)
+	s = append(s, effectiveSetting{Name: "prefer_format", Value: strings.Join(formats, ", "), Origin: origin})
+	s = append(s, minTLSSetting(b))
+
+	headers := make([]string, 0, len(b.Headers))
+	for h := range b.Headers {
+		headers = append(headers, h)
+	}
+	sort.Strings(headers)
+	s = append(s, effectiveSetting{Name: "install_completions", Value: strconv.FormatBool(b.InstallCompletions), Origin: entryOrDefault(b.InstallCompletions)})
+	s = append(s, effectiveSetting{Name: "install_manpages", Value: strconv.FormatBool(b.InstallManpages), Origin: entryOrDefault(b.InstallManpages)})
+	s = append(s, entryString("headers", strings.Join(headers, ", "), ""))
+	s = append(s, entryString("basic_auth", b.BasicAuth, ""))
+	s = append(s, entryString("mirrors", strings.Join(b.Mirrors, ", "), ""))
+	s = append(s, entryString("signing_keys", strings.Join(b.SigningKeys, ", "), ""))
+	s = append(s, entryString("update_window", b.UpdateWindow, "always"))
+	s = append(s, verifySetting(b))
+	s = append(s, entryString("post_install", strings.Join(b.PostInstall, "; "), ""))
+	s = append(s, effectiveSetting{Name: "mirror_first", Value: strconv.FormatBool(b.MirrorFirst), Origin: entryOrDefault(b.MirrorFirst)})
+
+	host := ""
+	if pu, err := url.Parse(u); err == nil {
+		host = pu.Hostname()
+	}
+	if keys := settings.TokenKeys(b.Provider, host); len(keys) > 0 {
+		v, k, origin := settings.LookupFirst(keys...)
+		if v != "" {
+			v = "<redacted>"
+		}
+		s = append(s, effectiveSetting{Name: "token", Value: v, Origin: origin, Key: k})
+	}
+	return s, nil
+}
+
+// printSettings prints the settings as a table, the origin
+// of each value comes before it like `git config --show-origin`
+func printSettings(w io.Writer, s []effectiveSetting) {
+	nameWidth, originWidth := 0, 0
+	origins := make([]string, len(s))
+	for i, e := range s {
+		origins[i] = e.Origin
+		if e.Key != "" {
+			origins[i] += ":" + e.Key
+		}
+		nameWidth = max(nameWidth, len(e.Name))
+		originWidth = max(originWidth, len(origins[i]))
+	}
+	for i, e := range s {
+		fmt.Fprintf(w, "%s  %s  %s\n", _rPad(e.Name, nameWidth), _rPad(origins[i], originWidth), displayValue(e.Value))
+	}
+}
+
+func displayValue(v string) string {
+	if v == "" {
+		return "-"
+	}
+	return v
+}
+
+func minTLSSetting(b *config.Binary) effectiveSetting {
+	v, origin := config.ResolveMinTLSVersion(b)
+	if v == "" {
+		v = providers.DefaultMinTLSVersion
+	}
+	return effectiveSetting{Name: "min_tls_version", Value: v, Origin: origin}
+}
+
+func configString(name, v, def string) effectiveSetting {
+	if v == "" {
+		return effectiveSetting{Name: name, Value: def, Origin: config.OriginDefault}
+	}
+	return effectiveSetting{Name: name, Value: v, Origin: config.OriginConfig}
+}
+
+func configBool(name string, v bool) effectiveSetting {
+	origin := config.OriginDefault
+	if v {
+		origin = config.OriginConfig
+	}
+	return effectiveSetting{Name: name, Value: strconv.FormatBool(v), Origin: origin}
+}
+
+func entryString(name, v, def string) effectiveSetting {
+	if v == "" {
+		return effectiveSetting{Name: name, Value: def, Origin: config.OriginDefault}
+	}
+	return effectiveSetting{Name: name, Value: v, Origin: config.OriginEntry}
+}
+
+// verifySetting is the command the binary is run
+// with after being installed, if any
+func verifySetting(b *config.Binary) effectiveSetting {
+	args, ok := verifyArgs(b)
+	v := verifyArgsNone
+	if ok {
+		v = strings.Join(args, " ")
+	}
+	switch {
+	case b.VerifyArgs != "" || b.VerifyVersion:
+		return effectiveSetting{Name: "verify_args", Value: v, Origin: config.OriginEntry}
+	case config.Get().VerifyInstall:
+		return effectiveSetting{Name: "verify_args", Value: v, Origin: config.OriginConfig}
+	}
+	return effectiveSetting{Name: "verify_args", Value: v, Origin: config.OriginDefault}
+}
+
+func entryOrDefault(set bool) string {
+	if set {
+		return config.OriginEntry
+	}
+	return config.OriginDefault
+}
+
+func flagOrDefault(set bool) string {
+	if set {
+		return config.OriginFlag
+	}
+	return config.OriginDefault
+}
+
+func envOrDefault(key string) string {
+	if os.Getenv(key) != "" {
+		return config.OriginEnv
+	}
+	return config.OriginDefault
+}
+
+func envKey(key string) string {
+	if os.Getenv(key) != "" {
+		return key
+	}
+	return ""
+}
+
+// redactProxy hides the credentials of a proxy URL
+func redactProxy(v string) string {
+	u, err := url.Parse(v)
+	if err != nil || u.User == nil {
+		return v
+	}
+	u.User = url.User("redacted")
+	return u.String()
+}
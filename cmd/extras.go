@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// installExtras installs the shell completions and man pages
+// shipped along the binary, replacing the ones of the version
+// it was installed from, and records their paths
+func installExtras(b *config.Binary, extras []*assets.Extra) error {
+	if err := removeExtras(b); err != nil {
+		return err
+	}
+	b.Extras = nil
+	for _, e := range extras {
+		dir, err := config.GetExtrasDir(e.Kind, e.Section)
+		if err != nil {
+			return err
+		}
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+		p := filepath.Join(dir, e.Name)
+		if err := os.WriteFile(p, e.Data, 0o644); err != nil {
+			return fmt.Errorf("error installing %s: %w", p, err)
+		}
+		log.Debugf("Installed %s %s", e.Kind, p)
+		b.Extras = append(b.Extras, p)
+	}
+	return nil
+}
+
+// removeExtras removes the shell completions
+// and man pages installed along the binary
+func removeExtras(b *config.Binary) error {
+	for _, p := range b.Extras {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("error removing %s: %w", p, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+// hookTimeout is how long a post-install hook may run,
+// a var so the tests can shorten it
+var hookTimeout = 5 * time.Minute
+
+// runPostInstall runs the post_install hooks of the binary once it's
+// installed, in order. They're run without a shell, with the path, name
+// and version of the binary in BIN_PATH, BIN_NAME and BIN_VERSION which
+// the arguments can reference (i.e. `xattr -d com.apple.quarantine
+// $BIN_PATH`), the other variables are left to the commands. Their output
+// is logged as it comes and the first one failing fails the install.
+func runPostInstall(b *config.Binary) error {
+	if len(b.PostInstall) == 0 {
+		return nil
+	}
+	p := os.ExpandEnv(b.Path)
+	name := filepath.Base(p)
+	vars := strings.NewReplacer("${BIN_PATH}", p, "$BIN_PATH", p, "${BIN_NAME}", name, "$BIN_NAME", name, "${BIN_VERSION}", b.Version, "$BIN_VERSION", b.Version)
+	env := append(os.Environ(), "BIN_PATH="+p, "BIN_NAME="+name, "BIN_VERSION="+b.Version)
+	for _, h := range b.PostInstall {
+		args, err := providers.SplitCommand(h)
+		if err != nil {
+			return fmt.Errorf("invalid post-install hook %q of %s: %w", h, name, err)
+		}
+		for i := range args {
+			args[i] = vars.Replace(args[i])
+		}
+		log.WithField("binary", name).WithField("hook", h).Info("Running the post-install hook")
+		if err := runHook(name, args, env); err != nil {
+			return fmt.Errorf("post-install hook %q of %s failed: %w", h, name, err)
+		}
+	}
+	return nil
+}
+
+// skipPostInstall reports the hooks not run in dry-run mode
+func skipPostInstall(b *config.Binary) {
+	for _, h := range b.PostInstall {
+		binLog(b).WithField("hook", h).Info("Skipping the post-install hook in dry-run mode")
+	}
+}
+
+// runHook runs the command, logging its output line by line. It's
+// killed after hookTimeout, the processes it leaves in the background
+// holding its output (i.e. a daemon it starts) are left alone.
+func runHook(name string, args, env []string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), hookTimeout)
+	defer cancel()
+	pr, pw := io.Pipe()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Env = env
+	cmd.Stdout, cmd.Stderr = pw, pw
+	// the children of the command may keep its output open
+	cmd.WaitDelay = time.Second
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s := bufio.NewScanner(pr)
+		for s.Scan() {
+			log.WithField("binary", name).Info(s.Text())
+		}
+		// the rest of the output is discarded so the command never blocks
+		io.Copy(io.Discard, pr)
+	}()
+	err := cmd.Run()
+	pw.Close()
+	<-done
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("it didn't exit within %s", hookTimeout)
+	case errors.Is(err, exec.ErrWaitDelay):
+		log.WithField("binary", name).Debug("The hook left processes holding its output")
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestPostInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the hooks are shell commands")
+	}
+	dir, binDir := newTestConfig(t, "")
+
+	src := filepath.Join(dir, "tool-1.0.0")
+	writeScript(t, src, "tool 1.0.0")
+	out := filepath.Join(dir, "hook.out")
+	installed := filepath.Join(binDir, "tool")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "--post", "cp $BIN_PATH " + out + ".copy",
+		"--post", `sh -c 'echo "$BIN_NAME $BIN_VERSION $BIN_PATH" > ` + out + `'`, src, installed})
+
+	if b, _ := os.ReadFile(out); string(b) != "tool 1.0.0 "+installed+"\n" {
+		t.Errorf("unexpected environment of the hook: %q", b)
+	}
+	if _, err := os.Stat(out + ".copy"); err != nil {
+		t.Errorf("expected the first hook to run without a shell: %v", err)
+	}
+	if b := config.Get().Bins[installed]; len(b.PostInstall) != 2 {
+		t.Errorf("expected the hooks to be recorded, got %q", b.PostInstall)
+	}
+
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"install", "--force", "--post", "sh -c 'exit 3'", src, installed})
+	if code == 0 {
+		t.Error("expected a failing hook to fail the install")
+	}
+	Execute("test", func(c int) { code = c }, []string{"install", "--force", "--post", "sh -c 'exit", src, installed})
+	if code == 0 {
+		t.Error("expected an invalid hook to be refused")
+	}
+
+	// a process left in the background doesn't block the install
+	start := time.Now()
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "--force", "--post", "sh -c 'sleep 10 & echo started'", src, installed})
+	if d := time.Since(start); d > 5*time.Second {
+		t.Errorf("expected the install not to wait for the background process, took %s", d)
+	}
+
+	defer func(d time.Duration) { hookTimeout = d }(hookTimeout)
+	hookTimeout = 500 * time.Millisecond
+	code = 0
+	Execute("test", func(c int) { code = c }, []string{"install", "--force", "--post", "sleep 10", src, installed})
+	if code == 0 {
+		t.Error("expected a hanging hook to fail the install")
+	}
+}
@@ -0,0 +1,397 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/spf13/cobra"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/compat"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/prompt"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+type importCmd struct {
+	cmd  *cobra.Command
+	opts importOpts
+}
+
+type importOpts struct {
+	from         string
+	dryRun       bool
+	overwrite    bool
+	skipExisting bool
+}
+
+// importMapping is the source a tool of
+// a manifest is installed from, if any
+type importMapping struct {
+	tool *compat.Tool
+	url  string
+	err  error
+}
+
+func newImportCmd() *importCmd {
+	root := &importCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "import <file>",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Installs the binaries of a manifest of `bin export`, or the tools of an asdf, mise or aqua manifest",
+		Long:          "Merges the binaries of a manifest written by `bin export` into the configuration and installs the missing ones in the default path, resolving their assets for this machine. The binaries already installed from another source or version are conflicts, which are confirmed one by one unless --overwrite or --skip-existing is set.\n\nIt also installs the tools of an asdf .tool-versions, a mise configuration or an aqua.yaml, pinned at the versions they specify. The tools are mapped to their source through a registry of known tools, the ones which can't be mapped are reported for manual follow-up.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := os.ReadFile(args[0])
+			if err != nil {
+				return err
+			}
+			format := root.opts.from
+			if format == manifestFormat || (format == "" && config.IsManifest(data)) {
+				return root.importManifest(data)
+			}
+			if root.opts.overwrite || root.opts.skipExisting {
+				return fmt.Errorf("--overwrite and --skip-existing only apply to the manifests of `bin export`")
+			}
+			if format == "" {
+				if format, err = compat.DetectFormat(args[0]); err != nil {
+					return err
+				}
+			}
+			tools, err := compat.Parse(format, bytes.NewReader(data))
+			if err != nil {
+				return err
+			}
+
+			mappings := make([]*importMapping, 0, len(tools))
+			for _, t := range tools {
+				u, err := compat.Resolve(t)
+				mappings = append(mappings, &importMapping{tool: t, url: u, err: err})
+			}
+			printMappings(os.Stdout, mappings)
+			if root.opts.dryRun {
+				return nil
+			}
+
+			var failed []string
+			for _, m := range mappings {
+				if m.err != nil {
+					failed = append(failed, fmt.Sprintf("%s: %v", m.tool.Name, m.err))
+					continue
+				}
+				if err := importTool(m); err != nil {
+					log.WithField("tool", m.tool.Name).WithError(err).Error("Error importing")
+					failed = append(failed, fmt.Sprintf("%s: %v", m.tool.Name, err))
+				}
+			}
+
+			if len(failed) > 0 {
+				log.WithField("tools", failed).Warn("Some tools need to be installed manually")
+			}
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().StringVar(&root.opts.from, "from", "", "Format of the manifest: bin, asdf, mise or aqua (detected by default)")
+	root.cmd.Flags().BoolVar(&root.opts.dryRun, "dry-run", false, "Only show what would be installed")
+	root.cmd.Flags().BoolVar(&root.opts.overwrite, "overwrite", false, "Replace the binaries installed from another source or version without asking")
+	root.cmd.Flags().BoolVar(&root.opts.skipExisting, "skip-existing", false, "Keep the binaries installed from another source or version without asking")
+	root.cmd.MarkFlagsMutuallyExclusive("overwrite", "skip-existing")
+	return root
+}
+
+// printMappings shows the source of each tool of the manifest
+func printMappings(w io.Writer, mappings []*importMapping) {
+	nL, vL := len("Tool"), len("Version")
+	for _, m := range mappings {
+		nL = max(nL, len(m.tool.Name))
+		vL = max(vL, len(importVersion(m.tool)))
+	}
+	magentaItalic := color.New(color.FgMagenta, color.Italic).Sprint
+	fmt.Fprintf(w, "%s  %s  %s\n", magentaItalic(_rPad("Tool", nL)), magentaItalic(_rPad("Version", vL)), magentaItalic("Source"))
+	for _, m := range mappings {
+		source := m.url
+		if m.err != nil {
+			source = color.YellowString("unmapped (%v)", m.err)
+		}
+		fmt.Fprintf(w, "%s  %s  %s\n", _rPad(m.tool.Name, nL), _rPad(importVersion(m.tool), vL), source)
+	}
+}
+
+func importVersion(t *compat.Tool) string {
+	if t.Version == "" {
+		return "latest"
+	}
+	return t.Version
+}
+
+// importTool installs a mapped tool into the default
+// path, pinned when the manifest has its version
+func importTool(m *importMapping) error {
+	u, err := providers.NormalizeURL(m.url, "", settings)
+	if err != nil {
+		return err
+	}
+	for _, b := range config.Get().Bins {
+		if b.URL == u {
+			binLog(b).WithField("tool", m.tool.Name).Info("Already installed")
+			return nil
+		}
+	}
+
+	b := &config.Binary{URL: u, Pinned: m.tool.Version != ""}
+	p, err := newProvider(b)
+	if err != nil {
+		return err
+	}
+	pResult, err := p.Fetch(&providers.FetchOpts{RequireChecksum: config.Get().RequireChecksum, AssetRewrites: b.AssetRewrites, BuildFromSource: b.BuildFromSource, MainPackage: b.MainPackage})
+	if err != nil {
+		return err
+	}
+
+	path, err := checkFinalPath(config.Get().DefaultPath, binaryName(pResult))
+	if err != nil {
+		return err
+	}
+	if !b.IsFile() {
+		path = executablePath(path)
+	}
+	hash, err := saveToDisk(pResult, path, b, false)
+	if err != nil {
+		return fmt.Errorf("error installing binary: %w", err)
+	}
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("error converting to absolute path: %w", err)
+	}
+
+	b.RemoteName = pResult.Name
+	b.Path = absPath
+	b.Version = pResult.Version
+	b.Hash = fmt.Sprintf("%x", hash)
+	b.Provider = p.GetID()
+	b.PackagePath = pResult.PackagePath
+	b.AssetDigest = pResult.AssetDigest
+	b.VerifiedWith = pResult.VerifiedWith
+	b.SignedBy, b.SignedFile = pResult.SignedBy, pResult.SignedFile
+	b.InstalledAsset, b.AssetSHA256 = pResult.Asset, pResult.AssetSHA256
+	b.AssetURL, b.InstalledAt = pResult.AssetURL, installedNow()
+	b.Emulated = pResult.Emulated
+	b.AssetProfile = recordAsset(b.AssetProfile, pResult)
+	b.AssetHintBypassed = pResult.AssetHintBypassed
+	b.SelectedAsset = pResult.SelectedAsset
+	b.Source = pResult.Source
+	if err := config.UpsertBinary(b); err != nil {
+		return err
+	}
+	withDownload(binLog(b), pResult).WithField("tool", m.tool.Name).Info("Done importing")
+	return nil
+}
+
+// manifestFormat is the format of the manifests of `bin export`
+const manifestFormat = "bin"
+
+// manifestEntry is a binary of a manifest along with what's
+// already installed at its path on this machine
+type manifestEntry struct {
+	b *config.Binary
+	// existing is the binary configured at the path, if any
+	existing *config.Binary
+	// unmanaged is set when a file bin doesn't manage is at the path
+	unmanaged bool
+	err       error
+}
+
+// importManifest merges the binaries of a manifest of `bin export` into
+// the configuration and installs the ones missing on this machine
+func (r *importCmd) importManifest(data []byte) error {
+	m, err := config.ReadManifest(data)
+	if err != nil {
+		return err
+	}
+	entries := make([]*manifestEntry, 0, len(m.Bins))
+	for _, mb := range m.Bins {
+		entries = append(entries, newManifestEntry(mb))
+	}
+	if r.opts.dryRun {
+		printManifestEntries(os.Stdout, entries)
+		return nil
+	}
+
+	// the conflicts are confirmed one at a time, then
+	// the binaries are installed concurrently
+	var install []*config.Binary
+	failures := map[*config.Binary]error{}
+	for _, e := range entries {
+		ep := os.ExpandEnv(e.b.Path)
+		switch {
+		case e.err != nil:
+			failures[e.b] = e.err
+		case e.existing == nil && !e.unmanaged:
+			install = append(install, e.b)
+		case !e.conflicts():
+			if _, err := os.Stat(ep); os.IsNotExist(err) {
+				install = append(install, e.existing)
+			} else {
+				binLog(e.b).Info("Already installed")
+			}
+		default:
+			replace, err := r.replace(e)
+			if err != nil {
+				failures[e.b] = err
+				continue
+			}
+			if !replace {
+				binLog(e.b).Info("Keeping as installed")
+				continue
+			}
+			install = append(install, e.replacement())
+		}
+	}
+
+	cache := assets.NewDownloadCache()
+	defer cache.Close()
+	errs := forEach(len(install), defaultConcurrency, false, func(i int) error {
+		nb, file, err := installPinned(install[i], nil, cache)
+		if err != nil {
+			return err
+		}
+		withDownload(binLog(nb), file).Info("Done importing")
+		return nil
+	})
+	for i, err := range errs {
+		if err != nil {
+			failures[install[i]] = err
+		}
+	}
+	if n := warnFailures(failures); n > 0 {
+		return failuresError("import", n)
+	}
+	return nil
+}
+
+// newManifestEntry looks up what's installed at
+// the path of the binary of the manifest
+func newManifestEntry(mb *config.ManifestBinary) *manifestEntry {
+	cfg := config.Get()
+	dir := cfg.DefaultPath
+	if mb.Kind == config.KindFile {
+		dir = cfg.DefaultFilesPath
+	}
+	target, targetOK := config.PathDir(mb.Target)
+	if targetOK {
+		dir = target
+	}
+	e := &manifestEntry{b: mb.Binary(dir)}
+	if mb.Target != "" && !targetOK {
+		e.err = fmt.Errorf("%s is installed in the path %s, add it to the paths of the configuration to import it", mb.Name, mb.Target)
+		return e
+	}
+	if !e.b.IsFile() {
+		// manifests exported on other platforms
+		// have names without the .exe suffix
+		e.b.Path = executablePath(e.b.Path)
+	}
+	if dir == "" {
+		e.err = fmt.Errorf("%s is a file, set default_files_path in the configuration to import it", mb.Name)
+		return e
+	}
+	ep := os.ExpandEnv(e.b.Path)
+	for _, b := range cfg.Bins {
+		if os.ExpandEnv(b.Path) == ep {
+			e.existing = b
+			return e
+		}
+	}
+	if _, err := os.Lstat(ep); err == nil {
+		e.unmanaged = true
+	}
+	return e
+}
+
+// conflicts reports whether the binary installed at the
+// path doesn't come from the source of the manifest
+func (e *manifestEntry) conflicts() bool {
+	if e.unmanaged {
+		return true
+	}
+	x, b := e.existing, e.b
+	return x != nil && (x.URL != b.URL || x.Pinned != b.Pinned || (b.Pinned && x.Version != b.Version) ||
+		x.Asset != b.Asset || x.TagPrefix != b.TagPrefix || x.TagPattern != b.TagPattern ||
+		!slices.Equal(x.PreferFormat, b.PreferFormat) || x.IsFile() != b.IsFile())
+}
+
+// replacement returns the binary replacing the installed one, the
+// configured fields the manifest doesn't hold are kept
+func (e *manifestEntry) replacement() *config.Binary {
+	if e.existing == nil {
+		return e.b
+	}
+	nb := *e.existing
+	nb.URL, nb.Provider, nb.Pinned, nb.Version = e.b.URL, e.b.Provider, e.b.Pinned, e.b.Version
+	nb.Asset, nb.TagPrefix, nb.TagPattern, nb.PreferFormat, nb.Kind, nb.Target = e.b.Asset, e.b.TagPrefix, e.b.TagPattern, e.b.PreferFormat, e.b.Kind, e.b.Target
+	// they're only meaningful to the previous source
+	nb.SelectedAsset, nb.PackagePath = "", ""
+	return &nb
+}
+
+// installed describes what's installed at the path of the entry
+func (e *manifestEntry) installed() string {
+	if e.unmanaged {
+		return "a file not managed by bin"
+	}
+	return fmt.Sprintf("%s %s", e.existing.URL, e.existing.Version)
+}
+
+// replace tells whether the conflicting binary is replaced
+// by the one of the manifest, asking when no flag is set
+func (r *importCmd) replace(e *manifestEntry) (bool, error) {
+	ep := os.ExpandEnv(e.b.Path)
+	switch {
+	case r.opts.overwrite:
+		return true, nil
+	case r.opts.skipExisting:
+		return false, nil
+	case !prompt.IsInteractive():
+		return false, fmt.Errorf("%s is %s, pass --overwrite or --skip-existing", ep, e.installed())
+	}
+	return prompt.Confirm(fmt.Sprintf("%s is %s, replace it with %s %s?", ep, e.installed(), e.b.URL, manifestVersion(e.b))) == nil, nil
+}
+
+// printManifestEntries shows what importing the manifest does
+func printManifestEntries(w io.Writer, entries []*manifestEntry) {
+	header := []tableColumn{{header: "Path", truncate: true}, {header: "Version"}, {header: "URL", truncate: true}, {header: "Action"}}
+	rows := make([][]tableCell, 0, len(entries))
+	for _, e := range entries {
+		action := tableCell{text: "install"}
+		switch {
+		case e.err != nil:
+			action = tableCell{text: e.err.Error(), color: color.New(color.FgRed).Sprint}
+		case e.conflicts():
+			action = tableCell{text: "conflicts with " + e.installed(), color: color.New(color.FgYellow).Sprint}
+		case e.existing != nil:
+			if _, err := os.Stat(os.ExpandEnv(e.b.Path)); err == nil {
+				action = tableCell{text: "already installed"}
+			}
+		}
+		rows = append(rows, []tableCell{{text: os.ExpandEnv(e.b.Path)}, {text: manifestVersion(e.b)}, {text: e.b.URL}, action})
+	}
+	printTable(w, header, rows, terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+}
+
+func manifestVersion(b *config.Binary) string {
+	if !b.Pinned {
+		return config.LatestVersion
+	}
+	return b.Version
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestImportManifest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handler of the test is a shell script")
+	}
+	dir := t.TempDir()
+	for _, v := range []string{"0.9.0", "1.0.0"} {
+		writeScript(t, filepath.Join(dir, "synctool-"+v), v)
+	}
+	handlers := fmt.Sprintf(`{"corp://": {"latest": "echo 1.0.0", "download": "cp %s/synctool-$BIN_VERSION $BIN_OUTPUT_DIR/synctool"}}`, dir)
+
+	// the binary is installed and exported on a first machine
+	newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "corp://team/synctool"})
+	manifest := filepath.Join(dir, "manifest.json")
+	Execute("test", func(code int) { t.Fatalf("export exited with %d", code) }, []string{"export", "-o", manifest})
+	data, err := os.ReadFile(manifest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := config.ReadManifest(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var exported *config.ManifestBinary
+	for _, mb := range m.Bins {
+		if mb.Name == "synctool" {
+			exported = mb
+		}
+	}
+	if exported == nil || exported.URL != "corp://team/synctool" || exported.Version != config.LatestVersion {
+		t.Fatalf("expected synctool to be exported at the latest version, got %s", data)
+	}
+
+	// and imported on a second one
+	_, second := newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+	writeManifest := func(version string, pinned bool) {
+		t.Helper()
+		content := fmt.Sprintf(`{"manifest_version": 1, "bins": [{"name": "synctool", "url": "corp://team/synctool", "version": %q, "pinned": %t}]}`, version, pinned)
+		if err := os.WriteFile(manifest, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tool := filepath.Join(second, "synctool")
+	installed := func() string {
+		t.Helper()
+		out, err := exec.Command(tool).Output()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(out)
+	}
+	writeManifest(config.LatestVersion, false)
+	Execute("test", func(code int) { t.Fatalf("import exited with %d", code) }, []string{"import", manifest})
+	if v := installed(); v != "1.0.0\n" {
+		t.Fatalf("expected the latest version to be imported, got %q", v)
+	}
+	if b := config.Get().Bins[tool]; b == nil || b.Version != "1.0.0" || b.Pinned {
+		t.Fatalf("expected synctool to be configured, got %+v", b)
+	}
+
+	// a pinned version conflicts with the installed one
+	writeManifest("0.9.0", true)
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"import", manifest})
+	if code == 0 {
+		t.Error("expected the conflict to fail without a terminal to confirm it")
+	}
+	Execute("test", func(code int) { t.Fatalf("import exited with %d", code) }, []string{"import", "--skip-existing", manifest})
+	if v := installed(); v != "1.0.0\n" {
+		t.Fatalf("expected the installed version to be kept, got %q", v)
+	}
+	Execute("test", func(code int) { t.Fatalf("import exited with %d", code) }, []string{"import", "--overwrite", manifest})
+	if v := installed(); v != "0.9.0\n" {
+		t.Fatalf("expected the pinned version to be installed, got %q", v)
+	}
+	if b := config.Get().Bins[tool]; b == nil || b.Version != "0.9.0" || !b.Pinned {
+		t.Fatalf("expected synctool to be pinned to 0.9.0, got %+v", b)
+	}
+}
@@ -0,0 +1,185 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+type infoCmd struct {
+	cmd  *cobra.Command
+	opts infoOpts
+}
+
+type infoOpts struct {
+	json   bool
+	remote bool
+}
+
+// infoOutput is the JSON output of `bin info`, see pkg/schema/schemas/info.json
+type infoOutput struct {
+	SchemaVersion int    `json:"schema_version"`
+	Name          string `json:"name"`
+	Path          string `json:"path"`
+	Provider      string `json:"provider"`
+	URL           string `json:"url"`
+	Version       string `json:"version"`
+	Hash          string `json:"hash"`
+	Pinned        bool   `json:"pinned"`
+	// Asset is the asset hint and SelectedAsset the pattern of
+	// the asset picked among several ones, both remembered for
+	// the updates, InstalledAsset the last one installed
+	Asset          string `json:"asset,omitempty"`
+	SelectedAsset  string `json:"selected_asset,omitempty"`
+	InstalledAsset string `json:"installed_asset,omitempty"`
+	// UpdatedAt is when the file was last written,
+	// missing when it doesn't exist
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	PostInstall []string   `json:"post_install,omitempty"`
+	// Details are the ones of the provider, see providers.Describer
+	Details map[string]string `json:"details,omitempty"`
+	// Latest and LatestURL are the latest release, with --remote
+	Latest    string `json:"latest,omitempty"`
+	LatestURL string `json:"latest_url,omitempty"`
+	// Config is the configuration the binary comes from,
+	// only in a project, see configOf
+	Config string `json:"config,omitempty"`
+}
+
+func newInfoCmd() *infoCmd {
+	root := &infoCmd{}
+
+	cmd := &cobra.Command{
+		Use:               "info <binary>",
+		ValidArgsFunction: completeBinaries(1),
+		Short:             "Shows everything bin knows about a binary",
+		Long:              "Shows the path, provider, source, version, hash, pin and remembered asset of a binary along with the details of its provider (i.e. the repository of GitHub releases). --remote checks its latest version as well.",
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Args:              cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := managedBinPath(args[0])
+			if err != nil {
+				return err
+			}
+			out, err := newInfo(config.Get().Bins[p], root.opts.remote)
+			if err != nil {
+				return err
+			}
+			if root.opts.json {
+				out.SchemaVersion = schema.Version
+				return writeJSON(os.Stdout, out)
+			}
+			printInfo(os.Stdout, out)
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print the information as JSON, see `bin schema info`")
+	root.cmd.Flags().BoolVar(&root.opts.remote, "remote", false, "Check the latest version available and its release URL")
+	return root
+}
+
+// newInfo gathers the information of the binary, the latest
+// version is checked with the provider when remote is set
+func newInfo(b *config.Binary, remote bool) (*infoOutput, error) {
+	p := os.ExpandEnv(b.Path)
+	out := &infoOutput{
+		Name:           filepath.Base(p),
+		Path:           p,
+		Provider:       b.Provider,
+		URL:            b.URL,
+		Version:        b.Version,
+		Hash:           b.Hash,
+		Pinned:         b.Pinned,
+		Asset:          b.Asset,
+		SelectedAsset:  b.SelectedAsset,
+		InstalledAsset: b.InstalledAsset,
+		PostInstall:    b.PostInstall,
+		Config:         configOf(b.Path),
+	}
+	if fi, err := os.Stat(p); err == nil {
+		t := fi.ModTime().UTC()
+		out.UpdatedAt = &t
+	}
+
+	prov, err := newProvider(b)
+	if err != nil {
+		if remote {
+			return nil, err
+		}
+		log.Debugf("Unable to get the provider of %s: %v", p, err)
+		return out, nil
+	}
+	if d, ok := prov.(providers.Describer); ok {
+		out.Details = map[string]string{}
+		for k, v := range d.Describe() {
+			if v != "" {
+				out.Details[k] = v
+			}
+		}
+	}
+	if remote {
+		out.Latest, out.LatestURL, err = prov.GetLatestVersion()
+		if err != nil {
+			return nil, fmt.Errorf("Error checking the latest version of %s, %w", p, err)
+		}
+	}
+	return out, nil
+}
+
+// printInfo prints the information as aligned name and value pairs
+func printInfo(w io.Writer, out *infoOutput) {
+	rows := [][2]string{
+		{"name", out.Name},
+		{"path", out.Path},
+		{"provider", out.Provider},
+		{"url", out.URL},
+		{"version", out.Version},
+		{"hash", out.Hash},
+		{"pinned", strconv.FormatBool(out.Pinned)},
+		{"asset", out.Asset},
+		{"selected_asset", out.SelectedAsset},
+		{"installed_asset", out.InstalledAsset},
+	}
+	updated := "missing"
+	if out.UpdatedAt != nil {
+		updated = fmt.Sprintf("%s (%s ago)", out.UpdatedAt.Local().Format(time.DateTime), formatAge(time.Since(*out.UpdatedAt)))
+	}
+	rows = append(rows, [2]string{"updated", updated})
+	rows = append(rows, [2]string{"post_install", strings.Join(out.PostInstall, "; ")})
+	if out.Config != "" {
+		rows = append(rows, [2]string{"config", out.Config})
+	}
+	details := make([]string, 0, len(out.Details))
+	for k := range out.Details {
+		details = append(details, k)
+	}
+	sort.Strings(details)
+	for _, k := range details {
+		rows = append(rows, [2]string{out.Provider + "." + k, out.Details[k]})
+	}
+	if out.Latest != "" {
+		rows = append(rows, [2]string{"latest", out.Latest}, [2]string{"latest_url", out.LatestURL})
+	}
+
+	width := 0
+	for _, r := range rows {
+		width = max(width, len(r[0]))
+	}
+	for _, r := range rows {
+		fmt.Fprintf(w, "%s  %s\n", _rPad(r[0], width), displayValue(r[1]))
+	}
+}
@@ -0,0 +1,52 @@
+package cmd
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestInfo(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { demoForge = nil }()
+	demoForge = forge
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	t.Setenv("BIN_DEMO", "1")
+	for _, k := range []string{"BIN_CONFIG", "BIN_STATE", "XDG_DATA_HOME"} {
+		t.Setenv(k, "")
+	}
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool/releases/tag/v1.0.0"})
+	installed := executablePath(filepath.Join(demoDir(), "bin", "tool"))
+
+	var out infoOutput
+	b := captureStdout(t, func() {
+		Execute("test", func(code int) { t.Fatalf("info exited with %d", code) }, []string{"info", "--remote", "--json", installed})
+	})
+	if err := json.Unmarshal(b, &out); err != nil {
+		t.Fatalf("%v: %s", err, b)
+	}
+	if out.Path != installed || out.Provider != "github" || out.Version != "v1.0.0" || out.Hash == "" || out.UpdatedAt == nil {
+		t.Fatalf("expected the installed binary, got %+v", out)
+	}
+	if out.Details["owner"] != "acme" || out.Details["repo"] != "tool" || out.Details["tag"] != "v1.0.0" {
+		t.Fatalf("expected the repository and tag of the release, got %v", out.Details)
+	}
+	if out.Latest != "v1.1.0" || !strings.HasSuffix(out.LatestURL, "/acme/tool/releases/tag/v1.1.0") {
+		t.Fatalf("expected the latest release v1.1.0, got %s at %s", out.Latest, out.LatestURL)
+	}
+	validateOutput(t, "info", out)
+
+	if _, err := managedBinPath("tol"); err == nil || !strings.Contains(err.Error(), "did you mean tool?") {
+		t.Fatalf("expected tool to be suggested, got %v", err)
+	}
+}
@@ -2,18 +2,24 @@ package cmd
 
 import (
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"io"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
 
-	"github.com/caarlos0/log"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/spf13/cobra"
 
 	"github.com/marcosnils/bin/pkg/assets"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/journal"
+	"github.com/marcosnils/bin/pkg/prompt"
 	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/marcosnils/bin/pkg/store"
 )
 
 type installCmd struct {
@@ -23,81 +29,335 @@ type installCmd struct {
 
 type installOpts struct {
 	force      bool
+	name       string
+	path       string
 	provider   string
 	all        bool
+	sideFiles  bool
 	versionURL string
+	versionRe  string
+	versionJP  string
+	versionCmd string
+	// versionCmdShell runs versionCmd with the shell
+	versionCmdShell bool
+	scrape          bool
+	linkRegex       string
+	sources         []string
+	strategy        string
+	tagPrefix       string
+	tagPattern      string
+	mutableTag      bool
+
+	asset           string
+	assetHintPolicy string
+	preferFormat    []string
+	platforms       []string
+	versionProbe    string
+	probeFrom       string
+	version         string
+
+	minTLSVersion  string
+	allowLegacyTLS bool
+
+	headers   []string
+	basicAuth string
+
+	trackLatest  int
+	nameTemplate string
+
+	kind string
+
+	binaries       []string
+	selectMultiple bool
+
+	completions bool
+	manpages    bool
+
+	buildFromSource bool
+	mainPackage     string
+
+	// packagePath is the path of the binary inside the archive
+	packagePath string
+
+	// enforceQuota refuses the installs going over the quota
+	enforceQuota bool
+
+	mirrors     []string
+	mirrorFirst bool
+
+	signingKeys []string
+
+	updateWindow string
+
+	verifyArgs    string
+	verifyVersion bool
+
+	postInstall []string
+
+	// fromBundle installs from a bundle of bin download
+	// instead of the provider
+	fromBundle string
 }
 
 func newInstallCmd() *installCmd {
 	root := &installCmd{}
 	// nolint: dupl
 	cmd := &cobra.Command{
-		Use:           "install <url> [name | path]",
-		Aliases:       []string{"i"},
-		Short:         "Installs the specified binary from a url",
-		SilenceUsage:  true,
-		SilenceErrors: true,
-		Args:          cobra.MinimumNArgs(1),
+		Use:               "install <url | owner/repo[@version]> [name | path]",
+		ValidArgsFunction: completeInstall,
+		Annotations:       map[string]string{writesConfig: "true"},
+		Aliases:           []string{"i"},
+		Short:             "Installs the specified binary from a url",
+		SilenceUsage:      true,
+		SilenceErrors:     true,
+		Args:              cobra.ArbitraryArgs,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			u := args[0]
+			if root.opts.fromBundle != "" {
+				if err := openBundle(root.opts.fromBundle); err != nil {
+					return err
+				}
+				defer closeBundle()
+				if len(args) == 0 {
+					return installBundle()
+				}
+			}
+			if len(args) == 0 {
+				return fmt.Errorf("requires the URL of the binary to install, or --from-bundle")
+			}
+
+			u, err := installURL(args[0], root.opts.provider)
+			if err != nil {
+				return err
+			}
+			if err := config.ValidateKind(root.opts.kind); err != nil {
+				return err
+			}
 			defaultPath := config.Get().DefaultPath
+			if root.opts.mirrorFirst && len(root.opts.mirrors) == 0 {
+				return fmt.Errorf("--mirror-first requires at least one --mirror")
+			}
+			for _, h := range root.opts.postInstall {
+				if _, err := providers.SplitCommand(h); err != nil {
+					return fmt.Errorf("invalid --post %q: %w", h, err)
+				}
+			}
+			if root.opts.updateWindow != "" {
+				if _, err := config.ParseUpdateWindow(root.opts.updateWindow); err != nil {
+					return err
+				}
+			}
+			target := ""
+			if root.opts.path != "" {
+				if len(args) > 1 {
+					return fmt.Errorf("--path and the path argument can't be used together")
+				}
+				dir, ok := config.PathDir(root.opts.path)
+				if ok {
+					// the configured paths are created on demand
+					if err := os.MkdirAll(dir, 0o755); err != nil {
+						return err
+					}
+					target = root.opts.path
+				} else if !strings.ContainsAny(root.opts.path, `/\`) {
+					return fmt.Errorf("%s isn't one of the paths of the configuration, add it to paths or pass a directory", root.opts.path)
+				} else {
+					dir = root.opts.path
+				}
+				args = append(args[:1], dir)
+			}
+
+			if root.opts.kind == config.KindFile {
+				defaultPath, err = filesPath(args)
+				if err != nil {
+					return err
+				}
+			}
 
 			var resolvedPath string
 			if len(args) > 1 {
 				resolvedPath = args[1]
-				if !strings.Contains(resolvedPath, "/") {
+				if !strings.Contains(resolvedPath, "/") && !filepath.IsAbs(resolvedPath) {
 					resolvedPath = filepath.Join(defaultPath, resolvedPath)
 				}
 
 			} else {
 				resolvedPath = defaultPath
 			}
+			// --name only renames the main binary, the other
+			// ones keep their name in the same directory
+			entriesPath := resolvedPath
+			if root.opts.name != "" {
+				if err := validateName(root.opts.name); err != nil {
+					return err
+				}
+				if root.opts.trackLatest > 0 {
+					return fmt.Errorf("--name can't be used with --track-latest, the series are named after --name-template")
+				}
+				if len(args) > 1 && !isDir(resolvedPath) {
+					return fmt.Errorf("--name sets the name of the file, %s must be a directory", args[1])
+				}
+				resolvedPath = executablePath(filepath.Join(resolvedPath, root.opts.name))
+				if err := checkNameCollision(resolvedPath, u, root.opts.force); err != nil {
+					return err
+				}
+			}
 
 			// TODO check if binary already exists in config
 			// and triger the update process if that's the case
 
-			p, err := providers.New(u, root.opts.provider, root.opts.versionURL)
-			if err != nil {
+			b := &config.Binary{
+				URL:        u,
+				Provider:   root.opts.provider,
+				TagPrefix:  root.opts.tagPrefix,
+				TagPattern: root.opts.tagPattern,
+				MutableTag: root.opts.mutableTag,
+				Target:     target,
+
+				VersionURL:      root.opts.versionURL,
+				VersionRegex:    root.opts.versionRe,
+				VersionJSONPath: root.opts.versionJP,
+				VersionCmd:      root.opts.versionCmd,
+				VersionCmdShell: root.opts.versionCmdShell,
+
+				Scrape:    root.opts.scrape || root.opts.linkRegex != "",
+				LinkRegex: root.opts.linkRegex,
+
+				Sources:  root.opts.sources,
+				Strategy: root.opts.strategy,
+
+				Version:      root.opts.probeFrom,
+				VersionProbe: root.opts.versionProbe,
+
+				Asset:           root.opts.asset,
+				AssetHintPolicy: root.opts.assetHintPolicy,
+				PreferFormat:    root.opts.preferFormat,
+
+				MinTLSVersion: root.opts.minTLSVersion,
+
+				InstallCompletions: root.opts.completions,
+				InstallManpages:    root.opts.manpages,
+
+				BuildFromSource: root.opts.buildFromSource || root.opts.mainPackage != "",
+				MainPackage:     root.opts.mainPackage,
+
+				PackagePath: root.opts.packagePath,
+
+				Mirrors:     root.opts.mirrors,
+				MirrorFirst: root.opts.mirrorFirst,
+
+				UpdateWindow: root.opts.updateWindow,
+
+				VerifyArgs:    root.opts.verifyArgs,
+				VerifyVersion: root.opts.verifyVersion,
+
+				PostInstall: root.opts.postInstall,
+			}
+			for _, k := range root.opts.signingKeys {
+				abs, err := filepath.Abs(k)
+				if err != nil {
+					return err
+				}
+				b.SigningKeys = append(b.SigningKeys, abs)
+			}
+			if root.opts.kind == config.KindFile {
+				b.Kind = config.KindFile
+			}
+			if root.opts.version != "" {
+				b.Version = root.opts.version
+			}
+			if root.opts.allowLegacyTLS {
+				b.MinTLSVersion = "1.0"
+			}
+			for _, h := range root.opts.headers {
+				name, value, err := providers.ParseHeader(h)
+				if err != nil {
+					return err
+				}
+				if b.Headers == nil {
+					b.Headers = map[string]string{}
+				}
+				b.Headers[name] = value
+			}
+			b.BasicAuth = root.opts.basicAuth
+			if err := assets.ValidateFormats(b.PreferFormat); err != nil {
 				return err
 			}
-			log.Debugf("Using provider '%s' for '%s'", p.GetID(), u)
+			if len(root.opts.platforms) > 0 {
+				// the URL argument is the one of the current platform
+				// unless it's explicitly mapped
+				b.Platforms = map[string]string{runtime.GOOS: u}
+				for _, s := range root.opts.platforms {
+					platform, pu, err := parsePlatformURL(s)
+					if err != nil {
+						return err
+					}
+					if b.Platforms[platform], err = installURL(pu, root.opts.provider); err != nil {
+						return err
+					}
+				}
+				if b.URL, err = platformURL(b, runtime.GOOS, runtime.GOARCH); err != nil {
+					return err
+				}
+			}
 
-			pResult, err := p.Fetch(&providers.FetchOpts{All: root.opts.all, PackagePath: args[1]})
+			p, err := newProvider(b)
 			if err != nil {
 				return err
 			}
+			log.Debugf("Using provider '%s' for '%s'", p.GetID(), u)
+			if b.IsFile() && (p.GetID() == "docker" || p.GetID() == "goinstall") {
+				return fmt.Errorf("the %s provider can only install binaries", p.GetID())
+			}
 
-			resolvedPath, err = checkFinalPath(resolvedPath, assets.SanitizeName(pResult.Name, pResult.Version))
-			if err != nil {
-				return err
+			if root.opts.trackLatest > 0 {
+				b.TrackLatest, b.NameTemplate = root.opts.trackLatest, root.opts.nameTemplate
+				dir := defaultPath
+				if len(args) > 1 {
+					dir = args[1]
+				}
+				return installTracked(b, p, os.ExpandEnv(dir), root.opts.all)
 			}
 
-			hash, err := saveToDisk(pResult, resolvedPath, root.opts.force)
-			if err != nil {
-				return fmt.Errorf("error installing binary: %w", err)
+			multiple := len(root.opts.binaries) > 0 || root.opts.selectMultiple
+			if multiple && len(args) > 1 && !isDir(entriesPath) {
+				return fmt.Errorf("--binaries and --select-multiple install into a directory, %s isn't one", entriesPath)
 			}
+			// the other binaries of the archive don't download it again
+			cache := assets.NewDownloadCache()
+			defer cache.Close()
+			base := *b
 
-			// Convert to absolute path before storing in config
-			absPath, err := filepath.Abs(resolvedPath)
-			if err != nil {
-				return fmt.Errorf("error converting to absolute path: %w", err)
-			}
-
-			err = config.UpsertBinary(&config.Binary{
-				RemoteName:  pResult.Name,
-				Path:        absPath,
-				Version:     pResult.Version,
-				VersionURL:  root.opts.versionURL,
-				Hash:        fmt.Sprintf("%x", hash),
-				URL:         u,
-				Provider:    p.GetID(),
-				PackagePath: pResult.PackagePath,
-			})
+			pResult, err := p.Fetch(&providers.FetchOpts{All: root.opts.all, SideFiles: root.opts.sideFiles, PackagePath: b.PackagePath, Formats: b.PreferFormat, RequireChecksum: config.Get().RequireChecksum, Files: b.IsFile(), Entries: root.opts.binaries, SelectMultiple: root.opts.selectMultiple, Completions: b.InstallCompletions, Manpages: b.InstallManpages, Mirrors: b.Mirrors, MirrorFirst: b.MirrorFirst, SigningKeys: config.SigningKeys(b), RequireSignature: config.Get().RequireSignature, Cache: cache, AssetRewrites: b.AssetRewrites, BuildFromSource: b.BuildFromSource, MainPackage: b.MainPackage})
 			if err != nil {
 				return err
 			}
+			if len(root.opts.binaries) > 1 && len(pResult.OtherEntries) == 0 {
+				log.WithField("asset", pResult.Asset).WithField("binary", pResult.Name).Warn("Not an archive, only one binary is installed")
+			}
+
+			if err := installFetched(b, p, pResult, resolvedPath, root.opts.force, multiple, root.opts.enforceQuota); err != nil {
+				return err
+			}
+			warnHintBypassed(b)
+			warnEmulated(b)
+			warnShadowed(b)
+			if len(b.Platforms) > 1 {
+				checkPlatformVersions(b, pResult.Version)
+			}
 
-			log.Infof("Done installing %s %s", pResult.Name, pResult.Version)
+			// the other binaries are separate entries sharing the URL
+			// and version, their updates share a single download
+			for _, entry := range pResult.OtherEntries {
+				nb := base
+				f, err := p.Fetch(&providers.FetchOpts{Version: pResult.Version, PackagePath: entry, SelectedAsset: pResult.SelectedAsset, Formats: nb.PreferFormat, RequireChecksum: config.Get().RequireChecksum, Files: nb.IsFile(), Completions: nb.InstallCompletions, Manpages: nb.InstallManpages, Mirrors: nb.Mirrors, MirrorFirst: nb.MirrorFirst, SigningKeys: config.SigningKeys(&nb), RequireSignature: config.Get().RequireSignature, Cache: cache, AssetRewrites: nb.AssetRewrites, BuildFromSource: nb.BuildFromSource, MainPackage: nb.MainPackage})
+				if err != nil {
+					return fmt.Errorf("error fetching %s: %w", entry, err)
+				}
+				if err := installFetched(&nb, p, f, entriesPath, root.opts.force, true, root.opts.enforceQuota); err != nil {
+					return err
+				}
+				warnShadowed(&nb)
+			}
 
 			return nil
 		},
@@ -105,12 +365,274 @@ func newInstallCmd() *installCmd {
 
 	root.cmd = cmd
 	root.cmd.Flags().BoolVarP(&root.opts.force, "force", "f", false, "Force the installation even if the file already exists")
+	root.cmd.Flags().StringVar(&root.opts.fromBundle, "from-bundle", "", "Install from a bundle written by `bin download`, without network access. Every binary of the bundle is installed when no URL is given")
+	root.cmd.Flags().StringVar(&root.opts.name, "name", "", "Name of the installed file instead of the one of the asset, kept by the updates")
+	root.cmd.Flags().StringVar(&root.opts.path, "path", "", "Directory to install into, or the name of one of the paths of the configuration which is kept by the binary")
+	root.cmd.Flags().BoolVar(&root.opts.enforceQuota, "enforce-quota", false, "Refuse the installation when it brings the disk usage over the configured quota instead of warning")
 	root.cmd.Flags().BoolVarP(&root.opts.all, "all", "a", false, "Show all possible download options (skip scoring & filtering)")
+	root.cmd.Flags().BoolVar(&root.opts.sideFiles, "side-files", false, "Don't exclude the checksums, signatures, SBOMs and source archives from the download options")
 	root.cmd.Flags().StringVarP(&root.opts.provider, "provider", "p", "", "Forces to use a specific provider")
 	root.cmd.Flags().StringVar(&root.opts.versionURL, "version-url", "", "URL used to fetch for the version from (if supported by the provider)")
+	root.cmd.Flags().StringArrayVar(&root.opts.sources, "source", nil, "Other URL the binary is published at, in priority order. Can be repeated")
+	root.cmd.Flags().StringVar(&root.opts.strategy, "source-strategy", "", "How to choose between the sources: newest (default) or priority")
+	root.cmd.Flags().BoolVar(&root.opts.scrape, "scrape", false, "Find the versions in the links of the HTML page or directory listing at the URL")
+	root.cmd.Flags().StringVar(&root.opts.linkRegex, "link-regex", "", "Only consider the links matching this regex when scraping, its first capture group is used as the version if any (implies --scrape)")
+	root.cmd.Flags().StringVar(&root.opts.versionRe, "version-regex", "", "Regex extracting the version from the --version-url response, using its first capture group (i.e. 'Latest: (v[0-9.]+)')")
+	root.cmd.Flags().StringVar(&root.opts.versionJP, "version-json-path", "", "Path of the version in the JSON --version-url response (i.e. '[0].version' or 'data.latest')")
+	root.cmd.Flags().StringVar(&root.opts.versionCmd, "version-cmd", "", "Command printing the version to use for the {version} placeholder instead of a --version-url, run without a shell")
+	root.cmd.Flags().BoolVar(&root.opts.versionCmdShell, "version-cmd-shell", false, "Run --version-cmd with 'sh -c', for pipes and redirections")
+	root.cmd.Flags().StringVar(&root.opts.tagPrefix, "tag-prefix", "", "Only consider releases whose tag starts with this prefix (i.e. for monorepos)")
+	root.cmd.Flags().StringVar(&root.opts.tagPattern, "tag-pattern", "", "Only consider releases whose tag matches this regex. The first capture group, if any, is used as version")
+	root.cmd.Flags().BoolVar(&root.opts.mutableTag, "mutable-tag", false, "The installed tag gets its assets re-published (i.e. nightly builds), check them for changes on updates")
+	root.cmd.Flags().StringVar(&root.opts.asset, "asset", "", "Only consider the release assets matching this name, glob (tool-*-linux-amd64.tar.gz) or regex wrapped in slashes (/^tool-.*$/)")
+	_ = root.cmd.RegisterFlagCompletionFunc("asset", completeAssets)
+	root.cmd.Flags().StringArrayVar(&root.opts.platforms, "platform", nil, "URL of the binary for another platform as os[/arch]=url (i.e. darwin=github.com/owner/tool-macos), for tools published in a repository per platform. Can be repeated")
+	root.cmd.Flags().StringSliceVar(&root.opts.preferFormat, "prefer-format", nil, "Preference order of the asset formats when several are available, i.e. tar.gz,zip,binary (default binary, appimage, tar.gz, tar.xz, ..., zip, deb, rpm)")
+	root.cmd.Flags().StringVar(&root.opts.assetHintPolicy, "asset-hint-policy", "", "What to do when the asset from the URL is not found: warn, fail or fallback (default: fail when non-interactive, warn otherwise)")
+	root.cmd.Flags().StringVar(&root.opts.versionProbe, "version-probe", "", "Discover new versions by probing the {version} URL with the next ones, i.e. patch,minor (generic provider, use with --probe-from)")
+	root.cmd.Flags().StringVar(&root.opts.version, "version", "", "Version of a direct download whose file name doesn't have one")
+	root.cmd.Flags().StringVar(&root.opts.probeFrom, "probe-from", "", "Version to start probing from when using --version-probe")
+	root.cmd.Flags().StringVar(&root.opts.minTLSVersion, "min-tls-version", "", "Minimum TLS version accepted from the hosts of this binary (1.0, 1.1, 1.2 or 1.3)")
+	root.cmd.Flags().IntVar(&root.opts.trackLatest, "track-latest", 0, "Keep the newest release of each of the last N minor series installed side by side, named after --name-template")
+	root.cmd.Flags().StringVar(&root.opts.nameTemplate, "name-template", "", "Name of each series installed with --track-latest, i.e. 'terraform-{major}.{minor}'")
+	root.cmd.Flags().StringArrayVar(&root.opts.headers, "header", nil, "Header sent to a generic URL as 'Name: value'. Credentials must reference environment variables, i.e. 'Authorization: Bearer ${TOKEN}'")
+	root.cmd.Flags().StringVar(&root.opts.basicAuth, "basic-auth", "", "Basic auth credentials for a generic URL, referencing environment variables as '${USER}:${PASSWORD}'")
+	root.cmd.Flags().StringVar(&root.opts.kind, "kind", config.KindBinary, "Kind of the artifact, binary or file for data files and fonts installed as is into the given directory or default_files_path")
+	root.cmd.Flags().StringSliceVar(&root.opts.binaries, "binaries", nil, "Install these executables of the archive (i.e. etcd,etcdctl,etcdutl) as separate binaries updated together")
+	root.cmd.Flags().BoolVar(&root.opts.selectMultiple, "select-multiple", false, "Pick several executables of the archive to install as separate binaries updated together")
+	root.cmd.Flags().BoolVar(&root.opts.completions, "completions", false, "Install the shell completions shipped in the archive along the binary")
+	root.cmd.Flags().BoolVar(&root.opts.manpages, "manpages", false, "Install the man pages shipped in the archive along the binary")
+	root.cmd.Flags().BoolVar(&root.opts.buildFromSource, "build-from-source", false, "Build the Go module of a GitHub release with the local toolchain when none of its assets matches the platform")
+	root.cmd.Flags().StringVar(&root.opts.packagePath, "package-path", "", "Path of the binary inside the archive of the asset, kept by the updates")
+	root.cmd.Flags().StringVar(&root.opts.mainPackage, "main-package", "", "Main package built by --build-from-source, relative to the module root (cmd/<repo> or the root by default)")
+	root.cmd.Flags().BoolVar(&root.opts.allowLegacyTLS, "allow-legacy-tls", false, "Accept TLS 1.0 and 1.1 from the hosts of this binary only")
+	root.cmd.Flags().StringArrayVar(&root.opts.mirrors, "mirror", nil, "URL template of a mirror of the assets, i.e. 'https://mirror.example.com/tool/{version}/{asset}', tried when the asset URL fails. Can be repeated")
+	root.cmd.Flags().BoolVar(&root.opts.mirrorFirst, "mirror-first", false, "Try the --mirror URLs before the asset URL")
+	root.cmd.Flags().StringVar(&root.opts.updateWindow, "update-window", "", "Only update the binary with the others during these days and/or hours, i.e. 'fri' or 'mon-fri 18:00-08:00'")
+	root.cmd.Flags().StringVar(&root.opts.verifyArgs, "verify-args", "", "Run the installed binary with these arguments (--version by default with verify_install or --verify-version) and keep the previous file if it fails, 'none' to never run it")
+	root.cmd.Flags().BoolVar(&root.opts.verifyVersion, "verify-version", false, "Run the installed binary and require the installed version in its output, keeping the previous file otherwise")
+	root.cmd.Flags().StringArrayVar(&root.opts.postInstall, "post", nil, "Command run after the binary is installed or updated, without a shell unless it's 'sh -c ...'. $BIN_PATH, $BIN_NAME and $BIN_VERSION are set. Can be repeated")
+	root.cmd.Flags().StringArrayVar(&root.opts.signingKeys, "signing-key", nil, "OpenPGP public key file, or directory of them, verifying the signatures of the checksum files and assets. Can be repeated")
 	return root
 }
 
+// filesPath returns the directory where the files are installed
+// when the destination given to install isn't a path
+func filesPath(args []string) (string, error) {
+	if len(args) > 1 && strings.Contains(args[1], "/") {
+		return "", nil
+	}
+	dir := config.Get().DefaultFilesPath
+	if dir == "" {
+		return "", fmt.Errorf("files need a destination directory, pass it to install or set default_files_path in the configuration")
+	}
+	if err := os.MkdirAll(os.ExpandEnv(dir), 0o755); err != nil {
+		return "", err
+	}
+	return dir, nil
+}
+
+// binaryName returns the name of the installed binary, AppImages
+// lose their extension unless keep_appimage_extension is set
+func binaryName(f *providers.File) string {
+	name := assets.SanitizeName(f.Name, f.Version)
+	if assets.Format(name) == assets.FormatAppImage && !config.Get().KeepAppImageExtension {
+		name = strings.TrimSuffix(name, ".appimage")
+	}
+	return name
+}
+
+// warnHintBypassed reports in the run summary that the binary
+// was installed ignoring its asset hint
+func warnHintBypassed(b *config.Binary) {
+	if b.AssetHintBypassed {
+		binLog(b).WithField("asset_hint_policy", b.AssetHintPolicy).Warn("Installed without matching its asset hint")
+	}
+}
+
+func warnEmulated(b *config.Binary) {
+	if b.Emulated != "" {
+		binLog(b).WithField("arch", b.Emulated).WithField("emulation", config.Emulation()).Warn("Runs under emulation")
+	}
+}
+
+// newProvider returns the provider configured for the given
+// binary, or its asset in the bundle installs are made from
+func newProvider(b *config.Binary) (providers.Provider, error) {
+	if offlineBundle != nil {
+		return bundledProvider(b)
+	}
+	goos, _ := config.ResolveOS()
+	goarch, _ := config.ResolveArch()
+	u, err := platformURL(b, goos, goarch)
+	if err != nil {
+		return nil, err
+	}
+	minTLSVersion, _ := config.ResolveMinTLSVersion(b)
+	return providers.New(u, &providers.Opts{
+		Provider:   b.Provider,
+		TagPrefix:  b.TagPrefix,
+		TagPattern: b.TagPattern,
+		Settings:   settings,
+
+		VersionURL:      b.VersionURL,
+		VersionRegex:    b.VersionRegex,
+		VersionJSONPath: b.VersionJSONPath,
+		VersionCmd:      b.VersionCmd,
+		VersionCmdShell: b.VersionCmdShell,
+
+		Scrape:    b.Scrape,
+		LinkRegex: b.LinkRegex,
+
+		Sources:  b.Sources,
+		Strategy: b.Strategy,
+
+		Version:      b.Version,
+		VersionProbe: b.VersionProbe,
+
+		Asset:           b.Asset,
+		AssetHintPolicy: b.AssetHintPolicy,
+
+		Headers:   b.Headers,
+		BasicAuth: b.BasicAuth,
+
+		MinTLSVersion: minTLSVersion,
+	})
+}
+
+// installFetched saves the fetched file under path, or into it when
+// it's a directory, and records the binary. With confirm, an existing
+// file is overwritten once the user confirms it, since several binaries
+// are installed at once.
+func installFetched(b *config.Binary, p providers.Provider, f *providers.File, path string, force, confirm, enforceQuota bool) error {
+	// files keep their name, i.e. fonts
+	name := f.Name
+	if !b.IsFile() {
+		name = binaryName(f)
+	}
+	path, err := checkFinalPath(path, name)
+	if err != nil {
+		return err
+	}
+	if !b.IsFile() {
+		path = executablePath(path)
+	}
+
+	overwrite := force
+	if _, err := os.Stat(os.ExpandEnv(path)); err == nil && !force && confirm && prompt.IsInteractive() {
+		if err := prompt.Confirm(fmt.Sprintf("%s already exists, overwrite it?", path)); err != nil {
+			return err
+		}
+		overwrite = true
+	}
+	if err := guardQuota(path, f, enforceQuota); err != nil {
+		return err
+	}
+	hash, err := saveToDisk(f, path, b, overwrite)
+	if err != nil {
+		return fmt.Errorf("error installing binary: %w", err)
+	}
+	if err := installExtras(b, f.Extras); err != nil {
+		return err
+	}
+
+	// Convert to absolute path before storing in config
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("error converting to absolute path: %w", err)
+	}
+
+	b.RemoteName = f.Name
+	b.Path = absPath
+	b.Version = f.Version
+	b.Hash = fmt.Sprintf("%x", hash)
+	b.Provider = p.GetID()
+	b.PackagePath = f.PackagePath
+	b.AssetDigest = f.AssetDigest
+	b.VerifiedWith = f.VerifiedWith
+	b.SignedBy, b.SignedFile = f.SignedBy, f.SignedFile
+	b.InstalledAsset, b.AssetSHA256 = f.Asset, f.AssetSHA256
+	b.AssetURL, b.InstalledAt = f.AssetURL, installedNow()
+	b.Emulated = f.Emulated
+	b.AssetProfile = recordAsset(b.AssetProfile, f)
+	b.AssetHintBypassed = f.AssetHintBypassed
+	b.SelectedAsset = f.SelectedAsset
+	b.Source = f.Source
+
+	if err := config.UpsertBinary(b); err != nil {
+		return err
+	}
+	if err := runPostInstall(b); err != nil {
+		return err
+	}
+
+	withDownload(binLog(b), f).Info("Done installing")
+	return nil
+}
+
+// binLog returns a log entry carrying the binary: its name,
+// path, provider and source, see pkg/log
+func binLog(b *config.Binary) *log.Entry {
+	p := os.ExpandEnv(b.Path)
+	return log.WithField("binary", filepath.Base(p)).WithField("path", p).WithField("provider", b.Provider).WithField("url", b.URL)
+}
+
+// withDownload adds the version of the fetched file and the bytes
+// downloaded to fetch it, for the final line of the commands
+func withDownload(e *log.Entry, f *providers.File) *log.Entry {
+	return e.WithField("version", f.Version).WithField("bytes", f.Downloaded)
+}
+
+// isDir reports whether path is an existing directory
+func isDir(path string) bool {
+	fi, err := os.Stat(os.ExpandEnv(path))
+	return err == nil && fi.IsDir()
+}
+
+// installURL returns the URL recorded for the argument of install,
+// shorthands are expanded so nothing else has to care about them
+// and local files are recorded by their absolute path
+func installURL(a, provider string) (string, error) {
+	if strings.HasPrefix(a, "file://") {
+		return a, nil
+	}
+	if strings.HasPrefix(a, "./") || strings.HasPrefix(a, "../") || filepath.IsAbs(a) {
+		if _, err := os.Stat(a); err != nil {
+			return "", err
+		}
+		return filepath.Abs(a)
+	}
+
+	u, err := providers.ExpandShorthand(a, config.Get().DefaultForge)
+	if err != nil {
+		return "", err
+	}
+	return providers.NormalizeURL(u, provider, settings)
+}
+
+// validateName checks the name given to a binary, it can't be a path
+func validateName(name string) error {
+	if name == "" || name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("invalid name %q, it can't be empty nor a path", name)
+	}
+	return nil
+}
+
+// checkNameCollision fails when another binary is already
+// configured at path, even if its file is missing
+func checkNameCollision(path, url string, force bool) error {
+	abs, err := filepath.Abs(os.ExpandEnv(path))
+	if err != nil {
+		return err
+	}
+	for p, b := range config.Get().Bins {
+		if os.ExpandEnv(p) == abs && b.URL != url && !force {
+			return fmt.Errorf("%s is already the name of a binary installed from %s, pick another name or use --force to replace it", abs, b.URL)
+		}
+	}
+	return nil
+}
+
 // checkFinalPath checks if path exists and if it's a dir or not
 // and returns the correct final file path. It also
 // checks if the path already exists and prompts
@@ -131,41 +653,101 @@ func checkFinalPath(path, fileName string) (string, error) {
 }
 
 // saveToDisk saves the specified binary to the desired path
-// and makes it executable. It also checks if any other binary
-// has the same hash and exists if so.
+// and makes it executable, unless it's of kind file. It also
+// checks if any other binary has the same hash and exists if so.
+// The file is written next to the destination and moved into place once
+// complete, after running it when the binary is verified (see smokeTest).
+// The operation is journaled so an interrupted install gets cleaned up
+// on the next run. With versioned_installs the file is written to the
+// store and the path is pointed to it once complete, see store.Activate.
 
 // TODO check if other binary has the same hash and warn about it.
 // TODO if the file is zipped, tared, whatever then extract it
-func saveToDisk(f *providers.File, path string, overwrite bool) ([]byte, error) {
+func saveToDisk(f *providers.File, path string, b *config.Binary, overwrite bool) ([]byte, error) {
 	epath := os.ExpandEnv((path))
 
-	extraFlags := os.O_EXCL
+	if _, err := os.Stat(epath); err == nil && !overwrite {
+		if o, oerr := ownerOf(epath); oerr == nil && o.owned() {
+			return nil, fmt.Errorf("%s already exists, it's managed by bin (%s), use --force to overwrite it", epath, o.entry.URL)
+		}
+		return nil, fmt.Errorf("%s already exists and isn't managed by bin, use --force to overwrite it", epath)
+	}
 
-	if overwrite {
-		extraFlags = 0
-		err := os.Remove(epath)
-		log.Debugf("Overwrite flag set, removing file %s\n", epath)
-		if err != nil && !os.IsNotExist(err) {
+	// with usage statistics the shim stays in place
+	// and the real binary is replaced
+	target := stats.Resolve(epath)
+	dest := target
+	var st *store.Store
+	if versioned(b) {
+		var err error
+		if st, err = versionStore(); err != nil {
+			return nil, err
+		}
+		dest = st.Path(epath, f.Version)
+		if err := os.MkdirAll(filepath.Dir(dest), 0o755); err != nil {
 			return nil, err
 		}
 	}
 
-	file, err := os.OpenFile(epath, os.O_RDWR|os.O_CREATE|extraFlags, 0o766)
+	jd, err := config.GetJournalDir()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := journal.Begin(jd)
 	if err != nil {
 		return nil, err
 	}
 
-	defer file.Close()
+	perm := os.FileMode(0o755)
+	if b.IsFile() {
+		perm = 0o644
+	}
+	file, err := tx.Stage(dest, perm)
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
 
 	h := sha256.New()
 
 	tr := io.TeeReader(f.Data, h)
 
-	log.Infof("Copying for %s@%s into %s", f.Name, f.Version, epath)
+	log.WithField("binary", f.Name).WithField("version", f.Version).WithField("path", epath).Info("Copying")
 	_, err = io.Copy(file, tr)
+	if cerr := file.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = smokeTest(b, f, file.Name())
+	}
 	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+
+	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
+	if st != nil {
+		if err := st.Record(dest, newStoredVersion(f)); err != nil {
+			log.WithField("path", epath).WithError(err).Warn("Could not record the version in the store")
+		}
+		if err := st.Activate(dest, target); err != nil {
+			return nil, fmt.Errorf("Error activating %s %s: %w", epath, f.Version, err)
+		}
+		if err := st.Prune(epath, target); err != nil {
+			log.WithField("path", epath).WithError(err).Warn("Could not remove the previous versions from the store")
+		}
+	}
+
+	if config.Get().Stats && !b.IsFile() {
+		shims, err := statsShims()
+		if err != nil {
+			return nil, err
+		}
+		if err := shims.Enable(epath); err != nil {
+			binLog(b).WithError(err).Warn("Could not record the usage of the binary")
+		}
+	}
+
 	return h.Sum(nil), nil
 }
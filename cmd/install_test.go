@@ -0,0 +1,338 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/fakeforge"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+// newTestConfig points BIN_CONFIG to a configuration installing into
+// binDir, both in the temporary directory of the test. extraJSON holds
+// the other settings of the configuration, i.e. `"verify_install": true`.
+func newTestConfig(t *testing.T, extraJSON string) (dir, binDir string) {
+	t.Helper()
+	dir = t.TempDir()
+	binDir = filepath.Join(dir, "bin")
+	if err := os.Mkdir(binDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	c := fmt.Sprintf(`{"default_path": %q`, binDir)
+	if extraJSON != "" {
+		c += ", " + extraJSON
+	}
+	conf := filepath.Join(dir, "config.json")
+	if err := os.WriteFile(conf, []byte(c+"}"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("BIN_CONFIG", conf)
+	return dir, binDir
+}
+
+// writeScript writes a shell script echoing out to p
+// and returns its content
+func writeScript(t *testing.T, p, out string) []byte {
+	t.Helper()
+	content := []byte("#!/bin/sh\necho " + out + "\n")
+	if err := os.WriteFile(p, content, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return content
+}
+
+func TestSaveToDiskZstd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there's no exec bit on Windows")
+	}
+	dir, _ := newTestConfig(t, "")
+
+	content := []byte("#!/bin/sh\necho tool\n")
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tw := tar.NewWriter(zw)
+	if err := tw.WriteHeader(&tar.Header{Name: "tool", Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tw.Write(content); err != nil {
+		t.Fatal(err)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := assets.NewFilter(&assets.FilterOpts{}).ProcessReader("tool.tar.zst", &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(dir, "tool")
+	if _, err := saveToDisk(&providers.File{Data: out.Source, Name: out.Name, Version: "v1.0.0"}, p, &config.Binary{}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0o111 == 0 {
+		t.Fatalf("expected %s to be executable, got %s", p, fi.Mode())
+	}
+	if b, _ := os.ReadFile(p); !bytes.Equal(b, content) {
+		t.Fatalf("expected the binary to be extracted, got %q", b)
+	}
+}
+
+func TestSaveToDiskFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there's no exec bit on Windows")
+	}
+	dir, _ := newTestConfig(t, "")
+
+	p := filepath.Join(dir, "GeoLite2-City.mmdb")
+	if _, err := saveToDisk(&providers.File{Data: strings.NewReader("db"), Name: "GeoLite2-City.mmdb", Version: "2024.01"}, p, &config.Binary{Kind: config.KindFile}, false); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode().Perm()&0o111 != 0 {
+		t.Fatalf("expected %s not to be executable, got %s", p, fi.Mode())
+	}
+}
+
+func TestInstallAppImage(t *testing.T) {
+	if runtime.GOOS != "linux" || runtime.GOARCH != "amd64" {
+		t.Skip("the AppImage fixture is a linux/amd64 executable")
+	}
+	fixture, err := filepath.Abs("../pkg/assets/testdata/hello-x86_64.AppImage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, keep := range []bool{false, true} {
+		_, binDir := newTestConfig(t, fmt.Sprintf(`"keep_appimage_extension": %t`, keep))
+
+		Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", fixture})
+
+		p := filepath.Join(binDir, "hello")
+		if keep {
+			p += ".appimage"
+		}
+		out, err := exec.Command(p).Output()
+		if err != nil {
+			t.Fatalf("expected %s to be installed and to run: %v", p, err)
+		}
+		if string(out) != "hello from an AppImage\n" {
+			t.Fatalf("unexpected output of the AppImage: %q", out)
+		}
+	}
+}
+
+func TestInstallMultipleBinaries(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the archive holds shell scripts")
+	}
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, n := range []string{"etcd", "etcdctl", "etcdutl", "README.md"} {
+		content := fmt.Sprintf("#!/bin/sh\necho %s\n", n)
+		if err := tw.WriteHeader(&tar.Header{Name: "etcd-v3.5.0-linux-amd64/" + n, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "etcd-v3.5.0-linux-amd64.tar")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, binDir := newTestConfig(t, "")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", archive, "--binaries", "etcdctl,etcdutl"})
+
+	for _, n := range []string{"etcdctl", "etcdutl"} {
+		p := filepath.Join(binDir, n)
+		b := config.Get().Bins[p]
+		if b == nil {
+			t.Fatalf("expected %s to be recorded", p)
+		}
+		if b.URL != archive || b.PackagePath != "etcd-v3.5.0-linux-amd64/"+n {
+			t.Errorf("expected %s to share the URL of the archive with its own package path, got %s %s", n, b.URL, b.PackagePath)
+		}
+		if out, err := exec.Command(p).Output(); err != nil || string(out) != n+"\n" {
+			t.Errorf("expected %s to be installed, got %q (%v)", n, out, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(binDir, "etcd")); err == nil {
+		t.Error("expected etcd not to be installed")
+	}
+
+	// the binaries already exist and nobody can confirm their overwrite
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"install", archive, "--binaries", "etcdutl"})
+	if code == 0 {
+		t.Fatal("expected the name collision to fail the install without --force")
+	}
+}
+
+func TestInstallExtras(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the archive holds a shell script")
+	}
+	dir := t.TempDir()
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, n := range []string{"tool-v1.0.0/tool", "tool-v1.0.0/completions/tool.bash", "tool-v1.0.0/completions/_tool", "tool-v1.0.0/man/tool.1"} {
+		content := "#!/bin/sh\necho tool\n"
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	archive := filepath.Join(dir, "tool-v1.0.0-linux-amd64.tar")
+	if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, binDir := newTestConfig(t, "")
+	data := filepath.Join(dir, "share")
+	t.Setenv("XDG_DATA_HOME", data)
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", archive, "--completions", "--manpages"})
+
+	extras := []string{
+		filepath.Join(data, "bash-completion", "completions", "tool"),
+		filepath.Join(data, "zsh", "site-functions", "_tool"),
+		filepath.Join(data, "man", "man1", "tool.1"),
+	}
+	b := config.Get().Bins[filepath.Join(binDir, "tool")]
+	if b == nil || len(b.Extras) != len(extras) {
+		t.Fatalf("expected the extra files to be recorded, got %+v", b)
+	}
+	for _, p := range extras {
+		if _, err := os.Stat(p); err != nil {
+			t.Errorf("expected %s to be installed: %v", p, err)
+		}
+	}
+
+	Execute("test", func(code int) { t.Fatalf("remove exited with %d", code) }, []string{"remove", filepath.Join(binDir, "tool")})
+	for _, p := range extras {
+		if _, err := os.Stat(p); !os.IsNotExist(err) {
+			t.Errorf("expected %s to be removed along the binary", p)
+		}
+	}
+}
+
+func TestInstallHandler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handler of the test is a shell script")
+	}
+	dir := t.TempDir()
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		writeScript(t, filepath.Join(dir, "tool-"+v), v)
+	}
+	latest := filepath.Join(dir, "latest")
+	if err := os.WriteFile(latest, []byte("1.0.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	handlers := fmt.Sprintf(`{"corp://": {"latest": "cat %s", "download": "cp %s/tool-$BIN_VERSION $BIN_OUTPUT_DIR/tool"}}`, latest, dir)
+	_, binDir := newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "corp://team/tool"})
+	if err := os.WriteFile(latest, []byte("1.1.0\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", filepath.Join(binDir, "tool")})
+
+	out, err := exec.Command(filepath.Join(binDir, "tool")).Output()
+	if err != nil || string(out) != "1.1.0\n" {
+		t.Fatalf("expected the tool to be updated to 1.1.0, got %q (%v)", out, err)
+	}
+	b := config.Get().Bins[filepath.Join(binDir, "tool")]
+	if b == nil || b.URL != "corp://team/tool" || b.Version != "1.1.0" {
+		t.Errorf("unexpected configuration %+v", b)
+	}
+}
+
+func TestInstallPathAlias(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	binDir := versionedDemo(t, forge)
+	work := filepath.Join(demoDir(), "work")
+	conf := fmt.Sprintf(`{"default_path": %q, "paths": {"work": %q}}`, binDir, work)
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(conf), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "--path", "work", "http://" + demoHost + "/acme/tool"})
+	p := executablePath(filepath.Join(work, "tool"))
+	if _, err := os.Stat(p); err != nil {
+		t.Fatalf("expected the binary in the directory of the path: %v", err)
+	}
+	if b := config.Get().Bins[p]; b == nil || b.Target != "work" {
+		t.Fatalf("expected the binary to keep its path, got %+v", b)
+	}
+
+	// ensure creates the missing directory back
+	if err := os.RemoveAll(work); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("ensure exited with %d", code) }, []string{"ensure", "--yes"})
+	if _, err := os.Stat(p); err != nil {
+		t.Fatalf("expected the binary to be ensured in its directory: %v", err)
+	}
+
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"install", "--path", "nope", "http://" + demoHost + "/acme/tool"})
+	if code == 0 {
+		t.Fatal("expected an unknown path to fail the install")
+	}
+}
+
+// TestInstallDestination checks the destination of the binary
+// isn't taken for the path of the package in the release
+func TestInstallDestination(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	binDir := versionedDemo(t, forge)
+
+	p := executablePath(filepath.Join(binDir, "tl"))
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool", p})
+	if b, _ := os.ReadFile(p); string(b) != string(fakeforge.Script("tool", "v1.0.0")) {
+		t.Fatalf("expected the binary to be installed at %s, got %q", p, b)
+	}
+	if b := config.Get().Bins[p]; b == nil || b.PackagePath != "tool" {
+		t.Fatalf("expected the binary of the archive to be recorded, got %+v", b)
+	}
+}
@@ -2,12 +2,22 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"text/template"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/hashicorp/go-version"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/schema"
 	"github.com/spf13/cobra"
 )
 
@@ -20,7 +30,127 @@ func _rPad(s string, width int) string {
 }
 
 type listCmd struct {
-	cmd *cobra.Command
+	cmd  *cobra.Command
+	opts listOpts
+}
+
+type listOpts struct {
+	json bool
+	// format is a Go template executed for each binary
+	format string
+
+	columns    []string
+	wide       bool
+	noTruncate bool
+	sort       string
+}
+
+// listOutput is the JSON output of `bin list`, see pkg/schema/schemas/list.json
+type listOutput struct {
+	SchemaVersion int        `json:"schema_version"`
+	Bins          []listItem `json:"bins"`
+}
+
+type listItem struct {
+	Name    string `json:"name"`
+	Path    string `json:"path"`
+	Version string `json:"version"`
+	// Latest is the latest version found by the last update
+	// check, if any, it's empty when it's obsolete
+	Latest   string `json:"latest,omitempty"`
+	Hash     string `json:"hash"`
+	Kind     string `json:"kind"`
+	URL      string `json:"url"`
+	Provider string `json:"provider"`
+	Pinned   bool   `json:"pinned"`
+	Status   string `json:"status"`
+	Emulated string `json:"emulated,omitempty"`
+	// Modified is set when the file doesn't match
+	// the sha256 recorded when it was installed
+	Modified bool `json:"modified,omitempty"`
+	// Published is when the installed asset of a rolling
+	// tag (i.e. nightly) was published, the tag doesn't
+	// tell the builds apart
+	Published string `json:"published,omitempty"`
+	// Config is the configuration the binary comes
+	// from, only set in a project, see configOf
+	Config string `json:"config,omitempty"`
+}
+
+// listedBins returns the binaries to list: the ones of the configuration
+// in use and, in a project, the global ones it doesn't shadow
+func listedBins() map[string]*config.Binary {
+	bins := maps.Clone(config.Get().Bins)
+	maps.Copy(bins, config.GlobalBins())
+	return bins
+}
+
+// configOf returns the configuration the binary comes from,
+// project or global, empty outside of a project
+func configOf(path string) string {
+	if config.ProjectRoot() == "" {
+		return ""
+	}
+	if _, ok := config.Get().Bins[path]; ok {
+		return "project"
+	}
+	return "global"
+}
+
+// rollingPublished returns when the asset of a binary installed
+// from a rolling tag was published, see providers.AssetPublished
+func rollingPublished(b *config.Binary) (time.Time, bool) {
+	if !b.MutableTag && !providers.IsRollingTag(b.Version) {
+		return time.Time{}, false
+	}
+	return providers.AssetPublished(b.AssetDigest)
+}
+
+// kind returns the kind of the entry, binary when it's not set
+func kind(b *config.Binary) string {
+	if b.Kind == "" {
+		return config.KindBinary
+	}
+	return b.Kind
+}
+
+// listJSON builds the JSON output of the given binaries, the
+// latest versions come from the results of the update checks
+func listJSON(bins map[string]*config.Binary, binPaths []string, checks *checkCache) listOutput {
+	out := listOutput{SchemaVersion: schema.Version, Bins: []listItem{}}
+	for _, k := range binPaths {
+		b := bins[k]
+		p := os.ExpandEnv(b.Path)
+		status, modified := "ok", false
+		if _, err := os.Stat(p); err != nil {
+			status = "missing"
+		} else if modified, err = isModified(b); err != nil {
+			binLog(b).WithError(err).Warn("Unable to check whether the binary was modified")
+		}
+		latest := ""
+		if r, ok := checks.Bins[k]; ok && r.Installed == b.Version {
+			latest = r.Latest
+		}
+		out.Bins = append(out.Bins, listItem{
+			Name:     filepath.Base(p),
+			Path:     p,
+			Version:  b.Version,
+			Latest:   latest,
+			Hash:     b.Hash,
+			Kind:     kind(b),
+			URL:      b.URL,
+			Provider: b.Provider,
+			Pinned:   b.Pinned,
+			Status:   status,
+			Emulated: b.Emulated,
+			Modified: modified,
+			Config:   configOf(k),
+		})
+		if t, ok := rollingPublished(b); ok {
+			out.Bins[len(out.Bins)-1].Published = t.UTC().Format(time.RFC3339)
+		}
+	}
+	return out
 }
 
 func newListCmd() *listCmd {
@@ -33,67 +163,251 @@ func newListCmd() *listCmd {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cfg := config.Get()
+			bins := listedBins()
 
 			binPaths := []string{}
-			for k := range cfg.Bins {
+			for k := range bins {
 				binPaths = append(binPaths, k)
 			}
 			sort.Strings(binPaths)
+			if err := sortListPaths(bins, binPaths, root.opts.sort); err != nil {
+				return err
+			}
 
-			// Calculate maximum length of each column
-			maxLengths := make([]int, 3)
-			for _, k := range binPaths {
-				b := cfg.Bins[k]
-				p := os.ExpandEnv(b.Path)
-
-				if len(p) > maxLengths[0] {
-					maxLengths[0] = len(p)
+			if root.opts.json || root.opts.format != "" {
+				if root.opts.json && root.opts.format != "" {
+					return fmt.Errorf("--json and --format can't be used together")
 				}
-
-				versionLength := len(b.Version)
-				if b.Pinned {
-					versionLength++ // for the '*' char
+				checks, err := loadCheckCache()
+				if err != nil {
+					log.Debugf("Error reading the update checks: %v", err)
+					checks = &checkCache{Bins: map[string]*checkResult{}}
 				}
-				if versionLength > maxLengths[1] {
-					maxLengths[1] = versionLength
+				out := listJSON(bins, binPaths, checks)
+				if root.opts.json {
+					return writeJSON(os.Stdout, out)
 				}
+				return writeFormat(os.Stdout, root.opts.format, out.Bins)
+			}
 
-				if len(b.URL) > maxLengths[2] {
-					maxLengths[2] = len(b.URL)
-				}
+			columns, err := root.opts.listColumns()
+			if err != nil {
+				return err
+			}
+			rows := listRows(bins, binPaths, columns)
+
+			width := terminalWidth()
+			if root.opts.noTruncate || root.opts.wide {
+				width = 0
 			}
+			header := make([]tableColumn, len(columns))
+			for i, c := range columns {
+				header[i] = listColumnDefs[c]
+			}
+			printTable(os.Stdout, header, rows, width, color.New(color.FgMagenta, color.Italic).Sprint)
+			return nil
+		},
+	}
 
-			pL, vL, uL := maxLengths[0], maxLengths[1], maxLengths[2]
-			magentaItalic := color.New(color.FgMagenta, color.Italic).Sprint
-			p, v, u, s := magentaItalic(_rPad(("Path"), pL)), magentaItalic(_rPad("Version", vL)), magentaItalic(_rPad("URL", uL)), magentaItalic("Status")
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print the binaries as JSON, see `bin schema list`")
+	root.cmd.Flags().StringVar(&root.opts.format, "format", "", "Print each binary with a Go template, i.e. '{{.Name}} {{.Version}}', the fields are the ones of --json")
+	root.cmd.Flags().StringSliceVar(&root.opts.columns, "columns", nil, "Columns to print, in order, among "+strings.Join(allListColumns, ",")+" (default "+strings.Join(defaultListColumns, ",")+")")
+	root.cmd.Flags().BoolVar(&root.opts.wide, "wide", false, "Print every column, without truncating them")
+	root.cmd.Flags().BoolVar(&root.opts.noTruncate, "no-truncate", false, "Don't truncate the columns to the width of the terminal")
+	root.cmd.Flags().StringVar(&root.opts.sort, "sort", "path", "Sort the binaries by path, name, version or age (newest first)")
+	return root
+}
 
-			fmt.Printf("%s  %s  %s  %s", p, v, u, s)
+// writeFormat prints the binaries with the template, once per binary.
+// The fields are the ones of the JSON output, named like the Go ones
+// (i.e. {{.Name}} or {{.Latest}}).
+func writeFormat(w io.Writer, format string, items []listItem) error {
+	tmpl, err := template.New("format").Option("missingkey=error").Parse(format)
+	if err != nil {
+		return fmt.Errorf("invalid --format: %w", err)
+	}
+	for _, item := range items {
+		if err := tmpl.Execute(w, item); err != nil {
+			return fmt.Errorf("invalid --format: %w", err)
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// listColumnDefs are the columns of `bin list` by name, the
+// URLs and paths are truncated on narrow terminals
+var listColumnDefs = map[string]tableColumn{
+	"name":    {header: "Name"},
+	"path":    {header: "Path", truncate: true},
+	"version": {header: "Version"},
+	"kind":    {header: "Kind"},
+	"url":     {header: "URL", truncate: true},
+	"age":     {header: "Age"},
+	"pinned":  {header: "Pinned"},
+	"status":  {header: "Status"},
+	"config":  {header: "Config"},
+}
 
-			for _, k := range binPaths {
-				b := cfg.Bins[k]
+var (
+	defaultListColumns = []string{"path", "version", "kind", "url", "status"}
+	allListColumns     = []string{"name", "path", "version", "kind", "url", "age", "pinned", "status", "config"}
+)
 
-				p := os.ExpandEnv(b.Path)
+// listColumns returns the names of the columns to print, source
+// is accepted as an alias of url. In a project, the config
+// column is printed by default
+func (o *listOpts) listColumns() ([]string, error) {
+	if o.wide && len(o.columns) > 0 {
+		return nil, fmt.Errorf("--wide prints every column, it can't be used with --columns")
+	}
+	if o.wide {
+		return allListColumns, nil
+	}
+	if len(o.columns) == 0 {
+		if config.ProjectRoot() != "" {
+			return append(slices.Clone(defaultListColumns), "config"), nil
+		}
+		return defaultListColumns, nil
+	}
+	columns := make([]string, 0, len(o.columns))
+	for _, c := range o.columns {
+		c = strings.ToLower(strings.TrimSpace(c))
+		if c == "source" {
+			c = "url"
+		}
+		if _, ok := listColumnDefs[c]; !ok {
+			return nil, fmt.Errorf("unknown column %q, use some of %s", c, strings.Join(allListColumns, ","))
+		}
+		columns = append(columns, c)
+	}
+	return columns, nil
+}
 
-				_, err := os.Stat(p)
+// listRows returns the cells of the columns for the binaries
+func listRows(bins map[string]*config.Binary, binPaths []string, columns []string) [][]tableCell {
+	rows := make([][]tableCell, 0, len(binPaths))
+	for _, k := range binPaths {
+		b := bins[k]
+		p := os.ExpandEnv(b.Path)
+		fi, err := os.Stat(p)
+		// the files are only hashed when their status is printed
+		modified := false
+		if err == nil && slices.Contains(columns, "status") {
+			var herr error
+			if modified, herr = isModified(b); herr != nil {
+				binLog(b).WithError(herr).Warn("Unable to check whether the binary was modified")
+			}
+		}
 
-				status := color.GreenString("OK")
-				if err != nil {
-					status = color.RedString("missing %s", p)
+		row := make([]tableCell, len(columns))
+		for i, c := range columns {
+			switch c {
+			case "name":
+				row[i] = tableCell{text: filepath.Base(p)}
+			case "path":
+				row[i] = tableCell{text: p}
+			case "version":
+				v := b.Version
+				if t, ok := rollingPublished(b); ok {
+					v = fmt.Sprintf("%s (%s)", v, t.Local().Format("2006-01-02 15:04"))
 				}
-
 				if b.Pinned {
-					fmt.Printf("\n%s  %s  %s  %s", _rPad(p, pL), _rPad("*"+b.Version, vL), _rPad(b.URL, uL), status)
-					continue
+					v = "*" + v
+				}
+				row[i] = tableCell{text: v}
+			case "kind":
+				row[i] = tableCell{text: kind(b)}
+			case "url":
+				row[i] = tableCell{text: b.URL}
+			case "age":
+				row[i] = tableCell{text: "-"}
+				if err == nil {
+					row[i] = tableCell{text: formatAge(time.Since(fi.ModTime()))}
+				}
+			case "pinned":
+				if b.Pinned {
+					row[i] = tableCell{text: "yes"}
+				}
+			case "config":
+				row[i] = tableCell{text: configOf(k)}
+			case "status":
+				switch {
+				case err != nil:
+					row[i] = tableCell{text: fmt.Sprintf("missing %s", p), color: color.New(color.FgRed).Sprint}
+				case modified:
+					row[i] = tableCell{text: "modified (sha256 mismatch)", color: color.New(color.FgYellow).Sprint}
+				case b.Emulated != "":
+					row[i] = tableCell{text: fmt.Sprintf("OK (%s, %s)", b.Emulated, config.Emulation()), color: color.New(color.FgYellow).Sprint}
+				default:
+					row[i] = tableCell{text: "OK", color: color.New(color.FgGreen).Sprint}
 				}
-
-				fmt.Printf("\n%s  %s  %s  %s", _rPad(p, pL), _rPad(b.Version, vL), _rPad(b.URL, uL), status)
 			}
-			fmt.Print("\n")
-			return nil
-		},
+		}
+		rows = append(rows, row)
 	}
+	return rows
+}
 
-	root.cmd = cmd
-	return root
+// formatAge formats the age of an install with its largest unit
+func formatAge(d time.Duration) string {
+	const day = 24 * time.Hour
+	switch {
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 2*day:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	case d < 60*day:
+		return fmt.Sprintf("%dd", int(d/day))
+	case d < 730*day:
+		return fmt.Sprintf("%dmo", int(d/(30*day)))
+	}
+	return fmt.Sprintf("%dy", int(d/(365*day)))
+}
+
+// sortListPaths sorts the binaries, already sorted by path, by the
+// given key. Binaries with the same key keep their order, the ones
+// whose version can't be parsed or whose file is missing come last.
+func sortListPaths(bins map[string]*config.Binary, binPaths []string, key string) error {
+	switch key {
+	case "", "path":
+	case "name":
+		sort.SliceStable(binPaths, func(i, j int) bool {
+			return filepath.Base(os.ExpandEnv(bins[binPaths[i]].Path)) < filepath.Base(os.ExpandEnv(bins[binPaths[j]].Path))
+		})
+	case "version":
+		versions := map[string]*version.Version{}
+		for _, k := range binPaths {
+			if v, err := version.NewVersion(bins[k].Version); err == nil {
+				versions[k] = v
+			}
+		}
+		sort.SliceStable(binPaths, func(i, j int) bool {
+			vi, vj := versions[binPaths[i]], versions[binPaths[j]]
+			if vi == nil || vj == nil {
+				return vi != nil
+			}
+			return vi.LessThan(vj)
+		})
+	case "age":
+		installed := map[string]time.Time{}
+		for _, k := range binPaths {
+			if fi, err := os.Stat(os.ExpandEnv(bins[k].Path)); err == nil {
+				installed[k] = fi.ModTime()
+			}
+		}
+		sort.SliceStable(binPaths, func(i, j int) bool {
+			ti, tj := installed[binPaths[i]], installed[binPaths[j]]
+			if ti.IsZero() || tj.IsZero() {
+				return !ti.IsZero()
+			}
+			return ti.After(tj)
+		})
+	default:
+		return fmt.Errorf("invalid sort %q, use path, name, version or age", key)
+	}
+	return nil
 }
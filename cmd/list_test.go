@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestListGolden(t *testing.T) {
+	bins := map[string]*config.Binary{
+		"/opt/bin/gh":   {Path: "/opt/bin/gh", Version: "v2.40.0", Hash: "6a1f", URL: "https://github.com/cli/cli", Provider: "github"},
+		"/opt/bin/kind": {Path: "/opt/bin/kind", Version: "v0.20.0", Hash: "b3c2", URL: "https://github.com/kubernetes-sigs/kind", Provider: "github", Pinned: true},
+		"/opt/bin/nvim": {Path: "/opt/bin/nvim", Version: "nightly", Hash: "c4d5", URL: "https://github.com/neovim/neovim/releases/tag/nightly", Provider: "github", AssetDigest: "1@2024-01-02T03:04:05Z"},
+	}
+	checks := &checkCache{Bins: map[string]*checkResult{
+		"/opt/bin/gh":   {Installed: "v2.40.0", Latest: "v2.41.0"},
+		"/opt/bin/kind": {Installed: "v0.19.0", Latest: "v0.20.0"},
+	}}
+	out := listJSON(bins, []string{"/opt/bin/gh", "/opt/bin/kind", "/opt/bin/nvim"}, checks)
+
+	var b bytes.Buffer
+	if err := writeJSON(&b, out); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "list.json.golden", b.Bytes())
+
+	b.Reset()
+	if err := writeFormat(&b, "{{.Name}} {{.Version}}{{if .Latest}} -> {{.Latest}}{{end}}{{if .Pinned}} (pinned){{end}}", out.Bins); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "list-format.golden", b.Bytes())
+
+	if err := writeFormat(&b, "{{.Nope}}", out.Bins); err == nil {
+		t.Error("expected an unknown field to fail")
+	}
+}
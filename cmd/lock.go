@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// digestlessProviders don't download a release asset,
+// only the version of their binaries is locked
+var digestlessProviders = []string{"docker", "goinstall", "local"}
+
+type lockCmd struct {
+	cmd *cobra.Command
+}
+
+func newLockCmd() *lockCmd {
+	root := &lockCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "lock",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Writes the lockfile pinning the artifacts of the installed binaries",
+		Long:          "Writes the lockfile pinning the exact version, asset and asset digest of the installed binaries, so `bin ensure --locked` installs the same artifacts on other machines. It's kept up to date by the installs and updates.",
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cfg := config.Get()
+			paths := make([]string, 0, len(cfg.Bins))
+			for p, b := range cfg.Bins {
+				if b.AssetSHA256 == "" && !slices.Contains(digestlessProviders, b.Provider) {
+					paths = append(paths, os.ExpandEnv(p))
+				}
+			}
+			sort.Strings(paths)
+			for _, p := range paths {
+				log.WithField("path", p).Warn("The asset digest isn't known, update or reinstall the binary so it can be locked")
+			}
+
+			if err := config.WriteLock(); err != nil {
+				return err
+			}
+			p, err := config.GetLockPath()
+			if err != nil {
+				return err
+			}
+			log.WithField("binaries", len(cfg.Bins)).WithField("path", p).Info("Locked")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
+
+// lockedBinaries returns the binaries of the lockfile to ensure, all of
+// them unless some are given, along with their locked artifact. The
+// configured binaries missing from the lockfile can't be ensured.
+func lockedBinaries(args []string) (map[string]*config.Binary, map[*config.Binary]*config.LockedBinary, error) {
+	l, err := config.LoadLock()
+	if err != nil {
+		return nil, nil, err
+	}
+	locked := l.Locked()
+	bins := make(map[string]*config.Binary, len(locked))
+	for b := range locked {
+		bins[b.Path] = b
+	}
+
+	if len(args) == 0 {
+		for p := range config.Get().Bins {
+			if _, ok := bins[p]; !ok {
+				return nil, nil, fmt.Errorf("%s isn't in the lockfile, run `bin lock` to add it", os.ExpandEnv(p))
+			}
+		}
+		return bins, locked, nil
+	}
+	picked := map[string]*config.Binary{}
+	for _, a := range args {
+		if isPattern(a) {
+			keys, err := globBins(bins, a)
+			if err != nil {
+				return nil, nil, err
+			}
+			if len(keys) == 0 {
+				return nil, nil, fmt.Errorf("no binary of the lockfile matches %s", a)
+			}
+			for _, k := range keys {
+				picked[k] = bins[k]
+			}
+			continue
+		}
+		p, err := getBinPath(a)
+		if err != nil {
+			// binaries which aren't installed yet are only in the lockfile
+			for lp := range bins {
+				if filepath.Base(lp) == a || lp == a {
+					p, err = lp, nil
+				}
+			}
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		b, ok := bins[p]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s isn't in the lockfile, run `bin lock` to add it", os.ExpandEnv(p))
+		}
+		picked[p] = b
+	}
+	return picked, locked, nil
+}
+
+// ensureLockedBinary installs the locked artifact of the binary,
+// unless it's already installed from it and wasn't modified
+func ensureLockedBinary(b *config.Binary, lb *config.LockedBinary, cache *assets.DownloadCache) (*ensureResult, error) {
+	ep := os.ExpandEnv(b.Path)
+	if lb.Asset != "" && lb.Platform != config.Platform() {
+		return nil, fmt.Errorf("%s is locked to an asset for %s, not %s", ep, lb.Platform, config.Platform())
+	}
+	if lb.SHA256 == "" {
+		if !slices.Contains(digestlessProviders, lb.Provider) {
+			return nil, fmt.Errorf("no asset digest is locked for %s, it can't be verified", ep)
+		}
+		binLog(b).WithField("version", lb.Version).Warn("Only the version is locked with this provider")
+	}
+
+	if _, err := os.Stat(ep); err == nil && b.URL == lb.URL && b.Version == lb.Version && b.AssetSHA256 == lb.SHA256 {
+		modified, err := isModified(b)
+		if err != nil {
+			return nil, err
+		}
+		if !modified {
+			return nil, nil
+		}
+		binLog(b).Info("The hash doesn't match the configuration, installing the locked artifact again")
+	}
+
+	nb, file, err := installPinned(b, lb, cache)
+	if err != nil {
+		return nil, err
+	}
+	return &ensureResult{bin: nb, file: file}, nil
+}
+
+// lockedBinary returns the binary installed from its locked artifact
+func lockedBinary(b *config.Binary, lb *config.LockedBinary) *config.Binary {
+	nb := *b
+	nb.URL, nb.Provider, nb.Version, nb.PackagePath = lb.URL, lb.Provider, lb.Version, lb.PackagePath
+	return &nb
+}
+
+// selectedAsset returns the asset to pick: the locked one, if
+// any, or the one previously picked by the user
+func selectedAsset(b *config.Binary, lb *config.LockedBinary) string {
+	if lb != nil && lb.Asset != "" {
+		return lb.Asset
+	}
+	return b.SelectedAsset
+}
+
+// checkLocked makes sure the fetched file comes from the locked
+// artifact, lb can be nil when the binary isn't installed locked
+func checkLocked(path string, lb *config.LockedBinary, f *providers.File) error {
+	switch {
+	case lb == nil:
+		return nil
+	case lb.Asset != "" && f.Asset != lb.Asset:
+		return fmt.Errorf("the locked asset %s of %s %s isn't published anymore, got %s", lb.Asset, path, lb.Version, f.Asset)
+	case lb.SHA256 != "" && f.AssetSHA256 != lb.SHA256:
+		return fmt.Errorf("%s %s changed upstream: %s has the sha256 %s, %s is locked", path, lb.Version, f.Asset, f.AssetSHA256, lb.SHA256)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestEnsureLocked(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handler of the test is a shell script")
+	}
+	dir := t.TempDir()
+	upstream := filepath.Join(dir, "tool-1.0.0")
+	content := writeScript(t, upstream, "1.0.0")
+	handlers := fmt.Sprintf(`{"corp://": {"latest": "echo 1.0.0", "download": "cp %s/tool-$BIN_VERSION $BIN_OUTPUT_DIR/tool"}}`, dir)
+	_, binDir := newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+	tool := filepath.Join(binDir, "tool")
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "corp://team/tool"})
+	l, err := config.LoadLock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var locked *config.LockedBinary
+	for b, lb := range l.Locked() {
+		if b.Path == tool {
+			locked = lb
+		}
+	}
+	want := fmt.Sprintf("%x", sha256.Sum256(content))
+	if locked == nil || locked.Version != "1.0.0" || locked.Asset != "tool" || locked.SHA256 != want {
+		t.Fatalf("expected the tool to be locked to the 1.0.0 asset with the sha256 %s, got %+v", want, locked)
+	}
+
+	// the binary is installed again from the locked artifact
+	if err := os.Remove(tool); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("ensure exited with %d", code) }, []string{"ensure", "--locked", tool})
+	if out, err := exec.Command(tool).Output(); err != nil || string(out) != "1.0.0\n" {
+		t.Fatalf("expected the locked tool to be installed, got %q (%v)", out, err)
+	}
+
+	// a re-published asset doesn't match the lockfile anymore
+	writeScript(t, upstream, "evil")
+	if err := os.Remove(tool); err != nil {
+		t.Fatal(err)
+	}
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--locked", tool})
+	if code == 0 {
+		t.Error("expected an asset changed upstream to fail the locked ensure")
+	}
+	if _, err := os.Stat(tool); !os.IsNotExist(err) {
+		t.Errorf("expected the changed asset not to be installed, got %v", err)
+	}
+	if l, err := config.LoadLock(); err != nil || len(l.Bins) == 0 {
+		t.Errorf("expected the lockfile to be kept as is, got %+v (%v)", l, err)
+	}
+}
@@ -0,0 +1,262 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/spf13/cobra"
+)
+
+type migratePathCmd struct {
+	cmd  *cobra.Command
+	opts migratePathOpts
+}
+
+type migratePathOpts struct {
+	from   string
+	to     string
+	dryRun bool
+}
+
+// pathMove is the move of a managed binary, key
+// is the one of the binary in the configuration
+type pathMove struct {
+	b        *config.Binary
+	key      string
+	from, to string
+}
+
+func newMigratePathCmd() *migratePathCmd {
+	root := &migratePathCmd{}
+
+	cmd := &cobra.Command{
+		Use:         "migrate-path --from <directory> --to <directory>",
+		Annotations: map[string]string{writesConfig: "true"},
+		Short:       "Moves the binaries managed in a directory to another one",
+		Long: `Moves the binaries managed in a directory to another one.
+
+The files are moved, their recorded paths rewritten and their digests
+verified once moved. The files already moved by hand are only checked.
+The default path follows the move when it's the migrated directory.`,
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			from, err := expandDir(root.opts.from)
+			if err != nil {
+				return err
+			}
+			to, err := expandDir(root.opts.to)
+			if err != nil {
+				return err
+			}
+			if from == to {
+				return errors.New("--from and --to are the same directory")
+			}
+
+			moves, err := pathMoves(from, to)
+			if err != nil {
+				return err
+			}
+			for _, m := range moves {
+				log.WithField("from", m.from).WithField("path", m.to).Info("Move")
+			}
+			if root.opts.dryRun {
+				return nil
+			}
+
+			if err := os.MkdirAll(to, 0o755); err != nil {
+				return err
+			}
+			done := map[string]string{}
+			var moveErr error
+			for _, m := range moves {
+				if moveErr = moveBinary(m); moveErr != nil {
+					break
+				}
+				done[m.key] = m.to
+			}
+
+			// the moved binaries are recorded even if one failed
+			defaultPath := ""
+			if dp, err := expandDir(config.Get().DefaultPath); err == nil && dp == from {
+				defaultPath = root.opts.to
+			}
+			if err := config.MovePaths(done, defaultPath); err != nil {
+				return errors.Join(moveErr, err)
+			}
+			if moveErr != nil {
+				return moveErr
+			}
+			log.WithField("binaries", len(done)).WithField("path", to).Info("Moved")
+			warnPathOrder(from, to)
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().StringVar(&root.opts.from, "from", "", "Directory the binaries are managed in")
+	root.cmd.Flags().StringVar(&root.opts.to, "to", "", "Directory to move them to")
+	root.cmd.Flags().BoolVar(&root.opts.dryRun, "dry-run", false, "Only show the moves")
+	_ = root.cmd.MarkFlagRequired("from")
+	_ = root.cmd.MarkFlagRequired("to")
+	return root
+}
+
+// expandDir returns the absolute and clean path of
+// the directory, with its variables and ~ expanded
+func expandDir(dir string) (string, error) {
+	dir = os.ExpandEnv(dir)
+	if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || os.IsPathSeparator(rest[0])) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		dir = home + rest
+	}
+	return filepath.Abs(dir)
+}
+
+// pathMoves returns the moves of the binaries managed in from. It
+// fails before anything is moved when one of them can't be.
+func pathMoves(from, to string) ([]*pathMove, error) {
+	moves := []*pathMove{}
+	for p, b := range config.Get().Bins {
+		rel, err := filepath.Rel(from, os.ExpandEnv(p))
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		moves = append(moves, &pathMove{b: b, key: p, from: os.ExpandEnv(p), to: filepath.Join(to, rel)})
+	}
+	if len(moves) == 0 {
+		return nil, fmt.Errorf("no binary is managed in %s", from)
+	}
+	sort.Slice(moves, func(i, j int) bool { return moves[i].from < moves[j].from })
+
+	for _, m := range moves {
+		_, srcErr := os.Lstat(m.from)
+		_, dstErr := os.Lstat(m.to)
+		if srcErr == nil && dstErr == nil {
+			return nil, fmt.Errorf("%s already exists, can't move %s", m.to, m.from)
+		}
+	}
+	return moves, nil
+}
+
+// moveBinary moves the file of the binary, along with its usage
+// statistics shim and its versions in the store, and verifies its
+// digest once moved. Files already moved, or missing, only get their
+// path rewritten.
+func moveBinary(m *pathMove) error {
+	if _, err := os.Lstat(m.from); os.IsNotExist(err) {
+		if _, err := os.Stat(m.to); os.IsNotExist(err) {
+			log.WithField("path", m.from).Warn("The binary is missing, only its path is rewritten")
+			return nil
+		}
+	} else {
+		shimmed := stats.IsShim(m.from)
+		shims, err := statsShims()
+		if err != nil {
+			return err
+		}
+		if shimmed {
+			if err := shims.Disable(m.from); err != nil {
+				return err
+			}
+		}
+		st, err := versionStore()
+		if err != nil {
+			return err
+		}
+		active, inStore := st.Active(m.from)
+		if err := os.MkdirAll(filepath.Dir(m.to), 0o755); err != nil {
+			return err
+		}
+		if err := moveFile(m.from, m.to); err != nil {
+			return fmt.Errorf("error moving %s: %w", m.from, err)
+		}
+		if inStore {
+			// the path points to the active version of the moved store
+			moved, err := st.Move(m.from, m.to, active)
+			if err != nil {
+				return fmt.Errorf("error moving the versions of %s: %w", m.from, err)
+			}
+			if err := st.Activate(moved, m.to); err != nil {
+				return fmt.Errorf("error activating %s: %w", m.to, err)
+			}
+		}
+		if shimmed {
+			if err := shims.Enable(m.to); err != nil {
+				log.WithField("path", m.to).WithError(err).Warn("Error restoring the usage statistics shim")
+			}
+		}
+	}
+
+	moved := *m.b
+	moved.Path = m.to
+	modified, err := isModified(&moved)
+	if err != nil {
+		return err
+	}
+	if modified {
+		return fmt.Errorf("the digest of %s doesn't match its recorded hash once moved", m.to)
+	}
+	return nil
+}
+
+// moveFile renames the file, copying it when
+// the directories are on different devices
+func moveFile(from, to string) error {
+	err := os.Rename(from, to)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+	fi, err := os.Stat(from)
+	if err != nil {
+		return err
+	}
+	src, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	dst, err := os.OpenFile(to, os.O_WRONLY|os.O_CREATE|os.O_EXCL, fi.Mode())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		return errors.Join(err, dst.Close(), os.Remove(to))
+	}
+	if err := dst.Close(); err != nil {
+		return errors.Join(err, os.Remove(to))
+	}
+	return os.Remove(from)
+}
+
+// warnPathOrder warns when the binaries moved to dir won't
+// be found through PATH, or the previous directory shadows it
+func warnPathOrder(previous, dir string) {
+	paths := filepath.SplitList(os.Getenv("PATH"))
+	for i, p := range paths {
+		paths[i] = filepath.Clean(p)
+	}
+	to, from := slices.Index(paths, dir), slices.Index(paths, previous)
+	switch {
+	case to < 0:
+		log.WithField("path", dir).Warn("The directory isn't in your PATH, add it so the moved binaries are found (i.e. with `bin doctor --fix-path`)")
+	case from >= 0 && from < to:
+		log.WithField("path", dir).WithField("previous", previous).Warn("The previous directory comes first in your PATH, remove it so it doesn't shadow the moved binaries")
+	case from >= 0:
+		log.WithField("previous", previous).Warn("The previous directory is still in your PATH, it can be removed")
+	}
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestMigratePath(t *testing.T) {
+	dir, oldDir := newTestConfig(t, `"require_checksum": false, "relative_paths": true`)
+	newDir, conf := filepath.Join(dir, "local", "bin"), filepath.Join(dir, "config.json")
+	src := filepath.Join(dir, "tool")
+	writeScript(t, src, "tool")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src})
+
+	raw, err := os.ReadFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(raw), `"path": "tool"`) {
+		t.Errorf("expected the path to be written relative to the default path, got:\n%s", raw)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("migrate-path exited with %d", code) }, []string{"migrate-path", "--from", oldDir, "--to", newDir})
+	if _, err := os.Stat(filepath.Join(newDir, "tool")); err != nil {
+		t.Fatalf("expected the tool to be moved: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(oldDir, "tool")); !os.IsNotExist(err) {
+		t.Errorf("expected the tool to be removed from %s, got %v", oldDir, err)
+	}
+	cfg := config.Get()
+	if b := cfg.Bins[filepath.Join(newDir, "tool")]; b == nil || b.Path != filepath.Join(newDir, "tool") {
+		t.Errorf("expected the path of the tool to be rewritten, got %+v", b)
+	}
+	if _, ok := cfg.Bins[filepath.Join(oldDir, "tool")]; ok {
+		t.Error("expected the previous path to be dropped")
+	}
+	if cfg.DefaultPath != newDir {
+		t.Errorf("expected the default path to follow the move, got %s", cfg.DefaultPath)
+	}
+}
+
+func TestCheckDirectories(t *testing.T) {
+	moved := filepath.Join(t.TempDir(), "moved")
+	newTestConfig(t, fmt.Sprintf(`"bins": {%q: {"path": %q}}`, filepath.Join(moved, "tool"), filepath.Join(moved, "tool")))
+	if err := config.CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := fmt.Sprintf("bin migrate-path --from %s --to <directory>", moved)
+	for _, p := range checkDirectories() {
+		if strings.Contains(p, want) {
+			return
+		}
+	}
+	t.Errorf("expected %s to be suggested, got %v", want, checkDirectories())
+}
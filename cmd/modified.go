@@ -0,0 +1,98 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/prompt"
+	"github.com/marcosnils/bin/pkg/stats"
+)
+
+// isModified checks if the binary on disk isn't the one installed by
+// bin anymore (i.e. it was patched or wrapped). Missing binaries and
+// the ones without a recorded hash aren't considered modified.
+func isModified(b *config.Binary) (bool, error) {
+	if b.Hash == "" {
+		return false, nil
+	}
+	sum, err := fileSHA256(stats.Resolve(os.ExpandEnv(b.Path)))
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return sum != b.Hash, nil
+}
+
+// fileSHA256 returns the sha256 of the file, as recorded in the hash of the binaries
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
+}
+
+// guardModified protects the local modifications of the binary before
+// it gets replaced. Overwriting them requires either overwrite or the
+// confirmation of the user, who's offered to keep a copy aside.
+func guardModified(b *config.Binary, overwrite bool) error {
+	modified, err := isModified(b)
+	if err != nil || !modified {
+		return err
+	}
+
+	p := os.ExpandEnv(b.Path)
+	binLog(b).Warn("Modified since it was installed")
+	if overwrite {
+		return saveModified(p)
+	}
+	if !prompt.IsInteractive() {
+		return fmt.Errorf("%s was modified locally, use --overwrite-modified to replace it", p)
+	}
+	if err := prompt.Confirm(fmt.Sprintf("Replace the modified %s?", p)); err != nil {
+		return err
+	}
+	if err := prompt.Confirm(fmt.Sprintf("Keep a copy of it as %s.local?", p)); err != nil {
+		return nil
+	}
+	return saveModified(p)
+}
+
+// saveModified copies the modified binary aside as `<name>.local`
+func saveModified(p string) error {
+	src, err := os.Open(stats.Resolve(p))
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	fi, err := src.Stat()
+	if err != nil {
+		return err
+	}
+	dst, err := os.OpenFile(p+".local", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, fi.Mode().Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(dst, src); err != nil {
+		dst.Close()
+		return err
+	}
+	if err := dst.Close(); err != nil {
+		return err
+	}
+	log.WithField("path", p).WithField("saved", p+".local").Info("Saved the modified binary")
+	return nil
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestGuardModified(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(p, []byte("original"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := &config.Binary{Path: p, Hash: fmt.Sprintf("%x", sha256.Sum256([]byte("original")))}
+
+	if modified, err := isModified(b); err != nil || modified {
+		t.Fatalf("expected the binary not to be modified, got %v (%v)", modified, err)
+	}
+	if err := guardModified(b, false); err != nil {
+		t.Fatalf("expected unmodified binaries to be replaced, got %v", err)
+	}
+
+	if err := os.WriteFile(p, []byte("patched"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if modified, err := isModified(b); err != nil || !modified {
+		t.Fatalf("expected the binary to be modified, got %v (%v)", modified, err)
+	}
+	// tests aren't interactive
+	if err := guardModified(b, false); err == nil {
+		t.Fatal("expected modified binaries not to be replaced without --overwrite-modified")
+	}
+	if _, err := os.Stat(p + ".local"); !os.IsNotExist(err) {
+		t.Fatalf("expected no copy of the modified binary, got %v", err)
+	}
+
+	if err := guardModified(b, true); err != nil {
+		t.Fatal(err)
+	}
+	content, err := os.ReadFile(p + ".local")
+	if err != nil || string(content) != "patched" {
+		t.Fatalf("expected a copy of the modified binary, got %q (%v)", content, err)
+	}
+
+	// missing binaries and the ones installed without hash can be replaced
+	for _, nb := range []*config.Binary{{Path: p + ".missing", Hash: b.Hash}, {Path: p}} {
+		if modified, err := isModified(nb); err != nil || modified {
+			t.Errorf("%s: expected the binary not to be modified, got %v (%v)", nb.Path, modified, err)
+		}
+	}
+}
+
+func TestListModified(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "tool")
+	if err := os.WriteFile(p, []byte("patched"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bins := map[string]*config.Binary{
+		p:              {Path: p, Hash: fmt.Sprintf("%x", sha256.Sum256([]byte("original")))},
+		p + ".missing": {Path: p + ".missing", Hash: fmt.Sprintf("%x", sha256.Sum256([]byte("original")))},
+	}
+
+	out := listJSON(bins, []string{p, p + ".missing"}, &checkCache{})
+	if !out.Bins[0].Modified || out.Bins[0].Status != "ok" {
+		t.Errorf("expected %s to be flagged as modified, got %+v", p, out.Bins[0])
+	}
+	if out.Bins[1].Modified || out.Bins[1].Status != "missing" {
+		t.Errorf("expected missing binaries not to be flagged as modified, got %+v", out.Bins[1])
+	}
+	rows := listRows(bins, []string{p}, []string{"status"})
+	if rows[0][0].text != "modified (sha256 mismatch)" {
+		t.Errorf("expected the status to report the mismatch, got %q", rows[0][0].text)
+	}
+}
@@ -0,0 +1,307 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+// checkResult is the outcome of the last update check of a binary
+type checkResult struct {
+	// Installed is the version installed when it was checked,
+	// the result is obsolete once the binary is updated
+	Installed string `json:"installed"`
+	Latest    string `json:"latest"`
+	// URL is the release of the latest version, when it's newer
+	URL       string    `json:"url,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// checkCache persists the results of the update checks so they
+// can be reported without hitting the network
+type checkCache struct {
+	Bins map[string]*checkResult `json:"bins"`
+}
+
+func checkCachePath() (string, error) {
+	dir, err := config.GetCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "checks.json"), nil
+}
+
+// loadCheckCache reads the results of the previous update checks
+func loadCheckCache() (*checkCache, error) {
+	c := &checkCache{Bins: map[string]*checkResult{}}
+	p, err := checkCachePath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, c); err != nil {
+		return nil, err
+	}
+	if c.Bins == nil {
+		c.Bins = map[string]*checkResult{}
+	}
+	return c, nil
+}
+
+// recordChecks stores the results of the update checks. The cache is
+// only an optimization so errors are logged and ignored.
+func recordChecks(results map[string]*checkResult) {
+	if err := saveChecks(results); err != nil {
+		log.Debugf("Error recording the update checks: %v", err)
+	}
+}
+
+func saveChecks(results map[string]*checkResult) error {
+	c, err := loadCheckCache()
+	if err != nil {
+		c = &checkCache{Bins: map[string]*checkResult{}}
+	}
+	for p, r := range results {
+		c.Bins[p] = r
+	}
+	// forget the binaries which aren't managed anymore
+	for p := range c.Bins {
+		if _, ok := config.Get().Bins[p]; !ok {
+			delete(c.Bins, p)
+		}
+	}
+
+	p, err := checkCachePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		return err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, b, 0o644)
+}
+
+type outdatedCmd struct {
+	cmd  *cobra.Command
+	opts outdatedOpts
+}
+
+type outdatedOpts struct {
+	summary      bool
+	json         bool
+	maxStaleness time.Duration
+	// check refreshes the results with the latest versions, without
+	// downloading anything, and fails when updates are found
+	check       bool
+	concurrency int
+	// exclude are the globs of the binaries not to report
+	exclude []string
+}
+
+// outdatedReport is what the last update checks tell
+// about the binaries currently installed
+type outdatedReport struct {
+	outdated []string
+	total    int
+	// oldest is when the least recently checked binary was
+	// checked, zero if any of them was never checked
+	oldest time.Time
+}
+
+// outdatedOutput is the JSON output of `bin outdated`, see pkg/schema/schemas/outdated.json
+type outdatedOutput struct {
+	SchemaVersion int            `json:"schema_version"`
+	Total         int            `json:"total"`
+	OldestCheck   *time.Time     `json:"oldest_check,omitempty"`
+	Stale         bool           `json:"stale"`
+	Outdated      []outdatedItem `json:"outdated"`
+}
+
+type outdatedItem struct {
+	Path      string    `json:"path"`
+	Installed string    `json:"installed"`
+	Latest    string    `json:"latest"`
+	URL       string    `json:"url,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// json builds the JSON output of the report
+func (r outdatedReport) json(c *checkCache, stale bool) outdatedOutput {
+	out := outdatedOutput{SchemaVersion: schema.Version, Total: r.total, Stale: stale, Outdated: []outdatedItem{}}
+	if !r.oldest.IsZero() {
+		oldest := r.oldest
+		out.OldestCheck = &oldest
+	}
+	for _, p := range r.outdated {
+		res := c.Bins[p]
+		out.Outdated = append(out.Outdated, outdatedItem{
+			Path:      os.ExpandEnv(p),
+			Installed: res.Installed,
+			Latest:    res.Latest,
+			URL:       res.URL,
+			CheckedAt: res.CheckedAt,
+		})
+	}
+	return out
+}
+
+func newOutdatedCmd() *outdatedCmd {
+	root := &outdatedCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "outdated [binary_path]...",
+		Short:         "Lists the binaries found outdated by the last update check, without hitting the network",
+		Long:          "Lists the binaries found outdated by the last update check, without hitting the network. With --check, the latest versions are checked first, without downloading anything, and it exits with 3 when updates are found.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bins, err := selectBins(args, root.opts.exclude)
+			if err != nil {
+				return err
+			}
+			failed := 0
+			if root.opts.check {
+				if err := checkConcurrency(root.opts.concurrency); err != nil {
+					return err
+				}
+				_, failures := checkUpdates(checkedBins(bins), root.opts.concurrency, false)
+				failed = warnFailures(failures)
+			}
+
+			c, err := loadCheckCache()
+			if err != nil {
+				return err
+			}
+			r := outdated(bins, c)
+			// with --check, the result is the exit code
+			result := func() error {
+				switch {
+				case failed > 0:
+					return failuresError("check", failed)
+				case root.opts.check && len(r.outdated) > 0:
+					return wrapErrorWithCode(fmt.Errorf("%d binaries are outdated", len(r.outdated)), 3, "")
+				}
+				return nil
+			}
+
+			stale := root.opts.maxStaleness > 0 && (r.oldest.IsZero() || time.Since(r.oldest) > root.opts.maxStaleness)
+			if root.opts.json {
+				if err := writeJSON(os.Stdout, r.json(c, stale)); err != nil {
+					return err
+				}
+				return result()
+			}
+			if root.opts.summary {
+				switch {
+				case stale:
+					fmt.Println("stale")
+				case len(r.outdated) > 0:
+					fmt.Printf("%d/%d outdated\n", len(r.outdated), r.total)
+				}
+				return result()
+			}
+
+			if stale {
+				log.WithField("max_staleness", root.opts.maxStaleness).Warn("The last update check is stale, run `bin update --dry-run` to refresh it")
+			}
+			if len(r.outdated) == 0 {
+				log.Info("No outdated binaries found by the last update check")
+				return result()
+			}
+			printTable(os.Stdout, outdatedColumns, outdatedRows(config.Get().Bins, c, r.outdated), terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+			return result()
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.summary, "summary", false, "Print a one-line summary (i.e. `3/42 outdated`, nothing when up to date) for shell prompts and status bars")
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print the report as JSON, see `bin schema outdated`")
+	root.cmd.Flags().BoolVar(&root.opts.check, "check", false, "Check the latest versions first, without downloading anything, and exit with 3 when updates are found")
+	root.cmd.Flags().IntVar(&root.opts.concurrency, "concurrency", defaultConcurrency, "Number of binaries checked at once with --check")
+	root.cmd.Flags().StringSliceVar(&root.opts.exclude, "exclude", nil, "Don't report the binaries whose name matches one of these globs (i.e. 'kube*')")
+	root.cmd.Flags().DurationVar(&root.opts.maxStaleness, "max-staleness", 0, "Report the results as stale when the last check is older than this (i.e. 24h)")
+	return root
+}
+
+// outdatedColumns are the columns of the outdated binaries
+var outdatedColumns = []tableColumn{{header: "Name"}, {header: "Current"}, {header: "Latest"}, {header: "URL", truncate: true}}
+
+// outdatedRows returns the cells of the outdated binaries
+func outdatedRows(bins map[string]*config.Binary, c *checkCache, paths []string) [][]tableCell {
+	rows := make([][]tableCell, 0, len(paths))
+	for _, p := range paths {
+		res := c.Bins[p]
+		u := res.URL
+		if u == "" {
+			u = "-"
+		}
+		rows = append(rows, []tableCell{
+			{text: filepath.Base(os.ExpandEnv(p))},
+			{text: bins[p].Version, color: color.New(color.FgYellow).Sprint},
+			{text: res.Latest, color: color.New(color.FgGreen).Sprint},
+			{text: u},
+		})
+	}
+	return rows
+}
+
+// checkedBins returns the binaries whose latest version is checked,
+// neither the pinned ones nor the series of --track-latest are
+func checkedBins(bins map[string]*config.Binary) map[string]*config.Binary {
+	checked := map[string]*config.Binary{}
+	for k, b := range bins {
+		if !b.Pinned && b.TrackLatest == 0 {
+			checked[k] = b
+		}
+	}
+	return checked
+}
+
+// outdated reports the binaries whose last check found a newer
+// version than the one still installed. Pinned binaries aren't
+// checked so they're never outdated.
+func outdated(bins map[string]*config.Binary, c *checkCache) outdatedReport {
+	r := outdatedReport{total: len(bins)}
+	never := false
+	for p, b := range bins {
+		if b.Pinned {
+			continue
+		}
+		res, ok := c.Bins[p]
+		if !ok {
+			never = true
+			continue
+		}
+		if r.oldest.IsZero() || res.CheckedAt.Before(r.oldest) {
+			r.oldest = res.CheckedAt
+		}
+		if res.Installed == b.Version && res.Latest != b.Version {
+			r.outdated = append(r.outdated, p)
+		}
+	}
+	if never {
+		r.oldest = time.Time{}
+	}
+	sort.Strings(r.outdated)
+	return r
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestOutdated(t *testing.T) {
+	now := time.Now()
+	bins := map[string]*config.Binary{
+		"/bin/kind":    {Path: "/bin/kind", Version: "v0.19.0"},
+		"/bin/jq":      {Path: "/bin/jq", Version: "jq-1.7"},
+		"/bin/gh":      {Path: "/bin/gh", Version: "v2.40.0"},
+		"/bin/yq":      {Path: "/bin/yq", Version: "v4.0.0", Pinned: true},
+		"/bin/updated": {Path: "/bin/updated", Version: "v2.0.0"},
+	}
+	c := &checkCache{Bins: map[string]*checkResult{
+		"/bin/kind":    {Installed: "v0.19.0", Latest: "v0.20.0", CheckedAt: now.Add(-time.Hour)},
+		"/bin/jq":      {Installed: "jq-1.7", Latest: "jq-1.7", CheckedAt: now},
+		"/bin/gh":      {Installed: "v2.40.0", Latest: "v2.41.0", CheckedAt: now},
+		"/bin/updated": {Installed: "v1.0.0", Latest: "v2.0.0", CheckedAt: now},
+	}}
+
+	r := outdated(bins, c)
+	if !reflect.DeepEqual(r.outdated, []string{"/bin/gh", "/bin/kind"}) || r.total != 5 {
+		t.Fatalf("expected gh and kind out of 5 to be outdated, got %v out of %d", r.outdated, r.total)
+	}
+	if !r.oldest.Equal(now.Add(-time.Hour)) {
+		t.Fatalf("expected the oldest check to be the one of kind, got %s", r.oldest)
+	}
+
+	// binaries never checked make the results stale
+	bins["/bin/new"] = &config.Binary{Path: "/bin/new", Version: "v1.0.0"}
+	if r := outdated(bins, c); !r.oldest.IsZero() {
+		t.Fatalf("expected no oldest check, got %s", r.oldest)
+	}
+}
+
+func TestOutdatedCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handler of the test is a shell script")
+	}
+	dir := t.TempDir()
+	latest := filepath.Join(dir, "latest")
+	if err := os.WriteFile(latest, []byte("1.0.0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	writeScript(t, filepath.Join(dir, "checked-1.0.0"), "1.0.0")
+	handlers := fmt.Sprintf(`{"check://": {"latest": "cat %s", "download": "cp %s/checked-$BIN_VERSION $BIN_OUTPUT_DIR/checked"}}`, latest, dir)
+	_, binDir := newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "check://team/checked"})
+	checked := filepath.Join(binDir, "checked")
+	bins := map[string]*config.Binary{checked: config.Get().Bins[checked]}
+
+	// a new version is published, nothing is downloaded to find it
+	if err := os.WriteFile(latest, []byte("1.1.0"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	updates, failures := checkUpdates(bins, 2, false)
+	if len(failures) > 0 || len(updates) != 1 || updates[bins[checked]].version != "1.1.0" {
+		t.Fatalf("expected 1.1.0 to be found, got %v (%v)", updates, failures)
+	}
+	if b := config.Get().Bins[checked]; b.Version != "1.0.0" {
+		t.Fatalf("expected the binary not to be updated, got %s", b.Version)
+	}
+
+	c, err := loadCheckCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := outdated(bins, c)
+	if !reflect.DeepEqual(r.outdated, []string{checked}) {
+		t.Fatalf("expected the binary to be outdated, got %v", r.outdated)
+	}
+	rows := outdatedRows(bins, c, r.outdated)
+	if rows[0][0].text != "checked" || rows[0][1].text != "1.0.0" || rows[0][2].text != "1.1.0" {
+		t.Fatalf("unexpected row %+v", rows[0])
+	}
+}
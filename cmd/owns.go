@@ -0,0 +1,102 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/labels"
+	"github.com/spf13/cobra"
+)
+
+// Exit codes of owns
+const (
+	ownsNotManaged = 1
+	ownsFailed     = 2
+)
+
+type ownsCmd struct {
+	cmd *cobra.Command
+}
+
+// ownership is what's known about who installed a file: the binary
+// recording it in the configuration and the label of the file
+type ownership struct {
+	entry *config.Binary
+	// label is nil when the file isn't labeled, or the
+	// filesystem doesn't support labels
+	label *labels.Label
+}
+
+// ownerOf returns the ownership of the file at path
+func ownerOf(path string) (ownership, error) {
+	o := ownership{entry: config.Owner(path)}
+	l, err := labels.Get(os.ExpandEnv(path))
+	if err != nil && !errors.Is(err, labels.ErrUnsupported) {
+		return o, err
+	}
+	o.label = l
+	return o, nil
+}
+
+// owned reports whether bin manages the file: a binary records it
+// and, if the file is labeled, the label is the one of that binary
+func (o ownership) owned() bool {
+	return o.entry != nil && !o.replaced()
+}
+
+// replaced reports whether the file recorded by the binary was
+// replaced by one installed from another source
+func (o ownership) replaced() bool {
+	return o.entry != nil && o.label != nil && o.label.Source != o.entry.URL
+}
+
+// orphaned reports whether the file was installed by bin but
+// isn't recorded anymore, i.e. by another configuration
+func (o ownership) orphaned() bool {
+	return o.entry == nil && o.label != nil
+}
+
+func newOwnsCmd() *ownsCmd {
+	root := &ownsCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "owns <path>",
+		Short: "Tells whether a file is managed by bin",
+		Long: `Tells whether a file is managed by bin, and by which entry.
+
+It exits with 0 when the file is managed, 1 when it isn't and 2
+when it can't be checked.`,
+		SilenceUsage:  true,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := filepath.Abs(os.ExpandEnv(args[0]))
+			if err != nil {
+				return wrapErrorWithCode(err, ownsFailed, "")
+			}
+			if _, err := os.Lstat(p); err != nil {
+				return wrapErrorWithCode(err, ownsFailed, "")
+			}
+			o, err := ownerOf(p)
+			if err != nil {
+				return wrapErrorWithCode(err, ownsFailed, "")
+			}
+			switch {
+			case o.owned():
+				fmt.Printf("%s\t%s\t%s\n", o.entry.Path, o.entry.Version, o.entry.URL)
+				return nil
+			case o.replaced():
+				return wrapErrorWithCode(fmt.Errorf("%s was replaced by a file installed from %s", p, o.label.Source), ownsNotManaged, "not managed")
+			case o.orphaned():
+				return wrapErrorWithCode(fmt.Errorf("%s was installed by bin from %s but isn't in the configuration", p, o.label.Source), ownsNotManaged, "not managed")
+			}
+			return wrapErrorWithCode(fmt.Errorf("%s isn't managed by bin", p), ownsNotManaged, "not managed")
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/labels"
+)
+
+func TestOwns(t *testing.T) {
+	dir, binDir := newTestConfig(t, `"require_checksum": false`)
+	src := filepath.Join(dir, "owned")
+	writeScript(t, src, "owned")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src})
+	installed := filepath.Join(binDir, "owned")
+	unmanaged := filepath.Join(binDir, "unmanaged")
+	if err := os.WriteFile(unmanaged, []byte("#!/bin/sh\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	owns := func(p string) int {
+		code := 0
+		Execute("test", func(c int) { code = c }, []string{"owns", p})
+		return code
+	}
+	if code := owns(installed); code != 0 {
+		t.Errorf("expected the installed binary to be managed, got %d", code)
+	}
+	if code := owns(unmanaged); code != ownsNotManaged {
+		t.Errorf("expected %s not to be managed, got %d", unmanaged, code)
+	}
+	if code := owns(filepath.Join(binDir, "missing")); code != ownsFailed {
+		t.Errorf("expected a missing file to fail, got %d", code)
+	}
+
+	l, err := labels.Get(installed)
+	if errors.Is(err, labels.ErrUnsupported) {
+		t.Skip("the filesystem of the temporary directory doesn't support labels")
+	}
+	if err != nil || l == nil || l.Source != src {
+		t.Fatalf("expected the installed binary to be labeled with its source, got %+v (%v)", l, err)
+	}
+
+	// a file installed by another configuration
+	if err := labels.Set(unmanaged, labels.Label{Version: "1.0.0", Source: "https://example.com/unmanaged"}); err != nil {
+		t.Fatal(err)
+	}
+	if code := owns(unmanaged); code != ownsNotManaged {
+		t.Errorf("expected an orphaned file not to be managed, got %d", code)
+	}
+	found := false
+	for _, p := range checkOwnership() {
+		found = found || p == fmt.Sprintf("%s was installed by bin from https://example.com/unmanaged but isn't in the configuration, install it again or remove it", unmanaged)
+	}
+	if !found {
+		t.Errorf("expected doctor to report the orphaned file, got %v", checkOwnership())
+	}
+
+	// the installed file replaced by another install
+	if err := labels.Set(installed, labels.Label{Version: "2.0.0", Source: "https://example.com/other"}); err != nil {
+		t.Fatal(err)
+	}
+	if code := owns(installed); code != ownsNotManaged {
+		t.Errorf("expected a replaced file not to be managed, got %d", code)
+	}
+}
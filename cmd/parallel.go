@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+// defaultConcurrency is the number of binaries
+// checked and downloaded at once by batch operations
+const defaultConcurrency = 4
+
+// errSkipped is the error of the binaries which weren't
+// processed because another one failed with --fail-fast
+var errSkipped = errors.New("skipped after a previous failure")
+
+// configMu serializes the configuration writes of the workers
+var configMu sync.Mutex
+
+// upsertBinary is config.UpsertBinary safe for concurrent use
+func upsertBinary(b *config.Binary) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return config.UpsertBinary(b)
+}
+
+// forEach calls fn for the n items with up to concurrency of them
+// running at once, and returns their errors by item index. A failure
+// doesn't stop the other items unless failFast is set, the ones not
+// started yet then fail with errSkipped.
+func forEach(n, concurrency int, failFast bool, fn func(i int) error) []error {
+	errs := make([]error, n)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	var (
+		mu     sync.Mutex
+		failed bool
+		wg     sync.WaitGroup
+	)
+	sem := make(chan struct{}, concurrency)
+	for i := 0; i < n; i++ {
+		sem <- struct{}{}
+		mu.Lock()
+		stop := failed && failFast
+		mu.Unlock()
+		if stop {
+			<-sem
+			errs[i] = errSkipped
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := fn(i); err != nil {
+				errs[i] = err
+				mu.Lock()
+				failed = true
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	return errs
+}
+
+// hasFailure reports whether any of the items failed
+func hasFailure(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// checkConcurrency validates the --concurrency flag
+func checkConcurrency(n int) error {
+	if n < 1 {
+		return fmt.Errorf("--concurrency must be at least 1, got %d", n)
+	}
+	return nil
+}
+
+// warnFailures logs the failures sorted by binary path and returns
+// how many they are, the binaries skipped with --fail-fast aren't
+func warnFailures(failures map[*config.Binary]error) int {
+	bins := make([]*config.Binary, 0, len(failures))
+	for b, err := range failures {
+		if !errors.Is(err, errSkipped) {
+			bins = append(bins, b)
+		}
+	}
+	sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
+	for _, b := range bins {
+		binLog(b).WithError(failures[b]).Warn("Failed")
+	}
+	return len(bins)
+}
+
+// failuresError is the error of a batch operation which failed for n binaries
+func failuresError(action string, n int) error {
+	if n == 1 {
+		return fmt.Errorf("unable to %s 1 binary", action)
+	}
+	return fmt.Errorf("unable to %s %d binaries", action, n)
+}
@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestForEach(t *testing.T) {
+	var mu sync.Mutex
+	running, most := 0, 0
+	errs := forEach(10, 3, false, func(i int) error {
+		mu.Lock()
+		running++
+		most = max(most, running)
+		mu.Unlock()
+		time.Sleep(5 * time.Millisecond)
+		mu.Lock()
+		running--
+		mu.Unlock()
+		if i == 2 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if most > 3 {
+		t.Errorf("expected at most 3 items at once, got %d", most)
+	}
+	for i, err := range errs {
+		if (err != nil) != (i == 2) {
+			t.Errorf("item %d: unexpected error %v", i, err)
+		}
+	}
+
+	// the items not started yet are skipped after a failure
+	errs = forEach(5, 1, true, func(i int) error {
+		if i == 1 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	for i, err := range errs {
+		if i > 1 && !errors.Is(err, errSkipped) {
+			t.Errorf("item %d: expected to be skipped, got %v", i, err)
+		}
+	}
+}
+
+func TestEnsureConcurrently(t *testing.T) {
+	dir, binDir := newTestConfig(t, "")
+
+	tools := []string{"a", "b", "c", "d", "e"}
+	for _, name := range tools {
+		src := filepath.Join(dir, name)
+		writeScript(t, src, name)
+		Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src})
+		if err := os.Remove(filepath.Join(binDir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a failure doesn't stop the other binaries
+	if err := os.Remove(filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--concurrency", "3"})
+	if code == 0 {
+		t.Error("expected ensure to report the failure")
+	}
+	for _, name := range tools {
+		_, err := os.Stat(filepath.Join(binDir, name))
+		if (err == nil) == (name == "b") {
+			t.Errorf("%s: unexpected presence after ensure (%v)", name, err)
+		}
+	}
+}
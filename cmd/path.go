@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// the markers of the block written to the shell profiles by
+// `bin doctor --fix-path`, it's replaced when written again
+const (
+	pathBlockStart = "# >>> bin PATH >>>"
+	pathBlockEnd   = "# <<< bin PATH <<<"
+)
+
+// shadowingFile returns the file run instead of the binary at p when
+// it's called by name, if any. inPath reports whether the directory
+// of the binary is in PATH at all.
+func shadowingFile(p string) (shadowing string, inPath bool) {
+	inPath = dirInPath(filepath.Dir(p))
+	found, err := exec.LookPath(filepath.Base(p))
+	if err != nil || sameFile(found, p) {
+		return "", inPath
+	}
+	return found, inPath
+}
+
+func sameFile(a, b string) bool {
+	ia, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	ib, err := os.Stat(b)
+	return err == nil && os.SameFile(ia, ib)
+}
+
+// dirInPath reports whether the directory is one of PATH
+func dirInPath(dir string) bool {
+	for _, d := range filepath.SplitList(os.Getenv("PATH")) {
+		if d != "" && (filepath.Clean(d) == filepath.Clean(dir) || sameFile(d, dir)) {
+			return true
+		}
+	}
+	return false
+}
+
+// warnShadowed warns when the binary just installed isn't the one
+// run when it's called by name, i.e. an older copy from the distro
+// in a directory coming first in PATH
+func warnShadowed(b *config.Binary) {
+	if b.IsFile() {
+		return
+	}
+	p := os.ExpandEnv(b.Path)
+	shadowing, inPath := shadowingFile(p)
+	switch {
+	case shadowing != "":
+		l := binLog(b).WithField("shadowed_by", shadowing)
+		if v := reportedVersion(shadowing); v != "" {
+			l = l.WithField("shadowing_version", v)
+		}
+		l.Warn(color.New(color.FgYellow, color.Bold).Sprint("Another file comes first in PATH and runs instead of the binary"))
+	case !inPath:
+		binLog(b).Warn(color.New(color.FgYellow, color.Bold).Sprint("The directory of the binary isn't in PATH, it can't be run by its name"))
+	default:
+		return
+	}
+	log.WithField("path", filepath.Dir(p)).Warn("Run `bin doctor --fix-path` to put the directory first in PATH")
+}
+
+// pathDirs returns the directories of the managed binaries which
+// are shadowed or not in PATH, the default path first, and the
+// binaries shadowed by another file
+func pathDirs() ([]string, map[string]string) {
+	cfg := config.Get()
+	dirs, shadowed := []string{}, map[string]string{}
+	seen := map[string]bool{}
+	add := func(d string) {
+		if !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	if dp := os.ExpandEnv(cfg.DefaultPath); dp != "" && !dirInPath(dp) {
+		add(dp)
+	}
+	paths := []string{}
+	for _, b := range cfg.Bins {
+		if !b.IsFile() {
+			paths = append(paths, os.ExpandEnv(b.Path))
+		}
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		if _, err := os.Stat(p); err != nil {
+			continue
+		}
+		shadowing, inPath := shadowingFile(p)
+		if shadowing != "" {
+			shadowed[p] = shadowing
+		}
+		if shadowing != "" || !inPath {
+			add(filepath.Dir(p))
+		}
+	}
+	return dirs, shadowed
+}
+
+// checkPath reports the managed binaries shadowed by other files
+// and the directories of binaries which aren't in PATH
+func checkPath() []string {
+	dirs, shadowed := pathDirs()
+	problems := []string{}
+	for _, d := range dirs {
+		if !dirInPath(d) {
+			problems = append(problems, fmt.Sprintf("%s isn't in PATH, run `bin doctor --fix-path` to add it", d))
+		}
+	}
+	paths := make([]string, 0, len(shadowed))
+	for p := range shadowed {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	for _, p := range paths {
+		problems = append(problems, fmt.Sprintf("%s is shadowed by %s, run `bin doctor --fix-path` to put %s first in PATH", p, shadowed[p], filepath.Dir(p)))
+	}
+	return problems
+}
+
+// fixPath writes the block putting the directories of the managed
+// binaries first in PATH to the profile of the shell
+func fixPath(shell string) error {
+	dirs, _ := pathDirs()
+	if len(dirs) == 0 {
+		log.Info("The managed binaries are already first in PATH")
+		return nil
+	}
+	if shell == "" {
+		shell = detectShell()
+	}
+	profile, err := shellProfile(shell)
+	if err != nil {
+		return err
+	}
+	export, err := pathExport(shell, dirs)
+	if err != nil {
+		return err
+	}
+	changed, err := writePathBlock(profile, export)
+	if err != nil {
+		return err
+	}
+	if !changed {
+		log.WithField("profile", profile).WithField("paths", dirs).Info("The profile already puts the directories first in PATH, open a new shell to apply it")
+		return nil
+	}
+	log.WithField("profile", profile).WithField("paths", dirs).Info("Added the directories first in PATH, open a new shell to apply it")
+	return nil
+}
+
+// detectShell returns the shell of the user, from SHELL
+func detectShell() string {
+	if s := os.Getenv("SHELL"); s != "" {
+		return strings.TrimSuffix(filepath.Base(s), ".exe")
+	}
+	if runtime.GOOS == "windows" {
+		return "powershell"
+	}
+	return "sh"
+}
+
+// shellProfile returns the file sourced by the interactive shells
+func shellProfile(shell string) (string, error) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	configDir := os.Getenv("XDG_CONFIG_HOME")
+	if configDir == "" {
+		configDir = filepath.Join(home, ".config")
+	}
+	switch shell {
+	case "bash":
+		return filepath.Join(home, ".bashrc"), nil
+	case "zsh":
+		if d := os.Getenv("ZDOTDIR"); d != "" {
+			return filepath.Join(d, ".zshrc"), nil
+		}
+		return filepath.Join(home, ".zshrc"), nil
+	case "sh":
+		return filepath.Join(home, ".profile"), nil
+	case "fish":
+		return filepath.Join(configDir, "fish", "config.fish"), nil
+	case "powershell", "pwsh":
+		if runtime.GOOS == "windows" {
+			return filepath.Join(home, "Documents", "PowerShell", "Microsoft.PowerShell_profile.ps1"), nil
+		}
+		return filepath.Join(configDir, "powershell", "Microsoft.PowerShell_profile.ps1"), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q, use --shell with bash, zsh, fish or powershell", shell)
+}
+
+// pathExport returns the line of the shell putting the directories
+// first in PATH, in order
+func pathExport(shell string, dirs []string) (string, error) {
+	quoted := make([]string, len(dirs))
+	switch shell {
+	case "bash", "zsh", "sh":
+		for i, d := range dirs {
+			quoted[i] = "'" + strings.ReplaceAll(d, "'", `'\''`) + "'"
+		}
+		return fmt.Sprintf(`export PATH=%s:"$PATH"`, strings.Join(quoted, ":")), nil
+	case "fish":
+		for i, d := range dirs {
+			quoted[i] = "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(d) + "'"
+		}
+		return fmt.Sprintf("set -gx PATH %s $PATH", strings.Join(quoted, " ")), nil
+	case "powershell", "pwsh":
+		for i, d := range dirs {
+			quoted[i] = "'" + strings.ReplaceAll(d, "'", "''") + "'"
+		}
+		return fmt.Sprintf("$env:PATH = (@(%s) + $env:PATH) -join [IO.Path]::PathListSeparator", strings.Join(quoted, ", ")), nil
+	}
+	return "", fmt.Errorf("unsupported shell %q, use --shell with bash, zsh, fish or powershell", shell)
+}
+
+// writePathBlock writes the content in the marked block of the
+// profile, replacing the one written before. It reports whether
+// the profile changed.
+func writePathBlock(profile, content string) (bool, error) {
+	b, err := os.ReadFile(profile)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	block := pathBlockStart + "\n" + content + "\n" + pathBlockEnd + "\n"
+	s := string(b)
+	start := strings.Index(s, pathBlockStart)
+	end := strings.Index(s, pathBlockEnd)
+	switch {
+	case start >= 0 && end > start:
+		end += len(pathBlockEnd)
+		if end < len(s) && s[end] == '\n' {
+			end++
+		}
+		if s[start:end] == block {
+			return false, nil
+		}
+		s = s[:start] + block + s[end:]
+	case s == "" || strings.HasSuffix(s, "\n\n"):
+		s += block
+	case strings.HasSuffix(s, "\n"):
+		s += "\n" + block
+	default:
+		s += "\n\n" + block
+	}
+
+	if err := os.MkdirAll(filepath.Dir(profile), 0o755); err != nil {
+		return false, err
+	}
+	mode := os.FileMode(0o644)
+	if fi, err := os.Stat(profile); err == nil {
+		mode = fi.Mode().Perm()
+	}
+	return true, os.WriteFile(profile, []byte(s), mode)
+}
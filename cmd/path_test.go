@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestWritePathBlock(t *testing.T) {
+	profile := filepath.Join(t.TempDir(), "rc", ".bashrc")
+	if changed, err := writePathBlock(profile, "export PATH='/a':\"$PATH\""); err != nil || !changed {
+		t.Fatalf("expected the profile to be created, got %v (%v)", changed, err)
+	}
+
+	if err := os.WriteFile(profile, []byte("alias ll='ls -l'"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(profile, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writePathBlock(profile, "one"); err != nil {
+		t.Fatal(err)
+	}
+	if err := appendFile(profile, "alias la='ls -a'\n"); err != nil {
+		t.Fatal(err)
+	}
+	if changed, err := writePathBlock(profile, "one"); err != nil || changed {
+		t.Fatalf("expected the same block not to change the profile, got %v (%v)", changed, err)
+	}
+	if _, err := writePathBlock(profile, "two"); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(profile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "alias ll='ls -l'\n\n" + pathBlockStart + "\ntwo\n" + pathBlockEnd + "\nalias la='ls -a'\n"
+	if string(b) != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, b)
+	}
+	if fi, err := os.Stat(profile); err != nil || (runtime.GOOS != "windows" && fi.Mode().Perm() != 0o600) {
+		t.Errorf("expected the mode of the profile to be kept, got %v (%v)", fi.Mode(), err)
+	}
+}
+
+func appendFile(path, s string) error {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(s)
+	return err
+}
+
+func TestPathExport(t *testing.T) {
+	dirs := []string{"/home/me/bin", "/opt/it's"}
+	cases := map[string]string{
+		"bash":       `export PATH='/home/me/bin':'/opt/it'\''s':"$PATH"`,
+		"zsh":        `export PATH='/home/me/bin':'/opt/it'\''s':"$PATH"`,
+		"fish":       `set -gx PATH '/home/me/bin' '/opt/it\'s' $PATH`,
+		"powershell": `$env:PATH = (@('/home/me/bin', '/opt/it''s') + $env:PATH) -join [IO.Path]::PathListSeparator`,
+	}
+	for shell, want := range cases {
+		got, err := pathExport(shell, dirs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Errorf("%s: expected %s, got %s", shell, want, got)
+		}
+	}
+	if _, err := pathExport("csh", dirs); err == nil {
+		t.Error("expected an unsupported shell to fail")
+	}
+}
+
+func TestShadowingFile(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binaries of the test are shell scripts")
+	}
+	dir := t.TempDir()
+	first, second, outside := filepath.Join(dir, "first"), filepath.Join(dir, "second"), filepath.Join(dir, "outside")
+	for _, d := range []string{first, second, outside} {
+		if err := os.Mkdir(d, 0o755); err != nil {
+			t.Fatal(err)
+		}
+		writeScript(t, filepath.Join(d, "shadowed"), "1.0.0")
+	}
+	t.Setenv("PATH", first+string(os.PathListSeparator)+second)
+
+	cases := []struct {
+		dir, shadowing string
+		inPath         bool
+	}{
+		{first, "", true},
+		{second, filepath.Join(first, "shadowed"), true},
+		{outside, filepath.Join(first, "shadowed"), false},
+	}
+	for _, c := range cases {
+		shadowing, inPath := shadowingFile(filepath.Join(c.dir, "shadowed"))
+		if shadowing != c.shadowing || inPath != c.inPath {
+			t.Errorf("%s: expected %q shadowing it and in PATH %v, got %q and %v", c.dir, c.shadowing, c.inPath, shadowing, inPath)
+		}
+	}
+}
+
+func TestDoctorFixPath(t *testing.T) {
+	dir, binDir := newTestConfig(t, "")
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(dir, "config"))
+
+	for i := 0; i < 2; i++ {
+		Execute("test", func(code int) { t.Fatalf("doctor exited with %d", code) }, []string{"doctor", "--fix-path", "--shell", "fish"})
+	}
+	b, err := os.ReadFile(filepath.Join(dir, "config", "fish", "config.fish"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(string(b), pathBlockStart) != 1 || !strings.Contains(string(b), fmt.Sprintf("set -gx PATH '%s'", binDir)) {
+		t.Fatalf("expected a single block putting %s first in PATH, got\n%s", binDir, b)
+	}
+}
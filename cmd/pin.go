@@ -1,10 +1,8 @@
 package cmd
 
 import (
-	"strings"
-
-	"github.com/caarlos0/log"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/spf13/cobra"
 )
 
@@ -16,27 +14,40 @@ func newPinCmd() *pinCmd {
 	root := &pinCmd{}
 
 	cmd := &cobra.Command{
-		Use:           "pin [<name> | <paths...>]",
-		Short:         "Pins current version of the binaries",
+		Use:               "pin [<name> [version] | <paths...>]",
+		ValidArgsFunction: completeBinaries(0),
+		Annotations:       map[string]string{writesConfig: "true"},
+		Short:             "Pins current version of the binaries",
+		Long: `Pins current version of the binaries, bin update skips them.
+
+Given a single binary and a version, the binary is switched to that
+version first.`,
 		SilenceUsage:  true,
 		Args:          cobra.MinimumNArgs(1),
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := config.Get()
 
-			binsToPin := map[string]*config.Binary{}
-
-			// To pin
-			if len(args) > 0 {
-				for _, a := range args {
-					bin, err := getBinPath(a)
+			// a second argument which isn't a binary is the version to pin
+			if len(args) == 2 {
+				if _, err := getBinPath(args[1]); err != nil {
+					bin, err := managedBinPath(args[0])
 					if err != nil {
 						return err
 					}
-					binsToPin[a] = cfg.Bins[bin]
+					return updateTo(cfg.Bins[bin], args[1], updateOpts{})
+				}
+			}
+
+			binsToPin := map[string]*config.Binary{}
+
+			// To pin
+			for _, a := range args {
+				bin, err := managedBinPath(a)
+				if err != nil {
+					return err
 				}
-			} else {
-				return nil
+				binsToPin[a] = cfg.Bins[bin]
 			}
 
 			pinned := []string{}
@@ -52,7 +63,7 @@ func newPinCmd() *pinCmd {
 				continue
 			}
 
-			log.Infof("Pinned " + strings.Join(pinned, " "))
+			log.WithField("binaries", pinned).Info("Pinned")
 
 			return nil
 		},
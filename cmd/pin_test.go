@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestPinVersion(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handler of the test is a shell script")
+	}
+	dir := t.TempDir()
+	for _, v := range []string{"1.0.0", "1.1.0"} {
+		writeScript(t, filepath.Join(dir, "pinned-"+v), v)
+	}
+	handlers := fmt.Sprintf(`{"pinned://": {"latest": "echo 1.1.0", "download": "cp %s/pinned-$BIN_VERSION $BIN_OUTPUT_DIR/pinned"}}`, dir)
+	_, binDir := newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+	tool := filepath.Join(binDir, "pinned")
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "pinned://team/pinned"})
+	Execute("test", func(code int) { t.Fatalf("pin exited with %d", code) }, []string{"pin", tool, "1.0.0"})
+	if out, err := exec.Command(tool).Output(); err != nil || string(out) != "1.0.0\n" {
+		t.Fatalf("expected the binary to be switched to 1.0.0, got %q (%v)", out, err)
+	}
+	if b := config.Get().Bins[tool]; !b.Pinned || b.Version != "1.0.0" {
+		t.Fatalf("expected the binary to be pinned to 1.0.0, got %+v", b)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", tool})
+	if b := config.Get().Bins[tool]; b.Version != "1.0.0" {
+		t.Fatalf("expected the pinned binary to be skipped, got %s", b.Version)
+	}
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", "--include-pinned", tool})
+	if b := config.Get().Bins[tool]; !b.Pinned || b.Version != "1.1.0" {
+		t.Fatalf("expected the binary to be updated and stay pinned, got %+v", b)
+	}
+
+	if _, err := managedBinPath("pinend"); err == nil || !strings.Contains(err.Error(), "did you mean pinned?") {
+		t.Errorf("expected the closest binary to be suggested, got %v", err)
+	}
+	if _, err := managedBinPath("zzzzzzzzzzzz"); err == nil || strings.Contains(err.Error(), "did you mean") {
+		t.Errorf("expected no suggestion for an unrelated name, got %v", err)
+	}
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// platformAliases are the names vendors usually
+// give to the operating systems in their repositories
+var platformAliases = map[string]string{
+	"macos": "darwin",
+	"osx":   "darwin",
+	"mac":   "darwin",
+	"win":   "windows",
+}
+
+// parsePlatformURL parses an `os[/arch]=url` platform mapping
+func parsePlatformURL(s string) (string, string, error) {
+	platform, u, ok := strings.Cut(s, "=")
+	if !ok || platform == "" || u == "" {
+		return "", "", fmt.Errorf("invalid platform %q, expected os[/arch]=url", s)
+	}
+	platform = strings.ToLower(platform)
+	goos, goarch, _ := strings.Cut(platform, "/")
+	if alias, ok := platformAliases[goos]; ok {
+		goos = alias
+	}
+	if goarch != "" {
+		return goos + "/" + goarch, u, nil
+	}
+	return goos, u, nil
+}
+
+// platformURL returns the URL of the binary for the given platform,
+// the most specific entry of its platform map is used if it has one
+func platformURL(b *config.Binary, goos, goarch string) (string, error) {
+	if len(b.Platforms) == 0 {
+		return b.URL, nil
+	}
+	if u, ok := b.Platforms[goos+"/"+goarch]; ok {
+		return u, nil
+	}
+	if u, ok := b.Platforms[goos]; ok {
+		return u, nil
+	}
+	return "", fmt.Errorf("%s is not published for %s/%s, only for %s", b.RemoteName, goos, goarch, strings.Join(platforms(b), ", "))
+}
+
+// platforms returns the platforms of the binary in a stable order
+func platforms(b *config.Binary) []string {
+	ps := make([]string, 0, len(b.Platforms))
+	for p := range b.Platforms {
+		ps = append(ps, p)
+	}
+	sort.Strings(ps)
+	return ps
+}
+
+// checkPlatformVersions warns when the repositories of the other
+// platforms aren't at the version installed from the local one
+func checkPlatformVersions(b *config.Binary, version string) {
+	local, err := platformURL(b, runtime.GOOS, runtime.GOARCH)
+	if err != nil {
+		return
+	}
+	for _, platform := range platforms(b) {
+		u := b.Platforms[platform]
+		if u == local {
+			continue
+		}
+		nb := *b
+		nb.URL, nb.Platforms, nb.Version, nb.Provider = u, nil, "", ""
+		p, err := newProvider(&nb)
+		if err != nil {
+			log.WithField("binary", b.RemoteName).WithField("platform", platform).WithError(err).Warn("Can't check the version")
+			continue
+		}
+		v, _, err := p.GetLatestVersion()
+		if err != nil {
+			log.WithField("binary", b.RemoteName).WithField("platform", platform).WithError(err).Warn("Can't check the version")
+			continue
+		}
+		if strings.TrimPrefix(v, "v") != strings.TrimPrefix(version, "v") {
+			log.WithField("binary", b.RemoteName).WithField("platform", platform).WithField("version", v).WithField("installed", version).Warn("The platforms are out of sync")
+		}
+	}
+}
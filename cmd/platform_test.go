@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestParsePlatformURL(t *testing.T) {
+	cases := []struct {
+		in       string
+		platform string
+		url      string
+		err      bool
+	}{
+		{"linux=github.com/owner/tool-linux", "linux", "github.com/owner/tool-linux", false},
+		{"macOS=github.com/owner/tool-macos", "darwin", "github.com/owner/tool-macos", false},
+		{"osx/arm64=github.com/owner/tool-macos-arm", "darwin/arm64", "github.com/owner/tool-macos-arm", false},
+		{"win=https://example.com/tool.exe?a=b", "windows", "https://example.com/tool.exe?a=b", false},
+		{"github.com/owner/tool-linux", "", "", true},
+		{"linux=", "", "", true},
+	}
+
+	for _, c := range cases {
+		platform, u, err := parsePlatformURL(c.in)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.in)
+			}
+			continue
+		}
+		if err != nil || platform != c.platform || u != c.url {
+			t.Errorf("%s: expected %s %s, got %s %s (%v)", c.in, c.platform, c.url, platform, u, err)
+		}
+	}
+}
+
+func TestPlatformURL(t *testing.T) {
+	b := &config.Binary{
+		RemoteName: "tool",
+		URL:        "https://github.com/owner/tool-linux",
+		Platforms: map[string]string{
+			"linux":        "https://github.com/owner/tool-linux",
+			"darwin":       "https://github.com/owner/tool-macos",
+			"darwin/arm64": "https://github.com/owner/tool-macos-arm",
+		},
+	}
+
+	cases := []struct {
+		goos, goarch string
+		url          string
+	}{
+		{"linux", "amd64", "https://github.com/owner/tool-linux"},
+		{"darwin", "amd64", "https://github.com/owner/tool-macos"},
+		{"darwin", "arm64", "https://github.com/owner/tool-macos-arm"},
+	}
+	for _, c := range cases {
+		u, err := platformURL(b, c.goos, c.goarch)
+		if err != nil || u != c.url {
+			t.Errorf("%s/%s: expected %s, got %s (%v)", c.goos, c.goarch, c.url, u, err)
+		}
+	}
+
+	if _, err := platformURL(b, "windows", "amd64"); err == nil {
+		t.Error("expected an error for a platform without URL")
+	}
+
+	b.Platforms = nil
+	if u, err := platformURL(b, "windows", "amd64"); err != nil || u != b.URL {
+		t.Errorf("expected the URL of binaries without platforms, got %s (%v)", u, err)
+	}
+}
@@ -1,44 +1,154 @@
 package cmd
 
 import (
+	"errors"
+	"fmt"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
 
-	"github.com/caarlos0/log"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/marcosnils/bin/pkg/prompt"
 	"github.com/spf13/cobra"
 )
 
+// stagedFileRe matches the files staged next to their target
+// by the installs, see journal.Tx.Stage
+var stagedFileRe = regexp.MustCompile(`^\..+\.bin-\d+$`)
+
 type pruneCmd struct {
 	cmd  *cobra.Command
 	opts pruneOpts
 }
 
 type pruneOpts struct {
-	force bool
+	force  bool
+	unused string
+	// files removes the files bin installed in the
+	// managed directories which aren't referenced anymore
+	files  bool
+	dryRun bool
+	// maxAge is the age of the temporary and cached
+	// files swept, i.e. interrupted downloads
+	maxAge string
+}
+
+// pruneItem is something prune removes
+type pruneItem struct {
+	path, reason string
 }
 
 func newPruneCmd() *pruneCmd {
 	root := &pruneCmd{}
 	// nolint: dupl
 	cmd := &cobra.Command{
-		Use:           "prune",
-		Short:         "Prunes binaries that no longer exist in the system",
+		Use:         "prune",
+		Annotations: map[string]string{writesConfig: "true"},
+		Short:       "Prunes binaries that no longer exist in the system",
+		Long: `Prunes binaries that no longer exist in the system.
+
+With --unused, the binaries not run for longer than the given duration
+are removed too. It requires the usage statistics, see 'bin stats'.
+
+With --files, the files of the managed directories which bin installed but
+aren't in the configuration anymore are removed too: the ones labeled by bin
+and the copies of managed binaries left behind when their path changed.
+
+The interrupted downloads, cached responses and files staged by interrupted
+installs older than --max-age are swept.`,
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := config.Get()
+			maxAge, err := parseAge(root.opts.maxAge)
+			if err != nil {
+				return err
+			}
 
-			pathsToDel := []string{}
+			entries := []pruneItem{}
 			for _, b := range cfg.Bins {
 				ep := os.ExpandEnv(b.Path)
 				if _, err := os.Stat(ep); os.IsNotExist(err) {
-					log.Infof("%s not found removing", ep)
-					pathsToDel = append(pathsToDel, b.Path)
+					entries = append(entries, pruneItem{b.Path, "not found"})
+					continue
+				}
+				// the file left in place belongs to someone else
+				if o, err := ownerOf(ep); err == nil && o.replaced() {
+					entries = append(entries, pruneItem{b.Path, fmt.Sprintf("replaced by a file installed from %s", o.label.Source)})
 				}
 			}
 
-			if len(pathsToDel) == 0 {
+			unused := []pruneItem{}
+			if root.opts.unused != "" {
+				if !cfg.Stats {
+					return errors.New("usage statistics are disabled, run 'bin stats enable' first")
+				}
+				age, err := parseAge(root.opts.unused)
+				if err != nil {
+					return err
+				}
+				bins, err := unusedBinaries(age)
+				if err != nil {
+					return err
+				}
+				for _, b := range bins {
+					unused = append(unused, pruneItem{b.Path, fmt.Sprintf("not run for %s", root.opts.unused)})
+				}
+			}
+
+			leftovers := []pruneItem{}
+			if root.opts.files {
+				if leftovers, err = leftoverFiles(); err != nil {
+					return err
+				}
+			}
+			stale, err := staleFiles(maxAge)
+			if err != nil {
+				return err
+			}
+
+			sortItems := func(items []pruneItem) {
+				sort.Slice(items, func(i, j int) bool { return items[i].path < items[j].path })
+			}
+			sortItems(entries)
+			sortItems(unused)
+			report := func(action, path, reason string) {
+				log.WithField("path", path).WithField("reason", reason).Info(action)
+			}
+			if root.opts.dryRun {
+				report = func(action, path, reason string) { fmt.Printf("%s %s: %s\n", action, path, reason) }
+			}
+			for _, it := range entries {
+				report("Forget", os.ExpandEnv(it.path), it.reason)
+			}
+			for _, it := range unused {
+				report("Remove", os.ExpandEnv(it.path), it.reason)
+			}
+			for _, it := range leftovers {
+				report("Remove", it.path, it.reason)
+			}
+			for _, it := range stale {
+				report("Remove", it.path, it.reason)
+			}
+			if len(entries)+len(unused)+len(leftovers)+len(stale) == 0 {
+				log.Info("Nothing to prune")
+				return nil
+			}
+			if root.opts.dryRun {
+				return nil
+			}
+
+			// the temporary files are only kept to be resumed or recovered
+			for _, it := range stale {
+				if err := os.Remove(it.path); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			if len(entries)+len(unused)+len(leftovers) == 0 {
 				return nil
 			}
 
@@ -49,11 +159,170 @@ func newPruneCmd() *pruneCmd {
 				}
 			}
 
+			for _, it := range leftovers {
+				if err := os.Remove(it.path); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+			pathsToDel := make([]string, 0, len(entries)+len(unused))
+			for _, it := range entries {
+				pathsToDel = append(pathsToDel, it.path)
+			}
+			for _, it := range unused {
+				if err := removeBinary(os.ExpandEnv(it.path)); err != nil {
+					return err
+				}
+				pathsToDel = append(pathsToDel, it.path)
+			}
+			for _, p := range pathsToDel {
+				if err := removeExtras(cfg.Bins[p]); err != nil {
+					return err
+				}
+			}
+
 			return config.RemoveBinaries(pathsToDel)
 		},
 	}
 
 	root.cmd = cmd
 	root.cmd.Flags().BoolVarP(&root.opts.force, "force", "f", false, "Bypass confirmation prompt")
+	root.cmd.Flags().BoolVarP(&root.opts.force, "yes", "y", false, "Bypass confirmation prompt")
+	root.cmd.Flags().StringVar(&root.opts.unused, "unused", "", "Also remove the binaries not run for longer than this (i.e. 180d), requires 'bin stats enable'")
+	root.cmd.Flags().BoolVar(&root.opts.files, "files", false, "Also remove the files bin installed in the managed directories which aren't in the configuration anymore")
+	root.cmd.Flags().BoolVar(&root.opts.dryRun, "dry-run", false, "Only print what would be removed")
+	root.cmd.Flags().StringVar(&root.opts.maxAge, "max-age", "7d", "Age of the interrupted downloads, cached responses and staged files swept")
 	return root
 }
+
+// managedDirs returns the default path and
+// the directories of the managed binaries
+func managedDirs() []string {
+	cfg := config.Get()
+	seen := map[string]bool{}
+	dirs := []string{}
+	add := func(d string) {
+		if d != "" && !seen[d] {
+			seen[d] = true
+			dirs = append(dirs, d)
+		}
+	}
+	add(os.ExpandEnv(cfg.DefaultPath))
+	for p := range cfg.Bins {
+		add(filepath.Dir(os.ExpandEnv(p)))
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// leftoverFiles returns the files of the managed directories which bin
+// installed but no binary references anymore: the ones labeled by bin
+// and the ones with the content of a managed binary, left behind when
+// its path changed
+func leftoverFiles() ([]pruneItem, error) {
+	// the managed binaries by size then hash, only
+	// the files of the same size are hashed
+	hashes := map[int64]map[string]string{}
+	for _, b := range config.Get().Bins {
+		fi, err := os.Stat(os.ExpandEnv(b.Path))
+		if err != nil || b.Hash == "" || !fi.Mode().IsRegular() {
+			continue
+		}
+		if hashes[fi.Size()] == nil {
+			hashes[fi.Size()] = map[string]string{}
+		}
+		hashes[fi.Size()][b.Hash] = os.ExpandEnv(b.Path)
+	}
+
+	items := []pruneItem{}
+	for _, d := range managedDirs() {
+		entries, err := os.ReadDir(d)
+		if err != nil {
+			if os.IsNotExist(err) {
+				continue
+			}
+			return nil, err
+		}
+		for _, e := range entries {
+			p := filepath.Join(d, e.Name())
+			if !e.Type().IsRegular() || stagedFileRe.MatchString(e.Name()) || config.Owner(p) != nil {
+				continue
+			}
+			if o, err := ownerOf(p); err == nil && o.orphaned() {
+				items = append(items, pruneItem{p, fmt.Sprintf("installed from %s but not in the configuration", o.label.Source)})
+				continue
+			}
+			fi, err := e.Info()
+			if err != nil || hashes[fi.Size()] == nil {
+				continue
+			}
+			sum, err := fileSHA256(p)
+			if err != nil {
+				return nil, err
+			}
+			if managed, ok := hashes[fi.Size()][sum]; ok {
+				items = append(items, pruneItem{p, fmt.Sprintf("copy of %s left behind", managed)})
+			}
+		}
+	}
+	return items, nil
+}
+
+// staleFiles returns the interrupted downloads, the cached responses
+// and the files staged by interrupted installs older than maxAge. The
+// results of the update checks aren't a cache, they're kept
+func staleFiles(maxAge time.Duration) ([]pruneItem, error) {
+	items := []pruneItem{}
+	old := func(fi fs.FileInfo) bool {
+		return time.Since(fi.ModTime()) >= maxAge
+	}
+
+	checks, err := checkCachePath()
+	if err != nil {
+		return nil, err
+	}
+	downloads, err := config.GetDownloadsDir()
+	if err != nil {
+		return nil, err
+	}
+	cache, err := config.GetCacheDir()
+	if err != nil {
+		return nil, err
+	}
+	for dir, reason := range map[string]string{downloads: "interrupted download", cache: "cached response"} {
+		err := filepath.WalkDir(dir, func(p string, e fs.DirEntry, err error) error {
+			if err != nil {
+				if os.IsNotExist(err) {
+					return nil
+				}
+				return err
+			}
+			if e.IsDir() || p == checks {
+				return nil
+			}
+			if fi, err := e.Info(); err == nil && old(fi) {
+				items = append(items, pruneItem{p, reason})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, d := range managedDirs() {
+		entries, err := os.ReadDir(d)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !stagedFileRe.MatchString(e.Name()) {
+				continue
+			}
+			if fi, err := e.Info(); err == nil && old(fi) {
+				items = append(items, pruneItem{filepath.Join(d, e.Name()), "staged by an interrupted install"})
+			}
+		}
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].path < items[j].path })
+	return items, nil
+}
@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestPruneFiles(t *testing.T) {
+	dir, binDir := newTestConfig(t, "")
+	downloads := filepath.Join(dir, "downloads")
+	if err := os.Mkdir(downloads, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("#!/bin/sh\necho pruned\n")
+	kept, gone := filepath.Join(binDir, "prunedkept"), filepath.Join(binDir, "prunedgone")
+	if err := config.CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []*config.Binary{
+		{Path: kept, URL: "https://github.com/o/prunedkept", Provider: "github", Version: "v1.0.0", Hash: fmt.Sprintf("%x", sha256.Sum256(content))},
+		{Path: gone, URL: "https://github.com/o/prunedgone", Provider: "github", Version: "v1.0.0"},
+	} {
+		if err := config.UpsertBinary(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	files := map[string][]byte{
+		kept: content,
+		// left behind by a change of path
+		filepath.Join(binDir, "prunedcopy"):          content,
+		filepath.Join(binDir, "unrelated"):           []byte("#!/bin/sh\necho mine\n"),
+		filepath.Join(binDir, ".prunedkept.bin-123"): content,
+		filepath.Join(downloads, "old.part"):         []byte("partial"),
+		filepath.Join(downloads, "recent.part"):      []byte("partial"),
+	}
+	for p, c := range files {
+		if err := os.WriteFile(p, c, 0o755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, p := range []string{filepath.Join(binDir, ".prunedkept.bin-123"), filepath.Join(downloads, "old.part")} {
+		if err := os.Chtimes(p, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := string(captureStdout(t, func() {
+		Execute("test", func(code int) { t.Fatalf("prune --dry-run exited with %d", code) }, []string{"prune", "--files", "--dry-run"})
+	}))
+	want := fmt.Sprintf(`Forget %s: not found
+Remove %s: copy of %s left behind
+Remove %s: staged by an interrupted install
+Remove %s: interrupted download
+`, gone, filepath.Join(binDir, "prunedcopy"), kept, filepath.Join(binDir, ".prunedkept.bin-123"), filepath.Join(downloads, "old.part"))
+	// the binaries of the other tests leak into the configuration
+	got := ""
+	for _, l := range strings.SplitAfter(out, "\n") {
+		if strings.Contains(l, dir) {
+			got += l
+		}
+	}
+	if got != want {
+		t.Fatalf("expected the dry run to print\n%s\ngot\n%s", want, got)
+	}
+	for p := range files {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("expected the dry run not to remove anything, got %v", err)
+		}
+	}
+	if _, ok := config.Get().Bins[gone]; !ok {
+		t.Fatal("expected the dry run not to change the configuration")
+	}
+
+	Execute("test", func(code int) { t.Fatalf("prune exited with %d", code) }, []string{"prune", "--files", "--yes"})
+	for p := range files {
+		_, err := os.Stat(p)
+		removed := os.IsNotExist(err)
+		if shouldRemove := strings.Contains(got, p+":"); removed != shouldRemove {
+			t.Errorf("%s: expected removed to be %v, got %v (%v)", p, shouldRemove, removed, err)
+		}
+	}
+	if _, ok := config.Get().Bins[gone]; ok {
+		t.Error("expected the entry of the missing binary to be removed")
+	}
+	if _, ok := config.Get().Bins[kept]; !ok {
+		t.Error("expected the present binary to be kept")
+	}
+}
@@ -2,7 +2,6 @@ package cmd
 
 import (
 	"errors"
-	"fmt"
 	"os"
 
 	"github.com/marcosnils/bin/pkg/config"
@@ -17,12 +16,14 @@ func newRemoveCmd() *removeCmd {
 	root := &removeCmd{}
 	// nolint: dupl
 	cmd := &cobra.Command{
-		Use:           "remove [<name> | <paths...>]",
-		Aliases:       []string{"rm"},
-		Short:         "Removes binaries managed by bin",
-		SilenceUsage:  true,
-		Args:          cobra.MinimumNArgs(1),
-		SilenceErrors: true,
+		Use:               "remove [<name> | <paths...>]",
+		ValidArgsFunction: completeBinaries(0),
+		Annotations:       map[string]string{writesConfig: "true"},
+		Aliases:           []string{"rm"},
+		Short:             "Removes binaries managed by bin",
+		SilenceUsage:      true,
+		Args:              cobra.MinimumNArgs(1),
+		SilenceErrors:     true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := config.Get()
 
@@ -43,8 +44,11 @@ func newRemoveCmd() *removeCmd {
 						// TODO some providers (like docker) might download
 						// additional things somewhere else, maybe we should
 						// call the provider to do a cleanup here.
-						if err := os.Remove(os.ExpandEnv(bp)); err != nil && !os.IsNotExist(err) {
-							return fmt.Errorf("Error removing path %s: %v", os.ExpandEnv(bp), err)
+						if err := removeBinary(os.ExpandEnv(bp)); err != nil {
+							return err
+						}
+						if err := removeExtras(b); err != nil {
+							return err
 						}
 						continue
 					}
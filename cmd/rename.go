@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+type renameCmd struct {
+	cmd *cobra.Command
+}
+
+func newRenameCmd() *renameCmd {
+	root := &renameCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "rename <name | path> <new name | path>",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Renames a managed binary",
+		Long:          "Renames a managed binary, or moves it when the new name is a path. The file is moved and its entry updated together: the file is moved back when the configuration can't be written. The updates keep the new name.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			key, err := getBinPath(args[0])
+			if err != nil {
+				return err
+			}
+			b := config.Get().Bins[key]
+			from := os.ExpandEnv(key)
+			to := args[1]
+			if strings.ContainsAny(to, `/\`) {
+				if to, err = filepath.Abs(os.ExpandEnv(to)); err != nil {
+					return err
+				}
+			} else {
+				if err := validateName(to); err != nil {
+					return err
+				}
+				to = filepath.Join(filepath.Dir(from), to)
+			}
+			if !b.IsFile() {
+				to = executablePath(to)
+			}
+
+			if to == from {
+				return fmt.Errorf("%s is already named %s", from, filepath.Base(to))
+			}
+			for p, o := range config.Get().Bins {
+				if os.ExpandEnv(p) == to {
+					return fmt.Errorf("%s is already the name of a binary installed from %s", to, o.URL)
+				}
+			}
+			if _, err := os.Lstat(to); err == nil {
+				return fmt.Errorf("%s already exists", to)
+			}
+
+			m := &pathMove{b: b, key: key, from: from, to: to}
+			if err := moveBinary(m); err != nil {
+				return errors.Join(err, undoMove(m))
+			}
+			if err := config.MovePaths(map[string]string{key: to}, ""); err != nil {
+				return errors.Join(err, undoMove(m))
+			}
+			log.WithField("from", from).WithField("path", to).Info("Renamed")
+			warnShadowed(config.Get().Bins[to])
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
+
+// undoMove moves the file of the binary back, if it was moved,
+// so it stays where the configuration records it
+func undoMove(m *pathMove) error {
+	if _, err := os.Lstat(m.from); !os.IsNotExist(err) {
+		return nil
+	}
+	if _, err := os.Lstat(m.to); err != nil {
+		return nil
+	}
+	log.WithField("from", m.to).WithField("path", m.from).Warn("Moving the binary back")
+	return moveBinary(&pathMove{b: m.b, key: m.key, from: m.to, to: m.from})
+}
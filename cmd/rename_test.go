@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestInstallNameAndRename(t *testing.T) {
+	dir, binDir := newTestConfig(t, `"require_checksum": false`)
+	for _, n := range []string{"tool-v1.2.3-linux-amd64", "other"} {
+		writeScript(t, filepath.Join(dir, n), n)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", filepath.Join(dir, "tool-v1.2.3-linux-amd64"), "--name", "tool"})
+	tool := filepath.Join(binDir, "tool")
+	if _, err := os.Stat(tool); err != nil {
+		t.Fatalf("expected the binary to be installed under its name: %v", err)
+	}
+	if b := config.Get().Bins[tool]; b == nil {
+		t.Fatalf("expected the binary to be recorded at %s", tool)
+	}
+
+	// another binary can't take the name
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"install", filepath.Join(dir, "other"), "--name", "tool"})
+	if code == 0 {
+		t.Error("expected the name of a managed binary to be refused")
+	}
+	code = 0
+	Execute("test", func(c int) { code = c }, []string{"install", filepath.Join(dir, "other"), "--name", "../tool"})
+	if code == 0 {
+		t.Error("expected a path to be refused as name")
+	}
+
+	Execute("test", func(code int) { t.Fatalf("rename exited with %d", code) }, []string{"rename", tool, "tl"})
+	renamed := filepath.Join(binDir, "tl")
+	if _, err := os.Stat(tool); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be moved, got %v", tool, err)
+	}
+	if _, err := os.Stat(renamed); err != nil {
+		t.Fatalf("expected the binary to be renamed: %v", err)
+	}
+	if _, ok := config.Get().Bins[tool]; ok {
+		t.Error("expected the previous entry to be removed")
+	}
+	if b := config.Get().Bins[renamed]; b == nil || b.Path != renamed {
+		t.Fatalf("expected the entry to follow the file, got %+v", b)
+	}
+	if modified, err := isModified(config.Get().Bins[renamed]); err != nil || modified {
+		t.Errorf("expected the renamed binary to match its hash, got %v (%v)", modified, err)
+	}
+
+	// the new name can't be taken
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", filepath.Join(dir, "other")})
+	code = 0
+	Execute("test", func(c int) { code = c }, []string{"rename", renamed, "other"})
+	if code == 0 {
+		t.Error("expected renaming to the name of another binary to fail")
+	}
+	if _, err := os.Stat(renamed); err != nil {
+		t.Errorf("expected the binary to stay in place: %v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"sort"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/schema"
+)
+
+// the actions reported by the --json summaries of `bin update`
+// and `bin ensure`, they're part of their schemas
+const (
+	actionUpdated   = "updated"
+	actionAvailable = "available"
+	actionUpToDate  = "up-to-date"
+	actionPinned    = "pinned"
+	actionDeferred  = "deferred"
+	actionInstalled = "installed"
+	actionPresent   = "present"
+	actionResolved  = "resolved"
+	actionRemoved   = "removed"
+	actionFailed    = "failed"
+	actionSkipped   = "skipped"
+)
+
+// actionOutput is the JSON summary of `bin update` and `bin ensure`,
+// see pkg/schema/schemas/update.json and ensure.json
+type actionOutput struct {
+	SchemaVersion int           `json:"schema_version"`
+	Bins          []*actionItem `json:"bins"`
+}
+
+type actionItem struct {
+	Path       string `json:"path"`
+	Action     string `json:"action"`
+	OldVersion string `json:"old_version,omitempty"`
+	NewVersion string `json:"new_version,omitempty"`
+	// Resolution is how the divergence of a
+	// binary was resolved by `bin ensure`
+	Resolution string `json:"resolution,omitempty"`
+	// Relaxed is the relaxation of the asset selection
+	// which found the asset installed by `bin ensure`
+	Relaxed string `json:"relaxed,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// actionReport collects what a batch command did to each binary, the
+// last action recorded for a binary is the one reported
+type actionReport struct {
+	bins map[string]*actionItem
+}
+
+func newActionReport() *actionReport {
+	return &actionReport{bins: map[string]*actionItem{}}
+}
+
+// record sets the action taken on the binary
+func (r *actionReport) record(b *config.Binary, action, oldVersion, newVersion string) *actionItem {
+	p := os.ExpandEnv(b.Path)
+	item := &actionItem{Path: p, Action: action, OldVersion: oldVersion, NewVersion: newVersion}
+	r.bins[p] = item
+	return item
+}
+
+// fail records the failures, the binaries which weren't
+// processed after another one failed are reported as skipped
+func (r *actionReport) fail(failures map[*config.Binary]error) {
+	for b, err := range failures {
+		action := actionFailed
+		if errors.Is(err, errSkipped) {
+			action = actionSkipped
+		}
+		r.record(b, action, b.Version, "").Error = err.Error()
+	}
+}
+
+// output returns the summary, sorted by path
+func (r *actionReport) output() actionOutput {
+	out := actionOutput{SchemaVersion: schema.Version, Bins: make([]*actionItem, 0, len(r.bins))}
+	for _, item := range r.bins {
+		out.Bins = append(out.Bins, item)
+	}
+	sort.Slice(out.Bins, func(i, j int) bool { return out.Bins[i].Path < out.Bins[j].Path })
+	return out
+}
+
+// recordTracked records the changes of a series of binaries installed
+// with --track-latest, group being the binaries before the update
+func (r *actionReport) recordTracked(group []*config.Binary, changed, dryRun bool, err error) {
+	for _, b := range group {
+		switch {
+		case err != nil:
+			r.record(b, actionFailed, b.Version, "").Error = err.Error()
+		case !changed:
+			r.record(b, actionUpToDate, b.Version, "")
+		case dryRun:
+			r.record(b, actionAvailable, b.Version, "")
+		default:
+			nb, ok := config.Get().Bins[b.Path]
+			switch {
+			case !ok:
+				r.record(b, actionRemoved, b.Version, "")
+			case nb.Version != b.Version:
+				r.record(b, actionUpdated, b.Version, nb.Version)
+			default:
+				r.record(b, actionUpToDate, b.Version, "")
+			}
+		}
+	}
+	if err != nil || !changed || dryRun || len(group) == 0 {
+		return
+	}
+	// the series new to the group
+	for _, nb := range config.Get().Bins {
+		if _, ok := r.bins[os.ExpandEnv(nb.Path)]; !ok && nb.TrackLatest > 0 && trackKey(nb) == trackKey(group[0]) {
+			r.record(nb, actionInstalled, "", nb.Version)
+		}
+	}
+}
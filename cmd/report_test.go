@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of testdata")
+
+// golden compares the output with testdata/<name>, the
+// machine-readable outputs are a contract
+func golden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	p := filepath.Join("testdata", name)
+	if *updateGolden {
+		if err := os.WriteFile(p, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("the output doesn't match %s, run the tests with -update if the change is expected:\n%s", p, got)
+	}
+}
+
+// captureStdout returns what fn prints to the standard output
+func captureStdout(t *testing.T, fn func()) []byte {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestActionReportGolden(t *testing.T) {
+	kind := &config.Binary{Path: "/opt/bin/kind", Version: "v0.19.0"}
+	jq := &config.Binary{Path: "/opt/bin/jq", Version: "jq-1.7"}
+	gh := &config.Binary{Path: "/opt/bin/gh", Version: "v2.40.0"}
+	tf := &config.Binary{Path: "/opt/bin/terraform", Version: "1.5.7", Pinned: true}
+	yq := &config.Binary{Path: "/opt/bin/yq", Version: "v4.40.0"}
+
+	r := newActionReport()
+	r.record(kind, actionAvailable, kind.Version, "v0.20.0")
+	r.record(kind, actionUpdated, kind.Version, "v0.20.0")
+	r.record(jq, actionUpToDate, jq.Version, "")
+	r.record(tf, actionPinned, tf.Version, "")
+	r.fail(map[*config.Binary]error{
+		gh: fmt.Errorf("Error while getting latest version of %s: rate limited", gh.Path),
+		yq: errSkipped,
+	})
+	validateOutput(t, "update", r.output())
+	var out bytes.Buffer
+	if err := writeJSON(&out, r.output()); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "update.json.golden", out.Bytes())
+
+	r = newActionReport()
+	r.record(kind, actionInstalled, "", kind.Version)
+	r.record(jq, actionPresent, jq.Version, "")
+	r.record(tf, actionResolved, tf.Version, "1.6.0").Resolution = string(resolveKeepLocal)
+	r.fail(map[*config.Binary]error{gh: errors.New("error fetching /opt/bin/gh: not found")})
+	validateOutput(t, "ensure", r.output())
+	out.Reset()
+	if err := writeJSON(&out, r.output()); err != nil {
+		t.Fatal(err)
+	}
+	golden(t, "ensure.json.golden", out.Bytes())
+}
+
+func TestEnsureJSON(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handler of the test is a shell script")
+	}
+	dir := t.TempDir()
+	writeScript(t, filepath.Join(dir, "summarized-1.0.0"), "1.0.0")
+	handlers := fmt.Sprintf(`{"corp://": {"latest": "echo 1.0.0", "download": "cp %s/summarized-$BIN_VERSION $BIN_OUTPUT_DIR/summarized"}}`, dir)
+	_, binDir := newTestConfig(t, `"require_checksum": false, "handlers": `+handlers)
+	tool := filepath.Join(binDir, "summarized")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "corp://team/summarized"})
+
+	ensure := func() actionOutput {
+		var out actionOutput
+		b := captureStdout(t, func() {
+			Execute("test", func(code int) { t.Fatalf("ensure exited with %d", code) }, []string{"ensure", "--json", "summarized"})
+		})
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("expected a JSON summary, got %q: %v", b, err)
+		}
+		return out
+	}
+	if out := ensure(); len(out.Bins) != 1 || out.Bins[0].Action != actionPresent {
+		t.Fatalf("expected the binary to be present, got %+v", out.Bins)
+	}
+	if err := os.Remove(tool); err != nil {
+		t.Fatal(err)
+	}
+	if out := ensure(); len(out.Bins) != 1 || out.Bins[0].Action != actionInstalled || out.Bins[0].NewVersion != "1.0.0" {
+		t.Fatalf("expected the binary to be installed again, got %+v", out.Bins)
+	}
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/spf13/cobra"
+)
+
+type reproduceCmd struct {
+	cmd *cobra.Command
+}
+
+// reproduction is the outcome of re-processing the asset of a binary
+type reproduction struct {
+	Path  string
+	Asset string
+	// AssetChanged is set when the digest of the remote asset
+	// isn't the one recorded when the binary was installed
+	AssetChanged bool
+	Installed    string
+	Rebuilt      string
+	// Modified is set when the installed file isn't the one
+	// recorded when it was installed
+	Modified   bool
+	Normalized []normalization
+}
+
+// normalization is a metadata of the archive entry
+// which isn't kept by the installed file
+type normalization struct {
+	Field     string
+	Archive   string
+	Installed string
+}
+
+func (r *reproduction) reproducible() bool {
+	return r.Installed == r.Rebuilt
+}
+
+func newReproduceCmd() *reproduceCmd {
+	root := &reproduceCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "reproduce <binary>",
+		Short: "Checks that re-processing the asset of a binary yields the installed file",
+		Long: `Checks that re-processing the asset of a binary yields the installed file.
+
+The asset of the installed version is downloaded again and goes through
+the same extraction, the digest of the result is compared with the one
+of the installed file. The metadata of the archive entry which isn't kept
+on install (mode, modification time, owner) is reported as normalized.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := getBinPath(args[0])
+			if err != nil {
+				return err
+			}
+			r, err := reproduce(config.Get().Bins[p])
+			if err != nil {
+				return err
+			}
+			printReproduction(os.Stdout, r)
+			if !r.reproducible() {
+				return fmt.Errorf("%s is not reproducible", r.Path)
+			}
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
+
+// reproduce fetches the installed version of the binary again, without
+// installing it, and compares the result with the installed file
+func reproduce(b *config.Binary) (*reproduction, error) {
+	p := os.ExpandEnv(b.Path)
+	installed, mode, err := installedDigest(p)
+	if err != nil {
+		return nil, err
+	}
+
+	pv, err := newProvider(b)
+	if err != nil {
+		return nil, err
+	}
+	log.Debugf("Using provider '%s' for '%s'", pv.GetID(), b.URL)
+
+	f, err := pv.Fetch(&providers.FetchOpts{Version: b.Version, PackagePath: b.PackagePath, PackageName: b.RemoteName, Formats: b.PreferFormat, SelectedAsset: b.SelectedAsset, RequireChecksum: config.Get().RequireChecksum, Files: b.IsFile(), Mirrors: b.Mirrors, MirrorFirst: b.MirrorFirst, SigningKeys: config.SigningKeys(b), RequireSignature: config.Get().RequireSignature, AssetRewrites: b.AssetRewrites, BuildFromSource: b.BuildFromSource, MainPackage: b.MainPackage})
+	if err != nil {
+		return nil, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f.Data); err != nil {
+		return nil, err
+	}
+
+	r := &reproduction{
+		Path:         p,
+		Asset:        f.Asset,
+		AssetChanged: b.AssetDigest != "" && f.AssetDigest != "" && b.AssetDigest != f.AssetDigest,
+		Installed:    installed,
+		Rebuilt:      fmt.Sprintf("%x", h.Sum(nil)),
+		Modified:     b.Hash != "" && b.Hash != installed,
+	}
+	if r.Asset == "" {
+		r.Asset = f.Name
+	}
+	r.Normalized = normalizations(f, mode)
+	return r, nil
+}
+
+// installedDigest returns the sha256 digest and the mode of the
+// installed file, the real binary when it's a shim
+func installedDigest(p string) (string, os.FileInfo, error) {
+	f, err := os.Open(stats.Resolve(p))
+	if err != nil {
+		return "", nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return "", nil, err
+	}
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), fi, nil
+}
+
+// normalizations compares the metadata of the archive entry
+// the file comes from with the one of the installed file
+func normalizations(f *providers.File, installed os.FileInfo) []normalization {
+	e := f.Entry
+	if e == nil {
+		return nil
+	}
+	var n []normalization
+	// Windows has no permission bits, they're never preserved
+	if e.Mode != 0 && runtime.GOOS != "windows" && e.Mode.Perm() != installed.Mode().Perm() {
+		n = append(n, normalization{"mode", fmt.Sprintf("%#o", e.Mode.Perm()), fmt.Sprintf("%#o", installed.Mode().Perm())})
+	}
+	if !e.ModTime.IsZero() {
+		n = append(n, normalization{"modification time", e.ModTime.UTC().Format(time.RFC3339), installed.ModTime().UTC().Format(time.RFC3339)})
+	}
+	if e.Owner != "" {
+		n = append(n, normalization{"owner", e.Owner, "the user installing it"})
+	}
+	return n
+}
+
+func printReproduction(w io.Writer, r *reproduction) {
+	fmt.Fprintf(w, "%s  %s\n", _rPad("Binary", 10), r.Path)
+	fmt.Fprintf(w, "%s  %s\n", _rPad("Asset", 10), r.Asset)
+	fmt.Fprintf(w, "%s  sha256:%s\n", _rPad("Installed", 10), r.Installed)
+	fmt.Fprintf(w, "%s  sha256:%s\n", _rPad("Rebuilt", 10), r.Rebuilt)
+	for _, n := range r.Normalized {
+		fmt.Fprintf(w, "%s  %s %s -> %s\n", _rPad("Normalized", 10), n.Field, n.Archive, n.Installed)
+	}
+	if r.AssetChanged {
+		fmt.Fprintf(w, "\n%s the asset changed since the binary was installed\n", color.YellowString("Warning:"))
+	}
+	if r.Modified {
+		fmt.Fprintf(w, "\n%s the installed file was modified since it was installed\n", color.YellowString("Warning:"))
+	}
+	if r.reproducible() {
+		fmt.Fprintf(w, "\n%s\n", color.GreenString("Reproducible"))
+	} else {
+		fmt.Fprintf(w, "\n%s\n", color.RedString("Not reproducible"))
+	}
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestReproduce(t *testing.T) {
+	dir, binDir := newTestConfig(t, "")
+
+	archive := func(content string) {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		if err := tw.WriteHeader(&tar.Header{Name: "tool", Mode: 0o700, Size: int64(len(content)), ModTime: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "tool.tar"), buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	archive("#!/bin/sh\necho tool\n")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", filepath.Join(dir, "tool.tar")})
+
+	r, err := reproduce(config.Get().Bins[filepath.Join(binDir, "tool")])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !r.reproducible() {
+		t.Fatalf("expected the binary to be reproducible, got %+v", r)
+	}
+	var out bytes.Buffer
+	printReproduction(&out, r)
+	if !strings.Contains(out.String(), "modification time 2024-01-02T03:04:05Z") || !strings.Contains(out.String(), "mode 0700") {
+		t.Errorf("expected the normalized metadata to be reported, got:\n%s", out.String())
+	}
+
+	archive("#!/bin/sh\necho changed\n")
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"reproduce", "tool"})
+	if code == 0 {
+		t.Error("expected a changed asset not to be reproducible")
+	}
+}
@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/marcosnils/bin/pkg/store"
+	"github.com/spf13/cobra"
+)
+
+type rollbackCmd struct {
+	cmd  *cobra.Command
+	opts rollbackOpts
+}
+
+type rollbackOpts struct {
+	list bool
+}
+
+func newRollbackCmd() *rollbackCmd {
+	root := &rollbackCmd{}
+
+	cmd := &cobra.Command{
+		Use:               "rollback <binary>",
+		ValidArgsFunction: completeBinaries(1),
+		Annotations:       map[string]string{writesConfig: "true"},
+		Short:             "Restores the previously installed version of a binary",
+		Long: `Restores the previously installed version of a binary from the store,
+it's pinned so the next bin update doesn't upgrade it again.
+
+The versions are only kept with versioned_installs, --list shows them.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := managedBinPath(args[0])
+			if err != nil {
+				return err
+			}
+			b := config.Get().Bins[p]
+			versions, active, err := storedVersions(b)
+			if err != nil {
+				return err
+			}
+			if root.opts.list {
+				printStoredVersions(os.Stdout, versions, active)
+				return nil
+			}
+			return rollback(b, versions, active)
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.list, "list", false, "List the versions kept to roll back to")
+	return root
+}
+
+// storedVersions returns the versions of the binary kept
+// in the store, newest first, and the active one
+func storedVersions(b *config.Binary) ([]store.Version, string, error) {
+	st, err := versionStore()
+	if err != nil {
+		return nil, "", err
+	}
+	p := os.ExpandEnv(b.Path)
+	active, ok := st.Active(stats.Resolve(p))
+	if !ok {
+		return nil, "", fmt.Errorf("no previous version of %s is kept, set versioned_installs in the configuration to keep them", p)
+	}
+	versions, err := st.Versions(p)
+	return versions, active, err
+}
+
+// rollback activates the version stored before the active one,
+// its entry is restored from what was recorded along it and pinned
+func rollback(b *config.Binary, versions []store.Version, active string) error {
+	p := os.ExpandEnv(b.Path)
+	var to *store.Version
+	for i, v := range versions {
+		if v.Path == active && i+1 < len(versions) {
+			to = &versions[i+1]
+		}
+	}
+	if to == nil {
+		return fmt.Errorf("no version of %s older than %s is kept to roll back to", p, b.Version)
+	}
+
+	st, err := versionStore()
+	if err != nil {
+		return err
+	}
+	if err := st.Activate(to.Path, stats.Resolve(p)); err != nil {
+		return fmt.Errorf("Error activating %s %s: %w", p, to.Version, err)
+	}
+	hash, err := fileSHA256(to.Path)
+	if err != nil {
+		return err
+	}
+
+	nb := *b
+	nb.Version = to.Version
+	// the provenance of the versions stored without a record isn't known
+	nb.AssetURL, nb.InstalledAt = "", nil
+	if len(to.Meta) > 0 {
+		var sv storedVersion
+		if err := json.Unmarshal(to.Meta, &sv); err != nil {
+			return fmt.Errorf("invalid record of %s %s in the store: %w", p, to.Version, err)
+		}
+		nb.Version = sv.Version
+		nb.RemoteName, nb.PackagePath = sv.RemoteName, sv.PackagePath
+		nb.InstalledAsset, nb.AssetSHA256, nb.AssetDigest = sv.InstalledAsset, sv.AssetSHA256, sv.AssetDigest
+		nb.VerifiedWith, nb.SignedBy, nb.SignedFile = sv.VerifiedWith, sv.SignedBy, sv.SignedFile
+		nb.AssetURL, nb.InstalledAt = sv.AssetURL, sv.InstalledAt
+	}
+	nb.Hash = hash
+	nb.Pinned = true
+	if err := config.UpsertBinary(&nb); err != nil {
+		return err
+	}
+
+	binLog(&nb).WithField("from", b.Version).WithField("version", nb.Version).Info("Rolled back")
+	binLog(&nb).WithField("version", nb.Version).Info("Pinned, unpin it to get updates again")
+	return nil
+}
+
+// printStoredVersions lists the versions kept in the store
+func printStoredVersions(w io.Writer, versions []store.Version, active string) {
+	vL, sL := len("Version"), len("Stored")
+	names, ages := make([]string, len(versions)), make([]string, len(versions))
+	for i, v := range versions {
+		names[i] = v.Version
+		var sv storedVersion
+		if json.Unmarshal(v.Meta, &sv) == nil && sv.Version != "" {
+			names[i] = sv.Version
+		}
+		ages[i] = formatAge(time.Since(v.StoredAt)) + " ago"
+		vL, sL = max(vL, len(names[i])), max(sL, len(ages[i]))
+	}
+
+	magentaItalic := color.New(color.FgMagenta, color.Italic).Sprint
+	fmt.Fprintf(w, "%s  %s  %s\n", magentaItalic(_rPad("Version", vL)), magentaItalic(_rPad("Stored", sL)), magentaItalic("Status"))
+	for i, v := range versions {
+		status := ""
+		if v.Path == active {
+			status = color.GreenString("active")
+		}
+		fmt.Fprintf(w, "%s  %s  %s\n", _rPad(names[i], vL), _rPad(ages[i], sL), status)
+	}
+}
@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestRollback(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	installed := executablePath(filepath.Join(versionedDemo(t, forge), "tool"))
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool/releases/tag/v1.0.0"})
+	first := *config.Get().Bins[installed]
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", installed})
+
+	out := captureStdout(t, func() {
+		Execute("test", func(code int) { t.Fatalf("rollback exited with %d", code) }, []string{"rollback", "--list", installed})
+	})
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[1], "v1.1.0") || !strings.Contains(lines[1], "active") || !strings.HasPrefix(lines[2], "v1.0.0") {
+		t.Fatalf("expected v1.1.0 active and v1.0.0 kept, got %s", out)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("rollback exited with %d", code) }, []string{"rollback", installed})
+	if b, _ := os.ReadFile(installed); string(b) != string(fakeforge.Script("tool", "v1.0.0")) {
+		t.Fatalf("expected v1.0.0 to be restored, got %q", b)
+	}
+	b := config.Get().Bins[installed]
+	if b.Version != "v1.0.0" || !b.Pinned || b.Hash != first.Hash || b.InstalledAsset != first.InstalledAsset {
+		t.Fatalf("expected the entry of v1.0.0 to be restored and pinned, got %+v", b)
+	}
+
+	// v1.0.0 is the oldest version kept
+	versions, active, err := storedVersions(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rollback(b, versions, active); err == nil || !strings.Contains(err.Error(), "no version of") {
+		t.Fatalf("expected nothing to roll back to, got %v", err)
+	}
+}
+
+// TestRollbackRenamed checks the versions in the store follow
+// the binary when it's renamed
+func TestRollbackRenamed(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	binDir := versionedDemo(t, forge)
+	installed := executablePath(filepath.Join(binDir, "tool"))
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool/releases/tag/v1.0.0"})
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", installed})
+	st, err := versionStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := filepath.Dir(filepath.Dir(st.Path(installed, "v1.0.0")))
+
+	Execute("test", func(code int) { t.Fatalf("rename exited with %d", code) }, []string{"rename", installed, "tl"})
+	renamed := executablePath(filepath.Join(binDir, "tl"))
+	if _, err := os.Stat(previous); !os.IsNotExist(err) {
+		t.Fatalf("expected the versions to be moved out of %s, got %v", previous, err)
+	}
+	if b, _ := os.ReadFile(renamed); string(b) != string(fakeforge.Script("tool", "v1.1.0")) {
+		t.Fatalf("expected v1.1.0 to stay active, got %q", b)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("rollback exited with %d", code) }, []string{"rollback", renamed})
+	if b, _ := os.ReadFile(renamed); string(b) != string(fakeforge.Script("tool", "v1.0.0")) {
+		t.Fatalf("expected v1.0.0 to be restored, got %q", b)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("remove exited with %d", code) }, []string{"remove", renamed})
+	if entries, err := os.ReadDir(filepath.Dir(previous)); err != nil || len(entries) != 0 {
+		t.Fatalf("expected the store to be emptied, got %v, %v", entries, err)
+	}
+}
@@ -7,9 +7,13 @@ import (
 	"path/filepath"
 	"strings"
 
-	"github.com/caarlos0/log"
 	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/assets"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/journal"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	bstrings "github.com/marcosnils/bin/pkg/strings"
 	"github.com/spf13/cobra"
 )
 
@@ -48,7 +52,46 @@ func (cmd *rootCmd) Execute(args []string) {
 type rootCmd struct {
 	cmd   *cobra.Command
 	debug bool
-	exit  func(int)
+	pure  bool
+	env   []string
+
+	readOnly    bool
+	libc        string
+	arch        string
+	noFallback  bool
+	debugAssets bool
+	quiet       bool
+	configPath  string
+	logFormat   string
+	exit        func(int)
+
+	traceHTTP      string
+	traceHTTPBody  bool
+	traceBodyLimit int
+}
+
+// settings resolves the ambient configuration of the providers.
+// It's populated once from the root flags before running any command
+// nolint: gochecknoglobals
+var settings *providers.Settings
+
+// writesConfig annotates the commands which can't
+// run with a read-only configuration
+const writesConfig = "writes-config"
+
+// checkReadOnly refuses the commands writing the configuration
+// before doing anything when it's read-only
+func checkReadOnly(cmd *cobra.Command) error {
+	if !config.ReadOnly() || cmd.Annotations[writesConfig] == "" {
+		return nil
+	}
+	// dry runs, checks and listings only report what would be done
+	for _, name := range []string{"dry-run", "check", "list"} {
+		if f := cmd.Flags().Lookup(name); f != nil && f.Value.String() == "true" {
+			return nil
+		}
+	}
+	return fmt.Errorf("'%s' needs to write the configuration, which is read-only", cmd.CommandPath())
 }
 
 func newRootCmd(version string, exit func(int)) *rootCmd {
@@ -62,29 +105,149 @@ func newRootCmd(version string, exit func(int)) *rootCmd {
 		SilenceUsage:  true,
 		SilenceErrors: true,
 		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			if err := log.SetFormat(root.logFormat); err != nil {
+				log.Fatalf("%v", err)
+			}
 			if root.debug {
 				log.SetLevel(log.DebugLevel)
 				log.Debugf("debug logs enabled, version: %s\n", version)
 			}
 
+			explicit := map[string]string{}
+			for _, e := range root.env {
+				k, v, ok := strings.Cut(e, "=")
+				if !ok {
+					log.Fatalf("Invalid --env value %q, expected KEY=VALUE", e)
+				}
+				explicit[k] = v
+			}
+			settings = providers.NewSettings(root.pure, explicit)
+			if root.traceHTTP != "" {
+				w := os.Stderr
+				if root.traceHTTP != "-" {
+					f, err := os.OpenFile(root.traceHTTP, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o600)
+					if err != nil {
+						log.Fatalf("Error opening the HTTP trace file %v", err)
+					}
+					// bin is a short lived CLI, the file is closed on exit
+					w = f
+				}
+				settings.Trace(w, root.traceHTTPBody, root.traceBodyLimit)
+			}
+			if root.pure {
+				log.Debugf("pure mode enabled, ignoring the environment")
+			}
+			if demoEnabled() {
+				if err := startDemo(settings); err != nil {
+					log.Fatalf("Error starting the demo mode %v", err)
+				}
+			}
+
 			// check and load config after handlers are configured
+			config.SetReadOnly(root.readOnly)
+			config.SetPath(root.configPath)
 			err := config.CheckAndLoad()
 			if err != nil {
 				log.Fatalf("Error loading config file %v", err)
 			}
+			if err := checkReadOnly(cmd); err != nil {
+				log.Fatalf("%v", err)
+			}
+			if err := checkTrusted(cmd); err != nil {
+				log.Fatalf("%v", err)
+			}
+
+			settings.Configure(config.Get().Settings)
+			settings.SetHandlers(config.Get().Handlers)
+			if dir, err := config.GetCacheDir(); err == nil {
+				settings.SetCacheDir(dir)
+			}
+			if dir, err := config.GetDownloadsDir(); err == nil && !config.ReadOnly() {
+				assets.SetDownloadDir(dir)
+			}
+			if err := config.SetLibc(root.libc); err != nil {
+				log.Fatalf("%v", err)
+			}
+			if err := config.SetArch(root.arch); err != nil {
+				log.Fatalf("%v", err)
+			}
+			config.SetFallbackArch(!root.noFallback)
+			assets.SetQuiet(root.quiet)
+			if root.debugAssets {
+				assets.SetScoreReporter(func(repoName string, scored []*assets.ScoredAsset) {
+					printScores(os.Stderr, repoName, scored)
+				})
+			}
+			if v, _ := config.ResolveMinTLSVersion(nil); v != "" {
+				if err := settings.SetMinTLSVersion(v); err != nil {
+					log.Fatalf("Error loading config file %v", err)
+				}
+			}
+
+			// clean up the installs interrupted in previous runs
+			if config.ReadOnly() {
+				return
+			}
+			jd, err := config.GetJournalDir()
+			if err == nil {
+				err = journal.Recover(jd)
+			}
+			if err != nil {
+				log.WithError(err).Warn("Error recovering interrupted installs")
+			}
 		},
 	}
 
 	cmd.PersistentFlags().BoolVar(&root.debug, "debug", false, "Enable debug mode")
+	cmd.PersistentFlags().StringVar(&root.logFormat, "log-format", log.FormatText, "Format of the logs on stderr: text, or json for one object per line with the level, timestamp, message and fields (binary, provider, version, url, bytes, ...)")
+	cmd.PersistentFlags().StringVar(&root.configPath, "config", "", "Use the given configuration file, created if needed, instead of the one of the project or the global one (same as BIN_CONFIG)")
+	cmd.PersistentFlags().BoolVar(&root.readOnly, "read-only", false, "Never write the configuration, commands needing to do so are refused up front")
+	cmd.PersistentFlags().StringVar(&root.libc, "libc", "", "Pick the assets built for the given libc (musl, gnu or any) instead of the one detected on the host")
+	cmd.PersistentFlags().StringVar(&root.arch, "arch", "", "Pick the assets built for the given architecture (i.e. arm64 or armv7) instead of the host's one")
+	cmd.PersistentFlags().BoolVar(&root.noFallback, "no-fallback-arch", false, "Never pick the amd64 assets on arm64 hosts which run them through emulation (i.e. Rosetta), even when there's no native one")
+	cmd.PersistentFlags().BoolVar(&root.debugAssets, "debug-assets", false, "Print the score of every candidate asset, with the contribution of each rule, to debug wrong picks")
+	cmd.PersistentFlags().BoolVar(&root.quiet, "quiet", false, "Don't render progress bars, the progress of the downloads is logged periodically instead")
+	cmd.PersistentFlags().BoolVar(&root.pure, "pure", false, "Ignore the environment (tokens, proxies, ...), only use explicitly provided settings")
+	cmd.PersistentFlags().StringArrayVar(&root.env, "env", nil, "Explicitly set a provider setting as KEY=VALUE (i.e. GITHUB_TOKEN=xxx), honored in pure mode")
+	cmd.PersistentFlags().StringVar(&root.traceHTTP, "trace-http", "", "Log every HTTP request to stderr or to the given file (--trace-http=file), credentials are redacted")
+	cmd.PersistentFlags().Lookup("trace-http").NoOptDefVal = "-"
+	cmd.PersistentFlags().BoolVar(&root.traceHTTPBody, "trace-http-body", false, "Include the bodies of the API and version responses in the HTTP trace, never the ones of binary downloads")
+	cmd.PersistentFlags().IntVar(&root.traceBodyLimit, "trace-http-body-limit", 2048, "Maximum number of bytes of each traced body")
 	cmd.AddCommand(
 		newInstallCmd().cmd,
 		newEnsureCmd().cmd,
 		newUpdateCmd().cmd,
 		newPinCmd().cmd,
+		newLockCmd().cmd,
 		newUnpinCmd().cmd,
 		newRemoveCmd().cmd,
 		newListCmd().cmd,
+		newInfoCmd().cmd,
+		newRollbackCmd().cmd,
+		newStatusCmd().cmd,
+		newSearchCmd().cmd,
 		newPruneCmd().cmd,
+		newRenameCmd().cmd,
+		newMigratePathCmd().cmd,
+		newDoctorCmd().cmd,
+		newOwnsCmd().cmd,
+		newVersionsCmd().cmd,
+		newStatsCmd().cmd,
+		newExplainConfigCmd().cmd,
+		newDuCmd().cmd,
+		newReproduceCmd().cmd,
+		newSplitStateCmd().cmd,
+		newExportCmd().cmd,
+		newImportCmd().cmd,
+		newAdoptCmd().cmd,
+		newSelfUpdateCmd().cmd,
+		newDownloadCmd().cmd,
+		newAuditCmd().cmd,
+		newOutdatedCmd().cmd,
+		newSchemaCmd().cmd,
+		newConfigCmd().cmd,
+		newTrustCmd().cmd,
+		newDemoCmd().cmd,
 	)
 
 	root.cmd = cmd
@@ -126,17 +289,35 @@ func defaultCommand(cmd *cobra.Command, args []string) bool {
 	return true
 }
 
+// managedBinPath is getBinPath suggesting the closest
+// managed binary when name isn't one of them
+func managedBinPath(name string) (string, error) {
+	p, err := getBinPath(name)
+	if err == nil {
+		return p, nil
+	}
+	names := make([]string, 0, len(config.Get().Bins))
+	for _, b := range config.Get().Bins {
+		names = append(names, filepath.Base(b.Path))
+	}
+	base := filepath.Base(name)
+	if closest := bstrings.Closest(base, names); closest != "" && bstrings.Distance(base, closest) <= max(2, len(base)/2) {
+		return "", fmt.Errorf("%s isn't managed by bin, did you mean %s?", name, closest)
+	}
+	return "", fmt.Errorf("%s isn't managed by bin", name)
+}
+
 func getBinPath(name string) (string, error) {
 	var f string
 	f, err := exec.LookPath(name)
 	cfg := config.Get()
 	if err != nil {
-		log.Log.Debugf("binary %s not found in PATH %v", name, err)
-		if !strings.Contains(name, "/") {
-			for _, b := range cfg.Bins {
-				if filepath.Base(b.Path) == name {
-					return b.Path, nil
-				}
+		log.Debugf("binary %s not found in PATH %v", name, err)
+		// the binaries missing on disk are matched by their
+		// name, or their path when it's given
+		for _, b := range cfg.Bins {
+			if os.ExpandEnv(b.Path) == name || filepath.Base(b.Path) == name {
+				return b.Path, nil
 			}
 		}
 		return "", err
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestCheckReadOnly(t *testing.T) {
+	config.SetReadOnly(true)
+	defer config.SetReadOnly(false)
+
+	if err := checkReadOnly(newListCmd().cmd); err != nil {
+		t.Errorf("expected list to be allowed, got %v", err)
+	}
+	if err := checkReadOnly(newInstallCmd().cmd); err == nil {
+		t.Error("expected install to be refused")
+	}
+
+	update := newUpdateCmd().cmd
+	if err := checkReadOnly(update); err == nil {
+		t.Error("expected update to be refused")
+	}
+	if err := update.Flags().Set("dry-run", "true"); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkReadOnly(update); err != nil {
+		t.Errorf("expected update --dry-run to be allowed, got %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/spf13/cobra"
+)
+
+type schemaCmd struct {
+	cmd *cobra.Command
+}
+
+func newSchemaCmd() *schemaCmd {
+	root := &schemaCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "schema [command]",
+		Short:         "Prints the JSON schema of the --json output of a command",
+		Long:          "Prints the JSON schema of the --json output of a command, or the commands having one when none is given",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.MaximumNArgs(1),
+		ValidArgs:     schema.Commands(),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) == 0 {
+				for _, c := range schema.Commands() {
+					fmt.Println(c)
+				}
+				return nil
+			}
+			b, err := schema.Get(args[0])
+			if err != nil {
+				return err
+			}
+			_, err = os.Stdout.Write(b)
+			return err
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
+
+// writeJSON prints the JSON output of a command
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
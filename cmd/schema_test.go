@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+)
+
+// validateOutput checks the JSON output of the
+// command against its embedded schema
+func validateOutput(t *testing.T, command string, v any) {
+	t.Helper()
+	s, err := schema.Get(command)
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(s))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(command+".json", doc); err != nil {
+		t.Fatal(err)
+	}
+	sch, err := c.Compile(command + ".json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := writeJSON(&out, v); err != nil {
+		t.Fatal(err)
+	}
+	inst, err := jsonschema.UnmarshalJSON(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := sch.Validate(inst); err != nil {
+		t.Fatalf("the output of %s doesn't match its schema: %v", command, err)
+	}
+}
+
+func TestListJSONSchema(t *testing.T) {
+	dir := t.TempDir()
+	gh := filepath.Join(dir, "gh")
+	if err := os.WriteFile(gh, []byte("gh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	bins := map[string]*config.Binary{
+		gh:                         {Path: gh, Version: "v2.40.0", URL: "https://github.com/cli/cli", Provider: "github", Emulated: "amd64"},
+		filepath.Join(dir, "kind"): {Path: filepath.Join(dir, "kind"), Version: "v0.20.0", URL: "https://github.com/kubernetes-sigs/kind", Provider: "github", Pinned: true},
+	}
+	checks := &checkCache{Bins: map[string]*checkResult{gh: {Installed: "v2.40.0", Latest: "v2.41.0"}}}
+	out := listJSON(bins, []string{gh, filepath.Join(dir, "kind")}, checks)
+	if out.Bins[0].Status != "ok" || out.Bins[1].Status != "missing" {
+		t.Fatalf("expected gh to be ok and kind missing, got %+v", out.Bins)
+	}
+	if out.Bins[0].Latest != "v2.41.0" || out.Bins[1].Latest != "" {
+		t.Fatalf("expected the latest version of gh only, got %+v", out.Bins)
+	}
+	validateOutput(t, "list", out)
+	validateOutput(t, "list", listJSON(nil, nil, checks))
+}
+
+func TestOutdatedJSONSchema(t *testing.T) {
+	now := time.Now()
+	bins := map[string]*config.Binary{
+		"/bin/kind": {Path: "/bin/kind", Version: "v0.19.0"},
+		"/bin/jq":   {Path: "/bin/jq", Version: "jq-1.7"},
+	}
+	c := &checkCache{Bins: map[string]*checkResult{
+		"/bin/kind": {Installed: "v0.19.0", Latest: "v0.20.0", CheckedAt: now.Add(-time.Hour)},
+		"/bin/jq":   {Installed: "jq-1.7", Latest: "jq-1.7", CheckedAt: now},
+	}}
+	out := outdated(bins, c).json(c, false)
+	if len(out.Outdated) != 1 || out.Outdated[0].Latest != "v0.20.0" || out.OldestCheck == nil {
+		t.Fatalf("expected kind to be outdated, got %+v", out)
+	}
+	validateOutput(t, "outdated", out)
+
+	// never checked
+	out = outdated(bins, &checkCache{Bins: map[string]*checkResult{}}).json(c, true)
+	if out.OldestCheck != nil {
+		t.Fatalf("expected no oldest check, got %s", out.OldestCheck)
+	}
+	validateOutput(t, "outdated", out)
+}
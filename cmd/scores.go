@@ -0,0 +1,46 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+// printScores renders the scored candidates of a release,
+// with the contribution of every rule, for --debug-assets
+func printScores(w io.Writer, repoName string, scored []*assets.ScoredAsset) {
+	nameL := len("Asset")
+	for _, s := range scored {
+		nameL = max(nameL, len(s.String()))
+	}
+
+	magentaItalic := color.New(color.FgMagenta, color.Italic).Sprint
+	fmt.Fprintf(w, "\nAsset scores of %s:\n", repoName)
+	fmt.Fprintf(w, "%s  %s  %s  %s\n", magentaItalic(_rPad("Score", 5)), magentaItalic(_rPad("Asset", nameL)), magentaItalic(_rPad("Format", 7)), magentaItalic("Breakdown"))
+	for _, s := range scored {
+		score := fmt.Sprint(s.Score)
+		if s.Excluded != "" {
+			score = "-"
+		}
+		fmt.Fprintf(w, "%s  %s  %s  %s\n", _rPad(score, 5), _rPad(s.String(), nameL), _rPad(s.Format, 7), scoreBreakdown(s))
+	}
+}
+
+// scoreBreakdown describes the rules contributing to the score
+func scoreBreakdown(s *assets.ScoredAsset) string {
+	if s.Excluded != "" {
+		return color.YellowString("excluded: %s", s.Excluded)
+	}
+	parts := make([]string, 0, len(s.Rules))
+	for _, r := range s.Rules {
+		rule := r.Rule
+		if r.Match != "" {
+			rule += " " + r.Match
+		}
+		parts = append(parts, fmt.Sprintf("%s %+d", rule, r.Points))
+	}
+	return strings.Join(parts, ", ")
+}
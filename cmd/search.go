@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/options"
+	"github.com/marcosnils/bin/pkg/prompt"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+type searchCmd struct {
+	cmd  *cobra.Command
+	opts searchOpts
+}
+
+type searchOpts struct {
+	limit int
+	host  string
+}
+
+// searchResult is a repository offered to be installed
+type searchResult struct {
+	*providers.SearchResult
+}
+
+func (r searchResult) String() string {
+	release := "no release"
+	if r.Release != "" {
+		release = r.Release
+	}
+	return fmt.Sprintf("%s (%d stars, %s) %s", r.FullName, r.Stars, release, r.Description)
+}
+
+func newSearchCmd() *searchCmd {
+	root := &searchCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "search <query>",
+		Short: "Searches the GitHub repositories to install a binary from",
+		Long: `Searches the GitHub repositories matching the query, most starred first,
+along with their latest release. The repository picked is installed right
+away, the results are only printed when the session isn't interactive.
+
+The query supports the qualifiers of the GitHub search (i.e. language:go).`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			host := root.opts.host
+			if host == "" && demoEnabled() {
+				host = demoHost
+			}
+			results, err := settings.Search(host, joinArgs(args), root.opts.limit)
+			if err != nil {
+				return fmt.Errorf("Error searching the repositories: %w", err)
+			}
+			if len(results) == 0 {
+				return fmt.Errorf("no repository matches %q", joinArgs(args))
+			}
+			if !prompt.IsInteractive() {
+				printSearch(os.Stdout, results)
+				return nil
+			}
+
+			opts := make([]fmt.Stringer, 0, len(results))
+			for _, r := range results {
+				opts = append(opts, searchResult{r})
+			}
+			choice, err := options.Select("Select the repository to install:", opts)
+			if err != nil {
+				return err
+			}
+			install := newInstallCmd()
+			return install.cmd.RunE(install.cmd, []string{choice.(searchResult).URL})
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().IntVarP(&root.opts.limit, "limit", "n", 10, "Maximum number of repositories shown")
+	root.cmd.Flags().StringVar(&root.opts.host, "host", "", "Search a GitHub Enterprise Server instead of github.com")
+	return root
+}
+
+// joinArgs joins the words of the query
+func joinArgs(args []string) string {
+	q := args[0]
+	for _, a := range args[1:] {
+		q += " " + a
+	}
+	return q
+}
+
+// printSearch prints the repositories found
+func printSearch(w io.Writer, results []*providers.SearchResult) {
+	header := []tableColumn{{header: "Repository"}, {header: "Stars"}, {header: "Release"}, {header: "Description", truncate: true}}
+	rows := make([][]tableCell, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, []tableCell{{text: r.FullName}, {text: strconv.Itoa(r.Stars)}, {text: displayValue(r.Release)}, {text: r.Description}})
+	}
+	printTable(w, header, rows, terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestSearch(t *testing.T) {
+	forge := fakeforge.New()
+	for _, r := range []string{"acme/tool", "acme/other"} {
+		if err := forge.AddTool(r, "v1.0.0"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer func() { demoForge = nil }()
+	demoForge = forge
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	t.Setenv("BIN_DEMO", "1")
+	for _, k := range []string{"BIN_CONFIG", "BIN_STATE", "XDG_DATA_HOME"} {
+		t.Setenv(k, "")
+	}
+
+	out := string(captureStdout(t, func() {
+		Execute("test", func(code int) { t.Fatalf("search exited with %d", code) }, []string{"search", "tool"})
+	}))
+	if !strings.Contains(out, "acme/tool") || !strings.Contains(out, "v1.0.0") {
+		t.Fatalf("expected the matching repository and its release, got %q", out)
+	}
+	if strings.Contains(out, "acme/other") {
+		t.Fatalf("expected only the matching repositories, got %q", out)
+	}
+}
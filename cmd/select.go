@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// isPattern reports whether the argument is a glob (i.e. `kube*`)
+// rather than the name or path of a binary
+func isPattern(a string) bool {
+	return strings.ContainsAny(a, "*?[")
+}
+
+// globBins returns the keys of the binaries matching the glob, by
+// name or by path when the glob has a slash
+func globBins(bins map[string]*config.Binary, pattern string) ([]string, error) {
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	keys := []string{}
+	for k, b := range bins {
+		p := os.ExpandEnv(b.Path)
+		if !strings.Contains(pattern, "/") {
+			p = filepath.Base(p)
+		}
+		if ok, _ := filepath.Match(pattern, p); ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys, nil
+}
+
+// selectBins returns the configured binaries given as arguments, all of
+// them when there are none. Names and paths must be managed binaries,
+// globs are matched against the managed ones and each glob matching
+// nothing is reported. The excluded globs are left out afterwards.
+func selectBins(args, exclude []string) (map[string]*config.Binary, error) {
+	bins := config.Get().Bins
+	if len(args) == 0 {
+		selected := maps.Clone(bins)
+		return selected, excludeBins(selected, exclude)
+	}
+
+	selected := map[string]*config.Binary{}
+	unmatched := []string{}
+	for _, a := range args {
+		if !isPattern(a) {
+			bin, err := getBinPath(a)
+			if err != nil {
+				return nil, err
+			}
+			selected[bin] = bins[bin]
+			continue
+		}
+		keys, err := globBins(bins, a)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			log.WithField("pattern", a).Warn("No binary matches")
+			unmatched = append(unmatched, a)
+		}
+		for _, k := range keys {
+			selected[k] = bins[k]
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no binary matches %s", strings.Join(unmatched, ", "))
+	}
+	return selected, excludeBins(selected, exclude)
+}
+
+// excludeBins removes the binaries matching one of the globs, the
+// globs excluding nothing are reported
+func excludeBins(bins map[string]*config.Binary, exclude []string) error {
+	for _, e := range exclude {
+		keys, err := globBins(bins, e)
+		if err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			log.WithField("pattern", e).Warn("No binary matches the excluded pattern")
+		}
+		for _, k := range keys {
+			delete(bins, k)
+		}
+	}
+	return nil
+}
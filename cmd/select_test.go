@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestSelectBins(t *testing.T) {
+	_, dir := newTestConfig(t, "")
+	if err := config.CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"selkubectl", "selkubens", "selterraform", "selhelm"} {
+		b := &config.Binary{Path: filepath.Join(dir, name), URL: "https://github.com/o/" + name, Provider: "github", Version: "v1.0.0"}
+		if err := config.UpsertBinary(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	names := func(bins map[string]*config.Binary) []string {
+		n := []string{}
+		for p := range bins {
+			n = append(n, filepath.Base(p))
+		}
+		sort.Strings(n)
+		return n
+	}
+	cases := []struct {
+		args, exclude []string
+		want          []string
+	}{
+		{[]string{"selkube*", "selterraform"}, nil, []string{"selkubectl", "selkubens", "selterraform"}},
+		{[]string{"selkube*"}, []string{"*ns"}, []string{"selkubectl"}},
+		{[]string{"sel[hk]*"}, []string{"selkubectl", "selkubens"}, []string{"selhelm"}},
+		// globs matching nothing are reported, the others still apply
+		{[]string{"selkubectl", "nomatch*"}, nil, []string{"selkubectl"}},
+		{[]string{filepath.Join(dir, "selh*")}, nil, []string{"selhelm"}},
+	}
+	for _, c := range cases {
+		selected, err := selectBins(c.args, c.exclude)
+		if err != nil {
+			t.Errorf("%v without %v: %v", c.args, c.exclude, err)
+			continue
+		}
+		if got := names(selected); !reflect.DeepEqual(got, c.want) {
+			t.Errorf("%v without %v: expected %v, got %v", c.args, c.exclude, c.want, got)
+		}
+	}
+
+	if _, err := selectBins([]string{"nomatch*", "nothing?"}, nil); err == nil || err.Error() != "no binary matches nomatch*, nothing?" {
+		t.Errorf("expected the globs matching nothing to fail, got %v", err)
+	}
+	if _, err := selectBins([]string{"selkube"}, nil); err == nil {
+		t.Error("expected a literal name to keep matching exactly")
+	}
+	if _, err := selectBins([]string{"selkube["}, nil); err == nil {
+		t.Error("expected an invalid glob to fail")
+	}
+	all, err := selectBins(nil, []string{"sel*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := all[filepath.Join(dir, "selhelm")]; ok || len(config.Get().Bins) < 4 {
+		t.Errorf("expected the excluded binaries to be left out of a copy of the configuration, got %v", names(all))
+	}
+}
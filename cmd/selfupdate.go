@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/journal"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+// selfRepo is where bin itself is released
+const selfRepo = "github.com/marcosnils/bin"
+
+// selfExecutable returns the path of the running bin,
+// the tests replace it to not overwrite themselves
+var selfExecutable = os.Executable
+
+type selfUpdateCmd struct {
+	cmd  *cobra.Command
+	opts selfUpdateOpts
+}
+
+type selfUpdateOpts struct {
+	to    string
+	check bool
+}
+
+func newSelfUpdateCmd() *selfUpdateCmd {
+	root := &selfUpdateCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "self-update",
+		Short: "Updates bin itself",
+		Long: `Updates bin itself to the latest release, or the one given with --to
+for downgrades. The running executable is replaced once the release
+is downloaded and verified.
+
+With --check, it only tells whether a newer release exists and exits
+with 3 when there's one.`,
+		SilenceUsage:  true,
+		Args:          cobra.NoArgs,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			current, _, _ := strings.Cut(cmd.Root().Version, "\n")
+			exe, err := selfExecutable()
+			if err != nil {
+				return err
+			}
+			if exe, err = filepath.EvalSymlinks(exe); err != nil {
+				return err
+			}
+
+			p, err := newProvider(&config.Binary{URL: selfURL(), Provider: "github"})
+			if err != nil {
+				return err
+			}
+			target := root.opts.to
+			if target == "" {
+				if target, _, err = p.GetLatestVersion(); err != nil {
+					return fmt.Errorf("error getting the latest release of bin: %w", err)
+				}
+			}
+
+			if root.opts.check {
+				if !newerVersion(target, current) {
+					log.WithField("version", current).Info("bin is up to date")
+					return nil
+				}
+				return wrapErrorWithCode(fmt.Errorf("bin %s is available, %s is installed", target, current), 3, "")
+			}
+			// the version of bin has no v prefix, unlike its tags. Builds
+			// newer than the latest release are only downgraded with --to
+			upToDate := strings.TrimPrefix(target, "v") == strings.TrimPrefix(current, "v")
+			if root.opts.to == "" {
+				upToDate = !newerVersion(target, current)
+			}
+			if upToDate {
+				log.WithField("version", current).Info("bin is up to date")
+				return nil
+			}
+			if err := checkWritable(filepath.Dir(exe)); err != nil {
+				return err
+			}
+
+			f, err := p.Fetch(&providers.FetchOpts{Version: target, RequireChecksum: true})
+			if err != nil {
+				return fmt.Errorf("unable to get bin %s: %w", target, err)
+			}
+			hash, err := replaceExecutable(f, exe)
+			if err != nil {
+				return fmt.Errorf("error replacing %s: %w", exe, err)
+			}
+
+			// keep the entry of a bin managed by itself in sync
+			if b := config.Owner(exe); b != nil {
+				b.Version, b.Hash = f.Version, fmt.Sprintf("%x", hash)
+				b.VerifiedWith, b.SignedBy, b.SignedFile = f.VerifiedWith, f.SignedBy, f.SignedFile
+				b.InstalledAsset, b.AssetSHA256 = f.Asset, f.AssetSHA256
+				b.AssetURL, b.InstalledAt = f.AssetURL, installedNow()
+				if err := config.UpsertBinary(b); err != nil {
+					return err
+				}
+			}
+			log.WithField("path", exe).WithField("from", current).WithField("version", f.Version).Info("Updated bin")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().StringVar(&root.opts.to, "to", "", "Version to install instead of the latest one, i.e. to downgrade")
+	root.cmd.Flags().BoolVar(&root.opts.check, "check", false, "Only tell whether a newer release exists, exiting with 3 when there's one")
+	return root
+}
+
+// selfURL returns the repository bin is released from,
+// the demo forge publishes its own
+func selfURL() string {
+	if demoEnabled() {
+		return "http://" + demoHost + "/marcosnils/bin"
+	}
+	return "https://" + selfRepo
+}
+
+// newerVersion reports whether v is newer than current. Versions
+// which can't be compared, i.e. dev builds, are different ones.
+func newerVersion(v, current string) bool {
+	nv, err := version.NewVersion(v)
+	if err != nil {
+		return v != current
+	}
+	cv, err := version.NewVersion(current)
+	if err != nil {
+		return v != current
+	}
+	return nv.GreaterThan(cv)
+}
+
+// checkWritable fails when the files of dir can't be replaced,
+// i.e. bin was installed system-wide
+func checkWritable(dir string) error {
+	f, err := os.CreateTemp(dir, ".bin-write-check-*")
+	if err == nil {
+		f.Close()
+		return os.Remove(f.Name())
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		return fmt.Errorf("%s isn't writable, run bin self-update from an elevated prompt", dir)
+	}
+	return fmt.Errorf("%s isn't writable, run it with sudo: sudo bin self-update", dir)
+}
+
+// replaceExecutable writes the file next to the executable and moves it
+// into place, the running one being moved aside where it can't be
+// replaced. It returns the sha256 of the file.
+func replaceExecutable(f *providers.File, exe string) ([]byte, error) {
+	jd, err := config.GetJournalDir()
+	if err != nil {
+		return nil, err
+	}
+	tx, err := journal.Begin(jd)
+	if err != nil {
+		return nil, err
+	}
+	out, err := tx.Stage(exe, 0o755)
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+	h := sha256.New()
+	_, err = io.Copy(out, io.TeeReader(f.Data, h))
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, errors.Join(err, tx.Rollback())
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return h.Sum(nil), nil
+}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+func TestSelfUpdate(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("marcosnils/bin", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	versionedDemo(t, forge)
+	exe := filepath.Join(t.TempDir(), "bin")
+	if err := os.WriteFile(exe, []byte("bin 1.0.0"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	selfExecutable = func() (string, error) { return exe, nil }
+	defer func() { selfExecutable = os.Executable }()
+
+	code := 0
+	Execute("1.0.0", func(c int) { code = c }, []string{"self-update", "--check"})
+	if code != 3 {
+		t.Fatalf("expected the check to find v1.1.0, got exit code %d", code)
+	}
+	if b, _ := os.ReadFile(exe); string(b) != "bin 1.0.0" {
+		t.Fatalf("expected the check not to replace the executable, got %q", b)
+	}
+
+	Execute("1.0.0", func(code int) { t.Fatalf("self-update exited with %d", code) }, []string{"self-update"})
+	if b, _ := os.ReadFile(exe); string(b) != string(fakeforge.Script("bin", "v1.1.0")) {
+		t.Fatalf("expected the executable to be replaced by v1.1.0, got %q", b)
+	}
+	Execute("1.1.0", func(code int) { t.Fatalf("check exited with %d", code) }, []string{"self-update", "--check"})
+
+	Execute("1.1.0", func(code int) { t.Fatalf("self-update exited with %d", code) }, []string{"self-update", "--to", "v1.0.0"})
+	if b, _ := os.ReadFile(exe); string(b) != string(fakeforge.Script("bin", "v1.0.0")) {
+		t.Fatalf("expected the executable to be downgraded to v1.0.0, got %q", b)
+	}
+	// a build newer than the latest release is kept
+	Execute("1.2.0", func(code int) { t.Fatalf("self-update exited with %d", code) }, []string{"self-update"})
+	if b, _ := os.ReadFile(exe); string(b) != string(fakeforge.Script("bin", "v1.0.0")) {
+		t.Fatalf("expected the newer build not to be downgraded, got %q", b)
+	}
+	if left, _ := filepath.Glob(filepath.Join(filepath.Dir(exe), ".*")); len(left) > 0 {
+		t.Errorf("unexpected leftovers %v", left)
+	}
+
+	if runtime.GOOS == "windows" || os.Geteuid() == 0 {
+		return
+	}
+	if err := os.Chmod(filepath.Dir(exe), 0o555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Dir(exe), 0o755)
+	code = 0
+	Execute("1.0.0", func(c int) { code = c }, []string{"self-update"})
+	if code == 0 {
+		t.Fatal("expected the update of a read-only directory to fail")
+	}
+}
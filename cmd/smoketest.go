@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+// verifyArgsNone disables the verification of a binary
+const verifyArgsNone = "none"
+
+// smokeTimeout is how long an installed binary may run
+// when it's verified, a var so the tests can shorten it
+var smokeTimeout = 10 * time.Second
+
+// smokeOutputLimit caps the output quoted in the errors
+const smokeOutputLimit = 512
+
+// verifyArgs returns the arguments the binary is run with after being
+// installed and whether it's run at all, see config.Binary.VerifyArgs
+func verifyArgs(b *config.Binary) ([]string, bool) {
+	if b.IsFile() || b.VerifyArgs == verifyArgsNone {
+		return nil, false
+	}
+	if b.VerifyArgs != "" {
+		return strings.Fields(b.VerifyArgs), true
+	}
+	return []string{"--version"}, b.VerifyVersion || config.Get().VerifyInstall
+}
+
+// smokeTest runs the staged file of the binary with its verification
+// arguments before it replaces the installed one, catching the assets
+// which aren't a binary of the host (wrong architecture, text files).
+// It's run directly without a shell, killed after smokeTimeout, and
+// must exit successfully, printing the version with VerifyVersion.
+func smokeTest(b *config.Binary, f *providers.File, staged string) error {
+	args, ok := verifyArgs(b)
+	if !ok {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), smokeTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, staged, args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	// children inheriting the output don't keep it waiting
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = fmt.Errorf("it didn't exit within %s", smokeTimeout)
+	}
+	if err == nil && b.VerifyVersion && !strings.Contains(stdout.String()+stderr.String(), strings.TrimPrefix(f.Version, "v")) {
+		err = fmt.Errorf("its output doesn't mention version %s: %s", f.Version, quoteOutput(stdout.String()))
+	}
+	if err == nil {
+		return nil
+	}
+	if s := quoteOutput(stderr.String()); s != "" {
+		err = fmt.Errorf("%w: %s", err, s)
+	}
+
+	asset := f.Asset
+	if asset == "" {
+		asset = f.Name
+	}
+	return fmt.Errorf("running `%s %s` failed, the previous file was kept: %w. It was installed from the asset %s, select another one with --asset or set verify_args to %s if it can't be run",
+		f.Name, strings.Join(args, " "), err, asset, verifyArgsNone)
+}
+
+// quoteOutput returns the trimmed output of
+// a command, cut to smokeOutputLimit bytes
+func quoteOutput(out string) string {
+	out = strings.TrimSpace(out)
+	if len(out) > smokeOutputLimit {
+		out = out[:smokeOutputLimit] + "..."
+	}
+	return out
+}
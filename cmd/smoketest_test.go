@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+func TestSmokeTest(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binaries are shell scripts")
+	}
+	defer func(d time.Duration) { smokeTimeout = d }(smokeTimeout)
+	smokeTimeout = 500 * time.Millisecond
+
+	dir := t.TempDir()
+	script := func(name, content string, perm os.FileMode) string {
+		p := filepath.Join(dir, name)
+		if err := os.WriteFile(p, []byte(content), perm); err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	ok := script("ok", "#!/bin/sh\necho tool 1.2.0\n", 0o755)
+	failing := script("failing", "#!/bin/sh\necho 'unknown flag' >&2\nexit 2\n", 0o755)
+	hanging := script("hanging", "#!/bin/sh\nexec sleep 5\n", 0o755)
+	text := script("text", "Not Found\n", 0o755)
+
+	f := &providers.File{Name: "tool", Version: "v1.2.0", Asset: "tool_linux_amd64.tar.gz"}
+	cases := []struct {
+		name string
+		b    *config.Binary
+		path string
+		err  string
+	}{
+		{"not verified", &config.Binary{}, failing, ""},
+		{"ok", &config.Binary{VerifyArgs: "--version"}, ok, ""},
+		{"version", &config.Binary{VerifyVersion: true}, ok, ""},
+		{"other version", &config.Binary{VerifyVersion: true}, script("old", "#!/bin/sh\necho tool 1.1.0\n", 0o755), "doesn't mention version v1.2.0"},
+		{"exit status", &config.Binary{VerifyArgs: "version --short"}, failing, "exit status 2: unknown flag"},
+		{"timeout", &config.Binary{VerifyArgs: "--version"}, hanging, "didn't exit within"},
+		{"not a binary", &config.Binary{VerifyArgs: "--version"}, text, "exec format error"},
+		{"disabled", &config.Binary{VerifyArgs: verifyArgsNone, VerifyVersion: true}, failing, ""},
+		{"file", &config.Binary{Kind: config.KindFile, VerifyArgs: "--version"}, failing, ""},
+	}
+	for _, c := range cases {
+		err := smokeTest(c.b, f, c.path)
+		if c.err == "" {
+			if err != nil {
+				t.Errorf("%s: %v", c.name, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), c.err) || !strings.Contains(err.Error(), f.Asset) {
+			t.Errorf("%s: expected an error containing %q and the asset, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+// TestVerifyInstall checks that a binary failing its verification
+// doesn't replace the previously installed one
+func TestVerifyInstall(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binaries are shell scripts")
+	}
+	dir, binDir := newTestConfig(t, `"verify_install": true`)
+
+	src := filepath.Join(dir, "tool-1.0.0")
+	good := writeScript(t, src, "tool 1.0.0")
+	installed := filepath.Join(binDir, "tool")
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src, installed})
+
+	broken := filepath.Join(dir, "tool-1.1.0")
+	if err := os.WriteFile(broken, []byte("#!/bin/sh\nexit 1\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	code := 0
+	Execute("test", func(c int) { code = c }, []string{"install", "--force", broken, installed})
+	if code == 0 {
+		t.Fatal("expected the install of a failing binary to fail")
+	}
+	if b, _ := os.ReadFile(installed); string(b) != string(good) {
+		t.Errorf("expected the previous binary to be kept, got %q", b)
+	}
+	if b := config.Get().Bins[installed]; b.Version != "1.0.0" {
+		t.Errorf("expected the entry to stay at 1.0.0, got %s", b.Version)
+	}
+	if staged, _ := filepath.Glob(filepath.Join(binDir, ".tool.*")); len(staged) != 0 {
+		t.Errorf("expected the staged file to be removed, got %v", staged)
+	}
+}
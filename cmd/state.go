@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+type splitStateCmd struct {
+	cmd *cobra.Command
+}
+
+func newSplitStateCmd() *splitStateCmd {
+	root := &splitStateCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "split-state",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Moves the machine-local fields of the configuration to a state file",
+		Long:          "Moves the paths, installed versions and digests of the binaries to a state file in the data directory, so the configuration only holds what can be shared across machines (i.e. in a dotfiles repository)",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if config.Get().SplitState {
+				log.Info("The state is already split")
+				return nil
+			}
+			if err := config.SplitState(); err != nil {
+				return err
+			}
+			p, err := config.GetStatePath()
+			if err != nil {
+				return err
+			}
+			log.WithField("path", p).Info("Machine-local state moved")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
+
+type exportCmd struct {
+	cmd  *cobra.Command
+	opts exportOpts
+}
+
+type exportOpts struct {
+	output      string
+	declarative bool
+	state       bool
+}
+
+func newExportCmd() *exportCmd {
+	root := &exportCmd{}
+
+	cmd := &cobra.Command{
+		Use:           "export",
+		Short:         "Writes a portable manifest of the binaries, for `bin import`",
+		Long:          "Writes a versioned manifest of the binaries: their name, source URL, provider, version (or \"latest\" when they aren't pinned) and asset hints, without the local paths or any secret. `bin import` installs them on another machine. --declarative prints the declarative part of the configuration instead.",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var v any = config.NewManifest()
+			switch {
+			case root.opts.state:
+				v = config.Get()
+			case root.opts.declarative:
+				decl, err := config.Declarative()
+				if err != nil {
+					return err
+				}
+				v = decl
+			}
+
+			w := io.Writer(os.Stdout)
+			if root.opts.output != "" {
+				f, err := os.Create(root.opts.output)
+				if err != nil {
+					return err
+				}
+				defer f.Close()
+				w = f
+			}
+			encoder := json.NewEncoder(w)
+			encoder.SetIndent("", "    ")
+			if err := encoder.Encode(v); err != nil {
+				return err
+			}
+			if m, ok := v.(*config.Manifest); ok && root.opts.output != "" {
+				log.WithField("binaries", len(m.Bins)).WithField("path", root.opts.output).Info("Exported")
+			}
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().StringVarP(&root.opts.output, "output", "o", "", "Write to this file instead of stdout")
+	root.cmd.Flags().BoolVar(&root.opts.declarative, "declarative", false, "Print the declarative part of the configuration instead of the manifest")
+	root.cmd.Flags().BoolVar(&root.opts.state, "with-state", false, "Print the whole configuration, machine-local fields (paths, installed versions, digests) included")
+	root.cmd.MarkFlagsMutuallyExclusive("declarative", "with-state")
+	return root
+}
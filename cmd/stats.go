@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/spf13/cobra"
+)
+
+type statsCmd struct {
+	cmd  *cobra.Command
+	opts statsOpts
+}
+
+type statsOpts struct {
+	unused string
+}
+
+func newStatsCmd() *statsCmd {
+	root := &statsCmd{}
+	// nolint: dupl
+	cmd := &cobra.Command{
+		Use:   "stats",
+		Short: "Shows how often the binaries managed by bin are run",
+		Long: `Shows how often the binaries managed by bin are run.
+
+The usage is recorded locally, and never transmitted, by shims installed
+with 'bin stats enable'. Binaries not run for longer than --unused are
+reported as candidates for removal, see 'bin prune --unused'.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !config.Get().Stats {
+				return errors.New("usage statistics are disabled, run 'bin stats enable' first")
+			}
+			age, err := parseAge(root.opts.unused)
+			if err != nil {
+				return err
+			}
+			shims, err := statsShims()
+			if err != nil {
+				return err
+			}
+
+			cfg := config.Get()
+			paths := []string{}
+			for k := range cfg.Bins {
+				paths = append(paths, k)
+			}
+			sort.Strings(paths)
+
+			for _, k := range paths {
+				p := os.ExpandEnv(k)
+				u, ok, err := shims.Usage(p)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					fmt.Printf("%s  %s\n", p, color.YellowString("not recorded"))
+					continue
+				}
+				last := fmt.Sprintf("last run %s", u.LastRun.Format(time.DateOnly))
+				if u.Runs == 0 {
+					last = fmt.Sprintf("recorded since %s", u.LastRun.Format(time.DateOnly))
+				}
+				line := fmt.Sprintf("%s  %d runs, %s", p, u.Runs, last)
+				if time.Since(u.LastRun) > age {
+					line += color.RedString("  unused")
+				}
+				fmt.Println(line)
+			}
+			return nil
+		},
+	}
+
+	enable := &cobra.Command{
+		Use:           "enable",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Records how often the binaries are run by installing shims",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shims, err := statsShims()
+			if err != nil {
+				return err
+			}
+			if err := config.SetStats(true); err != nil {
+				return err
+			}
+			for _, b := range config.Get().Bins {
+				p := os.ExpandEnv(b.Path)
+				if _, err := os.Stat(p); err != nil || b.IsFile() {
+					continue
+				}
+				if err := shims.Enable(p); err != nil {
+					return fmt.Errorf("error installing the shim of %s: %w", p, err)
+				}
+			}
+			log.Info("Usage statistics enabled, they are only stored locally")
+			return nil
+		},
+	}
+
+	disable := &cobra.Command{
+		Use:           "disable",
+		Annotations:   map[string]string{writesConfig: "true"},
+		Short:         "Removes the shims and restores the binaries",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			shims, err := statsShims()
+			if err != nil {
+				return err
+			}
+			for _, b := range config.Get().Bins {
+				p := os.ExpandEnv(b.Path)
+				if err := shims.Disable(p); err != nil {
+					return fmt.Errorf("error restoring %s: %w", p, err)
+				}
+			}
+			if err := config.SetStats(false); err != nil {
+				return err
+			}
+			log.Info("Usage statistics disabled")
+			return nil
+		},
+	}
+
+	cmd.AddCommand(enable, disable)
+	root.cmd = cmd
+	root.cmd.Flags().StringVar(&root.opts.unused, "unused", "180d", "Report the binaries not run for longer than this (i.e. 90d, 720h)")
+	return root
+}
+
+func statsShims() (*stats.Shims, error) {
+	dir, err := config.GetStatsDir()
+	if err != nil {
+		return nil, err
+	}
+	return stats.New(dir), nil
+}
+
+// removeBinary removes the binary at path along with its
+// usage statistics shim and its versions in the store
+func removeBinary(path string) error {
+	shims, err := statsShims()
+	if err != nil {
+		return err
+	}
+	st, err := versionStore()
+	if err != nil {
+		return err
+	}
+	_, inStore := st.Active(stats.Resolve(path))
+	if err := shims.Remove(path); err != nil {
+		return fmt.Errorf("Error removing the shim of %s: %v", path, err)
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Error removing path %s: %v", path, err)
+	}
+	if inStore && !storeShared(path) {
+		if err := st.Remove(path, path); err != nil {
+			return fmt.Errorf("Error removing the versions of %s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// unusedBinaries returns the binaries whose recorded
+// last run is older than age
+func unusedBinaries(age time.Duration) ([]*config.Binary, error) {
+	shims, err := statsShims()
+	if err != nil {
+		return nil, err
+	}
+	res := []*config.Binary{}
+	for _, b := range config.Get().Bins {
+		u, ok, err := shims.Usage(os.ExpandEnv(b.Path))
+		if err != nil {
+			return nil, err
+		}
+		if ok && time.Since(u.LastRun) > age {
+			res = append(res, b)
+		}
+	}
+	return res, nil
+}
+
+// parseAge parses a duration which can also be
+// expressed in days (i.e. 180d)
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid duration %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return d, nil
+}
@@ -0,0 +1,196 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/marcosnils/bin/pkg/stats"
+	"github.com/spf13/cobra"
+)
+
+// statusUnknown is the status of the binaries installed
+// before their hash was recorded, they can't be compared
+const statusUnknown = "unknown"
+
+type statusCmd struct {
+	cmd  *cobra.Command
+	opts statusOpts
+}
+
+type statusOpts struct {
+	versions bool
+	fix      bool
+	json     bool
+}
+
+// statusOutput is the JSON output of `bin status`, see pkg/schema/schemas/status.json
+type statusOutput struct {
+	SchemaVersion int           `json:"schema_version"`
+	Bins          []*statusItem `json:"bins"`
+}
+
+// statusItem is the state of a binary on disk compared to its entry
+type statusItem struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	Version string `json:"version"`
+	// Reported is the version the binary reports, with --versions
+	Reported string `json:"reported,omitempty"`
+	Error    string `json:"error,omitempty"`
+
+	b *config.Binary
+}
+
+func newStatusCmd() *statusCmd {
+	root := &statusCmd{}
+
+	cmd := &cobra.Command{
+		Use:               "status [binary]...",
+		ValidArgsFunction: completeBinaries(0),
+		Short:             "Checks that the binaries on disk are the ones bin installed",
+		Long: `Checks that the binaries on disk are the ones bin installed, comparing
+their sha256 with the recorded hash: ok, modified (i.e. overwritten by
+another installer) or missing. Nothing leaves the machine.
+
+--versions also runs the binaries to show the version they report and
+--fix re-installs the modified and missing ones at their recorded version,
+keeping a copy of the modified ones aside as <name>.local.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if root.opts.fix && config.ReadOnly() {
+				return fmt.Errorf("'%s --fix' needs to write the configuration, which is read-only", cmd.CommandPath())
+			}
+			binsToCheck, err := selectBins(args, nil)
+			if err != nil {
+				return err
+			}
+			bins := make([]*config.Binary, 0, len(binsToCheck))
+			for _, b := range binsToCheck {
+				bins = append(bins, b)
+			}
+			sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
+
+			items := make([]*statusItem, len(bins))
+			for i, b := range bins {
+				items[i] = binaryStatus(b, root.opts.versions)
+			}
+
+			var errs []error
+			if root.opts.fix {
+				errs = fixStatus(items, root.opts.versions)
+			}
+			if root.opts.json {
+				if err := writeJSON(os.Stdout, statusOutput{SchemaVersion: schema.Version, Bins: items}); err != nil {
+					return err
+				}
+			} else {
+				printStatus(os.Stdout, items, root.opts.versions)
+			}
+			return errors.Join(errs...)
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().BoolVar(&root.opts.versions, "versions", false, "Run the binaries to show the version they report")
+	root.cmd.Flags().BoolVar(&root.opts.fix, "fix", false, "Re-install the modified and missing binaries at their recorded version")
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print the status as JSON, see `bin schema status`")
+	return root
+}
+
+// binaryStatus compares the binary on disk with its recorded hash,
+// it's run to get its version when versions is set
+func binaryStatus(b *config.Binary, versions bool) *statusItem {
+	ep := os.ExpandEnv(b.Path)
+	s := &statusItem{Path: ep, Status: verifyOK, Version: b.Version, b: b}
+	sum, err := fileSHA256(stats.Resolve(ep))
+	switch {
+	case os.IsNotExist(err):
+		s.Status = verifyMissing
+		return s
+	case err != nil:
+		s.Status, s.Error = verifyError, err.Error()
+		return s
+	case b.Hash == "":
+		s.Status = statusUnknown
+	case sum != b.Hash:
+		s.Status = verifyModified
+	}
+	if versions {
+		s.Reported = detectVersion(b)
+	}
+	return s
+}
+
+// fixStatus re-installs the modified and missing binaries at their
+// recorded version, the items are updated with their new status
+func fixStatus(items []*statusItem, versions bool) []error {
+	cache := assets.NewDownloadCache()
+	defer cache.Close()
+	var errs []error
+	for _, s := range items {
+		if s.Status != verifyModified && s.Status != verifyMissing {
+			continue
+		}
+		nb, err := fixBinary(s.b, cache)
+		if err != nil {
+			s.Error = err.Error()
+			errs = append(errs, fmt.Errorf("Error fixing %s: %w", s.Path, err))
+			continue
+		}
+		binLog(nb).WithField("status", s.Status).Info("Re-installed")
+		*s = *binaryStatus(nb, versions)
+	}
+	if len(errs) > 0 {
+		log.WithField("binaries", len(errs)).Warn("Some binaries couldn't be fixed")
+	}
+	return errs
+}
+
+// fixBinary re-installs the binary at its recorded version, a
+// modified one is kept aside first
+func fixBinary(b *config.Binary, cache *assets.DownloadCache) (*config.Binary, error) {
+	if err := guardModified(b, true); err != nil {
+		return nil, err
+	}
+	nb, _, err := installPinned(b, nil, cache)
+	return nb, err
+}
+
+// printStatus prints the status of every binary, along the
+// version they report with versions
+func printStatus(w io.Writer, items []*statusItem, versions bool) {
+	header := []tableColumn{{header: "Path", truncate: true}, {header: "Version"}, {header: "Status"}}
+	if versions {
+		header = append(header, tableColumn{header: "Reported"})
+	}
+	rows := make([][]tableCell, 0, len(items))
+	for _, s := range items {
+		status := tableCell{text: strings.ToUpper(s.Status), color: color.New(color.FgGreen).Sprint}
+		switch s.Status {
+		case statusUnknown:
+			status.color = color.New(color.FgYellow).Sprint
+		case verifyModified, verifyMissing, verifyError:
+			status.color = color.New(color.FgRed).Sprint
+		}
+		row := []tableCell{{text: s.Path}, {text: s.Version}, status}
+		if versions {
+			reported := tableCell{text: displayValue(s.Reported)}
+			if s.Reported != "" && s.Reported != s.Version {
+				reported.color = color.New(color.FgRed).Sprint
+			}
+			row = append(row, reported)
+		}
+		rows = append(rows, row)
+	}
+	printTable(w, header, rows, terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+}
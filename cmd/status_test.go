@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binaries are shell scripts")
+	}
+	dir, binDir := newTestConfig(t, "")
+
+	paths := []string{}
+	for _, n := range []string{"ok", "missing", "modified"} {
+		src := filepath.Join(dir, n+"-1.0.0")
+		writeScript(t, src, n+" 1.0.0")
+		paths = append(paths, filepath.Join(binDir, n))
+		Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src, paths[len(paths)-1]})
+	}
+	if err := os.Remove(filepath.Join(binDir, "missing")); err != nil {
+		t.Fatal(err)
+	}
+	// another installer overwrites it with another version
+	writeScript(t, filepath.Join(binDir, "modified"), "modified 1.1.0")
+
+	status := func(args ...string) map[string]*statusItem {
+		t.Helper()
+		var out statusOutput
+		b := captureStdout(t, func() {
+			Execute("test", func(code int) { t.Fatalf("status exited with %d", code) }, append(append([]string{"status", "--json"}, args...), paths...))
+		})
+		if err := json.Unmarshal(b, &out); err != nil {
+			t.Fatalf("%v: %s", err, b)
+		}
+		validateOutput(t, "status", out)
+		res := map[string]*statusItem{}
+		for _, s := range out.Bins {
+			res[filepath.Base(s.Path)] = s
+		}
+		return res
+	}
+
+	items := status("--versions")
+	for n, expected := range map[string]string{"ok": verifyOK, "missing": verifyMissing, "modified": verifyModified} {
+		if items[n].Status != expected {
+			t.Errorf("expected %s to be %s, got %s", n, expected, items[n].Status)
+		}
+	}
+	if items["modified"].Reported != "1.1.0" || items["ok"].Reported != "1.0.0" {
+		t.Fatalf("expected the versions reported by the binaries, got %s and %s", items["modified"].Reported, items["ok"].Reported)
+	}
+
+	items = status("--fix")
+	for n, s := range items {
+		if s.Status != verifyOK {
+			t.Errorf("expected %s to be fixed, got %s: %s", n, s.Status, s.Error)
+		}
+	}
+	if b, _ := os.ReadFile(filepath.Join(binDir, "modified.local")); string(b) != "#!/bin/sh\necho modified 1.1.0\n" {
+		t.Fatalf("expected the modified binary to be kept aside, got %q", b)
+	}
+}
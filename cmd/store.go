@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"os"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/marcosnils/bin/pkg/store"
+)
+
+// storedVersion is recorded along the versions in the store
+// to restore the fields of their entry on rollback
+type storedVersion struct {
+	Version        string `json:"version"`
+	RemoteName     string `json:"remote_name,omitempty"`
+	PackagePath    string `json:"package_path,omitempty"`
+	InstalledAsset string `json:"installed_asset,omitempty"`
+	AssetSHA256    string `json:"asset_sha256,omitempty"`
+	AssetDigest    string `json:"asset_digest,omitempty"`
+	VerifiedWith   string `json:"verified_with,omitempty"`
+	SignedBy       string `json:"signed_by,omitempty"`
+	SignedFile     string `json:"signed_file,omitempty"`
+	AssetURL       string `json:"asset_url,omitempty"`
+	// InstalledAt is when the version was downloaded, rolling
+	// back to it doesn't change the provenance of the binary
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+}
+
+func newStoredVersion(f *providers.File) *storedVersion {
+	return &storedVersion{
+		Version:        f.Version,
+		RemoteName:     f.Name,
+		PackagePath:    f.PackagePath,
+		InstalledAsset: f.Asset,
+		AssetSHA256:    f.AssetSHA256,
+		AssetDigest:    f.AssetDigest,
+		VerifiedWith:   f.VerifiedWith,
+		SignedBy:       f.SignedBy,
+		SignedFile:     f.SignedFile,
+		AssetURL:       f.AssetURL,
+		InstalledAt:    installedNow(),
+	}
+}
+
+func versionStore() (*store.Store, error) {
+	dir, err := config.GetStoreDir()
+	if err != nil {
+		return nil, err
+	}
+	jd, err := config.GetJournalDir()
+	if err != nil {
+		return nil, err
+	}
+	return store.New(dir, jd, config.Get().KeepVersions), nil
+}
+
+// versioned checks if the binary is installed in the store
+func versioned(b *config.Binary) bool {
+	return config.Get().VersionedInstalls && !b.IsFile()
+}
+
+// storeShared checks if more than one entry is installed at path,
+// i.e. $HOME/bin/tool and /home/me/bin/tool, the versions of the
+// store are keyed by the expanded path so they're shared
+func storeShared(path string) bool {
+	n := 0
+	for _, b := range config.Get().Bins {
+		if os.ExpandEnv(b.Path) == path {
+			n++
+		}
+	}
+	return n > 1
+}
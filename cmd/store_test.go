@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+// versionedDemo runs the commands in demo mode against the
+// forge with versioned_installs, it returns the bin directory
+func versionedDemo(t *testing.T, forge *fakeforge.Forge) string {
+	t.Helper()
+	t.Cleanup(func() { demoForge = nil })
+	demoForge = forge
+
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	t.Setenv("TMP", tmp)
+	t.Setenv("BIN_DEMO", "1")
+	for _, k := range []string{"BIN_CONFIG", "BIN_STATE", "XDG_DATA_HOME"} {
+		t.Setenv(k, "")
+	}
+	binDir := filepath.Join(demoDir(), "bin")
+	if err := os.MkdirAll(binDir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(demoDir(), "config.json"), []byte(`{"default_path": "`+binDir+`", "versioned_installs": true}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return binDir
+}
+
+func TestVersionedInstalls(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the versions are copied on windows")
+	}
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	installed := filepath.Join(versionedDemo(t, forge), "tool")
+
+	Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", "http://" + demoHost + "/acme/tool/releases/tag/v1.0.0"})
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", installed})
+	st, err := versionStore()
+	if err != nil {
+		t.Fatal(err)
+	}
+	storeDir := filepath.Dir(filepath.Dir(st.Path(installed, "v1.0.0")))
+	if target, err := os.Readlink(installed); err != nil || target != filepath.Join(storeDir, "v1.1.0", "tool") {
+		t.Fatalf("expected a symlink to v1.1.0 in the store, got %s, %v", target, err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(storeDir, "v1.0.0", "tool")); string(b) != string(fakeforge.Script("tool", "v1.0.0")) {
+		t.Fatalf("expected the previous version to be kept, got %q", b)
+	}
+
+	// only the last 2 versions are kept
+	if err := forge.AddTool("acme/tool", "v1.2.0"); err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(code int) { t.Fatalf("update exited with %d", code) }, []string{"update", "--yes", installed})
+	entries, err := os.ReadDir(storeDir)
+	if err != nil || len(entries) != 2 || entries[0].Name() != "v1.1.0" || entries[1].Name() != "v1.2.0" {
+		t.Fatalf("expected v1.1.0 and v1.2.0 in the store, got %v, %v", entries, err)
+	}
+	if b, _ := os.ReadFile(installed); string(b) != string(fakeforge.Script("tool", "v1.2.0")) {
+		t.Fatalf("expected v1.2.0 to be active, got %q", b)
+	}
+
+	Execute("test", func(code int) { t.Fatalf("remove exited with %d", code) }, []string{"remove", installed})
+	if _, err := os.Lstat(installed); !os.IsNotExist(err) {
+		t.Fatalf("expected the symlink to be removed, got %v", err)
+	}
+	if _, err := os.Stat(storeDir); !os.IsNotExist(err) {
+		t.Fatalf("expected the store of the tool to be removed, got %v", err)
+	}
+}
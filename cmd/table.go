@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// minTruncatedWidth is the width under which
+// columns aren't truncated any further
+const minTruncatedWidth = 12
+
+// tableColumn is a column of a table printed to the terminal
+type tableColumn struct {
+	header string
+	// truncate allows to shorten the cells with an ellipsis
+	// in the middle when the table is wider than the terminal
+	truncate bool
+}
+
+// tableCell is the text of a cell, along with its color
+type tableCell struct {
+	text  string
+	color func(a ...interface{}) string
+}
+
+// columnWidths returns the width of each column so the table fits in
+// width, the widest truncatable columns are shrunk first. The table
+// isn't truncated when width is 0, i.e. when stdout isn't a terminal.
+func columnWidths(columns []tableColumn, rows [][]tableCell, width int) []int {
+	widths := make([]int, len(columns))
+	for i, c := range columns {
+		widths[i] = utf8.RuneCountInString(c.header)
+	}
+	for _, row := range rows {
+		for i, cell := range row {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell.text))
+		}
+	}
+	if width <= 0 {
+		return widths
+	}
+
+	total := 2 * (len(widths) - 1)
+	for _, w := range widths {
+		total += w
+	}
+	for total > width {
+		widest := -1
+		for i, c := range columns {
+			if c.truncate && widths[i] > minTruncatedWidth && (widest < 0 || widths[i] > widths[widest]) {
+				widest = i
+			}
+		}
+		if widest < 0 {
+			break
+		}
+		// shrinking the widest one at a time spreads
+		// the truncation over the wide columns
+		widths[widest]--
+		total--
+	}
+	return widths
+}
+
+// truncateMiddle shortens s to width with an ellipsis in its middle,
+// which keeps both the host and the name of a URL or a path readable
+func truncateMiddle(s string, width int) string {
+	r := []rune(s)
+	if len(r) <= width {
+		return s
+	}
+	if width < 1 {
+		return ""
+	}
+	head := (width - 1) / 2
+	tail := width - 1 - head
+	return string(r[:head]) + "…" + string(r[len(r)-tail:])
+}
+
+// padRight pads s with spaces to width runes
+func padRight(s string, width int) string {
+	if n := utf8.RuneCountInString(s); n < width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	return s
+}
+
+// printTable prints the rows under the headers of the columns, the
+// last column isn't padded. See columnWidths for the truncation.
+func printTable(w io.Writer, columns []tableColumn, rows [][]tableCell, width int, headerColor func(a ...interface{}) string) {
+	widths := columnWidths(columns, rows, width)
+	line := func(cells []tableCell) {
+		parts := make([]string, len(cells))
+		for i, cell := range cells {
+			text := cell.text
+			if columns[i].truncate {
+				text = truncateMiddle(text, widths[i])
+			}
+			if i < len(cells)-1 {
+				text = padRight(text, widths[i])
+			}
+			if cell.color != nil {
+				text = cell.color(text)
+			}
+			parts[i] = text
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(parts, "  "), " "))
+	}
+
+	headers := make([]tableCell, len(columns))
+	for i, c := range columns {
+		headers[i] = tableCell{text: c.header, color: headerColor}
+	}
+	line(headers)
+	for _, row := range rows {
+		line(row)
+	}
+}
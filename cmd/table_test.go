@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestTruncateMiddle(t *testing.T) {
+	for _, c := range []struct {
+		s     string
+		width int
+		out   string
+	}{
+		{"https://github.com/owner/repo", 40, "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo", 15, "https:/…er/repo"},
+		{"/usr/local/bin/tool", 2, "…l"},
+	} {
+		if out := truncateMiddle(c.s, c.width); out != c.out {
+			t.Errorf("%s to %d: expected %q, got %q", c.s, c.width, c.out, out)
+		}
+	}
+}
+
+func TestPrintTable(t *testing.T) {
+	columns := []tableColumn{{header: "Path", truncate: true}, {header: "Version"}, {header: "URL", truncate: true}}
+	rows := [][]tableCell{
+		{{text: "/home/user/.local/bin/tool"}, {text: "1.0.0"}, {text: "https://github.com/owner/tool/releases/tag/v1.0.0"}},
+		{{text: "/home/user/.local/bin/other"}, {text: "10.2.0"}, {text: "https://example.com/other"}},
+	}
+
+	var out bytes.Buffer
+	printTable(&out, columns, rows, 0, nil)
+	if lines := strings.Split(strings.TrimSpace(out.String()), "\n"); !strings.HasSuffix(lines[1], "https://github.com/owner/tool/releases/tag/v1.0.0") {
+		t.Errorf("expected the table not to be truncated without a terminal, got\n%s", out.String())
+	}
+
+	out.Reset()
+	printTable(&out, columns, rows, 60, nil)
+	for _, l := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if n := len([]rune(l)); n > 60 {
+			t.Errorf("expected the lines to fit in 60 columns, got %d: %s", n, l)
+		}
+	}
+	if !strings.Contains(out.String(), "10.2.0") || !strings.Contains(out.String(), "…") {
+		t.Errorf("expected the long columns only to be truncated, got\n%s", out.String())
+	}
+}
+
+func TestSortListPaths(t *testing.T) {
+	dir := t.TempDir()
+	bins := map[string]*config.Binary{}
+	for i, b := range []struct{ name, version string }{{"b", "1.10.0"}, {"a", "1.9.0"}, {"c", "nightly"}} {
+		p := filepath.Join(dir, b.name)
+		if b.name != "c" {
+			if err := os.WriteFile(p, nil, 0o755); err != nil {
+				t.Fatal(err)
+			}
+			mtime := time.Now().Add(-time.Duration(i) * time.Hour)
+			if err := os.Chtimes(p, mtime, mtime); err != nil {
+				t.Fatal(err)
+			}
+		}
+		bins[p] = &config.Binary{Path: p, Version: b.version}
+	}
+
+	for key, expected := range map[string][]string{
+		"name":    {"a", "b", "c"},
+		"version": {"a", "b", "c"},
+		"age":     {"b", "a", "c"},
+	} {
+		paths := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b"), filepath.Join(dir, "c")}
+		if err := sortListPaths(bins, paths, key); err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, len(paths))
+		for i, p := range paths {
+			names[i] = filepath.Base(p)
+		}
+		if !reflect.DeepEqual(names, expected) {
+			t.Errorf("%s: expected %v, got %v", key, expected, names)
+		}
+	}
+	if err := sortListPaths(bins, nil, "size"); err == nil {
+		t.Error("expected an unknown sort to fail")
+	}
+}
@@ -0,0 +1,237 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/prompt"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+// trackedSeries is the newest release of a minor series (i.e. 1.7)
+type trackedSeries struct {
+	series  string
+	version *version.Version
+	release *providers.Release
+}
+
+// latestSeries returns the newest stable release of each
+// of the last n minor series, newest first
+func latestSeries(releases []*providers.Release, tags *providers.TagFilter, n int) []*trackedSeries {
+	bySeries := map[string]*trackedSeries{}
+	for _, r := range releases {
+		if r.Prerelease || !tags.Match(r.Version) {
+			continue
+		}
+		v, err := version.NewVersion(tags.Version(r.Version))
+		if err != nil || v.Prerelease() != "" {
+			continue
+		}
+		seg := v.Segments()
+		s := fmt.Sprintf("%d.%d", seg[0], seg[1])
+		if cur, ok := bySeries[s]; !ok || v.GreaterThan(cur.version) {
+			bySeries[s] = &trackedSeries{series: s, version: v, release: r}
+		}
+	}
+
+	series := make([]*trackedSeries, 0, len(bySeries))
+	for _, s := range bySeries {
+		series = append(series, s)
+	}
+	sort.Slice(series, func(i, j int) bool {
+		return series[i].version.GreaterThan(series[j].version)
+	})
+	if len(series) > n {
+		series = series[:n]
+	}
+	return series
+}
+
+// expandNameTemplate returns the name of the binary of a series,
+// the template supports {major}, {minor}, {patch} and {version}
+func expandNameTemplate(tmpl string, v *version.Version) string {
+	seg := v.Segments()
+	return strings.NewReplacer(
+		"{major}", strconv.Itoa(seg[0]),
+		"{minor}", strconv.Itoa(seg[1]),
+		"{patch}", strconv.Itoa(seg[2]),
+		"{version}", v.String(),
+	).Replace(tmpl)
+}
+
+// trackKey identifies the binaries installed by the
+// same --track-latest install
+func trackKey(b *config.Binary) string {
+	return b.URL + "|" + b.NameTemplate
+}
+
+// installTracked installs the newest release of each of
+// the last b.TrackLatest series into dir
+func installTracked(b *config.Binary, p providers.Provider, dir string, all bool) error {
+	if !strings.Contains(b.NameTemplate, "{") {
+		return fmt.Errorf("--track-latest requires a --name-template with placeholders, i.e. 'terraform-{major}.{minor}'")
+	}
+	latest, err := resolveTracked(b, p)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range latest {
+		path := filepath.Join(dir, expandNameTemplate(b.NameTemplate, s.version))
+		if err := installSeries(b, p, s, path, all); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func resolveTracked(b *config.Binary, p providers.Provider) ([]*trackedSeries, error) {
+	tags, err := providers.NewTagFilter(b.TagPrefix, b.TagPattern)
+	if err != nil {
+		return nil, err
+	}
+	if !p.Capabilities().Has(providers.CapListVersions) {
+		return nil, fmt.Errorf("--track-latest lists the versions, which is not supported by the %s provider for %s", p.GetID(), b.URL)
+	}
+	releases, err := p.ListVersions(0)
+	if err != nil {
+		return nil, err
+	}
+	latest := latestSeries(releases, tags, b.TrackLatest)
+	if len(latest) == 0 {
+		return nil, fmt.Errorf("no stable release found for %s", b.URL)
+	}
+	return latest, nil
+}
+
+// installSeries installs the release of the series, the binary
+// is used as template for the config of the installed one
+func installSeries(b *config.Binary, p providers.Provider, s *trackedSeries, path string, all bool) error {
+	pResult, err := p.Fetch(&providers.FetchOpts{All: all, Version: s.release.Version, PackagePath: b.PackagePath, PackageName: b.RemoteName, Formats: b.PreferFormat, SelectedAsset: b.SelectedAsset, RequireChecksum: config.Get().RequireChecksum, Files: b.IsFile(), Mirrors: b.Mirrors, MirrorFirst: b.MirrorFirst, SigningKeys: config.SigningKeys(b), RequireSignature: config.Get().RequireSignature, AssetRewrites: b.AssetRewrites, BuildFromSource: b.BuildFromSource, MainPackage: b.MainPackage})
+	if err != nil {
+		return err
+	}
+
+	hash, err := saveToDisk(pResult, path, b, true)
+	if err != nil {
+		return fmt.Errorf("error installing binary: %w", err)
+	}
+
+	nb := *b
+	nb.Path = path
+	nb.RemoteName = pResult.Name
+	nb.Version = pResult.Version
+	nb.Hash = fmt.Sprintf("%x", hash)
+	nb.Provider = p.GetID()
+	nb.PackagePath = pResult.PackagePath
+	nb.AssetDigest = pResult.AssetDigest
+	nb.VerifiedWith = pResult.VerifiedWith
+	nb.SignedBy, nb.SignedFile = pResult.SignedBy, pResult.SignedFile
+	nb.InstalledAsset, nb.AssetSHA256 = pResult.Asset, pResult.AssetSHA256
+	nb.AssetURL, nb.InstalledAt = pResult.AssetURL, installedNow()
+	nb.Emulated = pResult.Emulated
+	nb.AssetProfile = recordAsset(nb.AssetProfile, pResult)
+	nb.AssetHintBypassed = pResult.AssetHintBypassed
+	if pResult.SelectedAsset != "" {
+		nb.SelectedAsset = pResult.SelectedAsset
+	}
+	nb.Source = pResult.Source
+	nb.Series = s.series
+	if err := config.UpsertBinary(&nb); err != nil {
+		return err
+	}
+	if err := runPostInstall(&nb); err != nil {
+		return err
+	}
+	// don't prompt for the package path again for the next series
+	b.PackagePath = pResult.PackagePath
+
+	withDownload(binLog(&nb), pResult).Info("Done installing")
+	warnHintBypassed(&nb)
+	return nil
+}
+
+// updateTracked keeps the last series of a --track-latest install:
+// new series get installed, the installed ones get their latest
+// patch release and the ones out of the window are retired after
+// confirmation. It reports whether there were changes to apply.
+func updateTracked(group []*config.Binary, opts updateOpts) (bool, error) {
+	b := group[0]
+	p, err := newProvider(b)
+	if err != nil {
+		return false, err
+	}
+	latest, err := resolveTracked(b, p)
+	if err != nil {
+		return false, err
+	}
+
+	installed := map[string]*config.Binary{}
+	for _, ib := range group {
+		installed[ib.Series] = ib
+	}
+
+	type change struct {
+		s    *trackedSeries
+		path string
+	}
+	changes := []change{}
+	for _, s := range latest {
+		cur, ok := installed[s.series]
+		delete(installed, s.series)
+		if ok && cur.Version == s.release.Version {
+			continue
+		}
+		if ok {
+			binLog(cur).WithField("from", cur.Version).WithField("version", s.release.Version).WithField("release", s.release.URL).WithField("series", s.series).Info("Update available")
+			changes = append(changes, change{s, cur.Path})
+			continue
+		}
+		path := filepath.Join(filepath.Dir(b.Path), expandNameTemplate(b.NameTemplate, s.version))
+		log.WithField("path", path).WithField("version", s.release.Version).WithField("series", s.series).Info("New series")
+		changes = append(changes, change{s, path})
+	}
+
+	retired := []*config.Binary{}
+	for _, ib := range installed {
+		binLog(ib).WithField("version", ib.Version).WithField("series", ib.Series).Info("Will be retired")
+		retired = append(retired, ib)
+	}
+
+	if len(changes) == 0 && len(retired) == 0 {
+		return false, nil
+	}
+	if opts.dryRun {
+		return true, nil
+	}
+	if !opts.yesToUpdate {
+		if err := prompt.Confirm("Do you want to continue?"); err != nil {
+			return true, err
+		}
+	}
+
+	for _, c := range changes {
+		if err := installSeries(b, p, c.s, c.path, opts.all); err != nil {
+			return true, err
+		}
+	}
+
+	paths := []string{}
+	for _, r := range retired {
+		if err := removeBinary(os.ExpandEnv(r.Path)); err != nil {
+			return true, err
+		}
+		paths = append(paths, r.Path)
+	}
+	if len(paths) > 0 {
+		log.WithField("paths", paths).Info("Retired")
+	}
+	return true, config.RemoveBinaries(paths)
+}
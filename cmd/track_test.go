@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/providers"
+)
+
+func TestLatestSeries(t *testing.T) {
+	releases := []*providers.Release{
+		{Version: "v1.8.0-rc1", Prerelease: true},
+		{Version: "v1.7.5"},
+		{Version: "v1.7.4"},
+		{Version: "v1.6.6"},
+		{Version: "v1.10.0"},
+		{Version: "v1.5.7"},
+		{Version: "nightly"},
+	}
+
+	series := latestSeries(releases, nil, 3)
+	want := []string{"v1.10.0", "v1.7.5", "v1.6.6"}
+	if len(series) != len(want) {
+		t.Fatalf("expected %d series, got %d", len(want), len(series))
+	}
+	for i, s := range series {
+		if s.release.Version != want[i] {
+			t.Errorf("series %d: expected %s, got %s", i, want[i], s.release.Version)
+		}
+	}
+}
+
+func TestExpandNameTemplate(t *testing.T) {
+	v := version.Must(version.NewVersion("v1.7.5"))
+	cases := map[string]string{
+		"terraform-{major}.{minor}": "terraform-1.7",
+		"terraform-{version}":       "terraform-1.7.5",
+		"tf{major}{minor}{patch}":   "tf175",
+	}
+	for tmpl, want := range cases {
+		if got := expandNameTemplate(tmpl, v); got != want {
+			t.Errorf("%s: expected %s, got %s", tmpl, want, got)
+		}
+	}
+}
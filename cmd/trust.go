@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/spf13/cobra"
+)
+
+type trustCmd struct {
+	cmd *cobra.Command
+}
+
+func newTrustCmd() *trustCmd {
+	root := &trustCmd{}
+
+	cmd := &cobra.Command{
+		Use:   "trust",
+		Short: "Trusts the configuration of the project",
+		Long: `Trusts the .bin/config.json of the project the working directory is in,
+as it's currently written. Until then, the commands are refused when it
+sets handlers, settings, version_cmd, post_install, verify_args,
+build_from_source, headers referencing the environment or basic_auth,
+since they run commands, send credentials or route the requests.
+
+Review the configuration before trusting it, it has to be trusted again
+when it changes, unless the change is made by bin itself.`,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		Args:          cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			p, err := config.Trust()
+			if err != nil {
+				return err
+			}
+			log.WithField("path", p).Info("Trusted")
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	return root
+}
+
+// checkTrusted refuses the commands, but trust, when the project
+// configuration in use sets commands or credentials and isn't trusted
+func checkTrusted(cmd *cobra.Command) error {
+	s := config.UntrustedSettings()
+	if len(s) == 0 || cmd.Name() == "trust" {
+		return nil
+	}
+	return fmt.Errorf("the configuration of the project at %s isn't trusted and sets %s, review it and run 'bin trust' to use it", config.ProjectRoot(), strings.Join(s, ", "))
+}
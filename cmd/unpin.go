@@ -1,10 +1,8 @@
 package cmd
 
 import (
-	"strings"
-
-	"github.com/caarlos0/log"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/spf13/cobra"
 )
 
@@ -16,11 +14,13 @@ func newUnpinCmd() *unpinCmd {
 	root := &unpinCmd{}
 
 	cmd := &cobra.Command{
-		Use:           "unpin [<name> | <paths...>]",
-		Short:         "Unpins current version of the binaries",
-		SilenceUsage:  true,
-		Args:          cobra.MinimumNArgs(1),
-		SilenceErrors: true,
+		Use:               "unpin [<name> | <paths...>]",
+		ValidArgsFunction: completeBinaries(0),
+		Annotations:       map[string]string{writesConfig: "true"},
+		Short:             "Unpins current version of the binaries",
+		SilenceUsage:      true,
+		Args:              cobra.MinimumNArgs(1),
+		SilenceErrors:     true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			cfg := config.Get()
 
@@ -29,7 +29,7 @@ func newUnpinCmd() *unpinCmd {
 			// To unpin
 			if len(args) > 0 {
 				for _, a := range args {
-					bin, err := getBinPath(a)
+					bin, err := managedBinPath(a)
 					if err != nil {
 						return err
 					}
@@ -52,7 +52,7 @@ func newUnpinCmd() *unpinCmd {
 				continue
 			}
 
-			log.Infof("Unpinned " + strings.Join(unpinned, " "))
+			log.WithField("binaries", unpinned).Info("Unpinned")
 
 			return nil
 		},
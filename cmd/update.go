@@ -3,11 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
 
-	"github.com/caarlos0/log"
-	"github.com/fatih/color"
 	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/assets"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/marcosnils/bin/pkg/prompt"
 	"github.com/marcosnils/bin/pkg/providers"
 	"github.com/spf13/cobra"
@@ -22,86 +26,210 @@ type updateOpts struct {
 	yesToUpdate     bool
 	dryRun          bool
 	all             bool
+	sideFiles       bool
 	skipPathCheck   bool
 	continueOnError bool
+	to              string
+	unpin           bool
+	// overwriteModified replaces the binaries modified
+	// since they were installed without asking
+	overwriteModified bool
+	// reselect forgets the assets previously picked by the user
+	reselect bool
+	// allowAnomalous installs the assets which don't look
+	// like the previous ones without asking
+	allowAnomalous bool
+	// enforceQuota refuses the updates going over the quota
+	enforceQuota bool
+	concurrency  int
+	failFast     bool
+	// includePinned updates the pinned binaries too
+	includePinned bool
+	json          bool
+	// exclude are the globs of the binaries not to update
+	exclude []string
+	// changelog shows the release notes of the
+	// updated binaries, see showReleaseNotes
+	changelog string
 }
 
-type updateInfo struct{ version, url string }
+type updateInfo struct {
+	version, url string
+	// source identifies the releases source shared
+	// by binaries installed from the same release
+	source string
+	// releases are the releases of the source fetched by
+	// the updates, each one is only requested once
+	releases *providers.ReleaseCache
+}
 
 func newUpdateCmd() *updateCmd {
 	root := &updateCmd{}
 	// nolint: dupl
 	cmd := &cobra.Command{
-		Use:           "update [binary_path]",
-		Aliases:       []string{"u"},
-		Short:         "Updates one or multiple binaries managed by bin",
-		SilenceUsage:  true,
-		SilenceErrors: true,
+		Use:               "update [binary_path] [--to version]",
+		ValidArgsFunction: completeBinaries(0),
+		Annotations:       map[string]string{writesConfig: "true"},
+		Aliases:           []string{"u"},
+		Short:             "Updates one or multiple binaries managed by bin",
+		SilenceUsage:      true,
+		SilenceErrors:     true,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			// TODO add support to update from a specific URL.
 			// This allows to update binares from a repo that contains
 			// multiple tags for different binaries
 
-			// TODO update should check all binaries with a
-			// certain configured parallelism (default 10, can be changed with -p) and report
-			// which binarines could be potentially upgraded.
-			// It's very likely that we have to extend the provider
-			// interface to support this use-case
-
 			toUpdate := map[*updateInfo]*config.Binary{}
 			cfg := config.Get()
-			binsToProcess := map[string]*config.Binary{}
-
-			// Update specific binaries
-			if len(args) > 0 {
-				for _, a := range args {
-					bin, err := getBinPath(a)
-					if err != nil {
-						return err
-					}
-					if cfg.Bins[bin].Pinned {
-						log.Infof("%s is a pinned binary", a)
-						continue
+			updateFailures := map[*config.Binary]error{}
+			report := newActionReport()
+			if root.opts.json {
+				defer func() {
+					report.fail(updateFailures)
+					if err := writeJSON(os.Stdout, report.output()); err != nil {
+						log.WithError(err).Warn("Error printing the summary")
 					}
-					binsToProcess[bin] = cfg.Bins[bin]
-				}
-			} else {
-				binsToProcess = cfg.Bins
+				}()
 			}
 
-			updateFailures := map[*config.Binary]error{}
+			if root.opts.changelog == "" {
+				root.opts.changelog = cfg.Changelog
+			}
+			if err := validateChangelog(root.opts.changelog); err != nil {
+				return err
+			}
+			if root.opts.json {
+				// the notes would be mixed with the summary
+				root.opts.changelog = ""
+			}
 
-			for _, b := range binsToProcess {
-				p, err := providers.New(b.URL, b.Provider, b.VersionURL)
+			if root.opts.to != "" {
+				if len(args) != 1 {
+					return fmt.Errorf("--to requires exactly one binary to update")
+				}
+				bin, err := getBinPath(args[0])
 				if err != nil {
 					return err
 				}
-				log.Debugf("Using provider '%s' for '%s'", p.GetID(), b.URL)
+				b := cfg.Bins[bin]
+				if err := updateTo(b, root.opts.to, root.opts); err != nil {
+					updateFailures[b] = err
+					return err
+				}
+				nb := config.Get().Bins[bin]
+				report.record(b, actionUpdated, b.Version, nb.Version)
+				showReleaseNotes(os.Stdout, nb, b.Version, root.opts.changelog)
+				return nil
+			}
 
-				if ui, err := getLatestVersion(b, p); err != nil {
-					if root.opts.continueOnError {
-						updateFailures[b] = fmt.Errorf("Error while getting latest version of %v: %v", b.Path, err)
-						continue
-					}
+			binsToProcess, err := selectBins(args, root.opts.exclude)
+			if err != nil {
+				return err
+			}
+			if !root.opts.includePinned {
+				for _, b := range skipPinned(binsToProcess) {
+					report.record(b, actionPinned, b.Version, "")
+				}
+			}
+			// the binaries given explicitly are updated regardless of their window
+			if len(args) == 0 {
+				deferred, err := deferOutsideWindow(binsToProcess, time.Now())
+				if err != nil {
 					return err
-				} else if ui != nil {
-					toUpdate[ui] = b
 				}
+				for _, b := range deferred {
+					report.record(b, actionDeferred, b.Version, "")
+				}
+			}
+
+			if err := checkConcurrency(root.opts.concurrency); err != nil {
+				return err
+			}
+
+			// failed counts the failures already reported
+			failed := 0
+			// fail stops the update at the first failure with --fail-fast,
+			// the failures are collected and reported at the end otherwise
+			fail := func(b *config.Binary, err error) error {
+				updateFailures[b] = err
+				if root.opts.failFast {
+					return err
+				}
+				return nil
+			}
+
+			// binaries installed with --track-latest are updated as a group
+			tracked := map[string][]*config.Binary{}
+			for k, b := range binsToProcess {
+				if b.TrackLatest > 0 {
+					tracked[trackKey(b)] = nil
+					delete(binsToProcess, k)
+				}
+			}
+			for _, b := range cfg.Bins {
+				if _, ok := tracked[trackKey(b)]; ok && b.TrackLatest > 0 {
+					tracked[trackKey(b)] = append(tracked[trackKey(b)], b)
+				}
+			}
+			trackedChanges := false
+			for _, group := range tracked {
+				changed, err := updateTracked(group, root.opts)
+				report.recordTracked(group, changed, root.opts.dryRun, err)
+				if err != nil {
+					if err := fail(group[0], fmt.Errorf("Error while updating the series of %v: %v", group[0].URL, err)); err != nil {
+						return err
+					}
+					continue
+				}
+				trackedChanges = trackedChanges || changed
+			}
+
+			checked, checkFailures := checkUpdates(binsToProcess, root.opts.concurrency, root.opts.failFast)
+			var pending []*config.Binary
+			for _, b := range sortedBins(binsToProcess) {
+				if err, ok := checkFailures[b]; ok {
+					updateFailures[b] = err
+					continue
+				}
+				ui, ok := checked[b]
+				if !ok {
+					report.record(b, actionUpToDate, b.Version, "")
+					continue
+				}
+				binLog(b).WithField("from", b.Version).WithField("version", ui.version).WithField("release", ui.url).Info("Update available")
+				toUpdate[ui] = b
+				pending = append(pending, b)
+				report.record(b, actionAvailable, b.Version, ui.version)
+			}
+			if root.opts.failFast && len(updateFailures) > 0 {
+				return failuresError("update", warnFailures(updateFailures))
 			}
 
 			if len(toUpdate) == 0 && len(updateFailures) == 0 {
-				log.Infof("All binaries are up to date")
+				if trackedChanges && root.opts.dryRun {
+					return wrapErrorWithCode(fmt.Errorf("Updates found, exit (dry-run mode)."), 3, "")
+				}
+				if !trackedChanges {
+					log.Info("All binaries are up to date")
+				}
 				return nil
 			}
 
 			if root.opts.dryRun {
+				for _, b := range pending {
+					skipPostInstall(b)
+				}
+				if n := warnFailures(updateFailures); n > 0 && !root.opts.continueOnError {
+					return failuresError("update", n)
+				}
 				return wrapErrorWithCode(fmt.Errorf("Updates found, exit (dry-run mode)."), 3, "")
 			}
 
 			if len(toUpdate) > 0 && !root.opts.yesToUpdate {
-				for _, err := range updateFailures {
-					log.Warnf("%v", err)
-				}
+				// the failures are reported before asking so
+				// the user knows what's not going to be updated
+				failed += warnFailures(updateFailures)
+				report.fail(updateFailures)
 				updateFailures = map[*config.Binary]error{}
 
 				err := prompt.Confirm("Do you want to continue?")
@@ -110,49 +238,65 @@ func newUpdateCmd() *updateCmd {
 				}
 			}
 
-			// TODO	:S code smell here, this pretty much does
-			// the same thing as install logic. Refactor to
-			// use the same code in both places
+			// the modified binaries are guarded before the downloads
+			// start, the questions they may ask go together
+			infos := map[*config.Binary]*updateInfo{}
+			var jobs []*config.Binary
+			sort.Slice(pending, func(i, j int) bool { return pending[i].Path < pending[j].Path })
 			for ui, b := range toUpdate {
-
-				p, err := providers.New(ui.url, b.Provider, b.VersionURL)
-				if err != nil {
-					return err
-				}
-				log.Debugf("Using provider '%s' for '%s'", p.GetID(), ui.url)
-
-				pResult, err := p.Fetch(&providers.FetchOpts{All: root.opts.all, PackagePath: b.PackagePath, SkipPatchCheck: root.opts.skipPathCheck, PackageName: b.RemoteName})
-				if err != nil {
-					if root.opts.continueOnError {
-						updateFailures[b] = fmt.Errorf("Error while fetching %v: %w", ui.url, err)
-						continue
+				infos[b] = ui
+			}
+			for _, b := range pending {
+				if err := guardModified(b, root.opts.overwriteModified); err != nil {
+					if err := fail(b, err); err != nil {
+						return err
 					}
-					return err
+					continue
 				}
+				jobs = append(jobs, b)
+			}
 
-				hash, err := saveToDisk(pResult, b.Path, true)
-				if err != nil {
-					return fmt.Errorf("error installing binary: %w", err)
-				}
+			cache := assets.NewDownloadCache()
+			defer cache.Close()
+			results := make([]*config.Binary, len(jobs))
+			files := make([]*providers.File, len(jobs))
+			errs := forEach(len(jobs), root.opts.concurrency, root.opts.failFast, func(i int) error {
+				var err error
+				results[i], files[i], err = updateBinary(jobs[i], infos[jobs[i]], root.opts, cache)
+				return err
+			})
 
-				err = config.UpsertBinary(&config.Binary{
-					RemoteName:  pResult.Name,
-					Path:        b.Path,
-					Version:     pResult.Version,
-					Hash:        fmt.Sprintf("%x", hash),
-					URL:         ui.url,
-					PackagePath: pResult.PackagePath,
-				})
-				if err != nil {
-					return err
+			updated := map[string][]string{}
+			for i, b := range jobs {
+				if errs[i] != nil {
+					updateFailures[b] = errs[i]
+					continue
+				}
+				ui, nb := infos[b], results[i]
+				report.record(b, actionUpdated, b.Version, nb.Version)
+				withDownload(binLog(nb), files[i]).WithField("from", b.Version).Info("Done updating")
+				warnHintBypassed(nb)
+				warnEmulated(nb)
+				if len(nb.Platforms) > 1 {
+					checkPlatformVersions(nb, files[i].Version)
+				}
+				updated[ui.source] = append(updated[ui.source], os.ExpandEnv(b.Path))
+			}
+			for _, paths := range updated {
+				if len(paths) > 1 {
+					sort.Strings(paths)
+					log.WithField("paths", paths).Info("Updated together from the same release")
+				}
+			}
+			for i, b := range jobs {
+				if errs[i] == nil {
+					showReleaseNotes(os.Stdout, results[i], b.Version, root.opts.changelog)
 				}
-
-				log.Infof("Done updating %s to %s", os.ExpandEnv(b.Path), color.GreenString(ui.version))
 			}
-			for _, err := range updateFailures {
-				log.Warnf("%v", err)
+			failed += warnFailures(updateFailures)
+			if failed > 0 && !root.opts.continueOnError {
+				return failuresError("update", failed)
 			}
-			// TODO: Return wrapping error with specific exit code if len(updateFailures) > 0?
 			return nil
 		},
 	}
@@ -161,29 +305,398 @@ func newUpdateCmd() *updateCmd {
 	root.cmd.Flags().BoolVarP(&root.opts.dryRun, "dry-run", "", false, "Only show status, don't prompt for update")
 	root.cmd.Flags().BoolVarP(&root.opts.yesToUpdate, "yes", "y", false, "Assume yes to update prompt")
 	root.cmd.Flags().BoolVarP(&root.opts.all, "all", "a", false, "Show all possible download options (skip scoring & filtering)")
+	root.cmd.Flags().BoolVar(&root.opts.sideFiles, "side-files", false, "Don't exclude the checksums, signatures, SBOMs and source archives from the download options")
 	root.cmd.Flags().BoolVarP(&root.opts.skipPathCheck, "skip-path-check", "p", false, "Skips path checking when looking into packages")
-	root.cmd.Flags().BoolVarP(&root.opts.continueOnError, "continue-on-error", "c", false, "Continues to update next package if an error is encountered")
+	root.cmd.Flags().BoolVarP(&root.opts.continueOnError, "continue-on-error", "c", false, "Don't exit with an error when some binaries fail to update")
+	root.cmd.Flags().BoolVar(&root.opts.includePinned, "include-pinned", false, "Update the pinned binaries too, they stay pinned to their new version")
+	root.cmd.Flags().StringVar(&root.opts.to, "to", "", "Install a specific version (upgrade or downgrade), the binary gets pinned to it")
+	root.cmd.Flags().BoolVar(&root.opts.unpin, "unpin", false, "Don't pin the binary when using --to")
+	root.cmd.Flags().BoolVar(&root.opts.reselect, "reselect", false, "Forget the assets previously picked when several of them matched, and ask again")
+	root.cmd.Flags().BoolVar(&root.opts.enforceQuota, "enforce-quota", false, "Refuse the updates bringing the disk usage over the configured quota instead of warning")
+	root.cmd.Flags().BoolVar(&root.opts.allowAnomalous, "allow-anomalous", false, "Install the assets which don't look like the previous ones (name, format, size) without asking")
+	root.cmd.Flags().BoolVar(&root.opts.overwriteModified, "overwrite-modified", false, "Replace the binaries modified since they were installed without asking, a copy is kept as <name>.local")
+	root.cmd.Flags().IntVar(&root.opts.concurrency, "concurrency", defaultConcurrency, "Number of binaries checked and fetched at once")
+	root.cmd.Flags().BoolVar(&root.opts.failFast, "fail-fast", false, "Stop at the first binary failing to be updated")
+	root.cmd.Flags().BoolVar(&root.opts.json, "json", false, "Print a summary of what was done to each binary as JSON, see `bin schema update`")
+	root.cmd.Flags().StringSliceVar(&root.opts.exclude, "exclude", nil, "Don't update the binaries whose name matches one of these globs (i.e. 'kube*')")
+	root.cmd.Flags().StringVar(&root.opts.changelog, "changelog", "", "Show the release notes of the updated binaries: target for the version updated to, all for the releases in between too")
+	root.cmd.Flags().Lookup("changelog").NoOptDefVal = changelogTarget
 	return root
 }
 
+// checkUpdates checks the latest version of the binaries concurrently,
+// the ones sharing a source are only resolved once. It returns the
+// updates found and the failures, and records the results of the
+// checks for `bin outdated`.
+func checkUpdates(bins map[string]*config.Binary, concurrency int, failFast bool) (map[*config.Binary]*updateInfo, map[*config.Binary]error) {
+	updates := map[*config.Binary]*updateInfo{}
+	failures := map[*config.Binary]error{}
+
+	// binaries installed from the same source (i.e. several tools
+	// of one release) are only resolved once
+	sources := map[string][]*config.Binary{}
+	resolvers := map[string]*sourceResolver{}
+	for _, b := range bins {
+		p, err := newProvider(b)
+		if err != nil {
+			failures[b] = err
+			continue
+		}
+		log.Debugf("Using provider '%s' for '%s'", p.GetID(), b.URL)
+
+		key := sourceKey(b, p)
+		if _, ok := resolvers[key]; !ok {
+			resolvers[key] = &sourceResolver{p: p, releases: providers.NewReleaseCache()}
+		}
+		sources[key] = append(sources[key], b)
+	}
+	if failFast && len(failures) > 0 {
+		return updates, failures
+	}
+
+	// the sources are checked concurrently
+	keys := make([]string, 0, len(sources))
+	for key, bins := range sources {
+		sort.Slice(bins, func(i, j int) bool { return bins[i].Path < bins[j].Path })
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	checked := make([][]*updateInfo, len(keys))
+	checkErrs := make([][]error, len(keys))
+	errs := forEach(len(keys), concurrency, failFast, func(i int) error {
+		bins := sources[keys[i]]
+		checked[i] = make([]*updateInfo, len(bins))
+		checkErrs[i] = make([]error, len(bins))
+		var first error
+		for j, b := range bins {
+			checked[i][j], checkErrs[i][j] = resolvers[keys[i]].check(b)
+			if first == nil {
+				first = checkErrs[i][j]
+			}
+		}
+		return first
+	})
+	for i, key := range keys {
+		for j, b := range sources[key] {
+			if errs[i] == errSkipped {
+				failures[b] = errSkipped
+				continue
+			}
+			if err := checkErrs[i][j]; err != nil {
+				failures[b] = fmt.Errorf("Error while getting latest version of %v: %v", b.Path, err)
+			} else if ui := checked[i][j]; ui != nil {
+				ui.source, ui.releases = key, resolvers[key].releases
+				updates[b] = ui
+			}
+		}
+	}
+
+	now := time.Now()
+	checks := map[string]*checkResult{}
+	for k, b := range bins {
+		if _, failed := failures[b]; failed {
+			continue
+		}
+		checks[k] = &checkResult{Installed: b.Version, Latest: b.Version, CheckedAt: now}
+		if ui, ok := updates[b]; ok {
+			checks[k].Latest, checks[k].URL = ui.version, ui.url
+		}
+	}
+	recordChecks(checks)
+	return updates, failures
+}
+
+// sortedBins returns the binaries sorted by path
+func sortedBins(bins map[string]*config.Binary) []*config.Binary {
+	sorted := make([]*config.Binary, 0, len(bins))
+	for _, b := range bins {
+		sorted = append(sorted, b)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted
+}
+
+// updateBinary fetches the new version of the binary and installs it.
+//
+// TODO	:S code smell here, this pretty much does
+// the same thing as install logic. Refactor to
+// use the same code in both places
+func updateBinary(b *config.Binary, ui *updateInfo, opts updateOpts, cache *assets.DownloadCache) (*config.Binary, *providers.File, error) {
+	nb := *b
+	if opts.reselect {
+		nb.SelectedAsset = ""
+	}
+	// templates are resolved with the new version
+	// so they keep working on other platforms
+	version := ui.version
+	if !providers.IsTemplate(b.URL) {
+		version = ""
+		// binaries with several sources keep their URLs
+		if len(b.Sources) == 0 {
+			nb.URL = ui.url
+		}
+	}
+	p, err := newProvider(&nb)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Debugf("Using provider '%s' for '%s'", p.GetID(), ui.url)
+
+	pResult, err := p.Fetch(&providers.FetchOpts{All: opts.all, SideFiles: opts.sideFiles, PackagePath: b.PackagePath, SkipPatchCheck: opts.skipPathCheck, PackageName: b.RemoteName, Cache: cache, Releases: ui.releases, Version: version, Formats: b.PreferFormat, SelectedAsset: nb.SelectedAsset, RequireChecksum: config.Get().RequireChecksum, Files: b.IsFile(), Completions: b.InstallCompletions, Manpages: b.InstallManpages, Mirrors: b.Mirrors, MirrorFirst: b.MirrorFirst, SigningKeys: config.SigningKeys(b), RequireSignature: config.Get().RequireSignature, AssetRewrites: b.AssetRewrites, BuildFromSource: b.BuildFromSource, MainPackage: b.MainPackage})
+	if err != nil {
+		return nil, nil, fmt.Errorf("Error while fetching %v: %w", ui.url, err)
+	}
+
+	if err := guardAnomalous(b, pResult, opts.allowAnomalous); err != nil {
+		return nil, nil, err
+	}
+	if err := guardQuota(b.Path, pResult, opts.enforceQuota); err != nil {
+		return nil, nil, err
+	}
+
+	hash, err := saveToDisk(pResult, b.Path, b, true)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error installing binary: %w", err)
+	}
+
+	nb.RemoteName = pResult.Name
+	nb.Version = pResult.Version
+	nb.Hash = fmt.Sprintf("%x", hash)
+	nb.PackagePath = pResult.PackagePath
+	nb.AssetDigest = pResult.AssetDigest
+	nb.VerifiedWith = pResult.VerifiedWith
+	nb.SignedBy, nb.SignedFile = pResult.SignedBy, pResult.SignedFile
+	nb.InstalledAsset, nb.AssetSHA256 = pResult.Asset, pResult.AssetSHA256
+	nb.AssetURL, nb.InstalledAt = pResult.AssetURL, installedNow()
+	nb.Emulated = pResult.Emulated
+	nb.AssetProfile = recordAsset(b.AssetProfile, pResult)
+	nb.AssetHintBypassed = pResult.AssetHintBypassed
+	if pResult.SelectedAsset != "" {
+		nb.SelectedAsset = pResult.SelectedAsset
+	}
+	nb.Source = pResult.Source
+	if err := installExtras(&nb, pResult.Extras); err != nil {
+		return nil, nil, err
+	}
+	if err := upsertBinary(&nb); err != nil {
+		return nil, nil, err
+	}
+	if err := runPostInstall(&nb); err != nil {
+		return nil, nil, err
+	}
+	return &nb, pResult, nil
+}
+
+// updateTo replaces the binary with the given version. It's pinned
+// unless requested otherwise so the next update doesn't revert it
+func updateTo(b *config.Binary, v string, opts updateOpts) error {
+	// allow to omit the component prefix of monorepo tags
+	if b.TagPrefix != "" && !strings.HasPrefix(v, b.TagPrefix) {
+		v = b.TagPrefix + v
+	}
+
+	if err := guardModified(b, opts.overwriteModified); err != nil {
+		return err
+	}
+
+	p, err := newProvider(b)
+	if err != nil {
+		return err
+	}
+	log.Debugf("Using provider '%s' for '%s'", p.GetID(), b.URL)
+
+	selected := b.SelectedAsset
+	if opts.reselect {
+		selected = ""
+	}
+	pResult, err := p.Fetch(&providers.FetchOpts{All: opts.all, SideFiles: opts.sideFiles, PackagePath: b.PackagePath, SkipPatchCheck: opts.skipPathCheck, PackageName: b.RemoteName, Version: v, Formats: b.PreferFormat, SelectedAsset: selected, RequireChecksum: config.Get().RequireChecksum, Files: b.IsFile(), Completions: b.InstallCompletions, Manpages: b.InstallManpages, Mirrors: b.Mirrors, MirrorFirst: b.MirrorFirst, SigningKeys: config.SigningKeys(b), RequireSignature: config.Get().RequireSignature, AssetRewrites: b.AssetRewrites, BuildFromSource: b.BuildFromSource, MainPackage: b.MainPackage})
+	if err != nil {
+		return fmt.Errorf("unable to get version %s of %s, list the available ones with `bin versions %s`: %w", v, b.Path, filepath.Base(b.Path), err)
+	}
+
+	if err := guardAnomalous(b, pResult, opts.allowAnomalous); err != nil {
+		return err
+	}
+	if err := guardQuota(b.Path, pResult, opts.enforceQuota); err != nil {
+		return err
+	}
+
+	hash, err := saveToDisk(pResult, b.Path, b, true)
+	if err != nil {
+		return fmt.Errorf("error installing binary: %w", err)
+	}
+
+	nb := *b
+	nb.RemoteName = pResult.Name
+	nb.Version = pResult.Version
+	nb.Hash = fmt.Sprintf("%x", hash)
+	nb.PackagePath = pResult.PackagePath
+	nb.AssetDigest = pResult.AssetDigest
+	nb.VerifiedWith = pResult.VerifiedWith
+	nb.SignedBy, nb.SignedFile = pResult.SignedBy, pResult.SignedFile
+	nb.InstalledAsset, nb.AssetSHA256 = pResult.Asset, pResult.AssetSHA256
+	nb.AssetURL, nb.InstalledAt = pResult.AssetURL, installedNow()
+	nb.Emulated = pResult.Emulated
+	nb.AssetProfile = recordAsset(b.AssetProfile, pResult)
+	nb.AssetHintBypassed = pResult.AssetHintBypassed
+	nb.SelectedAsset = selected
+	if pResult.SelectedAsset != "" {
+		nb.SelectedAsset = pResult.SelectedAsset
+	}
+	nb.Pinned = !opts.unpin
+	if err := installExtras(&nb, pResult.Extras); err != nil {
+		return err
+	}
+	if err := config.UpsertBinary(&nb); err != nil {
+		return err
+	}
+	if err := runPostInstall(&nb); err != nil {
+		return err
+	}
+
+	withDownload(binLog(&nb), pResult).WithField("from", b.Version).Info("Done updating")
+	if nb.Pinned {
+		binLog(&nb).WithField("version", nb.Version).Info("Pinned, unpin it to get updates again")
+	}
+	warnHintBypassed(&nb)
+	warnEmulated(&nb)
+	return nil
+}
+
+// sourceKey identifies the releases source of the binary. Providers
+// which can't tell it are identified by the binary URL
+func sourceKey(b *config.Binary, p providers.Provider) string {
+	source := b.URL
+	if s, ok := p.(providers.Sourcer); ok {
+		source = s.GetSource()
+	}
+	return strings.Join([]string{p.GetID(), source, b.TagPrefix, b.TagPattern}, "|")
+}
+
+// sourceResolver checks the updates of the binaries sharing the
+// same source, its latest version is only requested once
+type sourceResolver struct {
+	p providers.Provider
+	// releases are shared by the updates of the binaries
+	releases *providers.ReleaseCache
+
+	resolved     bool
+	version, url string
+}
+
 func getLatestVersion(b *config.Binary, p providers.Provider) (*updateInfo, error) {
+	return (&sourceResolver{p: p}).check(b)
+}
+
+func (r *sourceResolver) check(b *config.Binary) (*updateInfo, error) {
 	log.Debugf("Checking updates for %s", b.Path)
-	v, u, err := p.GetLatestVersion()
-	if err != nil {
-		return nil, fmt.Errorf("Error checking updates for %s, %w", b.Path, err)
+	// local files are re-installed whenever their content changes
+	if d, ok := r.p.(providers.AssetDigester); ok && (b.MutableTag || providers.IsRollingTag(b.Version) || r.p.GetID() == "local") {
+		return checkRepublished(b, d)
+	}
+
+	if r.p.GetID() == "direct" {
+		binLog(b).WithField("version", b.Version).Info("Direct download, install the URL of another version to change it")
+		return nil, nil
 	}
 
+	if !r.resolved {
+		v, u, err := r.p.GetLatestVersion()
+		if err != nil {
+			return nil, fmt.Errorf("Error checking updates for %s, %w", b.Path, err)
+		}
+		r.version, r.url, r.resolved = v, u, true
+	}
+	v, u := r.version, r.url
+
 	if b.Version == v {
 		return nil, nil
 	}
 
-	bSemver, bSemverErr := version.NewVersion(b.Version)
-	vSemver, vSemverErr := version.NewVersion(v)
+	// strip the component prefix of monorepo tags
+	// so the versions can be compared
+	tags, err := providers.NewTagFilter(b.TagPrefix, b.TagPattern)
+	if err != nil {
+		return nil, err
+	}
+	bSemver, bSemverErr := version.NewVersion(tags.Version(b.Version))
+	vSemver, vSemverErr := version.NewVersion(tags.Version(v))
 	if bSemverErr == nil && vSemverErr == nil && vSemver.LessThanOrEqual(bSemver) {
 		return nil, nil
 	}
 
 	log.Debugf("Found new version %s for %s at %s", v, b.Path, u)
-	log.Infof("%s %s -> %s (%s)", b.Path, color.YellowString(b.Version), color.GreenString(v), u)
-	return &updateInfo{v, u}, nil
+	return &updateInfo{version: v, url: u}, nil
+}
+
+// checkRepublished checks if the assets of a mutable tag were
+// re-published since the binary was installed
+func checkRepublished(b *config.Binary, d providers.AssetDigester) (*updateInfo, error) {
+	log.Debugf("%s is installed from mutable tag %s, checking its assets", b.Path, b.Version)
+	digests, err := d.GetAssetDigests(b.Version)
+	if err != nil {
+		return nil, fmt.Errorf("Error checking updates for %s, %w", b.Path, err)
+	}
+
+	if b.AssetDigest == "" {
+		binLog(b).WithField("version", b.Version).Info("No recorded asset digest, re-installing")
+		return &updateInfo{version: b.Version, url: b.URL}, nil
+	}
+
+	for _, digest := range digests {
+		if digest == b.AssetDigest {
+			return nil, nil
+		}
+	}
+
+	binLog(b).WithField("version", b.Version).Info("The assets were re-published")
+	return &updateInfo{version: b.Version, url: b.URL}, nil
+}
+
+// deferOutsideWindow removes the binaries outside of their update
+// window at the given time from the ones to update, with a notice
+// listing them, and returns them
+func deferOutsideWindow(bins map[string]*config.Binary, now time.Time) ([]*config.Binary, error) {
+	var deferred []*config.Binary
+	var names []string
+	for k, b := range bins {
+		if b.UpdateWindow == "" {
+			continue
+		}
+		w, err := config.ParseUpdateWindow(b.UpdateWindow)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", os.ExpandEnv(b.Path), err)
+		}
+		if !w.Contains(now) {
+			deferred = append(deferred, b)
+			names = append(names, fmt.Sprintf("%s (window %s)", filepath.Base(b.Path), b.UpdateWindow))
+			delete(bins, k)
+		}
+	}
+	if len(deferred) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+	log.WithField("binaries", names).Info("Deferring until their update window, name them to update them now")
+	return deferred, nil
+}
+
+// skipPinned removes the pinned binaries from the ones to
+// update, with a notice listing them, and returns them
+func skipPinned(bins map[string]*config.Binary) []*config.Binary {
+	var pinned []*config.Binary
+	var names []string
+	for k, b := range bins {
+		if b.Pinned {
+			pinned = append(pinned, b)
+			names = append(names, filepath.Base(b.Path))
+			delete(bins, k)
+		}
+	}
+	if len(pinned) == 0 {
+		return nil
+	}
+	sort.Strings(names)
+	log.WithField("binaries", names).Info("Skipping pinned binaries, use --include-pinned to update them")
+	return pinned
 }
@@ -3,6 +3,7 @@ package cmd
 import (
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/marcosnils/bin/pkg/config"
 	"github.com/marcosnils/bin/pkg/providers"
@@ -23,6 +24,10 @@ func (m mockProvider) GetID() string {
 	return "github"
 }
 
+func (m mockProvider) Capabilities() providers.Capabilities {
+	return providers.NewCapabilities(providers.CapListVersions)
+}
+
 func TestGetLatestVersion(t *testing.T) {
 	type mockValues struct {
 		latestVersion    string
@@ -59,6 +64,33 @@ func TestGetLatestVersion(t *testing.T) {
 			mockValues{"1.1.1", "https://github.com/Mirantis/launchpad/releases/download/1.1.1/launchpad-linux-x64", nil},
 			nil,
 		},
+		{
+			&config.Binary{
+				Path:       "/home/user/bin/protoc-gen-openapiv2",
+				Version:    "protoc-gen-openapiv2/v2.9.0",
+				URL:        "https://github.com/grpc-ecosystem/grpc-gateway/releases/tag/protoc-gen-openapiv2/v2.9.0",
+				RemoteName: "protoc-gen-openapiv2-v2.9.0-linux-x86_64",
+				Provider:   "github",
+				TagPrefix:  "protoc-gen-openapiv2/",
+			},
+			mockValues{"protoc-gen-openapiv2/v2.10.0", "https://github.com/grpc-ecosystem/grpc-gateway/releases/tag/protoc-gen-openapiv2/v2.10.0", nil},
+			&updateInfo{
+				version: "protoc-gen-openapiv2/v2.10.0",
+				url:     "https://github.com/grpc-ecosystem/grpc-gateway/releases/tag/protoc-gen-openapiv2/v2.10.0",
+			},
+		},
+		{
+			&config.Binary{
+				Path:       "/home/user/bin/protoc-gen-openapiv2",
+				Version:    "protoc-gen-openapiv2/v2.10.0",
+				URL:        "https://github.com/grpc-ecosystem/grpc-gateway/releases/tag/protoc-gen-openapiv2/v2.10.0",
+				RemoteName: "protoc-gen-openapiv2-v2.10.0-linux-x86_64",
+				Provider:   "github",
+				TagPrefix:  "protoc-gen-openapiv2/",
+			},
+			mockValues{"protoc-gen-openapiv2/v2.9.0", "https://github.com/grpc-ecosystem/grpc-gateway/releases/tag/protoc-gen-openapiv2/v2.9.0", nil},
+			nil,
+		},
 	}
 
 	for _, c := range cases {
@@ -71,3 +103,126 @@ func TestGetLatestVersion(t *testing.T) {
 	}
 
 }
+
+type mockDigesterProvider struct {
+	mockProvider
+	digests []string
+}
+
+func (m mockDigesterProvider) GetAssetDigests(version string) ([]string, error) {
+	return m.digests, nil
+}
+
+func TestGetLatestVersionMutableTag(t *testing.T) {
+	nightly := func(digest string, mutable bool) *config.Binary {
+		return &config.Binary{
+			Path:        "/home/user/bin/nvim",
+			Version:     "nightly",
+			URL:         "https://github.com/neovim/neovim/releases/tag/nightly",
+			RemoteName:  "nvim",
+			Provider:    "github",
+			AssetDigest: digest,
+			MutableTag:  mutable,
+		}
+	}
+	republished := &updateInfo{version: "nightly", url: "https://github.com/neovim/neovim/releases/tag/nightly"}
+
+	cases := []struct {
+		name    string
+		in      *config.Binary
+		digests []string
+		out     *updateInfo
+	}{
+		{"unchanged asset", nightly("1@2024-01-01T00:00:00Z", false), []string{"2@2024-01-02T00:00:00Z", "1@2024-01-01T00:00:00Z"}, nil},
+		{"re-published asset", nightly("1@2024-01-01T00:00:00Z", false), []string{"1@2024-01-02T00:00:00Z"}, republished},
+		{"missing digest", nightly("", false), []string{"1@2024-01-02T00:00:00Z"}, republished},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			// the latest version must not be used for mutable tags
+			p := mockDigesterProvider{mockProvider{latestVersion: "v0.10.0"}, c.digests}
+			v, err := getLatestVersion(c.in, p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(v, c.out) {
+				t.Fatalf("%#v does not match %#v", v, c.out)
+			}
+		})
+	}
+
+	t.Run("explicitly mutable tag", func(t *testing.T) {
+		b := nightly("1@2024-01-01T00:00:00Z", true)
+		b.Version = "tip"
+		p := mockDigesterProvider{mockProvider{latestVersion: "v0.10.0"}, []string{"1@2024-01-03T00:00:00Z"}}
+		v, err := getLatestVersion(b, p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v == nil || v.version != "tip" {
+			t.Fatalf("expected tip to be re-installed, got %#v", v)
+		}
+	})
+}
+
+type countingProvider struct {
+	mockProvider
+	calls *int
+}
+
+func (m countingProvider) GetLatestVersion() (string, string, error) {
+	*m.calls++
+	return m.mockProvider.GetLatestVersion()
+}
+
+func TestSourceResolverSharedRelease(t *testing.T) {
+	calls := 0
+	p := countingProvider{mockProvider{latestVersion: "v1.30.0", latestVersionURL: "https://github.com/kubernetes/kubernetes/releases/tag/v1.30.0"}, &calls}
+	r := &sourceResolver{p: p}
+
+	for _, name := range []string{"kubectl", "kubeadm", "kubelet"} {
+		b := &config.Binary{
+			Path:     "/home/user/bin/" + name,
+			Version:  "v1.29.0",
+			URL:      "https://github.com/kubernetes/kubernetes/releases/tag/v1.29.0",
+			Provider: "github",
+		}
+		ui, err := r.check(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ui == nil || ui.version != "v1.30.0" {
+			t.Fatalf("expected %s to be updated to v1.30.0, got %#v", name, ui)
+		}
+	}
+
+	if calls != 1 {
+		t.Fatalf("expected the latest version to be resolved once, got %d calls", calls)
+	}
+}
+
+func TestDeferOutsideWindow(t *testing.T) {
+	bins := map[string]*config.Binary{
+		"/bin/kubectl": {Path: "/bin/kubectl", UpdateWindow: "fri"},
+		"/bin/kind":    {Path: "/bin/kind", UpdateWindow: "mon-fri 18:00-08:00"},
+		"/bin/jq":      {Path: "/bin/jq"},
+	}
+	// 2024-03-07 is a thursday
+	thursday := time.Date(2024, 3, 7, 19, 0, 0, 0, time.Local)
+	deferred, err := deferOutsideWindow(bins, thursday)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deferred) != 1 || deferred[0].Path != "/bin/kubectl" {
+		t.Fatalf("expected kubectl to be deferred, got %+v", deferred)
+	}
+	if _, ok := bins["/bin/kubectl"]; ok || len(bins) != 2 {
+		t.Fatalf("expected kind and jq to be updated, got %v", bins)
+	}
+
+	bins["/bin/yq"] = &config.Binary{Path: "/bin/yq", UpdateWindow: "someday"}
+	if _, err := deferOutsideWindow(bins, thursday); err == nil {
+		t.Fatal("expected an invalid window to fail")
+	}
+}
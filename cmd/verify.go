@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"sort"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/schema"
+	"github.com/marcosnils/bin/pkg/stats"
+)
+
+// the statuses reported by `bin ensure --check`,
+// they're part of its schema, see ensure-check.json
+const (
+	verifyOK            = "ok"
+	verifyMissing       = "missing"
+	verifyNotExecutable = "not-executable"
+	verifyModified      = "modified"
+	verifyMismatch      = "version-mismatch"
+	verifyOutdated      = "outdated"
+	verifyError         = "error"
+)
+
+// verifyOutput is the JSON output of `bin ensure --check`
+type verifyOutput struct {
+	SchemaVersion int           `json:"schema_version"`
+	Bins          []*verifyItem `json:"bins"`
+}
+
+// verifyItem is the status of a binary compared to its entry
+type verifyItem struct {
+	Path    string `json:"path"`
+	Status  string `json:"status"`
+	Version string `json:"version"`
+	// LocalVersion is the version reported by a modified binary
+	LocalVersion string `json:"local_version,omitempty"`
+	// Latest is the latest version upstream, with --remote
+	Latest string `json:"latest,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// ok reports whether the binary matches its entry
+func (v *verifyItem) ok() bool {
+	return v.Status == verifyOK
+}
+
+// verifyBinary compares the binary on disk with its entry without
+// changing anything. The file is hashed and, when it doesn't match, the
+// version it reports is compared instead. Nothing is fetched unless
+// remote is set, the latest version of the binaries which aren't pinned
+// is then compared too.
+func verifyBinary(b *config.Binary, remote bool) *verifyItem {
+	ep := os.ExpandEnv(b.Path)
+	v := &verifyItem{Path: ep, Status: verifyOK, Version: b.Version}
+	fi, err := os.Stat(stats.Resolve(ep))
+	if os.IsNotExist(err) {
+		v.Status = verifyMissing
+		return v
+	}
+	if err != nil {
+		v.Status, v.Error = verifyError, err.Error()
+		return v
+	}
+	// Windows has no executable bit, the extension makes a file executable
+	if !b.IsFile() && runtime.GOOS != "windows" && fi.Mode().Perm()&0o111 == 0 {
+		v.Status = verifyNotExecutable
+		return v
+	}
+
+	if b.Hash != "" {
+		sum, err := fileSHA256(stats.Resolve(ep))
+		if err != nil {
+			v.Status, v.Error = verifyError, err.Error()
+			return v
+		}
+		if sum != b.Hash {
+			v.Status = verifyModified
+		}
+	}
+	if b.Hash == "" || v.Status == verifyModified {
+		if v.LocalVersion = detectVersion(b); v.LocalVersion != "" && v.LocalVersion != b.Version {
+			v.Status = verifyMismatch
+		}
+	}
+	if !v.ok() || !remote || b.Pinned {
+		return v
+	}
+
+	p, err := newProvider(b)
+	if err != nil {
+		v.Status, v.Error = verifyError, err.Error()
+		return v
+	}
+	if v.Latest, _, err = p.GetLatestVersion(); err != nil {
+		v.Status, v.Error = verifyError, fmt.Sprintf("error checking the latest version: %v", err)
+		return v
+	}
+	if v.Latest != b.Version {
+		v.Status = verifyOutdated
+	}
+	return v
+}
+
+// verifyDetails describes why the binary doesn't match its entry
+func verifyDetails(v *verifyItem) string {
+	switch v.Status {
+	case verifyModified:
+		return "doesn't match the recorded hash"
+	case verifyMismatch:
+		return "reports version " + v.LocalVersion
+	case verifyOutdated:
+		return v.Latest + " is available"
+	case verifyError:
+		return v.Error
+	}
+	return ""
+}
+
+// printVerified prints the status of every binary
+func printVerified(w io.Writer, items []*verifyItem) {
+	header := []tableColumn{{header: "Path", truncate: true}, {header: "Version"}, {header: "Status"}, {header: "Details", truncate: true}}
+	rows := make([][]tableCell, 0, len(items))
+	for _, v := range items {
+		status := tableCell{text: v.Status, color: color.New(color.FgGreen).Sprint}
+		if !v.ok() {
+			status.color = color.New(color.FgRed).Sprint
+		}
+		rows = append(rows, []tableCell{{text: v.Path}, {text: v.Version}, status, {text: verifyDetails(v)}})
+	}
+	printTable(w, header, rows, terminalWidth(), color.New(color.FgMagenta, color.Italic).Sprint)
+}
+
+// verifyBins checks the binaries concurrently and returns their
+// status sorted by path, with an error if any of them diverges
+func verifyBins(bins []*config.Binary, concurrency int, remote bool) ([]*verifyItem, error) {
+	items := make([]*verifyItem, len(bins))
+	forEach(len(bins), concurrency, false, func(i int) error {
+		items[i] = verifyBinary(bins[i], remote)
+		return nil
+	})
+	sort.Slice(items, func(i, j int) bool { return items[i].Path < items[j].Path })
+
+	n := 0
+	for _, v := range items {
+		if !v.ok() {
+			n++
+		}
+	}
+	switch n {
+	case 0:
+		return items, nil
+	case 1:
+		return items, fmt.Errorf("1 binary doesn't match the configuration")
+	}
+	return items, fmt.Errorf("%d binaries don't match the configuration", n)
+}
+
+func newVerifyOutput(items []*verifyItem) verifyOutput {
+	return verifyOutput{SchemaVersion: schema.Version, Bins: items}
+}
@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestEnsureCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the binaries are shell scripts")
+	}
+	dir, binDir := newTestConfig(t, "")
+
+	// the configuration is shared by the tests, the
+	// binaries of this one are passed explicitly
+	paths := []string{}
+	for _, n := range []string{"ok", "missing", "noexec", "modified", "mismatch"} {
+		src := filepath.Join(dir, n+"-1.0.0")
+		writeScript(t, src, n+" 1.0.0")
+		paths = append(paths, filepath.Join(binDir, n))
+		Execute("test", func(code int) { t.Fatalf("install exited with %d", code) }, []string{"install", src, paths[len(paths)-1]})
+	}
+	check := append([]string{"ensure", "--check"}, paths...)
+	code := 0
+	Execute("test", func(c int) { code = c }, check)
+	if code != 0 {
+		t.Fatalf("expected the check to pass after the install, exited with %d", code)
+	}
+
+	if err := os.Remove(filepath.Join(binDir, "missing")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(filepath.Join(binDir, "noexec"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(binDir, "modified"), []byte("#!/bin/sh\n# patched\necho modified 1.0.0\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	writeScript(t, filepath.Join(binDir, "mismatch"), "mismatch 1.1.0")
+
+	bins := []*config.Binary{}
+	for _, p := range paths {
+		bins = append(bins, config.Get().Bins[p])
+	}
+	items, err := verifyBins(bins, 2, false)
+	if err == nil {
+		t.Fatal("expected an error for the diverging binaries")
+	}
+	expected := map[string]string{"ok": verifyOK, "missing": verifyMissing, "noexec": verifyNotExecutable, "modified": verifyModified, "mismatch": verifyMismatch}
+	for _, v := range items {
+		if s := expected[filepath.Base(v.Path)]; v.Status != s {
+			t.Errorf("expected %s to be %s, got %s", v.Path, s, v.Status)
+		}
+	}
+	validateOutput(t, "ensure-check", newVerifyOutput(items))
+
+	// nothing is fixed or written by a check
+	conf := filepath.Join(dir, "config.json")
+	before, err := os.ReadFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Execute("test", func(c int) { code = c }, check)
+	if code == 0 {
+		t.Fatal("expected the check to fail")
+	}
+	if _, err := os.Stat(filepath.Join(binDir, "missing")); !os.IsNotExist(err) {
+		t.Error("expected the missing binary not to be installed by the check")
+	}
+	if after, _ := os.ReadFile(conf); string(after) != string(before) {
+		t.Error("expected the configuration to be left untouched")
+	}
+
+	Execute("test", func(c int) { code = c }, []string{"ensure", "--remote"})
+	if code == 0 {
+		t.Fatal("expected --remote to require --check")
+	}
+}
@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/marcosnils/bin/pkg/browser"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/marcosnils/bin/pkg/providers"
+	"github.com/spf13/cobra"
+)
+
+type versionsCmd struct {
+	cmd  *cobra.Command
+	opts versionsOpts
+}
+
+type versionsOpts struct {
+	limit int
+	open  bool
+}
+
+func newVersionsCmd() *versionsCmd {
+	root := &versionsCmd{}
+	// nolint: dupl
+	cmd := &cobra.Command{
+		Use:           "versions <name | url>",
+		Aliases:       []string{"v"},
+		Short:         "Lists the available versions of a binary",
+		SilenceUsage:  true,
+		Args:          cobra.ExactArgs(1),
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			b, err := versionsBinary(args[0])
+			if err != nil {
+				return err
+			}
+
+			p, err := newProvider(b)
+			if err != nil {
+				return err
+			}
+			log.Debugf("Using provider '%s' for '%s'", p.GetID(), b.URL)
+			if !p.Capabilities().Has(providers.CapListVersions) {
+				return fmt.Errorf("listing versions is not supported by the %s provider for this entry", p.GetID())
+			}
+
+			releases, err := p.ListVersions(root.opts.limit)
+			if err != nil {
+				return err
+			}
+
+			printVersions(releases, b.Version)
+			if root.opts.open {
+				u, err := releasePage(releases, b.Version)
+				if err != nil {
+					return err
+				}
+				browser.Open(u, os.Stdout)
+			}
+			if ms, ok := p.(providers.MultiSourcer); ok {
+				printSources(ms.LatestBySource(), b.Source)
+			}
+			return nil
+		},
+	}
+
+	root.cmd = cmd
+	root.cmd.Flags().IntVarP(&root.opts.limit, "limit", "n", 20, "Maximum number of versions to list, 0 lists all of them")
+	root.cmd.Flags().BoolVar(&root.opts.open, "open", false, "Open the release page of the installed version, or the latest one, in the browser")
+	return root
+}
+
+// versionsBinary returns the managed binary with the given name,
+// otherwise the argument is used as the URL of the binary
+func versionsBinary(a string) (*config.Binary, error) {
+	if bin, err := getBinPath(a); err == nil {
+		return config.Get().Bins[bin], nil
+	}
+
+	u, err := providers.ExpandShorthand(a, config.Get().DefaultForge)
+	if err != nil {
+		return nil, err
+	}
+	return &config.Binary{URL: u}, nil
+}
+
+// releasePage returns the web page of the installed
+// release, the latest one listed otherwise
+func releasePage(releases []*providers.Release, installed string) (string, error) {
+	var page string
+	for i, r := range releases {
+		if (i == 0 || r.Version == installed) && strings.HasPrefix(r.URL, "http") {
+			page = r.URL
+		}
+	}
+	if page == "" {
+		return "", errors.New("the provider doesn't have release pages")
+	}
+	return page, nil
+}
+
+func printVersions(releases []*providers.Release, installed string) {
+	vL, dL := len("Version"), len("Published")
+	for _, r := range releases {
+		if len(r.Version) > vL {
+			vL = len(r.Version)
+		}
+	}
+
+	magentaItalic := color.New(color.FgMagenta, color.Italic).Sprint
+	fmt.Printf("%s  %s  %s", magentaItalic(_rPad("Version", vL)), magentaItalic(_rPad("Published", dL)), magentaItalic("Status"))
+
+	for _, r := range releases {
+		published := "-"
+		if !r.PublishedAt.IsZero() {
+			published = r.PublishedAt.Format("2006-01-02")
+		}
+
+		status := ""
+		if r.Prerelease {
+			status = color.YellowString("pre-release")
+		}
+		if r.Version == installed {
+			status = color.GreenString("installed")
+		}
+
+		fmt.Printf("\n%s  %s  %s", _rPad(r.Version, vL), _rPad(published, dL), status)
+	}
+	fmt.Print("\n")
+}
+
+// printSources shows the latest version of each
+// source of a binary published in several places
+func printSources(latest []*providers.SourceLatest, used string) {
+	fmt.Printf("\n%s\n", color.New(color.FgMagenta, color.Italic).Sprint("Sources"))
+	for _, l := range latest {
+		v := l.Version
+		if l.Err != nil {
+			v = color.RedString("error: %v", l.Err)
+		}
+		if l.URL == used {
+			v += color.GreenString("  (installed from)")
+		}
+		fmt.Printf("%s  %s\n", l.URL, v)
+	}
+}
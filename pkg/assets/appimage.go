@@ -0,0 +1,18 @@
+package assets
+
+import "bytes"
+
+// FormatAppImage is the format of the AppImages, they're
+// executables already and installed as is
+const FormatAppImage = "appimage"
+
+// appImagePenalty is taken from the score of the AppImages on
+// platforms other than Linux so native assets always win
+const appImagePenalty = -15
+
+// isAppImage checks the magic bytes of AppImages, they're ELF
+// executables with `AI` and the AppImage type in their padding
+func isAppImage(head []byte) bool {
+	return len(head) >= 11 && bytes.HasPrefix(head, []byte("\x7fELF")) &&
+		head[8] == 'A' && head[9] == 'I' && (head[10] == 1 || head[10] == 2)
+}
@@ -0,0 +1,67 @@
+package assets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestProcessReaderAppImage(t *testing.T) {
+	b, err := os.ReadFile("testdata/hello-x86_64.AppImage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isAppImage(b) {
+		t.Fatal("expected the fixture to be detected as an AppImage")
+	}
+	elf := append([]byte{}, b...)
+	elf[8], elf[9], elf[10] = 0, 0, 0
+	if isAppImage(elf) {
+		t.Fatal("expected a plain ELF not to be detected as an AppImage")
+	}
+
+	out, err := NewFilter(&FilterOpts{}).ProcessReader("hello-x86_64.AppImage", bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := io.ReadAll(out.Source)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, b) {
+		t.Fatal("expected the AppImage to be installed as is")
+	}
+}
+
+func TestScoreAppImage(t *testing.T) {
+	as := []*Asset{
+		{Name: "tool-x86_64.AppImage"},
+		{Name: "tool-darwin-amd64.tar.gz"},
+		{Name: "tool-linux-amd64.tar.gz"},
+	}
+
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+	gf, err := NewFilter(&FilterOpts{}).FilterAssets("tool", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gf.Name != "tool-x86_64.AppImage" {
+		t.Fatalf("expected the AppImage to be picked on Linux, got %s", gf.Name)
+	}
+
+	resolver = &mockOSResolver{OS: []string{"darwin", "macos"}, Arch: []string{"amd64", "x86_64"}}
+	gf, err = NewFilter(&FilterOpts{}).FilterAssets("tool", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gf.Name != "tool-darwin-amd64.tar.gz" {
+		t.Fatalf("expected the native build to win over the AppImage, got %s", gf.Name)
+	}
+	for _, s := range NewFilter(&FilterOpts{}).Score("tool", as) {
+		if s.Name == "tool-x86_64.AppImage" && (!s.hasRule("appimage") || s.Score != 1) {
+			t.Fatalf("expected the AppImage to be penalized, got %d (%v)", s.Score, s.Rules)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package assets
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// armVariantScore is the score of the assets built for the exact ARM
+// version of the host, older versions get a point less per version
+const armVariantScore = 3
+
+var (
+	arm64Re = regexp.MustCompile(`arm64|aarch64|armv8`)
+	// armVersionRe matches the versioned ARM assets, i.e. armv7l, armv6 or arm-7
+	armVersionRe = regexp.MustCompile(`arm[v_-]?([5-7])(?:[^0-9]|$)`)
+)
+
+// armVersion returns the ARM version the asset was built for, 7
+// for hard-float builds (armhf) and 5 for soft-float ones (armel).
+// It's 0 when the asset doesn't tell.
+func armVersion(name string) int {
+	switch {
+	case strings.Contains(name, "armhf"):
+		return 7
+	case strings.Contains(name, "armel"):
+		return 5
+	}
+	if m := armVersionRe.FindStringSubmatch(name); m != nil {
+		v, _ := strconv.Atoi(m[1])
+		return v
+	}
+	return 0
+}
+
+// armScore ranks the ARM variants of an asset for 32-bit ARM hosts
+// of the given version: the exact version comes first, then the older
+// ones, then the assets which don't tell. The 64-bit builds and the
+// ones needing a newer version than the host's are excluded.
+func armScore(name string, host int) (int, string) {
+	name = strings.ToLower(name)
+	if arm64Re.MatchString(name) {
+		return 0, "64-bit ARM build"
+	}
+	v := armVersion(name)
+	switch {
+	case v == 0:
+		return 0, ""
+	case v > host:
+		return 0, "needs armv" + strconv.Itoa(v)
+	}
+	return max(0, armVariantScore-(host-v)), ""
+}
@@ -0,0 +1,108 @@
+package assets
+
+import (
+	"testing"
+)
+
+var (
+	testLinuxARMv7Resolver = &mockOSResolver{OS: []string{"linux"}, Arch: []string{"arm"}, ARM: 7}
+	testLinuxARMv6Resolver = &mockOSResolver{OS: []string{"linux"}, Arch: []string{"arm"}, ARM: 6}
+	testLinuxARM64Resolver = &mockOSResolver{OS: []string{"linux"}, Arch: []string{"arm64", "aarch64"}}
+)
+
+func TestFilterAssetsARM(t *testing.T) {
+	restic := []*Asset{
+		{Name: "restic_0.16.4_linux_386.bz2"},
+		{Name: "restic_0.16.4_linux_amd64.bz2"},
+		{Name: "restic_0.16.4_linux_arm.bz2"},
+		{Name: "restic_0.16.4_linux_arm64.bz2"},
+		{Name: "restic_0.16.4_linux_mips.bz2"},
+		{Name: "SHA256SUMS"},
+		{Name: "SHA256SUMS.asc"},
+	}
+	gotify := []*Asset{
+		{Name: "gotify-linux-386.zip"},
+		{Name: "gotify-linux-amd64.zip"},
+		{Name: "gotify-linux-arm-7.zip"},
+		{Name: "gotify-linux-arm64.zip"},
+		{Name: "gotify-windows-amd64.exe.zip"},
+	}
+	variants := []*Asset{
+		{Name: "tool_linux_aarch64.tar.gz"},
+		{Name: "tool_linux_armel.tar.gz"},
+		{Name: "tool_linux_armv6.tar.gz"},
+		{Name: "tool_linux_armhf.tar.gz"},
+	}
+	oldVariants := []*Asset{
+		{Name: "tool_linux_arm64.tar.gz"},
+		{Name: "tool_linux_armv5.tar.gz"},
+		{Name: "tool_linux_armv6l.tar.gz"},
+	}
+
+	cases := []struct {
+		resolver platformResolver
+		repo     string
+		in       []*Asset
+		out      string
+	}{
+		{testLinuxARMv7Resolver, "restic", restic, "restic_0.16.4_linux_arm.bz2"},
+		{testLinuxARMv6Resolver, "restic", restic, "restic_0.16.4_linux_arm.bz2"},
+		{testLinuxARM64Resolver, "restic", restic, "restic_0.16.4_linux_arm64.bz2"},
+		{testLinuxARMv7Resolver, "gotify", gotify, "gotify-linux-arm-7.zip"},
+		{testLinuxARM64Resolver, "gotify", gotify, "gotify-linux-arm64.zip"},
+		{testLinuxARMv7Resolver, "tool", variants, "tool_linux_armhf.tar.gz"},
+		{testLinuxARMv6Resolver, "tool", variants, "tool_linux_armv6.tar.gz"},
+		{testLinuxARM64Resolver, "tool", variants, "tool_linux_aarch64.tar.gz"},
+		{testLinuxARMv7Resolver, "tool", oldVariants, "tool_linux_armv6l.tar.gz"},
+	}
+
+	defer func() { resolver = runtimeResolver{} }()
+	for _, c := range cases {
+		resolver = c.resolver
+		gf, err := NewFilter(&FilterOpts{}).FilterAssets(c.repo, c.in)
+		if err != nil {
+			t.Errorf("%s (arm %d): %v", c.repo, c.resolver.GetARMVersion(), err)
+			continue
+		}
+		if gf.Name != c.out {
+			t.Errorf("%s (arm %d): expected %s, got %s", c.repo, c.resolver.GetARMVersion(), c.out, gf.Name)
+		}
+	}
+}
+
+func TestFilterAssetsARMNoCompatible(t *testing.T) {
+	resolver = testLinuxARMv6Resolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	as := []*Asset{
+		{Name: "tool_linux_arm64.tar.gz"},
+		{Name: "tool_linux_armv7.tar.gz"},
+	}
+	if gf, err := NewFilter(&FilterOpts{}).FilterAssets("tool", as); err == nil {
+		t.Errorf("Expected no compatible asset on armv6, got %s", gf.Name)
+	}
+}
+
+func TestArmScore(t *testing.T) {
+	cases := []struct {
+		name     string
+		host     int
+		score    int
+		excluded bool
+	}{
+		{"tool_linux_armv7.tar.gz", 7, 3, false},
+		{"tool_linux_armv6.tar.gz", 7, 2, false},
+		{"tool_linux_armel.tar.gz", 7, 1, false},
+		{"tool_linux_arm.tar.gz", 7, 0, false},
+		{"tool_linux_arm64.tar.gz", 7, 0, true},
+		{"tool-aarch64-linux.tar.gz", 7, 0, true},
+		{"tool_linux_armhf.tar.gz", 6, 0, true},
+		{"tool_linux_arm_7.tar.gz", 6, 0, true},
+	}
+	for _, c := range cases {
+		score, excluded := armScore(c.name, c.host)
+		if score != c.score || (excluded != "") != c.excluded {
+			t.Errorf("armScore(%s, %d): expected %d (excluded %v), got %d (excluded %q)", c.name, c.host, c.score, c.excluded, score, excluded)
+		}
+	}
+}
@@ -5,22 +5,24 @@ import (
 	"bytes"
 	"compress/bzip2"
 	"compress/gzip"
+	"crypto/sha256"
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"path/filepath"
 	"sort"
 	"strings"
 
-	"github.com/caarlos0/log"
-	"github.com/cheggaaa/pb"
+	"github.com/ProtonMail/go-crypto/openpgp"
 	"github.com/h2non/filetype"
 	"github.com/h2non/filetype/matchers"
 	"github.com/h2non/filetype/types"
+	"github.com/klauspost/compress/zstd"
 	"github.com/krolaw/zipstream"
 	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/marcosnils/bin/pkg/options"
-	bstrings "github.com/marcosnils/bin/pkg/strings"
 	"github.com/xi2/xz"
 )
 
@@ -36,6 +38,10 @@ type Asset struct {
 	// outputs for bin
 	DisplayName string
 	URL         string
+	// NameFromURL is set when the name is guessed from the URL
+	// instead of given by the provider, the name served by the
+	// server is used then, see canonicalName
+	NameFromURL bool
 }
 
 func (g Asset) String() string {
@@ -50,27 +56,82 @@ type FilteredAsset struct {
 	Name         string
 	DisplayName  string
 	URL          string
+	NameFromURL  bool
 	score        int
+	emulated     bool
 	ExtraHeaders map[string]string
+	// AttestedDigest is the sha256 digest of the asset attested by
+	// an immutable release, it takes precedence over checksum files
+	AttestedDigest string
+	// FallbackURL is downloaded when URL and the mirrors fail, i.e.
+	// the browser URL of an asset the API doesn't serve
+	FallbackURL string
 }
 
 type finalFile struct {
 	Source      io.Reader
 	Name        string
 	PackagePath string
+	// Asset, AssetSize and AssetSHA256 are the name, size and
+	// sha256 digest of the downloaded asset the file comes from,
+	// AssetURL the URL it was downloaded from
+	Asset       string
+	AssetSize   int64
+	AssetSHA256 string
+	AssetURL    string
 }
 
 type platformResolver interface {
 	GetOS() []string
 	GetArch() []string
 	GetOSSpecificExtensions() []string
+	// GetLibc returns musl or gnu, empty if
+	// both kinds of builds can be used
+	GetLibc() string
+	// GetARMVersion returns the version of
+	// 32-bit ARM hosts, 0 on other ones
+	GetARMVersion() int
+	// GetFallbackArch returns the architecture the host
+	// emulates and whether its assets can be picked
+	GetFallbackArch() ([]string, bool)
 }
 
+// Filter picks the asset of a release and extracts the binary from it.
+// It holds the state of a single fetch so it's not safe for concurrent
+// use, each fetch needs its own. Filters can run concurrently though,
+// their FilterOpts can be shared as long as they aren't modified.
 type Filter struct {
 	opts        *FilterOpts
 	repoName    string
 	name        string
 	packagePath string
+	// picked is set when the user picked the asset
+	picked bool
+	// sideFiles are the checksums, signatures, ... dropped from the candidates
+	sideFiles []*Asset
+	// verified is the algorithm of the checksum which verified the asset
+	verified string
+	// signedBy is the id of the key which signed the asset or the
+	// checksum file which verified it, signedFile the signed file
+	signedBy, signedFile string
+	// keys are the signing keys, loaded on first use
+	keys openpgp.EntityList
+	// emulated is the architecture of the picked asset
+	// when it runs through emulation
+	emulated string
+	// others are the other entries of the archive picked
+	// along the installed one
+	others []string
+	// extras are the completions and man pages of the binary
+	extras []*Extra
+	// downloaded is the number of bytes downloaded, cached
+	// downloads aren't accounted
+	downloadedBytes int64
+	// entry is the metadata of the extracted archive entry
+	entry *EntryMeta
+	// assetURL is the URL the asset was downloaded from,
+	// the one of a mirror when the asset URL failed
+	assetURL string
 }
 
 type FilterOpts struct {
@@ -85,6 +146,78 @@ type FilterOpts struct {
 	// variable to filter the resulting outputs. This is very useful
 	// so we don't prompt the user to pick the file again on updates
 	PackagePath string
+
+	// HTTPClient is used to download the assets, defaults
+	// to http.DefaultClient
+	HTTPClient *http.Client
+
+	// ReleaseURL is the page of the release the assets come from,
+	// it can be opened while the user picks one of them
+	ReleaseURL string
+
+	// Cache shares the downloaded assets between the
+	// binaries processed in the same run
+	Cache *DownloadCache
+
+	// Selected is the pattern of the asset previously picked by the
+	// user, the candidates are restricted to the ones matching it so
+	// the user isn't asked again
+	Selected string
+
+	// RequireChecksum fails when the asset can't be verified
+	// with a checksum published along it
+	RequireChecksum bool
+
+	// SideFiles keeps the checksums, signatures, SBOMs and source
+	// archives among the candidates
+	SideFiles bool
+
+	// Files relaxes the scoring for the data files and fonts, the
+	// assets don't need to match the platform nor to be executables.
+	// The platform still breaks ties when it's in some of the names.
+	Files bool
+
+	// Formats is the preference order of the asset formats used to
+	// break ties, DefaultFormats if empty. The deb and rpm packages
+	// come last, their executables are extracted on Linux hosts only.
+	Formats []string
+
+	// Entries picks the files of the archive with these names (i.e.
+	// etcdctl) and SelectMultiple lets the user pick several of them,
+	// for the archives bundling several tools
+	Entries        []string
+	SelectMultiple bool
+
+	// Completions and Manpages collect the shell completions and
+	// the man pages shipped in the archive along the binary
+	Completions bool
+	Manpages    bool
+
+	// Mirrors are the URLs of mirrors of the asset, with {asset}
+	// replaced by its name. They're tried in order after the asset
+	// URL fails, or before it with MirrorFirst. The checksums are
+	// still the ones published along the asset.
+	Mirrors     []string
+	MirrorFirst bool
+
+	// SigningKeys are the OpenPGP public key files, or directories of
+	// them, which verify the signatures of the checksum files and of
+	// the assets. RequireSignature fails when neither is signed.
+	SigningKeys      []string
+	RequireSignature bool
+
+	// Relax loosens the requirements of the selection, it's
+	// used to retry a selection which found no asset
+	Relax Relaxation
+
+	// Rewrites rename the candidates before they're scored, they
+	// must already be restricted to the version being fetched
+	Rewrites []*config.AssetRewrite
+
+	// RequirePlatform excludes the assets which don't name the OS,
+	// even when there's a single one, instead of offering them, i.e.
+	// when the binary can be built from source otherwise
+	RequirePlatform bool
 }
 
 type runtimeResolver struct{}
@@ -101,6 +234,18 @@ func (runtimeResolver) GetOSSpecificExtensions() []string {
 	return config.GetOSSpecificExtensions()
 }
 
+func (runtimeResolver) GetLibc() string {
+	return config.GetLibc()
+}
+
+func (runtimeResolver) GetARMVersion() int {
+	return config.GetARMVersion()
+}
+
+func (runtimeResolver) GetFallbackArch() ([]string, bool) {
+	return config.GetFallbackArch()
+}
+
 var resolver platformResolver = runtimeResolver{}
 
 func (g FilteredAsset) String() string {
@@ -114,56 +259,66 @@ func NewFilter(opts *FilterOpts) *Filter {
 	return &Filter{opts: opts}
 }
 
+// libcScore favors the musl builds on musl hosts and slightly
+// disfavors them on glibc ones. Static builds run everywhere
+// so they're left alone.
+func libcScore(name, libc string) int {
+	name = strings.ToLower(name)
+	if libc == "" || strings.Contains(name, "static") || !strings.Contains(name, "musl") {
+		return 0
+	}
+	if libc == config.LibcMusl {
+		return 3
+	}
+	return -1
+}
+
+// Downloaded returns the number of bytes downloaded for the
+// asset, including its checksums
+func (f *Filter) Downloaded() int64 {
+	return f.downloadedBytes
+}
+
+// Emulated returns the architecture of the picked asset when
+// the host runs it through emulation, empty for native ones
+func (f *Filter) Emulated() string {
+	return f.emulated
+}
+
 // FilterAssets receives a slice of GL assets and tries to
 // select the proper one and ask the user to manually select one
 // in case it can't determine it
 func (f *Filter) FilterAssets(repoName string, as []*Asset) (*FilteredAsset, error) {
+	as = f.dropSideFiles(as)
+	as = f.preselect(as)
 	matches := []*FilteredAsset{}
-	if len(as) == 1 {
+	if len(as) == 1 && !f.opts.RequirePlatform {
 		a := as[0]
-		matches = append(matches, &FilteredAsset{RepoName: repoName, Name: a.Name, URL: a.URL, score: 0})
+		matches = append(matches, &FilteredAsset{RepoName: repoName, Name: a.Name, URL: a.URL, NameFromURL: a.NameFromURL, score: 0})
 	} else {
 		if !f.opts.SkipScoring {
-			scores := map[string]int{}
-			scoreKeys := []string{}
-			scores[repoName] = 1
-			for _, os := range resolver.GetOS() {
-				scores[os] = 10
-			}
-			for _, arch := range resolver.GetArch() {
-				scores[arch] = 5
-			}
-			for _, osSpecificExtension := range resolver.GetOSSpecificExtensions() {
-				scores[osSpecificExtension] = 15
-			}
-
-			for key := range scores {
-				scoreKeys = append(scoreKeys, strings.ToLower(key))
-			}
-
-			for _, a := range as {
-				highestScoreForAsset := 0
-				gf := &FilteredAsset{RepoName: repoName, Name: a.Name, DisplayName: a.DisplayName, URL: a.URL, score: 0}
-				candidate := a.Name
-				candidateScore := 0
-				if bstrings.ContainsAny(strings.ToLower(candidate), scoreKeys) &&
-					isSupportedExt(candidate) {
-					for toMatch, score := range scores {
-						if strings.Contains(strings.ToLower(candidate), strings.ToLower(toMatch)) {
-							log.Debugf("Candidate %s contains %s. Adding score %d", candidate, toMatch, score)
-							candidateScore += score
-						}
-					}
-					if candidateScore > highestScoreForAsset {
-						highestScoreForAsset = candidateScore
-						gf.Name = candidate
-						gf.score = candidateScore
-					}
+			rewritten, originals := f.rewrite(as)
+			scored := f.Score(repoName, rewritten)
+			f.reportScores(repoName, scored)
+			native, plain := false, false
+			for _, s := range scored {
+				if s.Excluded != "" || (s.Score == 0 && !f.opts.Files && !(f.opts.Relax >= RelaxRaw && rawBinary(s.Name))) {
+					continue
 				}
-
-				if highestScoreForAsset > 0 {
-					matches = append(matches, gf)
+				log.Debugf("Candidate %s scored %d", s.Name, s.Score)
+				name := s.Name
+				if o, ok := originals[s.Asset]; ok {
+					name = o
 				}
+				matches = append(matches, &FilteredAsset{RepoName: repoName, Name: name, DisplayName: s.DisplayName, URL: s.URL, NameFromURL: s.NameFromURL, score: s.Score, emulated: s.Emulated})
+				native = native || (!s.Emulated && s.hasRule("os"))
+				plain = plain || (!isMacOSInstaller(s.Format) && s.hasRule("os"))
+			}
+			if native {
+				matches = dropEmulated(matches)
+			}
+			if plain {
+				matches = dropInstallers(matches)
 			}
 			highestAssetScore := 0
 			for i := range matches {
@@ -179,18 +334,19 @@ func (f *Filter) FilterAssets(repoName string, as []*Asset) (*FilteredAsset, err
 					log.Debugf("Keeping %v (URL %v) with highest score %v", matches[i].Name, matches[i].URL, matches[i].score)
 				}
 			}
+			matches = f.preferFormat(matches)
 
 		} else {
 			log.Debugf("--all flag was supplied, skipping scoring")
 			for _, a := range as {
-				matches = append(matches, &FilteredAsset{RepoName: repoName, Name: a.Name, DisplayName: a.DisplayName, URL: a.URL, score: 0})
+				matches = append(matches, &FilteredAsset{RepoName: repoName, Name: a.Name, DisplayName: a.DisplayName, URL: a.URL, NameFromURL: a.NameFromURL, score: 0})
 			}
 		}
 	}
 
 	var gf *FilteredAsset
 	if len(matches) == 0 {
-		return nil, fmt.Errorf("Could not find any compatible files")
+		return nil, NoMatch("Could not find any compatible files")
 	} else if len(matches) > 1 {
 		generic := make([]fmt.Stringer, 0)
 		for _, f := range matches {
@@ -201,16 +357,22 @@ func (f *Filter) FilterAssets(repoName string, as []*Asset) (*FilteredAsset, err
 			return generic[i].String() < generic[j].String()
 		})
 
-		choice, err := options.Select("Multiple matches found, please select one:", generic)
+		choice, err := options.SelectOpen("Multiple matches found, please select one:", generic, f.opts.ReleaseURL)
 		if err != nil {
 			return nil, err
 		}
 		gf = choice.(*FilteredAsset)
+		f.picked = true
 		// TODO make user select the proper file
 	} else {
 		gf = matches[0]
 	}
 
+	if gf.emulated {
+		archs, _ := resolver.GetFallbackArch()
+		f.emulated = archs[0]
+		log.WithField("binary", repoName).WithField("arch", f.emulated).WithField("emulation", config.Emulation()).Info("No native build, using one which runs under emulation")
+	}
 	return gf, nil
 }
 
@@ -253,40 +415,88 @@ func SanitizeName(name, version string) string {
 }
 
 // ProcessURL processes a FilteredAsset by uncompressing/unarchiving the URL of the asset.
+// The name of the asset is replaced by its canonical name once downloaded.
 func (f *Filter) ProcessURL(gf *FilteredAsset) (*finalFile, error) {
-	f.name = gf.Name
-	// We're not closing the body here since the caller is in charge of that
-	req, err := http.NewRequest(http.MethodGet, gf.URL, nil)
+	sum, err := f.lookupChecksum(gf)
 	if err != nil {
 		return nil, err
 	}
-	for name, value := range gf.ExtraHeaders {
-		req.Header.Add(name, value)
-	}
-	log.Debugf("Checking binary from %s", gf.URL)
-	res, err := http.DefaultClient.Do(req)
+	h, err := f.checksumHash(gf, sum)
 	if err != nil {
 		return nil, err
 	}
 
-	if res.StatusCode > 299 || res.StatusCode < 200 {
-		return nil, fmt.Errorf("%d response when checking binary from %s", res.StatusCode, gf.URL)
+	if name, b, ok := f.opts.Cache.get(gf.URL); ok {
+		log.Debugf("Using already downloaded %s", gf.URL)
+		if err := f.verifyChecksum(gf, sum, hashBytes(h, b)); err != nil {
+			return nil, err
+		}
+		if err := f.checkSignature(gf, b); err != nil {
+			return nil, err
+		}
+		gf.Name, f.name = name, name
+		f.assetURL = gf.URL
+		return f.downloaded(gf, b)
 	}
 
+	log.Debugf("Checking binary from %s", gf.URL)
 	// We're caching the whole file into memory so we can prompt
 	// the user which file they want to download
+	b, err := f.downloadMirrored(gf)
+	if err != nil {
+		return nil, err
+	}
+	if err := f.verifyChecksum(gf, sum, hashBytes(h, b)); err != nil {
+		return nil, err
+	}
+	if err := f.checkSignature(gf, b); err != nil {
+		return nil, err
+	}
+	f.opts.Cache.put(gf.URL, gf.Name, b)
+	return f.downloaded(gf, b)
+}
 
-	log.Infof("Starting download of %s", gf.URL)
-	bar := pb.Full.Start64(res.ContentLength)
-	barReader := bar.NewProxyReader(res.Body)
-	defer bar.Finish()
-	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, barReader)
+// downloaded processes the content of the downloaded asset
+func (f *Filter) downloaded(gf *FilteredAsset, b []byte) (*finalFile, error) {
+	out, err := f.processReader(bytes.NewReader(b))
 	if err != nil {
 		return nil, err
 	}
-	bar.Finish()
-	return f.processReader(buf)
+	out.Asset, out.AssetSize = gf.Name, int64(len(b))
+	out.AssetSHA256 = fmt.Sprintf("%x", sha256.Sum256(b))
+	out.AssetURL = f.assetURL
+	return out, nil
+}
+
+// get requests the URL, failing on unsuccessful responses
+func (f *Filter) get(u string, headers map[string]string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range headers {
+		req.Header.Add(name, value)
+	}
+	client := f.opts.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode > 299 || res.StatusCode < 200 {
+		defer res.Body.Close()
+		return nil, newStatusError(res, u)
+	}
+	return res, nil
+}
+
+// ProcessReader extracts the binary from the content of
+// a file which isn't downloaded, i.e. a local one
+func (f *Filter) ProcessReader(name string, r io.Reader) (*finalFile, error) {
+	f.name = name
+	return f.processReader(r)
 }
 
 func (f *Filter) processReader(r io.Reader) (*finalFile, error) {
@@ -300,6 +510,11 @@ func (f *Filter) processReader(r io.Reader) (*finalFile, error) {
 
 	outputFile := io.MultiReader(&buf, r)
 
+	if isAppImage(buf.Bytes()) {
+		log.Debugf("%s is an AppImage, installing it as is", f.name)
+		return &finalFile{Source: outputFile, Name: f.name, PackagePath: f.packagePath}, nil
+	}
+
 	type processorFunc func(repoName string, r io.Reader) (*finalFile, error)
 	var processor processorFunc
 	switch t {
@@ -311,8 +526,25 @@ func (f *Filter) processReader(r io.Reader) (*finalFile, error) {
 		processor = f.processXz
 	case matchers.TypeBz2:
 		processor = f.processBz2
+	case matchers.TypeZstd:
+		processor = f.processZstd
 	case matchers.TypeZip:
 		processor = f.processZip
+	case matchers.Type7z:
+		processor = f.processSevenZip
+	case matchers.TypeDeb, matchers.TypeAr:
+		// deb packages are ar archives, they can be detected as such
+		processor = f.processDeb
+	case matchers.TypeRpm:
+		processor = f.processRpm
+	}
+	// disk images are told by their name, their signature
+	// is at the end and some are detected as bzip2
+	switch {
+	case Format(f.name) == "dmg":
+		processor = f.processDmg
+	case Format(f.name) == "pkg" || bytes.HasPrefix(buf.Bytes(), []byte("xar!")):
+		processor = f.processPkg
 	}
 
 	if processor != nil {
@@ -342,12 +574,16 @@ func (f *Filter) processGz(name string, r io.Reader) (*finalFile, error) {
 		return nil, err
 	}
 
+	if !gr.ModTime.IsZero() {
+		f.entry = &EntryMeta{Path: gr.Name, ModTime: gr.ModTime}
+	}
 	return &finalFile{Source: gr, Name: gr.Name}, nil
 }
 
 func (f *Filter) processTar(name string, r io.Reader) (*finalFile, error) {
 	tr := tar.NewReader(r)
-	tarFiles := map[string][]byte{}
+	tarFiles, extras := map[string][]byte{}, map[string][]byte{}
+	metas := map[string]*EntryMeta{}
 	if len(f.opts.PackagePath) > 0 {
 		log.Debugf("Processing tag with PackagePath %s\n", f.opts.PackagePath)
 	}
@@ -361,7 +597,21 @@ func (f *Filter) processTar(name string, r io.Reader) (*finalFile, error) {
 			continue
 		}
 
-		if !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && header.Name != f.opts.PackagePath {
+		entry, ok := entryName(header.Name)
+		if !ok {
+			continue
+		}
+
+		if header.Typeflag == tar.TypeReg && f.wantsExtra(entry) {
+			bs, err := io.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			extras[entry] = bs
+			continue
+		}
+
+		if !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && entry != f.opts.PackagePath && header.Name != f.opts.PackagePath {
 			continue
 		}
 
@@ -374,26 +624,25 @@ func (f *Filter) processTar(name string, r io.Reader) (*finalFile, error) {
 			if err != nil {
 				return nil, err
 			}
-			tarFiles[header.Name] = bs
+			tarFiles[entry] = bs
+			metas[entry] = tarEntryMeta(entry, header)
 		}
 	}
 	if len(tarFiles) == 0 {
 		return nil, fmt.Errorf("no files found in tar archive, use -p flag to manually select . PackagePath [%s]", f.opts.PackagePath)
 	}
 
-	as := make([]*Asset, 0)
-	for f := range tarFiles {
-		as = append(as, &Asset{Name: f, URL: ""})
-	}
-	choice, err := f.FilterAssets(name, as)
+	choice, err := f.pickEntry(name, entryAssets(tarFiles))
 	if err != nil {
 		return nil, err
 	}
 	selectedFile := choice.String()
+	f.collectExtras(extras, path.Base(selectedFile))
+	f.entry = metas[selectedFile]
 
 	tf := tarFiles[selectedFile]
 
-	return &finalFile{Source: bytes.NewReader(tf), Name: filepath.Base(selectedFile), PackagePath: selectedFile}, nil
+	return &finalFile{Source: bytes.NewReader(tf), Name: path.Base(selectedFile), PackagePath: selectedFile}, nil
 }
 
 func (f *Filter) processBz2(name string, r io.Reader) (*finalFile, error) {
@@ -411,10 +660,22 @@ func (f *Filter) processXz(name string, r io.Reader) (*finalFile, error) {
 	return &finalFile{Source: xr, Name: name}, nil
 }
 
+func (f *Filter) processZstd(name string, r io.Reader) (*finalFile, error) {
+	// nothing closes the sources, a single goroutine decoder
+	// doesn't leave background goroutines behind
+	zr, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+
+	return &finalFile{Source: zr.IOReadCloser(), Name: name}, nil
+}
+
 func (f *Filter) processZip(name string, r io.Reader) (*finalFile, error) {
 	zr := zipstream.NewReader(r)
 
-	zipFiles := map[string][]byte{}
+	zipFiles, extras := map[string][]byte{}, map[string][]byte{}
+	metas := map[string]*EntryMeta{}
 	if len(f.opts.PackagePath) > 0 {
 		log.Debugf("Processing tag with PackagePath %s\n", f.opts.PackagePath)
 	}
@@ -428,7 +689,21 @@ func (f *Filter) processZip(name string, r io.Reader) (*finalFile, error) {
 			continue
 		}
 
-		if !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && header.Name != f.opts.PackagePath {
+		entry, ok := entryName(header.Name)
+		if !ok {
+			continue
+		}
+
+		if f.wantsExtra(entry) {
+			bs, err := io.ReadAll(zr)
+			if err != nil {
+				return nil, err
+			}
+			extras[entry] = bs
+			continue
+		}
+
+		if !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && entry != f.opts.PackagePath && header.Name != f.opts.PackagePath {
 			continue
 		}
 
@@ -441,27 +716,78 @@ func (f *Filter) processZip(name string, r io.Reader) (*finalFile, error) {
 			return nil, err
 		}
 
-		zipFiles[header.Name] = bs
+		zipFiles[entry] = bs
+		metas[entry] = zipEntryMeta(entry, header)
 	}
 	if len(zipFiles) == 0 {
 		return nil, fmt.Errorf("No files found in zip archive. PackagePath [%s]", f.opts.PackagePath)
 	}
 
-	as := make([]*Asset, 0)
-	for f := range zipFiles {
-		as = append(as, &Asset{Name: f, URL: ""})
-	}
-	choice, err := f.FilterAssets(name, as)
+	choice, err := f.pickEntry(name, entryAssets(zipFiles))
 	if err != nil {
 		return nil, err
 	}
 	selectedFile := choice.String()
+	f.collectExtras(extras, path.Base(selectedFile))
+	f.entry = metas[selectedFile]
 
 	fr := bytes.NewReader(zipFiles[selectedFile])
 
 	// return base of selected file since tar
 	// files usually have folders inside
-	return &finalFile{Name: filepath.Base(selectedFile), Source: fr, PackagePath: selectedFile}, nil
+	return &finalFile{Name: path.Base(selectedFile), Source: fr, PackagePath: selectedFile}, nil
+}
+
+// preselect restricts the assets to the ones matching the previous
+// choice of the user, if any of them still does
+func (f *Filter) preselect(as []*Asset) []*Asset {
+	if f.opts.Selected == "" || f.opts.SkipScoring {
+		return as
+	}
+	s, err := NewSelector(f.opts.Selected)
+	if err != nil {
+		log.Debugf("Ignoring the previously selected asset: %v", err)
+		return as
+	}
+	matches := []*Asset{}
+	for _, a := range as {
+		if s.Match(a.Name) {
+			matches = append(matches, a)
+		}
+	}
+	if len(matches) == 0 {
+		log.Debugf("No asset matches the previously selected %s anymore", f.opts.Selected)
+		return as
+	}
+	return matches
+}
+
+// Picked reports whether the user picked the asset interactively
+func (f *Filter) Picked() bool {
+	return f.picked
+}
+
+// preferred checks if the format of the asset is explicitly preferred
+func (f *Filter) preferred(name string) bool {
+	return len(f.opts.Formats) > 0 && formatRank(name, f.opts.Formats) < len(f.opts.Formats)
+}
+
+// preferFormat keeps the assets with the most preferred format
+func (f *Filter) preferFormat(matches []*FilteredAsset) []*FilteredAsset {
+	formats, _ := ResolveFormats(f.opts.Formats)
+	best := len(formats)
+	for _, m := range matches {
+		best = min(best, formatRank(m.Name, formats))
+	}
+	kept := matches[:0]
+	for _, m := range matches {
+		if formatRank(m.Name, formats) == best {
+			kept = append(kept, m)
+		} else {
+			log.Debugf("Removing %v, %s is a less preferred format", m.Name, Format(m.Name))
+		}
+	}
+	return kept
 }
 
 // isSupportedExt checks if this provider supports
@@ -469,10 +795,10 @@ func (f *Filter) processZip(name string, r io.Reader) (*finalFile, error) {
 func isSupportedExt(filename string) bool {
 	if ext := strings.TrimPrefix(filepath.Ext(filename), "."); len(ext) > 0 {
 		switch filetype.GetType(ext) {
-		case msiType, matchers.TypeDeb, matchers.TypeRpm, ascType:
+		case msiType, ascType:
 			log.Debugf("Filename %s doesn't have a supported extension", filename)
 			return false
-		case matchers.TypeGz, types.Unknown, matchers.TypeZip, matchers.TypeXz, matchers.TypeTar, matchers.TypeBz2, matchers.TypeExe:
+		case matchers.TypeGz, types.Unknown, matchers.TypeZip, matchers.Type7z, matchers.TypeXz, matchers.TypeZstd, matchers.TypeTar, matchers.TypeBz2, matchers.TypeExe, matchers.TypeDeb, matchers.TypeRpm:
 			break
 		default:
 			log.Debugf("Filename %s doesn't have a supported extension", filename)
@@ -1,15 +1,24 @@
 package assets
 
 import (
+	"archive/tar"
+	"bytes"
 	"fmt"
+	"io"
 	"strings"
 	"testing"
+
+	"github.com/klauspost/compress/zstd"
 )
 
 type mockOSResolver struct {
 	OS                   []string
 	Arch                 []string
 	OSSpecificExtensions []string
+	Libc                 string
+	ARM                  int
+	FallbackArch         []string
+	NoFallbackArch       bool
 }
 
 func (m *mockOSResolver) GetOS() []string {
@@ -24,9 +33,23 @@ func (m *mockOSResolver) GetOSSpecificExtensions() []string {
 	return m.OSSpecificExtensions
 }
 
+func (m *mockOSResolver) GetLibc() string {
+	return m.Libc
+}
+
+func (m *mockOSResolver) GetARMVersion() int {
+	return m.ARM
+}
+
+func (m *mockOSResolver) GetFallbackArch() ([]string, bool) {
+	return m.FallbackArch, !m.NoFallbackArch
+}
+
 var (
 	testLinuxAMDResolver   = &mockOSResolver{OS: []string{"linux"}, Arch: []string{"amd64", "x86_64", "x64", "64"}, OSSpecificExtensions: []string{"AppImage"}}
-	testWindowsAMDResolver = &mockOSResolver{OS: []string{"windows", "win"}, Arch: []string{"amd64", "x86_64", "x64", "64"}, OSSpecificExtensions: []string{"exe"}}
+	testWindowsAMDResolver = &mockOSResolver{OS: []string{"windows", "win"}, Arch: []string{"amd64", "x86_64", "x64", "64"}, OSSpecificExtensions: []string{"exe", "cmd"}}
+	testLinuxMuslResolver  = &mockOSResolver{OS: []string{"linux"}, Arch: []string{"amd64", "x86_64", "x64", "64"}, OSSpecificExtensions: []string{"AppImage"}, Libc: "musl"}
+	testLinuxGNUResolver   = &mockOSResolver{OS: []string{"linux"}, Arch: []string{"amd64", "x86_64", "x64", "64"}, OSSpecificExtensions: []string{"AppImage"}, Libc: "gnu"}
 )
 
 func TestSanitizeName(t *testing.T) {
@@ -144,6 +167,11 @@ func TestFilterAssets(t *testing.T) {
 			{Name: "usql-0.8.2-linux-amd64.tar.bz2", URL: "https://github.com/xo/usql/releases/download/v0.8.2/usql-0.8.2-linux-amd64.tar.bz2"},
 			{Name: "usql-0.8.2-windows-amd64.zip", URL: "https://github.com/xo/usql/releases/download/v0.8.2/usql-0.8.2-windows-amd64.zip"},
 		}}, "usql-0.8.2-windows-amd64.zip", testWindowsAMDResolver},
+		{args{"tool", []*Asset{
+			{Name: "tool_linux_amd64", URL: "https://example.com/tool_linux_amd64"},
+			{Name: "tool_windows_amd64.zip", URL: "https://example.com/tool_windows_amd64.zip"},
+			{Name: "tool_windows_amd64.cmd", URL: "https://example.com/tool_windows_amd64.cmd"},
+		}}, "tool_windows_amd64.cmd", testWindowsAMDResolver},
 		{args{"cli", []*Asset{
 			{Name: "dapr", URL: ""},
 		}}, "dapr", testLinuxAMDResolver},
@@ -164,6 +192,77 @@ func TestFilterAssets(t *testing.T) {
 
 }
 
+func TestFilterAssetsLibc(t *testing.T) {
+	ripgrep := []*Asset{
+		{Name: "ripgrep-14.1.0-aarch64-unknown-linux-gnu.tar.gz"},
+		{Name: "ripgrep-14.1.0-x86_64-apple-darwin.tar.gz"},
+		{Name: "ripgrep-14.1.0-x86_64-pc-windows-msvc.zip"},
+		{Name: "ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz"},
+	}
+	bottom := []*Asset{
+		{Name: "bottom_aarch64-unknown-linux-gnu.tar.gz"},
+		{Name: "bottom_aarch64-unknown-linux-musl.tar.gz"},
+		{Name: "bottom_x86_64-apple-darwin.tar.gz"},
+		{Name: "bottom_x86_64-pc-windows-msvc.zip"},
+		{Name: "bottom_x86_64-unknown-linux-gnu.tar.gz"},
+		{Name: "bottom_x86_64-unknown-linux-musl.tar.gz"},
+	}
+	static := []*Asset{
+		{Name: "tool_linux_amd64_musl.tar.gz"},
+		{Name: "tool_linux_amd64_musl_static.tar.gz"},
+	}
+
+	cases := []struct {
+		name     string
+		repo     string
+		as       []*Asset
+		resolver platformResolver
+		out      string
+	}{
+		{"ripgrep on musl", "ripgrep", ripgrep, testLinuxMuslResolver, "ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz"},
+		{"ripgrep on glibc only has a musl build", "ripgrep", ripgrep, testLinuxGNUResolver, "ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz"},
+		{"bottom on musl", "bottom", bottom, testLinuxMuslResolver, "bottom_x86_64-unknown-linux-musl.tar.gz"},
+		{"bottom on glibc", "bottom", bottom, testLinuxGNUResolver, "bottom_x86_64-unknown-linux-gnu.tar.gz"},
+		{"static build on glibc", "tool", static, testLinuxGNUResolver, "tool_linux_amd64_musl_static.tar.gz"},
+		{"musl build on musl", "tool", static[:1], testLinuxMuslResolver, "tool_linux_amd64_musl.tar.gz"},
+	}
+
+	f := NewFilter(&FilterOpts{})
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			resolver = c.resolver
+			defer func() { resolver = testLinuxAMDResolver }()
+			got, err := f.FilterAssets(c.repo, c.as)
+			if err != nil {
+				t.Fatalf("Error filtering assets %v", err)
+			}
+			if got.Name != c.out {
+				t.Fatalf("Expected %s, got %s", c.out, got.Name)
+			}
+		})
+	}
+}
+
+func TestLibcScore(t *testing.T) {
+	cases := []struct {
+		name, libc string
+		out        int
+	}{
+		{"tool-x86_64-unknown-linux-musl.tar.gz", "musl", 3},
+		{"tool-x86_64-unknown-linux-musl.tar.gz", "gnu", -1},
+		{"tool-x86_64-unknown-linux-musl.tar.gz", "", 0},
+		{"tool-x86_64-unknown-linux-gnu.tar.gz", "musl", 0},
+		{"tool-x86_64-linux-musl-static.tar.gz", "musl", 0},
+		{"tool-x86_64-linux-musl-static.tar.gz", "gnu", 0},
+	}
+
+	for _, c := range cases {
+		if got := libcScore(c.name, c.libc); got != c.out {
+			t.Errorf("libcScore(%s, %s): expected %d, got %d", c.name, c.libc, c.out, got)
+		}
+	}
+}
+
 func TestIsSupportedExt(t *testing.T) {
 	cases := []struct {
 		in  string
@@ -177,6 +276,10 @@ func TestIsSupportedExt(t *testing.T) {
 			"Ultimaker_Cura-4.7.1-win64.msi",
 			false,
 		},
+		{
+			"tool_linux_amd64.tar.zst",
+			true,
+		},
 	}
 
 	for _, c := range cases {
@@ -187,3 +290,63 @@ func TestIsSupportedExt(t *testing.T) {
 	}
 
 }
+
+// zstdCompress compresses the content, wrapping it in a tar
+// archive holding a single executable file if name isn't empty
+func zstdCompress(t *testing.T, name string, content []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name == "" {
+		_, err = zw.Write(content)
+	} else {
+		tw := tar.NewWriter(zw)
+		if err = tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err == nil {
+			if _, err = tw.Write(content); err == nil {
+				err = tw.Close()
+			}
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestProcessReaderZstd(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	content := []byte("\x7fELF tool")
+	cases := []struct {
+		desc, name string
+		b          []byte
+		out        string
+	}{
+		{"tar.zst", "tool_linux_amd64.tar.zst", zstdCompress(t, "tool_linux_amd64/tool", content), "tool"},
+		// single compressed files are named after the repo, like xz and bz2 ones
+		{"zst", "tool_linux_amd64.zst", zstdCompress(t, "", content), ""},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			f := NewFilter(&FilterOpts{})
+			out, err := f.ProcessReader(c.name, bytes.NewReader(c.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(out.Source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, content) || (c.out != "" && out.Name != c.out) {
+				t.Fatalf("expected %s to be extracted, got %s (%q)", c.out, out.Name, b)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+)
+
+// DownloadCache keeps the downloaded assets so binaries coming from
+// the same release asset (i.e. several tools shipped in one archive)
+// only download it once during batch operations. The assets are
+// spooled to a temporary directory, not kept in memory, so Close must
+// be called once the batch is done. It's safe for concurrent use, the
+// cached content is shared by the fetches and never modified.
+type DownloadCache struct {
+	mu    sync.Mutex
+	dir   string
+	files map[string]cachedFile
+	// spooled numbers the files of dir
+	spooled int
+}
+
+// cachedFile is a downloaded asset along with its canonical name
+type cachedFile struct {
+	name string
+	path string
+	sha  string
+}
+
+// NewDownloadCache returns an empty cache
+func NewDownloadCache() *DownloadCache {
+	return &DownloadCache{files: map[string]cachedFile{}}
+}
+
+// Close removes the cached assets
+func (c *DownloadCache) Close() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files = map[string]cachedFile{}
+	if c.dir == "" {
+		return nil
+	}
+	dir := c.dir
+	c.dir = ""
+	return os.RemoveAll(dir)
+}
+
+// Asset returns the downloaded asset with the given sha256
+// digest along with its name, i.e. to keep it aside
+func (c *DownloadCache) Asset(sha string) (string, []byte, bool) {
+	if c == nil {
+		return "", nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, f := range c.files {
+		if f.sha == sha {
+			return f.read()
+		}
+	}
+	return "", nil, false
+}
+
+func (c *DownloadCache) get(url string) (string, []byte, bool) {
+	if c == nil {
+		return "", nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	f, ok := c.files[url]
+	if !ok {
+		return "", nil, false
+	}
+	return f.read()
+}
+
+// read returns the content of the cached file, it's
+// considered missing when it can't be read anymore
+func (f cachedFile) read() (string, []byte, bool) {
+	b, err := os.ReadFile(f.path)
+	if err != nil {
+		return "", nil, false
+	}
+	return f.name, b, true
+}
+
+// put spools the content of the asset, it's only
+// downloaded again when it can't be written
+func (c *DownloadCache) put(url, name string, b []byte) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.dir == "" {
+		dir, err := os.MkdirTemp("", "bin-downloads-")
+		if err != nil {
+			return
+		}
+		c.dir = dir
+	}
+	c.spooled++
+	p := filepath.Join(c.dir, strconv.Itoa(c.spooled))
+	if err := os.WriteFile(p, b, 0o600); err != nil {
+		return
+	}
+	c.files[url] = cachedFile{name: name, path: p, sha: fmt.Sprintf("%x", sha256.Sum256(b))}
+}
@@ -0,0 +1,276 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
+)
+
+// checksumAlgorithm is a digest algorithm of the checksum files
+type checksumAlgorithm struct {
+	name string
+	// hexLen is the length of the hex encoded digests
+	hexLen int
+	// weak algorithms are still checked, with a warning
+	weak bool
+	new  func() hash.Hash
+}
+
+// checksumAlgorithms are the supported algorithms, the first one
+// of a given digest length is assumed when the file name doesn't
+// tell which algorithm it is (i.e. `checksums.txt`)
+var checksumAlgorithms = []*checksumAlgorithm{
+	{name: "sha256", hexLen: 64, new: sha256.New},
+	{name: "sha512", hexLen: 128, new: sha512.New},
+	{name: "blake3", hexLen: 64, new: func() hash.Hash { return blake3.New(32, nil) }},
+	{name: "blake2b", hexLen: 128, new: func() hash.Hash {
+		h, _ := blake2b.New512(nil)
+		return h
+	}},
+	{name: "sha1", hexLen: 40, weak: true, new: sha1.New},
+}
+
+// checksumNameAlgorithms maps the tokens found in the names
+// of the checksum files to their algorithm
+var checksumNameAlgorithms = []struct{ token, algorithm string }{
+	{"sha512", "sha512"}, {"sha256", "sha256"}, {"sha1", "sha1"},
+	{"blake2b", "blake2b"}, {"b2sum", "blake2b"},
+	{"blake3", "blake3"}, {"b3sum", "blake3"},
+}
+
+var (
+	hexDigestRe = regexp.MustCompile(`^[0-9a-fA-F]+$`)
+	// bsdChecksumRe matches the BSD style lines, i.e. `SHA256 (tool.tar.gz) = abc...`
+	bsdChecksumRe = regexp.MustCompile(`^([A-Za-z0-9-]+) ?\((.+)\) ?= ?([0-9a-fA-F]+)$`)
+)
+
+// checksum is the published digest of an asset
+type checksum struct {
+	// algorithm is nil if it's unknown
+	algorithm *checksumAlgorithm
+	digest    string
+	file      string
+}
+
+func algorithmByName(name string) *checksumAlgorithm {
+	for _, a := range checksumAlgorithms {
+		if a.name == name {
+			return a
+		}
+	}
+	return nil
+}
+
+// algorithmFor guesses the algorithm of a digest, from the name of the
+// checksum file (or the tag of a BSD style line) first, then from its length
+func algorithmFor(file, digest string) *checksumAlgorithm {
+	file = strings.ToLower(file)
+	for _, t := range checksumNameAlgorithms {
+		if strings.Contains(file, t.token) {
+			if a := algorithmByName(t.algorithm); a.hexLen == len(digest) {
+				return a
+			}
+			return nil
+		}
+	}
+	switch {
+	case strings.HasSuffix(file, ".b2"):
+		return algorithmByName("blake2b")
+	case strings.HasSuffix(file, ".b3"):
+		return algorithmByName("blake3")
+	}
+	for _, a := range checksumAlgorithms {
+		if a.hexLen == len(digest) {
+			return a
+		}
+	}
+	return nil
+}
+
+// isChecksumFile checks if the side file holds checksums,
+// as opposed to signatures, SBOMs, ...
+func isChecksumFile(name string) bool {
+	name = strings.ToLower(name)
+	base := strings.TrimSuffix(name, ".txt")
+	for _, n := range checksumListNames {
+		if strings.HasSuffix(base, n) {
+			return true
+		}
+	}
+	for _, s := range []string{".sha256", ".sha256sum", ".sha512", ".sha512sum", ".sha1", ".sha1sum", ".b2", ".b3", ".blake2b", ".blake3"} {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// checksumFiles returns the side files which might hold the checksum
+// of the asset, the ones dedicated to it come first
+func (f *Filter) checksumFiles(name string) []*Asset {
+	dedicated, lists := []*Asset{}, []*Asset{}
+	for _, a := range f.sideFiles {
+		if !isChecksumFile(a.Name) {
+			continue
+		}
+		if strings.HasPrefix(a.Name, name+".") {
+			dedicated = append(dedicated, a)
+		} else {
+			lists = append(lists, a)
+		}
+	}
+	return append(dedicated, lists...)
+}
+
+// parseChecksum looks for the digest of the asset in the content of
+// a checksum file. The files dedicated to the asset might only hold
+// the digest, without any file name.
+func parseChecksum(content []byte, name, file string, dedicated bool) (*checksum, bool) {
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if m := bsdChecksumRe.FindStringSubmatch(line); m != nil {
+			if path.Base(m[2]) == name || dedicated {
+				return &checksum{algorithm: algorithmFor(m[1], m[3]), digest: strings.ToLower(m[3]), file: file}, true
+			}
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if !hexDigestRe.MatchString(fields[0]) {
+			continue
+		}
+		digest := strings.ToLower(fields[0])
+		if len(fields) == 1 {
+			if dedicated {
+				return &checksum{algorithm: algorithmFor(file, digest), digest: digest, file: file}, true
+			}
+			continue
+		}
+		// sha256sum and friends prefix the binary files with `*`
+		listed := path.Base(strings.TrimPrefix(strings.Join(fields[1:], " "), "*"))
+		if listed == name || dedicated {
+			return &checksum{algorithm: algorithmFor(file, digest), digest: digest, file: file}, true
+		}
+	}
+	return nil, false
+}
+
+// lookupChecksum downloads the checksum files published along the
+// asset until one of them has its digest. The digest attested by an
+// immutable release is used instead when there's one.
+func (f *Filter) lookupChecksum(gf *FilteredAsset) (*checksum, error) {
+	if gf.AttestedDigest != "" {
+		return &checksum{algorithm: algorithmByName("sha256"), digest: strings.ToLower(gf.AttestedDigest), file: "the release attestation"}, nil
+	}
+	for _, a := range f.checksumFiles(gf.Name) {
+		content, err := f.sideFile(a, gf.ExtraHeaders)
+		if err != nil {
+			log.WithField("asset", a.Name).WithError(err).Warn("Error downloading")
+			continue
+		}
+		sum, ok := parseChecksum(content, gf.Name, a.Name, strings.HasPrefix(a.Name, gf.Name+"."))
+		if !ok {
+			log.Debugf("%s doesn't have the checksum of %s", a.Name, gf.Name)
+			continue
+		}
+		// the digests of a signed checksum file are
+		// only trusted once its signature is verified
+		id, err := f.verifySignature(a.Name, content, gf.ExtraHeaders)
+		if err != nil {
+			return nil, err
+		}
+		f.signedBy, f.signedFile = id, ""
+		if id != "" {
+			log.WithField("asset", a.Name).WithField("signer", id).Info("Checksum file signature verified")
+			f.signedFile = a.Name
+		}
+		return sum, nil
+	}
+	return nil, nil
+}
+
+// sideFile returns the content of the side file, downloading
+// it unless it was already downloaded in the same run
+func (f *Filter) sideFile(a *Asset, headers map[string]string) ([]byte, error) {
+	if _, content, ok := f.opts.Cache.get(a.URL); ok {
+		return content, nil
+	}
+	res, err := f.get(a.URL, headers)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	b, err := io.ReadAll(res.Body)
+	f.downloadedBytes += int64(len(b))
+	if err != nil {
+		return nil, err
+	}
+	f.opts.Cache.put(a.URL, a.Name, b)
+	return b, nil
+}
+
+// checksumHash returns the hash to compute while downloading the
+// asset, nil if it can't be verified. It fails when a checksum is
+// required and there's none, or it's of an unknown algorithm.
+func (f *Filter) checksumHash(gf *FilteredAsset, sum *checksum) (hash.Hash, error) {
+	switch {
+	case sum == nil && f.opts.RequireChecksum:
+		return nil, fmt.Errorf("no checksum is published for %s and checksums are required", gf.Name)
+	case sum == nil:
+		log.Debugf("No checksum is published for %s", gf.Name)
+		return nil, nil
+	case sum.algorithm == nil && f.opts.RequireChecksum:
+		return nil, fmt.Errorf("can't verify %s, the algorithm of %s is unknown and checksums are required", gf.Name, sum.file)
+	case sum.algorithm == nil:
+		log.WithField("asset", gf.Name).WithField("checksums", sum.file).Warn("Can't verify the asset, the algorithm of its checksum is unknown")
+		return nil, nil
+	}
+	return sum.algorithm.new(), nil
+}
+
+// verifyChecksum compares the computed digest of the asset to
+// the published one and records the algorithm which verified it
+func (f *Filter) verifyChecksum(gf *FilteredAsset, sum *checksum, h hash.Hash) error {
+	if h == nil {
+		return nil
+	}
+	got := hex.EncodeToString(h.Sum(nil))
+	if got != sum.digest {
+		return fmt.Errorf("%s checksum mismatch for %s: %s published %s, got %s", sum.algorithm.name, gf.Name, sum.file, sum.digest, got)
+	}
+	if sum.algorithm.weak {
+		log.WithField("asset", gf.Name).WithField("algorithm", sum.algorithm.name).Warn("Only verified with a weak algorithm")
+	}
+	log.Debugf("%s verified with its %s checksum from %s", gf.Name, sum.algorithm.name, sum.file)
+	f.verified = sum.algorithm.name
+	return nil
+}
+
+// Verified returns the algorithm of the checksum which verified
+// the processed asset, empty if it wasn't verified
+func (f *Filter) Verified() string {
+	return f.verified
+}
+
+// hashBytes hashes content which was already downloaded
+func hashBytes(h hash.Hash, b []byte) hash.Hash {
+	if h != nil {
+		io.Copy(h, bytes.NewReader(b))
+	}
+	return h
+}
@@ -0,0 +1,157 @@
+package assets
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/blake2b"
+	"lukechampine.com/blake3"
+)
+
+func TestAlgorithmFor(t *testing.T) {
+	cases := []struct {
+		file   string
+		digest int
+		out    string
+	}{
+		{"SHA512SUMS", 128, "sha512"},
+		{"tool.tar.gz.sha256", 64, "sha256"},
+		{"B3SUMS", 64, "blake3"},
+		{"B2SUMS", 128, "blake2b"},
+		{"tool.tar.gz.b3", 64, "blake3"},
+		{"tool.sha1", 40, "sha1"},
+		{"BLAKE2b", 128, "blake2b"},
+		{"checksums.txt", 64, "sha256"},
+		{"checksums.txt", 128, "sha512"},
+		{"checksums.txt", 40, "sha1"},
+		{"checksums.txt", 32, ""},
+		{"SHA256SUMS", 128, ""},
+	}
+	for _, c := range cases {
+		a := algorithmFor(c.file, strings.Repeat("a", c.digest))
+		got := ""
+		if a != nil {
+			got = a.name
+		}
+		if got != c.out {
+			t.Errorf("algorithmFor(%s, %d chars): expected %q, got %q", c.file, c.digest, c.out, got)
+		}
+	}
+}
+
+func TestParseChecksum(t *testing.T) {
+	d := strings.Repeat("ab", 32)
+	cases := []struct {
+		content   string
+		file      string
+		dedicated bool
+		digest    string
+		algorithm string
+	}{
+		{fmt.Sprintf("%s  other.tar.gz\n%s  tool.tar.gz\n", strings.Repeat("cd", 32), d), "checksums.txt", false, d, "sha256"},
+		{fmt.Sprintf("%s *tool.tar.gz\n", d), "SHA256SUMS", false, d, "sha256"},
+		{fmt.Sprintf("%s  ./dist/tool.tar.gz\n", d), "B3SUMS", false, d, "blake3"},
+		{fmt.Sprintf("SHA256 (tool.tar.gz) = %s\n", d), "CHECKSUMS", false, d, "sha256"},
+		{fmt.Sprintf("BLAKE2b (tool.tar.gz) = %s\n", strings.Repeat("ab", 64)), "CHECKSUMS", false, strings.Repeat("ab", 64), "blake2b"},
+		{d + "\n", "tool.tar.gz.sha256", true, d, "sha256"},
+		{fmt.Sprintf("%s  tool_linux.tar.gz\n", d), "tool.tar.gz.sha256", true, d, "sha256"},
+		{fmt.Sprintf("%s  other.tar.gz\n", d), "checksums.txt", false, "", ""},
+	}
+	for _, c := range cases {
+		sum, ok := parseChecksum([]byte(c.content), "tool.tar.gz", c.file, c.dedicated)
+		if c.digest == "" {
+			if ok {
+				t.Errorf("%s: expected no checksum, got %s", c.file, sum.digest)
+			}
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: expected checksum %s, got none", c.file, c.digest)
+			continue
+		}
+		if sum.digest != c.digest || sum.algorithm == nil || sum.algorithm.name != c.algorithm {
+			t.Errorf("%s: expected %s checksum %s, got %+v", c.file, c.algorithm, c.digest, sum)
+		}
+	}
+}
+
+func TestProcessURLChecksum(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	content := []byte("#!/bin/sh\necho tool\n")
+	b2 := blake2b.Sum512(content)
+	b3 := blake3.Sum256(content)
+	sums := map[string]string{
+		"sha256":  fmt.Sprintf("%x", sha256.Sum256(content)),
+		"sha512":  fmt.Sprintf("%x", sha512.Sum512(content)),
+		"sha1":    fmt.Sprintf("%x", sha1.Sum(content)),
+		"blake2b": fmt.Sprintf("%x", b2),
+		"blake3":  fmt.Sprintf("%x", b3),
+		"md5":     fmt.Sprintf("%x", md5.Sum(content)),
+	}
+
+	files := map[string]string{}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/tool_linux_amd64" {
+			w.Write(content)
+			return
+		}
+		f, ok := files[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		fmt.Fprint(w, f)
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		desc     string
+		files    map[string]string
+		require  bool
+		verified string
+		err      string
+	}{
+		{"sha512 list", map[string]string{"SHA512SUMS": sums["sha512"] + "  tool_linux_amd64\n"}, false, "sha512", ""},
+		{"blake3 list", map[string]string{"B3SUMS": sums["blake3"] + "  tool_linux_amd64\n"}, false, "blake3", ""},
+		{"blake2b list", map[string]string{"B2SUMS": sums["blake2b"] + "  tool_linux_amd64\n"}, false, "blake2b", ""},
+		{"weak sha1 file", map[string]string{"tool_linux_amd64.sha1": sums["sha1"] + "\n"}, true, "sha1", ""},
+		{"dedicated file first", map[string]string{"tool_linux_amd64.sha256": sums["sha256"], "checksums.txt": strings.Repeat("0", 64) + "  tool_linux_amd64\n"}, false, "sha256", ""},
+		{"mismatch", map[string]string{"checksums.txt": strings.Repeat("0", 64) + "  tool_linux_amd64\n"}, false, "", "sha256 checksum mismatch"},
+		{"unknown algorithm", map[string]string{"checksums.txt": sums["md5"] + "  tool_linux_amd64\n"}, false, "", ""},
+		{"unknown algorithm required", map[string]string{"checksums.txt": sums["md5"] + "  tool_linux_amd64\n"}, true, "", "algorithm of checksums.txt is unknown"},
+		{"no checksum", nil, false, "", ""},
+		{"no checksum required", nil, true, "", "no checksum is published"},
+	}
+
+	for _, c := range cases {
+		files = c.files
+		as := []*Asset{{Name: "tool_linux_amd64", URL: ts.URL + "/tool_linux_amd64"}}
+		for name := range c.files {
+			as = append(as, &Asset{Name: name, URL: ts.URL + "/" + name})
+		}
+
+		f := NewFilter(&FilterOpts{RequireChecksum: c.require})
+		gf, err := f.FilterAssets("tool", as)
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		_, err = f.ProcessURL(gf)
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", c.desc, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: expected error %q, got %v", c.desc, c.err, err)
+		case f.Verified() != c.verified:
+			t.Errorf("%s: expected to be verified with %q, got %q", c.desc, c.verified, f.Verified())
+		}
+	}
+}
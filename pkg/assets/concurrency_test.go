@@ -0,0 +1,203 @@
+package assets
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ulikunitz/xz"
+)
+
+// concurrentFixtures returns the assets served to the concurrent fetches
+// by name, along with the content of the binary they hold
+func concurrentFixtures(t *testing.T) (map[string][]byte, map[string][]byte) {
+	t.Helper()
+	bin := []byte("#!/bin/sh\necho tool\n")
+	tarball := func(w io.Writer) {
+		tw := tar.NewWriter(w)
+		if err := tw.WriteHeader(&tar.Header{Name: "tool", Mode: 0o755, Size: int64(len(bin)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(bin); err != nil {
+			t.Fatal(err)
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var tgz bytes.Buffer
+	gw := gzip.NewWriter(&tgz)
+	tarball(gw)
+	if err := gw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var txz bytes.Buffer
+	xw, err := xz.NewWriter(&txz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tarball(xw)
+	if err := xw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	var zb bytes.Buffer
+	zw := zip.NewWriter(&zb)
+	fw, err := zw.Create("tool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(bin); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	deb, err := os.ReadFile("testdata/tool_1.0.0_amd64.deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sevenZip, err := os.ReadFile("testdata/lzma2.7z")
+	if err != nil {
+		t.Fatal(err)
+	}
+	debOut, err := NewFilter(&FilterOpts{}).ProcessReader("tool_1.0.0_amd64.deb", bytes.NewReader(deb))
+	if err != nil {
+		t.Fatal(err)
+	}
+	debBin, _ := io.ReadAll(debOut.Source)
+	sevenZipOut, err := NewFilter(&FilterOpts{PackagePath: "05"}).ProcessReader("lzma2.7z", bytes.NewReader(sevenZip))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sevenZipBin, _ := io.ReadAll(sevenZipOut.Source)
+
+	served := map[string][]byte{
+		"tool-linux-amd64":         bin,
+		"tool-linux-amd64.tar.gz":  tgz.Bytes(),
+		"tool-linux-amd64.tar.xz":  txz.Bytes(),
+		"tool-linux-amd64.tar.zst": zstdCompress(t, "tool", bin),
+		"tool-linux-amd64.zip":     zb.Bytes(),
+		"tool-linux-amd64.7z":      sevenZip,
+		"tool_1.0.0_amd64.deb":     deb,
+	}
+	expected := map[string][]byte{
+		"tool-linux-amd64":         bin,
+		"tool-linux-amd64.tar.gz":  bin,
+		"tool-linux-amd64.tar.xz":  bin,
+		"tool-linux-amd64.tar.zst": bin,
+		"tool-linux-amd64.zip":     bin,
+		"tool-linux-amd64.7z":      sevenZipBin,
+		"tool_1.0.0_amd64.deb":     debBin,
+	}
+
+	var sums strings.Builder
+	for name, b := range served {
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256(b), name)
+	}
+	served["checksums.txt"] = []byte(sums.String())
+	return served, expected
+}
+
+// TestConcurrentFetches runs many fetches at once through every
+// processor, sharing a download cache. It's meant to be run with -race.
+func TestConcurrentFetches(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+	// the reporter isn't synchronized, the race detector
+	// catches concurrent calls
+	reports := 0
+	SetScoreReporter(func(string, []*ScoredAsset) { reports++ })
+	defer SetScoreReporter(nil)
+
+	served, expected := concurrentFixtures(t)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, ok := served[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(b)
+	}))
+	defer srv.Close()
+
+	cache := NewDownloadCache()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8*len(expected))
+	for i := 0; i < 8; i++ {
+		for name, content := range expected {
+			wg.Add(1)
+			go func(name string, content []byte, cache *DownloadCache) {
+				defer wg.Done()
+				// another platform keeps the scoring in the loop
+				as := []*Asset{
+					{Name: name, URL: srv.URL + "/" + name},
+					{Name: "tool-darwin-arm64.tar.gz", URL: srv.URL + "/tool-darwin-arm64.tar.gz"},
+					{Name: "checksums.txt", URL: srv.URL + "/checksums.txt"},
+				}
+				opts := &FilterOpts{HTTPClient: srv.Client(), Cache: cache, RequireChecksum: true}
+				if strings.HasSuffix(name, ".7z") {
+					opts.PackagePath = "05"
+				}
+				f := NewFilter(opts)
+				gf, err := f.FilterAssets("tool", as)
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+					return
+				}
+				out, err := f.ProcessURL(gf)
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+					return
+				}
+				b, err := io.ReadAll(out.Source)
+				if err != nil {
+					errs <- fmt.Errorf("%s: %w", name, err)
+					return
+				}
+				if !bytes.Equal(b, content) {
+					errs <- fmt.Errorf("%s: unexpected content %q", name, b)
+				}
+				if f.Verified() == "" {
+					errs <- fmt.Errorf("%s: expected the asset to be verified", name)
+				}
+			}(name, content, []*DownloadCache{nil, cache}[i%2])
+		}
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if reports != 8*len(expected) {
+		t.Errorf("expected %d score reports, got %d", 8*len(expected), reports)
+	}
+
+	// the assets are spooled to disk until the cache is closed
+	dir := cache.dir
+	if _, _, ok := cache.get(srv.URL + "/checksums.txt"); !ok || dir == "" {
+		t.Fatal("expected the downloads to be cached")
+	}
+	if err := cache.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed, got %v", dir, err)
+	}
+	if _, _, ok := cache.get(srv.URL + "/checksums.txt"); ok {
+		t.Error("expected nothing to be cached once closed")
+	}
+}
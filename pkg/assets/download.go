@@ -0,0 +1,352 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+const (
+	// maxDownloadAttempts bounds the attempts of a download
+	// interrupted by transient errors
+	maxDownloadAttempts = 3
+	// maxRetryAfter bounds the delay servers can ask for
+	// with Retry-After before a download is retried
+	maxRetryAfter = time.Minute
+	// bodySnippetSize is the size of the beginning of the
+	// body of unsuccessful responses kept in their errors
+	bodySnippetSize = 256
+)
+
+var (
+	// downloadRetryDelay is the delay before retrying an
+	// interrupted download, it's doubled on every attempt
+	downloadRetryDelay = time.Second
+	downloadSleep      = time.Sleep
+	// downloadJitter spreads the retries of concurrent downloads
+	downloadJitter = func(d time.Duration) time.Duration {
+		return time.Duration(rand.Int64N(int64(d)/2 + 1))
+	}
+
+	downloadMu  sync.Mutex
+	downloadDir string
+	// partialLocks serializes the downloads of the same URL
+	// so they don't write the same partial file
+	partialLocks = map[string]*sync.Mutex{}
+)
+
+// SetDownloadDir sets the directory where the downloads in progress
+// are written, so they can be resumed by a later attempt or run. A
+// temporary directory is used otherwise.
+func SetDownloadDir(dir string) {
+	downloadMu.Lock()
+	defer downloadMu.Unlock()
+	downloadDir = dir
+}
+
+// statusError is an unsuccessful response
+type statusError struct {
+	code int
+	url  string
+	// body is the beginning of the response body, it
+	// usually tells why the request failed
+	body string
+	// retryAfter is the delay asked by the server
+	// before retrying, 0 if none
+	retryAfter time.Duration
+}
+
+func newStatusError(res *http.Response, u string) *statusError {
+	b, _ := io.ReadAll(io.LimitReader(res.Body, bodySnippetSize))
+	return &statusError{
+		code:       res.StatusCode,
+		url:        u,
+		body:       strings.Join(strings.Fields(string(b)), " "),
+		retryAfter: parseRetryAfter(res.Header.Get("Retry-After"), time.Now()),
+	}
+}
+
+func (e *statusError) Error() string {
+	msg := fmt.Sprintf("%d response when checking binary from %s", e.code, e.url)
+	if e.body != "" {
+		msg += ": " + e.body
+	}
+	return msg
+}
+
+// parseRetryAfter parses a Retry-After header, either a number
+// of seconds or a date, 0 when it's missing or invalid
+func parseRetryAfter(h string, now time.Time) time.Duration {
+	if h == "" {
+		return 0
+	}
+	if s, err := strconv.Atoi(h); err == nil {
+		return max(time.Duration(s)*time.Second, 0)
+	}
+	if t, err := http.ParseTime(h); err == nil {
+		return max(t.Sub(now), 0)
+	}
+	return 0
+}
+
+// retryDelay returns how long to wait before retrying a download
+// which failed with err: the delay asked by the server if any, the
+// backoff delay with some jitter otherwise
+func retryDelay(delay time.Duration, err error) time.Duration {
+	var se *statusError
+	if errors.As(err, &se) && se.retryAfter > 0 {
+		return min(se.retryAfter, maxRetryAfter)
+	}
+	return delay + downloadJitter(delay)
+}
+
+// retryable reports whether a download failing with err
+// might succeed if retried
+func retryable(err error) bool {
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.code >= 500 || se.code == http.StatusRequestTimeout || se.code == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// partialMeta identifies the content of a partial download
+// so it's only resumed from the same remote file
+type partialMeta struct {
+	URL  string `json:"url"`
+	ETag string `json:"etag,omitempty"`
+	// Size is the size of the whole file, 0 if unknown
+	Size int64 `json:"size,omitempty"`
+}
+
+// partial is a download in progress
+type partial struct {
+	path string
+	meta partialMeta
+}
+
+func newPartial(dir, u string) *partial {
+	sum := sha256.Sum256([]byte(u))
+	p := &partial{path: filepath.Join(dir, hex.EncodeToString(sum[:8])+".part")}
+	if b, err := os.ReadFile(p.path + ".json"); err == nil {
+		if err := json.Unmarshal(b, &p.meta); err != nil || p.meta.URL != u {
+			p.meta = partialMeta{}
+		}
+	}
+	if p.meta.URL == "" {
+		// there's nothing to resume
+		os.Remove(p.path)
+	}
+	p.meta.URL = u
+	return p
+}
+
+// offset returns the number of bytes already downloaded
+func (p *partial) offset() int64 {
+	fi, err := os.Stat(p.path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// restart forgets the downloaded content and
+// records the remote file now downloaded
+func (p *partial) restart(meta partialMeta) error {
+	p.meta = meta
+	b, err := json.Marshal(p.meta)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(p.path+".json", b, 0o644); err != nil {
+		return err
+	}
+	return os.WriteFile(p.path, nil, 0o644)
+}
+
+func (p *partial) remove() {
+	os.Remove(p.path)
+	os.Remove(p.path + ".json")
+}
+
+// resumes reports whether the partial response continues the
+// partial download: it starts at its end and comes from the
+// same remote file
+func (p *partial) resumes(res *http.Response, offset int64) bool {
+	if res.StatusCode != http.StatusPartialContent {
+		return false
+	}
+	if etag := res.Header.Get("ETag"); etag != "" && p.meta.ETag != "" && etag != p.meta.ETag {
+		return false
+	}
+	start, total, ok := parseContentRange(res.Header.Get("Content-Range"))
+	return ok && start == offset && (total < 0 || p.meta.Size == 0 || total == p.meta.Size)
+}
+
+// parseContentRange parses a `bytes start-end/total` header,
+// total is -1 when unknown
+func parseContentRange(h string) (start, total int64, ok bool) {
+	rng, ok := strings.CutPrefix(h, "bytes ")
+	if !ok {
+		return 0, 0, false
+	}
+	rng, size, ok := strings.Cut(rng, "/")
+	if !ok {
+		return 0, 0, false
+	}
+	first, _, ok := strings.Cut(rng, "-")
+	if !ok {
+		return 0, 0, false
+	}
+	start, err := strconv.ParseInt(first, 10, 64)
+	if err != nil {
+		return 0, 0, false
+	}
+	total = -1
+	if size != "*" {
+		if total, err = strconv.ParseInt(size, 10, 64); err != nil {
+			return 0, 0, false
+		}
+	}
+	return start, total, true
+}
+
+// download downloads the asset into a partial file, resuming it
+// with a range request when it's interrupted by transient errors
+func (f *Filter) download(gf *FilteredAsset) ([]byte, error) {
+	downloadMu.Lock()
+	dir := downloadDir
+	lock, ok := partialLocks[gf.URL]
+	if !ok {
+		lock = &sync.Mutex{}
+		partialLocks[gf.URL] = lock
+	}
+	downloadMu.Unlock()
+
+	if dir == "" {
+		tmp, err := os.MkdirTemp("", "bin-download")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(tmp)
+		dir = tmp
+	} else if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	p := newPartial(dir, gf.URL)
+	delay := downloadRetryDelay
+	for attempt := 1; ; attempt++ {
+		b, err := f.fetch(gf, p)
+		if err == nil {
+			p.remove()
+			log.Debugf("Downloaded %s in %d attempt(s)", gf.URL, attempt)
+			return b, nil
+		}
+		if !retryable(err) {
+			return nil, err
+		}
+		if attempt == maxDownloadAttempts {
+			return nil, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		wait := retryDelay(delay, err)
+		log.Debugf("Attempt %d/%d to download %s failed: %v", attempt, maxDownloadAttempts, gf.URL, err)
+		log.WithField("url", gf.URL).WithError(err).WithField("retry_in", wait.Round(time.Millisecond).String()).Warn("Download interrupted, retrying")
+		downloadSleep(wait)
+		delay *= 2
+	}
+}
+
+// fetch downloads the rest of the partial download, or all of
+// it when the server doesn't resume it, and returns its content
+func (f *Filter) fetch(gf *FilteredAsset, p *partial) ([]byte, error) {
+	headers := map[string]string{}
+	for k, v := range gf.ExtraHeaders {
+		headers[k] = v
+	}
+	offset := p.offset()
+	if offset > 0 {
+		headers["Range"] = fmt.Sprintf("bytes=%d-", offset)
+		if p.meta.ETag != "" {
+			// the whole file is sent if it changed
+			headers["If-Range"] = p.meta.ETag
+		}
+	}
+
+	res, err := f.get(gf.URL, headers)
+	var se *statusError
+	if offset > 0 && errors.As(err, &se) && se.code == http.StatusRequestedRangeNotSatisfiable {
+		log.Debugf("Unable to resume the download of %s, restarting it", gf.URL)
+		if err := p.restart(partialMeta{URL: gf.URL}); err != nil {
+			return nil, err
+		}
+		return f.fetch(gf, p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	gf.Name = canonicalName(gf, res)
+	f.name = gf.Name
+
+	switch {
+	case offset > 0 && p.resumes(res, offset):
+		log.WithField("url", gf.URL).WithField("bytes", offset).Info("Resuming the download")
+	case res.StatusCode == http.StatusPartialContent:
+		// the range doesn't continue the partial download,
+		// it's restarted without asking for one
+		log.Debugf("Unable to resume the download of %s, restarting it", gf.URL)
+		res.Body.Close()
+		if err := p.restart(partialMeta{URL: gf.URL}); err != nil {
+			return nil, err
+		}
+		return f.fetch(gf, p)
+	default:
+		if offset > 0 {
+			log.WithField("url", gf.URL).Info("Changed since its download was interrupted, restarting it")
+		}
+		if err := p.restart(partialMeta{URL: gf.URL, ETag: res.Header.Get("ETag"), Size: max(res.ContentLength, 0)}); err != nil {
+			return nil, err
+		}
+		log.WithField("url", gf.URL).WithField("asset", gf.Name).Info("Starting the download")
+	}
+
+	out, err := os.OpenFile(p.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	progress := newProgressReader(res.Body, gf.Name, res.ContentLength)
+	_, err = io.Copy(out, progress)
+	progress.Close()
+	f.downloadedBytes += progress.read
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := os.ReadFile(p.path)
+	if err != nil {
+		return nil, err
+	}
+	if p.meta.Size > 0 && int64(len(b)) != p.meta.Size {
+		return nil, fmt.Errorf("downloaded %d bytes of %s instead of %d", len(b), gf.URL, p.meta.Size)
+	}
+	return b, nil
+}
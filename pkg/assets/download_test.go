@@ -0,0 +1,196 @@
+package assets
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// flakyServer serves content with range requests support, the first
+// response is cut after half of it like a connection reset
+func flakyServer(t *testing.T, content []byte, etags ...string) (*httptest.Server, *[]string) {
+	t.Helper()
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempt := len(ranges)
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", etags[min(attempt, len(etags)-1)])
+		if attempt == 0 {
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			w.Write(content[:len(content)/2])
+			panic(http.ErrAbortHandler)
+		}
+		http.ServeContent(w, r, "tool", time.Time{}, bytes.NewReader(content))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &ranges
+}
+
+func TestDownloadResumes(t *testing.T) {
+	defer func(f func(time.Duration)) { downloadSleep = f }(downloadSleep)
+	downloadSleep = func(time.Duration) {}
+	dir := t.TempDir()
+	SetDownloadDir(dir)
+	defer SetDownloadDir("")
+
+	content := []byte(strings.Repeat("0123456789", 1000))
+	for _, c := range []struct {
+		desc   string
+		etags  []string
+		resume string
+	}{
+		{"same file", []string{`"v1"`}, "bytes=5000-"},
+		// If-Range makes the server send the whole new file
+		{"changed file", []string{`"v1"`, `"v2"`}, "bytes=5000-"},
+	} {
+		srv, ranges := flakyServer(t, content, c.etags...)
+		f := NewFilter(&FilterOpts{})
+		b, err := f.download(&FilteredAsset{Name: "tool", URL: srv.URL + "/tool"})
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		if !bytes.Equal(b, content) {
+			t.Errorf("%s: expected the whole content, got %d bytes", c.desc, len(b))
+		}
+		if len(*ranges) != 2 || (*ranges)[1] != c.resume {
+			t.Errorf("%s: expected the download to be resumed with %s, got %q", c.desc, c.resume, *ranges)
+		}
+		if entries, _ := os.ReadDir(dir); len(entries) != 0 {
+			t.Errorf("%s: expected the partial download to be removed, got %d files", c.desc, len(entries))
+		}
+	}
+}
+
+func TestDownloadResumesAcrossRuns(t *testing.T) {
+	dir := t.TempDir()
+	SetDownloadDir(dir)
+	defer SetDownloadDir("")
+
+	content := []byte(strings.Repeat("0123456789", 1000))
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "tool", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	// a previous run was interrupted after 3000 bytes
+	u := srv.URL + "/tool"
+	p := newPartial(dir, u)
+	if err := p.restart(partialMeta{URL: u, ETag: `"v1"`, Size: int64(len(content))}); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p.path, content[:3000], 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFilter(&FilterOpts{})
+	b, err := f.download(&FilteredAsset{Name: "tool", URL: u})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Errorf("expected the whole content, got %d bytes", len(b))
+	}
+	if len(ranges) != 1 || ranges[0] != "bytes=3000-" {
+		t.Errorf("expected the download to be resumed at 3000, got %q", ranges)
+	}
+	if f.Downloaded() != int64(len(content)-3000) {
+		t.Errorf("expected only the rest to be downloaded, got %d bytes", f.Downloaded())
+	}
+}
+
+func TestDownloadDoesNotRetryClientErrors(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "Bad credentials", http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	_, err := NewFilter(&FilterOpts{}).download(&FilteredAsset{Name: "tool", URL: srv.URL + "/tool"})
+	if err == nil {
+		t.Fatal("expected the download to fail")
+	}
+	if requests != 1 {
+		t.Errorf("expected a single request, got %d", requests)
+	}
+	if !strings.HasSuffix(err.Error(), ": Bad credentials") {
+		t.Errorf("expected the body to be part of the error, got %v", err)
+	}
+}
+
+func TestDownloadHonorsRetryAfter(t *testing.T) {
+	var slept []time.Duration
+	defer func(f func(time.Duration)) { downloadSleep = f }(downloadSleep)
+	downloadSleep = func(d time.Duration) { slept = append(slept, d) }
+
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch requests {
+		case 1:
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusTooManyRequests)
+		case 2:
+			w.WriteHeader(http.StatusBadGateway)
+		default:
+			w.Write([]byte("tool"))
+		}
+	}))
+	defer srv.Close()
+
+	b, err := NewFilter(&FilterOpts{}).download(&FilteredAsset{Name: "tool", URL: srv.URL + "/tool"})
+	if err != nil || string(b) != "tool" {
+		t.Fatalf("expected the download to succeed on the third attempt, got %q (%v)", b, err)
+	}
+	if len(slept) != 2 || slept[0] != 7*time.Second {
+		t.Fatalf("expected to wait for the Retry-After delay first, got %v", slept)
+	}
+	// the backoff doubles with up to half of it of jitter
+	if slept[1] < 2*downloadRetryDelay || slept[1] > 3*downloadRetryDelay {
+		t.Errorf("expected a backoff between 2s and 3s, got %s", slept[1])
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for h, want := range map[string]time.Duration{
+		"":                              0,
+		"120":                           2 * time.Minute,
+		"-1":                            0,
+		"soon":                          0,
+		"Mon, 01 Jan 2024 00:00:30 GMT": 30 * time.Second,
+		"Sun, 31 Dec 2023 23:00:00 GMT": 0,
+	} {
+		if got := parseRetryAfter(h, now); got != want {
+			t.Errorf("%q: expected %s, got %s", h, want, got)
+		}
+	}
+}
+
+func TestParseContentRange(t *testing.T) {
+	cases := []struct {
+		in           string
+		start, total int64
+		ok           bool
+	}{
+		{"bytes 100-199/200", 100, 200, true},
+		{"bytes 0-99/*", 0, -1, true},
+		{"bytes */200", 0, 0, false},
+		{"items 0-1/2", 0, 0, false},
+		{"", 0, 0, false},
+	}
+	for _, c := range cases {
+		start, total, ok := parseContentRange(c.in)
+		if ok != c.ok || (ok && (start != c.start || total != c.total)) {
+			t.Errorf("%q: expected %d %d %t, got %d %d %t", c.in, c.start, c.total, c.ok, start, total, ok)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package assets
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"io/fs"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+)
+
+// EntryMeta is the metadata of the archive entry a file is extracted
+// from. It's normalized on install: the installed files get a fixed
+// mode, no owner and the time they're installed at.
+type EntryMeta struct {
+	Path    string
+	Mode    fs.FileMode
+	ModTime time.Time
+	Owner   string
+}
+
+func tarEntryMeta(entry string, h *tar.Header) *EntryMeta {
+	owner := h.Uname
+	if owner == "" && (h.Uid != 0 || h.Gid != 0) {
+		owner = strconv.Itoa(h.Uid) + ":" + strconv.Itoa(h.Gid)
+	}
+	return &EntryMeta{Path: entry, Mode: h.FileInfo().Mode(), ModTime: h.ModTime, Owner: owner}
+}
+
+func zipEntryMeta(entry string, h *zip.FileHeader) *EntryMeta {
+	return &EntryMeta{Path: entry, Mode: h.Mode(), ModTime: h.Modified}
+}
+
+// entryAssets returns the entries of an archive as assets sorted
+// by path, so the candidates and the order they're offered in
+// don't depend on the map iteration order
+func entryAssets[V any](files map[string]V) []*Asset {
+	as := make([]*Asset, 0, len(files))
+	for _, e := range slices.Sorted(maps.Keys(files)) {
+		as = append(as, &Asset{Name: e, URL: ""})
+	}
+	return as
+}
+
+// Entry returns the metadata of the archive entry the file was
+// extracted from, nil when it wasn't extracted or it has none
+func (f *Filter) Entry() *EntryMeta {
+	return f.entry
+}
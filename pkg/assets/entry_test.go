@@ -0,0 +1,46 @@
+package assets
+
+import (
+	"archive/tar"
+	"bytes"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProcessReaderEntryMeta(t *testing.T) {
+	mtime := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, name := range []string{"tool", "README"} {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o700, Size: 1, ModTime: mtime, Uname: "runner", Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFilter(&FilterOpts{PackagePath: "tool"})
+	if _, err := f.ProcessReader("tool.tar", &buf); err != nil {
+		t.Fatal(err)
+	}
+	e := f.Entry()
+	if e == nil || e.Path != "tool" || e.Mode.Perm() != 0o700 || !e.ModTime.Equal(mtime) || e.Owner != "runner" {
+		t.Errorf("unexpected entry metadata %+v", e)
+	}
+}
+
+func TestEntryAssetsSorted(t *testing.T) {
+	as := entryAssets(map[string][]byte{"c": nil, "a/b": nil, "b": nil, "a": nil})
+	var names []string
+	for _, a := range as {
+		names = append(names, a.Name)
+	}
+	if want := []string{"a", "a/b", "b", "c"}; !slices.Equal(names, want) {
+		t.Errorf("expected %v, got %v", want, names)
+	}
+}
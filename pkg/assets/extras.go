@@ -0,0 +1,99 @@
+package assets
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Kinds of the extra files shipped along the binaries
+const (
+	ExtraBash = "bash"
+	ExtraZsh  = "zsh"
+	ExtraFish = "fish"
+	ExtraMan  = "man"
+)
+
+// Extra is a shell completion or a man page
+// shipped in the archive along the binary
+type Extra struct {
+	Kind string
+	// Name is the name of the installed file, i.e. `_tool` for zsh
+	Name string
+	// Section is the section of the man pages, i.e. 1
+	Section string
+	Data    []byte
+}
+
+// manPage matches the man pages, which might be compressed
+var manPage = regexp.MustCompile(`\.([1-9])(\.gz)?$`)
+
+// classifyExtra tells whether the entry of an archive is a shell
+// completion or a man page by the usual naming conventions:
+// completions/tool.bash, completion/bash/tool, _tool, tool.fish
+// and man/tool.1. stem is the name of the tool it's about.
+func classifyExtra(entry string) (e *Extra, stem string) {
+	lower := strings.ToLower(entry)
+	base := path.Base(entry)
+	dir := path.Dir(lower)
+	completion := strings.Contains(dir, "complet")
+	switch {
+	case strings.HasSuffix(lower, ".fish"):
+		stem = strings.TrimSuffix(base, path.Ext(base))
+		return &Extra{Kind: ExtraFish, Name: base}, stem
+	case strings.HasSuffix(lower, ".zsh"):
+		stem = strings.TrimPrefix(strings.TrimSuffix(base, path.Ext(base)), "_")
+		return &Extra{Kind: ExtraZsh, Name: "_" + stem}, stem
+	case strings.HasPrefix(base, "_") && (completion || strings.Contains(dir, "zsh")):
+		return &Extra{Kind: ExtraZsh, Name: base}, base[1:]
+	case strings.HasSuffix(lower, ".bash"):
+		stem = strings.TrimSuffix(base, path.Ext(base))
+		return &Extra{Kind: ExtraBash, Name: stem}, stem
+	case completion && strings.Contains(dir, "bash"):
+		return &Extra{Kind: ExtraBash, Name: base}, base
+	case !completion && manPage.MatchString(lower):
+		m := manPage.FindStringSubmatch(lower)
+		stem = strings.TrimSuffix(base, m[0])
+		return &Extra{Kind: ExtraMan, Name: base, Section: m[1]}, stem
+	}
+	return nil, ""
+}
+
+// wantsExtra reports whether the entry is an extra file to install
+func (f *Filter) wantsExtra(entry string) bool {
+	e, _ := classifyExtra(entry)
+	if e == nil {
+		return false
+	}
+	if e.Kind == ExtraMan {
+		return f.opts.Manpages
+	}
+	return f.opts.Completions
+}
+
+// collectExtras keeps the extra files about the installed binary,
+// including the man pages of its subcommands (i.e. tool-sub.1)
+func (f *Filter) collectExtras(files map[string][]byte, binary string) {
+	tool := strings.ToLower(strings.TrimSuffix(binary, path.Ext(binary)))
+	entries := make([]string, 0, len(files))
+	for e := range files {
+		entries = append(entries, e)
+	}
+	sort.Strings(entries)
+	for _, entry := range entries {
+		e, stem := classifyExtra(entry)
+		stem = strings.ToLower(stem)
+		if stem != tool && !(e.Kind == ExtraMan && strings.HasPrefix(stem, tool+"-")) {
+			continue
+		}
+		e.Data = files[entry]
+		f.extras = append(f.extras, e)
+	}
+}
+
+// Extras returns the shell completions and man pages of the
+// binary found in the archive, see FilterOpts.Completions
+func (f *Filter) Extras() []*Extra {
+	return f.extras
+}
@@ -0,0 +1,83 @@
+package assets
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+)
+
+func TestClassifyExtra(t *testing.T) {
+	for _, c := range []struct {
+		entry, kind, name, section, stem string
+	}{
+		{"tool-v1.0.0/completions/tool.bash", ExtraBash, "tool", "", "tool"},
+		{"contrib/completion/bash/tool", ExtraBash, "tool", "", "tool"},
+		{"autocomplete/_tool", ExtraZsh, "_tool", "", "tool"},
+		{"completions/tool.zsh", ExtraZsh, "_tool", "", "tool"},
+		{"complete/tool.fish", ExtraFish, "tool.fish", "", "tool"},
+		{"man/tool.1", ExtraMan, "tool.1", "1", "tool"},
+		{"share/man/man5/tool-config.5.gz", ExtraMan, "tool-config.5.gz", "5", "tool-config"},
+		{"tool", "", "", "", ""},
+		{"README.md", "", "", "", ""},
+		{"lib/_internal.py", "", "", "", ""},
+	} {
+		e, stem := classifyExtra(c.entry)
+		if c.kind == "" {
+			if e != nil {
+				t.Errorf("%s: expected not to be an extra file, got %+v", c.entry, e)
+			}
+			continue
+		}
+		if e == nil || e.Kind != c.kind || e.Name != c.name || e.Section != c.section || stem != c.stem {
+			t.Errorf("%s: expected %s %s %s about %s, got %+v about %s", c.entry, c.kind, c.name, c.section, c.stem, e, stem)
+		}
+	}
+}
+
+func TestProcessReaderExtras(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, n := range []string{"tool", "other", "completions/tool.bash", "completions/_tool", "completions/other.bash", "man/tool.1", "man/tool-sub.1"} {
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o755, Size: int64(len(n)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, c := range []struct {
+		opts FilterOpts
+		want []string
+	}{
+		{FilterOpts{PackagePath: "tool"}, nil},
+		// the package path of updates doesn't drop them
+		{FilterOpts{PackagePath: "tool", Completions: true}, []string{"_tool", "tool"}},
+		{FilterOpts{PackagePath: "tool", Manpages: true}, []string{"tool-sub.1", "tool.1"}},
+	} {
+		opts := c.opts
+		f := NewFilter(&opts)
+		out, err := f.ProcessReader("tool.tar", bytes.NewReader(buf.Bytes()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.Name != "tool" {
+			t.Fatalf("expected tool to be extracted, got %s", out.Name)
+		}
+		var got []string
+		for _, e := range f.Extras() {
+			got = append(got, e.Name)
+		}
+		if len(got) != len(c.want) {
+			t.Fatalf("expected %v to be collected with %+v, got %v", c.want, c.opts, got)
+		}
+		for i := range got {
+			if got[i] != c.want[i] {
+				t.Fatalf("expected %v to be collected with %+v, got %v", c.want, c.opts, got)
+			}
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package assets
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+// FormatBinary is the format of the assets which
+// aren't archives nor packages
+const FormatBinary = "binary"
+
+// formatSuffixes maps the file name suffixes to their format,
+// the longest suffixes come first so `.tar.gz` isn't taken as `.gz`
+var formatSuffixes = []struct{ suffix, format string }{
+	{".tar.gz", "tar.gz"}, {".tgz", "tar.gz"},
+	{".tar.xz", "tar.xz"}, {".txz", "tar.xz"},
+	{".tar.zst", "tar.zst"}, {".tzst", "tar.zst"},
+	{".tar.bz2", "tar.bz2"}, {".tbz2", "tar.bz2"}, {".tbz", "tar.bz2"},
+	{".tar", "tar"},
+	{".zip", "zip"},
+	{".7z", "7z"},
+	{".gz", "gz"},
+	{".xz", "xz"},
+	{".zst", "zst"},
+	{".bz2", "bz2"},
+	{".deb", "deb"},
+	{".rpm", "rpm"},
+	{".apk", "apk"},
+	{".appimage", FormatAppImage},
+	{".dmg", "dmg"},
+	{".pkg", "pkg"},
+}
+
+// DefaultFormats is the order in which the asset formats are
+// preferred when several of them are available for the platform.
+// AppImages come right after the raw binaries since they bundle
+// what the archives of the same tools would need to be unpacked along.
+var DefaultFormats = []string{FormatBinary, FormatAppImage, "tar.gz", "tar.xz", "tar.zst", "tar.bz2", "tar", "zip", "7z", "gz", "xz", "zst", "bz2", "deb", "rpm", "apk", "dmg", "pkg"}
+
+// ResolveFormats returns the preference order of the asset formats
+// applied for the configured one, along with its origin
+func ResolveFormats(formats []string) ([]string, string) {
+	if len(formats) > 0 {
+		return formats, config.OriginEntry
+	}
+	return DefaultFormats, config.OriginDefault
+}
+
+// Format returns the archive or package format of the asset
+func Format(name string) string {
+	name = strings.ToLower(name)
+	for _, s := range formatSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.format
+		}
+	}
+	return FormatBinary
+}
+
+// ValidateFormats checks the formats of a preference list
+func ValidateFormats(formats []string) error {
+	for _, f := range formats {
+		known := false
+		for _, d := range DefaultFormats {
+			if f == d {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return fmt.Errorf("unknown format %q, must be one of %s", f, strings.Join(DefaultFormats, ", "))
+		}
+	}
+	return nil
+}
+
+// formatRank returns the position of the format of the asset in
+// the preference list, the formats not listed come last
+func formatRank(name string, formats []string) int {
+	f := Format(name)
+	for i, p := range formats {
+		if p == f {
+			return i
+		}
+	}
+	return len(formats)
+}
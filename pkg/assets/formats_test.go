@@ -0,0 +1,88 @@
+package assets
+
+import "testing"
+
+func TestFilterAssetsFormats(t *testing.T) {
+	as := []*Asset{
+		{Name: "tool_1.2.0_checksums.txt"},
+		{Name: "tool_1.2.0_darwin_amd64.tar.gz"},
+		{Name: "tool_1.2.0_linux_amd64.deb"},
+		{Name: "tool_1.2.0_linux_amd64.rpm"},
+		{Name: "tool_1.2.0_linux_amd64.sbom.json"},
+		{Name: "tool_1.2.0_linux_amd64.tar.gz"},
+		{Name: "tool_1.2.0_linux_amd64.tar.gz.sig"},
+		{Name: "tool_1.2.0_linux_amd64.zip"},
+		{Name: "tool_1.2.0_linux_amd64.zip.sha256"},
+	}
+
+	cases := []struct {
+		formats []string
+		out     string
+	}{
+		{nil, "tool_1.2.0_linux_amd64.tar.gz"},
+		{[]string{"zip"}, "tool_1.2.0_linux_amd64.zip"},
+		{[]string{"deb", "tar.gz"}, "tool_1.2.0_linux_amd64.deb"},
+		{[]string{"rpm"}, "tool_1.2.0_linux_amd64.rpm"},
+	}
+
+	resolver = testLinuxAMDResolver
+	for _, c := range cases {
+		f := NewFilter(&FilterOpts{Formats: c.formats})
+		got, err := f.FilterAssets("tool", as)
+		if err != nil {
+			t.Fatalf("%v: error filtering assets %v", c.formats, err)
+		}
+		if got.Name != c.out {
+			t.Errorf("%v: expected %s, got %s", c.formats, c.out, got.Name)
+		}
+	}
+}
+
+func TestFilterAssetsRawBinary(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	f := NewFilter(&FilterOpts{})
+	got, err := f.FilterAssets("tool", []*Asset{
+		{Name: "tool-linux-amd64"},
+		{Name: "tool-linux-amd64.tar.xz"},
+		{Name: "tool-linux-amd64.sha256"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "tool-linux-amd64" {
+		t.Fatalf("expected the raw binary, got %s", got.Name)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	cases := []struct {
+		in, out string
+	}{
+		{"tool_linux_amd64", FormatBinary},
+		{"tool_1.2.3_linux", FormatBinary},
+		{"tool.exe", FormatBinary},
+		{"tool.AppImage", FormatAppImage},
+		{"tool.tar.gz", "tar.gz"},
+		{"tool.tgz", "tar.gz"},
+		{"tool.TAR.XZ", "tar.xz"},
+		{"tool.gz", "gz"},
+		{"tool.zip", "zip"},
+		{"tool.deb", "deb"},
+		{"tool.tar.zst", "tar.zst"},
+		{"tool.tzst", "tar.zst"},
+		{"tool.zst", "zst"},
+		{"tool.7z", "7z"},
+	}
+	for _, c := range cases {
+		if got := Format(c.in); got != c.out {
+			t.Errorf("Format(%s): expected %s, got %s", c.in, c.out, got)
+		}
+	}
+
+	if err := ValidateFormats([]string{"tar.gz", "binary"}); err != nil {
+		t.Error(err)
+	}
+	if err := ValidateFormats([]string{"tarball"}); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
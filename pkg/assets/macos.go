@@ -0,0 +1,137 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// machOMagics are the magic bytes of the thin and universal Mach-O
+// executables, in both byte orders
+var machOMagics = [][]byte{
+	{0xfe, 0xed, 0xfa, 0xce}, {0xce, 0xfa, 0xed, 0xfe},
+	{0xfe, 0xed, 0xfa, 0xcf}, {0xcf, 0xfa, 0xed, 0xfe},
+	{0xca, 0xfe, 0xba, 0xbe},
+}
+
+// isMacOSInstaller reports whether the format is a disk
+// image or an installer, they're only extracted on macOS
+func isMacOSInstaller(format string) bool {
+	return format == "dmg" || format == "pkg"
+}
+
+func isMachO(head []byte) bool {
+	for _, m := range machOMagics {
+		if bytes.HasPrefix(head, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// macOSExecutables collects the Mach-O executables found under dir,
+// i.e. in the .app bundles of a disk image or the payload of an
+// installer. The scripts of the installers aren't considered.
+func (f *Filter) macOSExecutables(dir string) (packageEntries, error) {
+	files := packageEntries{}
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == "Scripts" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		if info.Mode()&0o111 == 0 {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && rel != f.opts.PackagePath {
+			return nil
+		}
+		b, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		if isMachO(b) {
+			files[rel] = b
+		}
+		return nil
+	})
+	return files, err
+}
+
+// processDmg extracts the executables of a disk image, in
+// the .app bundles (Contents/MacOS) or at its root
+func (f *Filter) processDmg(name string, r io.Reader) (*finalFile, error) {
+	dir, cleanup, err := attachDmg(r)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return f.pickMacOSExecutable(name, "dmg", dir)
+}
+
+// processPkg extracts the executables of the payloads of an installer
+func (f *Filter) processPkg(name string, r io.Reader) (*finalFile, error) {
+	dir, cleanup, err := expandPkg(r)
+	if err != nil {
+		return nil, err
+	}
+	defer cleanup()
+	return f.pickMacOSExecutable(name, "pkg", dir)
+}
+
+func (f *Filter) pickMacOSExecutable(name, format, dir string) (*finalFile, error) {
+	files, err := f.macOSExecutables(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no executable found in %s. PackagePath [%s]", format, f.opts.PackagePath)
+	}
+	return f.pickPackageEntry(name, format, files)
+}
+
+// writeTemp writes the content of the asset to a file
+// of a new temporary directory, for the macOS tools
+func writeTemp(r io.Reader, name string) (string, string, error) {
+	dir, err := os.MkdirTemp("", "bin-")
+	if err != nil {
+		return "", "", err
+	}
+	p := filepath.Join(dir, name)
+	out, err := os.Create(p)
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	_, err = io.Copy(out, r)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	return dir, p, nil
+}
+
+// trimOutput shortens the output of a failed command for its error
+func trimOutput(out []byte) string {
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,47 @@
+package assets
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// attachDmg mounts the disk image read-only, without showing it in
+// the Finder. It returns the mount point and a function detaching it.
+func attachDmg(r io.Reader) (string, func(), error) {
+	dir, img, err := writeTemp(r, "image.dmg")
+	if err != nil {
+		return "", nil, err
+	}
+	mnt := filepath.Join(dir, "mnt")
+	out, err := exec.Command("hdiutil", "attach", "-nobrowse", "-readonly", "-noautoopen", "-noverify", "-mountpoint", mnt, img).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("error attaching the disk image: %w: %s", err, trimOutput(out))
+	}
+	return mnt, func() {
+		if out, err := exec.Command("hdiutil", "detach", "-force", mnt).CombinedOutput(); err != nil {
+			log.WithField("path", mnt).WithError(err).WithField("output", trimOutput(out)).Warn("Error detaching the disk image")
+		}
+		os.RemoveAll(dir)
+	}, nil
+}
+
+// expandPkg expands the installer along with its payloads
+func expandPkg(r io.Reader) (string, func(), error) {
+	dir, pkg, err := writeTemp(r, "installer.pkg")
+	if err != nil {
+		return "", nil, err
+	}
+	expanded := filepath.Join(dir, "expanded")
+	out, err := exec.Command("pkgutil", "--expand-full", pkg, expanded).CombinedOutput()
+	if err != nil {
+		os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("error expanding the installer: %w: %s", err, trimOutput(out))
+	}
+	return expanded, func() { os.RemoveAll(dir) }, nil
+}
@@ -0,0 +1,17 @@
+//go:build !darwin
+// +build !darwin
+
+package assets
+
+import (
+	"errors"
+	"io"
+)
+
+func attachDmg(io.Reader) (string, func(), error) {
+	return "", nil, errors.New("disk images can only be extracted on macOS")
+}
+
+func expandPkg(io.Reader) (string, func(), error) {
+	return "", nil, errors.New("installers can only be extracted on macOS")
+}
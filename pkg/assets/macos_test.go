@@ -0,0 +1,124 @@
+package assets
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// machO is the start of a 64-bit Mach-O executable
+var machO = []byte{0xcf, 0xfa, 0xed, 0xfe, 0x07, 0x00, 0x00, 0x01}
+
+func writeExecutable(t *testing.T, p string, b []byte, perm os.FileMode) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, b, perm); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMacOSExecutables(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("there's no exec bit on Windows")
+	}
+	dir := t.TempDir()
+	writeExecutable(t, filepath.Join(dir, "Tool.app/Contents/MacOS/tool"), machO, 0o755)
+	writeExecutable(t, filepath.Join(dir, "Tool.app/Contents/Info.plist"), []byte("<plist/>"), 0o644)
+	writeExecutable(t, filepath.Join(dir, "Tool.app/Contents/Resources/helper.sh"), []byte("#!/bin/sh\n"), 0o755)
+	writeExecutable(t, filepath.Join(dir, "Scripts/postinstall"), machO, 0o755)
+	writeExecutable(t, filepath.Join(dir, "README"), machO, 0o644)
+
+	files, err := NewFilter(&FilterOpts{}).macOSExecutables(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files["Tool.app/Contents/MacOS/tool"] == nil {
+		t.Fatalf("expected the executable of the bundle only, got %v", files)
+	}
+
+	out, err := NewFilter(&FilterOpts{}).pickMacOSExecutable("tool", "dmg", dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.Name != "tool" || out.PackagePath != "Tool.app/Contents/MacOS/tool" {
+		t.Fatalf("unexpected pick %s (%s)", out.Name, out.PackagePath)
+	}
+
+	if _, err := NewFilter(&FilterOpts{PackagePath: "Tool.app/Contents/MacOS/other"}).pickMacOSExecutable("tool", "dmg", dir); err == nil {
+		t.Fatal("expected an error when the package path isn't found")
+	}
+}
+
+func TestScoreMacOSInstallers(t *testing.T) {
+	defer func() { resolver = runtimeResolver{} }()
+	as := []*Asset{
+		{Name: "tool-darwin-arm64.dmg"},
+		{Name: "tool-macos.pkg"},
+		{Name: "tool-linux-amd64.tar.gz"},
+	}
+
+	resolver = testLinuxAMDResolver
+	for _, s := range NewFilter(&FilterOpts{}).Score("tool", as) {
+		if isMacOSInstaller(s.Format) && s.Excluded == "" {
+			t.Errorf("expected %s to be excluded on Linux", s.Name)
+		}
+	}
+
+	resolver = testDarwinARMResolver
+	gf, err := NewFilter(&FilterOpts{}).FilterAssets("tool", append(as, &Asset{Name: "tool-darwin-arm64.tar.gz"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gf.Name != "tool-darwin-arm64.tar.gz" {
+		t.Fatalf("expected the tarball to win over the disk image, got %s", gf.Name)
+	}
+	gf, err = NewFilter(&FilterOpts{}).FilterAssets("tool", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gf.Name != "tool-darwin-arm64.dmg" {
+		t.Fatalf("expected the disk image when there's nothing else, got %s", gf.Name)
+	}
+}
+
+func TestProcessReaderDmg(t *testing.T) {
+	if runtime.GOOS != "darwin" {
+		_, err := NewFilter(&FilterOpts{}).ProcessReader("tool.dmg", bytes.NewReader([]byte("koly")))
+		if err == nil || !strings.Contains(err.Error(), "macOS") {
+			t.Fatalf("expected disk images to be refused, got %v", err)
+		}
+		return
+	}
+	if _, err := exec.LookPath("hdiutil"); err != nil {
+		t.Skip("hdiutil isn't available")
+	}
+
+	dir := t.TempDir()
+	src := filepath.Join(dir, "src")
+	writeExecutable(t, filepath.Join(src, "Tool.app/Contents/MacOS/tool"), machO, 0o755)
+	img := filepath.Join(dir, "tool.dmg")
+	if out, err := exec.Command("hdiutil", "create", "-quiet", "-srcfolder", src, "-format", "UDZO", img).CombinedOutput(); err != nil {
+		t.Skipf("can't create a disk image: %v: %s", err, out)
+	}
+	f, err := os.Open(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	out, err := NewFilter(&FilterOpts{}).ProcessReader("tool.dmg", f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := io.ReadAll(out.Source)
+	if !bytes.Equal(b, machO) {
+		t.Fatalf("expected the executable of the bundle, got %q", b)
+	}
+}
@@ -0,0 +1,70 @@
+package assets
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// mirrorSources returns the assets to download in order: the asset
+// itself and its mirrors, before it when MirrorFirst is set, then its
+// fallback URL. Mirrors don't get the extra headers since they hold
+// the provider's token, the fallback doesn't get the Accept one which
+// only applies to the asset URL.
+func (f *Filter) mirrorSources(gf *FilteredAsset) []*FilteredAsset {
+	mirrors := make([]*FilteredAsset, 0, len(f.opts.Mirrors))
+	for _, m := range f.opts.Mirrors {
+		mirror := *gf
+		mirror.URL = strings.ReplaceAll(m, "{asset}", gf.Name)
+		mirror.ExtraHeaders = nil
+		mirrors = append(mirrors, &mirror)
+	}
+	sources := append([]*FilteredAsset{gf}, mirrors...)
+	if f.opts.MirrorFirst {
+		sources = append(mirrors, gf)
+	}
+	if gf.FallbackURL != "" && gf.FallbackURL != gf.URL {
+		fallback := *gf
+		fallback.URL = gf.FallbackURL
+		fallback.ExtraHeaders = map[string]string{}
+		for k, v := range gf.ExtraHeaders {
+			if k != "Accept" {
+				fallback.ExtraHeaders[k] = v
+			}
+		}
+		sources = append(sources, &fallback)
+	}
+	return sources
+}
+
+// downloadMirrored downloads the asset from the first of its
+// sources which serves it. The content is verified by the caller
+// against the checksums published along the asset whichever
+// source served it.
+func (f *Filter) downloadMirrored(gf *FilteredAsset) ([]byte, error) {
+	if len(f.opts.Mirrors) == 0 && gf.FallbackURL == "" {
+		f.assetURL = gf.URL
+		return f.download(gf)
+	}
+
+	var errs []error
+	for _, src := range f.mirrorSources(gf) {
+		b, err := f.download(src)
+		if err == nil {
+			if src.URL == gf.FallbackURL {
+				log.WithField("asset", gf.Name).WithField("url", src.URL).Info("Downloaded from the fallback URL")
+				gf.Name = src.Name
+			} else if src != gf {
+				log.WithField("asset", gf.Name).WithField("url", src.URL).Info("Downloaded from a mirror")
+				gf.Name = src.Name
+			}
+			f.assetURL = src.URL
+			return b, nil
+		}
+		log.WithField("asset", gf.Name).WithField("url", src.URL).WithError(err).Warn("Unable to download")
+		errs = append(errs, fmt.Errorf("%s: %w", src.URL, err))
+	}
+	return nil, fmt.Errorf("unable to download %s from any of its sources: %w", gf.Name, errors.Join(errs...))
+}
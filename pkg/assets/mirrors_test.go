@@ -0,0 +1,131 @@
+package assets
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestProcessURLMirrors(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	content := []byte("#!/bin/sh\necho tool\n")
+	sums := fmt.Sprintf("%x  tool_linux_amd64\n", sha256.Sum256(content))
+
+	var (
+		requests []string
+		origin   int
+		mirrored []byte
+	)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/checksums.txt":
+			fmt.Fprint(w, sums)
+			return
+		case "/tool_linux_amd64":
+			requests = append(requests, "origin")
+			if origin != http.StatusOK {
+				w.WriteHeader(origin)
+				return
+			}
+			w.Write(content)
+		case "/mirror/1.0.0/tool_linux_amd64":
+			requests = append(requests, "mirror")
+			if r.Header.Get("Authorization") != "" {
+				t.Error("expected the mirror not to get the credentials")
+			}
+			w.Write(mirrored)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	cases := []struct {
+		desc     string
+		origin   int
+		mirror   string
+		mirrored []byte
+		first    bool
+		requests string
+		err      string
+	}{
+		{"origin", http.StatusOK, "/mirror/{version}/{asset}", content, false, "origin", ""},
+		{"fallback", http.StatusNotFound, "/mirror/{version}/{asset}", content, false, "origin mirror", ""},
+		{"mirror first", http.StatusOK, "/mirror/{version}/{asset}", content, true, "mirror", ""},
+		{"missing from mirror", http.StatusOK, "/missing/{asset}", content, true, "origin", ""},
+		{"tampered mirror", http.StatusNotFound, "/mirror/{version}/{asset}", []byte("#!/bin/sh\necho evil\n"), false, "origin mirror", "sha256 checksum mismatch"},
+		{"all failing", http.StatusNotFound, "/missing/{asset}", content, false, "origin", "unable to download tool_linux_amd64 from any of its sources"},
+	}
+
+	for _, c := range cases {
+		requests, origin, mirrored = nil, c.origin, c.mirrored
+		as := []*Asset{
+			{Name: "tool_linux_amd64", URL: ts.URL + "/tool_linux_amd64"},
+			{Name: "checksums.txt", URL: ts.URL + "/checksums.txt"},
+		}
+		// the providers resolve the placeholders but {asset}
+		mirror := ts.URL + strings.ReplaceAll(c.mirror, "{version}", "1.0.0")
+
+		f := NewFilter(&FilterOpts{Mirrors: []string{mirror}, MirrorFirst: c.first})
+		gf, err := f.FilterAssets("tool", as)
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		gf.ExtraHeaders = map[string]string{"Authorization": "token secret"}
+		out, err := f.ProcessURL(gf)
+		if got := strings.Join(requests, " "); got != c.requests {
+			t.Errorf("%s: expected the requests %q, got %q", c.desc, c.requests, got)
+		}
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", c.desc, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: expected error %q, got %v", c.desc, c.err, err)
+		case c.err == "" && (out.Asset != "tool_linux_amd64" || f.Verified() != "sha256"):
+			t.Errorf("%s: expected tool_linux_amd64 verified with sha256, got %s verified with %q", c.desc, out.Asset, f.Verified())
+		}
+	}
+}
+
+func TestProcessURLFallback(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	content := []byte("#!/bin/sh\necho tool\n")
+	var requests []string
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/assets/1":
+			requests = append(requests, "asset")
+			w.WriteHeader(http.StatusNotFound)
+		case "/download/tool_linux_amd64":
+			requests = append(requests, "fallback")
+			if r.Header.Get("Accept") == "application/octet-stream" || r.Header.Get("Authorization") == "" {
+				t.Errorf("expected the fallback to get the credentials only, got %v", r.Header)
+			}
+			w.Write(content)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer ts.Close()
+
+	f := NewFilter(&FilterOpts{})
+	gf, err := f.FilterAssets("tool", []*Asset{{Name: "tool_linux_amd64", URL: ts.URL + "/assets/1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gf.ExtraHeaders = map[string]string{"Accept": "application/octet-stream", "Authorization": "token secret"}
+	gf.FallbackURL = ts.URL + "/download/tool_linux_amd64"
+	if _, err := f.ProcessURL(gf); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(requests, " "); got != "asset fallback" {
+		t.Fatalf("expected the fallback URL to be downloaded after the asset one, got %q", got)
+	}
+}
@@ -0,0 +1,93 @@
+package assets
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/options"
+)
+
+// pickEntry selects the file to install among the entries of an
+// archive or package. With FilterOpts.Entries or SelectMultiple
+// several of them are picked: the first one is installed and the
+// others are recorded, see OtherEntries.
+func (f *Filter) pickEntry(name string, as []*Asset) (*FilteredAsset, error) {
+	var picked []string
+	var err error
+	switch {
+	case len(f.opts.Entries) > 0:
+		picked, err = entriesNamed(name, as, f.opts.Entries)
+	case f.opts.SelectMultiple:
+		picked, err = selectEntries(name, as)
+	default:
+		return f.FilterAssets(name, as)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f.others = picked[1:]
+	return &FilteredAsset{RepoName: name, Name: picked[0]}, nil
+}
+
+// OtherEntries returns the path, inside the archive, of the other
+// files picked along the installed one with FilterOpts.Entries or
+// SelectMultiple. They can be fetched using them as PackagePath.
+func (f *Filter) OtherEntries() []string {
+	return f.others
+}
+
+// entriesNamed returns the entries whose base name (i.e. etcdctl)
+// or path is one of the given names, in the order of the names
+func entriesNamed(archive string, as []*Asset, names []string) ([]string, error) {
+	entries := sortedEntries(as)
+	picked := make([]string, 0, len(names))
+	for _, n := range names {
+		found := ""
+		for _, e := range entries {
+			if e == n || path.Base(e) == n {
+				found = e
+				break
+			}
+		}
+		if found == "" {
+			return nil, fmt.Errorf("%s not found in %s, its files are: %s", n, archive, strings.Join(entries, ", "))
+		}
+		picked = append(picked, found)
+	}
+	return picked, nil
+}
+
+// selectEntries prompts the user which of the
+// files of the archive have to be installed
+func selectEntries(archive string, as []*Asset) ([]string, error) {
+	var candidates []fmt.Stringer
+	for _, e := range sortedEntries(as) {
+		if isSideFile(e) || !isSupportedExt(e) {
+			continue
+		}
+		candidates = append(candidates, options.LiteralStringer(e))
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no executable found in %s", archive)
+	}
+	selected, err := options.SelectMultiple(fmt.Sprintf("Files found in %s, please select the ones to install:", archive), candidates)
+	if err != nil {
+		return nil, err
+	}
+	picked := make([]string, 0, len(selected))
+	for _, s := range selected {
+		picked = append(picked, s.String())
+	}
+	return picked, nil
+}
+
+func sortedEntries(as []*Asset) []string {
+	entries := make([]string, 0, len(as))
+	for _, a := range as {
+		entries = append(entries, a.Name)
+	}
+	sort.Strings(entries)
+	return entries
+}
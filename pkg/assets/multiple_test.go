@@ -0,0 +1,49 @@
+package assets
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProcessReaderEntries(t *testing.T) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for _, n := range []string{"etcd-v3.5.0-linux-amd64/etcd", "etcd-v3.5.0-linux-amd64/etcdctl", "etcd-v3.5.0-linux-amd64/etcdutl", "etcd-v3.5.0-linux-amd64/README.md"} {
+		if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o755, Size: int64(len(n)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(n)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	f := NewFilter(&FilterOpts{Entries: []string{"etcdctl", "etcd-v3.5.0-linux-amd64/etcdutl"}})
+	out, err := f.ProcessReader("etcd-v3.5.0-linux-amd64.tar", bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(out.Source); out.Name != "etcdctl" || string(b) != "etcd-v3.5.0-linux-amd64/etcdctl" {
+		t.Fatalf("expected etcdctl to be extracted first, got %s (%q)", out.Name, b)
+	}
+	if others := f.OtherEntries(); !reflect.DeepEqual(others, []string{"etcd-v3.5.0-linux-amd64/etcdutl"}) {
+		t.Fatalf("unexpected other entries %v", others)
+	}
+
+	// the others are fetched by their package path
+	f = NewFilter(&FilterOpts{PackagePath: "etcd-v3.5.0-linux-amd64/etcdutl"})
+	if out, err = f.ProcessReader("etcd-v3.5.0-linux-amd64.tar", bytes.NewReader(buf.Bytes())); err != nil || out.Name != "etcdutl" {
+		t.Fatalf("expected etcdutl to be extracted, got %v (%v)", out, err)
+	}
+
+	_, err = NewFilter(&FilterOpts{Entries: []string{"etcdctl", "etcdadm"}}).ProcessReader("etcd-v3.5.0-linux-amd64.tar", bytes.NewReader(buf.Bytes()))
+	if err == nil || !strings.Contains(err.Error(), "etcdadm not found") {
+		t.Fatalf("expected the missing binary to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package assets
+
+import (
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// canonicalName returns the name of a downloaded asset, used for its
+// cache entry, the selection hint and the default name of the binary.
+// The name given by the provider API wins, then the filename of the
+// Content-Disposition header, then the base name of the final URL,
+// once redirects are followed.
+func canonicalName(gf *FilteredAsset, res *http.Response) string {
+	served := servedName(res)
+	if served == "" {
+		served = urlBase(gf.URL)
+	}
+	if gf.Name != "" && !gf.NameFromURL {
+		if served != gf.Name {
+			log.Debugf("%s is served as %s, keeping its name %s", gf.URL, served, gf.Name)
+		}
+		return gf.Name
+	}
+	if gf.Name != "" && served != gf.Name {
+		log.Debugf("%s is served as %s", gf.Name, served)
+	}
+	return served
+}
+
+// servedName returns the name of the file given by the server, either
+// in the Content-Disposition header or as the base of the final URL
+func servedName(res *http.Response) string {
+	if cd := res.Header.Get("Content-Disposition"); cd != "" {
+		if _, params, err := mime.ParseMediaType(cd); err == nil {
+			if n := cleanName(params["filename"]); n != "" {
+				return n
+			}
+		}
+	}
+	if res.Request != nil && res.Request.URL != nil {
+		return cleanName(res.Request.URL.Path)
+	}
+	return ""
+}
+
+// urlBase returns the last element of the path of the URL
+func urlBase(u string) string {
+	if pu, err := url.Parse(u); err == nil {
+		return cleanName(pu.Path)
+	}
+	return cleanName(u)
+}
+
+// cleanName keeps the base of the name so servers
+// can't make bin write outside of its directory
+func cleanName(n string) string {
+	n = path.Base(strings.ReplaceAll(n, "\\", "/"))
+	if n == "." || n == "/" || n == ".." {
+		return ""
+	}
+	return n
+}
@@ -0,0 +1,70 @@
+package assets
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCanonicalName(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/download/tool_linux_amd64", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("cd") != "" {
+			w.Header().Set("Content-Disposition", `attachment; filename="tool-v1.0-linux-amd64"`)
+		}
+		io.WriteString(w, "#!/bin/sh\n")
+	})
+	mux.HandleFunc("/redirect", func(w http.ResponseWriter, r *http.Request) {
+		u := "/download/tool_linux_amd64"
+		if r.URL.Query().Get("cd") != "" {
+			u += "?cd=1"
+		}
+		http.Redirect(w, r, u, http.StatusFound)
+	})
+	mux.HandleFunc("/files/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Disposition", `attachment; filename="../../tool cli"`)
+		io.WriteString(w, "#!/bin/sh\n")
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cases := []struct {
+		desc        string
+		name        string
+		nameFromURL bool
+		url         string
+		out         string
+	}{
+		{"API name wins over Content-Disposition", "tool-linux-amd64", false, "/redirect?cd=1", "tool-linux-amd64"},
+		{"API name wins over the final URL", "tool-linux-amd64", false, "/redirect", "tool-linux-amd64"},
+		{"Content-Disposition wins over the final URL", "", false, "/redirect?cd=1", "tool-v1.0-linux-amd64"},
+		{"Content-Disposition wins over a guessed name", "redirect", true, "/redirect?cd=1", "tool-v1.0-linux-amd64"},
+		{"final URL wins over a guessed name", "redirect", true, "/redirect", "tool_linux_amd64"},
+		{"final URL without redirects", "", false, "/download/tool_linux_amd64", "tool_linux_amd64"},
+		{"Content-Disposition can't escape the directory", "", false, "/files/1234", "tool cli"},
+	}
+
+	for _, c := range cases {
+		cache := NewDownloadCache()
+		defer cache.Close()
+		gf := &FilteredAsset{Name: c.name, NameFromURL: c.nameFromURL, URL: ts.URL + c.url}
+		out, err := NewFilter(&FilterOpts{Cache: cache}).ProcessURL(gf)
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		if gf.Name != c.out || out.Name != c.out {
+			t.Errorf("%s: expected %s, got asset %s and file %s", c.desc, c.out, gf.Name, out.Name)
+		}
+
+		// the cached download keeps the same name
+		gf = &FilteredAsset{Name: c.name, NameFromURL: c.nameFromURL, URL: ts.URL + c.url}
+		out, err = NewFilter(&FilterOpts{Cache: cache}).ProcessURL(gf)
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		if gf.Name != c.out || out.Name != c.out {
+			t.Errorf("%s: expected %s from the cache, got asset %s and file %s", c.desc, c.out, gf.Name, out.Name)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package assets
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/matchers"
+	"github.com/klauspost/compress/zstd"
+	"github.com/xi2/xz"
+)
+
+// packageBinDirs are the directories of the deb and rpm packages
+// whose executables are offered as candidates
+var packageBinDirs = []string{"usr/bin/", "usr/local/bin/", "opt/"}
+
+// packageEntries collects the executables of a package
+type packageEntries map[string][]byte
+
+// addPackageEntry keeps the content of the entry if it's an executable
+// from one of the binary directories of the package
+func (f *Filter) addPackageEntry(files packageEntries, name string, mode int64, r io.Reader) error {
+	entry, ok := entryName(strings.TrimPrefix(path.Clean("/"+name), "/"))
+	if !ok || mode&0o111 == 0 {
+		return nil
+	}
+	inBinDir := false
+	for _, d := range packageBinDirs {
+		inBinDir = inBinDir || strings.HasPrefix(entry, d)
+	}
+	if !inBinDir {
+		return nil
+	}
+	if !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && entry != f.opts.PackagePath {
+		return nil
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	files[entry] = b
+	return nil
+}
+
+// pickPackageEntry selects the executable to install among
+// the ones of the package
+func (f *Filter) pickPackageEntry(name, format string, files packageEntries) (*finalFile, error) {
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no executable found in %s package under %s. PackagePath [%s]", format, strings.Join(packageBinDirs, ", "), f.opts.PackagePath)
+	}
+
+	choice, err := f.pickEntry(name, entryAssets(files))
+	if err != nil {
+		return nil, err
+	}
+	selectedFile := choice.String()
+
+	return &finalFile{Source: bytes.NewReader(files[selectedFile]), Name: path.Base(selectedFile), PackagePath: selectedFile}, nil
+}
+
+// decompress uncompresses the payloads of the packages,
+// whatever their compression, if any
+func decompress(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	head, err := br.Peek(262)
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	t, _ := filetype.Match(head)
+	switch t {
+	case matchers.TypeGz:
+		return gzip.NewReader(br)
+	case matchers.TypeXz:
+		return xz.NewReader(br, 0)
+	case matchers.TypeZstd:
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return zr.IOReadCloser(), nil
+	case matchers.TypeBz2:
+		return bzip2.NewReader(br), nil
+	}
+	return br, nil
+}
+
+// processDeb extracts the executables from the data
+// archive of a deb package, which is an ar archive
+func (f *Filter) processDeb(name string, r io.Reader) (*finalFile, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, 8)
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != "!<arch>\n" {
+		return nil, fmt.Errorf("invalid deb package")
+	}
+
+	for {
+		// name, mtime, uid, gid, mode, size and magic
+		header := make([]byte, 60)
+		if _, err := io.ReadFull(br, header); err == io.EOF {
+			return nil, fmt.Errorf("no data archive found in deb package")
+		} else if err != nil {
+			return nil, err
+		}
+		member := strings.TrimSuffix(strings.TrimSpace(string(header[:16])), "/")
+		size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid deb package: %w", err)
+		}
+
+		if strings.HasPrefix(member, "data.tar") {
+			data, err := decompress(io.LimitReader(br, size))
+			if err != nil {
+				return nil, err
+			}
+			return f.processDebData(name, data)
+		}
+
+		// members are aligned on 2 bytes
+		if _, err := br.Discard(int(size + size%2)); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (f *Filter) processDebData(name string, r io.Reader) (*finalFile, error) {
+	tr := tar.NewReader(r)
+	files := packageEntries{}
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := f.addPackageEntry(files, header.Name, header.Mode, tr); err != nil {
+			return nil, err
+		}
+	}
+	return f.pickPackageEntry(name, "deb", files)
+}
+
+// rpmHeaderMagic starts the signature and main headers of rpm packages
+var rpmHeaderMagic = []byte{0x8e, 0xad, 0xe8, 0x01}
+
+// skipRPMHeader skips a header of a rpm package, the
+// signature one is aligned on 8 bytes
+func skipRPMHeader(r *bufio.Reader, align bool) error {
+	intro := make([]byte, 16)
+	if _, err := io.ReadFull(r, intro); err != nil {
+		return err
+	}
+	if !bytes.Equal(intro[:4], rpmHeaderMagic) {
+		return fmt.Errorf("invalid rpm header")
+	}
+	entries := binary.BigEndian.Uint32(intro[8:12])
+	size := binary.BigEndian.Uint32(intro[12:16])
+	n := int(entries)*16 + int(size)
+	if align && n%8 != 0 {
+		n += 8 - n%8
+	}
+	_, err := r.Discard(n)
+	return err
+}
+
+// processRpm extracts the executables from the cpio payload of a
+// rpm package. It comes after the lead and the signature and main
+// headers, which aren't needed.
+func (f *Filter) processRpm(name string, r io.Reader) (*finalFile, error) {
+	br := bufio.NewReader(r)
+	if _, err := br.Discard(96); err != nil {
+		return nil, fmt.Errorf("invalid rpm package: %w", err)
+	}
+	if err := skipRPMHeader(br, true); err != nil {
+		return nil, fmt.Errorf("invalid rpm package: %w", err)
+	}
+	if err := skipRPMHeader(br, false); err != nil {
+		return nil, fmt.Errorf("invalid rpm package: %w", err)
+	}
+
+	payload, err := decompress(br)
+	if err != nil {
+		return nil, err
+	}
+	files := packageEntries{}
+	cr := bufio.NewReader(payload)
+	for {
+		entry, mode, size, err := nextCPIOEntry(cr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid rpm payload: %w", err)
+		}
+		if entry == "TRAILER!!!" {
+			break
+		}
+		data := io.LimitReader(cr, size)
+		// regular files only
+		if mode&0o170000 == 0o100000 {
+			if err := f.addPackageEntry(files, entry, mode, data); err != nil {
+				return nil, err
+			}
+		}
+		// the data is aligned on 4 bytes
+		if _, err := io.Copy(io.Discard, data); err != nil {
+			return nil, err
+		}
+		if _, err := cr.Discard(int((4 - size%4) % 4)); err != nil {
+			return nil, err
+		}
+	}
+	return f.pickPackageEntry(name, "rpm", files)
+}
+
+// nextCPIOEntry reads the header of the next entry of a cpio archive
+// in the new ASCII format used by rpm, it returns its name, mode and size
+func nextCPIOEntry(r *bufio.Reader) (string, int64, int64, error) {
+	header := make([]byte, 110)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return "", 0, 0, err
+	}
+	if magic := string(header[:6]); magic != "070701" && magic != "070702" {
+		return "", 0, 0, fmt.Errorf("unsupported cpio format %q", magic)
+	}
+	field := func(i int) (int64, error) {
+		return strconv.ParseInt(string(header[6+i*8:14+i*8]), 16, 64)
+	}
+	mode, err := field(1)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	size, err := field(6)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	nameSize, err := field(11)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	// the header and the name are aligned on 4 bytes
+	name := make([]byte, nameSize+(4-(110+nameSize)%4)%4)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return "", 0, 0, err
+	}
+	return strings.TrimRight(string(name[:nameSize]), "\x00"), mode, size, nil
+}
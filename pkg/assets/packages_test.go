@@ -0,0 +1,127 @@
+package assets
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// cpioEntry is an entry of the payload of the test rpm packages
+type cpioEntry struct {
+	name    string
+	mode    int64
+	content string
+}
+
+// buildRPM builds a rpm package with empty headers, its cpio payload
+// is compressed with the given function
+func buildRPM(t *testing.T, entries []cpioEntry, compress func(io.Writer) io.WriteCloser) []byte {
+	t.Helper()
+	var payload bytes.Buffer
+	pad := func(n int) {
+		payload.Write(make([]byte, (4-n%4)%4))
+	}
+	for i, e := range append(entries, cpioEntry{name: "TRAILER!!!"}) {
+		name := e.name + "\x00"
+		fmt.Fprintf(&payload, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X", i, e.mode, 0, 0, 1, 0, len(e.content), 0, 0, 0, 0, len(name), 0)
+		payload.WriteString(name)
+		pad(110 + len(name))
+		payload.WriteString(e.content)
+		pad(len(e.content))
+	}
+
+	var b bytes.Buffer
+	lead := make([]byte, 96)
+	copy(lead, []byte{0xed, 0xab, 0xee, 0xdb, 3, 0})
+	b.Write(lead)
+	// signature and main headers, without entries
+	for i := 0; i < 2; i++ {
+		b.Write(rpmHeaderMagic)
+		b.Write(make([]byte, 12))
+	}
+	w := compress(&b)
+	if _, err := w.Write(payload.Bytes()); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestProcessReaderPackages(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	tool := "#!/bin/sh\necho tool\n"
+	// built with dpkg-deb -Zxz, it holds usr/bin/tool and non executable files
+	deb, err := os.ReadFile("testdata/tool_1.0.0_amd64.deb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []cpioEntry{
+		{"./usr/share/doc/tool/README", 0o100644, "doc"},
+		{"./usr/bin/tool", 0o100755, tool},
+		{"./etc/tool.conf", 0o100755, "conf"},
+		{"./opt/tool/data.txt", 0o100644, "data"},
+	}
+	zstdRPM := buildRPM(t, entries, func(w io.Writer) io.WriteCloser {
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return zw
+	})
+	gzipRPM := buildRPM(t, entries, func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+
+	cases := []struct {
+		desc, name string
+		b          []byte
+	}{
+		{"deb with xz data", "tool_1.0.0_amd64.deb", deb},
+		{"rpm with zstd payload", "tool-1.0.0.x86_64.rpm", zstdRPM},
+		{"rpm with gzip payload", "tool-1.0.0.x86_64.rpm", gzipRPM},
+	}
+	for _, c := range cases {
+		t.Run(c.desc, func(t *testing.T) {
+			out, err := NewFilter(&FilterOpts{}).ProcessReader(c.name, bytes.NewReader(c.b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, err := io.ReadAll(out.Source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tool || out.Name != "tool" || out.PackagePath != "usr/bin/tool" {
+				t.Fatalf("expected usr/bin/tool to be extracted, got %s from %s (%q)", out.Name, out.PackagePath, b)
+			}
+		})
+	}
+
+	noBinaries := buildRPM(t, entries[:1], func(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) })
+	if _, err := NewFilter(&FilterOpts{}).ProcessReader("docs.rpm", bytes.NewReader(noBinaries)); err == nil {
+		t.Fatal("expected an error for a package without executables")
+	}
+}
+
+func TestScorePackages(t *testing.T) {
+	as := []*Asset{
+		{Name: "tool_1.0.0_amd64.deb"},
+		{Name: "tool-1.0.0.x86_64.rpm"},
+	}
+	for _, r := range []*mockOSResolver{testLinuxAMDResolver, {OS: []string{"darwin", "macos"}, Arch: []string{"amd64", "x86_64"}}} {
+		resolver = r
+		for _, s := range NewFilter(&FilterOpts{}).Score("tool", as) {
+			linux := r == testLinuxAMDResolver
+			if (s.Excluded == "") != linux {
+				t.Errorf("expected %s to be a candidate only on Linux, got %q on %s", s.Name, s.Excluded, r.OS[0])
+			}
+		}
+	}
+	resolver = runtimeResolver{}
+}
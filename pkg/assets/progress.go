@@ -0,0 +1,133 @@
+package assets
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/cheggaaa/pb"
+	"github.com/marcosnils/bin/pkg/log"
+	"github.com/mattn/go-isatty"
+)
+
+// progressInterval is how often the progress of a
+// download is logged when no bar is rendered
+var progressInterval = 10 * time.Second
+
+var (
+	progressMu    sync.Mutex
+	progressQuiet bool
+	// progressBar is set while a bar is rendered, concurrent
+	// downloads log their progress instead of mixing their bars
+	progressBar bool
+)
+
+// progressTerminal reports whether progress bars can be rendered
+var progressTerminal = func() bool {
+	fd := os.Stdout.Fd()
+	return isatty.IsTerminal(fd) || isatty.IsCygwinTerminal(fd)
+}
+
+// SetQuiet disables the progress bars, the progress of the
+// downloads is only logged periodically
+func SetQuiet(quiet bool) {
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progressQuiet = quiet
+}
+
+// progressReader reports the progress of a download, with a
+// labeled bar on terminals and periodic log lines otherwise
+type progressReader struct {
+	r     io.Reader
+	label string
+	total int64
+	read  int64
+	bar   *pb.ProgressBar
+
+	now   func() time.Time
+	start time.Time
+	last  time.Time
+	logf  func(string, ...interface{})
+}
+
+// newProgressReader reports the progress of reading the
+// label download of the given size, -1 when unknown
+func newProgressReader(r io.Reader, label string, total int64) *progressReader {
+	p := &progressReader{r: r, label: label, total: total, now: time.Now}
+	p.logf = func(msg string, v ...interface{}) {
+		log.WithField("asset", label).WithField("bytes", p.read).WithField("total", total).Infof(msg, v...)
+	}
+	p.start, p.last = p.now(), p.now()
+
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	// bars would be mixed with the JSON logs
+	if !progressQuiet && !progressBar && !log.JSON() && progressTerminal() {
+		progressBar = true
+		p.bar = pb.New64(max(total, 0)).Set(pb.Bytes, true).Set("prefix", label+" ")
+		p.bar.SetTemplate(pb.Full).Start()
+	}
+	return p
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.read += int64(n)
+	if p.bar != nil {
+		p.bar.Add(n)
+	} else if now := p.now(); now.Sub(p.last) >= progressInterval {
+		p.last = now
+		p.logf("%s", p.status(now))
+	}
+	return n, err
+}
+
+// Close stops rendering the bar, it doesn't close the reader
+func (p *progressReader) Close() error {
+	if p.bar == nil {
+		return nil
+	}
+	p.bar.Finish()
+	p.bar = nil
+	progressMu.Lock()
+	defer progressMu.Unlock()
+	progressBar = false
+	return nil
+}
+
+// status describes the progress of the download: the bytes read,
+// the percentage, the throughput and the remaining time, the last
+// two only when the size is known
+func (p *progressReader) status(now time.Time) string {
+	elapsed := now.Sub(p.start)
+	var rate float64
+	if elapsed > 0 {
+		rate = float64(p.read) / elapsed.Seconds()
+	}
+	if p.total <= 0 {
+		return fmt.Sprintf("Downloading %s: %s, %s/s", p.label, FormatSize(p.read), FormatSize(int64(rate)))
+	}
+	s := fmt.Sprintf("Downloading %s: %s / %s (%d%%), %s/s", p.label, FormatSize(p.read), FormatSize(p.total), p.read*100/p.total, FormatSize(int64(rate)))
+	if rate > 0 {
+		eta := time.Duration(float64(p.total-p.read) / rate * float64(time.Second))
+		s += fmt.Sprintf(", ETA %s", eta.Round(time.Second))
+	}
+	return s
+}
+
+// FormatSize formats a number of bytes with binary units
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,92 @@
+package assets
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReaderLogs(t *testing.T) {
+	defer func(f func() bool) { progressTerminal = f }(progressTerminal)
+	progressTerminal = func() bool { return false }
+
+	now := time.Unix(0, 0)
+	p := newProgressReader(strings.NewReader(strings.Repeat("x", 4<<20)), "tool.tar.gz", 4<<20)
+	if p.bar != nil {
+		t.Fatal("expected no progress bar outside of a terminal")
+	}
+	var lines []string
+	p.now = func() time.Time { return now }
+	p.start, p.last = now, now
+	p.logf = func(format string, args ...interface{}) { lines = append(lines, fmt.Sprintf(format, args...)) }
+
+	buf := make([]byte, 1<<20)
+	for i := 0; i < 2; i++ {
+		now = now.Add(progressInterval / 2)
+		if _, err := p.Read(buf); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := "Downloading tool.tar.gz: 2.0 MiB / 4.0 MiB (50%), 204.8 KiB/s, ETA 10s"
+	if len(lines) != 1 || lines[0] != want {
+		t.Fatalf("expected a single line %q, got %q", want, lines)
+	}
+
+	p.total = -1
+	if s := p.status(now); s != "Downloading tool.tar.gz: 2.0 MiB, 204.8 KiB/s" {
+		t.Errorf("unexpected status without a size: %q", s)
+	}
+}
+
+func TestProgressQuiet(t *testing.T) {
+	defer func(f func() bool) { progressTerminal = f }(progressTerminal)
+	progressTerminal = func() bool { return true }
+	defer SetQuiet(false)
+
+	SetQuiet(true)
+	p := newProgressReader(strings.NewReader(""), "tool", 0)
+	if p.bar != nil {
+		t.Error("expected no progress bar when quiet")
+	}
+	p.Close()
+}
+
+func TestFilterDownloaded(t *testing.T) {
+	content := strings.Repeat("x", 1234)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, content)
+	}))
+	defer ts.Close()
+
+	cache := NewDownloadCache()
+	defer cache.Close()
+	for _, want := range []int64{1234, 0} {
+		f := NewFilter(&FilterOpts{Cache: cache})
+		if _, err := f.ProcessURL(&FilteredAsset{Name: "tool", URL: ts.URL + "/tool"}); err != nil {
+			t.Fatal(err)
+		}
+		if f.Downloaded() != want {
+			t.Errorf("expected %d bytes downloaded, got %d", want, f.Downloaded())
+		}
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	for n, want := range map[int64]string{
+		0:             "0 B",
+		1023:          "1023 B",
+		1024:          "1.0 KiB",
+		1536:          "1.5 KiB",
+		500 << 20:     "500.0 MiB",
+		3 << 30:       "3.0 GiB",
+		(5 << 40) / 2: "2.5 TiB",
+	} {
+		if s := FormatSize(n); s != want {
+			t.Errorf("%d: expected %s, got %s", n, want, s)
+		}
+	}
+}
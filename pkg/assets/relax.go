@@ -0,0 +1,85 @@
+package assets
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// ErrNoMatch is the error of the selections which found no asset
+// matching the requirements, they might succeed once relaxed
+var ErrNoMatch = errors.New("no matching asset")
+
+type noMatchError string
+
+func (e noMatchError) Error() string { return string(e) }
+
+func (e noMatchError) Is(target error) bool { return target == ErrNoMatch }
+
+// NoMatch returns an error with the message which is ErrNoMatch
+func NoMatch(msg string) error {
+	return noMatchError(msg)
+}
+
+// Relaxation loosens the requirements of the asset selection, they're
+// cumulative: each one also applies the ones before it
+type Relaxation int
+
+const (
+	// RelaxNone is the strict selection
+	RelaxNone Relaxation = iota
+	// RelaxLibc drops the libc (gnu, musl) from the asset
+	// hint, i.e. `tool-*-linux-gnu.tar.gz` matches
+	// `tool-1.0.0-linux.tar.gz`
+	RelaxLibc
+	// RelaxEmulated accepts the builds of the architecture the
+	// host emulates even when the arch fallback is disabled
+	RelaxEmulated
+	// RelaxRaw accepts the raw binaries whose name doesn't
+	// tell their platform
+	RelaxRaw
+)
+
+// Relaxations are the relaxations tried in order
+// after a strict selection found no asset
+var Relaxations = []Relaxation{RelaxLibc, RelaxEmulated, RelaxRaw}
+
+// String returns the name of the relaxation
+func (r Relaxation) String() string {
+	switch r {
+	case RelaxLibc:
+		return "libc"
+	case RelaxEmulated:
+		return "emulated arch"
+	case RelaxRaw:
+		return "raw binaries"
+	}
+	return "none"
+}
+
+// libcQualifier is the libc part of the asset names, i.e.
+// `-gnu` in `x86_64-unknown-linux-gnu` or `_musl`
+var libcQualifier = regexp.MustCompile(`[-_.]?(gnu|musl)(eabihf|eabi)?`)
+
+// WithoutLibc returns the selector without the libc of its pattern,
+// nil when the pattern doesn't have one
+func (s *Selector) WithoutLibc() *Selector {
+	if s == nil || !libcQualifier.MatchString(s.pattern) {
+		return nil
+	}
+	relaxed, err := NewSelector(libcQualifier.ReplaceAllString(s.pattern, ""))
+	if err != nil {
+		return nil
+	}
+	return relaxed
+}
+
+// rawBinary reports whether the asset is a raw binary which
+// can run on the host, i.e. not a Windows executable elsewhere
+func rawBinary(name string) bool {
+	if Format(name) != FormatBinary {
+		return false
+	}
+	return !strings.HasSuffix(strings.ToLower(name), ".exe") || slices.Contains(resolver.GetOSSpecificExtensions(), "exe")
+}
@@ -0,0 +1,100 @@
+package assets
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestFilterAssetsRelaxed(t *testing.T) {
+	noFallback := &mockOSResolver{OS: []string{"darwin", "macos", "osx"}, Arch: []string{"arm64", "aarch64"}, FallbackArch: []string{"amd64", "x86_64", "x64"}, NoFallbackArch: true}
+	cases := []struct {
+		desc     string
+		resolver platformResolver
+		in       []string
+		relax    Relaxation
+		out      string
+	}{
+		{"emulated strict", noFallback, []string{"tool_darwin_amd64.tar.gz", "tool_windows_amd64.zip"}, RelaxLibc, ""},
+		{"emulated relaxed", noFallback, []string{"tool_darwin_amd64.tar.gz", "tool_windows_amd64.zip"}, RelaxEmulated, "tool_darwin_amd64.tar.gz"},
+		{"raw strict", testLinuxAMDResolver, []string{"gh", "checksums.txt"}, RelaxEmulated, ""},
+		{"raw relaxed", testLinuxAMDResolver, []string{"gh", "checksums.txt", "gh.tar.gz"}, RelaxRaw, "gh"},
+		{"windows executable", testLinuxAMDResolver, []string{"gh.exe"}, RelaxRaw, ""},
+		{"platform match first", testLinuxAMDResolver, []string{"gh", "gh_linux_amd64"}, RelaxRaw, "gh_linux_amd64"},
+	}
+
+	defer func() { resolver = runtimeResolver{} }()
+	for _, c := range cases {
+		resolver = c.resolver
+		as := make([]*Asset, 0, len(c.in))
+		for _, n := range c.in {
+			as = append(as, &Asset{Name: n})
+		}
+		// a single asset isn't scored
+		as = append(as, &Asset{Name: "other_freebsd_arm.zip"})
+		gf, err := NewFilter(&FilterOpts{Relax: c.relax}).FilterAssets("cli", as)
+		if c.out == "" {
+			if !errors.Is(err, ErrNoMatch) {
+				t.Errorf("%s: expected no asset to match, got %v (%v)", c.desc, gf, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.desc, err)
+			continue
+		}
+		if gf.Name != c.out {
+			t.Errorf("%s: expected %s, got %s", c.desc, c.out, gf.Name)
+		}
+	}
+}
+
+func TestSelectorWithoutLibc(t *testing.T) {
+	for _, c := range []struct {
+		pattern, want string
+	}{
+		{"tool-*-x86_64-unknown-linux-gnu.tar.gz", "tool-*-x86_64-unknown-linux.tar.gz"},
+		{"tool_*_linux_musl_amd64.tar.gz", "tool_*_linux_amd64.tar.gz"},
+		{"/^tool-.*-linux-gnueabihf$/", "/^tool-.*-linux$/"},
+		{"tool-*-linux-amd64.tar.gz", ""},
+	} {
+		s, err := NewSelector(c.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := s.WithoutLibc().String(); got != c.want {
+			t.Errorf("%s: expected %q, got %q", c.pattern, c.want, got)
+		}
+	}
+}
+
+func TestFilterAssetsRequirePlatform(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	cases := []struct {
+		desc string
+		in   []string
+		out  string
+	}{
+		{"other platforms", []string{"tool_plan9_mips.tar.gz", "tool_aix_ppc64.tar.gz"}, ""},
+		{"single other platform", []string{"tool_plan9_mips.tar.gz"}, ""},
+		{"platform match", []string{"tool_plan9_mips.tar.gz", "tool_linux_amd64.tar.gz"}, "tool_linux_amd64.tar.gz"},
+		{"single platform match", []string{"tool_linux_amd64.tar.gz"}, "tool_linux_amd64.tar.gz"},
+	}
+	for _, c := range cases {
+		as := make([]*Asset, 0, len(c.in))
+		for _, n := range c.in {
+			as = append(as, &Asset{Name: n})
+		}
+		gf, err := NewFilter(&FilterOpts{RequirePlatform: true}).FilterAssets("tool", as)
+		if c.out == "" {
+			if !errors.Is(err, ErrNoMatch) {
+				t.Errorf("%s: expected no asset to match, got %v (%v)", c.desc, gf, err)
+			}
+			continue
+		}
+		if err != nil || gf.Name != c.out {
+			t.Errorf("%s: expected %s, got %v (%v)", c.desc, c.out, gf, err)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package assets
+
+import "github.com/marcosnils/bin/pkg/log"
+
+// rewrite returns the assets with their names rewritten by the rules
+// of FilterOpts.Rewrites, in order, and the original names of the
+// renamed ones. The renamed assets are copies, the URLs are unchanged.
+func (f *Filter) rewrite(as []*Asset) ([]*Asset, map[*Asset]string) {
+	if len(f.opts.Rewrites) == 0 {
+		return as, nil
+	}
+	out := make([]*Asset, len(as))
+	originals := map[*Asset]string{}
+	for i, a := range as {
+		out[i] = a
+		name := a.Name
+		for _, r := range f.opts.Rewrites {
+			if n, ok := r.Rewrite(name); ok && n != name {
+				log.Debugf("Asset rewrite %q renamed %s to %s", r.Match, name, n)
+				name = n
+			}
+		}
+		if name != a.Name {
+			c := *a
+			c.Name = name
+			out[i] = &c
+			originals[&c] = a.Name
+		}
+	}
+	return out, originals
+}
@@ -0,0 +1,32 @@
+package assets
+
+import (
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestFilterAssetsRewrites(t *testing.T) {
+	defer func() { resolver = runtimeResolver{} }()
+	resolver = testLinuxAMDResolver
+
+	as := []*Asset{
+		{Name: "tool-2.3.0-lnx-x86-64.tar.gz", URL: "https://example.com/tool-2.3.0-lnx-x86-64.tar.gz"},
+		{Name: "tool-2.3.0-mac-x86-64.tar.gz", URL: "https://example.com/tool-2.3.0-mac-x86-64.tar.gz"},
+		{Name: "tool-2.3.0-freebsd-arm.tar.gz", URL: "https://example.com/tool-2.3.0-freebsd-arm.tar.gz"},
+	}
+	rewrites := []*config.AssetRewrite{
+		{Match: `-lnx-`, Replace: "-linux-"},
+		{Match: `x86-64`, Replace: "x86_64"},
+	}
+	gf, err := NewFilter(&FilterOpts{Rewrites: rewrites}).FilterAssets("tool", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if gf.Name != "tool-2.3.0-lnx-x86-64.tar.gz" || gf.URL != "https://example.com/tool-2.3.0-lnx-x86-64.tar.gz" {
+		t.Errorf("expected the original name and URL of the rewritten asset, got %s (%s)", gf.Name, gf.URL)
+	}
+	if as[0].Name != "tool-2.3.0-lnx-x86-64.tar.gz" {
+		t.Errorf("expected the candidates to be left untouched, got %s", as[0].Name)
+	}
+}
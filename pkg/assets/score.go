@@ -0,0 +1,227 @@
+package assets
+
+import (
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/marcosnils/bin/pkg/log"
+	bstrings "github.com/marcosnils/bin/pkg/strings"
+)
+
+// fallbackArchScore is the arch score of the assets of the architecture
+// emulated by the host, they're only picked when there's no native one
+const fallbackArchScore = 2
+
+// ScoreRule is the contribution of a single scoring
+// rule to the score of an asset
+type ScoreRule struct {
+	// Rule is one of os, arch, arm, fallback arch, extension, name, universal, libc or appimage
+	Rule string
+	// Match is the part of the asset name which matched the rule
+	Match  string
+	Points int
+}
+
+// ScoredAsset is an asset along with the breakdown of its score
+type ScoredAsset struct {
+	*Asset
+	Score  int
+	Rules  []ScoreRule
+	Format string
+	// Emulated is set for the assets of the architecture
+	// the host runs through emulation
+	Emulated bool
+	// Excluded is the reason why the asset isn't a candidate at all
+	Excluded string
+}
+
+func (s *ScoredAsset) hasRule(rule string) bool {
+	for _, r := range s.Rules {
+		if r.Rule == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// scoreReporter receives the scored candidates of every filtered
+// release, it's used to debug wrong picks. It's called with
+// scoreReporterMu held so concurrent fetches report one at a time.
+// nolint: gochecknoglobals
+var (
+	scoreReporter   func(repoName string, scored []*ScoredAsset)
+	scoreReporterMu sync.Mutex
+)
+
+// SetScoreReporter sets the function receiving the scored
+// candidates every time the assets of a release are filtered.
+// The calls are serialized, the reporter doesn't need to be
+// safe for concurrent use.
+func SetScoreReporter(r func(repoName string, scored []*ScoredAsset)) {
+	scoreReporterMu.Lock()
+	defer scoreReporterMu.Unlock()
+	scoreReporter = r
+}
+
+// reportScores passes the scored candidates to the score reporter, if any
+func (f *Filter) reportScores(repoName string, scored []*ScoredAsset) {
+	scoreReporterMu.Lock()
+	defer scoreReporterMu.Unlock()
+	if scoreReporter == nil {
+		return
+	}
+	for _, a := range f.sideFiles {
+		scored = append(scored, &ScoredAsset{Asset: a, Format: Format(a.Name), Excluded: "checksum, signature, SBOM or source"})
+	}
+	scoreReporter(repoName, scored)
+}
+
+// dropEmulated removes the candidates of the emulated architecture
+func dropEmulated(matches []*FilteredAsset) []*FilteredAsset {
+	kept := matches[:0]
+	for _, m := range matches {
+		if m.emulated {
+			log.Debugf("Removing %v, there's a native build", m.Name)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// dropInstallers removes the disk images and installers when
+// there's another candidate for the platform, i.e. a tarball
+func dropInstallers(matches []*FilteredAsset) []*FilteredAsset {
+	kept := matches[:0]
+	for _, m := range matches {
+		if isMacOSInstaller(Format(m.Name)) {
+			log.Debugf("Removing %v, there's an archive or a binary", m.Name)
+			continue
+		}
+		kept = append(kept, m)
+	}
+	return kept
+}
+
+// Score scores the assets for the current platform and returns them
+// sorted by score, the excluded assets come last
+func (f *Filter) Score(repoName string, as []*Asset) []*ScoredAsset {
+	type rule struct {
+		name   string
+		points int
+	}
+	// later rules take precedence, i.e. when the repo is named after the OS
+	rules := map[string]rule{}
+	rules[strings.ToLower(repoName)] = rule{"name", 1}
+	for _, os := range resolver.GetOS() {
+		rules[strings.ToLower(os)] = rule{"os", 10}
+	}
+	for _, arch := range resolver.GetArch() {
+		rules[strings.ToLower(arch)] = rule{"arch", 5}
+	}
+	for _, ext := range resolver.GetOSSpecificExtensions() {
+		rules["."+strings.ToLower(ext)] = rule{"extension", 15}
+	}
+	keys := make([]string, 0, len(rules))
+	for k := range rules {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	libc := resolver.GetLibc()
+	arm := resolver.GetARMVersion()
+	archs := lower(resolver.GetArch())
+	fallback, fallbackEnabled := resolver.GetFallbackArch()
+	fallback = lower(fallback)
+	linux := slices.Contains(lower(resolver.GetOS()), "linux")
+	darwin := slices.Contains(lower(resolver.GetOS()), "darwin")
+
+	scored := make([]*ScoredAsset, 0, len(as))
+	for _, a := range as {
+		s := &ScoredAsset{Asset: a, Format: Format(a.Name)}
+		scored = append(scored, s)
+		name := strings.ToLower(a.Name)
+		switch {
+		case isSideFile(a.Name) && !f.opts.SideFiles:
+			s.Excluded = "checksum, signature, SBOM or source"
+			continue
+		case !isSupportedExt(a.Name) && !f.preferred(a.Name) && !f.opts.Files:
+			s.Excluded = "unsupported format"
+			continue
+		case (s.Format == "deb" || s.Format == "rpm") && !linux:
+			s.Excluded = "Linux package"
+			continue
+		case isMacOSInstaller(s.Format) && !darwin:
+			s.Excluded = "macOS disk image or installer"
+			continue
+		case !bstrings.ContainsAny(name, keys) && !f.opts.Files && !(f.opts.Relax >= RelaxRaw && rawBinary(a.Name)):
+			s.Excluded = "no platform match"
+			continue
+		}
+
+		var armPoints int
+		if arm != 0 {
+			if armPoints, s.Excluded = armScore(a.Name, arm); s.Excluded != "" {
+				continue
+			}
+		}
+
+		emulated := len(fallback) > 0 && !bstrings.ContainsAny(name, archs) && bstrings.ContainsAny(name, fallback)
+		if emulated && !fallbackEnabled && f.opts.Relax < RelaxEmulated {
+			s.Excluded = fallback[0] + " build, the arch fallback is disabled"
+			continue
+		}
+
+		for _, k := range keys {
+			if strings.Contains(name, k) {
+				r := rules[k]
+				s.Rules = append(s.Rules, ScoreRule{Rule: r.name, Match: k, Points: r.points})
+				s.Score += r.points
+			}
+		}
+		if f.opts.RequirePlatform && !f.opts.Files && !s.hasRule("os") && !s.hasRule("extension") {
+			// the arch aliases are too loose, i.e. 64 matches ppc64
+			s.Excluded, s.Rules, s.Score = "no OS match", nil, 0
+			continue
+		}
+		if armPoints > 0 {
+			s.Rules = append(s.Rules, ScoreRule{Rule: "arm", Match: "armv" + strconv.Itoa(armVersion(name)), Points: armPoints})
+			s.Score += armPoints
+		}
+		if emulated {
+			s.Rules = append(s.Rules, ScoreRule{Rule: "fallback arch", Match: fallback[0], Points: fallbackArchScore})
+			s.Score += fallbackArchScore
+			s.Emulated = true
+		}
+		if p := universalScore(a.Name, resolver.GetOS(), resolver.GetArch()); p > 0 {
+			s.Rules = append(s.Rules, ScoreRule{Rule: "universal", Points: p})
+			s.Score += p
+		}
+		if p := libcScore(a.Name, libc); p != 0 && s.Score > 0 {
+			// the libc only breaks ties, it never discards a candidate
+			p = max(1, s.Score+p) - s.Score
+			s.Rules = append(s.Rules, ScoreRule{Rule: "libc", Match: libc, Points: p})
+			s.Score += p
+		}
+		if s.Format == FormatAppImage && !linux && s.Score > 0 {
+			// AppImages only run on Linux, they're kept as a last resort
+			p := max(1, s.Score+appImagePenalty) - s.Score
+			s.Rules = append(s.Rules, ScoreRule{Rule: "appimage", Points: p})
+			s.Score += p
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		if (scored[i].Excluded == "") != (scored[j].Excluded == "") {
+			return scored[i].Excluded == ""
+		}
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].Name < scored[j].Name
+	})
+	return scored
+}
@@ -0,0 +1,174 @@
+package assets
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScore(t *testing.T) {
+	resolver = testLinuxMuslResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	as := []*Asset{
+		{Name: "bottom_x86_64-unknown-linux-gnu.tar.gz"},
+		{Name: "bottom_x86_64-unknown-linux-musl.tar.gz"},
+		{Name: "bottom_x86_64-unknown-linux-musl.tar.gz.sha256"},
+		{Name: "bottom_x86_64-pc-windows-msvc.msi"},
+		{Name: "bottom_aarch64-apple-darwin.tar.gz"},
+	}
+	scored := NewFilter(&FilterOpts{}).Score("bottom", as)
+
+	cases := []struct {
+		name     string
+		score    int
+		rules    []ScoreRule
+		excluded string
+	}{
+		{
+			name:  "bottom_x86_64-unknown-linux-musl.tar.gz",
+			score: 24,
+			rules: []ScoreRule{
+				{Rule: "arch", Match: "64", Points: 5},
+				{Rule: "name", Match: "bottom", Points: 1},
+				{Rule: "os", Match: "linux", Points: 10},
+				{Rule: "arch", Match: "x86_64", Points: 5},
+				{Rule: "libc", Match: "musl", Points: 3},
+			},
+		},
+		{
+			name:  "bottom_x86_64-unknown-linux-gnu.tar.gz",
+			score: 21,
+			rules: []ScoreRule{
+				{Rule: "arch", Match: "64", Points: 5},
+				{Rule: "name", Match: "bottom", Points: 1},
+				{Rule: "os", Match: "linux", Points: 10},
+				{Rule: "arch", Match: "x86_64", Points: 5},
+			},
+		},
+		{
+			name:  "bottom_aarch64-apple-darwin.tar.gz",
+			score: 6,
+			rules: []ScoreRule{
+				{Rule: "arch", Match: "64", Points: 5},
+				{Rule: "name", Match: "bottom", Points: 1},
+			},
+		},
+		{name: "bottom_x86_64-pc-windows-msvc.msi", excluded: "unsupported format"},
+		{name: "bottom_x86_64-unknown-linux-musl.tar.gz.sha256", excluded: "checksum, signature, SBOM or source"},
+	}
+
+	if len(scored) != len(cases) {
+		t.Fatalf("Expected %d scored assets, got %d", len(cases), len(scored))
+	}
+	for i, c := range cases {
+		s := scored[i]
+		if s.Name != c.name || s.Score != c.score || s.Excluded != c.excluded {
+			t.Errorf("Expected %s scored %d (excluded %q) at position %d, got %s scored %d (excluded %q)", c.name, c.score, c.excluded, i, s.Name, s.Score, s.Excluded)
+		}
+		if !reflect.DeepEqual(s.Rules, c.rules) {
+			t.Errorf("Expected rules %v for %s, got %v", c.rules, c.name, s.Rules)
+		}
+	}
+}
+
+func TestFilterAssetsReportsScores(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	var reported []*ScoredAsset
+	SetScoreReporter(func(repoName string, scored []*ScoredAsset) {
+		reported = scored
+	})
+	defer SetScoreReporter(nil)
+
+	as := []*Asset{
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "tool_darwin_amd64.tar.gz"},
+	}
+	gf, err := NewFilter(&FilterOpts{}).FilterAssets("tool", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 2 || reported[0].Name != gf.Name {
+		t.Errorf("Expected the picked asset %s to be reported first, got %v", gf.Name, reported)
+	}
+}
+
+func TestFilterAssetsFallbackArch(t *testing.T) {
+	rosetta := &mockOSResolver{OS: []string{"darwin", "macos", "osx"}, Arch: []string{"arm64", "aarch64"}, FallbackArch: []string{"amd64", "x86_64", "x64"}}
+	noFallback := &mockOSResolver{OS: []string{"darwin", "macos", "osx"}, Arch: []string{"arm64", "aarch64"}, FallbackArch: []string{"amd64", "x86_64", "x64"}, NoFallbackArch: true}
+
+	cases := []struct {
+		desc     string
+		resolver platformResolver
+		in       []string
+		out      string
+		emulated string
+	}{
+		{"amd64 only", rosetta, []string{"tool_darwin_amd64.tar.gz", "tool_linux_amd64.tar.gz", "tool_windows_amd64.zip"}, "tool_darwin_amd64.tar.gz", "amd64"},
+		{"x86_64 only", rosetta, []string{"tool-x86_64-apple-darwin.tar.gz", "tool-x86_64-unknown-linux-gnu.tar.gz"}, "tool-x86_64-apple-darwin.tar.gz", "amd64"},
+		{"native build", rosetta, []string{"tool_darwin_amd64.tar.gz", "tool_darwin_arm64.tar.gz"}, "tool_darwin_arm64.tar.gz", ""},
+		{"universal build", rosetta, []string{"tool_darwin_amd64.tar.gz", "tool_darwin_universal.tar.gz"}, "tool_darwin_universal.tar.gz", ""},
+		{"build without arch", rosetta, []string{"tool_darwin_amd64.tar.gz", "tool_darwin.tar.gz"}, "tool_darwin.tar.gz", ""},
+		{"fallback disabled", noFallback, []string{"tool_darwin_amd64.tar.gz", "tool_windows_amd64.zip"}, "", ""},
+	}
+
+	defer func() { resolver = runtimeResolver{} }()
+	for _, c := range cases {
+		resolver = c.resolver
+		as := make([]*Asset, 0, len(c.in))
+		for _, n := range c.in {
+			as = append(as, &Asset{Name: n})
+		}
+		f := NewFilter(&FilterOpts{})
+		gf, err := f.FilterAssets("tool", as)
+		if c.out == "" {
+			if err == nil {
+				t.Errorf("%s: expected no asset, got %s", c.desc, gf.Name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.desc, err)
+			continue
+		}
+		if gf.Name != c.out || f.Emulated() != c.emulated {
+			t.Errorf("%s: expected %s (emulated %q), got %s (emulated %q)", c.desc, c.out, c.emulated, gf.Name, f.Emulated())
+		}
+	}
+}
+
+func TestFilterAssetsFiles(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	// nothing matches the platform and fonts aren't a supported format
+	as := []*Asset{
+		{Name: "JetBrainsMono-Regular.ttf"},
+		{Name: "SHA256SUMS"},
+	}
+	if _, err := NewFilter(&FilterOpts{}).FilterAssets("nerd-fonts", append(as, &Asset{Name: "Hack-Regular.ttf"})); err == nil {
+		t.Fatal("expected no binary to be found")
+	}
+	a, err := NewFilter(&FilterOpts{Files: true}).FilterAssets("nerd-fonts", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "JetBrainsMono-Regular.ttf" {
+		t.Fatalf("expected the font to be picked, got %s", a.Name)
+	}
+
+	// the platform still breaks ties
+	as = []*Asset{
+		{Name: "tzdata-darwin.tar.gz"},
+		{Name: "tzdata-linux.tar.gz"},
+		{Name: "tzdata.tar.gz.asc"},
+	}
+	a, err = NewFilter(&FilterOpts{Files: true}).FilterAssets("tzdata", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Name != "tzdata-linux.tar.gz" {
+		t.Fatalf("expected the linux snapshot to be picked, got %s", a.Name)
+	}
+}
@@ -0,0 +1,81 @@
+package assets
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// Selector matches asset names against a user provided
+// pattern. The pattern can be an exact name, a glob
+// (`tool-*-linux-amd64.tar.gz`) or a regex wrapped
+// in slashes (`/^tool-.*-linux-amd64\.tar\.gz$/`).
+type Selector struct {
+	pattern string
+	re      *regexp.Regexp
+	glob    bool
+}
+
+// NewSelector validates the given pattern. It returns
+// nil if the pattern is empty
+func NewSelector(pattern string) (*Selector, error) {
+	if pattern == "" {
+		return nil, nil
+	}
+
+	s := &Selector{pattern: pattern}
+	switch {
+	case len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/"):
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid asset regex %s: %w", pattern, err)
+		}
+		s.re = re
+	case strings.ContainsAny(pattern, "*?["):
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid asset glob %s: %w", pattern, err)
+		}
+		s.glob = true
+	}
+
+	return s, nil
+}
+
+// Match checks if the asset name matches the pattern
+func (s *Selector) Match(name string) bool {
+	switch {
+	case s == nil:
+		return true
+	case s.re != nil:
+		return s.re.MatchString(name)
+	case s.glob:
+		ok, _ := path.Match(s.pattern, name)
+		return ok
+	default:
+		return s.pattern == name
+	}
+}
+
+func (s *Selector) String() string {
+	if s == nil {
+		return ""
+	}
+	return s.pattern
+}
+
+// SelectionPattern returns a pattern matching the asset picked by the
+// user in the next releases, the version in its name is replaced by
+// a wildcard
+func SelectionPattern(name, version string) string {
+	p := name
+	for _, c := range []string{`\`, "*", "?", "["} {
+		p = strings.ReplaceAll(p, c, `\`+c)
+	}
+	for _, v := range []string{version, strings.TrimPrefix(version, "v")} {
+		if v != "" && strings.Contains(p, v) {
+			return strings.ReplaceAll(p, v, "*")
+		}
+	}
+	return p
+}
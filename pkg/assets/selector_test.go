@@ -0,0 +1,93 @@
+package assets
+
+import "testing"
+
+func TestSelector(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		match         bool
+	}{
+		{"tool-1.2.3-linux-amd64.tar.gz", "tool-1.2.3-linux-amd64.tar.gz", true},
+		{"tool-1.2.3-linux-amd64.tar.gz", "tool-1.2.4-linux-amd64.tar.gz", false},
+		{"tool-*-linux-amd64.tar.gz", "tool-1.2.4-linux-amd64.tar.gz", true},
+		{"tool-*-linux-amd64.tar.gz", "tool-1.2.4-linux-arm64.tar.gz", false},
+		{"tool-?.?.?-linux-*", "tool-1.2.4-linux-arm64.tar.gz", true},
+		{`/^tool-\d+\.\d+\.\d+-linux-amd64\.tar\.gz$/`, "tool-1.20.4-linux-amd64.tar.gz", true},
+		{`/^tool-\d+\.\d+\.\d+-linux-amd64\.tar\.gz$/`, "tool-1.20.4-linux-amd64.tar.gz.sha256", false},
+	}
+
+	for _, c := range cases {
+		s, err := NewSelector(c.pattern)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if m := s.Match(c.name); m != c.match {
+			t.Errorf("%s matching %s: expected %v, got %v", c.pattern, c.name, c.match, m)
+		}
+	}
+
+	for _, invalid := range []string{"/(/", "tool-[-linux"} {
+		if _, err := NewSelector(invalid); err == nil {
+			t.Errorf("expected an error for %s", invalid)
+		}
+	}
+
+	if s, _ := NewSelector(""); s != nil || !s.Match("anything") {
+		t.Error("expected an empty pattern to match everything")
+	}
+}
+
+func TestSelectionPattern(t *testing.T) {
+	cases := []struct {
+		name, version, pattern string
+		next                   string
+	}{
+		{"tool_1.2.3_linux_amd64.tar.gz", "v1.2.3", "tool_*_linux_amd64.tar.gz", "tool_1.3.0_linux_amd64.tar.gz"},
+		{"tool-v1.2.3-linux-amd64", "v1.2.3", "tool-*-linux-amd64", "tool-v1.3.0-linux-amd64"},
+		{"tool-linux-amd64-gnu", "v1.2.3", "tool-linux-amd64-gnu", "tool-linux-amd64-gnu"},
+		{"tool[x]-1.2.3", "1.2.3", `tool\[x]-*`, "tool[x]-1.3.0"},
+	}
+
+	for _, c := range cases {
+		p := SelectionPattern(c.name, c.version)
+		if p != c.pattern {
+			t.Errorf("%s %s: expected %s, got %s", c.name, c.version, c.pattern, p)
+			continue
+		}
+		s, err := NewSelector(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !s.Match(c.name) || !s.Match(c.next) {
+			t.Errorf("expected %s to match %s and %s", p, c.name, c.next)
+		}
+	}
+}
+
+func TestFilterAssetsSelected(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	as := []*Asset{
+		{Name: "tool_1.3.0_linux_amd64_gnu.tar.gz"},
+		{Name: "tool_1.3.0_linux_amd64_musl.tar.gz"},
+		{Name: "tool_1.3.0_darwin_amd64.tar.gz"},
+	}
+
+	f := NewFilter(&FilterOpts{Selected: "tool_*_linux_amd64_musl.tar.gz"})
+	got, err := f.FilterAssets("tool", as)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "tool_1.3.0_linux_amd64_musl.tar.gz" || f.Picked() {
+		t.Fatalf("expected the previous choice to be used without asking, got %s", got.Name)
+	}
+
+	// the choice is ignored once nothing matches it
+	f = NewFilter(&FilterOpts{Selected: "tool_*_linux_x86_64.tar.gz"})
+	got, err = f.FilterAssets("tool", as[1:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "tool_1.3.0_linux_amd64_musl.tar.gz" {
+		t.Fatalf("expected the scoring to be used, got %s", got.Name)
+	}
+}
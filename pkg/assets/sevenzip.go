@@ -0,0 +1,81 @@
+package assets
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"path"
+
+	"github.com/bodgit/sevenzip"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// processSevenZip extracts the file to install from a 7z archive,
+// which can only be read once it's fully downloaded
+func (f *Filter) processSevenZip(name string, r io.Reader) (out *finalFile, err error) {
+	// the decoders might panic on corrupted archives
+	defer func() {
+		if p := recover(); p != nil {
+			out, err = nil, fmt.Errorf("corrupted 7z archive: %v", p)
+		}
+	}()
+
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := sevenzip.NewReader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid 7z archive: %w", err)
+	}
+
+	szFiles, extras := map[string][]byte{}, map[string][]byte{}
+	metas := map[string]*EntryMeta{}
+	if len(f.opts.PackagePath) > 0 {
+		log.Debugf("Processing tag with PackagePath %s\n", f.opts.PackagePath)
+	}
+	for _, zf := range zr.File {
+		if zf.FileInfo().IsDir() {
+			continue
+		}
+
+		entry, ok := entryName(zf.Name)
+		if !ok {
+			continue
+		}
+
+		extra := f.wantsExtra(entry)
+		if !extra && !f.opts.SkipPathCheck && len(f.opts.PackagePath) > 0 && entry != f.opts.PackagePath && zf.Name != f.opts.PackagePath {
+			continue
+		}
+
+		fr, err := zf.Open()
+		if err != nil {
+			return nil, fmt.Errorf("invalid 7z archive: %w", err)
+		}
+		bs, err := io.ReadAll(fr)
+		fr.Close()
+		if err != nil {
+			return nil, fmt.Errorf("corrupted 7z archive: %w", err)
+		}
+		if extra {
+			extras[entry] = bs
+			continue
+		}
+		szFiles[entry] = bs
+		metas[entry] = &EntryMeta{Path: entry, Mode: zf.Mode(), ModTime: zf.Modified}
+	}
+	if len(szFiles) == 0 {
+		return nil, fmt.Errorf("no files found in 7z archive. PackagePath [%s]", f.opts.PackagePath)
+	}
+
+	choice, err := f.pickEntry(name, entryAssets(szFiles))
+	if err != nil {
+		return nil, err
+	}
+	selectedFile := choice.String()
+	f.collectExtras(extras, path.Base(selectedFile))
+	f.entry = metas[selectedFile]
+
+	return &finalFile{Name: path.Base(selectedFile), Source: bytes.NewReader(szFiles[selectedFile]), PackagePath: selectedFile}, nil
+}
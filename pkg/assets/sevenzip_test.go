@@ -0,0 +1,50 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestProcessReaderSevenZip(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	// the archives come from github.com/bodgit/sevenzip, they
+	// hold the same 10 files named 01 to 10
+	for _, name := range []string{"lzma2.7z", "bcj2.7z"} {
+		t.Run(name, func(t *testing.T) {
+			b, err := os.ReadFile("testdata/" + name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, err := NewFilter(&FilterOpts{PackagePath: "05"}).ProcessReader(name, bytes.NewReader(b))
+			if err != nil {
+				t.Fatal(err)
+			}
+			content, err := io.ReadAll(out.Source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sum := fmt.Sprintf("%x", sha256.Sum256(content)); sum != "b5b0854b285cd124000aa1da114da35624c9dadd549a3eec8fb9c6dfd4c3645a" || out.PackagePath != "05" {
+				t.Fatalf("expected 05 to be extracted, got %s with digest %s", out.PackagePath, sum)
+			}
+
+			// the headers are at the end of the archive
+			truncated := b[:len(b)/2]
+			if _, err := NewFilter(&FilterOpts{PackagePath: "05"}).ProcessReader(name, bytes.NewReader(truncated)); err == nil {
+				t.Fatal("expected an error for a truncated archive")
+			}
+			corrupted := bytes.Clone(b)
+			for i := 32; i < len(corrupted)-200; i += 7 {
+				corrupted[i] ^= 0xff
+			}
+			if _, err := NewFilter(&FilterOpts{PackagePath: "05"}).ProcessReader(name, bytes.NewReader(corrupted)); err == nil {
+				t.Fatal("expected an error for a corrupted archive")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+package assets
+
+import (
+	"strings"
+)
+
+// sideFileSuffixes are the checksums, signatures, SBOMs and provenance
+// attestations published along the assets, they're never installed
+var sideFileSuffixes = []string{
+	".sha256", ".sha256sum", ".sha512", ".sha512sum", ".sha384", ".sha1", ".sha1sum", ".md5", ".b2", ".b3", ".blake2b", ".blake3",
+	".sig", ".minisig", ".asc", ".gpg", ".pem", ".cert", ".crt", ".pub", ".sigstore", ".sigstore.json", ".bundle",
+	".sbom", ".spdx", ".spdx.json", ".cdx.json", ".cyclonedx.json", ".cyclonedx.xml", ".sbom.json", ".bom.json",
+	".intoto.jsonl", ".intoto.json", ".provenance", ".att",
+}
+
+// checksumListNames are the names, without extension, of the files
+// listing the checksums of every asset
+var checksumListNames = []string{"checksums", "checksum", "sha256sums", "sha512sums", "shasums", "shasums256", "sha256sum", "sha512sum", "sha1sums", "b2sums", "b3sums"}
+
+// sourceArchiveTokens mark the archives of the source code,
+// they're only matched right before the archive extension
+// so tools like sourcegraph's `src` aren't dropped
+var sourceArchiveTokens = []string{"-src", "_src", ".src", "-source", "_source", "-sources", "_sources", "-vendor", "_vendor", "-vendored", "_vendored"}
+
+// isSideFile checks if the asset is a checksum, signature, SBOM
+// or provenance of another asset, or the source code archive
+func isSideFile(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sideFileSuffixes {
+		if strings.HasSuffix(name, s) {
+			return true
+		}
+	}
+	base := strings.TrimSuffix(strings.TrimSuffix(name, ".txt"), ".asc")
+	for _, n := range checksumListNames {
+		if strings.HasSuffix(base, n) {
+			return true
+		}
+	}
+	if sfx := archiveSuffix(name); sfx != "" {
+		stem := strings.TrimSuffix(name, sfx)
+		for _, t := range sourceArchiveTokens {
+			if strings.HasSuffix(stem, t) || stem == strings.TrimLeft(t, "-_.") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// archiveSuffix returns the suffix giving
+// the archive format of the name
+func archiveSuffix(name string) string {
+	for _, s := range formatSuffixes {
+		if strings.HasSuffix(name, s.suffix) {
+			return s.suffix
+		}
+	}
+	return ""
+}
+
+// dropSideFiles removes the side files from the candidates, unless
+// asked to keep them. They're kept aside so they can still be used,
+// i.e. to verify the picked asset.
+func (f *Filter) dropSideFiles(as []*Asset) []*Asset {
+	if f.opts.SideFiles {
+		return as
+	}
+	kept := make([]*Asset, 0, len(as))
+	for _, a := range as {
+		if isSideFile(a.Name) {
+			f.sideFiles = append(f.sideFiles, a)
+			continue
+		}
+		kept = append(kept, a)
+	}
+	return kept
+}
+
+// SideFiles returns the checksums, signatures, SBOMs, ... of
+// the release dropped from the candidates
+func (f *Filter) SideFiles() []*Asset {
+	return f.sideFiles
+}
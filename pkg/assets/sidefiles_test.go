@@ -0,0 +1,124 @@
+package assets
+
+import (
+	"testing"
+)
+
+func TestIsSideFile(t *testing.T) {
+	cases := []struct {
+		in  string
+		out bool
+	}{
+		{"tool.tar.gz.sha256", true},
+		{"checksums.txt", true},
+		{"tool_1.0_SHA256SUMS", true},
+		{"tool.tar.gz.sig", true},
+		{"tool.spdx.json", true},
+		{"tool.intoto.jsonl", true},
+		{"tool_linux_amd64.tar.gz", false},
+		{"tool-linux-amd64", false},
+		{"source.tar.gz", true},
+		{"tool-1.0-src.tar.gz", true},
+		{"tool-1.0-vendor.tar.xz", true},
+		{"tool_1.0_source.zip", true},
+	}
+	for _, c := range cases {
+		if got := isSideFile(c.in); got != c.out {
+			t.Errorf("isSideFile(%s): expected %v, got %v", c.in, c.out, got)
+		}
+	}
+}
+
+// TestIsSideFileReleases checks the assets of a few popular
+// releases so the binaries with unusual names aren't dropped
+func TestIsSideFileReleases(t *testing.T) {
+	cases := []struct {
+		release   string
+		binaries  []string
+		sideFiles []string
+	}{
+		{
+			release:   "goreleaser/goreleaser",
+			binaries:  []string{"goreleaser_Linux_x86_64.tar.gz", "goreleaser_1.21.0_amd64.deb", "goreleaser_Windows_x86_64.zip"},
+			sideFiles: []string{"checksums.txt", "checksums.txt.pem", "checksums.txt.sig", "goreleaser_Linux_x86_64.tar.gz.sbom.json"},
+		},
+		{
+			release:   "sigstore/cosign",
+			binaries:  []string{"cosign-linux-amd64", "cosign-darwin-arm64", "cosign-windows-amd64.exe"},
+			sideFiles: []string{"cosign-linux-amd64.sig", "cosign-linux-amd64-keyless.pem", "cosign-linux-amd64.sigstore.json", "cosign_checksums.txt", "multiple.intoto.jsonl"},
+		},
+		{
+			release:   "BurntSushi/ripgrep",
+			binaries:  []string{"ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz", "ripgrep_14.1.0-1_amd64.deb"},
+			sideFiles: []string{"ripgrep-14.1.0-x86_64-unknown-linux-musl.tar.gz.sha256", "ripgrep_14.1.0-1_amd64.deb.sha256"},
+		},
+		{
+			release:   "helm/helm",
+			binaries:  []string{"helm-v3.14.0-linux-amd64.tar.gz"},
+			sideFiles: []string{"helm-v3.14.0-linux-amd64.tar.gz.asc", "helm-v3.14.0-linux-amd64.tar.gz.sha256sum", "helm-v3.14.0-linux-amd64.tar.gz.sha256sum.asc"},
+		},
+		{
+			release:   "hashicorp/terraform",
+			binaries:  []string{"terraform_1.7.0_linux_amd64.zip"},
+			sideFiles: []string{"terraform_1.7.0_SHA256SUMS", "terraform_1.7.0_SHA256SUMS.sig", "terraform_1.7.0_SHA256SUMS.72D7468F.sig"},
+		},
+		{
+			release:   "sourcegraph/src-cli",
+			binaries:  []string{"src_linux_amd64", "src_darwin_arm64", "src_windows_amd64.exe", "src-cli_5.3.0_linux_amd64.tar.gz"},
+			sideFiles: []string{"src-cli_5.3.0_checksums.txt"},
+		},
+		{
+			release:   "openshift/source-to-image",
+			binaries:  []string{"source-to-image-v1.3.9-linux-amd64.tar.gz"},
+			sideFiles: []string{"source-to-image-v1.3.9-src.tar.gz"},
+		},
+		{
+			release:   "jedisct1/minisign",
+			binaries:  []string{"minisign-0.11-linux.tar.gz", "minisign-0.11-win64.zip"},
+			sideFiles: []string{"minisign-0.11-linux.tar.gz.minisig"},
+		},
+	}
+	for _, c := range cases {
+		for _, b := range c.binaries {
+			if isSideFile(b) {
+				t.Errorf("%s: %s is a binary, it was taken as a side file", c.release, b)
+			}
+		}
+		for _, s := range c.sideFiles {
+			if !isSideFile(s) {
+				t.Errorf("%s: %s is a side file, it was taken as a binary", c.release, s)
+			}
+		}
+	}
+}
+
+func TestFilterAssetsSideFiles(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	as := []*Asset{
+		{Name: "tool_linux_amd64.tar.gz"},
+		{Name: "tool_linux_amd64.tar.gz.sha256"},
+		{Name: "checksums.txt"},
+		{Name: "tool_linux_amd64.tar.gz.sbom.json"},
+	}
+
+	for _, skipScoring := range []bool{false, true} {
+		f := NewFilter(&FilterOpts{SkipScoring: skipScoring})
+		gf, err := f.FilterAssets("tool", as)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if gf.Name != "tool_linux_amd64.tar.gz" {
+			t.Errorf("Expected the binary to be picked (skip scoring %v), got %s", skipScoring, gf.Name)
+		}
+		if len(f.SideFiles()) != 3 {
+			t.Errorf("Expected the side files to be kept aside, got %v", f.SideFiles())
+		}
+	}
+
+	f := NewFilter(&FilterOpts{SideFiles: true})
+	if got := f.dropSideFiles(as); len(got) != len(as) || len(f.SideFiles()) != 0 {
+		t.Errorf("Expected the side files to be kept as candidates, got %v", got)
+	}
+}
@@ -0,0 +1,184 @@
+package assets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// signatureSuffixes are the extensions of the detached OpenPGP
+// signatures. .sig files might be signatures of other tools (i.e.
+// cosign), they're skipped when they aren't OpenPGP ones.
+var signatureSuffixes = []string{".asc", ".gpg", ".sig"}
+
+// keyFileSuffixes are the extensions of the key files
+// read from the keyring directories
+var keyFileSuffixes = []string{".asc", ".gpg", ".pub", ".key"}
+
+// LoadKeyring reads the OpenPGP public keys of the files, armored or
+// not, and of the key files of the directories among them
+func LoadKeyring(paths []string) (openpgp.EntityList, error) {
+	var keyring openpgp.EntityList
+	for _, p := range paths {
+		fi, err := os.Stat(p)
+		if err != nil {
+			return nil, fmt.Errorf("error reading the signing keys: %w", err)
+		}
+		files := []string{p}
+		if fi.IsDir() {
+			entries, err := os.ReadDir(p)
+			if err != nil {
+				return nil, err
+			}
+			files = files[:0]
+			for _, e := range entries {
+				for _, s := range keyFileSuffixes {
+					if !e.IsDir() && strings.HasSuffix(e.Name(), s) {
+						files = append(files, filepath.Join(p, e.Name()))
+						break
+					}
+				}
+			}
+		}
+		for _, f := range files {
+			keys, err := readKeys(f)
+			if err != nil {
+				return nil, fmt.Errorf("error reading the signing key %s: %w", f, err)
+			}
+			keyring = append(keyring, keys...)
+		}
+	}
+	return keyring, nil
+}
+
+func readKeys(path string) (openpgp.EntityList, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if isArmored(b) {
+		return openpgp.ReadArmoredKeyRing(bytes.NewReader(b))
+	}
+	return openpgp.ReadKeyRing(bytes.NewReader(b))
+}
+
+func isArmored(b []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(b), []byte("-----BEGIN PGP"))
+}
+
+// keyring returns the signing keys of the binary, nil when none is configured
+func (f *Filter) keyring() (openpgp.EntityList, error) {
+	if len(f.opts.SigningKeys) == 0 {
+		return nil, nil
+	}
+	if f.keys == nil {
+		keys, err := LoadKeyring(f.opts.SigningKeys)
+		if err != nil {
+			return nil, err
+		}
+		if len(keys) == 0 {
+			return nil, fmt.Errorf("no OpenPGP key found in %s", strings.Join(f.opts.SigningKeys, ", "))
+		}
+		f.keys = keys
+	}
+	return f.keys, nil
+}
+
+// signatureOf returns the detached signature published along the file
+func (f *Filter) signatureOf(name string) *Asset {
+	for _, s := range signatureSuffixes {
+		for _, a := range f.sideFiles {
+			if a.Name == name+s {
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// verifySignature checks the detached signature of the file published
+// along it, if any, against the signing keys. It returns the id of the
+// key which signed it, empty when there's no key or no signature.
+func (f *Filter) verifySignature(name string, content []byte, headers map[string]string) (string, error) {
+	keys, err := f.keyring()
+	if err != nil || keys == nil {
+		return "", err
+	}
+	sig := f.signatureOf(name)
+	if sig == nil {
+		return "", nil
+	}
+	b, err := f.sideFile(sig, headers)
+	if err != nil {
+		return "", fmt.Errorf("error downloading the signature %s: %w", sig.Name, err)
+	}
+
+	var signer *openpgp.Entity
+	if isArmored(b) {
+		signer, err = openpgp.CheckArmoredDetachedSignature(keys, bytes.NewReader(content), bytes.NewReader(b), nil)
+	} else {
+		signer, err = openpgp.CheckDetachedSignature(keys, bytes.NewReader(content), bytes.NewReader(b), nil)
+	}
+	if err != nil {
+		if strings.HasSuffix(sig.Name, ".sig") && notOpenPGP(err) {
+			log.Debugf("%s isn't an OpenPGP signature, skipping it", sig.Name)
+			return "", nil
+		}
+		return "", fmt.Errorf("invalid signature %s of %s: %w", sig.Name, name, err)
+	}
+	return signer.PrimaryKey.KeyIdString(), nil
+}
+
+// notOpenPGP reports whether the error is the one of
+// a signature which isn't in the OpenPGP format
+func notOpenPGP(err error) bool {
+	var structural pgperrors.StructuralError
+	var unsupported pgperrors.UnsupportedError
+	return errors.As(err, &structural) || errors.As(err, &unsupported) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// checkSignature makes sure the asset is signed, through the signature
+// of the checksum file which verified it or its own signature. It fails
+// if signatures are required and it isn't.
+func (f *Filter) checkSignature(gf *FilteredAsset, b []byte) error {
+	if f.signedBy != "" && f.verified != "" {
+		return nil
+	}
+	f.signedBy, f.signedFile = "", ""
+	id, err := f.verifySignature(gf.Name, b, gf.ExtraHeaders)
+	if err != nil {
+		return err
+	}
+	if id != "" {
+		log.WithField("asset", gf.Name).WithField("signer", id).Info("Signature verified")
+		f.signedBy, f.signedFile = id, gf.Name
+		return nil
+	}
+	if f.opts.RequireSignature {
+		if len(f.opts.SigningKeys) == 0 {
+			return fmt.Errorf("signatures are required but no signing key is configured to verify %s", gf.Name)
+		}
+		return fmt.Errorf("neither %s nor a checksum file verifying it has a valid signature and signatures are required", gf.Name)
+	}
+	return nil
+}
+
+// SignedBy returns the id of the key which signed the asset, or
+// the checksum file which verified it, empty if it wasn't signed
+func (f *Filter) SignedBy() string {
+	return f.signedBy
+}
+
+// SignedFile returns the name of the file whose signature was
+// verified, either the asset or its checksum file
+func (f *Filter) SignedFile() string {
+	return f.signedFile
+}
@@ -0,0 +1,112 @@
+package assets
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+)
+
+func TestProcessURLSignatures(t *testing.T) {
+	resolver = testLinuxAMDResolver
+	defer func() { resolver = runtimeResolver{} }()
+
+	key, err := openpgp.NewEntity("bin", "", "bin@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := openpgp.NewEntity("other", "", "other@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "bin.asc")
+	var pub bytes.Buffer
+	w, err := armor.Encode(&pub, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	if err := os.WriteFile(keyFile, pub.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sign := func(e *openpgp.Entity, b []byte) []byte {
+		var sig bytes.Buffer
+		if err := openpgp.ArmoredDetachSign(&sig, e, bytes.NewReader(b), nil); err != nil {
+			t.Fatal(err)
+		}
+		return sig.Bytes()
+	}
+
+	content := []byte("#!/bin/sh\necho tool\n")
+	sums := []byte(fmt.Sprintf("%x  tool_linux_amd64\n", sha256.Sum256(content)))
+	tampered := []byte(fmt.Sprintf("%x  tool_linux_amd64\n", sha256.Sum256([]byte("evil"))))
+
+	cases := []struct {
+		desc     string
+		files    map[string][]byte
+		keys     []string
+		require  bool
+		signedBy string
+		signed   string
+		err      string
+	}{
+		{"signed checksum file", map[string][]byte{"SHA256SUMS": sums, "SHA256SUMS.asc": sign(key, sums)}, []string{keyFile}, true, key.PrimaryKey.KeyIdString(), "SHA256SUMS", ""},
+		{"keyring directory", map[string][]byte{"SHA256SUMS": sums, "SHA256SUMS.asc": sign(key, sums)}, []string{dir}, true, key.PrimaryKey.KeyIdString(), "SHA256SUMS", ""},
+		{"tampered checksum file", map[string][]byte{"SHA256SUMS": tampered, "SHA256SUMS.asc": sign(key, sums)}, []string{keyFile}, false, "", "", "invalid signature SHA256SUMS.asc of SHA256SUMS"},
+		{"unknown key", map[string][]byte{"SHA256SUMS": sums, "SHA256SUMS.asc": sign(other, sums)}, []string{keyFile}, false, "", "", "invalid signature"},
+		{"signed asset", map[string][]byte{"tool_linux_amd64.asc": sign(key, content)}, []string{keyFile}, true, key.PrimaryKey.KeyIdString(), "tool_linux_amd64", ""},
+		{"unsigned", map[string][]byte{"SHA256SUMS": sums}, []string{keyFile}, true, "", "", "has a valid signature and signatures are required"},
+		{"no key", map[string][]byte{"SHA256SUMS": sums, "SHA256SUMS.asc": sign(key, sums)}, nil, true, "", "", "no signing key is configured"},
+		{"not required", map[string][]byte{"SHA256SUMS": sums}, []string{keyFile}, false, "", "", ""},
+		{"cosign signature", map[string][]byte{"SHA256SUMS": sums, "SHA256SUMS.sig": []byte("MEUCIQDcosign")}, []string{keyFile}, false, "", "", ""},
+	}
+
+	for _, c := range cases {
+		ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			name := strings.TrimPrefix(r.URL.Path, "/")
+			if name == "tool_linux_amd64" {
+				w.Write(content)
+				return
+			}
+			b, ok := c.files[name]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(b)
+		}))
+
+		as := []*Asset{{Name: "tool_linux_amd64", URL: ts.URL + "/tool_linux_amd64"}}
+		for name := range c.files {
+			as = append(as, &Asset{Name: name, URL: ts.URL + "/" + name})
+		}
+		f := NewFilter(&FilterOpts{SigningKeys: c.keys, RequireSignature: c.require})
+		gf, err := f.FilterAssets("tool", as)
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		_, err = f.ProcessURL(gf)
+		ts.Close()
+
+		switch {
+		case c.err == "" && err != nil:
+			t.Errorf("%s: unexpected error %v", c.desc, err)
+		case c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)):
+			t.Errorf("%s: expected error %q, got %v", c.desc, c.err, err)
+		case c.err == "" && (f.SignedBy() != c.signedBy || f.SignedFile() != c.signed):
+			t.Errorf("%s: expected %q signed by %q, got %q signed by %q", c.desc, c.signed, c.signedBy, f.SignedFile(), f.SignedBy())
+		}
+	}
+}
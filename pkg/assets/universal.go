@@ -0,0 +1,61 @@
+package assets
+
+import (
+	"strings"
+	"unicode"
+
+	bstrings "github.com/marcosnils/bin/pkg/strings"
+)
+
+// universalArchScore is the arch score of the assets built for every
+// architecture, it's lower than the one of an exact match so the
+// builds of the host architecture win when both are published
+const universalArchScore = 4
+
+var (
+	darwinTokens    = []string{"darwin", "macos", "osx"}
+	universalTokens = []string{"universal", "universal2", "all", "fat"}
+	anyArchTokens   = []string{"any", "noarch"}
+)
+
+// universalScore returns the arch score of macOS universal binaries
+// on darwin hosts, and of architecture independent assets (scripts,
+// JVM launchers) everywhere. Assets naming an architecture of the
+// host already got their arch score.
+func universalScore(name string, oses, archs []string) int {
+	name = strings.ToLower(name)
+	if bstrings.ContainsAny(name, lower(archs)) {
+		return 0
+	}
+
+	tokens := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if hasAny(tokens, anyArchTokens) {
+		return universalArchScore
+	}
+	if hasAny(lower(oses), []string{"darwin"}) && hasAny(tokens, universalTokens) &&
+		bstrings.ContainsAny(name, darwinTokens) {
+		return universalArchScore
+	}
+	return 0
+}
+
+func hasAny(tokens, candidates []string) bool {
+	for _, t := range tokens {
+		for _, c := range candidates {
+			if t == c {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func lower(ss []string) []string {
+	res := make([]string, len(ss))
+	for i, s := range ss {
+		res[i] = strings.ToLower(s)
+	}
+	return res
+}
@@ -0,0 +1,91 @@
+package assets
+
+import "testing"
+
+var (
+	testDarwinARMResolver = &mockOSResolver{OS: []string{"darwin", "macos", "osx"}, Arch: []string{"arm64"}}
+	testDarwinAMDResolver = &mockOSResolver{OS: []string{"darwin", "macos", "osx"}, Arch: []string{"amd64", "x86_64", "x64"}}
+)
+
+func TestFilterAssetsUniversal(t *testing.T) {
+	temporal := []*Asset{
+		{Name: "temporal_cli_0.13.2_checksums.txt"},
+		{Name: "temporal_cli_0.13.2_darwin_all.tar.gz"},
+		{Name: "temporal_cli_0.13.2_linux_amd64.tar.gz"},
+		{Name: "temporal_cli_0.13.2_linux_arm64.tar.gz"},
+		{Name: "temporal_cli_0.13.2_windows_amd64.zip"},
+		{Name: "temporal_cli_0.13.2_windows_arm64.zip"},
+	}
+	lima := []*Asset{
+		{Name: "lima-0.21.0-Darwin-arm64.tar.gz"},
+		{Name: "lima-0.21.0-Darwin-universal.tar.gz"},
+		{Name: "lima-0.21.0-Darwin-x86_64.tar.gz"},
+		{Name: "lima-0.21.0-Linux-aarch64.tar.gz"},
+		{Name: "lima-0.21.0-Linux-x86_64.tar.gz"},
+		{Name: "SHA256SUMS"},
+	}
+	macos := []*Asset{
+		{Name: "tool-linux-amd64"},
+		{Name: "tool-macos-fat"},
+		{Name: "tool-windows-amd64.exe"},
+	}
+	launcher := []*Asset{
+		{Name: "tool-1.2.0-noarch.tar.gz"},
+		{Name: "tool-1.2.0-sources.tar.gz"},
+	}
+
+	cases := []struct {
+		name     string
+		as       []*Asset
+		resolver platformResolver
+		out      string
+	}{
+		{"temporal_cli", temporal, testDarwinARMResolver, "temporal_cli_0.13.2_darwin_all.tar.gz"},
+		{"temporal_cli", temporal, testDarwinAMDResolver, "temporal_cli_0.13.2_darwin_all.tar.gz"},
+		{"temporal_cli", temporal, testLinuxAMDResolver, "temporal_cli_0.13.2_linux_amd64.tar.gz"},
+		{"lima", lima, testDarwinARMResolver, "lima-0.21.0-Darwin-arm64.tar.gz"},
+		{"lima", lima, testDarwinAMDResolver, "lima-0.21.0-Darwin-x86_64.tar.gz"},
+		{"lima", lima[1:2], testDarwinARMResolver, "lima-0.21.0-Darwin-universal.tar.gz"},
+		{"tool", macos, testDarwinARMResolver, "tool-macos-fat"},
+		{"tool", launcher, testLinuxAMDResolver, "tool-1.2.0-noarch.tar.gz"},
+		{"tool", launcher, testDarwinARMResolver, "tool-1.2.0-noarch.tar.gz"},
+	}
+
+	f := NewFilter(&FilterOpts{})
+	for _, c := range cases {
+		resolver = c.resolver
+		got, err := f.FilterAssets(c.name, c.as)
+		if err != nil {
+			t.Fatalf("%s %v: error filtering assets %v", c.name, c.resolver, err)
+		}
+		if got.Name != c.out {
+			t.Errorf("%s %v: expected %s, got %s", c.name, c.resolver, c.out, got.Name)
+		}
+	}
+	resolver = testLinuxAMDResolver
+}
+
+func TestUniversalScore(t *testing.T) {
+	darwin := []string{"darwin", "macos", "osx"}
+	cases := []struct {
+		name  string
+		oses  []string
+		score int
+	}{
+		{"tool_darwin_all.tar.gz", darwin, universalArchScore},
+		{"tool-macOS-universal.zip", darwin, universalArchScore},
+		{"tool-osx-fat", darwin, universalArchScore},
+		{"tool_darwin_arm64.tar.gz", darwin, 0},
+		{"tool_darwin_all.tar.gz", []string{"linux"}, 0},
+		{"tool_linux_all.tar.gz", darwin, 0},
+		{"install-darwin.sh", darwin, 0},
+		{"tool-any.jar", []string{"linux"}, universalArchScore},
+		{"tool-noarch.tar.gz", []string{"windows", "win"}, universalArchScore},
+		{"company-tool.tar.gz", []string{"linux"}, 0},
+	}
+	for _, c := range cases {
+		if got := universalScore(c.name, c.oses, []string{"arm64"}); got != c.score {
+			t.Errorf("universalScore(%s, %v): expected %d, got %d", c.name, c.oses, c.score, got)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package assets
+
+import (
+	"path"
+	"runtime"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// windowsNames enables the checks of the names which can't be used
+// on Windows, it's a variable so the tests can run on any platform
+var windowsNames = runtime.GOOS == "windows"
+
+// reservedNames are the device names Windows reserves
+// regardless of the extension (i.e. con.txt)
+var reservedNames = map[string]bool{
+	"CON": true, "PRN": true, "AUX": true, "NUL": true, "CONIN$": true, "CONOUT$": true,
+	"COM1": true, "COM2": true, "COM3": true, "COM4": true, "COM5": true, "COM6": true, "COM7": true, "COM8": true, "COM9": true,
+	"LPT1": true, "LPT2": true, "LPT3": true, "LPT4": true, "LPT5": true, "LPT6": true, "LPT7": true, "LPT8": true, "LPT9": true,
+}
+
+// entryName normalizes the separators of an archive entry, archives
+// built on Windows might use backslashes, and checks its file name can
+// be written on the current platform. It returns false if the entry
+// must be skipped.
+func entryName(name string) (string, bool) {
+	n := strings.ReplaceAll(name, `\`, "/")
+	if !windowsNames {
+		return n, true
+	}
+
+	base := path.Base(n)
+	// a colon would write to an alternate data stream
+	if strings.ContainsAny(base, `<>:"|?*`) || strings.IndexFunc(base, func(r rune) bool { return r < 32 }) >= 0 {
+		log.WithField("entry", name).Warn("Skipping the archive entry, its name isn't valid on Windows")
+		return "", false
+	}
+
+	// Windows silently drops them, so `tool.` would be written as `tool`
+	trimmed := strings.TrimRight(base, ". ")
+	if trimmed == "" {
+		log.WithField("entry", name).Warn("Skipping the archive entry, its name isn't valid on Windows")
+		return "", false
+	}
+
+	stem, _, _ := strings.Cut(trimmed, ".")
+	if reservedNames[strings.ToUpper(strings.TrimRight(stem, " "))] {
+		log.WithField("entry", name).WithField("reserved", base).Warn("Skipping the archive entry, its name is reserved on Windows")
+		return "", false
+	}
+
+	if trimmed != base {
+		log.WithField("entry", name).WithField("name", trimmed).Warn("Renaming the archive entry, Windows doesn't allow trailing dots and spaces")
+		n = path.Join(path.Dir(n), trimmed)
+	}
+	return n, true
+}
@@ -0,0 +1,105 @@
+package assets
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestEntryName(t *testing.T) {
+	defer func(w bool) { windowsNames = w }(windowsNames)
+
+	cases := []struct {
+		in      string
+		out     string
+		ok      bool
+		windows bool
+	}{
+		{`bin\tool.exe`, "bin/tool.exe", true, true},
+		{`bin\tool`, "bin/tool", true, false},
+		{"aux", "", false, true},
+		{"aux", "aux", true, false},
+		{"dir/con.txt", "", false, true},
+		{"Lpt1 .log", "", false, true},
+		{"auxiliary", "auxiliary", true, true},
+		{"tool:stream", "", false, true},
+		{"tool?", "", false, true},
+		{"bin/tool. ", "bin/tool", true, true},
+		{"...", "", false, true},
+	}
+	for _, c := range cases {
+		windowsNames = c.windows
+		out, ok := entryName(c.in)
+		if out != c.out || ok != c.ok {
+			t.Errorf("%q (windows %v): expected %q %v, got %q %v", c.in, c.windows, c.out, c.ok, out, ok)
+		}
+	}
+}
+
+func TestWindowsArchiveEntries(t *testing.T) {
+	defer func(w bool) { windowsNames = w }(windowsNames)
+	defer func(r platformResolver) { resolver = r }(resolver)
+	windowsNames = true
+	resolver = testWindowsAMDResolver
+
+	tarArchive := func(names ...string) []byte {
+		var buf bytes.Buffer
+		tw := tar.NewWriter(&buf)
+		for _, n := range names {
+			if err := tw.WriteHeader(&tar.Header{Name: n, Mode: 0o755, Size: int64(len(n)), Typeflag: tar.TypeReg}); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := tw.Write([]byte(n)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := tw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	zipArchive := func(names ...string) []byte {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		for _, n := range names {
+			w, err := zw.Create(n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write([]byte(n)); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := zw.Close(); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+
+	cases := []struct {
+		name        string
+		archive     []byte
+		file        string
+		packagePath string
+		content     string
+	}{
+		{"tar", tarArchive("aux", "docs/con.txt", "tool.exe:stream", `bin\tool.exe`), "tool.exe", "bin/tool.exe", `bin\tool.exe`},
+		{"zip", zipArchive("NUL.exe", "tool.exe. "), "tool.exe", "tool.exe", "tool.exe. "},
+	}
+	for _, c := range cases {
+		f := NewFilter(&FilterOpts{})
+		out, err := f.processReader(bytes.NewReader(c.archive))
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		content, err := io.ReadAll(out.Source)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out.Name != c.file || out.PackagePath != c.packagePath || string(content) != c.content {
+			t.Errorf("%s: expected %s (%s), got %s (%s) with %q", c.name, c.file, c.packagePath, out.Name, out.PackagePath, content)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Package browser opens URLs in the default browser of the user
+package browser
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// errNoGUI is returned when there's no browser to open the URL in
+var errNoGUI = errors.New("no graphical session")
+
+// command returns the platform opener of the URL
+var command = func(goos, u string) *exec.Cmd {
+	switch goos {
+	case "darwin":
+		return exec.Command("open", u)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", u)
+	}
+	return exec.Command("xdg-open", u)
+}
+
+// getenv is replaced in tests
+var getenv = os.Getenv
+
+// hasGUI reports whether a browser can be opened: it can't
+// from SSH sessions, nor without a display server on unices
+func hasGUI(goos string) bool {
+	for _, k := range []string{"SSH_CONNECTION", "SSH_CLIENT", "SSH_TTY"} {
+		if getenv(k) != "" {
+			return false
+		}
+	}
+	switch goos {
+	case "darwin", "windows":
+		return true
+	}
+	return getenv("DISPLAY") != "" || getenv("WAYLAND_DISPLAY") != ""
+}
+
+// Open opens the URL in the default browser, it's printed to w
+// instead when that's not possible (i.e. over SSH)
+func Open(u string, w io.Writer) {
+	if err := open(runtime.GOOS, u); err != nil {
+		fmt.Fprintf(w, "Unable to open a browser (%v), visit %s\n", err, u)
+	}
+}
+
+func open(goos, u string) error {
+	if !hasGUI(goos) {
+		return errNoGUI
+	}
+	return command(goos, u).Run()
+}
@@ -0,0 +1,61 @@
+package browser
+
+import (
+	"bytes"
+	"os/exec"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestHasGUI(t *testing.T) {
+	defer func(f func(string) string) { getenv = f }(getenv)
+
+	cases := []struct {
+		goos string
+		env  map[string]string
+		out  bool
+	}{
+		{"darwin", nil, true},
+		{"windows", nil, true},
+		{"darwin", map[string]string{"SSH_CONNECTION": "10.0.0.1 22 10.0.0.2 22"}, false},
+		{"linux", nil, false},
+		{"linux", map[string]string{"DISPLAY": ":0"}, true},
+		{"linux", map[string]string{"WAYLAND_DISPLAY": "wayland-0"}, true},
+		{"linux", map[string]string{"DISPLAY": ":0", "SSH_TTY": "/dev/pts/0"}, false},
+	}
+	for _, c := range cases {
+		getenv = func(k string) string { return c.env[k] }
+		if out := hasGUI(c.goos); out != c.out {
+			t.Errorf("%s %v: expected %t, got %t", c.goos, c.env, c.out, out)
+		}
+	}
+}
+
+func TestOpenFallsBackToPrinting(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the opener is replaced by true")
+	}
+	defer func(f func(string) string) { getenv = f }(getenv)
+	defer func(f func(string, string) *exec.Cmd) { command = f }(command)
+
+	var opened string
+	command = func(goos, u string) *exec.Cmd {
+		opened = u
+		return exec.Command("true")
+	}
+	u := "https://github.com/owner/repo/releases/tag/v1.0.0"
+
+	getenv = func(k string) string { return map[string]string{"DISPLAY": ":0"}[k] }
+	var out bytes.Buffer
+	if err := open("linux", u); err != nil || opened != u {
+		t.Fatalf("expected %s to be opened, got %q (%v)", u, opened, err)
+	}
+
+	opened = ""
+	getenv = func(k string) string { return map[string]string{"SSH_CONNECTION": "x"}[k] }
+	Open(u, &out)
+	if opened != "" || !strings.Contains(out.String(), u) {
+		t.Errorf("expected the URL to be printed over SSH, got %q", out.String())
+	}
+}
@@ -0,0 +1,187 @@
+// Package bundle packs the release assets of binaries into a single
+// archive so they can be installed on machines without network access.
+// The archive is a tar of the assets, stored once per digest, followed
+// by the index describing the binaries they're installed from.
+package bundle
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"time"
+)
+
+// Version is the version of the format of the bundles written
+const Version = 1
+
+const indexName = "index.json"
+
+// Index describes the content of a bundle
+type Index struct {
+	Version int `json:"bundle_version"`
+	// Platform is the os/arch the assets were picked for
+	Platform string    `json:"platform"`
+	Created  time.Time `json:"created"`
+	Entries  []Entry   `json:"entries"`
+}
+
+// Entry is a binary of the bundle and the asset it's installed from
+type Entry struct {
+	// Path is the path of the binary in the configuration
+	// it was bundled from
+	Path        string `json:"path"`
+	URL         string `json:"url"`
+	Provider    string `json:"provider"`
+	Version     string `json:"version"`
+	PackagePath string `json:"package_path,omitempty"`
+	Kind        string `json:"kind,omitempty"`
+	Asset       string `json:"asset"`
+	SHA256      string `json:"sha256"`
+	// AssetURL is where the asset was downloaded from
+	AssetURL string `json:"asset_url,omitempty"`
+}
+
+// file is the name of the asset in the archive
+func (e Entry) file() string {
+	return path.Join("assets", e.SHA256, e.Asset)
+}
+
+// Writer writes a bundle
+type Writer struct {
+	tw    *tar.Writer
+	index Index
+	// written are the digests of the assets already in the archive
+	written map[string]bool
+}
+
+// NewWriter starts a bundle of assets picked for the platform
+func NewWriter(w io.Writer, platform string) *Writer {
+	return &Writer{
+		tw:      tar.NewWriter(w),
+		index:   Index{Version: Version, Platform: platform, Created: time.Now().UTC()},
+		written: map[string]bool{},
+	}
+}
+
+// Add adds the binary and the content of its asset, which is only
+// written once when several binaries come from the same asset
+func (w *Writer) Add(e Entry, data []byte) error {
+	if sum := fmt.Sprintf("%x", sha256.Sum256(data)); sum != e.SHA256 {
+		return fmt.Errorf("the content of %s has the sha256 %s, not %s", e.Asset, sum, e.SHA256)
+	}
+	if !w.written[e.SHA256] {
+		if err := w.write(e.file(), data); err != nil {
+			return err
+		}
+		w.written[e.SHA256] = true
+	}
+	w.index.Entries = append(w.index.Entries, e)
+	return nil
+}
+
+// Close writes the index and completes the archive
+func (w *Writer) Close() error {
+	b, err := json.MarshalIndent(w.index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := w.write(indexName, b); err != nil {
+		return err
+	}
+	return w.tw.Close()
+}
+
+func (w *Writer) write(name string, data []byte) error {
+	h := &tar.Header{Name: name, Mode: 0o644, Size: int64(len(data)), ModTime: w.index.Created, Typeflag: tar.TypeReg}
+	if err := w.tw.WriteHeader(h); err != nil {
+		return err
+	}
+	_, err := w.tw.Write(data)
+	return err
+}
+
+// Bundle is a bundle opened to install its binaries
+type Bundle struct {
+	Index
+	path string
+}
+
+// Open reads the index of the bundle at p
+func Open(p string) (*Bundle, error) {
+	b := &Bundle{path: p}
+	data, err := b.read(indexName)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the bundle %s: %w", p, err)
+	}
+	if err := json.Unmarshal(data, &b.Index); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %w", p, err)
+	}
+	if b.Index.Version > Version {
+		return nil, fmt.Errorf("the bundle %s was written by a newer version of bin", p)
+	}
+	return b, nil
+}
+
+// Lookup returns the entry of the binary installed from the URL at
+// path. The entry of another path is returned when the binary is
+// installed elsewhere, i.e. on another machine.
+func (b *Bundle) Lookup(url, p string) (Entry, bool) {
+	var found *Entry
+	for i, e := range b.Entries {
+		if e.URL != url {
+			continue
+		}
+		if e.Path == p {
+			return e, true
+		}
+		if found == nil {
+			found = &b.Entries[i]
+		}
+	}
+	if found == nil {
+		return Entry{}, false
+	}
+	return *found, true
+}
+
+// Asset returns the content of the asset of the entry, once
+// checked against its digest
+func (b *Bundle) Asset(e Entry) ([]byte, error) {
+	data, err := b.read(e.file())
+	if err != nil {
+		return nil, fmt.Errorf("error reading %s from the bundle %s: %w", e.Asset, b.path, err)
+	}
+	if sum := fmt.Sprintf("%x", sha256.Sum256(data)); sum != e.SHA256 {
+		return nil, fmt.Errorf("%s is corrupted in the bundle %s: its sha256 is %s, %s is expected", e.Asset, b.path, sum, e.SHA256)
+	}
+	return data, nil
+}
+
+// read returns the content of the file of the archive,
+// the ones before it are skipped without being read
+func (b *Bundle) read(name string) ([]byte, error) {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("%s not found", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if h.Name == name {
+			return io.ReadAll(tr)
+		}
+	}
+}
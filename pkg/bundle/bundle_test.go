@@ -0,0 +1,116 @@
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeBundle(t *testing.T, p string, entries []Entry, data [][]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "linux/amd64")
+	for i, e := range entries {
+		if err := w.Add(e, data[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sum(data []byte) string {
+	return fmt.Sprintf("%x", sha256.Sum256(data))
+}
+
+func TestRoundtrip(t *testing.T) {
+	archive := []byte("archive")
+	entries := []Entry{
+		{Path: "/bin/tool", URL: "https://github.com/acme/tool", Provider: "github", Version: "v1.0.0", Asset: "tool.tar.gz", SHA256: sum(archive)},
+		// shipped in the same archive
+		{Path: "/bin/toolctl", URL: "https://github.com/acme/tool", Provider: "github", Version: "v1.0.0", PackagePath: "toolctl", Asset: "tool.tar.gz", SHA256: sum(archive)},
+	}
+	p := filepath.Join(t.TempDir(), "bundle.tar")
+	writeBundle(t, p, entries, [][]byte{archive, archive})
+
+	b, err := Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.Platform != "linux/amd64" || len(b.Entries) != 2 {
+		t.Fatalf("unexpected index %+v", b.Index)
+	}
+
+	e, ok := b.Lookup("https://github.com/acme/tool", "/bin/toolctl")
+	if !ok || e.PackagePath != "toolctl" {
+		t.Fatalf("expected the entry of the path, got %+v", e)
+	}
+	// installed elsewhere, the first one of the URL is used
+	if e, ok := b.Lookup("https://github.com/acme/tool", "/usr/local/bin/tool"); !ok || e.Path != "/bin/tool" {
+		t.Fatalf("expected the first entry of the URL, got %+v", e)
+	}
+	if _, ok := b.Lookup("https://github.com/acme/other", "/bin/other"); ok {
+		t.Fatal("expected no entry for another URL")
+	}
+
+	data, err := b.Asset(e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "archive" {
+		t.Errorf("unexpected asset %q", data)
+	}
+}
+
+func TestAddMismatch(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, "linux/amd64")
+	if err := w.Add(Entry{Asset: "tool.tar.gz", SHA256: sum([]byte("other"))}, []byte("archive")); err == nil {
+		t.Fatal("expected an asset not matching its digest to be refused")
+	}
+}
+
+func TestCorrupted(t *testing.T) {
+	archive := []byte("archive")
+	p := filepath.Join(t.TempDir(), "bundle.tar")
+	writeBundle(t, p, []Entry{{URL: "https://github.com/acme/tool", Asset: "tool.tar.gz", SHA256: sum(archive)}}, [][]byte{archive})
+
+	// tamper with the asset, the archive itself stays valid
+	content, err := os.ReadFile(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, bytes.Replace(content, archive, []byte("tamper!"), 1), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Open(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Asset(b.Entries[0]); err == nil {
+		t.Fatal("expected a corrupted asset to be refused")
+	}
+}
+
+func TestNewerVersion(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "bundle.tar")
+	var buf bytes.Buffer
+	w := NewWriter(&buf, "linux/amd64")
+	w.index.Version = Version + 1
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(p, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(p); err == nil {
+		t.Fatal("expected a bundle of a newer version to be refused")
+	}
+}
@@ -0,0 +1,43 @@
+package compat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// parseAqua reads the packages of an aqua configuration. Their
+// version is either set after an @ in the name or by the version key.
+func parseAqua(r io.Reader) ([]*Tool, error) {
+	var a struct {
+		Packages []struct {
+			Name     string `yaml:"name"`
+			Version  string `yaml:"version"`
+			Registry string `yaml:"registry"`
+			Import   string `yaml:"import"`
+		} `yaml:"packages"`
+	}
+	if err := yaml.NewDecoder(r).Decode(&a); err != nil && err != io.EOF {
+		return nil, fmt.Errorf("invalid aqua configuration: %w", err)
+	}
+
+	tools := []*Tool{}
+	for _, p := range a.Packages {
+		if p.Import != "" {
+			tools = append(tools, &Tool{Name: p.Import, Unsupported: "imports another file"})
+			continue
+		}
+		name, version, _ := strings.Cut(p.Name, "@")
+		if p.Version != "" {
+			version = p.Version
+		}
+		t := &Tool{Name: name, Version: version, Backend: "aqua", Exact: true}
+		if p.Registry != "" && p.Registry != "standard" {
+			t.Unsupported = fmt.Sprintf("comes from the %s registry", p.Registry)
+		}
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
@@ -0,0 +1,32 @@
+package compat
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// parseToolVersions reads an asdf .tool-versions file. Each line has
+// a tool followed by its versions, only the first one is used.
+func parseToolVersions(r io.Reader) ([]*Tool, error) {
+	tools := []*Tool{}
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line, _, _ := strings.Cut(s.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		t := &Tool{Name: fields[0]}
+		if len(fields) == 1 {
+			t.Unsupported = "has no version"
+		} else if t.Version = fields[1]; t.Version == "latest" {
+			t.Version = ""
+		}
+		if t.Unsupported == "" {
+			t.Unsupported = unsupportedVersion(t.Version)
+		}
+		tools = append(tools, t)
+	}
+	return tools, s.Err()
+}
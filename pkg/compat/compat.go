@@ -0,0 +1,70 @@
+// Package compat reads the manifests of other tool managers
+// (asdf, mise and aqua) so their tools can be imported into bin.
+package compat
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Formats are the manifest formats that can be imported
+var Formats = []string{"asdf", "mise", "aqua"}
+
+// Tool is a tool listed in a manifest, Version is
+// empty when the latest one is requested
+type Tool struct {
+	Name    string
+	Version string
+	// Backend is the mise backend of the tool (i.e. aqua
+	// or github), empty for the asdf plugins
+	Backend string
+	// Exact is set when Version is the release tag
+	// itself, as in the aqua configurations
+	Exact bool
+	// Unsupported explains why the tool can't be
+	// imported whatever the registry says
+	Unsupported string
+}
+
+// Parse reads the tools of a manifest in the given format
+func Parse(format string, r io.Reader) ([]*Tool, error) {
+	switch format {
+	case "asdf":
+		return parseToolVersions(r)
+	case "mise":
+		return parseMise(r)
+	case "aqua":
+		return parseAqua(r)
+	}
+	return nil, fmt.Errorf("unsupported manifest format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// DetectFormat guesses the format of a manifest from its file name
+func DetectFormat(path string) (string, error) {
+	switch name := filepath.Base(path); {
+	case name == ".tool-versions":
+		return "asdf", nil
+	case strings.HasSuffix(name, ".toml") && strings.Contains(name, "mise"):
+		return "mise", nil
+	case strings.HasPrefix(strings.TrimPrefix(name, "."), "aqua") &&
+		(strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")):
+		return "aqua", nil
+	}
+	return "", fmt.Errorf("can't tell the format of %s, pass it with --from (%s)", path, strings.Join(Formats, ", "))
+}
+
+// unsupportedVersion explains why a version of an
+// asdf or mise manifest can't be installed, if so
+func unsupportedVersion(v string) string {
+	switch {
+	case v == "system":
+		return "uses the system version"
+	case strings.HasPrefix(v, "ref:"):
+		return "builds from a git ref"
+	case strings.HasPrefix(v, "path:"), strings.HasPrefix(v, "prefix:"):
+		return "uses a local installation"
+	}
+	return ""
+}
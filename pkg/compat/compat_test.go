@@ -0,0 +1,106 @@
+package compat
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestParseAndResolve(t *testing.T) {
+	tests := []struct {
+		file     string
+		expected []string
+	}{
+		{
+			".tool-versions",
+			[]string{
+				"terraform 1.5.7 https://releases.hashicorp.com/terraform/1.5.7",
+				"ripgrep 14.1.0 https://github.com/BurntSushi/ripgrep/releases/tag/14.1.0",
+				"jq 1.7.1 https://github.com/jqlang/jq/releases/tag/jq-1.7.1",
+				"nodejs 20.11.0 error: not in the registry",
+				"gh  https://github.com/cli/cli",
+				"golang system error: uses the system version",
+			},
+		},
+		{
+			"mise.toml",
+			[]string{
+				"kustomize 5.3.0 https://github.com/kubernetes-sigs/kustomize/releases/tag/kustomize/v5.3.0",
+				"cli/cli 2.40.0 https://github.com/cli/cli/releases/tag/v2.40.0",
+				"BurntSushi/ripgrep 14.1.0 https://github.com/BurntSushi/ripgrep/releases/tag/14.1.0",
+				"eza 0.18.0 error: the cargo backend isn't supported",
+				"fzf 0.46.0 https://github.com/junegunn/fzf/releases/tag/v0.46.0",
+				"yq 4.40.5 https://github.com/mikefarah/yq/releases/tag/v4.40.5",
+				"task  https://github.com/go-task/task",
+			},
+		},
+		{
+			"aqua.yaml",
+			[]string{
+				"cli/cli v2.40.0 https://github.com/cli/cli/releases/tag/v2.40.0",
+				"hashicorp/terraform v1.5.7 https://releases.hashicorp.com/terraform/1.5.7",
+				"jqlang/jq jq-1.7.1 https://github.com/jqlang/jq/releases/tag/jq-1.7.1",
+				"kubernetes/kubectl  https://github.com/kubernetes/kubectl",
+				"suzuki-shunsuke/tfcmt v4.0.0 error: comes from the local registry",
+				"aqua/*.yaml  error: imports another file",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			format, err := DetectFormat("testdata/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := os.Open("testdata/" + tt.file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			tools, err := Parse(format, f)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := make([]string, 0, len(tools))
+			for _, tool := range tools {
+				u, err := Resolve(tool)
+				if err != nil {
+					u = "error: " + err.Error()
+				}
+				got = append(got, fmt.Sprintf("%s %s %s", tool.Name, tool.Version, u))
+			}
+			if fmt.Sprintf("%q", got) != fmt.Sprintf("%q", tt.expected) {
+				t.Fatalf("expected\n%q\ngot\n%q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	for path, expected := range map[string]string{
+		"/home/me/project/.tool-versions": "asdf",
+		".mise.toml":                      "mise",
+		"mise.local.toml":                 "mise",
+		".config/mise/config.toml":        "",
+		"aqua.yml":                        "aqua",
+		".aqua.yaml":                      "aqua",
+		"bin.json":                        "",
+	} {
+		format, err := DetectFormat(path)
+		if format != expected || (expected == "") != (err != nil) {
+			t.Errorf("expected %q for %s, got %q (%v)", expected, path, format, err)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	if _, err := Parse("mise", strings.NewReader("[tools\n")); err == nil {
+		t.Fatal("expected an error for an invalid mise configuration")
+	}
+	if _, err := Parse("brew", strings.NewReader("")); err == nil {
+		t.Fatal("expected an error for an unsupported format")
+	}
+}
@@ -0,0 +1,56 @@
+package compat
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// parseMise reads the [tools] table of a mise configuration. The
+// versions can be a string, a list whose first element is used or
+// a table with a version key.
+func parseMise(r io.Reader) ([]*Tool, error) {
+	var m struct {
+		Tools map[string]interface{} `toml:"tools"`
+	}
+	md, err := toml.NewDecoder(r).Decode(&m)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mise configuration: %w", err)
+	}
+
+	// the tools are kept in the order of the file
+	names := make([]string, 0, len(m.Tools))
+	for _, k := range md.Keys() {
+		if len(k) == 2 && k[0] == "tools" {
+			names = append(names, k[1])
+		}
+	}
+
+	tools := make([]*Tool, 0, len(names))
+	for _, n := range names {
+		t := &Tool{Name: n}
+		if backend, name, ok := strings.Cut(n, ":"); ok {
+			t.Backend = backend
+			// ubi and github take options, i.e. ubi:owner/repo[exe=rg]
+			t.Name, _, _ = strings.Cut(name, "[")
+		}
+		switch v := m.Tools[n].(type) {
+		case string:
+			t.Version = v
+		case []interface{}:
+			if len(v) > 0 {
+				t.Version, _ = v[0].(string)
+			}
+		case map[string]interface{}:
+			t.Version, _ = v["version"].(string)
+		}
+		if t.Version == "latest" {
+			t.Version = ""
+		}
+		t.Unsupported = unsupportedVersion(t.Version)
+		tools = append(tools, t)
+	}
+	return tools, nil
+}
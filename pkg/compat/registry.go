@@ -0,0 +1,146 @@
+package compat
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Source is where bin installs a tool from
+type Source struct {
+	// URL of the repository, or of the product for
+	// the HashiCorp releases
+	URL string
+	// Tag is the release tag of a version, v{version} by default
+	Tag string
+}
+
+// tag returns the release tag of the version. Exact
+// versions are release tags already (i.e. aqua ones).
+func (s Source) tag(version string, exact bool) string {
+	tmpl := s.Tag
+	if tmpl == "" {
+		tmpl = "v{version}"
+	}
+	prefix, _, _ := strings.Cut(tmpl, "{version}")
+	if exact || (prefix != "" && strings.HasPrefix(version, prefix)) {
+		return version
+	}
+	return strings.ReplaceAll(tmpl, "{version}", version)
+}
+
+// release returns the URL of the release of the version,
+// the one of the source when it's empty
+func (s Source) release(version string, exact bool) string {
+	if version == "" {
+		return s.URL
+	}
+	tag := s.tag(version, exact)
+	if strings.HasPrefix(s.URL, "https://github.com/") {
+		return s.URL + "/releases/tag/" + tag
+	}
+	// HashiCorp versions don't have a v
+	return s.URL + "/" + strings.TrimPrefix(tag, "v")
+}
+
+func github(repo string) Source {
+	return Source{URL: "https://github.com/" + repo}
+}
+
+func hashicorp(product string) Source {
+	return Source{URL: "https://releases.hashicorp.com/" + product, Tag: "{version}"}
+}
+
+// registry maps the names of the asdf plugins and mise tools, and
+// the aqua packages which aren't installed from their repository, to
+// their source
+var registry = map[string]Source{
+	"act":           github("nektos/act"),
+	"age":           github("FiloSottile/age"),
+	"argocd":        github("argoproj/argo-cd"),
+	"bat":           github("sharkdp/bat"),
+	"consul":        hashicorp("consul"),
+	"delta":         {URL: "https://github.com/dandavison/delta", Tag: "{version}"},
+	"direnv":        github("direnv/direnv"),
+	"dive":          github("wagoodman/dive"),
+	"fd":            github("sharkdp/fd"),
+	"flux2":         github("fluxcd/flux2"),
+	"fzf":           github("junegunn/fzf"),
+	"gh":            github("cli/cli"),
+	"github-cli":    github("cli/cli"),
+	"golangci-lint": github("golangci/golangci-lint"),
+	"goreleaser":    github("goreleaser/goreleaser"),
+	"hadolint":      github("hadolint/hadolint"),
+	"jq":            {URL: "https://github.com/jqlang/jq", Tag: "jq-{version}"},
+	"just":          {URL: "https://github.com/casey/just", Tag: "{version}"},
+	"k3d":           github("k3d-io/k3d"),
+	"k9s":           github("derailed/k9s"),
+	"kind":          github("kubernetes-sigs/kind"),
+	"kustomize":     {URL: "https://github.com/kubernetes-sigs/kustomize", Tag: "kustomize/v{version}"},
+	"lazygit":       github("jesseduffield/lazygit"),
+	"nomad":         hashicorp("nomad"),
+	"packer":        hashicorp("packer"),
+	"ripgrep":       {URL: "https://github.com/BurntSushi/ripgrep", Tag: "{version}"},
+	"shellcheck":    github("koalaman/shellcheck"),
+	"shfmt":         github("mvdan/sh"),
+	"sops":          github("getsops/sops"),
+	"starship":      github("starship/starship"),
+	"stern":         github("stern/stern"),
+	"task":          github("go-task/task"),
+	"terraform":     hashicorp("terraform"),
+	"terragrunt":    github("gruntwork-io/terragrunt"),
+	"tflint":        github("terraform-linters/tflint"),
+	"trivy":         github("aquasecurity/trivy"),
+	"vault":         hashicorp("vault"),
+	"yq":            github("mikefarah/yq"),
+
+	"hashicorp/consul":    hashicorp("consul"),
+	"hashicorp/nomad":     hashicorp("nomad"),
+	"hashicorp/packer":    hashicorp("packer"),
+	"hashicorp/terraform": hashicorp("terraform"),
+	"hashicorp/vault":     hashicorp("vault"),
+}
+
+// repoSource returns the source of a GitHub repository, using
+// the tags of the registry when it knows the repository
+func repoSource(repo string) (Source, bool) {
+	if s, ok := registry[repo]; ok {
+		return s, true
+	}
+	if strings.Count(repo, "/") != 1 {
+		return Source{}, false
+	}
+	s := github(repo)
+	for _, r := range registry {
+		if strings.EqualFold(r.URL, s.URL) {
+			return r, true
+		}
+	}
+	return s, true
+}
+
+// Resolve returns the URL bin installs the tool from. It's the URL of
+// the release of its version, or the one of the repository when the
+// latest version is requested.
+func Resolve(t *Tool) (string, error) {
+	if t.Unsupported != "" {
+		return "", fmt.Errorf("%s", t.Unsupported)
+	}
+
+	var (
+		s  Source
+		ok bool
+	)
+	switch t.Backend {
+	case "", "asdf", "core":
+		if s, ok = registry[t.Name]; !ok {
+			return "", fmt.Errorf("not in the registry")
+		}
+	case "aqua", "github", "ubi":
+		if s, ok = repoSource(t.Name); !ok {
+			return "", fmt.Errorf("not a GitHub repository")
+		}
+	default:
+		return "", fmt.Errorf("the %s backend isn't supported", t.Backend)
+	}
+	return s.release(t.Version, t.Exact), nil
+}
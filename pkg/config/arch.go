@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// knownArchs are the architectures accepted by --arch, the
+// 32-bit ARM ones can be given with their version (i.e. armv7)
+var knownArchs = []string{"386", "amd64", "arm", "arm64", "loong64", "mips", "mipsle", "mips64", "mips64le", "ppc64", "ppc64le", "riscv64", "s390x"}
+
+// knownOSes are the operating systems accepted by --os
+var knownOSes = []string{"aix", "android", "darwin", "dragonfly", "freebsd", "illumos", "ios", "linux", "netbsd", "openbsd", "plan9", "solaris", "windows"}
+
+var (
+	// osOverride is set through the --os flag of bin download
+	osOverride string
+	// archOverride is set through the --arch flag
+	archOverride string
+	// armOverride is the ARM version of the --arch flag, if any
+	armOverride int
+
+	detectARMOnce sync.Once
+	detectedARM   int
+
+	cpuArchRe = regexp.MustCompile(`(?m)^CPU architecture:\s*(\d+)`)
+)
+
+// SetArch overrides the architecture of the host for the current
+// run, i.e. to cross-install into a container. 32-bit ARM hosts
+// can be given as armv5, armv6 or armv7.
+func SetArch(arch string) error {
+	if arch == "" {
+		archOverride = ""
+		return nil
+	}
+	if v, ok := strings.CutPrefix(arch, "armv"); ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 5 || n > 7 {
+			return fmt.Errorf("invalid arch %q, ARM versions must be one of armv5, armv6 or armv7", arch)
+		}
+		archOverride, armOverride = "arm", n
+		return nil
+	}
+	for _, a := range knownArchs {
+		if a == arch {
+			archOverride = arch
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid arch %q, must be one of %s or armv5, armv6, armv7", arch, strings.Join(knownArchs, ", "))
+}
+
+// SetOS overrides the operating system of the host for the
+// current run, i.e. to download the assets of another machine
+func SetOS(name string) error {
+	if name == "" {
+		osOverride = ""
+		return nil
+	}
+	if alias, ok := map[string]string{"macos": "darwin", "osx": "darwin", "win": "windows"}[name]; ok {
+		name = alias
+	}
+	for _, o := range knownOSes {
+		if o == name {
+			osOverride = name
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid os %q, must be one of %s", name, strings.Join(knownOSes, ", "))
+}
+
+// ResolveOS returns the operating system the assets are picked
+// for along with its origin: the --os flag or the host
+func ResolveOS() (name, origin string) {
+	if osOverride != "" {
+		return osOverride, OriginFlag
+	}
+	return runtime.GOOS, OriginDetected
+}
+
+func goos() string {
+	name, _ := ResolveOS()
+	return name
+}
+
+// goarch returns the architecture the assets are picked for
+func goarch() string {
+	arch, _ := ResolveArch()
+	return arch
+}
+
+// ResolveArch returns the architecture the assets are picked for
+// (i.e. amd64 or arm) along with its origin: the --arch flag or the host
+func ResolveArch() (arch, origin string) {
+	if archOverride != "" {
+		return archOverride, OriginFlag
+	}
+	return runtime.GOARCH, OriginDetected
+}
+
+// GetArch is the running program's architecture target:
+// one of 386, amd64, arm, s390x, and so on.
+func GetArch() []string {
+	arch := goarch()
+	res := []string{arch}
+	switch arch {
+	case "amd64":
+		// Adding x86_64 manually since the uname syscall (man 2 uname)
+		// is not implemented in all systems
+		res = append(res, "x86_64")
+		res = append(res, "x64")
+	case "arm64":
+		res = append(res, "aarch64")
+	}
+	return res
+}
+
+// GetARMVersion returns the version (5, 6 or 7) of 32-bit ARM
+// hosts, and 0 for every other architecture
+func GetARMVersion() int {
+	if goarch() != "arm" {
+		return 0
+	}
+	if armOverride != 0 {
+		return armOverride
+	}
+	if archOverride != "" && runtime.GOARCH != "arm" {
+		// cross-installing without a version, armv6 runs almost everywhere
+		return 6
+	}
+	detectARMOnce.Do(func() {
+		detectedARM = detectARMVersion()
+	})
+	return detectedARM
+}
+
+// detectARMVersion uses the GOARM bin was built with,
+// falling back to the CPU architecture of /proc/cpuinfo
+func detectARMVersion() int {
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			if s.Key != "GOARM" {
+				continue
+			}
+			// i.e. `7` or `6,softfloat`
+			if v, err := strconv.Atoi(strings.Split(s.Value, ",")[0]); err == nil {
+				return v
+			}
+		}
+	}
+	b, err := os.ReadFile("/proc/cpuinfo")
+	if err != nil {
+		return 6
+	}
+	return parseCPUInfoARM(string(b))
+}
+
+// parseCPUInfoARM returns the ARM version of /proc/cpuinfo. ARMv8
+// CPUs running a 32-bit system execute armv7 binaries.
+func parseCPUInfoARM(cpuinfo string) int {
+	m := cpuArchRe.FindStringSubmatch(cpuinfo)
+	if m == nil {
+		return 6
+	}
+	v, _ := strconv.Atoi(m[1])
+	return max(5, min(v, 7))
+}
+
+// fallbackArchDisabled is set through the --no-fallback-arch flag
+var fallbackArchDisabled bool
+
+// SetFallbackArch enables or disables the fallback to
+// the assets of an emulated architecture
+func SetFallbackArch(enabled bool) {
+	fallbackArchDisabled = !enabled
+}
+
+// GetFallbackArch returns the names of the architecture whose binaries
+// the host runs through emulation (i.e. amd64 under Rosetta on Apple
+// Silicon), empty if there's none. enabled reports whether its assets
+// can be picked when there are no native ones.
+func GetFallbackArch() (archs []string, enabled bool) {
+	if goarch() != "arm64" {
+		return nil, false
+	}
+	switch goos() {
+	case "darwin", "windows":
+		return []string{"amd64", "x86_64", "x64"}, !fallbackArchDisabled
+	}
+	return nil, false
+}
+
+// FallbackArchOrigin tells whether the fallback to the assets of an
+// emulated architecture was disabled by the --no-fallback-arch flag
+func FallbackArchOrigin() string {
+	if fallbackArchDisabled {
+		return OriginFlag
+	}
+	return OriginDefault
+}
+
+// Emulation names the emulation layer running
+// the binaries of the fallback architecture
+func Emulation() string {
+	if runtime.GOOS == "darwin" {
+		return "Rosetta"
+	}
+	return "x64 emulation"
+}
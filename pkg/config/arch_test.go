@@ -0,0 +1,57 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseCPUInfoARM(t *testing.T) {
+	cases := []struct {
+		in  string
+		out int
+	}{
+		{"processor\t: 0\nmodel name\t: ARMv7 Processor rev 4 (v7l)\nCPU architecture: 7\n", 7},
+		{"processor\t: 0\nmodel name\t: ARMv6-compatible processor rev 7 (v6l)\nCPU architecture: 6\n", 6},
+		{"processor\t: 0\nCPU architecture: 8\n", 7},
+		{"processor\t: 0\n", 6},
+	}
+	for _, c := range cases {
+		if got := parseCPUInfoARM(c.in); got != c.out {
+			t.Errorf("parseCPUInfoARM(%q): expected %d, got %d", c.in, c.out, got)
+		}
+	}
+}
+
+func TestSetArch(t *testing.T) {
+	defer func() { archOverride, armOverride = "", 0 }()
+
+	cases := []struct {
+		in   string
+		arch []string
+		arm  int
+		err  bool
+	}{
+		{"armv7", []string{"arm"}, 7, false},
+		{"armv6", []string{"arm"}, 6, false},
+		{"arm64", []string{"arm64", "aarch64"}, 0, false},
+		{"amd64", []string{"amd64", "x86_64", "x64"}, 0, false},
+		{"armv8", nil, 0, true},
+		{"sparc", nil, 0, true},
+	}
+	for _, c := range cases {
+		archOverride, armOverride = "", 0
+		err := SetArch(c.in)
+		if (err != nil) != c.err {
+			t.Errorf("SetArch(%s): expected error %v, got %v", c.in, c.err, err)
+			continue
+		}
+		if c.err {
+			continue
+		}
+		if got := GetArch(); len(got) != len(c.arch) || got[0] != c.arch[0] {
+			t.Errorf("SetArch(%s): expected arch %v, got %v", c.in, c.arch, got)
+		}
+		if got := GetARMVersion(); got != c.arm {
+			t.Errorf("SetArch(%s): expected ARM version %d, got %d", c.in, c.arm, got)
+		}
+	}
+}
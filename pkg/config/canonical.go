@@ -0,0 +1,238 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// binaryFields is the rank of the fields of a binary in the
+// canonical form, the order they're declared in
+var binaryFields = fieldRanks(reflect.TypeOf(Binary{}))
+
+func fieldRanks(t reflect.Type) map[string]int {
+	ranks := map[string]int{}
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			ranks[name] = i
+		}
+	}
+	return ranks
+}
+
+// member is a member of a JSON object, in the order it was read
+type member struct {
+	key   string
+	value json.RawMessage
+}
+
+// encodeJSON encodes v indented by 4 spaces, without escaping
+// the HTML characters of the URLs, followed by a newline
+func encodeJSON(v any) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "    ")
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// canonicalConfig encodes the configuration in its canonical form: the
+// binaries sorted by name, then by key, and their fields in the order of
+// Binary. The other objects have their keys sorted, the settings in the
+// order of config. Encoding the same configuration always yields the
+// same bytes, whatever the order it was built or read in.
+func canonicalConfig(v any) ([]byte, error) {
+	raw, err := encodeJSON(v)
+	if err != nil {
+		return nil, err
+	}
+	members, err := objectMembers(raw)
+	if err != nil {
+		return nil, err
+	}
+	for i, m := range members {
+		if m.key != "bins" || bytes.Equal(m.value, []byte("null")) {
+			continue
+		}
+		bins, err := objectMembers(m.value)
+		if err != nil {
+			return nil, err
+		}
+		sort.SliceStable(bins, func(i, j int) bool {
+			ni, nj := filepath.Base(bins[i].key), filepath.Base(bins[j].key)
+			if ni != nj {
+				return ni < nj
+			}
+			return bins[i].key < bins[j].key
+		})
+		for j, b := range bins {
+			fields, err := objectMembers(b.value)
+			if err != nil {
+				return nil, err
+			}
+			sort.SliceStable(fields, func(i, j int) bool {
+				ri, oki := binaryFields[fields[i].key]
+				rj, okj := binaryFields[fields[j].key]
+				if oki != okj {
+					return oki
+				}
+				if !oki {
+					return fields[i].key < fields[j].key
+				}
+				return ri < rj
+			})
+			if bins[j].value, err = joinMembers(fields); err != nil {
+				return nil, err
+			}
+		}
+		if members[i].value, err = joinMembers(bins); err != nil {
+			return nil, err
+		}
+	}
+	compact, err := joinMembers(members)
+	if err != nil {
+		return nil, err
+	}
+	var out bytes.Buffer
+	if err := json.Indent(&out, compact, "", "    "); err != nil {
+		return nil, err
+	}
+	out.WriteByte('\n')
+	return out.Bytes(), nil
+}
+
+// objectMembers returns the members of the JSON object, in order
+func objectMembers(raw []byte) ([]member, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	t, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := t.(json.Delim); !ok || d != '{' {
+		return nil, fmt.Errorf("expected a JSON object, got %v", t)
+	}
+	members := []member{}
+	for decoder.More() {
+		t, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		var value json.RawMessage
+		if err := decoder.Decode(&value); err != nil {
+			return nil, err
+		}
+		members = append(members, member{key: t.(string), value: value})
+	}
+	return members, nil
+}
+
+// joinMembers encodes the members as a compact JSON object, in order
+func joinMembers(members []member) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, m := range members {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := encodeJSON(m.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(bytes.TrimSpace(key))
+		buf.WriteByte(':')
+		if err := json.Compact(&buf, m.value); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// Format returns the configuration file in its canonical form, the
+// one bin writes it in. The fields bin doesn't know are refused
+// instead of being dropped, the configuration read from the canonical
+// form must be the same as the one read from data.
+func Format(data []byte) ([]byte, error) {
+	c, err := decodeConfig(data)
+	if err != nil {
+		return nil, err
+	}
+	var v any = c
+	if c.SplitState {
+		// the binaries are keyed by name without their machine-local fields
+		decl, _, err := splitState(*c)
+		if err != nil {
+			return nil, err
+		}
+		v = decl
+	}
+	out, err := canonicalConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	// the configurations are compared encoded, the empty
+	// and missing values are the same to bin
+	formatted, err := decodeConfig(out)
+	if err != nil {
+		return nil, err
+	}
+	before, err := encodeJSON(c)
+	if err != nil {
+		return nil, err
+	}
+	after, err := encodeJSON(formatted)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(before, after) {
+		return nil, fmt.Errorf("the configuration can't be formatted without changing it, i.e. the version of a binary which isn't pinned is only kept in the state file")
+	}
+	return out, nil
+}
+
+// decodeConfig reads the configuration, refusing the unknown fields
+func decodeConfig(data []byte) (*config, error) {
+	c := &config{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	if decoder.More() {
+		return nil, fmt.Errorf("invalid configuration: trailing data after the JSON object")
+	}
+	if c.Bins == nil {
+		c.Bins = map[string]*Binary{}
+	}
+	return c, nil
+}
+
+// FormatFile rewrites the configuration file at p in its canonical
+// form, it reports whether the file changed
+func FormatFile(p string) (bool, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return false, err
+	}
+	out, err := Format(data)
+	if err != nil {
+		return false, err
+	}
+	if bytes.Equal(data, out) {
+		return false, nil
+	}
+	fi, err := os.Stat(p)
+	if err != nil {
+		return false, err
+	}
+	return true, os.WriteFile(p, out, fi.Mode().Perm())
+}
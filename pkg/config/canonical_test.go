@@ -0,0 +1,146 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCanonicalConfig(t *testing.T) {
+	bins := func(paths ...string) map[string]*Binary {
+		m := map[string]*Binary{}
+		for _, p := range paths {
+			m[p] = &Binary{Path: p, URL: "https://example.com/dl?os=linux&arch=amd64", Version: "v1"}
+		}
+		return m
+	}
+	a, err := canonicalConfig(config{DefaultPath: "/bin", Bins: bins("/z/jq", "/a/kind", "/b/jq")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := canonicalConfig(config{DefaultPath: "/bin", Bins: bins("/b/jq", "/z/jq", "/a/kind")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Fatalf("expected the same configuration to be encoded the same way\n%s\n%s", a, b)
+	}
+	s := string(a)
+	if i, j, k := strings.Index(s, `"/b/jq"`), strings.Index(s, `"/z/jq"`), strings.Index(s, `"/a/kind"`); i > j || j > k {
+		t.Fatalf("expected the binaries sorted by name then path, got %s", s)
+	}
+	if !strings.HasSuffix(s, "}\n") || !strings.Contains(s, "\n    \"default_path\"") || !strings.Contains(s, "os=linux&arch=amd64") {
+		t.Fatalf("expected an indented configuration ending with a newline, without escaped URLs, got %s", s)
+	}
+
+	// the fields of the declarative binaries follow Binary
+	out, err := canonicalConfig(map[string]any{"bins": map[string]any{"jq": map[string]any{"url": "u", "pinned": true, "asset": "a", "path": "p", "zz": 1}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{
+    "bins": {
+        "jq": {
+            "path": "p",
+            "url": "u",
+            "pinned": true,
+            "asset": "a",
+            "zz": 1
+        }
+    }
+}
+`; string(out) != want {
+		t.Fatalf("expected\n%s\ngot\n%s", want, out)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	for _, tc := range []struct {
+		name, config, err string
+	}{
+		{name: "empty bins", config: `{"default_path": "/bin"}`},
+		{name: "shuffled", config: `{"bins": {"/bin/kind": {"url": "https://github.com/kubernetes-sigs/kind", "version": "v0.20.0", "path": "/bin/kind", "pinned": false}, "/bin/gh": {"path": "/bin/gh", "url": "https://github.com/cli/cli", "headers": {"b": "2", "a": "1"}}}, "settings": {}, "default_path": "/bin"}`},
+		{name: "split state", config: `{"split_state": true, "bins": {"kind": {"url": "https://github.com/kubernetes-sigs/kind"}, "jq": {"version": "jq-1.6", "pinned": true, "url": "https://github.com/jqlang/jq"}}, "default_path": "/bin"}`},
+		{name: "unknown field", config: `{"default_path": "/bin", "colour": true}`, err: "unknown field"},
+		{name: "unknown field of a binary", config: `{"bins": {"/bin/jq": {"path": "/bin/jq", "pined": true}}}`, err: "unknown field"},
+		{name: "trailing data", config: `{"default_path": "/bin"} {}`, err: "trailing data"},
+		{name: "local field of split state", config: `{"split_state": true, "bins": {"kind": {"url": "https://github.com/kubernetes-sigs/kind", "version": "v0.20.0"}}}`, err: "without changing it"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			out, err := Format([]byte(tc.config))
+			if tc.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.err) {
+					t.Fatalf("expected an error containing %q, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			again, err := Format(out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out, again) {
+				t.Fatalf("expected formatting to be idempotent\n%s\n%s", out, again)
+			}
+		})
+	}
+}
+
+func TestLoadWriteIdempotent(t *testing.T) {
+	dir := t.TempDir()
+	p := filepath.Join(dir, "config.json")
+	t.Setenv("BIN_CONFIG", p)
+	t.Cleanup(func() { cfg = config{} })
+	for _, c := range []string{
+		`{"bins": {"/bin/kind": {"url": "https://github.com/kubernetes-sigs/kind", "version": "v0.20.0", "path": "/bin/kind"}, "/opt/jq": {"path": "/opt/jq", "url": "https://github.com/jqlang/jq?a=1&b=2", "pinned": true, "version": "jq-1.6"}}, "default_path": "/bin", "settings": {"B": "2", "A": "1"}}`,
+		`{"default_path": "/bin", "relative_paths": true, "bins": {"/bin/kind": {"path": "/bin/kind", "url": "https://github.com/kubernetes-sigs/kind"}}}`,
+	} {
+		if err := os.WriteFile(p, []byte(c), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		var written [][]byte
+		for i := 0; i < 2; i++ {
+			cfg = config{}
+			if err := CheckAndLoad(); err != nil {
+				t.Fatal(err)
+			}
+			if err := write(); err != nil {
+				t.Fatal(err)
+			}
+			b, err := os.ReadFile(p)
+			if err != nil {
+				t.Fatal(err)
+			}
+			written = append(written, b)
+		}
+		if !bytes.Equal(written[0], written[1]) {
+			t.Fatalf("expected writing the loaded configuration to be idempotent\n%s\n%s", written[0], written[1])
+		}
+		if out, err := Format(written[1]); err != nil || !bytes.Equal(out, written[1]) {
+			t.Fatalf("expected the written configuration to be canonical, got %v\n%s", err, out)
+		}
+	}
+}
+
+func FuzzFormat(f *testing.F) {
+	f.Add(`{"default_path": "/bin", "bins": {"/bin/jq": {"path": "/bin/jq", "url": "https://github.com/jqlang/jq", "pinned": true, "version": "1.6"}}}`)
+	f.Add(`{"split_state": true, "bins": {"jq": {"url": "u", "prefer_format": ["zip"]}, "/x/jq": {"url": "v"}}}`)
+	f.Add(`{"settings": {"GITHUB_TOKEN": "<&>"}, "handlers": {"corp://": {"latest": "echo 1"}}}`)
+	f.Fuzz(func(t *testing.T, s string) {
+		out, err := Format([]byte(s))
+		if err != nil {
+			return
+		}
+		again, err := Format(out)
+		if err != nil {
+			t.Fatalf("the canonical form of %q can't be formatted: %v", s, err)
+		}
+		if !bytes.Equal(out, again) {
+			t.Fatalf("formatting %q isn't idempotent\n%s\n%s", s, out, again)
+		}
+	})
+}
@@ -3,25 +3,125 @@ package config
 import (
 	"bufio"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"os"
 	"path"
 	"path/filepath"
-	"runtime"
 	"strings"
+	"syscall"
+	"time"
 
-	"github.com/caarlos0/log"
+	"github.com/marcosnils/bin/pkg/log"
 )
 
 var cfg config
 
+// readOnly is set when the configuration can't be written, either
+// explicitly or because its location isn't writable
+var readOnly bool
+
+// ErrReadOnly is returned when writing a read-only configuration
+var ErrReadOnly = errors.New("the configuration is read-only")
+
 type config struct {
 	// DefaultPath might not be expanded so it's important that
 	// the caller expands this variable with os.ExpandEnv(string)
 	// if necessary
 	DefaultPath string             `json:"default_path"`
 	Bins        map[string]*Binary `json:"bins"`
+	// DefaultForge is the forge used to expand `owner/repo`
+	// shorthands, either github (default) or gitlab
+	DefaultForge string `json:"default_forge,omitempty"`
+	// MinTLSVersion is the minimum TLS version accepted
+	// from any server, 1.2 by default
+	MinTLSVersion string `json:"min_tls_version,omitempty"`
+	// Stats installs shims recording locally how
+	// often each binary is executed
+	Stats bool `json:"stats,omitempty"`
+	// Settings of the providers (i.e. GITHUB_TOKEN), the
+	// environment and --env take precedence over them
+	Settings map[string]string `json:"settings,omitempty"`
+	// Libc overrides the detected libc of the host when
+	// picking assets, either musl, gnu or any
+	Libc string `json:"libc,omitempty"`
+	// SplitState writes the machine-local fields of the binaries
+	// (paths, installed versions, digests) to a separate state file
+	// so the configuration can be shared across machines
+	SplitState bool `json:"split_state,omitempty"`
+	// RequireChecksum refuses the assets which can't be verified with
+	// a checksum published along them, or whose algorithm is unknown
+	RequireChecksum bool `json:"require_checksum,omitempty"`
+	// DefaultFilesPath is where the entries of kind file are
+	// installed when no path is given, i.e. ~/.local/share/fonts
+	DefaultFilesPath string `json:"default_files_path,omitempty"`
+	// KeepAppImageExtension keeps the .AppImage extension
+	// of the AppImages in the name of the installed binary
+	KeepAppImageExtension bool `json:"keep_appimage_extension,omitempty"`
+	// BashCompletionsPath, ZshCompletionsPath, FishCompletionsPath
+	// and ManPath are where the completions and man pages shipped
+	// along the binaries are installed, see GetExtrasDir
+	BashCompletionsPath string `json:"bash_completions_path,omitempty"`
+	ZshCompletionsPath  string `json:"zsh_completions_path,omitempty"`
+	FishCompletionsPath string `json:"fish_completions_path,omitempty"`
+	ManPath             string `json:"man_path,omitempty"`
+	// Quota is a soft limit of the disk space taken by the managed
+	// binaries and files, the copies kept of them and the cache of
+	// bin (i.e. 2GiB). Going over it is reported on install and update
+	Quota string `json:"quota,omitempty"`
+	// Handlers delegate the binaries whose URL has the scheme
+	// (i.e. `corp://`) or the host (a glob like `*.corp.example.com`)
+	// of their key to external commands, see Handler
+	Handlers map[string]*Handler `json:"handlers,omitempty"`
+	// Keyring is a directory of OpenPGP public keys verifying the
+	// signatures of the checksum files and assets of every binary
+	Keyring string `json:"keyring,omitempty"`
+	// RequireSignature refuses the assets which aren't signed, nor
+	// verified by a signed checksum file, by one of the keys
+	RequireSignature bool `json:"require_signature,omitempty"`
+	// RelativePaths writes the paths of the binaries under the default
+	// path relative to it, moving the directory only changes default_path
+	RelativePaths bool `json:"relative_paths,omitempty"`
+	// Changelog shows the release notes of the updated binaries,
+	// either `target` for the version updated to or `all` for the
+	// releases in between too. Same as `bin update --changelog`
+	Changelog string `json:"changelog,omitempty"`
+	// VerifyInstall runs every installed binary once with --version
+	// before it replaces the previous one, see Binary.VerifyArgs
+	VerifyInstall bool `json:"verify_install,omitempty"`
+	// VersionedInstalls keeps the installed versions of the binaries in
+	// the store, the path of each binary points to the active one
+	VersionedInstalls bool `json:"versioned_installs,omitempty"`
+	// KeepVersions is the number of versions kept in the store per
+	// binary with versioned_installs, 2 by default
+	KeepVersions int `json:"keep_versions,omitempty"`
+	// Paths are named directories the binaries can be installed
+	// in, i.e. `"work": "~/work/bin"` for `bin install --path work`.
+	// The binaries refer to them by name, so the directories can
+	// differ on each machine
+	Paths map[string]string `json:"paths,omitempty"`
+}
+
+const (
+	// KindBinary is the kind of the executables, the default
+	KindBinary = "binary"
+	// KindFile is the kind of the data files and fonts, they're
+	// installed as is without being made executable
+	KindFile = "file"
+)
+
+// AssetProfile is what the assets of the previous installs of a binary
+// had in common: their name, once the version is removed, their format
+// and their size range
+type AssetProfile struct {
+	Pattern string `json:"pattern"`
+	Format  string `json:"format"`
+	MinSize int64  `json:"min_size,omitempty"`
+	MaxSize int64  `json:"max_size,omitempty"`
+	// Installs is the number of installs matching the profile
+	Installs int `json:"installs"`
 }
 
 type Binary struct {
@@ -29,17 +129,225 @@ type Binary struct {
 	RemoteName string `json:"remote_name"`
 	Version    string `json:"version"`
 	VersionURL string `json:"version_url"`
-	Hash       string `json:"hash"`
-	URL        string `json:"url"`
-	Provider   string `json:"provider"`
+	// VersionRegex extracts the version from the body of the
+	// version URL, its first capture group is used
+	VersionRegex string `json:"version_regex,omitempty"`
+	// VersionJSONPath extracts the version from the JSON body of the
+	// version URL, it's a dotted path with array indexes (`[0].version`)
+	VersionJSONPath string `json:"version_json_path,omitempty"`
+	// VersionCmd is run to get the version instead of getting the
+	// version URL, its trimmed stdout is used. It's run without a
+	// shell unless VersionCmdShell is set.
+	VersionCmd      string `json:"version_cmd,omitempty"`
+	VersionCmdShell bool   `json:"version_cmd_shell,omitempty"`
+	// Scrape finds the versions in the links of the HTML page at
+	// URL, LinkRegex restricts the links considered and its first
+	// capture group, if any, is used as the version
+	Scrape    bool   `json:"scrape,omitempty"`
+	LinkRegex string `json:"link_regex,omitempty"`
+	// Sources are other places the binary is published, in priority
+	// order after URL. Strategy picks the newest version (default) or
+	// the first source that answers, Source records the one used.
+	Sources []string `json:"sources,omitempty"`
+	// Platforms maps the platforms (`os` or `os/arch`) to the URL of
+	// the binary for tools published in a repository per platform,
+	// each machine uses the URL of its own
+	Platforms map[string]string `json:"platforms,omitempty"`
+	Strategy  string            `json:"strategy,omitempty"`
+	Source    string            `json:"source,omitempty"`
+	// VersionProbe lists the version components (major, minor, patch)
+	// to increment when probing the generic URL for new versions
+	VersionProbe string `json:"version_probe,omitempty"`
+	Hash         string `json:"hash"`
+	URL          string `json:"url"`
+	Provider     string `json:"provider"`
 	// if file is installed from a package format (zip, tar, etc) store
 	// the package path in config so we don't ask the user to select
 	// the path again when upgrading
 	PackagePath string `json:"package_path"`
 	Pinned      bool   `json:"pinned"`
+	// TagPrefix and TagPattern select the releases of a single
+	// component in repositories that host several of them
+	TagPrefix  string `json:"tag_prefix,omitempty"`
+	TagPattern string `json:"tag_pattern,omitempty"`
+	// AssetDigest identifies the remote asset the binary was installed
+	// from. It's used to detect re-published assets of mutable tags
+	AssetDigest string `json:"asset_digest,omitempty"`
+	// VerifiedWith is the algorithm of the checksum which verified
+	// the installed asset (i.e. sha256), empty if it wasn't verified
+	VerifiedWith string `json:"verified_with,omitempty"`
+	// AssetProfile describes the assets the binary was installed
+	// from so the unusual ones are flagged on updates
+	AssetProfile *AssetProfile `json:"asset_profile,omitempty"`
+	// Emulated is the architecture of the installed binary when
+	// it runs through emulation, i.e. amd64 under Rosetta
+	Emulated string `json:"emulated,omitempty"`
+	// MutableTag marks the installed tag as re-published over time
+	// (i.e. `nightly`). Tags named nightly or latest are detected
+	// automatically
+	MutableTag bool `json:"mutable_tag,omitempty"`
+	// Asset selects the release assets to consider. It can be an exact
+	// name, a glob or a regex wrapped in slashes
+	Asset string `json:"asset,omitempty"`
+	// AssetHintPolicy is one of warn, fail or fallback and defines what
+	// happens when the asset hint doesn't match any release asset
+	AssetHintPolicy string `json:"asset_hint_policy,omitempty"`
+	// AssetHintBypassed records that the binary was installed
+	// ignoring its asset hint because of the fallback policy
+	AssetHintBypassed bool `json:"asset_hint_bypassed,omitempty"`
+	// RelaxedFallback retries the asset selection of `bin ensure` with
+	// relaxed requirements when no asset matches, see assets.Relaxation
+	RelaxedFallback bool `json:"relaxed_fallback,omitempty"`
+	// BuildFromSource builds the Go modules from the source of the
+	// release when none of its assets matches the platform, from
+	// MainPackage (relative to the module root) when it's set
+	BuildFromSource bool   `json:"build_from_source,omitempty"`
+	MainPackage     string `json:"main_package,omitempty"`
+	// Target is the name of the directory of the configured paths
+	// the binary is installed in, the default path when it's empty
+	Target string `json:"target,omitempty"`
+	// AssetRewrites rename the candidate assets of some versions
+	// before they're scored, in order, see AssetRewrite
+	AssetRewrites []*AssetRewrite `json:"asset_rewrites,omitempty"`
+	// PreferFormat overrides the preference order of the asset
+	// formats (i.e. `tar.gz`, `zip`, `binary`, `deb`)
+	PreferFormat []string `json:"prefer_format,omitempty"`
+	// SelectedAsset is the pattern of the asset picked by the user
+	// when several of them matched, so it's not asked again
+	SelectedAsset string `json:"selected_asset,omitempty"`
+	// TrackLatest keeps the last minor series installed side by
+	// side, each one named after NameTemplate (i.e. `tool-{major}.{minor}`)
+	TrackLatest  int    `json:"track_latest,omitempty"`
+	NameTemplate string `json:"name_template,omitempty"`
+	// Series is the minor series (i.e. 1.7) of a tracked binary
+	Series string `json:"series,omitempty"`
+	// Headers are sent with the requests of the generic provider. Values
+	// reference credentials from the environment (`Bearer ${TOKEN}`) so
+	// they never get written here
+	Headers map[string]string `json:"headers,omitempty"`
+	// BasicAuth holds `${USER}:${PASSWORD}` references to the
+	// credentials of the generic provider
+	BasicAuth string `json:"basic_auth,omitempty"`
+	// MinTLSVersion overrides the global minimum TLS version for
+	// the hosts of this binary only (i.e. 1.0 for legacy servers)
+	MinTLSVersion string `json:"min_tls_version,omitempty"`
+	// Kind is either binary (default) or file for the data files
+	// and fonts, which don't need to match the platform
+	Kind string `json:"kind,omitempty"`
+	// Stats installs shims recording locally how
+	// often each binary is executed
+	Stats bool `json:"stats,omitempty"`
+	// InstallCompletions and InstallManpages install the shell
+	// completions and man pages shipped in the archive along the
+	// binary, Extras are the paths they're installed at
+	InstallCompletions bool     `json:"install_completions,omitempty"`
+	InstallManpages    bool     `json:"install_manpages,omitempty"`
+	Extras             []string `json:"extras,omitempty"`
+	// Mirrors are URL templates of mirrors of the release assets
+	// (i.e. `https://mirror.example.com/tool/{version}/{asset}`),
+	// tried in order after the asset URL fails, or before it with
+	// MirrorFirst
+	Mirrors     []string `json:"mirrors,omitempty"`
+	MirrorFirst bool     `json:"mirror_first,omitempty"`
+	// SigningKeys are OpenPGP public key files, or directories of
+	// them, verifying the signatures of the checksum files and assets
+	SigningKeys []string `json:"signing_keys,omitempty"`
+	// SignedBy is the id of the key which signed SignedFile, either the
+	// asset or the checksum file which verified it, on the last install
+	SignedBy   string `json:"signed_by,omitempty"`
+	SignedFile string `json:"signed_file,omitempty"`
+	// InstalledAsset and AssetSHA256 are the name and sha256 digest
+	// of the release asset the binary was installed from, they're
+	// pinned in the lockfile
+	InstalledAsset string `json:"installed_asset,omitempty"`
+	AssetSHA256    string `json:"asset_sha256,omitempty"`
+	// AssetURL is the URL the asset was downloaded from and InstalledAt
+	// when the binary was last installed, they're missing for the
+	// binaries installed before they were recorded
+	AssetURL    string     `json:"asset_url,omitempty"`
+	InstalledAt *time.Time `json:"installed_at,omitempty"`
+	// UpdateWindow restricts when the bulk updates update the
+	// binary (i.e. `fri`), see ParseUpdateWindow
+	UpdateWindow string `json:"update_window,omitempty"`
+	// VerifyArgs are the space separated arguments the binary is run
+	// with after being installed (--version by default, `none` to not
+	// run it), the install fails and the previous file is kept when it
+	// exits with an error. Setting them verifies the binary even
+	// without the global verify_install. VerifyVersion also requires
+	// the installed version in the output
+	VerifyArgs    string `json:"verify_args,omitempty"`
+	VerifyVersion bool   `json:"verify_version,omitempty"`
+	// PostInstall are the commands run after the binary is installed
+	// or updated, without a shell unless they're `sh -c '...'`
+	PostInstall []string `json:"post_install,omitempty"`
+}
+
+// IsFile reports whether the entry is a data file
+// instead of an executable
+func (b *Binary) IsFile() bool {
+	return b.Kind == KindFile
+}
+
+// ValidateKind checks the kind of an entry
+func ValidateKind(kind string) error {
+	switch kind {
+	case "", KindBinary, KindFile:
+		return nil
+	}
+	return fmt.Errorf("unknown kind %q, must be %s or %s", kind, KindBinary, KindFile)
+}
+
+// ResolveMinTLSVersion returns the minimum TLS version of the hosts of
+// the binary, b can be nil for the global one. It's empty when none
+// is configured and the default of the providers applies.
+func ResolveMinTLSVersion(b *Binary) (version, origin string) {
+	if b != nil && b.MinTLSVersion != "" {
+		return b.MinTLSVersion, OriginEntry
+	}
+	if cfg.MinTLSVersion != "" {
+		return cfg.MinTLSVersion, OriginConfig
+	}
+	return "", OriginDefault
+}
+
+// SigningKeys returns the key files and directories verifying the
+// signatures of the binary: its own ones and the global keyring
+func SigningKeys(b *Binary) []string {
+	keys := make([]string, 0, len(b.SigningKeys)+1)
+	for _, k := range b.SigningKeys {
+		keys = append(keys, os.ExpandEnv(k))
+	}
+	if cfg.Keyring != "" {
+		keys = append(keys, os.ExpandEnv(cfg.Keyring))
+	}
+	return keys
+}
+
+// GetConfigPath returns the path of the configuration file
+func GetConfigPath() (string, error) {
+	return getConfigPath()
+}
+
+// SetReadOnly prevents any write of the configuration,
+// it must be called before CheckAndLoad
+func SetReadOnly(ro bool) {
+	readOnly = ro
+}
+
+// ReadOnly reports whether the configuration can't be written
+func ReadOnly() bool {
+	return readOnly
+}
+
+// isReadOnlyErr checks if err is due to a location not being writable
+func isReadOnlyErr(err error) bool {
+	return errors.Is(err, fs.ErrPermission) || errors.Is(err, syscall.EROFS)
 }
 
 func CheckAndLoad() error {
+	// the settings missing from the file are
+	// the defaults, not the ones loaded before
+	cfg, project = config{}, nil
 	configPath, err := getConfigPath()
 	if err != nil {
 		return err
@@ -47,28 +355,68 @@ func CheckAndLoad() error {
 
 	confDir := filepath.Dir(configPath)
 
-	if err := os.Mkdir(confDir, 0755); err != nil && !os.IsExist(err) {
-		return fmt.Errorf("Error creating config directory [%v]", err)
+	if !readOnly {
+		if err := os.Mkdir(confDir, 0755); err != nil && !os.IsExist(err) {
+			if !isReadOnlyErr(err) {
+				return fmt.Errorf("Error creating config directory [%v]", err)
+			}
+			log.WithField("path", confDir).Warn("Config directory is not writable, continuing in read-only mode")
+			readOnly = true
+		}
 	}
 
 	log.Debugf("Config directory is: %s", confDir)
-	f, err := os.OpenFile(configPath, os.O_RDWR|os.O_CREATE, 0664)
+	flag := os.O_RDWR | os.O_CREATE
+	if readOnly {
+		flag = os.O_RDONLY
+	}
+	f, err := os.OpenFile(configPath, flag, 0664)
+	if err != nil && !readOnly && isReadOnlyErr(err) {
+		log.WithField("path", configPath).Warn("Config file is not writable, continuing in read-only mode")
+		readOnly = true
+		f, err = os.Open(configPath)
+	}
 	if err != nil && !os.IsNotExist(err) {
 		return err
 	}
 
-	defer f.Close()
-
-	if err := json.NewDecoder(f).Decode(&cfg); err != nil {
-		if err == io.EOF {
-			// Empty file and/or was just created, initialize cfg.Bins
-			cfg.Bins = map[string]*Binary{}
-		} else {
+	if f != nil {
+		defer f.Close()
+		if err := json.NewDecoder(f).Decode(&cfg); err != nil && err != io.EOF {
 			return err
 		}
 	}
+	if cfg.Bins == nil {
+		// Empty file and/or was just created
+		cfg.Bins = map[string]*Binary{}
+	}
+	if configPath == findProjectConfig() {
+		if err := loadProject(configPath); err != nil {
+			return fmt.Errorf("Error loading the global config [%w]", err)
+		}
+		log.Debugf("Using the config of the project at %s", project.root)
+	}
+	if cfg.SplitState {
+		st, err := loadState()
+		if err != nil {
+			return fmt.Errorf("Error loading state file [%w]", err)
+		}
+		cfg.Bins = mergeState(cfg.Bins, st, os.ExpandEnv(cfg.DefaultPath), cfg.Paths)
+	}
+	cfg.Bins = resolvePaths(cfg.Bins, cfg.DefaultPath)
+	if err := validateLibc(cfg.Libc); err != nil {
+		return err
+	}
+	if err := validateHandlers(cfg.Handlers); err != nil {
+		return err
+	}
+	if err := validateAssetRewrites(cfg.Bins); err != nil {
+		return err
+	}
 
-	if len(cfg.DefaultPath) == 0 {
+	// the default path is only needed to install, which
+	// isn't possible without writing the configuration
+	if len(cfg.DefaultPath) == 0 && !readOnly {
 		cfg.DefaultPath, err = getDefaultPath()
 		if err != nil {
 			for {
@@ -99,10 +447,6 @@ func CheckAndLoad() error {
 
 	}
 
-	if cfg.Bins == nil {
-		cfg.Bins = map[string]*Binary{}
-	}
-
 	log.Debugf("Download path set to %s", cfg.DefaultPath)
 	return nil
 }
@@ -120,6 +464,7 @@ func UpsertBinary(c *Binary) error {
 		if err != nil {
 			return err
 		}
+		labelFile(c)
 	}
 
 	return nil
@@ -136,54 +481,160 @@ func RemoveBinaries(paths []string) error {
 }
 
 func write() error {
+	if readOnly {
+		return ErrReadOnly
+	}
 	configPath, err := getConfigPath()
 	if err != nil {
 		return err
 	}
 
-	f, err := os.OpenFile(configPath, os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0664)
+	if cfg.SplitState {
+		err = writeSplit(configPath)
+	} else {
+		err = writeConfig(configPath, asWritten())
+	}
 	if err != nil {
 		return err
 	}
-
-	defer f.Close()
-
-	decoder := json.NewEncoder(f)
-	decoder.SetIndent("", "    ")
-	err = decoder.Encode(cfg)
-	if err != nil {
+	// the changes of bin don't need to be trusted again
+	if project != nil && project.trusted && configPath == project.path {
+		if err := trust(configPath); err != nil {
+			return err
+		}
+	}
+	if err := writeIndex(); err != nil {
 		return err
 	}
-
-	return nil
-}
-
-// GetArch is the running program's operating system target:
-// one of darwin, freebsd, linux, and so on.
-func GetArch() []string {
-	res := []string{runtime.GOARCH}
-	if runtime.GOARCH == "amd64" {
-		// Adding x86_64 manually since the uname syscall (man 2 uname)
-		// is not implemented in all systems
-		res = append(res, "x86_64")
-		res = append(res, "x64")
+	// the lockfile follows the installs and updates
+	if lockFrozen {
+		return nil
 	}
-	return res
+	return WriteLock()
 }
 
 // GetOS is the running program's architecture target:
 // one of 386, amd64, arm, s390x, and so on.
 func GetOS() []string {
-	res := []string{runtime.GOOS}
-	if runtime.GOOS == "windows" {
+	res := []string{goos()}
+	if goos() == "windows" {
 		// Adding win since some repositories release with that as the indicator of a windows binary
 		res = append(res, "win")
 	}
+	if goos() == "darwin" {
+		// macOS releases are often named after the marketing names
+		res = append(res, "macos", "osx")
+	}
 	return res
 }
 
-// getConfigPath returns the path to the configuration directory respecting
+// GetJournalDir returns the directory where the
+// in-progress installs are recorded
+func GetJournalDir() (string, error) {
+	configPath, err := globalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "journal"), nil
+}
+
+// GetStoreDir returns the directory where the versions
+// of the binaries are kept with versioned_installs
+func GetStoreDir() (string, error) {
+	configPath, err := globalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "store"), nil
+}
+
+// SetStats enables or disables the usage statistics
+func SetStats(enabled bool) error {
+	cfg.Stats = enabled
+	return write()
+}
+
+// GetStatsDir returns the directory where the
+// usage counters are stored
+func GetStatsDir() (string, error) {
+	configPath, err := globalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "stats"), nil
+}
+
+// GetExtrasDir returns the directory where the completions of the
+// given shell (bash, zsh or fish) or the man pages of the given section
+// are installed. They default to the user directories searched by the
+// shells and man under $XDG_DATA_HOME, ~/.local/share if unset.
+func GetExtrasDir(kind, section string) (string, error) {
+	configured := map[string]string{
+		"bash": cfg.BashCompletionsPath,
+		"zsh":  cfg.ZshCompletionsPath,
+		"fish": cfg.FishCompletionsPath,
+		"man":  cfg.ManPath,
+	}
+	defaults := map[string]string{
+		"bash": filepath.Join("bash-completion", "completions"),
+		"zsh":  filepath.Join("zsh", "site-functions"),
+		"fish": filepath.Join("fish", "vendor_completions.d"),
+		"man":  "man",
+	}
+	def, ok := defaults[kind]
+	if !ok {
+		return "", fmt.Errorf("unknown kind of extra file %q", kind)
+	}
+	dir := os.ExpandEnv(configured[kind])
+	if dir == "" {
+		data := os.Getenv("XDG_DATA_HOME")
+		if data == "" {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			data = filepath.Join(home, ".local", "share")
+		}
+		dir = filepath.Join(data, def)
+	}
+	if kind == "man" {
+		dir = filepath.Join(dir, "man"+section)
+	}
+	return dir, nil
+}
+
+// GetCacheDir returns the directory where the
+// responses of the APIs are cached
+func GetCacheDir() (string, error) {
+	configPath, err := globalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "cache"), nil
+}
+
+// GetDownloadsDir returns the directory where the downloads
+// in progress are written so they can be resumed
+func GetDownloadsDir() (string, error) {
+	configPath, err := globalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "downloads"), nil
+}
+
+// getConfigPath returns the path to the configuration file: the one of
+// the project when the working directory is in one, the global one otherwise
+func getConfigPath() (string, error) {
+	if p := findProjectConfig(); p != "" {
+		return p, nil
+	}
+	return globalConfigPath()
+}
+
+// globalConfigPath returns the path to the configuration file respecting
 // the `XDG Base Directory specification` using the following strategy:
+//   - honor --config if set, the file is created if needed
 //   - honor BIN_CONFIG is set
 //   - to prevent breaking of existing configurations, check if "$HOME/.bin/config.json"
 //     exists and return "$HOME/.bin"
@@ -194,7 +645,10 @@ func GetOS() []string {
 // ToDo: move the function to config_unix.go and add a similar function for windows,
 //
 //	%APPDATA% might be the right place on windows
-func getConfigPath() (string, error) {
+func globalConfigPath() (string, error) {
+	if explicitPath != "" {
+		return filepath.Abs(explicitPath)
+	}
 
 	c := os.Getenv("BIN_CONFIG")
 	if len(c) > 0 {
@@ -229,11 +683,11 @@ func getConfigPath() (string, error) {
 }
 
 func GetOSSpecificExtensions() []string {
-	switch runtime.GOOS {
+	switch goos() {
 	case "linux":
 		return []string{"AppImage"}
 	case "windows":
-		return []string{"exe"}
+		return []string{"exe", "cmd"}
 	default:
 		return nil
 	}
@@ -6,7 +6,7 @@ import (
 	"os"
 	"strings"
 
-	"github.com/caarlos0/log"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/marcosnils/bin/pkg/options"
 )
 
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"fmt"
+	"time"
+)
+
+// Handler delegates the binaries whose URL matches it to external
+// commands. bin runs Latest to get the latest version and Download
+// to download a version, everything else (verification, install,
+// updates) is done as for the other providers. The commands are run
+// by the shell, the context is passed as BIN_* environment variables.
+type Handler struct {
+	// Latest prints the latest version on its first line
+	Latest string `json:"latest"`
+	// Download writes the asset of $BIN_VERSION into $BIN_OUTPUT_DIR
+	Download string `json:"download"`
+	// Timeout bounds each run of the commands (i.e. 30s)
+	Timeout string `json:"timeout,omitempty"`
+}
+
+// TimeoutDuration returns the configured timeout, 0 if none
+func (h *Handler) TimeoutDuration() time.Duration {
+	d, _ := time.ParseDuration(h.Timeout)
+	return d
+}
+
+func validateHandlers(handlers map[string]*Handler) error {
+	for pattern, h := range handlers {
+		if pattern == "" {
+			return fmt.Errorf("handlers must match a scheme (i.e. corp://) or a host")
+		}
+		if h == nil || h.Latest == "" || h.Download == "" {
+			return fmt.Errorf("handler %s needs both a latest and a download command", pattern)
+		}
+		if h.Timeout != "" {
+			if d, err := time.ParseDuration(h.Timeout); err != nil || d <= 0 {
+				return fmt.Errorf("invalid timeout %q for handler %s, use a duration like 30s or 5m", h.Timeout, pattern)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+)
+
+const (
+	LibcMusl = "musl"
+	LibcGNU  = "gnu"
+	LibcAny  = "any"
+)
+
+var (
+	// libcOverride is set through the --libc flag
+	libcOverride string
+
+	detectLibcOnce sync.Once
+	detectedLibc   string
+)
+
+// SetLibc overrides the libc of the host for the
+// current run, it takes precedence over the configuration
+func SetLibc(libc string) error {
+	if err := validateLibc(libc); err != nil {
+		return err
+	}
+	libcOverride = libc
+	return nil
+}
+
+func validateLibc(libc string) error {
+	switch libc {
+	case "", LibcMusl, LibcGNU, LibcAny:
+		return nil
+	}
+	return fmt.Errorf("invalid libc %q, must be one of musl, gnu or any", libc)
+}
+
+// GetLibc returns the libc the assets are picked for, either musl or gnu.
+// It's empty when any of them can be used, when the libc can't be detected
+// or when not running on Linux.
+func GetLibc() string {
+	libc, _ := ResolveLibc()
+	return libc
+}
+
+// ResolveLibc is GetLibc along with the origin of the value:
+// the --libc flag, the configuration or the host
+func ResolveLibc() (libc, origin string) {
+	libc, origin = libcOverride, OriginFlag
+	if libc == "" {
+		libc, origin = cfg.Libc, OriginConfig
+	}
+	switch libc {
+	case LibcMusl, LibcGNU:
+		return libc, origin
+	case LibcAny:
+		return "", origin
+	}
+
+	// the libc of another system can't be detected
+	if runtime.GOOS != "linux" || goos() != "linux" {
+		return "", OriginDefault
+	}
+	detectLibcOnce.Do(func() {
+		detectedLibc = detectLibc()
+	})
+	return detectedLibc, OriginDetected
+}
+
+// detectLibc looks for the musl dynamic loader, falling
+// back to the output of `ldd --version`
+func detectLibc() string {
+	if m, _ := filepath.Glob("/lib/ld-musl-*"); len(m) > 0 {
+		return LibcMusl
+	}
+	// musl's ldd prints its version to stderr and exits with 1
+	out, _ := exec.Command("ldd", "--version").CombinedOutput()
+	s := strings.ToLower(string(out))
+	switch {
+	case strings.Contains(s, "musl"):
+		return LibcMusl
+	case strings.Contains(s, "glibc") || strings.Contains(s, "gnu libc"):
+		return LibcGNU
+	}
+	return ""
+}
@@ -0,0 +1,138 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LockVersion is the version of the format of the lockfile
+const LockVersion = 1
+
+// lockFrozen keeps the lockfile as is when the configuration is written
+var lockFrozen bool
+
+// Lock pins the exact artifacts the binaries are installed from so
+// they can be installed again, identically, on other machines
+type Lock struct {
+	LockVersion int `json:"lock_version"`
+	// Bins are keyed like the declarative configuration: by
+	// name, unless several binaries share it
+	Bins map[string]*LockedBinary `json:"bins"`
+}
+
+// LockedBinary is the artifact a binary is installed from
+type LockedBinary struct {
+	URL         string `json:"url"`
+	Provider    string `json:"provider"`
+	Version     string `json:"version"`
+	PackagePath string `json:"package_path,omitempty"`
+	// Asset and SHA256 are empty for the providers which
+	// don't download a release asset (i.e. docker)
+	Asset  string `json:"asset,omitempty"`
+	SHA256 string `json:"sha256,omitempty"`
+	// Platform is the os/arch the asset was picked for
+	Platform string `json:"platform"`
+}
+
+// GetLockPath returns the path of the lockfile, BIN_LOCK
+// if set, next to the configuration file otherwise
+func GetLockPath() (string, error) {
+	if l := os.Getenv("BIN_LOCK"); l != "" {
+		return l, nil
+	}
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "bin.lock"), nil
+}
+
+// Platform returns the os/arch of the assets picked on this
+// machine, or the one given with --os and --arch
+func Platform() string {
+	return goos() + "/" + goarch()
+}
+
+// NewLock returns the lock of the installed binaries
+func NewLock(bins map[string]*Binary) *Lock {
+	keys := declarativeKeys(bins)
+	l := &Lock{LockVersion: LockVersion, Bins: make(map[string]*LockedBinary, len(bins))}
+	for p, b := range bins {
+		l.Bins[keys[p]] = &LockedBinary{
+			URL:         b.URL,
+			Provider:    b.Provider,
+			Version:     b.Version,
+			PackagePath: b.PackagePath,
+			Asset:       b.InstalledAsset,
+			SHA256:      b.AssetSHA256,
+			Platform:    Platform(),
+		}
+	}
+	return l
+}
+
+// LoadLock reads the lockfile
+func LoadLock() (*Lock, error) {
+	p, err := GetLockPath()
+	if err != nil {
+		return nil, err
+	}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("there's no lockfile at %s, run `bin lock` to write it", p)
+	}
+	if err != nil {
+		return nil, err
+	}
+	l := &Lock{}
+	if err := json.Unmarshal(b, l); err != nil {
+		return nil, fmt.Errorf("error reading the lockfile %s: %w", p, err)
+	}
+	if l.LockVersion > LockVersion {
+		return nil, fmt.Errorf("the lockfile %s was written by a newer version of bin", p)
+	}
+	return l, nil
+}
+
+// FreezeLock keeps the lockfile as is while the locked artifacts
+// are installed, a failure must not drop them from it
+func FreezeLock(frozen bool) {
+	lockFrozen = frozen
+}
+
+// WriteLock writes the lock of the installed binaries
+func WriteLock() error {
+	if readOnly {
+		return ErrReadOnly
+	}
+	p, err := GetLockPath()
+	if err != nil {
+		return err
+	}
+	return writeJSON(p, NewLock(cfg.Bins))
+}
+
+// Locked returns the binaries of the lock, along with their locked
+// artifact. They're the configured ones when they're installed on this
+// machine, a new binary is expected in the default path otherwise.
+func (l *Lock) Locked() map[*Binary]*LockedBinary {
+	byKey := map[string]*Binary{}
+	for p, k := range declarativeKeys(cfg.Bins) {
+		byKey[k] = cfg.Bins[p]
+	}
+	locked := make(map[*Binary]*LockedBinary, len(l.Bins))
+	for k, lb := range l.Bins {
+		b, ok := byKey[k]
+		if !ok {
+			b = &Binary{URL: lb.URL, Provider: lb.Provider, Version: lb.Version, PackagePath: lb.PackagePath, Path: k}
+			if !filepath.IsAbs(k) {
+				b.Path = filepath.Join(os.ExpandEnv(cfg.DefaultPath), k)
+			}
+		}
+		locked[b] = lb
+	}
+	return locked
+}
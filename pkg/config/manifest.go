@@ -0,0 +1,164 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// ManifestVersion is the version of the manifests written by `bin
+// export`. It's bumped on incompatible changes, the manifests of
+// newer versions are refused instead of being misread.
+const ManifestVersion = 1
+
+// LatestVersion is the version of the binaries
+// of a manifest which aren't pinned
+const LatestVersion = "latest"
+
+// Manifest is the portable list of the binaries, to reproduce
+// them on another machine. It holds neither the paths, nor the
+// secrets (headers, basic auth, credentials of the URLs)
+type Manifest struct {
+	ManifestVersion int               `json:"manifest_version"`
+	Bins            []*ManifestBinary `json:"bins"`
+}
+
+// ManifestBinary is a binary of the manifest, it's
+// installed in the default path under its name
+type ManifestBinary struct {
+	Name     string `json:"name"`
+	URL      string `json:"url"`
+	Provider string `json:"provider,omitempty"`
+	// Version is the version the binary is pinned
+	// to, LatestVersion when it isn't pinned
+	Version      string   `json:"version"`
+	Pinned       bool     `json:"pinned,omitempty"`
+	Asset        string   `json:"asset,omitempty"`
+	TagPrefix    string   `json:"tag_prefix,omitempty"`
+	TagPattern   string   `json:"tag_pattern,omitempty"`
+	PreferFormat []string `json:"prefer_format,omitempty"`
+	Kind         string   `json:"kind,omitempty"`
+	// Target is the name of the configured path the binary is
+	// installed in, resolved by the configuration importing it
+	Target string `json:"target,omitempty"`
+}
+
+// NewManifest returns the manifest of the configured binaries, sorted
+// by name. The binaries sharing the name of another one are left out.
+func NewManifest() *Manifest {
+	return newManifest(cfg.Bins)
+}
+
+func newManifest(bins map[string]*Binary) *Manifest {
+	paths := make([]string, 0, len(bins))
+	for p := range bins {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	m := &Manifest{ManifestVersion: ManifestVersion, Bins: []*ManifestBinary{}}
+	names := map[string]string{}
+	for _, p := range paths {
+		b := bins[p]
+		name := filepath.Base(os.ExpandEnv(b.Path))
+		if other, ok := names[name]; ok {
+			log.WithField("path", os.ExpandEnv(b.Path)).WithField("same_name", other).Warn("Left out of the manifest, another binary has the same name")
+			continue
+		}
+		names[name] = os.ExpandEnv(b.Path)
+		mb := &ManifestBinary{
+			Name:         name,
+			URL:          withoutCredentials(b.URL),
+			Provider:     b.Provider,
+			Version:      LatestVersion,
+			Pinned:       b.Pinned,
+			Asset:        b.Asset,
+			TagPrefix:    b.TagPrefix,
+			TagPattern:   b.TagPattern,
+			PreferFormat: b.PreferFormat,
+			Kind:         b.Kind,
+			Target:       b.Target,
+		}
+		if b.Pinned {
+			mb.Version = b.Version
+		}
+		m.Bins = append(m.Bins, mb)
+	}
+	sort.Slice(m.Bins, func(i, j int) bool { return m.Bins[i].Name < m.Bins[j].Name })
+	return m
+}
+
+// withoutCredentials strips the user and password of the URL
+func withoutCredentials(s string) string {
+	u, err := url.Parse(s)
+	if err != nil || u.User == nil {
+		return s
+	}
+	u.User = nil
+	return u.String()
+}
+
+// IsManifest reports whether the content is a manifest written by `bin export`
+func IsManifest(data []byte) bool {
+	var v struct {
+		ManifestVersion *int `json:"manifest_version"`
+	}
+	return json.Unmarshal(data, &v) == nil && v.ManifestVersion != nil
+}
+
+// ReadManifest parses a manifest written by `bin export`
+func ReadManifest(data []byte) (*Manifest, error) {
+	m := &Manifest{}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("invalid manifest: %w", err)
+	}
+	switch {
+	case m.ManifestVersion < 1:
+		return nil, fmt.Errorf("invalid manifest: missing manifest_version")
+	case m.ManifestVersion > ManifestVersion:
+		return nil, fmt.Errorf("the manifest has the version %d, this version of bin only reads up to %d, upgrade it", m.ManifestVersion, ManifestVersion)
+	}
+	names := map[string]bool{}
+	for _, b := range m.Bins {
+		switch {
+		case b.Name == "" || b.URL == "":
+			return nil, fmt.Errorf("invalid manifest: every binary needs a name and an url")
+		case b.Name != filepath.Base(b.Name):
+			return nil, fmt.Errorf("invalid manifest: the name of %s can't be a path", b.Name)
+		case names[b.Name]:
+			return nil, fmt.Errorf("invalid manifest: %s is listed several times", b.Name)
+		case b.Pinned && (b.Version == "" || b.Version == LatestVersion):
+			return nil, fmt.Errorf("invalid manifest: %s is pinned without a version", b.Name)
+		}
+		if err := ValidateKind(b.Kind); err != nil {
+			return nil, fmt.Errorf("invalid manifest: %s: %w", b.Name, err)
+		}
+		names[b.Name] = true
+	}
+	return m, nil
+}
+
+// Binary returns the binary of the manifest installed in dir
+func (mb *ManifestBinary) Binary(dir string) *Binary {
+	b := &Binary{
+		Path:         filepath.Join(dir, mb.Name),
+		URL:          mb.URL,
+		Provider:     mb.Provider,
+		Pinned:       mb.Pinned,
+		Asset:        mb.Asset,
+		TagPrefix:    mb.TagPrefix,
+		TagPattern:   mb.TagPattern,
+		PreferFormat: mb.PreferFormat,
+		Kind:         mb.Kind,
+		Target:       mb.Target,
+	}
+	if mb.Pinned {
+		b.Version = mb.Version
+	}
+	return b
+}
@@ -0,0 +1,18 @@
+package config
+
+// Origins of the effective settings, they tell where
+// the value used by the commands comes from
+const (
+	// OriginDefault is the built-in default of bin
+	OriginDefault = "default"
+	// OriginDetected is detected from the host
+	OriginDetected = "detected"
+	// OriginConfig is a global field of the configuration
+	OriginConfig = "config"
+	// OriginEntry is a field of the binary's entry
+	OriginEntry = "entry"
+	// OriginEnv is an environment variable
+	OriginEnv = "env"
+	// OriginFlag is a command line flag
+	OriginFlag = "flag"
+)
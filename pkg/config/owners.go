@@ -0,0 +1,80 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+
+	"github.com/marcosnils/bin/pkg/labels"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// indexEntry is the binary managing a file in the index
+type indexEntry struct {
+	// Entry is the key of the binary in the configuration
+	Entry   string `json:"entry"`
+	URL     string `json:"url"`
+	Version string `json:"version"`
+}
+
+// index maps the expanded paths of the managed files to their
+// binary so other tools can look them up without parsing the
+// configuration
+type index struct {
+	Paths map[string]*indexEntry `json:"paths"`
+}
+
+// GetIndexPath returns the path of the index of the
+// managed files, next to the configuration file
+func GetIndexPath() (string, error) {
+	configPath, err := getConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(configPath), "index.json"), nil
+}
+
+func writeIndex() error {
+	p, err := GetIndexPath()
+	if err != nil {
+		return err
+	}
+	idx := index{Paths: make(map[string]*indexEntry, len(cfg.Bins))}
+	for k, b := range cfg.Bins {
+		idx.Paths[filepath.Clean(os.ExpandEnv(k))] = &indexEntry{Entry: k, URL: b.URL, Version: b.Version}
+	}
+	return writeJSON(p, idx)
+}
+
+// Owner returns the binary recording the file at path, nil if
+// none does. Symbolic links are resolved on both sides.
+func Owner(path string) *Binary {
+	path = filepath.Clean(os.ExpandEnv(path))
+	real, _ := filepath.EvalSymlinks(path)
+	for k, b := range cfg.Bins {
+		p := filepath.Clean(os.ExpandEnv(k))
+		if p == path {
+			return b
+		}
+		if real == "" {
+			continue
+		}
+		if r, err := filepath.EvalSymlinks(p); err == nil && r == real {
+			return b
+		}
+	}
+	return nil
+}
+
+// labelFile labels the file of the binary as installed by
+// bin, filesystems without extended attributes are skipped
+func labelFile(b *Binary) {
+	p := os.ExpandEnv(b.Path)
+	if _, err := os.Stat(p); err != nil {
+		return
+	}
+	err := labels.Set(p, labels.Label{Version: b.Version, Source: b.URL})
+	if err != nil && !errors.Is(err, labels.ErrUnsupported) {
+		log.Debugf("Unable to label %s: %v", p, err)
+	}
+}
@@ -0,0 +1,106 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// relativePath returns the path relative to root, if it's under it
+func relativePath(p, root string) (string, bool) {
+	if root == "" {
+		return p, false
+	}
+	rel, err := filepath.Rel(os.ExpandEnv(root), os.ExpandEnv(p))
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return p, false
+	}
+	return rel, true
+}
+
+// PathDir returns the directory of a named path of the
+// configuration, with its variables and ~ expanded
+func PathDir(name string) (string, bool) {
+	return pathDir(cfg.Paths, name)
+}
+
+func pathDir(paths map[string]string, name string) (string, bool) {
+	dir, ok := paths[name]
+	if !ok || name == "" {
+		return "", false
+	}
+	dir = os.ExpandEnv(dir)
+	if rest, ok := strings.CutPrefix(dir, "~"); ok && (rest == "" || os.IsPathSeparator(rest[0])) {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = home + rest
+		}
+	}
+	return filepath.Clean(dir), true
+}
+
+// resolvePaths returns the binaries keyed by their path, the
+// relative ones being resolved against the default path
+func resolvePaths(bins map[string]*Binary, defaultPath string) map[string]*Binary {
+	resolved := make(map[string]*Binary, len(bins))
+	for p, b := range bins {
+		if b.Path != "" && !filepath.IsAbs(os.ExpandEnv(b.Path)) {
+			b.Path = filepath.Join(defaultPath, b.Path)
+			p = b.Path
+		}
+		resolved[p] = b
+	}
+	return resolved
+}
+
+// withRelativePaths returns the configuration as it's written: the
+// paths of the binaries under the default path are relative to it
+// when RelativePaths is set
+func withRelativePaths(c config) config {
+	if !c.RelativePaths {
+		return c
+	}
+	bins := make(map[string]*Binary, len(c.Bins))
+	for p, b := range c.Bins {
+		rel, ok := relativePath(p, c.DefaultPath)
+		if !ok {
+			bins[p] = b
+			continue
+		}
+		nb := *b
+		nb.Path = rel
+		bins[rel] = &nb
+	}
+	c.Bins = bins
+	return c
+}
+
+// MovePaths moves the binaries to their new path, the keys of moves
+// being the current ones. The default path is changed to defaultPath
+// unless it's empty.
+func MovePaths(moves map[string]string, defaultPath string) error {
+	for from, to := range moves {
+		b, ok := cfg.Bins[from]
+		if !ok {
+			return fmt.Errorf("%s isn't managed by bin", from)
+		}
+		delete(cfg.Bins, from)
+		b.Path = to
+		// it's not in the directory of its target anymore
+		if d, ok := pathDir(cfg.Paths, b.Target); ok && filepath.Dir(os.ExpandEnv(to)) != d {
+			b.Target = ""
+		}
+		cfg.Bins[to] = b
+	}
+	if defaultPath != "" {
+		cfg.DefaultPath = defaultPath
+	}
+	if err := write(); err != nil {
+		return err
+	}
+	// the labels are lost when the files are copied across devices
+	for _, to := range moves {
+		labelFile(cfg.Bins[to])
+	}
+	return nil
+}
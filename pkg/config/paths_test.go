@@ -0,0 +1,35 @@
+package config
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestRelativePaths(t *testing.T) {
+	root := filepath.Join(string(filepath.Separator), "home", "user", "bin")
+	other := filepath.Join(string(filepath.Separator), "opt", "tool")
+	c := config{DefaultPath: root, RelativePaths: true, Bins: map[string]*Binary{
+		filepath.Join(root, "gh"):           {Path: filepath.Join(root, "gh")},
+		filepath.Join(root, "sub", "kind"):  {Path: filepath.Join(root, "sub", "kind")},
+		other:                               {Path: other},
+		filepath.Join(root, "..", "escape"): {Path: filepath.Join(root, "..", "escape")},
+	}}
+
+	written := withRelativePaths(c)
+	for _, p := range []string{"gh", filepath.Join("sub", "kind"), other} {
+		if b, ok := written.Bins[p]; !ok || b.Path != p {
+			t.Errorf("expected %s to be written as is, got %+v", p, b)
+		}
+	}
+	if _, ok := c.Bins[filepath.Join(root, "gh")]; !ok || c.Bins[filepath.Join(root, "gh")].Path != filepath.Join(root, "gh") {
+		t.Error("expected the configuration in memory to be left untouched")
+	}
+
+	moved := filepath.Join(string(filepath.Separator), "home", "user", ".local", "bin")
+	resolved := resolvePaths(written.Bins, moved)
+	for _, p := range []string{filepath.Join(moved, "gh"), filepath.Join(moved, "sub", "kind"), other} {
+		if b, ok := resolved[p]; !ok || b.Path != p {
+			t.Errorf("expected %s to be resolved, got %+v", p, b)
+		}
+	}
+}
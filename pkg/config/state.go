@@ -0,0 +1,274 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// localFields are the fields of a binary that only make sense on the
+// machine it's installed on. When the state is split, they're written
+// to the state file instead of the configuration.
+var localFields = []string{"path", "version", "hash", "asset_digest", "verified_with", "asset_profile", "emulated", "source", "asset_hint_bypassed", "extras", "signed_by", "signed_file", "installed_asset", "asset_sha256", "asset_url", "installed_at"}
+
+// binaryState is the machine-local part of a binary
+type binaryState struct {
+	Path              string        `json:"path"`
+	Version           string        `json:"version,omitempty"`
+	Hash              string        `json:"hash,omitempty"`
+	AssetDigest       string        `json:"asset_digest,omitempty"`
+	VerifiedWith      string        `json:"verified_with,omitempty"`
+	AssetProfile      *AssetProfile `json:"asset_profile,omitempty"`
+	Emulated          string        `json:"emulated,omitempty"`
+	Source            string        `json:"source,omitempty"`
+	AssetHintBypassed bool          `json:"asset_hint_bypassed,omitempty"`
+	Extras            []string      `json:"extras,omitempty"`
+	SignedBy          string        `json:"signed_by,omitempty"`
+	SignedFile        string        `json:"signed_file,omitempty"`
+	InstalledAsset    string        `json:"installed_asset,omitempty"`
+	AssetSHA256       string        `json:"asset_sha256,omitempty"`
+	AssetURL          string        `json:"asset_url,omitempty"`
+	InstalledAt       *time.Time    `json:"installed_at,omitempty"`
+}
+
+type state struct {
+	Bins map[string]*binaryState `json:"bins"`
+}
+
+// GetStatePath returns the path of the machine-local state file, next
+// to the configuration of the project in use if any, otherwise honoring
+// BIN_STATE and the `XDG Base Directory specification`
+func GetStatePath() (string, error) {
+	if project != nil {
+		return filepath.Join(project.root, ProjectDir, "state.json"), nil
+	}
+	return userStatePath()
+}
+
+// userStatePath returns the path of the state file of the global configuration
+func userStatePath() (string, error) {
+	if s := os.Getenv("BIN_STATE"); s != "" {
+		return s, nil
+	}
+	if d := os.Getenv("XDG_DATA_HOME"); d != "" {
+		return filepath.Join(d, "bin", "state.json"), nil
+	}
+	if runtime.GOOS == "windows" {
+		if d := os.Getenv("LOCALAPPDATA"); d != "" {
+			return filepath.Join(d, "bin", "state.json"), nil
+		}
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".local", "share", "bin", "state.json"), nil
+}
+
+// declarativeKeys returns the key of every binary in the declarative configuration.
+// Paths are machine specific so binaries are keyed by name, unless
+// several of them share it.
+func declarativeKeys(bins map[string]*Binary) map[string]string {
+	names := map[string]int{}
+	for p := range bins {
+		names[filepath.Base(p)]++
+	}
+	keys := make(map[string]string, len(bins))
+	for p := range bins {
+		if n := filepath.Base(p); names[n] == 1 {
+			keys[p] = n
+		} else {
+			keys[p] = p
+		}
+	}
+	return keys
+}
+
+// splitState returns the declarative configuration, without the
+// machine-local fields, and the state holding them
+func splitState(c config) (map[string]any, *state, error) {
+	raw, err := json.Marshal(c)
+	if err != nil {
+		return nil, nil, err
+	}
+	decl := map[string]any{}
+	if err := json.Unmarshal(raw, &decl); err != nil {
+		return nil, nil, err
+	}
+
+	keys := declarativeKeys(c.Bins)
+	bins := map[string]any{}
+	st := &state{Bins: map[string]*binaryState{}}
+	for p, b := range c.Bins {
+		fields, err := declarativeBinary(b)
+		if err != nil {
+			return nil, nil, err
+		}
+		bins[keys[p]] = fields
+		st.Bins[keys[p]] = &binaryState{
+			Path:              b.Path,
+			Version:           b.Version,
+			Hash:              b.Hash,
+			AssetDigest:       b.AssetDigest,
+			VerifiedWith:      b.VerifiedWith,
+			AssetProfile:      b.AssetProfile,
+			Emulated:          b.Emulated,
+			Source:            b.Source,
+			AssetHintBypassed: b.AssetHintBypassed,
+			Extras:            b.Extras,
+			SignedBy:          b.SignedBy,
+			SignedFile:        b.SignedFile,
+			InstalledAsset:    b.InstalledAsset,
+			AssetSHA256:       b.AssetSHA256,
+			AssetURL:          b.AssetURL,
+			InstalledAt:       b.InstalledAt,
+		}
+	}
+	decl["bins"] = bins
+	return decl, st, nil
+}
+
+// declarativeBinary returns the fields of the binary which can be
+// shared across machines. The version of pinned binaries is a
+// constraint so it's kept.
+func declarativeBinary(b *Binary) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(b)
+	if err != nil {
+		return nil, err
+	}
+	fields := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil, err
+	}
+	for _, f := range localFields {
+		if f == "version" && b.Pinned {
+			continue
+		}
+		delete(fields, f)
+	}
+	return fields, nil
+}
+
+// mergeState fills the machine-local fields of the declarative
+// binaries, keyed by name, and returns them keyed by path. Binaries
+// without state aren't installed on this machine yet, they're
+// expected in the default path or the directory of their target.
+func mergeState(bins map[string]*Binary, st *state, defaultPath string, paths map[string]string) map[string]*Binary {
+	merged := make(map[string]*Binary, len(bins))
+	for key, b := range bins {
+		s, ok := st.Bins[key]
+		switch {
+		case ok:
+			b.Path = s.Path
+			if !b.Pinned || b.Version == "" {
+				b.Version = s.Version
+			}
+			b.Hash = s.Hash
+			b.AssetDigest = s.AssetDigest
+			b.VerifiedWith = s.VerifiedWith
+			b.AssetProfile = s.AssetProfile
+			b.Emulated = s.Emulated
+			b.Source = s.Source
+			b.AssetHintBypassed = s.AssetHintBypassed
+			b.Extras = s.Extras
+			b.SignedBy = s.SignedBy
+			b.SignedFile = s.SignedFile
+			b.InstalledAsset = s.InstalledAsset
+			b.AssetSHA256 = s.AssetSHA256
+			b.AssetURL = s.AssetURL
+			b.InstalledAt = s.InstalledAt
+		case filepath.IsAbs(key):
+			b.Path = key
+		default:
+			dir := defaultPath
+			if d, ok := pathDir(paths, b.Target); ok {
+				dir = d
+			}
+			b.Path = filepath.Join(dir, key)
+		}
+		merged[b.Path] = b
+	}
+	return merged
+}
+
+// loadState reads the state file of the configuration in use
+func loadState() (*state, error) {
+	p, err := GetStatePath()
+	if err != nil {
+		return nil, err
+	}
+	return readState(p)
+}
+
+// readState reads the state file at p, which might not exist yet
+func readState(p string) (*state, error) {
+	st := &state{Bins: map[string]*binaryState{}}
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return st, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(b, st); err != nil {
+		return nil, err
+	}
+	if st.Bins == nil {
+		st.Bins = map[string]*binaryState{}
+	}
+	return st, nil
+}
+
+// writeSplit writes the declarative configuration and the state
+func writeSplit(configPath string) error {
+	decl, st, err := splitState(asWritten())
+	if err != nil {
+		return err
+	}
+
+	statePath, err := GetStatePath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(statePath), 0o755); err != nil {
+		return err
+	}
+	if err := writeJSON(statePath, st); err != nil {
+		return err
+	}
+	return writeConfig(configPath, decl)
+}
+
+// Declarative returns the configuration without the machine-local
+// fields, as it's written when the state is split
+func Declarative() (map[string]any, error) {
+	decl, _, err := splitState(cfg)
+	return decl, err
+}
+
+// SplitState moves the machine-local fields of the
+// binaries to the state file
+func SplitState() error {
+	cfg.SplitState = true
+	return write()
+}
+
+func writeJSON(p string, v any) error {
+	out, err := encodeJSON(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, out, 0664)
+}
+
+// writeConfig writes the configuration file in its canonical form
+func writeConfig(p string, v any) error {
+	out, err := canonicalConfig(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, out, 0664)
+}
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitState(t *testing.T) {
+	dir := t.TempDir()
+	c := config{
+		DefaultPath: dir,
+		Bins: map[string]*Binary{
+			filepath.Join(dir, "kind"): {
+				Path: filepath.Join(dir, "kind"), Version: "v0.20.0", Hash: "abc", URL: "https://github.com/kubernetes-sigs/kind", Provider: "github", AssetDigest: "sha256:def",
+			},
+			"/opt/bin/jq": {
+				Path: "/opt/bin/jq", Version: "jq-1.6", Hash: "123", URL: "https://github.com/jqlang/jq", Provider: "github", Pinned: true,
+			},
+		},
+	}
+
+	decl, st, err := splitState(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := json.Marshal(decl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shared config
+	if err := json.Unmarshal(raw, &shared); err != nil {
+		t.Fatal(err)
+	}
+	kind, jq := shared.Bins["kind"], shared.Bins["jq"]
+	if kind == nil || jq == nil {
+		t.Fatalf("expected the binaries to be keyed by name, got %v", shared.Bins)
+	}
+	if kind.Path != "" || kind.Version != "" || kind.Hash != "" || kind.AssetDigest != "" {
+		t.Fatalf("expected no machine-local fields, got %+v", kind)
+	}
+	if jq.Version != "jq-1.6" {
+		t.Fatalf("expected the version of pinned binaries to be kept, got %q", jq.Version)
+	}
+
+	merged := mergeState(shared.Bins, st, dir, nil)
+	if !reflect.DeepEqual(merged, c.Bins) {
+		t.Fatalf("expected the merged state to match the original configuration\n%+v\n%+v", merged, c.Bins)
+	}
+
+	// binaries not installed on this machine yet go to the default path
+	merged = mergeState(map[string]*Binary{"kind": {URL: "https://github.com/kubernetes-sigs/kind"}}, &state{}, dir, nil)
+	if b := merged[filepath.Join(dir, "kind")]; b == nil || b.Version != "" {
+		t.Fatalf("expected kind in the default path without version, got %v", merged)
+	}
+
+	// or to the directory of their target on this machine
+	work := filepath.Join(dir, "work")
+	merged = mergeState(map[string]*Binary{"kind": {URL: "https://github.com/kubernetes-sigs/kind", Target: "work"}}, &state{}, dir, map[string]string{"work": work})
+	if b := merged[filepath.Join(work, "kind")]; b == nil {
+		t.Fatalf("expected kind in the directory of its target, got %v", merged)
+	}
+}
+
+func TestDeclarativeKeys(t *testing.T) {
+	keys := declarativeKeys(map[string]*Binary{
+		"/usr/local/bin/tool": {},
+		"/opt/bin/tool":       {},
+		"/opt/bin/kind":       {},
+	})
+	expected := map[string]string{
+		"/usr/local/bin/tool": "/usr/local/bin/tool",
+		"/opt/bin/tool":       "/opt/bin/tool",
+		"/opt/bin/kind":       "kind",
+	}
+	if !reflect.DeepEqual(keys, expected) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// trustFile records the hashes of the project configurations the
+// user trusts, next to the global configuration, see Trust
+const trustFile = "trusted.json"
+
+// trustPath returns the path of the trust file
+func trustPath() (string, error) {
+	p, err := globalConfigPath()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(p), trustFile), nil
+}
+
+// readTrusted returns the hash of the trusted project
+// configurations by the absolute path of their file
+func readTrusted() (map[string]string, error) {
+	p, err := trustPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return map[string]string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	trusted := map[string]string{}
+	if err := json.Unmarshal(data, &trusted); err != nil {
+		return nil, fmt.Errorf("invalid trust file %s: %w", p, err)
+	}
+	return trusted, nil
+}
+
+// fileHash returns the hex sha256 digest of the file at p
+func fileHash(p string) (string, error) {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.Sum256(data)
+	return hex.EncodeToString(h[:]), nil
+}
+
+// isTrusted checks if the project configuration at p
+// was trusted with its current content
+func isTrusted(p string) (bool, error) {
+	trusted, err := readTrusted()
+	if err != nil {
+		return false, err
+	}
+	h, err := fileHash(p)
+	if err != nil {
+		return false, err
+	}
+	return trusted[p] == h, nil
+}
+
+// trust records the current content of the project configuration at p
+func trust(p string) error {
+	trusted, err := readTrusted()
+	if err != nil {
+		return err
+	}
+	h, err := fileHash(p)
+	if err != nil {
+		return err
+	}
+	trusted[p] = h
+	b, err := json.MarshalIndent(trusted, "", "    ")
+	if err != nil {
+		return err
+	}
+	tp, err := trustPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(tp), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(tp, b, 0o600)
+}
+
+// Trust trusts the configuration of the project in use with its
+// current content, it returns the path of the configuration. Once
+// its content changes it has to be trusted again, unless it's
+// written by bin itself.
+func Trust() (string, error) {
+	if project == nil {
+		return "", errors.New("the working directory isn't in a project, there's no .bin/config.json up from it")
+	}
+	if err := trust(project.path); err != nil {
+		return "", err
+	}
+	project.trusted = true
+	return project.path, nil
+}
+
+// UntrustedSettings returns the settings of the project configuration
+// in use which run commands, send credentials from the environment or
+// route the requests (the settings of the providers, i.e. proxies and
+// GHES_BASE_URL) when it isn't trusted, none when it is or for the
+// global one
+func UntrustedSettings() []string {
+	if project == nil || project.trusted {
+		return nil
+	}
+	res := []string{}
+	if len(cfg.Handlers) > 0 {
+		res = append(res, "handlers")
+	}
+	if len(cfg.Settings) > 0 {
+		res = append(res, "settings")
+	}
+	for _, b := range cfg.Bins {
+		name := filepath.Base(os.ExpandEnv(b.Path))
+		set := map[string]bool{
+			"version_cmd":  b.VersionCmd != "",
+			"post_install": len(b.PostInstall) > 0,
+			"verify_args":  b.VerifyArgs != "",
+			"basic_auth":   b.BasicAuth != "",
+			// the build runs the code of the module
+			"build_from_source": b.BuildFromSource,
+		}
+		for _, v := range b.Headers {
+			if strings.Contains(v, "$") {
+				set["headers"] = true
+			}
+		}
+		for k, ok := range set {
+			if ok {
+				res = append(res, fmt.Sprintf("%s of %s", k, name))
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// weekdays are the names of the days of an update window
+var weekdays = []string{"sunday", "monday", "tuesday", "wednesday", "thursday", "friday", "saturday"}
+
+// UpdateWindow restricts when a binary is updated by the bulk updates.
+// It's a list of periods separated by semicolons, each one made of
+// days and/or hours, i.e. `fri`, `sat,sun` or `mon-fri 18:00-08:00`.
+// Hours ending before they start run past midnight, from the days
+// they start on.
+type UpdateWindow struct {
+	periods []windowPeriod
+}
+
+type windowPeriod struct {
+	days [7]bool
+	// start and end are minutes of the day, both
+	// are 0 when the whole days are allowed
+	start, end int
+}
+
+// ParseUpdateWindow parses the update window of a binary
+func ParseUpdateWindow(s string) (*UpdateWindow, error) {
+	w := &UpdateWindow{}
+	for _, p := range strings.Split(s, ";") {
+		fields := strings.Fields(strings.ToLower(p))
+		if len(fields) == 0 || len(fields) > 2 {
+			return nil, fmt.Errorf("invalid update window %q, use days and/or hours, i.e. `mon-fri 18:00-08:00`", s)
+		}
+		period := windowPeriod{}
+		if strings.Contains(fields[0], ":") {
+			period.days = [7]bool{true, true, true, true, true, true, true}
+		} else {
+			days, err := parseDays(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("invalid update window %q: %w", s, err)
+			}
+			period.days = days
+			fields = fields[1:]
+		}
+		if len(fields) == 1 {
+			var err error
+			if period.start, period.end, err = parseHours(fields[0]); err != nil {
+				return nil, fmt.Errorf("invalid update window %q: %w", s, err)
+			}
+		}
+		w.periods = append(w.periods, period)
+	}
+	return w, nil
+}
+
+// parseDays parses days like `mon-fri` or `sat,sun`
+func parseDays(s string) ([7]bool, error) {
+	var days [7]bool
+	for _, r := range strings.Split(s, ",") {
+		from, to, isRange := strings.Cut(r, "-")
+		start, err := weekday(from)
+		if err != nil {
+			return days, err
+		}
+		end := start
+		if isRange {
+			if end, err = weekday(to); err != nil {
+				return days, err
+			}
+		}
+		for d := start; ; d = (d + 1) % 7 {
+			days[d] = true
+			if d == end {
+				break
+			}
+		}
+	}
+	return days, nil
+}
+
+// weekday returns the day named in full or abbreviated (i.e. fri)
+func weekday(s string) (int, error) {
+	names := make([]string, len(weekdays))
+	for i, d := range weekdays {
+		if s == d || s == d[:3] {
+			return i, nil
+		}
+		names[i] = d[:3]
+	}
+	return 0, fmt.Errorf("unknown day %q, use %s", s, strings.Join(names, ", "))
+}
+
+// parseHours parses hours like `18:00-08:00`, as minutes of the day
+func parseHours(s string) (int, int, error) {
+	from, to, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, fmt.Errorf("invalid hours %q, use a range like 18:00-08:00", s)
+	}
+	start, err := minuteOfDay(from)
+	if err != nil {
+		return 0, 0, err
+	}
+	end, err := minuteOfDay(to)
+	if err != nil {
+		return 0, 0, err
+	}
+	if start == end {
+		return 0, 0, fmt.Errorf("empty hours %q", s)
+	}
+	return start, end, nil
+}
+
+func minuteOfDay(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid hour %q, use HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t is in the window. It's evaluated with the
+// wall clock of t, so the hours keep their meaning across DST changes
+func (w *UpdateWindow) Contains(t time.Time) bool {
+	day, minute := int(t.Weekday()), t.Hour()*60+t.Minute()
+	yesterday := (day + 6) % 7
+	for _, p := range w.periods {
+		switch {
+		case p.start == 0 && p.end == 0:
+			if p.days[day] {
+				return true
+			}
+		case p.start < p.end:
+			if p.days[day] && minute >= p.start && minute < p.end {
+				return true
+			}
+		default:
+			// the hours run past midnight
+			if (p.days[day] && minute >= p.start) || (p.days[yesterday] && minute < p.end) {
+				return true
+			}
+		}
+	}
+	return false
+}
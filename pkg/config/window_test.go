@@ -0,0 +1,67 @@
+package config
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+func TestUpdateWindow(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	at := func(s string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", s, ny)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+
+	cases := []struct {
+		window string
+		at     time.Time
+		in     bool
+	}{
+		// 2024-03-08 is a friday
+		{"fri", at("2024-03-08 10:00"), true},
+		{"friday", at("2024-03-09 00:00"), false},
+		{"sat,sun", at("2024-03-10 12:00"), true},
+		{"mon-fri", at("2024-03-09 12:00"), false},
+		{"fri-mon", at("2024-03-11 23:59"), true},
+		{"09:00-17:00", at("2024-03-09 16:59"), true},
+		{"09:00-17:00", at("2024-03-09 17:00"), false},
+		// hours past midnight belong to the day they start on
+		{"fri 22:00-06:00", at("2024-03-09 05:59"), true},
+		{"fri 22:00-06:00", at("2024-03-08 05:59"), false},
+		{"mon-fri 18:00-08:00; sat,sun", at("2024-03-07 12:00"), false},
+		{"mon-fri 18:00-08:00; sat,sun", at("2024-03-10 12:00"), true},
+
+		// DST starts on 2024-03-10 at 02:00, the clock jumps to 03:00
+		{"sun 01:00-03:00", at("2024-03-10 01:59"), true},
+		{"sun 01:00-03:00", at("2024-03-10 01:59").Add(time.Minute), false},
+		// the hours are the ones of the wall clock, not elapsed ones
+		{"sun 00:00-04:00", at("2024-03-10 00:00").Add(2 * time.Hour), true},
+		{"sun 00:00-04:00", at("2024-03-10 00:00").Add(3 * time.Hour), false},
+		// DST ends on 2024-11-03 at 02:00, the clock goes back to 01:00
+		{"sat 22:00-02:00", at("2024-11-03 01:30"), true},
+		{"sat 22:00-02:00", at("2024-11-03 01:30").Add(time.Hour), true},
+		{"sat 22:00-02:00", at("2024-11-03 01:30").Add(2 * time.Hour), false},
+	}
+	for _, c := range cases {
+		w, err := ParseUpdateWindow(c.window)
+		if err != nil {
+			t.Fatalf("%s: %v", c.window, err)
+		}
+		if in := w.Contains(c.at); in != c.in {
+			t.Errorf("%s at %s: expected %v, got %v", c.window, c.at, c.in, in)
+		}
+	}
+
+	for _, s := range []string{"", "funday", "fri 9-17", "fri 17:00-17:00", "fri 09:00-17:00 now", "mon-fri;"} {
+		if _, err := ParseUpdateWindow(s); err == nil {
+			t.Errorf("expected %q to be invalid", s)
+		}
+	}
+}
@@ -0,0 +1,317 @@
+// Package fakeforge serves synthetic releases through the subset of the
+// GitHub API used by the github provider, so the providers and commands
+// can be exercised end-to-end without network. It backs `bin demo` and
+// the integration tests, which change the releases while it's serving
+// them (a release added, an asset renamed or re-published) to write
+// scenarios without fixtures.
+//
+// The forge answers like a GitHub Enterprise Server, the provider uses
+// it when GHES_BASE_URL points at its API, see APIPath.
+package fakeforge
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// APIPath is the path of the API under the URL of the forge,
+// GHES_BASE_URL is the URL of the forge followed by it
+const APIPath = "/api/v3/"
+
+// Forge is an http.Handler serving the releases of its repositories
+type Forge struct {
+	mu     sync.Mutex
+	repos  map[string][]*release
+	nextID int64
+	now    time.Time
+}
+
+// Release is a release published to the forge
+type Release struct {
+	Tag        string
+	Prerelease bool
+	Notes      string
+	Assets     []Asset
+}
+
+// Asset is a file attached to a release
+type Asset struct {
+	Name string
+	Data []byte
+}
+
+type release struct {
+	id int64
+	Release
+	assets      []*asset
+	publishedAt time.Time
+}
+
+type asset struct {
+	id        int64
+	name      string
+	data      []byte
+	updatedAt time.Time
+}
+
+// New returns an empty forge
+func New() *Forge {
+	return &Forge{repos: map[string][]*release{}, now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// tick returns the time of the next change, the
+// forge has its own clock so its output is stable
+func (f *Forge) tick() time.Time {
+	f.now = f.now.Add(time.Hour)
+	return f.now
+}
+
+func (f *Forge) id() int64 {
+	f.nextID++
+	return f.nextID
+}
+
+// AddRelease publishes a release of the repository (owner/repo),
+// the last one added is the latest
+func (f *Forge) AddRelease(repo string, r Release) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, err := f.find(repo, r.Tag); err == nil {
+		return fmt.Errorf("%s already has a release %s", repo, r.Tag)
+	}
+	rel := &release{id: f.id(), Release: r, publishedAt: f.tick()}
+	for _, a := range r.Assets {
+		rel.assets = append(rel.assets, &asset{id: f.id(), name: a.Name, data: a.Data, updatedAt: f.tick()})
+	}
+	f.repos[repo] = append(f.repos[repo], rel)
+	return nil
+}
+
+// RemoveRelease deletes a release, i.e. a yanked version
+func (f *Forge) RemoveRelease(repo, tag string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	rel, err := f.find(repo, tag)
+	if err != nil {
+		return err
+	}
+	f.repos[repo] = slices.DeleteFunc(f.repos[repo], func(r *release) bool { return r == rel })
+	return nil
+}
+
+// RenameAsset renames an asset of a release, i.e. when a
+// project changes its naming scheme
+func (f *Forge) RenameAsset(repo, tag, from, to string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, err := f.findAsset(repo, tag, from)
+	if err != nil {
+		return err
+	}
+	a.name, a.updatedAt = to, f.tick()
+	return nil
+}
+
+// ReplaceAsset re-publishes an asset of a release with other content.
+// The other assets are left alone, so the checksums published along it
+// don't match anymore
+func (f *Forge) ReplaceAsset(repo, tag, name string, data []byte) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	a, err := f.findAsset(repo, tag, name)
+	if err != nil {
+		return err
+	}
+	a.data, a.updatedAt = data, f.tick()
+	return nil
+}
+
+// Repos returns the repositories of the forge
+func (f *Forge) Repos() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.sortedRepos()
+}
+
+func (f *Forge) sortedRepos() []string {
+	repos := make([]string, 0, len(f.repos))
+	for r := range f.repos {
+		repos = append(repos, r)
+	}
+	slices.Sort(repos)
+	return repos
+}
+
+// Tags returns the tags of the releases of the repository, the latest first
+func (f *Forge) Tags(repo string) []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	tags := []string{}
+	for _, r := range slices.Backward(f.repos[repo]) {
+		tags = append(tags, r.Tag)
+	}
+	return tags
+}
+
+func (f *Forge) find(repo, tag string) (*release, error) {
+	for _, r := range f.repos[repo] {
+		if r.Tag == tag {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no release %s", repo, tag)
+}
+
+func (f *Forge) findAsset(repo, tag, name string) (*asset, error) {
+	r, err := f.find(repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range r.assets {
+		if a.name == name {
+			return a, nil
+		}
+	}
+	return nil, fmt.Errorf("release %s of %s has no asset %s", tag, repo, name)
+}
+
+// ServeHTTP answers the requests of the github provider: the
+// releases of a repository, by id and by tag, the latest one
+// and the content of the assets, along with the search of the
+// repositories whose name contains the query
+func (f *Forge) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	p, ok := strings.CutPrefix(r.URL.Path, APIPath)
+	if !ok {
+		notFound(w)
+		return
+	}
+	if p == "meta" {
+		writeJSON(w, map[string]any{})
+		return
+	}
+	host := "http://" + r.Host
+	if r.TLS != nil {
+		host = "https://" + r.Host
+	}
+	if p == "search/repositories" {
+		items := []map[string]any{}
+		for _, repo := range f.sortedRepos() {
+			if owner, name, _ := strings.Cut(repo, "/"); strings.Contains(name, r.URL.Query().Get("q")) {
+				items = append(items, map[string]any{"full_name": repo, "name": name, "owner": map[string]any{"login": owner}, "html_url": host + "/" + repo})
+			}
+		}
+		writeJSON(w, map[string]any{"total_count": len(items), "items": items})
+		return
+	}
+
+	// repos/{owner}/{repo}/releases[/...]
+	parts := strings.SplitN(p, "/", 5)
+	if len(parts) < 4 || parts[0] != "repos" || parts[3] != "releases" {
+		notFound(w)
+		return
+	}
+	repo := parts[1] + "/" + parts[2]
+	releases, ok := f.repos[repo]
+	if !ok {
+		notFound(w)
+		return
+	}
+	base := host + APIPath + "repos/" + repo
+	page := host + "/" + repo
+
+	rest := ""
+	if len(parts) == 5 {
+		rest = parts[4]
+	}
+	switch {
+	case rest == "":
+		list := []map[string]any{}
+		for _, rel := range slices.Backward(releases) {
+			list = append(list, rel.json(base, page))
+		}
+		writeJSON(w, list)
+	case rest == "latest":
+		for _, rel := range slices.Backward(releases) {
+			if !rel.Prerelease {
+				writeJSON(w, rel.json(base, page))
+				return
+			}
+		}
+		notFound(w)
+	case strings.HasPrefix(rest, "tags/"):
+		rel, err := f.find(repo, strings.TrimPrefix(rest, "tags/"))
+		if err != nil {
+			notFound(w)
+			return
+		}
+		writeJSON(w, rel.json(base, page))
+	case strings.HasPrefix(rest, "assets/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(rest, "assets/"), 10, 64)
+		for _, rel := range releases {
+			for _, a := range rel.assets {
+				if a.id == id {
+					w.Header().Set("Content-Type", "application/octet-stream")
+					w.Header().Set("Content-Length", strconv.Itoa(len(a.data)))
+					w.Write(a.data)
+					return
+				}
+			}
+		}
+		notFound(w)
+	default:
+		id, _ := strconv.ParseInt(rest, 10, 64)
+		for _, rel := range releases {
+			if rel.id == id {
+				writeJSON(w, rel.json(base, page))
+				return
+			}
+		}
+		notFound(w)
+	}
+}
+
+// json returns the release as the GitHub API describes it, base
+// is the API URL of the repository and page its web page
+func (r *release) json(base, page string) map[string]any {
+	as := []map[string]any{}
+	for _, a := range r.assets {
+		as = append(as, map[string]any{
+			"id":                   a.id,
+			"name":                 a.name,
+			"size":                 len(a.data),
+			"url":                  fmt.Sprintf("%s/releases/assets/%d", base, a.id),
+			"browser_download_url": fmt.Sprintf("%s/releases/assets/%d", base, a.id),
+			"updated_at":           a.updatedAt.Format(time.RFC3339),
+		})
+	}
+	return map[string]any{
+		"id":           r.id,
+		"tag_name":     r.Tag,
+		"name":         r.Tag,
+		"body":         r.Notes,
+		"prerelease":   r.Prerelease,
+		"published_at": r.publishedAt.Format(time.RFC3339),
+		"html_url":     page + "/releases/tag/" + r.Tag,
+		"assets":       as,
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func notFound(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusNotFound)
+	fmt.Fprint(w, `{"message": "Not Found"}`)
+}
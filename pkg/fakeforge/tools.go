@@ -0,0 +1,116 @@
+package fakeforge
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// Platforms are the os/arch the tools are built for, along the host
+var Platforms = []string{"linux/amd64", "linux/arm64", "darwin/amd64", "darwin/arm64", "windows/amd64"}
+
+// Script is the executable of a synthetic tool,
+// it prints its name and version when run
+func Script(name, version string) []byte {
+	return []byte(fmt.Sprintf("#!/bin/sh\necho \"%s version %s\"\n", name, strings.TrimPrefix(version, "v")))
+}
+
+// AssetName is the name of the archive of a tool for a platform,
+// like the ones of goreleaser (tool_1.2.0_linux_amd64.tar.gz)
+func AssetName(name, version, platform string) string {
+	goos, arch, _ := strings.Cut(platform, "/")
+	ext := "tar.gz"
+	if goos == "windows" {
+		ext = "zip"
+	}
+	return fmt.Sprintf("%s_%s_%s_%s.%s", name, strings.TrimPrefix(version, "v"), goos, arch, ext)
+}
+
+// ToolRelease returns the release of a synthetic tool: an archive of
+// its Script per platform and their checksums.txt. It's built for
+// the host and the given platforms, or Platforms when none is given
+func ToolRelease(name, version string, platforms ...string) (Release, error) {
+	if len(platforms) == 0 {
+		platforms = Platforms
+	}
+	if host := runtime.GOOS + "/" + runtime.GOARCH; !slices.Contains(platforms, host) {
+		platforms = append(slices.Clone(platforms), host)
+	}
+
+	r := Release{Tag: version, Notes: fmt.Sprintf("## %s %s\n\n- Synthetic release of the demo forge\n", name, version)}
+	var sums bytes.Buffer
+	for _, p := range platforms {
+		asset := AssetName(name, version, p)
+		var data []byte
+		var err error
+		if strings.HasPrefix(p, "windows/") {
+			data, err = zipArchive(name+".exe", Script(name, version))
+		} else {
+			data, err = tarGzArchive(name, Script(name, version))
+		}
+		if err != nil {
+			return Release{}, err
+		}
+		r.Assets = append(r.Assets, Asset{Name: asset, Data: data})
+		fmt.Fprintf(&sums, "%x  %s\n", sha256.Sum256(data), asset)
+	}
+	r.Assets = append(r.Assets, Asset{Name: "checksums.txt", Data: sums.Bytes()})
+	return r, nil
+}
+
+// AddTool publishes a release of a synthetic tool
+// per version, the last one is the latest
+func (f *Forge) AddTool(repo string, versions ...string) error {
+	name := repo[strings.LastIndex(repo, "/")+1:]
+	for _, v := range versions {
+		r, err := ToolRelease(name, v)
+		if err != nil {
+			return err
+		}
+		if err := f.AddRelease(repo, r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func tarGzArchive(name string, content []byte) ([]byte, error) {
+	var b bytes.Buffer
+	gw := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+		return nil, err
+	}
+	if _, err := tw.Write(content); err != nil {
+		return nil, err
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func zipArchive(name string, content []byte) ([]byte, error) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	w, err := zw.Create(name)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write(content); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
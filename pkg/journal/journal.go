@@ -0,0 +1,364 @@
+// Package journal makes the file operations of an install recoverable.
+// The intent is recorded before touching anything, files are written
+// next to their target and only moved into place once all of them
+// are complete, so an interrupted run can be rolled back or completed.
+package journal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+const (
+	statePending    = "pending"
+	stateCommitting = "committing"
+	// stateCleanup is a committed transaction whose replaced
+	// files couldn't be removed yet, they were still running
+	stateCleanup = "cleanup"
+)
+
+// crashPoint is called at each step of a transaction,
+// it allows tests to simulate interruptions
+var crashPoint = func(step string) {}
+
+// moveAside moves the targets aside before replacing them. Windows
+// can't overwrite a running executable but it can rename it, it's a
+// variable so the tests can run on any platform
+var moveAside = runtime.GOOS == "windows"
+
+// errLocked is returned when the transaction
+// is still open in another process
+var errLocked = errors.New("the transaction is open in another process")
+
+// rename and remove are replaced by the tests to
+// simulate the filesystems refusing them
+var (
+	rename = os.Rename
+	remove = os.Remove
+)
+
+// Tx is a set of files installed together
+type Tx struct {
+	path string
+	// lock is held while the transaction is open, so
+	// the recovery of the other runs leaves it alone
+	lock    *os.File
+	State   string  `json:"state"`
+	Entries []Entry `json:"entries"`
+	// Aside are the replaced files left to remove
+	Aside []string `json:"aside,omitempty"`
+}
+
+// Entry is a file of the transaction
+type Entry struct {
+	Target string `json:"target"`
+	Staged string `json:"staged"`
+	perm   os.FileMode
+}
+
+// Begin starts a transaction recorded in the given directory
+func Begin(dir string) (*Tx, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	// the lock is taken before the journal exists so
+	// it's never recovered while the install runs
+	lock, err := os.CreateTemp(dir, "tx-*.lock")
+	if err != nil {
+		return nil, err
+	}
+	if err := lockFile(lock); err != nil {
+		unlock(lock)
+		return nil, err
+	}
+
+	tx := &Tx{path: strings.TrimSuffix(lock.Name(), ".lock") + ".json", lock: lock, State: statePending}
+	if err := tx.write(); err != nil {
+		tx.release()
+		return nil, err
+	}
+	return tx, nil
+}
+
+// Stage records the target and returns the file its content must be
+// written to. It's created in the same directory as the target so it
+// can be atomically renamed.
+func (t *Tx) Stage(target string, perm os.FileMode) (*os.File, error) {
+	staged := longPath(sibling(target))
+	target = longPath(target)
+	t.Entries = append(t.Entries, Entry{Target: target, Staged: staged, perm: perm})
+	// the intent is written before the file exists so
+	// it's never left behind unnoticed
+	if err := t.write(); err != nil {
+		return nil, err
+	}
+	crashPoint("staging")
+	return os.OpenFile(staged, os.O_RDWR|os.O_CREATE|os.O_EXCL, perm)
+}
+
+// Commit moves the staged files into place. They're flushed to disk
+// and given their permissions first, the umask applied when they
+// were created aside. Once the commit is recorded, an interrupted
+// transaction gets completed on recovery.
+func (t *Tx) Commit() error {
+	// whatever is left is up to the recovery
+	defer t.release()
+	crashPoint("staged")
+	for _, e := range t.Entries {
+		if err := flush(e.Staged, e.perm); err != nil {
+			return fmt.Errorf("error writing %s: %w", e.Target, err)
+		}
+	}
+	t.State = stateCommitting
+	if err := t.write(); err != nil {
+		return err
+	}
+	crashPoint("committing")
+	return t.complete()
+}
+
+// Rollback removes the staged files
+func (t *Tx) Rollback() error {
+	defer t.release()
+	for _, e := range t.Entries {
+		if err := os.Remove(e.Staged); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return os.Remove(t.path)
+}
+
+func (t *Tx) complete() error {
+	for _, e := range t.Entries {
+		// already moved by a previous attempt
+		if _, err := os.Stat(e.Staged); os.IsNotExist(err) {
+			continue
+		}
+		aside, err := replace(e.Staged, e.Target)
+		if err != nil {
+			return err
+		}
+		if aside != "" {
+			t.Aside = append(t.Aside, aside)
+		}
+		syncDir(filepath.Dir(e.Target))
+		crashPoint("renamed")
+	}
+	if len(t.Aside) > 0 {
+		t.State = stateCleanup
+		return t.write()
+	}
+	return os.Remove(t.path)
+}
+
+// release unlocks the transaction once this run is done with it
+func (t *Tx) release() {
+	if t.lock != nil {
+		unlock(t.lock)
+		t.lock = nil
+	}
+}
+
+// unlock releases the lock file and removes it
+func unlock(f *os.File) {
+	// Windows can't remove the open files
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// lockPath returns the lock of the journal, or of its update
+func lockPath(journal string) string {
+	return strings.TrimSuffix(strings.TrimSuffix(journal, ".tmp"), ".json") + ".lock"
+}
+
+// cleanup removes the replaced files which are not running anymore,
+// the transaction is done once all of them are
+func (t *Tx) cleanup() error {
+	var left []string
+	for _, a := range t.Aside {
+		if err := remove(a); err != nil && !os.IsNotExist(err) {
+			log.Debugf("Leaving %s behind, it's still in use: %v", a, err)
+			left = append(left, a)
+		}
+	}
+	if t.Aside = left; len(left) > 0 {
+		return t.write()
+	}
+	return os.Remove(t.path)
+}
+
+// flush sets the permissions of the file and syncs it to disk
+func flush(p string, perm os.FileMode) error {
+	f, err := os.OpenFile(p, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	if perm != 0 {
+		if err := f.Chmod(perm); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// syncDir makes the renames in the directory durable. It's best
+// effort, directories can't be synced on every platform
+func syncDir(dir string) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return
+	}
+	d.Sync()
+	d.Close()
+}
+
+// sibling returns a temporary name next to the target,
+// `bin prune` removes the ones left behind
+func sibling(target string) string {
+	return filepath.Join(filepath.Dir(target), fmt.Sprintf(".%s.bin-%d", filepath.Base(target), time.Now().UnixNano()))
+}
+
+// replace moves the staged file to the target. With moveAside, or when
+// the filesystem refuses to rename over a running target, the target
+// is renamed first and removed once replaced. When it can't be removed
+// because it's running, its path is returned to be removed later.
+func replace(staged, target string) (string, error) {
+	if !moveAside {
+		err := rename(staged, target)
+		if err == nil {
+			return "", nil
+		}
+		log.Debugf("Moving %s aside, it can't be replaced: %v", target, err)
+	}
+	aside := sibling(target)
+	if err := rename(target, aside); err != nil {
+		if os.IsNotExist(err) {
+			return "", rename(staged, target)
+		}
+		return "", err
+	}
+	if err := rename(staged, target); err != nil {
+		return "", errors.Join(err, rename(aside, target))
+	}
+	if err := remove(aside); err != nil {
+		log.Debugf("Leaving %s behind, it's still in use: %v", aside, err)
+		return aside, nil
+	}
+	return "", nil
+}
+
+// write atomically replaces the journal file
+func (t *Tx) write() error {
+	b, err := json.Marshal(t)
+	if err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// Recover rolls back or completes the transactions interrupted in
+// previous runs. The ones still open in other runs are left alone.
+func Recover(dir string) error {
+	journals, err := filepath.Glob(filepath.Join(dir, "tx-*.json*"))
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, j := range journals {
+		lock, err := os.OpenFile(lockPath(j), os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := lockFile(lock); err != nil {
+			lock.Close()
+			if errors.Is(err, errLocked) {
+				log.Debugf("Leaving %s alone, its install is still running", j)
+				continue
+			}
+			errs = append(errs, err)
+			continue
+		}
+		errs = append(errs, recoverJournal(j))
+		unlock(lock)
+	}
+	return errors.Join(errs...)
+}
+
+// recoverJournal rolls back or completes the transaction of the
+// journal, the caller holds its lock
+func recoverJournal(j string) error {
+	if strings.HasSuffix(j, ".tmp") {
+		// the journal update itself was interrupted, the
+		// previous version is still in place
+		if err := os.Remove(j); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+
+	b, err := os.ReadFile(j)
+	if os.IsNotExist(err) {
+		// done by its run since it was listed
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	tx := &Tx{path: j}
+	if len(b) > 0 {
+		if err := json.Unmarshal(b, tx); err != nil {
+			return fmt.Errorf("invalid journal %s: %w", j, err)
+		}
+	}
+
+	if tx.State == stateCleanup {
+		return tx.cleanup()
+	}
+	if tx.State == stateCommitting {
+		log.WithField("paths", targets(tx)).Info("Completing an interrupted install")
+		return tx.complete()
+	}
+	if len(tx.Entries) > 0 {
+		log.WithField("paths", targets(tx)).Info("Rolling back an interrupted install")
+	}
+	return tx.Rollback()
+}
+
+func targets(t *Tx) []string {
+	ts := make([]string, 0, len(t.Entries))
+	for _, e := range t.Entries {
+		ts = append(ts, e.Target)
+	}
+	return ts
+}
@@ -0,0 +1,342 @@
+package journal
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+)
+
+const crashExitCode = 3
+
+// install writes new content into the targets, it's run in a
+// child process so it can be killed at the requested step
+func install(dir string, targets []string, content io.Reader) error {
+	tx, err := Begin(dir)
+	if err != nil {
+		return err
+	}
+	for _, t := range targets {
+		f, err := tx.Stage(t, 0o755)
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(f, content)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func TestMain(m *testing.M) {
+	if step := os.Getenv("JOURNAL_CRASH_AT"); step != "" {
+		nth, seen := os.Getenv("JOURNAL_CRASH_NTH"), 0
+		crashPoint = func(s string) {
+			if s == step {
+				seen++
+				if fmt.Sprint(seen) == nth {
+					os.Exit(crashExitCode)
+				}
+			}
+		}
+		dir := os.Getenv("JOURNAL_DIR")
+		var content io.Reader = &onceReader{s: "new"}
+		if step == "copying" {
+			// the parent kills the install while it waits for the rest
+			content = io.MultiReader(&onceReader{s: "ne"}, os.Stdin)
+		}
+		if err := install(filepath.Join(dir, "journal"), []string{filepath.Join(dir, "kubectl"), filepath.Join(dir, "kubeadm")}, content); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+func TestRecoverAfterCrash(t *testing.T) {
+	cases := []struct {
+		step     string
+		nth      int
+		expected string
+	}{
+		// the first file is staged, the second one isn't
+		{"staging", 2, "old"},
+		{"staged", 1, "old"},
+		{"committing", 1, "new"},
+		// only the first file was moved into place
+		{"renamed", 1, "new"},
+	}
+
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%s-%d", c.step, c.nth), func(t *testing.T) {
+			dir := t.TempDir()
+			targets := []string{filepath.Join(dir, "kubectl"), filepath.Join(dir, "kubeadm")}
+			for _, target := range targets {
+				if err := os.WriteFile(target, []byte("old"), 0o755); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			cmd := exec.Command(os.Args[0], "-test.run=^$")
+			cmd.Env = append(os.Environ(), "JOURNAL_CRASH_AT="+c.step, fmt.Sprintf("JOURNAL_CRASH_NTH=%d", c.nth), "JOURNAL_DIR="+dir)
+			err := cmd.Run()
+			if e, ok := err.(*exec.ExitError); !ok || e.ExitCode() != crashExitCode {
+				t.Fatalf("expected the install to crash, got %v", err)
+			}
+
+			if err := Recover(filepath.Join(dir, "journal")); err != nil {
+				t.Fatal(err)
+			}
+
+			for _, target := range targets {
+				b, err := os.ReadFile(target)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != c.expected {
+					t.Errorf("expected %s to contain %q, got %q", target, c.expected, b)
+				}
+			}
+
+			// nothing must be left behind
+			left, _ := filepath.Glob(filepath.Join(dir, ".*"))
+			journals, _ := filepath.Glob(filepath.Join(dir, "journal", "*"))
+			if len(left)+len(journals) > 0 {
+				t.Errorf("unexpected leftovers %v %v", left, journals)
+			}
+		})
+	}
+}
+
+// onceReader returns s once per target, the content of the
+// next one starts when it's read again after its EOF
+type onceReader struct {
+	s    string
+	done bool
+}
+
+func (r *onceReader) Read(p []byte) (int, error) {
+	if r.done {
+		r.done = false
+		return 0, io.EOF
+	}
+	r.done = true
+	return copy(p, r.s), nil
+}
+
+func TestKilledWhileCopying(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "kubectl")
+	if err := os.WriteFile(target, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	cmd.Env = append(os.Environ(), "JOURNAL_CRASH_AT=copying", "JOURNAL_DIR="+dir)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer stdin.Close()
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait for the copy to be half way
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		staged, _ := filepath.Glob(filepath.Join(dir, ".kubectl.bin-*"))
+		if len(staged) == 1 {
+			if fi, err := os.Stat(staged[0]); err == nil && fi.Size() > 0 {
+				break
+			}
+		}
+		if time.Now().After(deadline) {
+			cmd.Process.Kill()
+			t.Fatal("the copy didn't start")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := cmd.Process.Kill(); err != nil {
+		t.Fatal(err)
+	}
+	cmd.Wait()
+
+	if b, err := os.ReadFile(target); err != nil || string(b) != "old" {
+		t.Fatalf("expected the previous binary to be intact, got %q (%v)", b, err)
+	}
+	if err := Recover(filepath.Join(dir, "journal")); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "old" {
+		t.Errorf("expected the previous binary to be kept, got %q", b)
+	}
+	left, _ := filepath.Glob(filepath.Join(dir, ".*"))
+	if len(left) > 0 {
+		t.Errorf("unexpected leftovers %v", left)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	dir := t.TempDir()
+	tx, err := Begin(filepath.Join(dir, "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := tx.Stage(filepath.Join(dir, "tool"), 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("expected only the journal directory to be left, got %v", entries)
+	}
+}
+
+func TestRecoverSkipsOpenTransactions(t *testing.T) {
+	dir := t.TempDir()
+	jd := filepath.Join(dir, "journal")
+	target := filepath.Join(dir, "tool")
+	tx, err := Begin(jd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := tx.Stage(target, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("new")
+	f.Close()
+	// an update of the journal being written
+	if err := os.WriteFile(tx.path+".tmp", nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	// another run starts while the install is in progress
+	if err := Recover(jd); err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{tx.path, tx.path + ".tmp", tx.Entries[0].Staged} {
+		if _, err := os.Stat(p); err != nil {
+			t.Fatalf("expected the open transaction to be left alone: %v", err)
+		}
+	}
+
+	if err := os.Remove(tx.path + ".tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "new" {
+		t.Errorf("expected the target to be replaced, got %q", b)
+	}
+	if left, _ := filepath.Glob(filepath.Join(jd, "*")); len(left) > 0 {
+		t.Errorf("unexpected leftovers %v", left)
+	}
+}
+
+func TestMoveAside(t *testing.T) {
+	moveAside = true
+	defer func() { moveAside = runtime.GOOS == "windows" }()
+
+	dir := t.TempDir()
+	target := filepath.Join(dir, "tool.exe")
+	if err := os.WriteFile(target, []byte("old"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tx, err := Begin(filepath.Join(dir, "journal"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := tx.Stage(target, 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("new")
+	f.Close()
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := os.ReadFile(target); string(b) != "new" {
+		t.Errorf("expected the target to be replaced, got %q", b)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("expected the old file to be removed, got %v", entries)
+	}
+}
+
+func TestRenameRefused(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "tool")
+	if err := os.WriteFile(target, []byte("old"), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	// the filesystem refuses to replace the running binary, and
+	// to remove it until it exits
+	running := true
+	rename = func(from, to string) error {
+		if _, err := os.Stat(to); err == nil && to == target {
+			return &os.LinkError{Op: "rename", Old: from, New: to, Err: syscall.ETXTBSY}
+		}
+		return os.Rename(from, to)
+	}
+	remove = func(p string) error {
+		if running {
+			return &os.PathError{Op: "remove", Path: p, Err: syscall.ETXTBSY}
+		}
+		return os.Remove(p)
+	}
+	defer func() { rename, remove = os.Rename, os.Remove }()
+
+	jd := filepath.Join(dir, "journal")
+	if err := install(jd, []string{target}, &onceReader{s: "new"}); err != nil {
+		t.Fatal(err)
+	}
+	fi, err := os.Stat(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(target); string(b) != "new" {
+		t.Errorf("expected the target to be replaced, got %q", b)
+	}
+	if runtime.GOOS != "windows" && fi.Mode().Perm() != 0o755 {
+		t.Errorf("expected the permissions to be set, got %v", fi.Mode().Perm())
+	}
+
+	aside, _ := filepath.Glob(filepath.Join(dir, ".tool.bin-*"))
+	if len(aside) != 1 {
+		t.Fatalf("expected the running binary to be left aside, got %v", aside)
+	}
+	// it's removed by the next run once it exited
+	if err := Recover(jd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(aside[0]); err != nil {
+		t.Errorf("expected %s to be kept while running: %v", aside[0], err)
+	}
+	running = false
+	if err := Recover(jd); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(aside[0]); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("expected %s to be removed, got %v", aside[0], err)
+	}
+	if journals, _ := filepath.Glob(filepath.Join(jd, "*")); len(journals) > 0 {
+		t.Errorf("expected the transaction to be done, got %v", journals)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !windows
+// +build !windows
+
+package journal
+
+// longPath returns the path as is, only Windows limits their length
+func longPath(p string) string {
+	return p
+}
@@ -0,0 +1,24 @@
+package journal
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// maxPath is the length from which paths need the \\?\ prefix,
+// directories are limited to 248 characters instead of MAX_PATH
+const maxPath = 248
+
+// longPath makes the path absolute and prefixes long
+// ones with \\?\ so they aren't limited to MAX_PATH
+func longPath(p string) string {
+	if strings.HasPrefix(p, `\\`) {
+		// already prefixed or a UNC path
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil || len(abs) < maxPath {
+		return p
+	}
+	return `\\?\` + abs
+}
@@ -0,0 +1,21 @@
+package journal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLongPath(t *testing.T) {
+	short := `C:\Users\me\bin\tool.exe`
+	if p := longPath(short); p != short {
+		t.Errorf("expected %s to be kept, got %s", short, p)
+	}
+
+	long := `C:\Users\me\` + strings.Repeat(`very-long-directory\`, 15) + "tool.exe"
+	if p := longPath(long); p != `\\?\`+long {
+		t.Errorf("expected %s to be prefixed, got %s", long, p)
+	}
+	if p := longPath(`\\?\` + long); p != `\\?\`+long {
+		t.Errorf("expected the prefix to be added once, got %s", p)
+	}
+}
@@ -0,0 +1,51 @@
+// Package labels marks the files installed by bin with extended
+// attributes, so other tools can tell them apart from the other files
+// of a shared directory. Filesystems without extended attributes
+// (or platforms without them) simply don't get labels.
+package labels
+
+import "errors"
+
+// The extended attributes of the labels
+const (
+	attrManaged = "user.bin.managed"
+	attrVersion = "user.bin.version"
+	attrSource  = "user.bin.source"
+)
+
+// ErrUnsupported is returned when the filesystem of
+// the file, or the platform, doesn't support labels
+var ErrUnsupported = errors.New("extended attributes are not supported")
+
+// Label describes the install of a file by bin
+type Label struct {
+	Version string
+	// Source is the URL the file was installed from
+	Source string
+}
+
+// Set labels the file as installed by bin
+func Set(path string, l Label) error {
+	for _, a := range [][2]string{{attrManaged, "1"}, {attrVersion, l.Version}, {attrSource, l.Source}} {
+		if err := setAttr(path, a[0], a[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the label of the file, nil if it has none
+func Get(path string) (*Label, error) {
+	managed, ok, err := getAttr(path, attrManaged)
+	if err != nil || !ok || managed != "1" {
+		return nil, err
+	}
+	l := &Label{}
+	if l.Version, _, err = getAttr(path, attrVersion); err != nil {
+		return nil, err
+	}
+	if l.Source, _, err = getAttr(path, attrSource); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
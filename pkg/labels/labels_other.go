@@ -0,0 +1,11 @@
+//go:build !linux && !darwin
+
+package labels
+
+func setAttr(path, name, value string) error {
+	return ErrUnsupported
+}
+
+func getAttr(path, name string) (string, bool, error) {
+	return "", false, ErrUnsupported
+}
@@ -0,0 +1,36 @@
+package labels
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLabels(t *testing.T) {
+	p := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(p, []byte("tool"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	want := Label{Version: "v1.2.3", Source: "https://github.com/owner/tool"}
+	err := Set(p, want)
+	if errors.Is(err, ErrUnsupported) {
+		t.Skip("the filesystem of the temporary directory doesn't support labels")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Get(p)
+	if err != nil || got == nil || *got != want {
+		t.Fatalf("expected %+v, got %+v (%v)", want, got, err)
+	}
+
+	other := filepath.Join(filepath.Dir(p), "other")
+	if err := os.WriteFile(other, []byte("other"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := Get(other); err != nil || got != nil {
+		t.Errorf("expected a file without label, got %+v (%v)", got, err)
+	}
+}
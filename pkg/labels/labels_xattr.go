@@ -0,0 +1,42 @@
+//go:build linux || darwin
+
+package labels
+
+import (
+	"errors"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxAttrSize bounds the size of the values read,
+// the sources are URLs
+const maxAttrSize = 4096
+
+func unsupported(err error) bool {
+	return errors.Is(err, unix.ENOTSUP) || errors.Is(err, unix.EOPNOTSUPP)
+}
+
+func setAttr(path, name, value string) error {
+	err := unix.Setxattr(path, name, []byte(value), 0)
+	if unsupported(err) {
+		return ErrUnsupported
+	}
+	return err
+}
+
+// getAttr returns the value of the attribute, ok is
+// false when the file doesn't have it
+func getAttr(path, name string) (string, bool, error) {
+	buf := make([]byte, maxAttrSize)
+	n, err := unix.Getxattr(path, name, buf)
+	switch {
+	case unsupported(err):
+		return "", false, ErrUnsupported
+	case errors.Is(err, unix.ENOENT):
+		return "", false, err
+	case err != nil:
+		// the attribute is missing
+		return "", false, nil
+	}
+	return string(buf[:n]), true, nil
+}
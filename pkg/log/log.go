@@ -0,0 +1,244 @@
+// Package log wraps github.com/caarlos0/log, which renders the logs for
+// humans, to write them as JSON instead when bin is run by scripts. The
+// entries carry their subject (the binary, its provider, version, URL or
+// the bytes downloaded) in fields rather than in their message, so both
+// outputs stay readable.
+package log
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sync"
+	"time"
+
+	clog "github.com/caarlos0/log"
+)
+
+// Level is the severity of an entry
+type Level = clog.Level
+
+// Log levels.
+const (
+	DebugLevel = clog.DebugLevel
+	InfoLevel  = clog.InfoLevel
+	WarnLevel  = clog.WarnLevel
+	ErrorLevel = clog.ErrorLevel
+	FatalLevel = clog.FatalLevel
+)
+
+// Formats of the output.
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+var (
+	mu     sync.Mutex
+	level  = InfoLevel
+	format = FormatText
+	// output is where the JSON entries are written
+	output io.Writer = os.Stderr
+	now              = time.Now
+	exit             = os.Exit
+	// ansi matches the colors of the messages, which are only for terminals
+	ansi = regexp.MustCompile("\x1b\\[[0-9;]*m")
+)
+
+// SetLevel sets the minimum level of the entries written
+func SetLevel(l Level) {
+	mu.Lock()
+	defer mu.Unlock()
+	level = l
+	clog.SetLevel(l)
+}
+
+// SetFormat sets the format of the output, text or json
+func SetFormat(f string) error {
+	if f != FormatText && f != FormatJSON {
+		return fmt.Errorf("invalid log format %q, use text or json", f)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	format = f
+	return nil
+}
+
+// JSON reports whether the entries are written as JSON
+func JSON() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return format == FormatJSON
+}
+
+type field struct {
+	key   string
+	value any
+}
+
+// Entry is an entry being built with its fields
+type Entry struct {
+	fields []field
+}
+
+// WithField returns a new entry with the key set to the value
+func WithField(key string, value any) *Entry {
+	return (&Entry{}).WithField(key, value)
+}
+
+// WithError returns a new entry with the error set
+func WithError(err error) *Entry {
+	return (&Entry{}).WithError(err)
+}
+
+// WithField returns a copy of the entry with the key set to the value
+func (e *Entry) WithField(key string, value any) *Entry {
+	fields := make([]field, 0, len(e.fields)+1)
+	for _, f := range e.fields {
+		if f.key != key {
+			fields = append(fields, f)
+		}
+	}
+	return &Entry{fields: append(fields, field{key, value})}
+}
+
+// WithError returns a copy of the entry with the error set
+func (e *Entry) WithError(err error) *Entry {
+	if err == nil {
+		return e
+	}
+	return e.WithField("error", err.Error())
+}
+
+func (e *Entry) log(l Level, msg string) {
+	mu.Lock()
+	if l < level {
+		mu.Unlock()
+		return
+	}
+	if format == FormatJSON {
+		defer mu.Unlock()
+		e.writeJSON(l, msg)
+		return
+	}
+	mu.Unlock()
+
+	var ce clog.Interface = clog.Log
+	for _, f := range e.fields {
+		ce = ce.WithField(f.key, f.value)
+	}
+	switch l {
+	case DebugLevel:
+		ce.Debug(msg)
+	case InfoLevel:
+		ce.Info(msg)
+	case WarnLevel:
+		ce.Warn(msg)
+	case ErrorLevel:
+		ce.Error(msg)
+	default:
+		// caarlos0/log exits on fatal entries itself
+		ce.Fatal(msg)
+	}
+}
+
+// writeJSON writes the entry on a line: its level, time and
+// message followed by its fields in the order they were set
+func (e *Entry) writeJSON(l Level, msg string) {
+	var b []byte
+	b = append(b, `{"level":`...)
+	b = appendJSON(b, l.String())
+	b = append(b, `,"timestamp":`...)
+	b = appendJSON(b, now().UTC().Format(time.RFC3339Nano))
+	b = append(b, `,"message":`...)
+	b = appendJSON(b, ansi.ReplaceAllString(msg, ""))
+	for _, f := range e.fields {
+		if f.key == "level" || f.key == "timestamp" || f.key == "message" {
+			continue
+		}
+		b = append(b, ',')
+		b = appendJSON(b, f.key)
+		b = append(b, ':')
+		b = appendJSON(b, f.value)
+	}
+	b = append(b, "}\n"...)
+	output.Write(b)
+}
+
+// appendJSON appends the value as JSON, the values which can't
+// be encoded (i.e. errors) are written as strings
+func appendJSON(b []byte, v any) []byte {
+	if err, ok := v.(error); ok {
+		v = err.Error()
+	}
+	out, err := json.Marshal(v)
+	if err != nil {
+		out, _ = json.Marshal(fmt.Sprint(v))
+	}
+	return append(b, out...)
+}
+
+// Debug level message.
+func (e *Entry) Debug(msg string) { e.log(DebugLevel, msg) }
+
+// Info level message.
+func (e *Entry) Info(msg string) { e.log(InfoLevel, msg) }
+
+// Warn level message.
+func (e *Entry) Warn(msg string) { e.log(WarnLevel, msg) }
+
+// Error level message.
+func (e *Entry) Error(msg string) { e.log(ErrorLevel, msg) }
+
+// Fatal level message, followed by an exit.
+func (e *Entry) Fatal(msg string) {
+	e.log(FatalLevel, msg)
+	exit(1)
+}
+
+// Debugf level formatted message.
+func (e *Entry) Debugf(msg string, v ...any) { e.Debug(fmt.Sprintf(msg, v...)) }
+
+// Infof level formatted message.
+func (e *Entry) Infof(msg string, v ...any) { e.Info(fmt.Sprintf(msg, v...)) }
+
+// Warnf level formatted message.
+func (e *Entry) Warnf(msg string, v ...any) { e.Warn(fmt.Sprintf(msg, v...)) }
+
+// Errorf level formatted message.
+func (e *Entry) Errorf(msg string, v ...any) { e.Error(fmt.Sprintf(msg, v...)) }
+
+// Fatalf level formatted message, followed by an exit.
+func (e *Entry) Fatalf(msg string, v ...any) { e.Fatal(fmt.Sprintf(msg, v...)) }
+
+// Debug level message.
+func Debug(msg string) { (&Entry{}).Debug(msg) }
+
+// Info level message.
+func Info(msg string) { (&Entry{}).Info(msg) }
+
+// Warn level message.
+func Warn(msg string) { (&Entry{}).Warn(msg) }
+
+// Error level message.
+func Error(msg string) { (&Entry{}).Error(msg) }
+
+// Fatal level message, followed by an exit.
+func Fatal(msg string) { (&Entry{}).Fatal(msg) }
+
+// Debugf level formatted message.
+func Debugf(msg string, v ...any) { (&Entry{}).Debugf(msg, v...) }
+
+// Infof level formatted message.
+func Infof(msg string, v ...any) { (&Entry{}).Infof(msg, v...) }
+
+// Warnf level formatted message.
+func Warnf(msg string, v ...any) { (&Entry{}).Warnf(msg, v...) }
+
+// Errorf level formatted message.
+func Errorf(msg string, v ...any) { (&Entry{}).Errorf(msg, v...) }
+
+// Fatalf level formatted message, followed by an exit.
+func Fatalf(msg string, v ...any) { (&Entry{}).Fatalf(msg, v...) }
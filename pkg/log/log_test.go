@@ -0,0 +1,52 @@
+package log
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	prev := output
+	output, now = &buf, func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	t.Cleanup(func() {
+		output, now = prev, time.Now
+		SetFormat(FormatText)
+		SetLevel(InfoLevel)
+	})
+	if err := SetFormat("xml"); err == nil {
+		t.Fatal("expected an invalid format to be refused")
+	}
+	if err := SetFormat(FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	Debugf("not written at the info level")
+	e := WithField("binary", "kind").WithField("version", "v0.20.0")
+	e.WithField("bytes", 1024).Info("Done installing")
+	e.WithError(errors.New("boom")).Warnf("Error with \x1b[33m%s\x1b[0m", "color")
+	SetLevel(DebugLevel)
+	Debug("written")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 entries, got %q", lines)
+	}
+	if !strings.HasPrefix(lines[0], `{"level":"info","timestamp":"2024-01-02T03:04:05Z","message":"Done installing","binary":"kind","version":"v0.20.0","bytes":1024}`) {
+		t.Fatalf("expected the fields in order after the message, got %s", lines[0])
+	}
+	var warn map[string]any
+	if err := json.Unmarshal([]byte(lines[1]), &warn); err != nil {
+		t.Fatal(err)
+	}
+	if warn["level"] != "warn" || warn["message"] != "Error with color" || warn["error"] != "boom" || warn["binary"] != "kind" {
+		t.Fatalf("expected the warning with its error and without colors, got %v", warn)
+	}
+	if !strings.Contains(lines[2], `"level":"debug"`) {
+		t.Fatalf("expected the debug entry once enabled, got %s", lines[2])
+	}
+}
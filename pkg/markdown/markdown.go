@@ -0,0 +1,83 @@
+// Package markdown renders the markdown of release notes for the
+// terminal. It only handles what the notes usually contain: headings,
+// lists, emphasis, code and links. Anything else is printed as is.
+package markdown
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/fatih/color"
+)
+
+var (
+	commentRe = regexp.MustCompile(`(?s)<!--.*?-->`)
+	headingRe = regexp.MustCompile(`^#{1,6}\s+(.*?)\s*#*$`)
+	bulletRe  = regexp.MustCompile(`^(\s*)[-*+]\s+(.*)$`)
+	imageRe   = regexp.MustCompile(`!\[([^\]]*)\]\([^)]*\)`)
+	linkRe    = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)[^)]*\)`)
+	boldRe    = regexp.MustCompile(`\*\*(.+?)\*\*|__(.+?)__`)
+	codeRe    = regexp.MustCompile("`([^`]+)`")
+
+	bold = color.New(color.Bold).SprintFunc()
+	code = color.New(color.FgCyan).SprintFunc()
+)
+
+// Render returns the lines of the markdown rendered for the terminal,
+// consecutive blank lines are collapsed into one
+func Render(s string) []string {
+	s = commentRe.ReplaceAllString(strings.ReplaceAll(s, "\r\n", "\n"), "")
+	lines := []string{}
+	fenced, blank := false, true
+	for _, l := range strings.Split(s, "\n") {
+		if strings.HasPrefix(strings.TrimSpace(l), "```") {
+			fenced = !fenced
+			continue
+		}
+		if fenced {
+			lines = append(lines, "    "+code(l))
+			blank = false
+			continue
+		}
+		if strings.TrimSpace(l) == "" {
+			if !blank {
+				lines = append(lines, "")
+			}
+			blank = true
+			continue
+		}
+		blank = false
+		switch {
+		case headingRe.MatchString(l):
+			lines = append(lines, bold(inline(headingRe.FindStringSubmatch(l)[1])))
+		case bulletRe.MatchString(l):
+			m := bulletRe.FindStringSubmatch(l)
+			lines = append(lines, m[1]+"• "+inline(m[2]))
+		default:
+			lines = append(lines, inline(strings.TrimRight(l, " ")))
+		}
+	}
+	for len(lines) > 0 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// inline renders the emphasis, code spans and links of a line
+func inline(s string) string {
+	s = imageRe.ReplaceAllString(s, "$1")
+	s = linkRe.ReplaceAllStringFunc(s, func(l string) string {
+		m := linkRe.FindStringSubmatch(l)
+		if m[1] == m[2] {
+			return m[2]
+		}
+		return m[1] + " (" + m[2] + ")"
+	})
+	s = codeRe.ReplaceAllStringFunc(s, func(c string) string {
+		return code(codeRe.FindStringSubmatch(c)[1])
+	})
+	return boldRe.ReplaceAllStringFunc(s, func(b string) string {
+		m := boldRe.FindStringSubmatch(b)
+		return bold(m[1] + m[2])
+	})
+}
@@ -0,0 +1,42 @@
+package markdown
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/fatih/color"
+)
+
+func TestRender(t *testing.T) {
+	color.NoColor = true
+	body := "<!-- Release notes generated by a bot -->\r\n" +
+		"## What's Changed\r\n" +
+		"\r\n\r\n" +
+		"* Add `--foo` by @someone in [#12](https://github.com/o/r/pull/12)\r\n" +
+		"  - **Breaking:** drop the __bar__ flag\r\n" +
+		"![logo](https://example.com/logo.png)\r\n" +
+		"```sh\r\n" +
+		"tool --foo\r\n" +
+		"```\r\n" +
+		"**Full Changelog**: https://github.com/o/r/compare/v1...v2\r\n" +
+		"\r\n"
+
+	want := []string{
+		"What's Changed",
+		"",
+		"• Add --foo by @someone in #12 (https://github.com/o/r/pull/12)",
+		"  • Breaking: drop the bar flag",
+		"logo",
+		"    tool --foo",
+		"Full Changelog: https://github.com/o/r/compare/v1...v2",
+	}
+	if got := Render(body); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected\n%q\ngot\n%q", want, got)
+	}
+}
+
+func TestRenderEmpty(t *testing.T) {
+	if got := Render("\n<!-- nothing -->\n\n"); len(got) != 0 {
+		t.Fatalf("expected no lines, got %q", got)
+	}
+}
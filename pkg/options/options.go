@@ -3,9 +3,18 @@ package options
 import (
 	"fmt"
 	"io"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/marcosnils/bin/pkg/browser"
 )
 
+// mu serializes the prompts of concurrent fetches
+// so they don't read STDIN at the same time
+var mu sync.Mutex
+
 type LiteralStringer string
 
 func (l LiteralStringer) String() string {
@@ -16,33 +25,42 @@ func (l LiteralStringer) String() string {
 // of the available options is the desired
 // through STDIN and returns the selected one
 func Select(msg string, opts []fmt.Stringer) (interface{}, error) {
+	return SelectOpen(msg, opts, "")
+}
+
+// SelectOpen is like Select, the page at url (i.e. the release
+// the options come from) can also be opened in the browser by
+// typing `o` to help choosing
+func SelectOpen(msg string, opts []fmt.Stringer, url string) (interface{}, error) {
 	if len(opts) == 1 {
 		return opts[0], nil
 	}
+	mu.Lock()
+	defer mu.Unlock()
 	fmt.Printf("\n%s\n", msg)
 	for i, o := range opts {
 		fmt.Printf("\n [%d] %s", i+1, o)
 	}
 
-	var opt uint
-	var err error
+	question := "\n Select an option: "
+	if url != "" {
+		question = "\n Select an option, or o to open the release page: "
+	}
 	for {
-		fmt.Printf("\n Select an option: ")
-		_, err = fmt.Scanln(&opt)
-		if err != nil || opt < 1 || int(opt) > len(opts) {
-			if err != nil {
-				if err == io.EOF {
-					return nil, err
-				}
-			}
-			fmt.Printf("Invalid option")
+		fmt.Print(question)
+		var in string
+		if _, err := fmt.Scanln(&in); err == io.EOF {
+			return nil, err
+		}
+		if url != "" && strings.EqualFold(in, "o") {
+			browser.Open(url, os.Stdout)
 			continue
 		}
-		break
-
+		if v, err := strconv.Atoi(in); err == nil && v >= 1 && v <= len(opts) {
+			return opts[v-1], nil
+		}
+		fmt.Printf("Invalid option")
 	}
-
-	return opts[opt-1], nil
 }
 
 // SelectCustom prompts the user which
@@ -52,6 +70,8 @@ func SelectCustom(msg string, opts []fmt.Stringer) (interface{}, error) {
 	if len(opts) == 1 {
 		return opts[0], nil
 	}
+	mu.Lock()
+	defer mu.Unlock()
 	fmt.Printf("\n%s\n", msg)
 	for i, o := range opts {
 		fmt.Printf("\n [%d] %s", i+1, o)
@@ -88,3 +108,55 @@ func SelectCustom(msg string, opts []fmt.Stringer) (interface{}, error) {
 
 	return opts[v-1], nil
 }
+
+// SelectMultiple prompts the user which of the available
+// options are desired through STDIN, as a comma separated
+// list of their numbers or `all`, and returns the selected ones
+func SelectMultiple(msg string, opts []fmt.Stringer) ([]fmt.Stringer, error) {
+	if len(opts) == 1 {
+		return opts, nil
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	fmt.Printf("\n%s\n", msg)
+	for i, o := range opts {
+		fmt.Printf("\n [%d] %s", i+1, o)
+	}
+
+	for {
+		fmt.Printf("\n Select the options (i.e. 1,3 or all): ")
+		var in string
+		if _, err := fmt.Scanln(&in); err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			fmt.Printf("Invalid option")
+			continue
+		}
+		if selected, ok := parseSelection(in, opts); ok {
+			return selected, nil
+		}
+		fmt.Printf("Invalid option")
+	}
+}
+
+// parseSelection returns the options selected by
+// their numbers, ok is false for invalid input
+func parseSelection(in string, opts []fmt.Stringer) ([]fmt.Stringer, bool) {
+	if strings.EqualFold(in, "all") {
+		return opts, true
+	}
+	var selected []fmt.Stringer
+	seen := map[int]bool{}
+	for _, s := range strings.Split(in, ",") {
+		v, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil || v < 1 || v > len(opts) {
+			return nil, false
+		}
+		if !seen[v] {
+			seen[v] = true
+			selected = append(selected, opts[v-1])
+		}
+	}
+	return selected, len(selected) > 0
+}
@@ -6,14 +6,22 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
+
+	"github.com/mattn/go-isatty"
 )
 
 var stdin io.Reader = os.Stdin
 
+// mu serializes the prompts of concurrent operations
+var mu sync.Mutex
+
 // Confirm prints a confirmation prompt
 // for the given message and waits for the
 // users input.
 func Confirm(message string) error {
+	mu.Lock()
+	defer mu.Unlock()
 	fmt.Printf("\n%s [Y/n] ", message)
 	reader := bufio.NewReader(stdin)
 	var response string
@@ -31,3 +39,10 @@ func Confirm(message string) error {
 
 	return nil
 }
+
+// IsInteractive reports whether the user can
+// be prompted through STDIN
+func IsInteractive() bool {
+	f, ok := stdin.(*os.File)
+	return ok && (isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd()))
+}
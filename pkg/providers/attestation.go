@@ -0,0 +1,230 @@
+package providers
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// releasePredicateType is the predicate of the attestations
+// GitHub generates when an immutable release is published
+const releasePredicateType = "https://in-toto.io/attestation/release/v0.1"
+
+// sigstoreBundle is the subset of a Sigstore bundle
+// needed to verify a DSSE signed attestation
+type sigstoreBundle struct {
+	VerificationMaterial struct {
+		Certificate *struct {
+			RawBytes []byte `json:"rawBytes"`
+		} `json:"certificate"`
+		X509CertificateChain *struct {
+			Certificates []struct {
+				RawBytes []byte `json:"rawBytes"`
+			} `json:"certificates"`
+		} `json:"x509CertificateChain"`
+	} `json:"verificationMaterial"`
+	DSSEEnvelope *struct {
+		Payload     []byte `json:"payload"`
+		PayloadType string `json:"payloadType"`
+		Signatures  []struct {
+			Sig []byte `json:"sig"`
+		} `json:"signatures"`
+	} `json:"dsseEnvelope"`
+}
+
+// inTotoStatement is the attestation signed in the DSSE envelope
+type inTotoStatement struct {
+	Subject []struct {
+		Name   string            `json:"name"`
+		URI    string            `json:"uri"`
+		Digest map[string]string `json:"digest"`
+	} `json:"subject"`
+	PredicateType string `json:"predicateType"`
+	Predicate     struct {
+		PURL string `json:"purl"`
+	} `json:"predicate"`
+}
+
+// releaseAttestation fetches the attestation of an immutable release and
+// checks the assets of the release against it. It returns the sha256
+// digests of the assets by name, nil when the release isn't immutable.
+// The bundle comes from the GitHub API like the release itself and its
+// certificate isn't chained to a trust root, so it catches the assets
+// changed after the release was published but doesn't prove who
+// published them.
+func (g *gitHub) releaseAttestation(release *github.RepositoryRelease) (map[string]string, error) {
+	immutable, err := g.immutable(release)
+	if err != nil {
+		return nil, fmt.Errorf("error checking if %s is an immutable release: %w", release.GetTagName(), err)
+	}
+	if !immutable {
+		return nil, nil
+	}
+
+	commit, err := g.tagCommit(release.GetTagName())
+	if err != nil {
+		return nil, fmt.Errorf("error getting the commit of the immutable release %s: %w", release.GetTagName(), err)
+	}
+	req, err := g.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/attestations/sha1:%s?predicate_type=release", g.owner, g.repo, commit), nil)
+	if err != nil {
+		return nil, err
+	}
+	var res struct {
+		Attestations []struct {
+			Bundle json.RawMessage `json:"bundle"`
+		} `json:"attestations"`
+	}
+	if _, err := g.client.Do(context.TODO(), req, &res); err != nil {
+		return nil, fmt.Errorf("error getting the attestation of the immutable release %s: %w", release.GetTagName(), err)
+	}
+
+	purl := strings.ToLower(fmt.Sprintf("pkg:github/%s/%s@%s", g.owner, g.repo, release.GetTagName()))
+	for _, a := range res.Attestations {
+		st, err := verifyBundle(a.Bundle)
+		if err != nil {
+			return nil, fmt.Errorf("invalid attestation of the immutable release %s: %w", release.GetTagName(), err)
+		}
+		if st.PredicateType != releasePredicateType || (st.Predicate.PURL != "" && strings.ToLower(st.Predicate.PURL) != purl) {
+			continue
+		}
+		digests := map[string]string{}
+		for _, s := range st.Subject {
+			if s.Name != "" && s.Digest["sha256"] != "" {
+				digests[s.Name] = s.Digest["sha256"]
+			}
+		}
+		if err := checkAttestedAssets(release.Assets, digests); err != nil {
+			return nil, fmt.Errorf("the assets of the immutable release %s don't match its attestation: %w", release.GetTagName(), err)
+		}
+		log.Debugf("The assets of %s match the attestation of the immutable release", release.GetTagName())
+		return digests, nil
+	}
+	return nil, fmt.Errorf("no attestation found for the immutable release %s", release.GetTagName())
+}
+
+// immutable checks if the release is immutable, the field
+// isn't known by the GitHub client
+func (g *gitHub) immutable(release *github.RepositoryRelease) (bool, error) {
+	req, err := g.client.NewRequest(http.MethodGet, fmt.Sprintf("repos/%s/%s/releases/%d", g.owner, g.repo, release.GetID()), nil)
+	if err != nil {
+		return false, err
+	}
+	var r struct {
+		Immutable bool `json:"immutable"`
+	}
+	if _, err := g.client.Do(context.TODO(), req, &r); err != nil {
+		return false, err
+	}
+	return r.Immutable, nil
+}
+
+// tagCommit returns the commit the tag points
+// to, dereferencing annotated tags
+func (g *gitHub) tagCommit(tag string) (string, error) {
+	ref, _, err := g.client.Git.GetRef(context.TODO(), g.owner, g.repo, "tags/"+tag)
+	if err != nil {
+		return "", err
+	}
+	sha := ref.GetObject().GetSHA()
+	if ref.GetObject().GetType() == "tag" {
+		t, _, err := g.client.Git.GetTag(context.TODO(), g.owner, g.repo, sha)
+		if err != nil {
+			return "", err
+		}
+		sha = t.GetObject().GetSHA()
+	}
+	return sha, nil
+}
+
+// checkAttestedAssets fails when assets were added to
+// or removed from the release since it was attested
+func checkAttestedAssets(releaseAssets []*github.ReleaseAsset, digests map[string]string) error {
+	names := map[string]bool{}
+	var added, missing []string
+	for _, a := range releaseAssets {
+		names[a.GetName()] = true
+		if _, ok := digests[a.GetName()]; !ok {
+			added = append(added, a.GetName())
+		}
+	}
+	for n := range digests {
+		if !names[n] {
+			missing = append(missing, n)
+		}
+	}
+	sort.Strings(missing)
+	switch {
+	case len(added) > 0:
+		return fmt.Errorf("%s not attested", strings.Join(added, ", "))
+	case len(missing) > 0:
+		return fmt.Errorf("%s missing from the release", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// verifyBundle checks the DSSE signature of the bundle against the
+// certificate of the bundle and returns the statement it signs. It
+// tells the envelope is intact, not who signed it
+func verifyBundle(b []byte) (*inTotoStatement, error) {
+	var bundle sigstoreBundle
+	if err := json.Unmarshal(b, &bundle); err != nil {
+		return nil, err
+	}
+	env := bundle.DSSEEnvelope
+	if env == nil || len(env.Signatures) == 0 {
+		return nil, errors.New("the bundle doesn't have a signed envelope")
+	}
+
+	var raw []byte
+	vm := bundle.VerificationMaterial
+	switch {
+	case vm.Certificate != nil:
+		raw = vm.Certificate.RawBytes
+	case vm.X509CertificateChain != nil && len(vm.X509CertificateChain.Certificates) > 0:
+		raw = vm.X509CertificateChain.Certificates[0].RawBytes
+	default:
+		return nil, errors.New("the bundle doesn't have a certificate")
+	}
+	cert, err := x509.ParseCertificate(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	// DSSE signs the pre-authentication encoding of the payload
+	pae := fmt.Sprintf("DSSEv1 %d %s %d %s", len(env.PayloadType), env.PayloadType, len(env.Payload), env.Payload)
+	digest := sha256.Sum256([]byte(pae))
+	if !verifySignature(cert.PublicKey, []byte(pae), digest[:], env.Signatures[0].Sig) {
+		return nil, errors.New("invalid signature")
+	}
+
+	var st inTotoStatement
+	if err := json.Unmarshal(env.Payload, &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func verifySignature(key crypto.PublicKey, msg, digest, sig []byte) bool {
+	switch k := key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(k, digest, sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(k, msg, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(k, crypto.SHA256, digest, sig) == nil
+	}
+	return false
+}
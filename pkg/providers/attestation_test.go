@@ -0,0 +1,138 @@
+package providers
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// attestedRelease serves an immutable release with a single asset
+// whose attestation is signed with a throwaway key
+type attestedRelease struct {
+	immutable bool
+	asset     string
+	// attested is the content the attestation was made for
+	attested string
+	// extra is an asset published after the attestation
+	extra  string
+	tamper bool
+	// lookupFails fails the request telling if the release is immutable
+	lookupFails bool
+}
+
+func (a attestedRelease) handler(t *testing.T) http.Handler {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "sigstore-intermediate"}, NotBefore: time.Now().Add(-time.Hour), NotAfter: time.Now().Add(time.Hour)}
+	cert, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := sha256.Sum256([]byte(a.attested))
+	payload, _ := json.Marshal(map[string]interface{}{
+		"_type":         "https://in-toto.io/Statement/v1",
+		"subject":       []interface{}{map[string]interface{}{"name": "tool_linux_amd64", "digest": map[string]string{"sha256": fmt.Sprintf("%x", sum)}}},
+		"predicateType": releasePredicateType,
+		"predicate":     map[string]string{"purl": "pkg:github/grpc-ecosystem/grpc-gateway@v1.0.0"},
+	})
+	payloadType := "application/vnd.in-toto+json"
+	pae := sha256.Sum256([]byte(fmt.Sprintf("DSSEv1 %d %s %d %s", len(payloadType), payloadType, len(payload), payload)))
+	sig, err := ecdsa.SignASN1(rand.Reader, key, pae[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.tamper {
+		sig[len(sig)-1] ^= 0xff
+	}
+	bundle := map[string]interface{}{
+		"mediaType":            "application/vnd.dev.sigstore.bundle.v0.3+json",
+		"verificationMaterial": map[string]interface{}{"certificate": map[string][]byte{"rawBytes": cert}},
+		"dsseEnvelope":         map[string]interface{}{"payload": payload, "payloadType": payloadType, "signatures": []map[string][]byte{{"sig": sig}}},
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		assets := fmt.Sprintf(`{"id":1,"name":"tool_linux_amd64","url":"http://%s/assets/1"}`, r.Host)
+		if a.extra != "" {
+			assets += fmt.Sprintf(`,{"id":2,"name":%q,"url":"http://%s/assets/2"}`, a.extra, r.Host)
+		}
+		fmt.Fprintf(w, `{"id":42,"tag_name":"v1.0.0","assets":[%s]}`, assets)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/42", func(w http.ResponseWriter, r *http.Request) {
+		if a.lookupFails {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"id":42,"tag_name":"v1.0.0","immutable":%t}`, a.immutable)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/git/refs/tags/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"ref":"refs/tags/v1.0.0","object":{"type":"tag","sha":"aaaa"}}`)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/git/tags/aaaa", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"sha":"aaaa","object":{"type":"commit","sha":"bbbb"}}`)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/attestations/sha1:bbbb", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("predicate_type") != "release" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"attestations": []interface{}{map[string]interface{}{"bundle": bundle}}})
+	})
+	mux.HandleFunc("/assets/", func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, a.asset)
+	})
+	return mux
+}
+
+func TestGitHubImmutableRelease(t *testing.T) {
+	tests := []struct {
+		name     string
+		release  attestedRelease
+		verified string
+		err      string
+	}{
+		{"attested", attestedRelease{immutable: true, asset: "binary", attested: "binary"}, "sha256", ""},
+		{"changed asset", attestedRelease{immutable: true, asset: "evil", attested: "binary"}, "", "checksum mismatch"},
+		{"invalid signature", attestedRelease{immutable: true, asset: "binary", attested: "binary", tamper: true}, "", "invalid signature"},
+		{"asset added", attestedRelease{immutable: true, asset: "binary", attested: "binary", extra: "tool_darwin_arm64"}, "", "tool_darwin_arm64 not attested"},
+		{"mutable release", attestedRelease{asset: "evil", attested: "binary"}, "", ""},
+		{"unknown immutability", attestedRelease{immutable: true, asset: "binary", attested: "binary", lookupFails: true}, "", "error checking if v1.0.0 is an immutable release"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newTestGitHub(t, tt.release.handler(t), nil)
+			g.tag = "v1.0.0"
+			g.token = func() string { return "" }
+
+			f, err := g.Fetch(&FetchOpts{})
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("expected error containing %q, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if f.VerifiedWith != tt.verified {
+				t.Fatalf("expected the asset to be verified with %q, got %q", tt.verified, f.VerifiedWith)
+			}
+		})
+	}
+}
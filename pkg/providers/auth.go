@@ -0,0 +1,26 @@
+package providers
+
+import "net/http"
+
+// authTransport sets an authentication header resolved on every
+// request, so credentials changed during the run (i.e. a refreshed
+// token) are picked up
+type authTransport struct {
+	next   http.RoundTripper
+	header string
+	value  func() string
+}
+
+func (t *authTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	next := t.next
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	v := t.value()
+	if v == "" || req.Header.Get(t.header) != "" {
+		return next.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set(t.header, v)
+	return next.RoundTrip(req)
+}
@@ -0,0 +1,65 @@
+package providers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+// bundled installs an asset of an offline bundle (see bin download)
+// with the extraction logic of the remote ones, as if it was fetched
+// from the provider it was downloaded with
+type bundled struct {
+	id          string
+	version     string
+	asset       string
+	assetURL    string
+	packagePath string
+	data        []byte
+}
+
+// NewBundled returns the provider of an asset of an offline bundle
+// downloaded from the provider id at assetURL, its digest is already
+// verified
+func NewBundled(id, version, asset, assetURL, packagePath string, data []byte) Provider {
+	return &bundled{id: id, version: version, asset: asset, assetURL: assetURL, packagePath: packagePath, data: data}
+}
+
+func (b *bundled) Fetch(opts *FetchOpts) (*File, error) {
+	if opts.Version != "" && opts.Version != b.version {
+		return nil, fmt.Errorf("the bundle has version %s, not %s", b.version, opts.Version)
+	}
+	packagePath := opts.PackagePath
+	if packagePath == "" {
+		packagePath = b.packagePath
+	}
+
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: packagePath, SkipPathCheck: opts.SkipPatchCheck, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Relax: opts.Relax})
+	outFile, err := f.ProcessReader(b.asset, bytes.NewReader(b.data))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{Data: outFile.Source, Name: outFile.Name, Version: b.version, PackagePath: outFile.PackagePath, Asset: b.asset, AssetSize: int64(len(b.data)), AssetSHA256: fmt.Sprintf("%x", sha256.Sum256(b.data)), AssetURL: b.assetURL, OtherEntries: f.OtherEntries(), Extras: f.Extras(), Entry: f.Entry()}, nil
+}
+
+func (b *bundled) GetLatestVersion() (string, string, error) {
+	return b.version, "", nil
+}
+
+func (b *bundled) ListVersions(limit int) ([]*Release, error) {
+	return []*Release{{Version: b.version}}, nil
+}
+
+// GetID returns the provider the asset was downloaded with, so
+// the binary is updated from it once back online
+func (b *bundled) GetID() string {
+	return b.id
+}
+
+// Capabilities of bundles, they only hold a version
+func (b *bundled) Capabilities() Capabilities {
+	return NewCapabilities(CapChecksums, CapAssetSizes)
+}
@@ -0,0 +1,81 @@
+package providers
+
+import "strings"
+
+// Capability is a feature a provider may support. Commands check
+// them upfront instead of failing once they reach the provider
+type Capability uint
+
+const (
+	// CapListVersions lists the published versions, the
+	// providers without it only know the latest one
+	CapListVersions Capability = 1 << iota
+	// CapChecksums verifies the assets with the
+	// checksums published along them
+	CapChecksums
+	// CapReleaseNotes publishes notes along the releases
+	CapReleaseNotes
+	// CapAssetSizes reports the size of the downloaded assets,
+	// which the anomaly and quota checks rely on
+	CapAssetSizes
+	// CapPrereleases tells the prereleases apart
+	CapPrereleases
+)
+
+// capabilityNames are the names of the capabilities, in display order
+var capabilityNames = []struct {
+	c    Capability
+	name string
+}{
+	{CapListVersions, "versions"},
+	{CapChecksums, "checksums"},
+	{CapReleaseNotes, "release-notes"},
+	{CapAssetSizes, "asset-sizes"},
+	{CapPrereleases, "prereleases"},
+}
+
+// String returns the name of the capability
+func (c Capability) String() string {
+	for _, n := range capabilityNames {
+		if n.c == c {
+			return n.name
+		}
+	}
+	return "unknown"
+}
+
+// Capabilities is the set of capabilities of a provider
+type Capabilities Capability
+
+// NewCapabilities returns the set of the given capabilities
+func NewCapabilities(cs ...Capability) Capabilities {
+	var s Capabilities
+	for _, c := range cs {
+		s |= Capabilities(c)
+	}
+	return s
+}
+
+// Has reports whether the capability is in the set
+func (s Capabilities) Has(c Capability) bool {
+	return s&Capabilities(c) != 0
+}
+
+// Intersect returns the capabilities in both sets
+func (s Capabilities) Intersect(o Capabilities) Capabilities {
+	return s & o
+}
+
+// String returns the names of the capabilities, comma separated
+func (s Capabilities) String() string {
+	names := []string{}
+	for _, n := range capabilityNames {
+		if s.Has(n.c) {
+			names = append(names, n.name)
+		}
+	}
+	if len(names) == 0 {
+		return "-"
+	}
+	return strings.Join(names, ",")
+}
@@ -0,0 +1,92 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCapabilities(t *testing.T) {
+	content := []byte("#!/bin/sh\necho tool\n")
+	mux := http.NewServeMux()
+	mux.HandleFunc("/version", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "1.0.0")
+	})
+	mux.HandleFunc("/tool-1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(content)
+	})
+	mux.Handle("/listing.html", http.FileServer(http.Dir("testdata")))
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"tag_name":"v2.21.0-rc.1","prerelease":true},{"tag_name":"v2.20.0"}]`)
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	local := filepath.Join(t.TempDir(), "tool")
+	if err := os.WriteFile(local, content, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	build := func(u string, opts *Opts) Provider {
+		p, err := New(u, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	cases := []struct {
+		desc  string
+		p     Provider
+		fetch bool
+	}{
+		{"github", newTestGitHub(t, mux, &TagFilter{}), false},
+		{"scraper", build(srv.URL+"/listing.html", &Opts{Scrape: true}), false},
+		{"generic", build(srv.URL+"/tool-{version}", &Opts{VersionURL: srv.URL + "/version"}), true},
+		{"direct", build(srv.URL+"/tool-1.0.0", &Opts{Provider: "direct"}), true},
+		{"local", build(local, nil), true},
+		{"docker", build("docker://alpine:3.19", nil), false},
+	}
+
+	for _, c := range cases {
+		caps := c.p.Capabilities()
+		releases, err := c.p.ListVersions(0)
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		if caps.Has(CapListVersions) != (len(releases) > 1) {
+			t.Errorf("%s: advertises %s but lists %d versions", c.desc, caps, len(releases))
+		}
+		prerelease := false
+		for _, r := range releases {
+			prerelease = prerelease || r.Prerelease
+		}
+		if prerelease && !caps.Has(CapPrereleases) {
+			t.Errorf("%s: advertises %s but reports prereleases", c.desc, caps)
+		}
+		if c.fetch {
+			f, err := c.p.Fetch(&FetchOpts{})
+			if err != nil {
+				t.Fatalf("%s: %v", c.desc, err)
+			}
+			if caps.Has(CapAssetSizes) != (f.AssetSize > 0) {
+				t.Errorf("%s: advertises %s but reports an asset size of %d", c.desc, caps, f.AssetSize)
+			}
+		}
+	}
+}
+
+func TestCapabilitiesString(t *testing.T) {
+	if s := NewCapabilities(CapPrereleases, CapListVersions).String(); s != "versions,prereleases" {
+		t.Errorf("unexpected capabilities %s", s)
+	}
+	if s := NewCapabilities().String(); s != "-" {
+		t.Errorf("unexpected capabilities %s", s)
+	}
+	m := &multi{sources: []Provider{&fakeSource{}, &docker{}}}
+	if c := m.Capabilities(); c.Has(CapListVersions) {
+		t.Errorf("expected the capabilities of every source, got %s", c)
+	}
+}
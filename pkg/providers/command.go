@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// versionCmdTimeout bounds the version command of the generic provider
+var versionCmdTimeout = 30 * time.Second
+
+// SplitCommand splits the command into its arguments like a shell
+// does, honoring quotes and backslashes, without interpreting anything
+// else: `sh -c '...'` runs it through a shell when needed
+func SplitCommand(s string) ([]string, error) {
+	var args []string
+	var cur strings.Builder
+	inArg := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case r == '\\' && quote != '\'':
+			escaped, inArg = true, true
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inArg = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, cur.String())
+				cur.Reset()
+				inArg = false
+			}
+		default:
+			cur.WriteRune(r)
+			inArg = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inArg {
+		args = append(args, cur.String())
+	}
+	if len(args) == 0 {
+		return nil, fmt.Errorf("empty command")
+	}
+	return args, nil
+}
+
+// runVersionCmd runs the version command and returns its trimmed
+// stdout. It's run without a shell unless shell is set.
+func runVersionCmd(command string, shell bool) (string, error) {
+	args := []string{"sh", "-c", command}
+	if shell && goos == "windows" {
+		args = []string{"cmd", "/C", command}
+	}
+	if !shell {
+		var err error
+		if args, err = SplitCommand(command); err != nil {
+			return "", fmt.Errorf("invalid version command %q: %w", command, err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), versionCmdTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	// the children of the shell may keep its output open
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	log.Debugf("Getting version from the command %q", command)
+	if err := cmd.Run(); err != nil {
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", versionCmdTimeout)
+		}
+		return "", versionCmdError(command, err, stderr.String())
+	}
+	v := strings.TrimSpace(stdout.String())
+	if v == "" {
+		return "", versionCmdError(command, errors.New("no version printed on stdout"), stderr.String())
+	}
+	return v, nil
+}
+
+func versionCmdError(command string, err error, stderr string) error {
+	if s := tail(stderr, handlerStderrSize); s != "" {
+		return fmt.Errorf("version command %q failed: %w: %s", command, err, s)
+	}
+	return fmt.Errorf("version command %q failed: %w", command, err)
+}
@@ -0,0 +1,27 @@
+package providers
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	cases := []struct {
+		in  string
+		out []string
+		err bool
+	}{
+		{"helm plugin update", []string{"helm", "plugin", "update"}, false},
+		{`xattr -d com.apple.quarantine "$BIN_PATH"`, []string{"xattr", "-d", "com.apple.quarantine", "$BIN_PATH"}, false},
+		{`sh -c 'tool completion zsh > "$HOME/.zfunc/_tool"'`, []string{"sh", "-c", `tool completion zsh > "$HOME/.zfunc/_tool"`}, false},
+		{`echo a\ b "" 'c\d'`, []string{"echo", "a b", "", `c\d`}, false},
+		{`echo 'unterminated`, nil, true},
+		{"  ", nil, true},
+	}
+	for _, c := range cases {
+		out, err := SplitCommand(c.in)
+		if (err != nil) != c.err || !slices.Equal(out, c.out) {
+			t.Errorf("%s: expected %q (error %t), got %q (%v)", c.in, c.out, c.err, out, err)
+		}
+	}
+}
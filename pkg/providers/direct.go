@@ -0,0 +1,150 @@
+package providers
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+// directExts are the extensions of the files which can be installed
+// as is, the URL of the page of a download site can't be mistaken
+// for one of them
+var directExts = []string{".tar.gz", ".tgz", ".tar.xz", ".txz", ".tar.zst", ".tzst", ".tar.bz2", ".tbz", ".zip", ".7z", ".gz", ".xz", ".zst", ".bz2", ".exe", ".appimage", ".dmg", ".pkg"}
+
+// directContentTypes are the content types of the files
+// which can be installed as is
+var directContentTypes = map[string]bool{
+	"application/octet-stream":      true,
+	"application/gzip":              true,
+	"application/x-gzip":            true,
+	"application/zip":               true,
+	"application/x-7z-compressed":   true,
+	"application/x-apple-diskimage": true,
+	"application/x-xz":              true,
+	"application/x-bzip2":           true,
+	"application/zstd":              true,
+	"application/x-tar":             true,
+	"application/x-executable":      true,
+	"application/x-msdownload":      true,
+	"application/x-elf":             true,
+	"application/x-mach-binary":     true,
+	"application/x-sharedlib":       true,
+	"binary/octet-stream":           true,
+	"application/vnd.appimage":      true,
+	"application/x-iso9660-image":   true,
+}
+
+// direct is a one-off download of a versioned file, i.e.
+// https://example.com/tool-1.8.0-linux-amd64.tar.gz. There's
+// nothing to track, the file is the release.
+type direct struct {
+	url     *url.URL
+	version string
+	client  *http.Client
+	headers map[string]string
+}
+
+func newDirect(u *url.URL, opts *Opts, s *Settings) (Provider, error) {
+	d := &direct{url: u, version: opts.Version, client: s.HTTPClient()}
+	if d.version == "" {
+		d.version = strings.TrimPrefix(linkVersionRe.FindString(path.Base(u.Path)), "v")
+	}
+	if d.version == "" {
+		return nil, fmt.Errorf("can't find the version in %s, set it with --version", path.Base(u.Path))
+	}
+	var err error
+	if d.headers, err = resolveHeaders(s, opts.Headers, opts.BasicAuth); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// isDirect checks if the URL points straight at a versioned file,
+// either by its extension or by the content type served
+func isDirect(u *url.URL, opts *Opts, s *Settings) bool {
+	if opts.VersionURL != "" || opts.VersionCmd != "" || opts.VersionProbe != "" || opts.Scrape {
+		return false
+	}
+	name := path.Base(u.Path)
+	if linkVersionRe.FindString(name) == "" {
+		return false
+	}
+	lname := strings.ToLower(name)
+	for _, ext := range directExts {
+		if strings.HasSuffix(lname, ext) {
+			return true
+		}
+	}
+	if path.Ext(lname) != "" && !strings.ContainsAny(path.Ext(lname)[1:], "0123456789") {
+		// an unknown extension, i.e. .html
+		return false
+	}
+
+	// no extension or the version is the extension (tool-1.8.0)
+	res, err := s.HTTPClient().Head(u.String())
+	if err != nil {
+		log.Debugf("Error probing the content type of %s: %v", u, err)
+		return false
+	}
+	res.Body.Close()
+	ct, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	log.Debugf("%s is served as %q", u, ct)
+	return res.StatusCode < 300 && directContentTypes[ct]
+}
+
+func (d *direct) Fetch(opts *FetchOpts) (*File, error) {
+	if opts.Version != "" && opts.Version != d.version {
+		return nil, fmt.Errorf("%s is a direct download of version %s only, install the URL of the version %s instead", d.url, d.version, opts.Version)
+	}
+	name := path.Base(d.url.Path)
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, HTTPClient: d.client, Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, d.version), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax})
+
+	gf, err := f.FilterAssets(d.name(), []*assets.Asset{{Name: name, URL: d.url.String(), NameFromURL: true}})
+	if err != nil {
+		return nil, err
+	}
+	gf.ExtraHeaders = d.headers
+
+	outFile, err := f.ProcessURL(gf)
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{Data: outFile.Source, Name: outFile.Name, Version: d.version, PackagePath: outFile.PackagePath, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}, nil
+}
+
+// name guesses the name of the binary, it's what comes
+// before the version in the file name
+func (d *direct) name() string {
+	name := path.Base(d.url.Path)
+	if i := strings.Index(name, linkVersionRe.FindString(name)); i > 0 {
+		name = name[:i]
+	}
+	return strings.TrimRight(name, "-_.")
+}
+
+// GetLatestVersion returns the version of the file, direct
+// downloads don't have newer versions
+func (d *direct) GetLatestVersion() (string, string, error) {
+	return d.version, d.url.String(), nil
+}
+
+func (d *direct) ListVersions(limit int) ([]*Release, error) {
+	return []*Release{{Version: d.version, URL: d.url.String()}}, nil
+}
+
+func (d *direct) GetID() string {
+	return "direct"
+}
+
+// Capabilities of direct downloads, their version is the one of the URL
+func (d *direct) Capabilities() Capabilities {
+	return NewCapabilities(CapChecksums, CapAssetSizes)
+}
@@ -0,0 +1,135 @@
+package providers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+func TestDirect(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/tool-1.8.0":
+			w.Header().Set("Content-Type", "application/octet-stream")
+		default:
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		url     string
+		opts    *Opts
+		id      string
+		version string
+	}{
+		{"https://example.com/downloads/tool-1.8.0-linux-amd64.tar.gz", &Opts{}, "direct", "1.8.0"},
+		{"https://example.com/downloads/tool_v2.1_Linux_x86_64.zip", &Opts{}, "direct", "2.1"},
+		{srv.URL + "/tool-1.8.0", &Opts{}, "direct", "1.8.0"},
+		{srv.URL + "/release-1.8", &Opts{}, "generic", ""},
+		{"https://example.com/downloads/tool-1.8.0.html", &Opts{}, "generic", ""},
+		// no version, updates are detected from the download itself
+		{"https://example.com/tool/latest/tool-linux-amd64.tar.gz", &Opts{}, "generic", ""},
+		{"https://example.com/tool/latest/tool-linux-amd64.tar.gz", &Opts{Provider: "direct", Version: "3.0.0"}, "direct", "3.0.0"},
+		{"https://example.com/tool-1.8.0-linux-amd64.tar.gz", &Opts{VersionURL: "https://example.com/version"}, "generic", ""},
+	}
+
+	for _, c := range cases {
+		p, err := New(c.url, c.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", c.url, err)
+		}
+		if p.GetID() != c.id {
+			t.Errorf("%s: expected the %s provider, got %s", c.url, c.id, p.GetID())
+			continue
+		}
+		if c.id != "direct" {
+			continue
+		}
+		v, u, err := p.GetLatestVersion()
+		if err != nil || v != c.version || u != c.url {
+			t.Errorf("%s: expected version %s, got %s (%s) %v", c.url, c.version, v, u, err)
+		}
+	}
+
+	p, err := New("https://example.com/downloads/tool-1.8.0-linux-amd64.tar.gz", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name := p.(*direct).name(); name != "tool" {
+		t.Errorf("expected the binary name to be tool, got %s", name)
+	}
+	if _, err := p.Fetch(&FetchOpts{Version: "1.9.0"}); err == nil {
+		t.Error("expected fetching another version to fail")
+	}
+	if _, err := New("https://example.com/tool/latest/tool-linux-amd64.tar.gz", &Opts{Provider: "direct"}); err == nil {
+		t.Error("expected an error without any version")
+	}
+}
+
+// TestConcurrentDirectFetches fetches through providers sharing
+// their settings and download cache. It's meant to be run with -race.
+func TestConcurrentDirectFetches(t *testing.T) {
+	bin := []byte("#!/bin/sh\necho tool\n")
+	var tgz bytes.Buffer
+	gw := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "tool", Mode: 0o755, Size: int64(len(bin)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(bin)
+	tw.Close()
+	gw.Close()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".tar.gz") {
+			w.Write(tgz.Bytes())
+			return
+		}
+		w.Write(bin)
+	}))
+	defer srv.Close()
+
+	settings := NewSettings(true, nil)
+	cache := assets.NewDownloadCache()
+	defer cache.Close()
+	var wg sync.WaitGroup
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			u := fmt.Sprintf("%s/tool-1.%d.0", srv.URL, i%4)
+			if i%2 == 0 {
+				u += ".tar.gz"
+			}
+			p, err := New(u, &Opts{Provider: "direct", Settings: settings})
+			if err != nil {
+				errs <- err
+				return
+			}
+			f, err := p.Fetch(&FetchOpts{Cache: cache})
+			if err != nil {
+				errs <- err
+				return
+			}
+			if b, _ := io.ReadAll(f.Data); !bytes.Equal(b, bin) {
+				errs <- fmt.Errorf("%s: unexpected content %q", u, b)
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"os"
 	"strings"
 
-	"github.com/caarlos0/log"
 	"github.com/docker/docker/api/types/image"
 	"github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/marcosnils/bin/pkg/log"
 )
 
 type docker struct {
@@ -22,7 +22,7 @@ func (d *docker) Fetch(opts *FetchOpts) (*File, error) {
 		// this is used by for the `ensure` command
 		d.tag = opts.Version
 	}
-	log.Infof("Pulling docker image %s:%s", d.repo, d.tag)
+	log.WithField("provider", d.GetID()).WithField("repo", d.repo).WithField("version", d.tag).Info("Pulling the docker image")
 	out, err := d.client.ImageCreate(context.Background(), fmt.Sprintf("%s:%s", d.repo, d.tag), image.CreateOptions{})
 	if err != nil {
 		return nil, err
@@ -51,16 +51,36 @@ func (d *docker) GetLatestVersion() (string, string, error) {
 	return d.tag, "", nil
 }
 
+// ListVersions only returns the configured tag, image
+// registries are not queried
+func (d *docker) ListVersions(limit int) ([]*Release, error) {
+	return []*Release{{Version: d.tag}}, nil
+}
+
 func (d *docker) GetID() string {
 	return "docker"
 }
 
-func newDocker(imageURL string) (Provider, error) {
+// Capabilities of docker images, only their configured tag is known
+func (d *docker) Capabilities() Capabilities {
+	return NewCapabilities()
+}
+
+func newDocker(imageURL string, s *Settings) (Provider, error) {
 	imageURL = strings.TrimPrefix(imageURL, "docker://")
 
 	repo, tag := parseImage(imageURL)
 
-	c, err := client.NewClientWithOpts(client.FromEnv)
+	opts := []client.Opt{client.FromEnv}
+	if s.Pure() {
+		// only honor an explicitly configured docker host
+		opts = []client.Opt{client.WithAPIVersionNegotiation()}
+		if h := s.Get("DOCKER_HOST"); h != "" {
+			opts = append(opts, client.WithHost(h))
+		}
+	}
+
+	c, err := client.NewClientWithOpts(opts...)
 	if err != nil {
 		return nil, err
 	}
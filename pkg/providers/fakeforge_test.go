@@ -0,0 +1,71 @@
+package providers
+
+import (
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/fakeforge"
+)
+
+// TestFakeForgeScenario runs the github provider against the fake
+// forge while its releases change like they do upstream
+func TestFakeForgeScenario(t *testing.T) {
+	forge := fakeforge.New()
+	if err := forge.AddTool("acme/tool", "v1.0.0"); err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(forge)
+	defer srv.Close()
+
+	s := NewSettings(true, map[string]string{"GHES_BASE_URL": srv.URL + fakeforge.APIPath})
+	fetch := func(version string) (*File, error) {
+		t.Helper()
+		p, err := New(srv.URL+"/acme/tool", &Opts{Settings: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.GetID() != "github" {
+			t.Fatalf("expected the github provider, got %s", p.GetID())
+		}
+		return p.Fetch(&FetchOpts{Version: version})
+	}
+
+	f, err := fetch("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := io.ReadAll(f.Data); f.Version != "v1.0.0" || f.VerifiedWith != "sha256" || !bytes.Equal(b, fakeforge.Script("tool", "v1.0.0")) {
+		t.Fatalf("expected v1.0.0 verified with sha256, got %s verified with %q: %q", f.Version, f.VerifiedWith, b)
+	}
+
+	// a release is added
+	if err := forge.AddTool("acme/tool", "v1.1.0"); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = fetch(""); err != nil || f.Version != "v1.1.0" {
+		t.Fatalf("expected the new release v1.1.0, got %v (%v)", f, err)
+	}
+
+	// the asset of the host is renamed, it's still picked
+	host := runtime.GOOS + "/" + runtime.GOARCH
+	asset := fakeforge.AssetName("tool", "v1.1.0", host)
+	renamed := strings.Replace(asset, "tool_1.1.0", "tool-1.1.0", 1)
+	if err := forge.RenameAsset("acme/tool", "v1.1.0", asset, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if f, err = fetch("v1.1.0"); err != nil || f.Asset != renamed {
+		t.Fatalf("expected the renamed asset %s, got %v (%v)", renamed, f, err)
+	}
+
+	// the asset is re-published without updating the checksums
+	if err := forge.ReplaceAsset("acme/tool", "v1.0.0", fakeforge.AssetName("tool", "v1.0.0", host), []byte("tampered")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fetch("v1.0.0"); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected a checksum mismatch, got %v", err)
+	}
+}
@@ -0,0 +1,202 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+const (
+	forgeGitHub           = "github"
+	forgeGitHubEnterprise = "github-enterprise"
+	forgeGitLab           = "gitlab"
+
+	// forgeProbeTimeout bounds each request
+	// done to detect the forge of a host
+	forgeProbeTimeout = 10 * time.Second
+)
+
+// gitHubPublicAPI is the base URL of the API of github.com
+var gitHubPublicAPI = "https://api.github.com/"
+
+// isGitHubPublic reports whether the host is github.com
+func isGitHubPublic(host string) bool {
+	switch strings.ToLower(host) {
+	case "", "github.com", "api.github.com":
+		return true
+	}
+	return false
+}
+
+// gitHubEnterpriseHost reports whether the host is the
+// one of the configured GitHub Enterprise Server
+func (s *Settings) gitHubEnterpriseHost(host string) bool {
+	u, err := url.Parse(s.Get("GHES_BASE_URL"))
+	return err == nil && u.Hostname() != "" && strings.EqualFold(u.Hostname(), host)
+}
+
+// gitHubPathPrefix returns the path the GitHub Enterprise Server of
+// the host is served under, the one of GHES_BASE_URL before /api/v3
+func (s *Settings) gitHubPathPrefix(host string) string {
+	if !s.gitHubEnterpriseHost(host) {
+		return ""
+	}
+	u, err := url.Parse(s.Get("GHES_BASE_URL"))
+	if err != nil {
+		return ""
+	}
+	return strings.Trim(strings.TrimSuffix(strings.TrimRight(u.Path, "/"), "/api/v3"), "/")
+}
+
+// knownForge returns the forge of the host when it's known without
+// probing it: github.com, the configured GitHub Enterprise Server or
+// a host detected earlier in the run
+func (s *Settings) knownForge(host string) (string, bool) {
+	switch {
+	case isGitHubPublic(host):
+		return forgeGitHub, true
+	case s.gitHubEnterpriseHost(host):
+		return forgeGitHubEnterprise, true
+	case s == nil:
+		return "", false
+	}
+	s.forgesMu.Lock()
+	defer s.forgesMu.Unlock()
+	f, ok := s.forges[strings.ToLower(host)]
+	return f, ok
+}
+
+// detectForge returns the forge serving the GitHub-like URL. Unknown
+// hosts are probed once per run for the API of a GitHub Enterprise
+// Server, then the one of GitLab. They're assumed to be github.com
+// when neither answers.
+func (s *Settings) detectForge(u *url.URL) string {
+	if f, ok := s.knownForge(u.Hostname()); ok {
+		return f
+	}
+
+	// the lock is held while probing so concurrent
+	// lookups of the same host don't probe it twice
+	s.forgesMu.Lock()
+	defer s.forgesMu.Unlock()
+	host := strings.ToLower(u.Hostname())
+	if f, ok := s.forges[host]; ok {
+		return f
+	}
+	f := s.probeForge(u)
+	if s.forges == nil {
+		s.forges = map[string]string{}
+	}
+	s.forges[host] = f
+	return f
+}
+
+func (s *Settings) probeForge(u *url.URL) string {
+	probe := func(path string, codes ...int) bool {
+		ctx, cancel := context.WithTimeout(context.Background(), forgeProbeTimeout)
+		defer cancel()
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s://%s%s", u.Scheme, u.Host, path), nil)
+		if err != nil {
+			return false
+		}
+		res, err := s.HTTPClient().Do(req)
+		if err != nil {
+			log.Debugf("Error probing %s: %v", req.URL, err)
+			return false
+		}
+		res.Body.Close()
+		return slices.Contains(codes, res.StatusCode)
+	}
+
+	switch {
+	case probe("/api/v3/meta", http.StatusOK):
+		log.Debugf("Detected a GitHub Enterprise Server at %s", u.Host)
+		return forgeGitHubEnterprise
+	case probe("/api/v4/version", http.StatusOK, http.StatusUnauthorized):
+		log.Debugf("Detected a GitLab instance at %s", u.Host)
+		return forgeGitLab
+	}
+	log.Debugf("Unable to detect the forge of %s, assuming github.com", u.Host)
+	return forgeGitHub
+}
+
+// gitHubTokenKeys returns the settings the token of a GitHub forge
+// is read from, in order of precedence. GHES_AUTH_TOKEN is only sent
+// to the configured GitHub Enterprise Server, the detected ones only
+// get the token set for their host.
+func (s *Settings) gitHubTokenKeys(forge, host string) []string {
+	if forge != forgeGitHubEnterprise {
+		return []string{"GITHUB_AUTH_TOKEN", "GITHUB_TOKEN"}
+	}
+	keys := []string{fmt.Sprintf("GITHUB_TOKEN_%s", strings.ReplaceAll(strings.ToLower(host), `.`, "_"))}
+	if s.gitHubEnterpriseHost(host) {
+		keys = append(keys, "GHES_AUTH_TOKEN")
+	}
+	return keys
+}
+
+// gitHubAPIClient returns the client of the API of the given GitHub
+// forge for the host of the URL. It's created once per host and
+// shared by every repository of the host for the run.
+func (s *Settings) gitHubAPIClient(u *url.URL, forge string) (*github.Client, error) {
+	if s == nil {
+		return s.newGitHubAPIClient(u, forge)
+	}
+
+	key := forge + "/" + strings.ToLower(u.Host)
+	s.forgesMu.Lock()
+	defer s.forgesMu.Unlock()
+	if c, ok := s.gitHubClients[key]; ok {
+		return c, nil
+	}
+	client, err := s.newGitHubAPIClient(u, forge)
+	if err != nil {
+		return nil, err
+	}
+	if s.gitHubClients == nil {
+		s.gitHubClients = map[string]*github.Client{}
+	}
+	s.gitHubClients[key] = client
+	return client, nil
+}
+
+func (s *Settings) newGitHubAPIClient(u *url.URL, forge string) (*github.Client, error) {
+	var client *github.Client
+	if forge == forgeGitHubEnterprise {
+		keys := s.gitHubTokenKeys(forge, u.Hostname())
+		tc := &http.Client{Transport: &authTransport{next: s.HTTPClient().Transport, header: "Authorization", value: func() string {
+			if t := s.get(keys...); t != "" {
+				return "Bearer " + t
+			}
+			return ""
+		}}}
+
+		base := fmt.Sprintf("%s://%s/api/v3/", u.Scheme, u.Host)
+		upload := fmt.Sprintf("%s://%s/api/uploads/", u.Scheme, u.Host)
+		if s.gitHubEnterpriseHost(u.Hostname()) {
+			base = s.Get("GHES_BASE_URL")
+			if guu := s.Get("GHES_UPLOAD_URL"); guu != "" {
+				upload = guu
+			}
+		}
+		var err error
+		if client, err = github.NewEnterpriseClient(base, upload, tc); err != nil {
+			return nil, fmt.Errorf("error initializing GHES client %v", err)
+		}
+	} else {
+		client = github.NewClient(s.gitHub())
+		api, err := url.Parse(gitHubPublicAPI)
+		if err != nil {
+			return nil, err
+		}
+		client.BaseURL = api
+	}
+	return client, nil
+}
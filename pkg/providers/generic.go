@@ -5,10 +5,10 @@ import (
 	"io"
 	"net/http"
 	"net/url"
-	"path/filepath"
+	"regexp"
 	"strings"
 
-	"github.com/caarlos0/log"
+	"github.com/marcosnils/bin/pkg/log"
 
 	"github.com/marcosnils/bin/pkg/assets"
 )
@@ -17,49 +17,133 @@ type generic struct {
 	url        string
 	versionURL *url.URL
 	client     *http.Client
+	// gitHub is the shared GitHub client, used when
+	// the version URL is a GitHub API endpoint
+	gitHub *http.Client
+	// versionPath and versionRe extract the version
+	// from the body of the version URL
+	versionPath []jsonStep
+	versionRe   *regexp.Regexp
+	// versionCmd prints the version instead of the version URL,
+	// it's run with the shell when versionCmdShell is set
+	versionCmd      string
+	versionCmdShell bool
+	// headers are sent with every request, i.e. for authentication
+	headers map[string]string
+
+	// prober discovers new versions when there's no version
+	// URL, starting from the current one
+	prober  *prober
+	current string
 }
 
 func (g *generic) Fetch(opts *FetchOpts) (*File, error) {
-	// Get version
-	version, versionURL, err := g.GetLatestVersion()
-	if err != nil {
+	var version, versionURL string
+	var err error
+	if len(opts.Version) > 0 && strings.Contains(g.url, "{version}") {
+		// this is used by for the `ensure` command
+		version, versionURL = opts.Version, expandTemplate(g.url, opts.Version)
+	} else if version, versionURL, err = g.GetLatestVersion(); err != nil {
 		return nil, err
 	}
 
-	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName})
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, HTTPClient: g.client, Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, version), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax})
 
-	gf := &assets.FilteredAsset{URL: versionURL}
+	gf := &assets.FilteredAsset{URL: versionURL, ExtraHeaders: g.headers}
 
 	outFile, err := f.ProcessURL(gf)
 	if err != nil {
 		return nil, err
 	}
 
-	// Set default name to last url path element if none was provided by the filter
+	// Set default name to the canonical name of the download if none was provided by the filter
 	if outFile.Name == "" {
-		outFile.Name = filepath.Base(gf.URL)
+		outFile.Name = gf.Name
 	}
 
-	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath}
+	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}
 
 	return file, nil
 }
 
+// Describe returns the URL the version is checked at and
+// the template of the download URL, if any
+func (g *generic) Describe() map[string]string {
+	d := map[string]string{"version_url": "", "template": ""}
+	if g.versionURL != nil {
+		d["version_url"] = g.versionURL.String()
+	}
+	if IsTemplate(g.url) {
+		d["template"] = g.url
+	}
+	if g.versionCmd != "" {
+		d["version_cmd"] = g.versionCmd
+	}
+	return d
+}
+
+// ListVersions returns the current version only, generic
+// download servers have no way to list their versions
+func (g *generic) ListVersions(limit int) ([]*Release, error) {
+	if g.current != "" {
+		return []*Release{{Version: g.current, URL: expandTemplate(g.url, g.current)}}, nil
+	}
+
+	version, u, err := g.GetLatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	return []*Release{{Version: version, URL: u}}, nil
+}
+
 // GetLatestVersion checks the version url and
 // returns the corresponding name and url to fetch the version
 func (g *generic) GetLatestVersion() (string, string, error) {
+	if g.prober != nil {
+		log.Debugf("Probing versions newer than %s", g.current)
+		version, err := g.prober.latest(g.current, expandTemplate(g.url, "{version}"))
+		if err != nil {
+			return "", "", err
+		}
+		return version, expandTemplate(g.url, version), nil
+	}
+
+	if g.versionCmd != "" {
+		out, err := runVersionCmd(g.versionCmd, g.versionCmdShell)
+		if err != nil {
+			return "", "", err
+		}
+		version, err := g.extractVersion([]byte(out))
+		if err != nil {
+			return "", "", err
+		}
+		return version, expandTemplate(g.url, version), nil
+	}
+
 	if g.versionURL == nil {
-		u, err := url.Parse(g.url)
+		u, err := url.Parse(expandTemplate(g.url, ""))
 		if err != nil {
 			return "", "", err
 		}
 
-		return "", u.String(), nil
+		version, err := g.validatorVersion(u.String())
+		if err != nil {
+			return "", "", err
+		}
+		return version, u.String(), nil
 	}
 
 	log.Debugf("Getting version from %s", g.versionURL.String())
 
-	resp, err := g.client.Get(g.versionURL.String())
+	req, err := g.newRequest(http.MethodGet, g.versionURL.String())
+	if err != nil {
+		return "", "", err
+	}
+	client := g.client
+	if g.gitHub != nil {
+		client = g.gitHub
+	}
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", "", err
 	}
@@ -70,9 +154,12 @@ func (g *generic) GetLatestVersion() (string, string, error) {
 		return "", "", err
 	}
 
-	version := strings.TrimSpace(string(content))
+	version, err := g.extractVersion(content)
+	if err != nil {
+		return "", "", err
+	}
 
-	versionURLString := strings.ReplaceAll(g.url, "{version}", version)
+	versionURLString := expandTemplate(g.url, version)
 
 	versionURL, err := url.Parse(versionURLString)
 	if err != nil {
@@ -82,20 +169,175 @@ func (g *generic) GetLatestVersion() (string, string, error) {
 	return version, versionURL.String(), nil
 }
 
+// validatorVersion derives a pseudo-version from the ETag or the
+// Last-Modified header of the download URL, so updates of binaries
+// served from a stable URL without any version can be detected
+func (g *generic) validatorVersion(u string) (string, error) {
+	log.Debugf("Getting the validators of %s", u)
+	req, err := g.newRequest(http.MethodHead, u)
+	if err != nil {
+		return "", err
+	}
+	res, err := g.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusMethodNotAllowed {
+		// some servers don't support HEAD requests
+		req, err := g.newRequest(http.MethodGet, u)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set("Range", "bytes=0-0")
+		if res, err = g.client.Do(req); err != nil {
+			return "", err
+		}
+		res.Body.Close()
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return "", fmt.Errorf("%d response when checking %s", res.StatusCode, u)
+	}
+
+	if etag := strings.Trim(strings.TrimPrefix(res.Header.Get("ETag"), "W/"), `"`); etag != "" {
+		return "etag-" + etag, nil
+	}
+	if lm, err := http.ParseTime(res.Header.Get("Last-Modified")); err == nil {
+		return "lm-" + lm.UTC().Format("20060102T150405Z"), nil
+	}
+
+	log.WithField("url", u).Warn("No version URL and the server returns neither ETag nor Last-Modified, updates can't be detected")
+	return "", nil
+}
+
+// extractVersion returns the version found in the body of the version
+// URL. The JSON path is applied first and then the regex, if configured.
+func (g *generic) extractVersion(content []byte) (string, error) {
+	from := fmt.Sprintf("the output of %q", g.versionCmd)
+	if g.versionURL != nil {
+		from = "the response of " + g.versionURL.String()
+	}
+	if g.versionPath != nil {
+		v, err := jsonPathString(content, g.versionPath)
+		if err != nil {
+			return "", fmt.Errorf("error extracting the version from %s: %w", from, err)
+		}
+		content = []byte(v)
+	}
+
+	if g.versionRe == nil {
+		return strings.TrimSpace(string(content)), nil
+	}
+
+	m := g.versionRe.FindSubmatch(content)
+	if m == nil {
+		return "", fmt.Errorf("version regex %q doesn't match %s: %q", g.versionRe, from, snippet(content))
+	}
+	return strings.TrimSpace(string(m[1])), nil
+}
+
+func (g *generic) newRequest(method, u string) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range g.headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
+
+// snippet truncates the body so it can be shown in errors
+func snippet(b []byte) string {
+	const max = 200
+	if len(b) > max {
+		return string(b[:max]) + "..."
+	}
+	return string(b)
+}
+
 func (g *generic) GetID() string {
 	return "generic"
 }
 
-func newGeneric(u, versionURL string) (p Provider, err error) {
+// Capabilities of the generic provider, it only knows the latest version
+func (g *generic) Capabilities() Capabilities {
+	return NewCapabilities(CapChecksums, CapAssetSizes)
+}
+
+func newGeneric(u string, opts *Opts, s *Settings) (p Provider, err error) {
+	if opts.Scrape {
+		if IsTemplate(u) || opts.VersionURL != "" || opts.VersionCmd != "" || opts.VersionProbe != "" {
+			return nil, fmt.Errorf("scraping can't be used with URL placeholders, a version URL or command or version probing")
+		}
+		return newScraper(u, opts, s)
+	}
+
 	// Validate the versionURL
 	var lurl *url.URL
 
-	if versionURL != "" {
-		lurl, err = url.Parse(versionURL)
+	if opts.VersionURL != "" {
+		lurl, err = url.Parse(opts.VersionURL)
 		if err != nil {
 			return nil, fmt.Errorf("invalid versionURL: %w", err)
 		}
 	}
 
-	return &generic{url: u, versionURL: lurl, client: http.DefaultClient}, nil
+	if opts.VersionCmd != "" {
+		if lurl != nil || opts.VersionProbe != "" {
+			return nil, fmt.Errorf("version command can't be used with a version URL or version probing")
+		}
+		if !opts.VersionCmdShell {
+			if _, err := SplitCommand(opts.VersionCmd); err != nil {
+				return nil, fmt.Errorf("invalid version command %q: %w", opts.VersionCmd, err)
+			}
+		}
+	}
+
+	g := &generic{url: u, versionURL: lurl, versionCmd: opts.VersionCmd, versionCmdShell: opts.VersionCmdShell, client: s.HTTPClient(), current: opts.Version}
+	if isGitHubAPI(lurl) {
+		g.gitHub = s.gitHub()
+	}
+
+	// fail before doing any request if a credential is missing
+	if g.headers, err = resolveHeaders(s, opts.Headers, opts.BasicAuth); err != nil {
+		return nil, err
+	}
+
+	if opts.VersionJSONPath != "" {
+		if lurl == nil && opts.VersionCmd == "" {
+			return nil, fmt.Errorf("version JSON path requires a version URL or command")
+		}
+		if g.versionPath, err = parseJSONPath(opts.VersionJSONPath); err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.VersionRegex != "" {
+		if lurl == nil && opts.VersionCmd == "" {
+			return nil, fmt.Errorf("version regex requires a version URL or command")
+		}
+		if g.versionRe, err = regexp.Compile(opts.VersionRegex); err != nil {
+			return nil, fmt.Errorf("invalid version regex %q: %w", opts.VersionRegex, err)
+		}
+		if g.versionRe.NumSubexp() < 1 {
+			return nil, fmt.Errorf("version regex %q must have a capture group for the version", opts.VersionRegex)
+		}
+	}
+
+	if opts.VersionProbe != "" {
+		if lurl != nil {
+			return nil, fmt.Errorf("version URL and version probing can't be used together")
+		}
+		if !strings.Contains(u, "{version}") {
+			return nil, fmt.Errorf("version probing requires a {version} placeholder in the URL")
+		}
+		if g.prober, err = newProber(opts.VersionProbe, g.client); err != nil {
+			return nil, err
+		}
+		g.prober.headers = g.headers
+	}
+
+	return g, nil
 }
@@ -0,0 +1,271 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenericVersionRegex(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><body><p>Latest: v2.3.1</p></body></html>")
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		regex   string
+		version string
+		err     string
+	}{
+		{"", "<html><body><p>Latest: v2.3.1</p></body></html>", ""},
+		{`Latest: (v[0-9.]+)`, "v2.3.1", ""},
+		{`Stable: (v[0-9.]+)`, "", `doesn't match the response of ` + srv.URL + `: "<html><body><p>Latest: v2.3.1</p></body></html>"`},
+	}
+
+	for _, c := range cases {
+		p, err := New("https://dl.example.com/tool-{version}.tar.gz", &Opts{VersionURL: srv.URL, VersionRegex: c.regex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, u, err := p.GetLatestVersion()
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected error containing %q, got %v", c.regex, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c.regex != "" && (v != c.version || u != "https://dl.example.com/tool-v2.3.1.tar.gz") {
+			t.Errorf("%s: unexpected version %s (%s)", c.regex, v, u)
+		}
+		if c.regex == "" && v != c.version {
+			t.Errorf("expected the whole body to be used, got %s", v)
+		}
+	}
+
+	for _, opts := range []*Opts{
+		{VersionRegex: `v([0-9.]+)`},
+		{VersionURL: srv.URL, VersionRegex: `v[0-9.]+`},
+		{VersionURL: srv.URL, VersionRegex: `v([0-9.]+`},
+	} {
+		if _, err := New("https://dl.example.com/tool-{version}.tar.gz", opts); err == nil {
+			t.Errorf("expected an error for %#v", opts)
+		}
+	}
+}
+
+func TestGenericVersionJSONPath(t *testing.T) {
+	body := `[
+		{"version": "go1.22.1", "stable": true, "files": [{"os": "linux", "sha256": ["abc"]}]},
+		{"version": "go1.21.8", "stable": true}
+	]`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/version" {
+			fmt.Fprint(w, `{"data": {"releases": [{"name": "cli v1.2.3"}], "count": 1}}`)
+			return
+		}
+		fmt.Fprint(w, body)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		versionURL, path, regex string
+		version                 string
+		err                     string
+	}{
+		{srv.URL, "[0].version", "", "go1.22.1", ""},
+		{srv.URL, "[1].version", "", "go1.21.8", ""},
+		{srv.URL, "[0].files[0].sha256[0]", "", "abc", ""},
+		{srv.URL + "/api/version", "data.releases[0].name", `v([0-9.]+)`, "1.2.3", ""},
+		{srv.URL, "[2].version", "", "", "[2] is out of range"},
+		{srv.URL, "[0].name", "", "", "[0].name not found"},
+		{srv.URL, "[0].stable", "", "", "[0].stable is not a string"},
+		{srv.URL, "version", "", "", "version can't be found, its parent is not an object"},
+		{srv.URL + "/api/version", "data.count", "", "", "data.count is not a string"},
+		{srv.URL + "/api/version", "data[0]", "", "", "data[0] is not an array"},
+	}
+
+	for _, c := range cases {
+		p, err := New("https://dl.example.com/{version}/tool.tar.gz", &Opts{VersionURL: c.versionURL, VersionJSONPath: c.path, VersionRegex: c.regex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, u, err := p.GetLatestVersion()
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected error containing %q, got %v", c.path, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.path, err)
+		}
+		if v != c.version || u != "https://dl.example.com/"+c.version+"/tool.tar.gz" {
+			t.Errorf("%s: unexpected version %s (%s)", c.path, v, u)
+		}
+	}
+
+	for _, path := range []string{"[a].version", "[0", "."} {
+		if _, err := New("https://dl.example.com/{version}/tool.tar.gz", &Opts{VersionURL: srv.URL, VersionJSONPath: path}); err == nil {
+			t.Errorf("expected an error for path %q", path)
+		}
+	}
+}
+
+func TestGenericVersionCmd(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the commands are unix ones")
+	}
+	defer func(d time.Duration) { versionCmdTimeout = d }(versionCmdTimeout)
+	versionCmdTimeout = 500 * time.Millisecond
+
+	cases := []struct {
+		cmd     string
+		shell   bool
+		regex   string
+		version string
+		err     string
+	}{
+		{"echo '  v1.4.2 '", false, "", "v1.4.2", ""},
+		// without a shell the pipe is an argument of echo
+		{"echo v1.4.2 | tr v V", false, "", "v1.4.2 | tr v V", ""},
+		{"echo v1.4.2 | tr v V", true, "", "V1.4.2", ""},
+		{"echo 'tool version 1.4.2'", false, `version ([0-9.]+)`, "1.4.2", ""},
+		{"echo 'access denied' >&2; exit 3", true, "", "", "exit status 3: access denied"},
+		{"true", false, "", "", "no version printed on stdout"},
+		{"sleep 5", false, "", "", "timed out"},
+		{"bin-missing-command", false, "", "", "executable file not found"},
+	}
+	for _, c := range cases {
+		p, err := New("https://dl.example.com/{version}/tool.tar.gz", &Opts{VersionCmd: c.cmd, VersionCmdShell: c.shell, VersionRegex: c.regex})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, u, err := p.GetLatestVersion()
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected error containing %q, got %v", c.cmd, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.cmd, err)
+		}
+		if v != c.version || u != "https://dl.example.com/"+c.version+"/tool.tar.gz" {
+			t.Errorf("%s: unexpected version %s (%s)", c.cmd, v, u)
+		}
+	}
+
+	for _, opts := range []*Opts{
+		{VersionCmd: "echo 'v1", VersionRegex: `v([0-9.]+)`},
+		{VersionCmd: "echo v1", VersionURL: "https://dl.example.com/latest"},
+		{VersionCmd: "echo v1", VersionProbe: "patch"},
+	} {
+		if _, err := New("https://dl.example.com/{version}/tool.tar.gz", opts); err == nil {
+			t.Errorf("expected an error for %#v", opts)
+		}
+	}
+}
+
+func TestGenericHeaders(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" || r.Header.Get("X-Api-Key") != "k3y" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Path == "/version" {
+			fmt.Fprint(w, "1.2.3")
+			return
+		}
+		fmt.Fprint(w, "#!/bin/sh\necho "+r.URL.Path)
+	}))
+	defer srv.Close()
+
+	s := NewSettings(true, map[string]string{"ARTIFACT_TOKEN": "s3cr3t", "ARTIFACT_KEY": "k3y"})
+	headers := map[string]string{"Authorization": "Bearer ${ARTIFACT_TOKEN}", "X-Api-Key": "${ARTIFACT_KEY}"}
+	p, err := New(srv.URL+"/{version}/tool", &Opts{VersionURL: srv.URL + "/version", Headers: headers, Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	f, err := p.Fetch(&FetchOpts{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != "1.2.3" {
+		t.Fatalf("expected 1.2.3, got %s", f.Version)
+	}
+
+	cases := []struct {
+		headers   map[string]string
+		basicAuth string
+		err       string
+	}{
+		{map[string]string{"Authorization": "Bearer s3cr3t"}, "", "must reference an environment variable"},
+		{map[string]string{"Authorization": "Bearer s3cr3t${ARTIFACT_TOKEN}"}, "", "must reference an environment variable"},
+		{map[string]string{"X-Api-Key": "k3y-$ARTIFACT_KEY"}, "", "must reference an environment variable"},
+		{nil, "user:s3cr3t", "must reference an environment variable"},
+		{nil, "user:${ARTIFACT_TOKEN}", "must reference an environment variable"},
+		{nil, "${ARTIFACT_KEY}:s3cr3t", "must reference an environment variable"},
+		{nil, "${ARTIFACT_KEY}", "must reference an environment variable"},
+		{map[string]string{"X-Api-Key": "${MISSING_KEY}"}, "", "references MISSING_KEY which is not set"},
+	}
+	for _, c := range cases {
+		_, err := New(srv.URL+"/{version}/tool", &Opts{VersionURL: srv.URL + "/version", Headers: c.headers, BasicAuth: c.basicAuth, Settings: s})
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("expected error containing %q, got %v", c.err, err)
+		}
+	}
+
+	resolved, err := resolveHeaders(NewSettings(true, map[string]string{"USER": "bin", "PASSWORD": "pass"}), nil, "${USER}:${PASSWORD}")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resolved["Authorization"] != "Basic YmluOnBhc3M=" {
+		t.Errorf("unexpected basic auth header %s", resolved["Authorization"])
+	}
+}
+
+func TestGenericValidators(t *testing.T) {
+	cases := []struct {
+		name    string
+		headers map[string]string
+		head    bool
+		version string
+	}{
+		{"etag", map[string]string{"ETag": `W/"5f3a-abc"`, "Last-Modified": "Tue, 02 Jan 2024 15:04:05 GMT"}, true, "etag-5f3a-abc"},
+		{"last modified", map[string]string{"Last-Modified": "Tue, 02 Jan 2024 15:04:05 GMT"}, true, "lm-20240102T150405Z"},
+		{"no HEAD support", map[string]string{"ETag": `"v2"`}, false, "etag-v2"},
+		{"no validators", nil, true, ""},
+	}
+
+	for _, c := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodHead && !c.head {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			for k, v := range c.headers {
+				w.Header().Set(k, v)
+			}
+		}))
+
+		p, err := New(srv.URL+"/tool/latest/tool-linux-amd64", &Opts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, u, err := p.GetLatestVersion()
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if v != c.version || u != srv.URL+"/tool/latest/tool-linux-amd64" {
+			t.Errorf("%s: expected version %q, got %q (%s)", c.name, c.version, v, u)
+		}
+		srv.Close()
+	}
+}
@@ -2,17 +2,20 @@ package providers
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"strings"
+	"sync"
+	"time"
 
-	"github.com/caarlos0/log"
 	"github.com/google/go-github/v31/github"
-	"golang.org/x/oauth2"
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/log"
 
 	"github.com/marcosnils/bin/pkg/assets"
+	bstrings "github.com/marcosnils/bin/pkg/strings"
 )
 
 type gitHub struct {
@@ -21,8 +24,14 @@ type gitHub struct {
 	owner  string
 	repo   string
 	tag    string
-	asset  string
-	token  string
+	asset  *assets.Selector
+	// token is resolved on every request so a
+	// refreshed one is picked up
+	token func() string
+	tags  *TagFilter
+	http  *http.Client
+
+	assetHintPolicy string
 }
 
 func (g *gitHub) Fetch(opts *FetchOpts) (*File, error) {
@@ -36,12 +45,12 @@ func (g *gitHub) Fetch(opts *FetchOpts) (*File, error) {
 			// this is used by for the `ensure` command
 			g.tag = opts.Version
 		}
-		log.Infof("Getting %s release for %s/%s", g.tag, g.owner, g.repo)
-		release, _, err = g.client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repo, g.tag)
+		log.WithField("provider", g.GetID()).WithField("repo", g.owner+"/"+g.repo).WithField("version", g.tag).Info("Getting the release")
+		release, err = g.releaseByTag(g.tag, opts.Releases)
 	} else {
-		log.Infof("Getting latest release for %s/%s", g.owner, g.repo)
-		release, resp, err = g.client.Repositories.GetLatestRelease(context.TODO(), g.owner, g.repo)
-		if resp.StatusCode == http.StatusNotFound {
+		log.WithField("provider", g.GetID()).WithField("repo", g.owner+"/"+g.repo).Info("Getting the latest release")
+		release, resp, err = g.latestRelease()
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
 			err = fmt.Errorf("repository %s/%s does not have releases", g.owner, g.repo)
 		}
 	}
@@ -50,17 +59,39 @@ func (g *gitHub) Fetch(opts *FetchOpts) (*File, error) {
 		return nil, err
 	}
 
-	candidates := getCandidates(release.Assets, g.asset)
-	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName})
+	// immutable releases are checked against their attestation
+	// before anything is downloaded
+	attested, err := g.releaseAttestation(release)
+	if err != nil {
+		return nil, err
+	}
+
+	candidates, hintBypassed, err := getCandidates(release.Assets, g.asset, g.assetHintPolicy, opts.Relax)
+	if err != nil && opts.BuildFromSource && errors.Is(err, assets.ErrNoMatch) {
+		return g.buildFromSource(release, opts.MainPackage)
+	}
+	if err != nil {
+		return nil, err
+	}
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, HTTPClient: g.downloadClient(), ReleaseURL: release.GetHTMLURL(), Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, release.GetTagName()), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax, Rewrites: rewritesFor(opts.AssetRewrites, release.GetTagName()), RequirePlatform: opts.BuildFromSource})
 
 	gf, err := f.FilterAssets(g.repo, candidates)
+	if err != nil && opts.BuildFromSource && errors.Is(err, assets.ErrNoMatch) {
+		return g.buildFromSource(release, opts.MainPackage)
+	}
 	if err != nil {
 		return nil, err
 	}
 
+	gf.AttestedDigest = attested[gf.Name]
 	gf.ExtraHeaders = map[string]string{"Accept": "application/octet-stream"}
-	if g.token != "" {
-		gf.ExtraHeaders["Authorization"] = fmt.Sprintf("token %s", g.token)
+	if token := g.token(); token != "" {
+		gf.ExtraHeaders["Authorization"] = fmt.Sprintf("token %s", token)
+	}
+	for _, a := range release.Assets {
+		if a.GetURL() == gf.URL {
+			gf.FallbackURL = a.GetBrowserDownloadURL()
+		}
 	}
 
 	outFile, err := f.ProcessURL(gf)
@@ -70,40 +101,226 @@ func (g *gitHub) Fetch(opts *FetchOpts) (*File, error) {
 
 	version := release.GetTagName()
 
-	// TODO calculate file hash. Not sure if we can / should do it here
-	// since we don't want to read the file unnecesarily. Additionally, sometimes
-	// releases have .sha256 files, so it'd be nice to check for those also
-	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath}
+	// The asset digest allows to detect assets re-published under the
+	// same tag
+	var digest string
+	for _, a := range release.Assets {
+		if a.GetURL() == gf.URL {
+			digest = assetDigest(a)
+		}
+	}
+	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath, AssetDigest: digest, AssetHintBypassed: hintBypassed, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}
+	if f.Picked() {
+		file.SelectedAsset = assets.SelectionPattern(gf.Name, version)
+	}
 
 	return file, nil
 }
 
+// ReleaseCache keeps the releases fetched by tag so the binaries
+// installed from the same release (i.e. several tools of one
+// release) only request it once during batch operations. It's
+// safe for concurrent use.
+type ReleaseCache struct {
+	mu       sync.Mutex
+	releases map[string]*github.RepositoryRelease
+}
+
+// NewReleaseCache returns an empty cache
+func NewReleaseCache() *ReleaseCache {
+	return &ReleaseCache{releases: map[string]*github.RepositoryRelease{}}
+}
+
+func (c *ReleaseCache) get(key string) *github.RepositoryRelease {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.releases[key]
+}
+
+func (c *ReleaseCache) put(key string, r *github.RepositoryRelease) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.releases[key] = r
+}
+
+// releaseByTag gets the release of the tag, from the cache
+// when another binary of the release already fetched it
+func (g *gitHub) releaseByTag(tag string, cache *ReleaseCache) (*github.RepositoryRelease, error) {
+	key := g.client.BaseURL.String() + g.owner + "/" + g.repo + "@" + tag
+	if release := cache.get(key); release != nil {
+		log.Debugf("Using the release %s of %s/%s already fetched", tag, g.owner, g.repo)
+		return release, nil
+	}
+	release, _, err := g.client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repo, tag)
+	if err != nil {
+		return nil, err
+	}
+	cache.put(key, release)
+	return release, nil
+}
+
+// downloadClient follows the redirects of the asset downloads
+// without the token nor the API Accept header once they leave the
+// GitHub hosts, i.e. for the assets stored on a CDN or another host
+func (g *gitHub) downloadClient() *http.Client {
+	c := http.Client{}
+	if g.http != nil {
+		c = *g.http
+	}
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		if !g.gitHubHost(req.URL.Hostname()) {
+			log.Debugf("Following the redirect to %s without the GitHub headers", req.URL.Host)
+			req.Header.Del("Authorization")
+			req.Header.Del("Accept")
+		}
+		return nil
+	}
+	return &c
+}
+
+// gitHubHost tells whether host serves the repository or its API
+func (g *gitHub) gitHubHost(host string) bool {
+	return (g.url != nil && host == g.url.Hostname()) || (g.client.BaseURL != nil && host == g.client.BaseURL.Hostname())
+}
+
 // getCandidates returns a list of assets to be used as candidates for filtering
-// If userAsset is provided, it will try to find that asset and return it as the only candidate
-func getCandidates(githubAssets []*github.ReleaseAsset, userAsset string) []*assets.Asset {
+// If userAsset is provided, it will only return the assets matching it.
+// Otherwise the hint policy decides whether to fail or to consider every asset, in which
+// case the returned bool reports that the hint was bypassed. Relaxed
+// selections try the hint without its libc before.
+func getCandidates(githubAssets []*github.ReleaseAsset, userAsset *assets.Selector, hintPolicy string, relax assets.Relaxation) ([]*assets.Asset, bool, error) {
 	candidates := []*assets.Asset{}
-	foundUserAsset := false
+	matches := []*assets.Asset{}
 	for _, a := range githubAssets {
-		if userAsset != "" && a.GetName() == userAsset {
-			foundUserAsset = true
-			candidates = []*assets.Asset{&assets.Asset{Name: a.GetName(), URL: a.GetURL()}}
+		asset := &assets.Asset{Name: a.GetName(), URL: a.GetURL()}
+		if userAsset != nil && userAsset.Match(a.GetName()) {
+			matches = append(matches, asset)
+		}
+		candidates = append(candidates, asset)
+	}
+
+	if len(matches) > 0 {
+		return matches, false, nil
+	}
+	if relaxed := userAsset.WithoutLibc(); relaxed != nil && relax >= assets.RelaxLibc {
+		for _, c := range candidates {
+			if relaxed.Match(c.Name) {
+				matches = append(matches, c)
+			}
+		}
+		if len(matches) > 0 {
+			log.WithField("asset", userAsset.String()).WithField("relaxed", relaxed.String()).Warn("Asset not found in release, using the assets matching without the libc")
+			return matches, false, nil
+		}
+	}
+
+	if userAsset != nil {
+		names := make([]string, 0, len(candidates))
+		for _, c := range candidates {
+			names = append(names, c.Name)
+		}
+		msg := fmt.Sprintf("asset %s not found in release, available assets are: %s", userAsset, strings.Join(names, ", "))
+		l := log.WithField("asset", userAsset.String()).WithField("available", names)
+		if closest := bstrings.Closest(userAsset.String(), names); closest != "" {
+			msg = fmt.Sprintf("%s (closest match: %s)", msg, closest)
+			l = l.WithField("closest", closest)
+		}
+
+		hintPolicy, _ = ResolveAssetHintPolicy(hintPolicy)
+		switch hintPolicy {
+		case AssetHintFail:
+			return nil, false, assets.NoMatch(msg)
+		case AssetHintFallback:
+			l.Warn("Asset not found in release, falling back to all assets")
+			return candidates, true, nil
+		default:
+			l.Warn("Asset not found in release")
+		}
+	}
+
+	return candidates, false, nil
+}
+
+// latestRelease returns the latest release of the repository.
+// When a tag filter is configured, GitHub's latest release can't
+// be trusted since it might belong to a different component, so
+// releases are listed and the highest matching one is picked. The
+// same goes when it's a draft, which tokens with the repo scope
+// can get and whose assets can't be downloaded.
+func (g *gitHub) latestRelease() (*github.RepositoryRelease, *github.Response, error) {
+	if g.tags == nil {
+		release, resp, err := g.client.Repositories.GetLatestRelease(context.TODO(), g.owner, g.repo)
+		if err != nil || !release.GetDraft() {
+			return release, resp, err
+		}
+		log.Debugf("The latest release of %s/%s is the draft %s, looking for the latest published one", g.owner, g.repo, release.GetTagName())
+	}
+
+	var (
+		latest        *github.RepositoryRelease
+		latestVersion *version.Version
+		resp          *github.Response
+	)
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		var releases []*github.RepositoryRelease
+		var err error
+		releases, resp, err = g.client.Repositories.ListReleases(context.TODO(), g.owner, g.repo, opts)
+		if err != nil {
+			return nil, resp, err
+		}
+
+		for _, r := range releases {
+			if r.GetDraft() || r.GetPrerelease() || !g.tags.Match(r.GetTagName()) {
+				continue
+			}
+
+			v, err := version.NewVersion(g.tags.Version(r.GetTagName()))
+			if err != nil {
+				log.Debugf("Unable to parse version of tag %s: %v", r.GetTagName(), err)
+				// releases are listed newest first, so keep the first
+				// non semver one only if nothing else was found
+				if latest == nil {
+					latest = r
+				}
+				continue
+			}
+
+			if latestVersion == nil || v.GreaterThan(latestVersion) {
+				latest, latestVersion = r, v
+			}
+		}
+
+		if resp.NextPage == 0 {
 			break
 		}
-		candidates = append(candidates, &assets.Asset{Name: a.GetName(), URL: a.GetURL()})
+		opts.Page = resp.NextPage
 	}
 
-	if userAsset != "" && !foundUserAsset {
-		log.Warnf("asset %s not found in release", userAsset)
+	if latest == nil && g.tags == nil {
+		return nil, resp, fmt.Errorf("no published release found in %s/%s", g.owner, g.repo)
+	}
+	if latest == nil {
+		return nil, resp, fmt.Errorf("no release found in %s/%s matching the configured tag filter", g.owner, g.repo)
 	}
 
-	return candidates
+	return latest, resp, nil
 }
 
 // GetLatestVersion checks the latest repo release and
 // returns the corresponding name and url to fetch the version
 func (g *gitHub) GetLatestVersion() (string, string, error) {
 	log.Debugf("Getting latest release for %s/%s", g.owner, g.repo)
-	release, _, err := g.client.Repositories.GetLatestRelease(context.TODO(), g.owner, g.repo)
+	release, _, err := g.latestRelease()
 	if err != nil {
 		return "", "", err
 	}
@@ -111,70 +328,186 @@ func (g *gitHub) GetLatestVersion() (string, string, error) {
 	return release.GetTagName(), release.GetHTMLURL(), nil
 }
 
-func (g *gitHub) GetID() string {
-	return "github"
-}
+// ListVersions lists the published releases of the repository
+// which match the tag filter
+func (g *gitHub) ListVersions(limit int) ([]*Release, error) {
+	log.Debugf("Listing releases of %s/%s", g.owner, g.repo)
+	versions := []*Release{}
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		releases, resp, err := g.client.Repositories.ListReleases(context.TODO(), g.owner, g.repo, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range releases {
+			if r.GetDraft() || !g.tags.Match(r.GetTagName()) {
+				continue
+			}
+			versions = append(versions, &Release{
+				Version:     r.GetTagName(),
+				URL:         r.GetHTMLURL(),
+				PublishedAt: r.GetPublishedAt().Time,
+				Prerelease:  r.GetPrerelease(),
+			})
+			if limit > 0 && len(versions) == limit {
+				return versions, nil
+			}
+		}
 
-func newGitHub(u *url.URL) (Provider, error) {
-	// Supported Github URL formats:
-	// - https://github.com/owner/repo
-	// - https://github.com/owner/repo/releases/tag/v1.2.3
-	// - https://github.com/owner/repo/releases/download/v1.2.3
-	// - https://github.com/owner/repo/releases/download/v1.2.3/asset-name
-	splitedPath := strings.Split(u.Path, "/")
-	if len(splitedPath) < 3 {
-		return nil, fmt.Errorf("error parsing Github URL %s, can't find owner and repo", u.String())
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
 	}
 
-	// Github repository owner and name are always the 2nd and 3rd path elements
-	owner := splitedPath[1]
-	repo := splitedPath[2]
+	return versions, nil
+}
+
+func githubNotes(r *github.RepositoryRelease) *ReleaseNotes {
+	return &ReleaseNotes{Version: r.GetTagName(), Body: r.GetBody(), URL: r.GetHTMLURL()}
+}
 
-	// If the URL is a release or download URL, try to get the tag and asset name
-	// otherwise, latest release will be used
-	var tag string
-	var asset string
-	if len(splitedPath) > 5 && splitedPath[3] == "releases" {
-		// In release and download URL's, the tag is the 6th element
-		tag = splitedPath[5]
-		if len(splitedPath) > 6 && splitedPath[4] == "download" {
-			// In download URL's, the asset name is the 7th element
-			asset = splitedPath[6]
+// ReleaseNotes implements the ReleaseNoter interface
+func (g *gitHub) ReleaseNotes(from, to string) ([]*ReleaseNotes, error) {
+	log.Debugf("Getting release notes of %s/%s from %s to %s", g.owner, g.repo, from, to)
+	w := &notesWalker{from: from, to: to}
+	opts := &github.ListOptions{PerPage: 100}
+	for from != "" {
+		releases, resp, err := g.client.Repositories.ListReleases(context.TODO(), g.owner, g.repo, opts)
+		if err != nil {
+			return nil, err
 		}
+		done := false
+		for _, r := range releases {
+			if r.GetDraft() || !g.tags.Match(r.GetTagName()) {
+				continue
+			}
+			if done = !w.add(githubNotes(r), r.GetPrerelease()); done {
+				break
+			}
+		}
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(w.notes) > 0 {
+		return w.notes, nil
 	}
 
-	token := os.Getenv("GITHUB_AUTH_TOKEN")
-	if len(token) == 0 {
-		token = os.Getenv("GITHUB_TOKEN")
+	release, _, err := g.client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repo, to)
+	if err != nil {
+		return nil, err
 	}
+	return []*ReleaseNotes{githubNotes(release)}, nil
+}
 
-	// GHES client
-	gbu := os.Getenv("GHES_BASE_URL")
-	guu := os.Getenv("GHES_UPLOAD_URL")
-	gau := os.Getenv("GHES_AUTH_TOKEN")
+// assetDigest identifies the content of a release asset. Re-uploaded
+// assets either get a new ID or an updated timestamp
+func assetDigest(a *github.ReleaseAsset) string {
+	return fmt.Sprintf("%d@%s", a.GetID(), a.GetUpdatedAt().UTC().Format(time.RFC3339))
+}
 
-	var tc *http.Client
+// AssetPublished returns when the asset of a digest of a GitHub
+// asset was last published, it's what tells the builds of rolling
+// tags (i.e. nightly) apart
+func AssetPublished(digest string) (time.Time, bool) {
+	_, at, ok := strings.Cut(digest, "@")
+	if !ok {
+		return time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, at)
+	return t, err == nil
+}
 
-	if len(gbu) > 0 && len(guu) > 0 && len(gau) > 0 {
-		tc = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: gau},
-		))
-	} else if token != "" {
-		tc = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(
-			&oauth2.Token{AccessToken: token},
-		))
+// Describe returns the repository of the releases and
+// the tag of the URL, empty when it follows the latest one
+func (g *gitHub) Describe() map[string]string {
+	return map[string]string{"owner": g.owner, "repo": g.repo, "tag": g.tag}
+}
+
+// GetAssetDigests returns the digests of the assets
+// published in the release of the given tag
+func (g *gitHub) GetAssetDigests(tag string) ([]string, error) {
+	log.Debugf("Getting %s release assets for %s/%s", tag, g.owner, g.repo)
+	release, _, err := g.client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repo, tag)
+	if err != nil {
+		return nil, err
 	}
 
-	var client *github.Client
-	var err error
+	digests := []string{}
+	for _, a := range release.Assets {
+		digests = append(digests, assetDigest(a))
+	}
+	return digests, nil
+}
 
-	if len(gbu) > 0 && len(guu) > 0 && len(gau) > 0 {
-		if client, err = github.NewEnterpriseClient(gbu, guu, tc); err != nil {
-			return nil, fmt.Errorf("error initializing GHES client %v", err)
-		}
+// ListAssets returns the names of the assets published in the
+// release of the given tag, the one of the URL or the latest one
+// when it's empty
+func (g *gitHub) ListAssets(tag string) ([]string, error) {
+	var release *github.RepositoryRelease
+	var err error
+	if tag == "" {
+		tag = g.tag
+	}
+	if tag == "" {
+		release, _, err = g.latestRelease()
 	} else {
-		client = github.NewClient(tc)
+		release, _, err = g.client.Repositories.GetReleaseByTag(context.TODO(), g.owner, g.repo, tag)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, a := range release.Assets {
+		names = append(names, a.GetName())
+	}
+	return names, nil
+}
+
+func (g *gitHub) GetID() string {
+	return "github"
+}
+
+func (g *gitHub) Capabilities() Capabilities {
+	return NewCapabilities(CapListVersions, CapChecksums, CapReleaseNotes, CapAssetSizes, CapPrereleases)
+}
+
+// GetSource implements the Sourcer interface
+func (g *gitHub) GetSource() string {
+	return fmt.Sprintf("%s/%s/%s", g.url.Host, g.owner, g.repo)
+}
+
+func newGitHub(u *url.URL, tags *TagFilter, userAsset, assetHintPolicy string, s *Settings) (Provider, error) {
+	ref, err := parseGitHubURL(u, s.gitHubPathPrefix(u.Hostname()))
+	if err != nil {
+		return nil, err
+	}
+	asset := ref.asset
+
+	// An explicitly configured asset takes precedence
+	// over the one from the download URL
+	if userAsset != "" {
+		asset = userAsset
+	}
+	selector, err := assets.NewSelector(asset)
+	if err != nil {
+		return nil, err
+	}
+
+	// github.com, the configured GitHub Enterprise Server
+	// and the detected ones each get their own client
+	forge := s.detectForge(u)
+	client, err := s.gitHubAPIClient(u, forge)
+	if err != nil {
+		return nil, err
 	}
+	tokenKeys := s.gitHubTokenKeys(forge, u.Hostname())
+	token := func() string { return s.get(tokenKeys...) }
+	hc := s.HTTPClient()
 
-	return &gitHub{url: u, client: client, owner: owner, repo: repo, tag: tag, asset: asset, token: token}, nil
+	return &gitHub{url: u, client: client, owner: ref.owner, repo: ref.repo, tag: ref.tag, asset: selector, token: token, tags: tags, http: hc, assetHintPolicy: assetHintPolicy}, nil
 }
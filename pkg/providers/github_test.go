@@ -0,0 +1,345 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+// newTestGitHub returns a github provider whose client
+// talks to the given handler
+func newTestGitHub(t *testing.T, h http.Handler, tags *TagFilter) *gitHub {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+
+	client := github.NewClient(nil)
+	u, _ := url.Parse(srv.URL + "/")
+	client.BaseURL = u
+
+	return &gitHub{client: client, owner: "grpc-ecosystem", repo: "grpc-gateway", tags: tags}
+}
+
+func TestGitHubLatestReleaseWithTagFilter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"tag_name":"protoc-gen-openapiv2/v2.0.0"},{"tag_name":"protoc-gen-openapiv2/v2.10.0"}]`)
+			return
+		}
+		w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+		fmt.Fprint(w, `[
+			{"tag_name":"v2.20.0"},
+			{"tag_name":"protoc-gen-openapiv2/v2.11.0-rc.1","prerelease":true},
+			{"tag_name":"protoc-gen-openapiv2/v2.9.0"},
+			{"tag_name":"protoc-gen-openapiv2/v2.12.0","draft":true}
+		]`)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v2.20.0"}`)
+	})
+
+	tags, _ := NewTagFilter("protoc-gen-openapiv2/", "")
+	g := newTestGitHub(t, mux, tags)
+	v, _, err := g.GetLatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "protoc-gen-openapiv2/v2.10.0" {
+		t.Fatalf("expected protoc-gen-openapiv2/v2.10.0, got %s", v)
+	}
+
+	g = newTestGitHub(t, mux, nil)
+	v, _, err = g.GetLatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "v2.20.0" {
+		t.Fatalf("expected v2.20.0, got %s", v)
+	}
+
+	tags, _ = NewTagFilter("unknown/", "")
+	g = newTestGitHub(t, mux, tags)
+	if _, _, err := g.GetLatestVersion(); err == nil {
+		t.Fatal("expected an error when no release matches the tag filter")
+	}
+}
+
+func TestGitHubListVersions(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("page") == "2" {
+			fmt.Fprint(w, `[{"tag_name":"protoc-gen-openapiv2/v2.9.0"}]`)
+			return
+		}
+		w.Header().Set("Link", `<`+"http://"+r.Host+r.URL.Path+`?page=2>; rel="next"`)
+		fmt.Fprint(w, `[
+			{"tag_name":"protoc-gen-openapiv2/v2.12.0","draft":true},
+			{"tag_name":"v2.20.0"},
+			{"tag_name":"protoc-gen-openapiv2/v2.11.0-rc.1","prerelease":true,"published_at":"2024-03-01T10:00:00Z"},
+			{"tag_name":"protoc-gen-openapiv2/v2.10.0"}
+		]`)
+	})
+
+	tags, _ := NewTagFilter("protoc-gen-openapiv2/", "")
+	g := newTestGitHub(t, mux, tags)
+
+	cases := []struct {
+		limit    int
+		versions []string
+	}{
+		{0, []string{"protoc-gen-openapiv2/v2.11.0-rc.1", "protoc-gen-openapiv2/v2.10.0", "protoc-gen-openapiv2/v2.9.0"}},
+		{2, []string{"protoc-gen-openapiv2/v2.11.0-rc.1", "protoc-gen-openapiv2/v2.10.0"}},
+	}
+	for _, c := range cases {
+		releases, err := g.ListVersions(c.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions := []string{}
+		for _, r := range releases {
+			versions = append(versions, r.Version)
+		}
+		if strings.Join(versions, ",") != strings.Join(c.versions, ",") {
+			t.Errorf("limit %d: expected %v, got %v", c.limit, c.versions, versions)
+		}
+		if !releases[0].Prerelease || releases[0].PublishedAt.Format("2006-01-02") != "2024-03-01" {
+			t.Errorf("unexpected release details %#v", releases[0])
+		}
+	}
+}
+
+func TestNewGitHubSlashedTags(t *testing.T) {
+	cases := []struct {
+		in         string
+		tag, asset string
+	}{
+		{"https://github.com/owner/repo/releases/tag/v1.2.3", "v1.2.3", ""},
+		{"https://github.com/owner/repo/releases/tag/cli/v1.2.3", "cli/v1.2.3", ""},
+		{"https://github.com/owner/repo/releases/download/v1.2.3/tool_linux", "v1.2.3", "tool_linux"},
+		{"https://github.com/owner/repo/releases/download/cli/v1.2.3/tool_linux", "cli/v1.2.3", "tool_linux"},
+	}
+
+	for _, c := range cases {
+		u, _ := url.Parse(c.in)
+		p, err := newGitHub(u, nil, "", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		g := p.(*gitHub)
+		if g.tag != c.tag || g.asset.String() != c.asset {
+			t.Errorf("%s: expected tag %q and asset %q, got %q and %q", c.in, c.tag, c.asset, g.tag, g.asset)
+		}
+	}
+}
+
+func TestGitHubGetAssetDigests(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/tags/nightly", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"nightly","assets":[
+			{"id":1,"name":"tool-linux","updated_at":"2024-01-02T10:00:00Z"},
+			{"id":2,"name":"tool-darwin","updated_at":"2024-01-02T11:00:00+01:00"}
+		]}`)
+	})
+
+	g := newTestGitHub(t, mux, nil)
+	digests, err := g.GetAssetDigests("nightly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"1@2024-01-02T10:00:00Z", "2@2024-01-02T10:00:00Z"}
+	if fmt.Sprint(digests) != fmt.Sprint(expected) {
+		t.Fatalf("expected %v, got %v", expected, digests)
+	}
+}
+
+func TestGitHubReleaseCache(t *testing.T) {
+	requests := 0
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/tags/v2.20.0", func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		fmt.Fprint(w, `{"tag_name":"v2.20.0"}`)
+	})
+	g := newTestGitHub(t, mux, nil)
+
+	cache := NewReleaseCache()
+	for i := 0; i < 3; i++ {
+		r, err := g.releaseByTag("v2.20.0", cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.GetTagName() != "v2.20.0" {
+			t.Fatalf("expected v2.20.0, got %s", r.GetTagName())
+		}
+	}
+	if requests != 1 {
+		t.Fatalf("expected the release to be requested once, got %d requests", requests)
+	}
+
+	if _, err := g.releaseByTag("v2.20.0", nil); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 2 {
+		t.Fatalf("expected the release to be requested without a cache, got %d requests", requests)
+	}
+}
+
+func TestGetCandidatesHintPolicy(t *testing.T) {
+	releaseAssets := []*github.ReleaseAsset{
+		{Name: github.String("tool_1.2.0_linux_amd64.tar.gz"), URL: github.String("https://api.github.com/assets/1")},
+		{Name: github.String("tool_1.2.0_darwin_amd64.tar.gz"), URL: github.String("https://api.github.com/assets/2")},
+	}
+
+	cases := []struct {
+		name       string
+		hint       string
+		policy     string
+		candidates int
+		bypassed   bool
+		relax      assets.Relaxation
+		err        string
+	}{
+		{name: "hint found", hint: "tool_1.2.0_darwin_amd64.tar.gz", policy: AssetHintFail, candidates: 1},
+		{name: "no hint", candidates: 2},
+		{name: "glob", hint: "tool_*_linux_amd64.tar.gz", policy: AssetHintFail, candidates: 1},
+		{name: "glob matching several assets", hint: "tool_*_amd64.tar.gz", policy: AssetHintFail, candidates: 2},
+		{name: "regex", hint: `/^tool_[\d.]+_darwin/`, policy: AssetHintFail, candidates: 1},
+		{name: "unmatched glob", hint: "tool_*_windows_amd64.zip", policy: AssetHintFail, err: "available assets are: tool_1.2.0_linux_amd64.tar.gz, tool_1.2.0_darwin_amd64.tar.gz"},
+		{name: "warn", hint: "tool_1.1.0_linux_amd64.tar.gz", policy: AssetHintWarn, candidates: 2},
+		{name: "fallback", hint: "tool_1.1.0_linux_amd64.tar.gz", policy: AssetHintFallback, candidates: 2, bypassed: true},
+		{name: "fail", hint: "tool_1.1.0_linux_amd64.tar.gz", policy: AssetHintFail, err: "closest match: tool_1.2.0_linux_amd64.tar.gz"},
+		{name: "libc", hint: "tool_*_linux-gnu_amd64.tar.gz", policy: AssetHintFail, err: "not found in release"},
+		{name: "relaxed libc", hint: "tool_*_linux-gnu_amd64.tar.gz", policy: AssetHintFail, relax: assets.RelaxLibc, candidates: 1},
+		// tests don't run in a terminal, so the default policy fails
+		{name: "non-interactive default", hint: "tool_1.1.0_linux_amd64.tar.gz", err: "available assets are: tool_1.2.0_linux_amd64.tar.gz, tool_1.2.0_darwin_amd64.tar.gz"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			hint, err := assets.NewSelector(c.hint)
+			if err != nil {
+				t.Fatal(err)
+			}
+			candidates, bypassed, err := getCandidates(releaseAssets, hint, c.policy, c.relax)
+			if c.err != "" {
+				if err == nil || !strings.Contains(err.Error(), c.err) {
+					t.Fatalf("expected error containing %q, got %v", c.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(candidates) != c.candidates || bypassed != c.bypassed {
+				t.Fatalf("expected %d candidates (bypassed %v), got %d (bypassed %v)", c.candidates, c.bypassed, len(candidates), bypassed)
+			}
+		})
+	}
+}
+
+func TestGitHubReleaseNotes(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[
+			{"tag_name":"v1.4.0-rc.1","prerelease":true,"body":"rc"},
+			{"tag_name":"v1.3.0","body":"three","html_url":"https://github.com/o/r/releases/v1.3.0"},
+			{"tag_name":"v1.2.1-rc.1","prerelease":true,"body":"rc"},
+			{"tag_name":"v1.2.0","body":"two"},
+			{"tag_name":"v1.1.5","draft":true,"body":"draft"},
+			{"tag_name":"v1.1.0","body":"one"},
+			{"tag_name":"v1.0.0","body":"zero"}
+		]`)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/tags/", func(w http.ResponseWriter, r *http.Request) {
+		tag := strings.TrimPrefix(r.URL.Path, "/repos/grpc-ecosystem/grpc-gateway/releases/tags/")
+		fmt.Fprintf(w, `{"tag_name":%q,"body":"notes of %s"}`, tag, tag)
+	})
+	g := newTestGitHub(t, mux, nil)
+
+	cases := []struct {
+		from, to string
+		want     []string
+	}{
+		// the intermediate prereleases and drafts are left out
+		{"v1.1.0", "v1.3.0", []string{"v1.3.0: three", "v1.2.0: two"}},
+		{"", "v1.3.0", []string{"v1.3.0: notes of v1.3.0"}},
+		// a downgrade only gets the notes of the target
+		{"v1.3.0", "v1.1.0", []string{"v1.1.0: notes of v1.1.0"}},
+	}
+	for _, c := range cases {
+		notes, err := g.ReleaseNotes(c.from, c.to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, n := range notes {
+			got = append(got, n.Version+": "+n.Body)
+		}
+		if strings.Join(got, ",") != strings.Join(c.want, ",") {
+			t.Errorf("notes from %q to %s: expected %v, got %v", c.from, c.to, c.want, got)
+		}
+	}
+}
+
+func TestGitHubLatestReleaseSkipsDrafts(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"tag_name":"v2.21.0","draft":true}`)
+	})
+	mux.HandleFunc("/repos/grpc-ecosystem/grpc-gateway/releases", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `[{"tag_name":"v2.21.0","draft":true},{"tag_name":"v2.20.0"},{"tag_name":"v2.19.0"}]`)
+	})
+
+	g := newTestGitHub(t, mux, nil)
+	v, _, err := g.GetLatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "v2.20.0" {
+		t.Fatalf("expected the latest published release v2.20.0, got %s", v)
+	}
+}
+
+func TestGitHubDownloadRedirects(t *testing.T) {
+	external := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "" || r.Header.Get("Accept") == "application/octet-stream" {
+			t.Errorf("expected the external host not to get the GitHub headers, got %v", r.Header)
+		}
+		fmt.Fprint(w, "content")
+	}))
+	defer external.Close()
+	// another host name than the API one for the same address
+	externalURL := strings.Replace(external.URL, "127.0.0.1", "localhost", 1)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/assets/1", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/assets/1/storage", http.StatusFound)
+	})
+	mux.HandleFunc("/assets/1/storage", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "token secret" || r.Header.Get("Accept") != "application/octet-stream" {
+			t.Errorf("expected the redirects within GitHub to keep the headers, got %v", r.Header)
+		}
+		http.Redirect(w, r, externalURL+"/tool_linux_amd64", http.StatusFound)
+	})
+	g := newTestGitHub(t, mux, nil)
+	g.url, g.http = &url.URL{Host: "github.com"}, http.DefaultClient
+
+	req, _ := http.NewRequest(http.MethodGet, g.client.BaseURL.String()+"assets/1", nil)
+	req.Header.Set("Authorization", "token secret")
+	req.Header.Set("Accept", "application/octet-stream")
+	res, err := g.downloadClient().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || res.Request.URL.Hostname() != "localhost" {
+		t.Fatalf("expected the asset to be downloaded from the external host, got %d from %s", res.StatusCode, res.Request.URL)
+	}
+}
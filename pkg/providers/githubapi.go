@@ -0,0 +1,174 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+const (
+	// maxRateLimitWait bounds how long a rate-limited
+	// request waits before being retried
+	maxRateLimitWait = time.Minute
+	// maxRateLimitRetries bounds the retries of a rate-limited request
+	maxRateLimitRetries = 2
+)
+
+// gitHubAPIHost is the host of the GitHub API, requests to it
+// from any provider go through the shared GitHub client
+var gitHubAPIHost = "api.github.com"
+
+// isGitHubAPI checks if the URL is an endpoint of the GitHub API
+func isGitHubAPI(u *url.URL) bool {
+	return u != nil && u.Host == gitHubAPIHost
+}
+
+// gitHubTransport authenticates the requests to the GitHub API,
+// revalidates the cached responses with their ETag so they don't
+// count against the rate limit and waits for it to be reset when
+// it's exceeded
+type gitHubTransport struct {
+	next  http.RoundTripper
+	token func() string
+	etags *etagCache
+	sleep func(time.Duration)
+}
+
+func (t *gitHubTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if tok := t.token(); tok != "" && req.Header.Get("Authorization") == "" {
+		req.Header.Set("Authorization", "Bearer "+tok)
+	}
+
+	key := req.URL.String()
+	cacheable := req.Method == http.MethodGet && req.Header.Get("Range") == ""
+	var cached *http.Response
+	if cacheable {
+		if cached = t.etags.get(key, req); cached != nil {
+			req.Header.Set("If-None-Match", cached.Header.Get("ETag"))
+		}
+	}
+
+	res, err := t.roundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case res.StatusCode == http.StatusNotModified && cached != nil:
+		log.Debugf("%s not modified, using the cached response", key)
+		res.Body.Close()
+		return cached, nil
+	case res.StatusCode == http.StatusOK && cacheable && res.Header.Get("ETag") != "":
+		return t.etags.put(key, res)
+	}
+	return res, nil
+}
+
+// roundTrip retries the request when the rate limit is exceeded and
+// it's reset soon enough
+func (t *gitHubTransport) roundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		res, err := t.next.RoundTrip(req)
+		if err != nil || attempt == maxRateLimitRetries {
+			return res, err
+		}
+		wait, limited := rateLimitWait(res, time.Now())
+		if !limited || wait > maxRateLimitWait {
+			return res, nil
+		}
+		res.Body.Close()
+		log.WithField("retry_in", wait.Round(time.Second).String()).Warn("GitHub API rate limit exceeded")
+		t.sleep(wait)
+	}
+}
+
+// rateLimitWait returns how long to wait before retrying a
+// rate-limited response
+func rateLimitWait(res *http.Response, now time.Time) (time.Duration, bool) {
+	if res.StatusCode != http.StatusForbidden && res.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if s := res.Header.Get("Retry-After"); s != "" {
+		if secs, err := strconv.Atoi(s); err == nil {
+			return time.Duration(secs) * time.Second, true
+		}
+	}
+	if res.Header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(res.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return max(time.Unix(reset, 0).Sub(now), 0), true
+		}
+	}
+	return 0, false
+}
+
+// etagCache keeps the responses with an ETag in memory and, if
+// dir is set, on disk so they can be revalidated in later runs
+type etagCache struct {
+	dir string
+
+	mu      sync.Mutex
+	entries map[string][]byte
+}
+
+func newETagCache(dir string) *etagCache {
+	return &etagCache{dir: dir, entries: map[string][]byte{}}
+}
+
+func (c *etagCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:]))
+}
+
+// get returns the cached response of the request, if any
+func (c *etagCache) get(key string, req *http.Request) *http.Response {
+	c.mu.Lock()
+	b, ok := c.entries[key]
+	c.mu.Unlock()
+	if !ok && c.dir != "" {
+		b, _ = os.ReadFile(c.path(key))
+	}
+	if len(b) == 0 {
+		return nil
+	}
+	res, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(b)), req)
+	if err != nil {
+		return nil
+	}
+	return res
+}
+
+// put stores the response, its body is restored so
+// it can still be read by the caller
+func (c *etagCache) put(key string, res *http.Response) (*http.Response, error) {
+	b, err := httputil.DumpResponse(res, true)
+	if err != nil {
+		res.Body.Close()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.entries[key] = b
+	c.mu.Unlock()
+	if c.dir != "" {
+		// the cache is only an optimization, i.e. it can't
+		// be written when the configuration is read-only
+		if err := os.MkdirAll(c.dir, 0o755); err == nil {
+			if err := os.WriteFile(c.path(key), b, 0o644); err != nil {
+				log.Debugf("Error caching %s: %v", key, err)
+			}
+		}
+	}
+	return res, nil
+}
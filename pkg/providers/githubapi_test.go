@@ -0,0 +1,131 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestGenericGitHubAPIVersionURL(t *testing.T) {
+	requests, notModified := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") != "Bearer s3cr3t" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"abc"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"abc"`)
+		fmt.Fprint(w, `{"tag_name": "v1.4.0"}`)
+	}))
+	defer srv.Close()
+
+	u, _ := url.Parse(srv.URL)
+	defer func(h string) { gitHubAPIHost = h }(gitHubAPIHost)
+	gitHubAPIHost = u.Host
+
+	dir := t.TempDir()
+	latest := func() string {
+		s := NewSettings(true, map[string]string{"GITHUB_TOKEN": "s3cr3t"})
+		s.SetCacheDir(dir)
+		p, err := New("https://dl.example.com/tool-{version}", &Opts{VersionURL: srv.URL + "/repos/o/tool/releases/latest", VersionJSONPath: "tag_name", Settings: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		v, _, err := p.GetLatestVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v
+	}
+
+	// the second run revalidates the response cached by the first one
+	for i := 0; i < 2; i++ {
+		if v := latest(); v != "v1.4.0" {
+			t.Fatalf("expected v1.4.0, got %s", v)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Fatalf("expected a conditional request, got %d requests and %d not modified", requests, notModified)
+	}
+
+	// other hosts don't get the GitHub token
+	gitHubAPIHost = "api.github.com"
+	s := NewSettings(true, map[string]string{"GITHUB_TOKEN": "s3cr3t"})
+	p, err := New("https://dl.example.com/tool-{version}", &Opts{VersionURL: srv.URL + "/version", Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _, err := p.GetLatestVersion(); err != nil || v != "" {
+		t.Fatalf("expected the request to be unauthenticated, got %q, %v", v, err)
+	}
+}
+
+func TestGitHubTransportRateLimit(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(30*time.Second).Unix(), 10))
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	var slept time.Duration
+	c := &http.Client{Transport: &gitHubTransport{
+		next:  http.DefaultTransport,
+		token: func() string { return "" },
+		etags: newETagCache(""),
+		sleep: func(d time.Duration) { slept += d },
+	}}
+	res, err := c.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK || requests != 2 {
+		t.Fatalf("expected the request to be retried, got %d after %d requests", res.StatusCode, requests)
+	}
+	if slept <= 0 || slept > 30*time.Second {
+		t.Fatalf("expected to wait for the reset, waited %s", slept)
+	}
+}
+
+func TestRateLimitWait(t *testing.T) {
+	now := time.Unix(1000, 0)
+	cases := []struct {
+		status  int
+		headers map[string]string
+		wait    time.Duration
+		limited bool
+	}{
+		{http.StatusOK, nil, 0, false},
+		{http.StatusForbidden, nil, 0, false},
+		{http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "1010"}, 10 * time.Second, true},
+		{http.StatusForbidden, map[string]string{"X-RateLimit-Remaining": "0", "X-RateLimit-Reset": "900"}, 0, true},
+		{http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}, 5 * time.Second, true},
+	}
+
+	for _, c := range cases {
+		res := &http.Response{StatusCode: c.status, Header: http.Header{}}
+		for k, v := range c.headers {
+			res.Header.Set(k, v)
+		}
+		wait, limited := rateLimitWait(res, now)
+		if wait != c.wait || limited != c.limited {
+			t.Errorf("%d %v: expected %s %v, got %s %v", c.status, c.headers, c.wait, c.limited, wait, limited)
+		}
+	}
+}
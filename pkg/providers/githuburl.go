@@ -0,0 +1,185 @@
+package providers
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// NormalizeURL returns the canonical form of the URL for the providers
+// which support it, so only clean sources get stored. Other URLs are
+// returned unchanged.
+func NormalizeURL(u, provider string, s *Settings) (string, error) {
+	if dockerUrlPrefix.MatchString(u) || goinstallUrlPrefix.MatchString(u) || provider == "goinstall" || IsTemplate(u) {
+		return u, nil
+	}
+
+	raw := u
+	if !httpUrlPrefix.MatchString(raw) {
+		raw = fmt.Sprintf("https://%s", raw)
+	}
+	purl, err := url.Parse(raw)
+	if err != nil {
+		return "", err
+	}
+
+	if !isGitHub(purl, provider) && !s.gitHubEnterpriseHost(purl.Hostname()) {
+		return u, nil
+	}
+
+	n, err := normalizeGitHubURL(purl, s.gitHubPathPrefix(purl.Hostname()))
+	if err != nil {
+		return "", err
+	}
+	return n.String(), nil
+}
+
+// isGitHub reports whether the URL is handled by the GitHub provider
+func isGitHub(u *url.URL, provider string) bool {
+	return strings.Contains(strings.ToLower(u.Host), "github") || provider == "github"
+}
+
+// gitHubRef is what a GitHub URL points to. The tag is empty for
+// the latest release, the asset is only known from download URLs
+type gitHubRef struct {
+	// prefix is the path the instance is served under,
+	// i.e. a GitHub Enterprise Server behind a proxy
+	prefix      string
+	owner, repo string
+	tag, asset  string
+	// download is set for the download URLs of the assets
+	download bool
+	// ignored is the path after the repository which
+	// isn't relevant to find its releases (tree, blob, ...)
+	ignored []string
+}
+
+// parseGitHubURL parses the supported GitHub URLs, duplicated and
+// trailing slashes aside:
+//   - https://github.com/owner/repo(.git)
+//   - https://github.com/owner/repo/releases(/latest)
+//   - https://github.com/owner/repo/releases/tag/v1.2.3
+//   - https://github.com/owner/repo/releases/download/v1.2.3(/asset-name)
+//   - https://github.com/owner/repo/releases/latest/download/asset-name
+//
+// prefix is the path the instance is served under. Tags might contain
+// slashes (i.e `component/v1.2.3`), the asset is always the last element
+// of the download URLs. Other repository pages point to the latest release.
+func parseGitHubURL(u *url.URL, prefix string) (*gitHubRef, error) {
+	segments := []string{}
+	for _, s := range strings.Split(u.Path, "/") {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	ref := &gitHubRef{}
+	if prefix != "" {
+		ps := strings.Split(prefix, "/")
+		if len(segments) >= len(ps) && strings.EqualFold(strings.Join(segments[:len(ps)], "/"), prefix) {
+			ref.prefix, segments = prefix, segments[len(ps):]
+		}
+	}
+
+	fail := func(part string) error {
+		return fmt.Errorf("error parsing Github URL %s, can't determine %s", u.Redacted(), part)
+	}
+	if len(segments) == 0 {
+		return nil, fail("the owner of the repository")
+	}
+	if len(segments) < 2 || trimGitSuffix(segments[1]) == "" {
+		return nil, fail("the name of the repository")
+	}
+	ref.owner, ref.repo = segments[0], trimGitSuffix(segments[1])
+
+	rest := segments[2:]
+	if len(rest) == 0 || rest[0] != "releases" {
+		ref.ignored = rest
+		return ref, nil
+	}
+	switch rest = rest[1:]; {
+	case len(rest) == 0:
+	case rest[0] == "latest":
+		if len(rest) > 1 && rest[1] == "download" {
+			if len(rest) < 3 {
+				return nil, fail("the asset of the download URL")
+			}
+			ref.download, ref.asset = true, strings.Join(rest[2:], "/")
+		}
+	case rest[0] == "tag" || rest[0] == "expanded_assets":
+		if len(rest) < 2 {
+			return nil, fail("the tag of the release URL")
+		}
+		ref.tag = strings.Join(rest[1:], "/")
+	case rest[0] == "download":
+		if len(rest) < 2 {
+			return nil, fail("the tag of the download URL")
+		}
+		ref.download, ref.tag = true, strings.Join(rest[1:], "/")
+		if len(rest) > 2 {
+			ref.tag, ref.asset = strings.Join(rest[1:len(rest)-1], "/"), rest[len(rest)-1]
+		}
+	default:
+		ref.ignored = append([]string{"releases"}, rest...)
+	}
+	return ref, nil
+}
+
+// trimGitSuffix trims the `.git` suffixes of clone URLs
+func trimGitSuffix(repo string) string {
+	for strings.HasSuffix(repo, ".git") {
+		repo = strings.TrimSuffix(repo, ".git")
+	}
+	return repo
+}
+
+// path returns the canonical path of the URL
+func (r *gitHubRef) path() string {
+	path := []string{r.owner, r.repo}
+	if r.prefix != "" {
+		path = append([]string{r.prefix}, path...)
+	}
+	switch {
+	case r.download && r.tag == "":
+		path = append(path, "releases", "latest", "download", r.asset)
+	case r.download && r.asset == "":
+		path = append(path, "releases", "download", r.tag)
+	case r.download:
+		path = append(path, "releases", "download", r.tag, r.asset)
+	case r.tag != "":
+		path = append(path, "releases", "tag", r.tag)
+	}
+	return "/" + strings.Join(path, "/")
+}
+
+// normalizeGitHubURL strips everything which is not relevant to find
+// the releases of a repository: `.git` suffixes, queries, fragments
+// and the paths of the repository pages (tree, blob, issues, ...).
+func normalizeGitHubURL(u *url.URL, prefix string) (*url.URL, error) {
+	n := &url.URL{Scheme: u.Scheme, Host: strings.ToLower(u.Host)}
+	if n.Scheme == "" {
+		n.Scheme = "https"
+	}
+	n.Host = strings.TrimPrefix(n.Host, "www.")
+
+	ref, err := parseGitHubURL(u, prefix)
+	if err != nil {
+		return nil, err
+	}
+	switch rest := ref.ignored; {
+	case len(rest) == 0:
+	case (rest[0] == "tree" || rest[0] == "blob") && len(rest) > 2:
+		sub := strings.Join(rest[2:], "/")
+		hint := fmt.Sprintf("pick the file of the release archives with --package-path %s", sub)
+		if rest[0] == "tree" {
+			hint += fmt.Sprintf(", or build the package from source with --build-from-source --main-package %s", sub)
+		}
+		log.WithField("url", u.String()).WithField("repo", ref.owner+"/"+ref.repo).WithField("path", sub).Warn("The URL points inside the repository, its releases are used instead: " + hint)
+	default:
+		log.Debugf("Ignoring the %s path of %s", strings.Join(rest, "/"), u)
+	}
+
+	n.Path = ref.path()
+	return n, nil
+}
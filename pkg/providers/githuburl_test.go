@@ -0,0 +1,164 @@
+package providers
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	cases := []struct {
+		in, provider, out string
+	}{
+		{"github.com/owner/repo", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo.git", "", "https://github.com/owner/repo"},
+		{"https://www.GitHub.com/owner/repo?tab=readme-ov-file#install", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/tree/main/cmd/tool", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/blob/main/README.md", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/issues/42", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/releases", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/releases/latest", "", "https://github.com/owner/repo"},
+		{"https://github.com/owner/repo/releases/tag/cli/v1.2.3?foo=bar", "", "https://github.com/owner/repo/releases/tag/cli/v1.2.3"},
+		{"https://github.com/owner/repo/releases/download/v1.2.3/tool_linux", "", "https://github.com/owner/repo/releases/download/v1.2.3/tool_linux"},
+		{"https://github.company.com/owner/repo.git", "", "https://github.company.com/owner/repo"},
+		{"https://git.company.com/owner/repo/tree/main", "github", "https://git.company.com/owner/repo"},
+		{"https://gitlab.com/owner/repo.git", "", "https://gitlab.com/owner/repo.git"},
+		{"docker://hashicorp/terraform:light", "", "docker://hashicorp/terraform:light"},
+		{"https://dl.example.com/tool-{version}.tar.gz", "", "https://dl.example.com/tool-{version}.tar.gz"},
+	}
+
+	for _, c := range cases {
+		out, err := NormalizeURL(c.in, c.provider, nil)
+		if err != nil {
+			t.Fatalf("%s: %v", c.in, err)
+		}
+		if out != c.out {
+			t.Errorf("%s: expected %s, got %s", c.in, c.out, out)
+		}
+	}
+
+	if _, err := NormalizeURL("https://github.com/owner", "", nil); err == nil {
+		t.Error("expected an error for a URL without repo")
+	}
+}
+
+func TestParseGitHubURL(t *testing.T) {
+	cases := []struct {
+		in, prefix                     string
+		owner, repo, tag, asset, error string
+	}{
+		{in: "https://github.com/owner/repo", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/", owner: "owner", repo: "repo"},
+		{in: "https://github.com//owner//repo//", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo.git", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo.git/", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo?tab=readme-ov-file#install", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/tree/main/cmd/tool", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/blob/main/README.md", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/issues/42", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/releases", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/releases/", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/releases/latest", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/releases/latest/", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/releases?page=2", owner: "owner", repo: "repo"},
+		{in: "https://github.com/owner/repo/releases/tag/v1.2.3", owner: "owner", repo: "repo", tag: "v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/tag/v1.2.3/", owner: "owner", repo: "repo", tag: "v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/tag/cli/v1.2.3", owner: "owner", repo: "repo", tag: "cli/v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/tag/cli%2Fv1.2.3", owner: "owner", repo: "repo", tag: "cli/v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/tag/v1.2.3?foo=bar", owner: "owner", repo: "repo", tag: "v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/tag/nightly", owner: "owner", repo: "repo", tag: "nightly"},
+		{in: "https://github.com/owner/repo/releases/expanded_assets/v1.2.3", owner: "owner", repo: "repo", tag: "v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/download/v1.2.3", owner: "owner", repo: "repo", tag: "v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/download/v1.2.3/", owner: "owner", repo: "repo", tag: "v1.2.3"},
+		{in: "https://github.com/owner/repo/releases/download/v1.2.3/tool_linux_amd64.tar.gz", owner: "owner", repo: "repo", tag: "v1.2.3", asset: "tool_linux_amd64.tar.gz"},
+		{in: "https://github.com/owner/repo/releases/download/v1.2.3//tool_linux", owner: "owner", repo: "repo", tag: "v1.2.3", asset: "tool_linux"},
+		{in: "https://github.com/owner/repo/releases/download/cli/v1.2.3/tool", owner: "owner", repo: "repo", tag: "cli/v1.2.3", asset: "tool"},
+		{in: "https://github.com/owner/repo/releases/download/cli%2Fv1.2.3/tool", owner: "owner", repo: "repo", tag: "cli/v1.2.3", asset: "tool"},
+		{in: "https://github.com/owner/repo/releases/latest/download/tool_linux", owner: "owner", repo: "repo", asset: "tool_linux"},
+		{in: "https://github.com/owner/repo/releases/latest/download/tool_linux/", owner: "owner", repo: "repo", asset: "tool_linux"},
+		{in: "https://github.com/Owner-Name/repo.name_1/releases/tag/v0.1.0-rc.1+build.5", owner: "Owner-Name", repo: "repo.name_1", tag: "v0.1.0-rc.1+build.5"},
+		{in: "https://github.company.com/owner/repo/releases/download/v1/tool", owner: "owner", repo: "repo", tag: "v1", asset: "tool"},
+		{in: "https://git.company.com/github/owner/repo", prefix: "github", owner: "owner", repo: "repo"},
+		{in: "https://git.company.com/github/owner/repo/releases/download/v1/tool", prefix: "github", owner: "owner", repo: "repo", tag: "v1", asset: "tool"},
+		{in: "https://git.company.com/tools/github/owner/repo/releases/latest/download/tool", prefix: "tools/github", owner: "owner", repo: "repo", asset: "tool"},
+		{in: "https://git.company.com/GitHub/owner/repo/", prefix: "github", owner: "owner", repo: "repo"},
+		// URLs outside of the prefix are parsed as is
+		{in: "https://git.company.com/owner/repo", prefix: "github", owner: "owner", repo: "repo"},
+		{in: "https://github.com/", error: "the owner of the repository"},
+		{in: "https://github.com/owner", error: "the name of the repository"},
+		{in: "https://github.com/owner/.git", error: "the name of the repository"},
+		{in: "https://git.company.com/github/owner", prefix: "github", error: "the name of the repository"},
+		{in: "https://github.com/owner/repo/releases/tag", error: "the tag of the release URL"},
+		{in: "https://github.com/owner/repo/releases/download/", error: "the tag of the download URL"},
+		{in: "https://github.com/owner/repo/releases/latest/download", error: "the asset of the download URL"},
+	}
+
+	for _, c := range cases {
+		u, err := url.Parse(c.in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ref, err := parseGitHubURL(u, c.prefix)
+		if c.error != "" {
+			if err == nil || !strings.Contains(err.Error(), "can't determine "+c.error) {
+				t.Errorf("%s: expected an error about %s, got %v", c.in, c.error, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", c.in, err)
+			continue
+		}
+		if ref.owner != c.owner || ref.repo != c.repo || ref.tag != c.tag || ref.asset != c.asset {
+			t.Errorf("%s: expected %s/%s tag %q asset %q, got %s/%s tag %q asset %q", c.in, c.owner, c.repo, c.tag, c.asset, ref.owner, ref.repo, ref.tag, ref.asset)
+		}
+	}
+}
+
+func TestNormalizeGitHubEnterpriseURL(t *testing.T) {
+	t.Setenv("GHES_BASE_URL", "https://git.company.com/github/api/v3/")
+	s := NewSettings(false, nil)
+	out, err := NormalizeURL("https://git.company.com/github/owner/repo.git/releases/latest/download/tool", "", s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "https://git.company.com/github/owner/repo/releases/latest/download/tool"; out != want {
+		t.Fatalf("expected %s, got %s", want, out)
+	}
+}
+
+func FuzzParseGitHubURL(f *testing.F) {
+	for _, s := range []string{
+		"/owner/repo",
+		"//owner/repo.git/",
+		"/owner/repo/releases/tag/cli/v1.2.3",
+		"/owner/repo/releases/download/v1.2.3/tool",
+		"/owner/repo/releases/latest/download/tool",
+		"/github/owner/repo/releases/download/v1",
+	} {
+		f.Add(s, "")
+		f.Add(s, "github")
+	}
+	f.Fuzz(func(t *testing.T, path, prefix string) {
+		prefix = strings.Trim(prefix, "/")
+		ref, err := parseGitHubURL(&url.URL{Path: path}, prefix)
+		if err != nil {
+			return
+		}
+		if ref.owner == "" || ref.repo == "" || strings.Contains(ref.owner, "/") || strings.Contains(ref.repo, "/") {
+			t.Fatalf("%q: invalid owner %q or repo %q", path, ref.owner, ref.repo)
+		}
+		if ref.download && ref.tag == "" && ref.asset == "" {
+			t.Fatalf("%q: download URL without tag nor asset", path)
+		}
+		// the canonical path points to the same release
+		again, err := parseGitHubURL(&url.URL{Path: ref.path()}, prefix)
+		if err != nil {
+			t.Fatalf("%q: the canonical path %q can't be parsed: %v", path, ref.path(), err)
+		}
+		if again.owner != ref.owner || again.repo != ref.repo || again.tag != ref.tag || again.asset != ref.asset || again.download != ref.download {
+			t.Fatalf("%q: the canonical path %q points to %+v instead of %+v", path, ref.path(), again, ref)
+		}
+	})
+}
@@ -5,13 +5,12 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
-	"os"
 	"sort"
 	"strings"
 
-	"github.com/caarlos0/log"
 	"github.com/coreos/go-semver/semver"
 	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/yuin/goldmark"
 	goldast "github.com/yuin/goldmark/ast"
 	"github.com/yuin/goldmark/text"
@@ -21,10 +20,13 @@ import (
 type gitLab struct {
 	url    *url.URL
 	client *gitlab.Client
-	token  string
-	owner  string
-	repo   string
-	tag    string
+	// token is resolved on every request so a
+	// refreshed one is picked up
+	token func() string
+	owner string
+	repo  string
+	tag   string
+	http  *http.Client
 }
 
 func (g *gitLab) Fetch(opts *FetchOpts) (*File, error) {
@@ -38,11 +40,11 @@ func (g *gitLab) Fetch(opts *FetchOpts) (*File, error) {
 			// this is used by for the `ensure` command
 			g.tag = opts.Version
 		}
-		log.Infof("Getting %s release for %s/%s", g.tag, g.owner, g.repo)
+		log.WithField("provider", g.GetID()).WithField("repo", g.owner+"/"+g.repo).WithField("version", g.tag).Info("Getting the release")
 		release, _, err = g.client.Releases.GetRelease(projectPath, g.tag)
 	} else {
 		// TODO: handle case when repo doesn't have releases?
-		log.Infof("Getting latest release for %s/%s", g.owner, g.repo)
+		log.WithField("provider", g.GetID()).WithField("repo", g.owner+"/"+g.repo).Info("Getting the latest release")
 		var name string
 		name, _, err = g.GetLatestVersion()
 		if err != nil {
@@ -62,7 +64,7 @@ func (g *gitLab) Fetch(opts *FetchOpts) (*File, error) {
 	if err != nil {
 		return nil, err
 	}
-	projectIsPublic := g.token == "" || project.Visibility == "" || project.Visibility == gitlab.PublicVisibility
+	projectIsPublic := g.token() == "" || project.Visibility == "" || project.Visibility == gitlab.PublicVisibility
 	log.Debugf("Project is public: %v", projectIsPublic)
 	tryPackages := projectIsPublic || project.PackagesEnabled
 	if tryPackages {
@@ -154,18 +156,18 @@ func (g *gitLab) Fetch(opts *FetchOpts) (*File, error) {
 		return nil, err
 	}
 
-	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck})
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, HTTPClient: g.http, Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, release.TagName), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax, Rewrites: rewritesFor(opts.AssetRewrites, release.TagName)})
 
 	gf, err := f.FilterAssets(g.repo, candidates)
 	if err != nil {
 		return nil, err
 	}
 
-	if g.token != "" {
+	if token := g.token(); token != "" {
 		if gf.ExtraHeaders == nil {
 			gf.ExtraHeaders = map[string]string{}
 		}
-		gf.ExtraHeaders["PRIVATE-TOKEN"] = g.token
+		gf.ExtraHeaders["PRIVATE-TOKEN"] = token
 	}
 
 	outFile, err := f.ProcessURL(gf)
@@ -175,10 +177,10 @@ func (g *gitLab) Fetch(opts *FetchOpts) (*File, error) {
 
 	version := release.TagName
 
-	// TODO calculate file hash. Not sure if we can / should do it here
-	// since we don't want to read the file unnecesarily. Additionally, sometimes
-	// releases have .sha256 files, so it'd be nice to check for those also
-	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version}
+	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}
+	if f.Picked() {
+		file.SelectedAsset = assets.SelectionPattern(gf.Name, version)
+	}
 
 	return file, nil
 }
@@ -187,6 +189,15 @@ func (g *gitLab) GetID() string {
 	return "gitlab"
 }
 
+func (g *gitLab) Capabilities() Capabilities {
+	return NewCapabilities(CapListVersions, CapChecksums, CapReleaseNotes, CapAssetSizes, CapPrereleases)
+}
+
+// GetSource implements the Sourcer interface
+func (g *gitLab) GetSource() string {
+	return fmt.Sprintf("%s/%s/%s", g.url.Host, g.owner, g.repo)
+}
+
 // GetLatestVersion checks the latest repo release and
 // returns the corresponding name and url to fetch the version
 func (g *gitLab) GetLatestVersion() (string, string, error) {
@@ -226,7 +237,94 @@ func (g *gitLab) GetLatestVersion() (string, string, error) {
 	return highestTagName, tagNameToRelease[highestTagName].Commit.WebURL, nil
 }
 
-func newGitLab(u *url.URL) (Provider, error) {
+// ListVersions lists the releases of the project. GitLab has no
+// pre-release flag so it's inferred from the semver of the tag
+func (g *gitLab) ListVersions(limit int) ([]*Release, error) {
+	log.Debugf("Listing releases of %s/%s", g.owner, g.repo)
+	projectPath := fmt.Sprintf("%s/%s", g.owner, g.repo)
+
+	versions := []*Release{}
+	opts := &gitlab.ListReleasesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for {
+		releases, resp, err := g.client.Releases.ListReleases(projectPath, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, r := range releases {
+			v := &Release{
+				Version:    r.TagName,
+				URL:        fmt.Sprintf("https://%s/%s/-/releases/%s", g.url.Host, projectPath, r.TagName),
+				Prerelease: r.UpcomingRelease,
+			}
+			if sv, err := semver.NewVersion(strings.TrimPrefix(r.TagName, "v")); err == nil && sv.PreRelease != "" {
+				v.Prerelease = true
+			}
+			if r.ReleasedAt != nil {
+				v.PublishedAt = *r.ReleasedAt
+			}
+			versions = append(versions, v)
+			if limit > 0 && len(versions) == limit {
+				return versions, nil
+			}
+		}
+
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	return versions, nil
+}
+
+func (g *gitLab) notes(r *gitlab.Release) *ReleaseNotes {
+	projectPath := fmt.Sprintf("%s/%s", g.owner, g.repo)
+	return &ReleaseNotes{
+		Version: r.TagName,
+		Body:    r.Description,
+		URL:     fmt.Sprintf("https://%s/%s/-/releases/%s", g.url.Host, projectPath, r.TagName),
+	}
+}
+
+// ReleaseNotes implements the ReleaseNoter interface
+func (g *gitLab) ReleaseNotes(from, to string) ([]*ReleaseNotes, error) {
+	log.Debugf("Getting release notes of %s/%s from %s to %s", g.owner, g.repo, from, to)
+	projectPath := fmt.Sprintf("%s/%s", g.owner, g.repo)
+	w := &notesWalker{from: from, to: to}
+	opts := &gitlab.ListReleasesOptions{ListOptions: gitlab.ListOptions{PerPage: 100}}
+	for from != "" {
+		releases, resp, err := g.client.Releases.ListReleases(projectPath, opts)
+		if err != nil {
+			return nil, err
+		}
+		done := false
+		for _, r := range releases {
+			prerelease := r.UpcomingRelease
+			if sv, err := semver.NewVersion(strings.TrimPrefix(r.TagName, "v")); err == nil && sv.PreRelease != "" {
+				prerelease = true
+			}
+			if done = !w.add(g.notes(r), prerelease); done {
+				break
+			}
+		}
+		if done || resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	if len(w.notes) > 0 {
+		return w.notes, nil
+	}
+
+	release, _, err := g.client.Releases.GetRelease(projectPath, to)
+	if err != nil {
+		return nil, err
+	}
+	return []*ReleaseNotes{g.notes(release)}, nil
+}
+
+func newGitLab(u *url.URL, settings *Settings) (Provider, error) {
 	s := strings.Split(u.Path, "/")
 	if len(s) < 3 {
 		return nil, fmt.Errorf("Error parsing GitLab URL %s, can't find owner and repo", u.String())
@@ -246,15 +344,24 @@ func newGitLab(u *url.URL) (Provider, error) {
 
 	}
 
-	token := os.Getenv("GITLAB_TOKEN")
-	hostnameSpecificEnvVarName := fmt.Sprintf("GITLAB_TOKEN_%s", strings.ReplaceAll(u.Hostname(), `.`, "_"))
-	hostnameSpecificToken := os.Getenv(hostnameSpecificEnvVarName)
-	if hostnameSpecificToken != "" {
-		token = hostnameSpecificToken
-	}
-	client, err := gitlab.NewClient(token, gitlab.WithBaseURL(fmt.Sprintf("https://%s/api/v4", u.Hostname())))
+	tokenKeys := settings.TokenKeys("gitlab", u.Hostname())
+	token := func() string { return settings.get(tokenKeys...) }
+	hc := settings.HTTPClient()
+	client, err := gitlab.NewAuthSourceClient(tokenAuthSource(token), gitlab.WithBaseURL(fmt.Sprintf("https://%s/api/v4", u.Hostname())), gitlab.WithHTTPClient(hc))
 	if err != nil {
 		return nil, err
 	}
-	return &gitLab{url: u, client: client, token: token, owner: s[1], repo: s[2], tag: tag}, nil
+	return &gitLab{url: u, client: client, token: token, owner: s[1], repo: s[2], tag: tag, http: hc}, nil
+}
+
+// tokenAuthSource authenticates the gitlab client
+// with the token currently set
+type tokenAuthSource func() string
+
+func (tokenAuthSource) Init(context.Context, *gitlab.Client) error {
+	return nil
+}
+
+func (t tokenAuthSource) Header(context.Context) (string, string, error) {
+	return gitlab.AccessTokenHeaderName, t(), nil
 }
@@ -8,13 +8,16 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"github.com/caarlos0/log"
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/log"
 )
 
 type goinstall struct {
 	name, repo, tag, latestURL string
+	client                     *http.Client
 }
 
 func parseRepo(path string) (string, string, string, string) {
@@ -35,10 +38,10 @@ func parseRepo(path string) (string, string, string, string) {
 	return repo, tag, name, latestURL
 }
 
-func newGoInstall(repo string) (Provider, error) {
+func newGoInstall(repo string, s *Settings) (Provider, error) {
 	repoUrl := strings.TrimPrefix(repo, "goinstall://")
 	repo, tag, name, latestURL := parseRepo(repoUrl)
-	return &goinstall{repo: repo, tag: tag, name: name, latestURL: latestURL}, nil
+	return &goinstall{repo: repo, tag: tag, name: name, latestURL: latestURL, client: s.HTTPClient()}, nil
 }
 
 func getGoPath() (string, error) {
@@ -61,9 +64,9 @@ func (g *goinstall) Fetch(opts *FetchOpts) (*File, error) {
 			// this is used by for the `ensure` command
 			g.tag = opts.Version
 		}
-		log.Infof("Getting %s release for %s", g.tag, g.repo)
+		log.WithField("provider", g.GetID()).WithField("repo", g.repo).WithField("version", g.tag).Info("Getting the release")
 	} else {
-		log.Infof("Getting latest release for %s", g.repo)
+		log.WithField("provider", g.GetID()).WithField("repo", g.repo).Info("Getting the latest release")
 		if name, _, err := g.GetLatestVersion(); err != nil {
 			return nil, fmt.Errorf("failed to get latest version: %w", err)
 		} else {
@@ -97,7 +100,7 @@ func (g *goinstall) Fetch(opts *FetchOpts) (*File, error) {
 }
 
 func (g *goinstall) GetLatestVersion() (string, string, error) {
-	resp, err := http.Get(g.latestURL)
+	resp, err := g.client.Get(g.latestURL)
 	if err != nil {
 		return "", "", err
 	}
@@ -121,6 +124,45 @@ func (g *goinstall) GetLatestVersion() (string, string, error) {
 	return version, g.repo, nil
 }
 
+// ListVersions lists the module versions known by the Go module proxy
+func (g *goinstall) ListVersions(limit int) ([]*Release, error) {
+	resp, err := g.client.Get(fmt.Sprintf("https://proxy.golang.org/%s/@v/list", g.repo))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%d response when listing versions of %s", resp.StatusCode, g.repo)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var vs []*version.Version
+	for _, v := range strings.Fields(string(body)) {
+		if sv, err := version.NewVersion(v); err == nil {
+			vs = append(vs, sv)
+		}
+	}
+	sort.Sort(sort.Reverse(version.Collection(vs)))
+
+	versions := []*Release{}
+	for _, v := range vs {
+		if limit > 0 && len(versions) == limit {
+			break
+		}
+		versions = append(versions, &Release{Version: v.Original(), URL: g.repo, Prerelease: v.Prerelease() != ""})
+	}
+	return versions, nil
+}
+
 func (g *goinstall) GetID() string {
 	return "goinstall"
 }
+
+// Capabilities of go install, the binaries are built locally
+func (g *goinstall) Capabilities() Capabilities {
+	return NewCapabilities(CapListVersions, CapPrereleases)
+}
@@ -0,0 +1,284 @@
+package providers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// The commands of the handlers are killed after these
+// timeouts unless the handler configures its own
+const (
+	handlerLatestTimeout   = 30 * time.Second
+	handlerDownloadTimeout = 10 * time.Minute
+)
+
+// handlerStderrSize is the size of the end of the
+// stderr of a failed command kept in its error
+const handlerStderrSize = 512
+
+// HandlerError is the failure of a command of a handler: a
+// nonzero exit, a timeout or an output which breaks the contract
+type HandlerError struct {
+	// Pattern is the key of the handler in the configuration
+	Pattern string
+	// Command is either latest or download
+	Command  string
+	ExitCode int
+	TimedOut bool
+	// Stderr is the end of what the command wrote on stderr
+	Stderr string
+	Err    error
+}
+
+func (e *HandlerError) Error() string {
+	msg := fmt.Sprintf("%s command of the %s handler", e.Command, e.Pattern)
+	switch {
+	case e.TimedOut:
+		msg += " timed out"
+	case e.ExitCode != 0:
+		msg += fmt.Sprintf(" exited with %d", e.ExitCode)
+	case e.Err != nil:
+		msg += fmt.Sprintf(" failed: %v", e.Err)
+	}
+	if e.Stderr != "" {
+		msg += ": " + e.Stderr
+	}
+	return msg
+}
+
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// handler delegates the versions and downloads of a URL to
+// the commands configured for its scheme or host
+type handler struct {
+	url     string
+	pattern string
+	cfg     *config.Handler
+	// current is the installed version, if any
+	current string
+}
+
+// SetHandlers sets the handlers of the configuration file
+func (s *Settings) SetHandlers(handlers map[string]*config.Handler) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers = handlers
+}
+
+// handlerFor returns the handler of the URL along with its key. The
+// scheme handlers take precedence over the host ones, which are
+// matched in the order of their keys
+func (s *Settings) handlerFor(u string) (string, *config.Handler) {
+	if s == nil {
+		return "", nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if len(s.handlers) == 0 {
+		return "", nil
+	}
+
+	if scheme, _, ok := strings.Cut(u, "://"); ok {
+		if h, ok := s.handlers[scheme+"://"]; ok {
+			return scheme + "://", h
+		}
+	}
+	if !httpUrlPrefix.MatchString(u) {
+		u = fmt.Sprintf("https://%s", u)
+	}
+	pu, err := url.Parse(u)
+	if err != nil || pu.Hostname() == "" {
+		return "", nil
+	}
+	patterns := make([]string, 0, len(s.handlers))
+	for p := range s.handlers {
+		if !strings.HasSuffix(p, "://") {
+			patterns = append(patterns, p)
+		}
+	}
+	sort.Strings(patterns)
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, pu.Hostname()); ok {
+			return p, s.handlers[p]
+		}
+	}
+	return "", nil
+}
+
+func newHandler(u, pattern string, cfg *config.Handler, opts *Opts) (Provider, error) {
+	return &handler{url: u, pattern: pattern, cfg: cfg, current: opts.Version}, nil
+}
+
+// env returns the context passed to the commands
+func (h *handler) env(extra ...string) []string {
+	env := []string{"BIN_URL=" + h.url, "BIN_OS=" + goos, "BIN_ARCH=" + goarch}
+	if pu, err := url.Parse(h.url); err == nil {
+		env = append(env, "BIN_SCHEME="+pu.Scheme, "BIN_HOST="+pu.Host, "BIN_PATH="+pu.Path)
+	}
+	return append(env, extra...)
+}
+
+// run runs the command of the handler with the shell and returns its stdout
+func (h *handler) run(name, command string, timeout time.Duration, env []string) ([]byte, error) {
+	if d := h.cfg.TimeoutDuration(); d > 0 {
+		timeout = d
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	if goos == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+	// the children of the shell may keep its output open
+	cmd.WaitDelay = time.Second
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+
+	log.Debugf("Running the %s command of the %s handler for %s", name, h.pattern, h.url)
+	err := cmd.Run()
+	if err == nil {
+		return stdout.Bytes(), nil
+	}
+
+	herr := &HandlerError{Pattern: h.pattern, Command: name, Stderr: tail(stderr.String(), handlerStderrSize), Err: err}
+	var exitErr *exec.ExitError
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		herr.TimedOut, herr.Err = true, ctx.Err()
+	case errors.As(err, &exitErr):
+		herr.ExitCode = exitErr.ExitCode()
+	}
+	return nil, herr
+}
+
+// tail returns the last n bytes of the trimmed string
+func tail(s string, n int) string {
+	s = strings.TrimSpace(s)
+	if len(s) > n {
+		s = "..." + s[len(s)-n:]
+	}
+	return s
+}
+
+func (h *handler) GetLatestVersion() (string, string, error) {
+	out, err := h.run("latest", h.cfg.Latest, handlerLatestTimeout, h.env("BIN_CURRENT_VERSION="+h.current))
+	if err != nil {
+		return "", "", err
+	}
+	line, _, _ := strings.Cut(strings.TrimSpace(string(out)), "\n")
+	v := strings.TrimSpace(line)
+	if v == "" {
+		return "", "", &HandlerError{Pattern: h.pattern, Command: "latest", Err: errors.New("no version printed on stdout")}
+	}
+	return v, h.url, nil
+}
+
+// ListVersions returns the latest version only, handlers can't list them
+func (h *handler) ListVersions(limit int) ([]*Release, error) {
+	v, u, err := h.GetLatestVersion()
+	if err != nil {
+		return nil, err
+	}
+	return []*Release{{Version: v, URL: u}}, nil
+}
+
+func (h *handler) Fetch(opts *FetchOpts) (*File, error) {
+	version := opts.Version
+	if version == "" {
+		var err error
+		if version, _, err = h.GetLatestVersion(); err != nil {
+			return nil, err
+		}
+	}
+
+	dir, err := os.MkdirTemp("", "bin-handler")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	log.WithField("provider", h.GetID()).WithField("url", h.url).WithField("version", version).WithField("handler", h.pattern).Info("Downloading with the handler")
+	out, err := h.run("download", h.cfg.Download, handlerDownloadTimeout, h.env("BIN_VERSION="+version, "BIN_OUTPUT_DIR="+dir))
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) != 1 || !entries[0].Type().IsRegular() {
+		return nil, &HandlerError{Pattern: h.pattern, Command: "download", Err: fmt.Errorf("expected a single file in $BIN_OUTPUT_DIR, got %d entries", len(entries))}
+	}
+	name := entries[0].Name()
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return nil, err
+	}
+
+	verified, err := h.verify(name, b, out)
+	if err != nil {
+		return nil, err
+	}
+	if verified == "" && opts.RequireChecksum {
+		return nil, fmt.Errorf("no checksum is printed by the download command of the %s handler for %s and require_checksum is set", h.pattern, name)
+	}
+	if opts.RequireSignature {
+		return nil, fmt.Errorf("the %s handler can't verify the signature of %s and require_signature is set", h.pattern, name)
+	}
+
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Relax: opts.Relax})
+	outFile, err := f.ProcessReader(name, bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath, VerifiedWith: verified, Asset: name, AssetSize: int64(len(b)), AssetSHA256: fmt.Sprintf("%x", sha256.Sum256(b)), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: int64(len(b)), Entry: f.Entry()}, nil
+}
+
+// verify checks the file against the `sha256:<digest>` line
+// the download command may print on stdout, it returns the
+// algorithm which verified it, empty if none was printed
+func (h *handler) verify(name string, b, out []byte) (string, error) {
+	s := bufio.NewScanner(bytes.NewReader(out))
+	for s.Scan() {
+		want, ok := strings.CutPrefix(strings.TrimSpace(s.Text()), "sha256:")
+		if !ok {
+			continue
+		}
+		got := fmt.Sprintf("%x", sha256.Sum256(b))
+		if !strings.EqualFold(want, got) {
+			return "", fmt.Errorf("sha256 checksum mismatch for %s: the %s handler printed %s, got %s", name, h.pattern, want, got)
+		}
+		return "sha256", nil
+	}
+	return "", nil
+}
+
+func (h *handler) GetID() string {
+	return "handler"
+}
+
+// Capabilities of the handlers, the checksums are the ones printed by their download command
+func (h *handler) Capabilities() Capabilities {
+	return NewCapabilities(CapChecksums, CapAssetSizes)
+}
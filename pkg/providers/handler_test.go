@@ -0,0 +1,84 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/marcosnils/bin/pkg/config"
+)
+
+func TestHandlerFor(t *testing.T) {
+	s := NewSettings(true, nil)
+	s.SetHandlers(map[string]*config.Handler{
+		"corp://":             {Latest: "a", Download: "a"},
+		"*.corp.example.com":  {Latest: "b", Download: "b"},
+		"dl.corp.example.com": {Latest: "c", Download: "c"},
+	})
+	for u, expected := range map[string]string{
+		"corp://team/tool":                       "corp://",
+		"https://artifacts.corp.example.com/too": "*.corp.example.com",
+		"artifacts.corp.example.com/tool":        "*.corp.example.com",
+		// globs are matched in the order of the keys
+		"https://dl.corp.example.com/tool": "*.corp.example.com",
+		"https://github.com/owner/repo":    "",
+		"other://tool":                     "",
+	} {
+		if pattern, _ := s.handlerFor(u); pattern != expected {
+			t.Errorf("%s: expected the %q handler, got %q", u, expected, pattern)
+		}
+	}
+}
+
+func TestHandler(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the handlers of the test are shell scripts")
+	}
+	content := "#!/bin/sh\necho tool\n"
+	download := fmt.Sprintf(`test "$BIN_VERSION" = 1.2.0 && test "$BIN_HOST" = team && printf '%s' > "$BIN_OUTPUT_DIR/tool"`, strings.ReplaceAll(content, "\n", `\n`))
+
+	cases := []struct {
+		desc     string
+		handler  *config.Handler
+		verified string
+		err      string
+		exitCode int
+		timedOut bool
+	}{
+		{"ok", &config.Handler{Latest: `echo 1.2.0; echo ignored`, Download: download}, "", "", 0, false},
+		{"checksum", &config.Handler{Latest: `echo 1.2.0`, Download: download + ` && echo sha256:$(sha256sum "$BIN_OUTPUT_DIR/tool" | cut -d' ' -f1)`}, "sha256", "", 0, false},
+		{"checksum mismatch", &config.Handler{Latest: `echo 1.2.0`, Download: download + ` && echo sha256:0000`}, "", "sha256 checksum mismatch", 0, false},
+		{"failing latest", &config.Handler{Latest: `echo denied >&2; exit 3`, Download: download}, "", "latest command of the corp:// handler exited with 3: denied", 3, false},
+		{"no version", &config.Handler{Latest: `true`, Download: download}, "", "no version printed", 0, false},
+		{"no file", &config.Handler{Latest: `echo 1.2.0`, Download: `true`}, "", "expected a single file", 0, false},
+		{"timeout", &config.Handler{Latest: `echo 1.2.0`, Download: `sleep 5`, Timeout: "100ms"}, "", "download command of the corp:// handler timed out", 0, true},
+	}
+
+	for _, c := range cases {
+		p, err := newHandler("corp://team/tool", "corp://", c.handler, &Opts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		f, err := p.Fetch(&FetchOpts{})
+		if c.err != "" {
+			var herr *HandlerError
+			switch {
+			case err == nil || !strings.Contains(err.Error(), c.err):
+				t.Errorf("%s: expected error %q, got %v", c.desc, c.err, err)
+			case errors.As(err, &herr) && (herr.ExitCode != c.exitCode || herr.TimedOut != c.timedOut):
+				t.Errorf("%s: expected exit code %d and timeout %v, got %d and %v", c.desc, c.exitCode, c.timedOut, herr.ExitCode, herr.TimedOut)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.desc, err)
+		}
+		b, _ := io.ReadAll(f.Data)
+		if string(b) != content || f.Version != "1.2.0" || f.VerifiedWith != c.verified {
+			t.Errorf("%s: unexpected file %q, version %s verified with %q", c.desc, b, f.Version, f.VerifiedWith)
+		}
+	}
+}
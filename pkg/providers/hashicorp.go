@@ -9,9 +9,9 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/caarlos0/log"
 	"github.com/coreos/go-semver/semver"
 	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/log"
 	"github.com/marcosnils/bin/pkg/options"
 )
 
@@ -70,6 +70,10 @@ func (g *hashiCorp) GetID() string {
 	return "hashicorp"
 }
 
+func (g *hashiCorp) Capabilities() Capabilities {
+	return NewCapabilities(CapListVersions, CapChecksums, CapAssetSizes, CapPrereleases)
+}
+
 func (g *hashiCorp) Fetch(opts *FetchOpts) (*File, error) {
 	var release *hashiCorpRelease
 
@@ -80,7 +84,7 @@ func (g *hashiCorp) Fetch(opts *FetchOpts) (*File, error) {
 			// this is used by for the `ensure` command
 			g.tag = opts.Version
 		}
-		log.Infof("Getting %s release for %s", g.tag, g.repo)
+		log.WithField("provider", g.GetID()).WithField("repo", g.repo).WithField("version", g.tag).Info("Getting the release")
 		release, err = g.getRelease(g.repo, g.tag)
 	} else {
 		var version string
@@ -100,7 +104,7 @@ func (g *hashiCorp) Fetch(opts *FetchOpts) (*File, error) {
 		candidates = append(candidates, &assets.Asset{Name: link.Filename, URL: link.URL})
 	}
 
-	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck})
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, HTTPClient: g.client, Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, release.Version), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax, Rewrites: rewritesFor(opts.AssetRewrites, release.Version)})
 	gf, err := f.FilterAssets(g.repo, candidates)
 	if err != nil {
 		return nil, err
@@ -113,10 +117,10 @@ func (g *hashiCorp) Fetch(opts *FetchOpts) (*File, error) {
 
 	version := release.Version
 
-	// TODO calculate file hash. Not sure if we can / should do it here
-	// since we don't want to read the file unnecesarily. Additionally, sometimes
-	// releases have .sha256 files, so it'd be nice to check for those also
-	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version}
+	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}
+	if f.Picked() {
+		file.SelectedAsset = assets.SelectionPattern(gf.Name, version)
+	}
 
 	return file, nil
 }
@@ -180,7 +184,40 @@ func (g *hashiCorp) GetLatestVersion() (string, string, error) {
 	return release.Version, g.buildHashiCorpAPIURL(g.repo, release.Version), nil
 }
 
-func newHashiCorp(u *url.URL) (Provider, error) {
+// ListVersions lists the released versions of the product. The
+// releases index doesn't expose publication dates
+func (g *hashiCorp) ListVersions(limit int) ([]*Release, error) {
+	releases, err := g.listReleases(g.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	var svs semver.Versions
+	for _, version := range releases.Versions {
+		sv, err := semver.NewVersion(version.Version)
+		if err != nil {
+			log.Debugf("unable to parse %q as a semantic version: %+v", version.Version, err)
+			continue
+		}
+		svs = append(svs, sv)
+	}
+	sort.Sort(sort.Reverse(svs))
+
+	versions := []*Release{}
+	for _, sv := range svs {
+		if limit > 0 && len(versions) == limit {
+			break
+		}
+		versions = append(versions, &Release{
+			Version:    sv.String(),
+			URL:        g.buildHashiCorpAPIURL(g.repo, sv.String()),
+			Prerelease: sv.PreRelease != "",
+		})
+	}
+	return versions, nil
+}
+
+func newHashiCorp(u *url.URL, settings *Settings) (Provider, error) {
 	s := strings.Split(u.Path, "/")
 	if len(s) < 1 {
 		return nil, fmt.Errorf("Error parsing HashiCorp releases URL %s, can't find repo", u.String())
@@ -194,5 +231,5 @@ func newHashiCorp(u *url.URL) (Provider, error) {
 
 	baseURL, _ := url.Parse(releasesURLBase)
 
-	return &hashiCorp{url: u, client: http.DefaultClient, owner: "", repo: s[1], tag: tag, baseURL: baseURL}, nil
+	return &hashiCorp{url: u, client: settings.HTTPClient(), owner: "", repo: s[1], tag: tag, baseURL: baseURL}, nil
 }
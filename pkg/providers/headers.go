@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// resolveHeaders expands the environment references (`${TOKEN}`) of the
+// configured headers. Credentials must be referenced from the environment
+// so they never end up written in the configuration file.
+func resolveHeaders(s *Settings, headers map[string]string, basicAuth string) (map[string]string, error) {
+	for name, value := range headers {
+		if redactedHeaders[http.CanonicalHeaderKey(name)] && !isCredentialReference(value) {
+			return nil, fmt.Errorf("the %s header must reference an environment variable (i.e. 'Bearer ${TOKEN}') instead of holding the credentials", name)
+		}
+	}
+	if basicAuth != "" {
+		user, password, ok := strings.Cut(basicAuth, ":")
+		if !ok || !envReference.MatchString(user) || !envReference.MatchString(password) {
+			return nil, fmt.Errorf("the basic auth user and password must reference an environment variable each (i.e. '${USER}:${PASSWORD}') instead of holding the credentials")
+		}
+		headers = copyHeaders(headers)
+		headers["Authorization"] = basicAuth
+	}
+	if len(headers) == 0 {
+		return nil, nil
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := map[string]string{}
+	for _, name := range names {
+		value := headers[name]
+		missing := []string{}
+		value = os.Expand(value, func(k string) string {
+			v := s.Get(k)
+			if v == "" {
+				missing = append(missing, k)
+			}
+			return v
+		})
+		if len(missing) > 0 {
+			return nil, fmt.Errorf("the %s header references %s which is not set", name, strings.Join(missing, ", "))
+		}
+		resolved[name] = value
+	}
+
+	if basicAuth != "" {
+		resolved["Authorization"] = "Basic " + base64.StdEncoding.EncodeToString([]byte(resolved["Authorization"]))
+	}
+	return resolved, nil
+}
+
+// envReference is a whole `${VAR}` reference
+var envReference = regexp.MustCompile(`^\$\{[A-Za-z_][A-Za-z0-9_]*\}$`)
+
+// isCredentialReference tells whether the value of a credential header
+// is a `${VAR}` reference, after its scheme (i.e. `Bearer ${TOKEN}`).
+// Part of the credentials can't be held in the configuration.
+func isCredentialReference(value string) bool {
+	if _, token, ok := strings.Cut(strings.TrimSpace(value), " "); ok {
+		value = token
+	}
+	return envReference.MatchString(strings.TrimSpace(value))
+}
+
+func copyHeaders(h map[string]string) map[string]string {
+	c := make(map[string]string, len(h)+1)
+	for k, v := range h {
+		c[k] = v
+	}
+	return c
+}
+
+// ParseHeader parses headers given as `Name: value`
+func ParseHeader(h string) (string, string, error) {
+	name, value, ok := strings.Cut(h, ":")
+	if !ok || strings.TrimSpace(name) == "" {
+		return "", "", fmt.Errorf("invalid header %q, expected 'Name: value'", h)
+	}
+	return strings.TrimSpace(name), strings.TrimSpace(value), nil
+}
@@ -0,0 +1,26 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+)
+
+// interceptor serves the requests to some hosts with in-process
+// handlers instead of the network, i.e. the forge of `bin demo`
+type interceptor struct {
+	next     http.RoundTripper
+	handlers map[string]http.Handler
+}
+
+func (i *interceptor) RoundTrip(r *http.Request) (*http.Response, error) {
+	h, ok := i.handlers[strings.ToLower(r.URL.Hostname())]
+	if !ok {
+		return i.next.RoundTrip(r)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, r)
+	res := rec.Result()
+	res.Request = r
+	return res, nil
+}
@@ -0,0 +1,91 @@
+package providers
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// jsonStep is either an object key or an array index
+type jsonStep struct {
+	key   string
+	index int
+}
+
+// parseJSONPath parses dotted paths with index steps for arrays,
+// i.e. `[0].version` or `data.releases[1].tag`
+func parseJSONPath(path string) ([]jsonStep, error) {
+	steps := []jsonStep{}
+	rest := path
+	for rest != "" {
+		switch {
+		case rest[0] == '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q, missing ]", path)
+			}
+			i, err := strconv.Atoi(rest[1:end])
+			if err != nil || i < 0 {
+				return nil, fmt.Errorf("invalid JSON path %q, %q is not an array index", path, rest[1:end])
+			}
+			steps = append(steps, jsonStep{index: i})
+			rest = rest[end+1:]
+		case rest[0] == '.':
+			rest = rest[1:]
+		default:
+			end := strings.IndexAny(rest, ".[")
+			if end < 0 {
+				end = len(rest)
+			}
+			steps = append(steps, jsonStep{key: rest[:end]})
+			rest = rest[end:]
+		}
+	}
+	if len(steps) == 0 {
+		return nil, fmt.Errorf("invalid JSON path %q", path)
+	}
+	return steps, nil
+}
+
+// jsonPathString returns the string found at the path of the JSON document
+func jsonPathString(content []byte, steps []jsonStep) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(content, &v); err != nil {
+		return "", fmt.Errorf("invalid JSON response: %w", err)
+	}
+
+	walked := ""
+	for _, s := range steps {
+		if s.key == "" {
+			walked += fmt.Sprintf("[%d]", s.index)
+			a, ok := v.([]interface{})
+			if !ok {
+				return "", fmt.Errorf("%s is not an array", walked)
+			}
+			if s.index >= len(a) {
+				return "", fmt.Errorf("%s is out of range, the array has %d elements", walked, len(a))
+			}
+			v = a[s.index]
+			continue
+		}
+
+		if walked != "" {
+			walked += "."
+		}
+		walked += s.key
+		o, ok := v.(map[string]interface{})
+		if !ok {
+			return "", fmt.Errorf("%s can't be found, its parent is not an object", walked)
+		}
+		if v, ok = o[s.key]; !ok {
+			return "", fmt.Errorf("%s not found", walked)
+		}
+	}
+
+	str, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s is not a string: %v", walked, v)
+	}
+	return str, nil
+}
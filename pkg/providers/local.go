@@ -0,0 +1,110 @@
+package providers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+var fileUrlPrefix = regexp.MustCompile("^file://")
+
+// local installs a file of the machine, i.e. a build artifact,
+// with the same extraction logic as the remote assets. Its content
+// digest is recorded so it's re-installed when the file changes.
+type local struct {
+	path string
+}
+
+// IsLocal checks if u is a local file, either an
+// absolute path or a file:// URL
+func IsLocal(u string) bool {
+	return fileUrlPrefix.MatchString(u) || filepath.IsAbs(u)
+}
+
+func newLocal(u string) (Provider, error) {
+	p := u
+	if fileUrlPrefix.MatchString(u) {
+		fu, err := url.Parse(u)
+		if err != nil {
+			return nil, err
+		}
+		p = fu.Path
+		// file:///C:/tools/tool.zip
+		if len(p) > 2 && p[0] == '/' && p[2] == ':' {
+			p = p[1:]
+		}
+		p = filepath.FromSlash(p)
+	}
+	if !filepath.IsAbs(p) {
+		return nil, fmt.Errorf("local file %s must be an absolute path", u)
+	}
+	return &local{path: p}, nil
+}
+
+func (l *local) read() ([]byte, error) {
+	b, err := os.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("source file %s doesn't exist anymore", l.path)
+	}
+	return b, err
+}
+
+func digest(b []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(b))
+}
+
+// version is the one in the file name, if any
+func (l *local) version() string {
+	if v := linkVersionRe.FindString(filepath.Base(l.path)); v != "" {
+		return strings.TrimPrefix(v, "v")
+	}
+	return "local"
+}
+
+func (l *local) Fetch(opts *FetchOpts) (*File, error) {
+	b, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Relax: opts.Relax})
+	outFile, err := f.ProcessReader(filepath.Base(l.path), bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+
+	return &File{Data: outFile.Source, Name: outFile.Name, Version: l.version(), PackagePath: outFile.PackagePath, AssetDigest: digest(b), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry()}, nil
+}
+
+// GetAssetDigests returns the digest of the current content of the file
+func (l *local) GetAssetDigests(version string) ([]string, error) {
+	b, err := l.read()
+	if err != nil {
+		return nil, err
+	}
+	return []string{digest(b)}, nil
+}
+
+func (l *local) GetLatestVersion() (string, string, error) {
+	return l.version(), l.path, nil
+}
+
+func (l *local) ListVersions(limit int) ([]*Release, error) {
+	return []*Release{{Version: l.version(), URL: l.path}}, nil
+}
+
+func (l *local) GetID() string {
+	return "local"
+}
+
+// Capabilities of local files, they're installed again when their content changes
+func (l *local) Capabilities() Capabilities {
+	return NewCapabilities()
+}
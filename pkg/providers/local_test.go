@@ -0,0 +1,68 @@
+package providers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocal(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "tool-1.2.0-linux-amd64.tar.gz")
+	write := func(content string) {
+		var buf bytes.Buffer
+		gw := gzip.NewWriter(&buf)
+		tw := tar.NewWriter(gw)
+		if err := tw.WriteHeader(&tar.Header{Name: "tool", Mode: 0o755, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+		tw.Close()
+		gw.Close()
+		if err := os.WriteFile(archive, buf.Bytes(), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("v1")
+
+	for _, u := range []string{archive, "file://" + filepath.ToSlash(archive)} {
+		p, err := New(u, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.GetID() != "local" {
+			t.Fatalf("%s: expected the local provider, got %s", u, p.GetID())
+		}
+		f, err := p.Fetch(&FetchOpts{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(f.Data)
+		if f.Name != "tool" || f.Version != "1.2.0" || string(content) != "v1" {
+			t.Errorf("%s: unexpected file %s %s %q", u, f.Name, f.Version, content)
+		}
+	}
+
+	p, _ := New(archive, nil)
+	f, _ := p.Fetch(&FetchOpts{})
+	d := p.(AssetDigester)
+	if digests, err := d.GetAssetDigests(f.Version); err != nil || digests[0] != f.AssetDigest {
+		t.Errorf("expected the digest to be unchanged, got %v %v", digests, err)
+	}
+	write("v2")
+	if digests, err := d.GetAssetDigests(f.Version); err != nil || digests[0] == f.AssetDigest {
+		t.Errorf("expected the digest to change with the content, got %v %v", digests, err)
+	}
+
+	os.Remove(archive)
+	if _, err := p.Fetch(&FetchOpts{}); err == nil || !strings.Contains(err.Error(), "doesn't exist anymore") {
+		t.Errorf("expected a missing file error, got %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package providers
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// Strategies to choose between the sources of a binary
+const (
+	// StrategyNewest picks the source with the newest version,
+	// the first one on ties
+	StrategyNewest = "newest"
+	// StrategyPriority picks the first source which answers
+	StrategyPriority = "priority"
+)
+
+// multi is a binary published in several places, i.e. on GitHub
+// and on a vendor download site. The sources are in priority order
+// and failures of one of them fall back to the next one.
+type multi struct {
+	urls     []string
+	sources  []Provider
+	strategy string
+}
+
+// SourceLatest is the latest version of one of the sources
+type SourceLatest struct {
+	URL     string
+	Version string
+	Err     error
+}
+
+func newMulti(u string, opts *Opts, s *Settings) (Provider, error) {
+	switch opts.Strategy {
+	case "", StrategyNewest, StrategyPriority:
+	default:
+		return nil, fmt.Errorf("invalid source strategy %q, must be %s or %s", opts.Strategy, StrategyNewest, StrategyPriority)
+	}
+
+	m := &multi{urls: append([]string{u}, opts.Sources...), strategy: opts.Strategy}
+	for i, su := range m.urls {
+		o := *opts
+		o.Sources, o.Strategy = nil, ""
+		// the provider is only known for the primary source
+		if i > 0 {
+			o.Provider = ""
+		}
+		p, err := New(su, &o)
+		if err != nil {
+			return nil, fmt.Errorf("invalid source %s: %w", su, err)
+		}
+		m.sources = append(m.sources, p)
+	}
+	return m, nil
+}
+
+// LatestBySource returns the latest version of every source,
+// they are queried concurrently
+func (m *multi) LatestBySource() []*SourceLatest {
+	res := make([]*SourceLatest, len(m.sources))
+	var wg sync.WaitGroup
+	for i, p := range m.sources {
+		wg.Add(1)
+		go func(i int, p Provider) {
+			defer wg.Done()
+			v, _, err := p.GetLatestVersion()
+			res[i] = &SourceLatest{URL: m.urls[i], Version: v, Err: err}
+		}(i, p)
+	}
+	wg.Wait()
+	return res
+}
+
+// order returns the indexes of the sources in the
+// order they should be tried to get the latest version
+func (m *multi) order() ([]int, []*SourceLatest, error) {
+	latest := m.LatestBySource()
+
+	best := -1
+	var bestV *version.Version
+	errs := []error{}
+	for i, l := range latest {
+		if l.Err != nil {
+			log.WithField("url", l.URL).WithError(l.Err).Warn("Error checking the latest version")
+			errs = append(errs, l.Err)
+			continue
+		}
+		if m.strategy == StrategyPriority {
+			if best == -1 {
+				best = i
+			}
+			continue
+		}
+		v, err := version.NewVersion(l.Version)
+		if err != nil {
+			log.Debugf("Can't compare version %q of %s: %v", l.Version, l.URL, err)
+			if best == -1 {
+				best = i
+			}
+			continue
+		}
+		if bestV == nil || v.GreaterThan(bestV) {
+			best, bestV = i, v
+		}
+	}
+	if best == -1 {
+		return nil, latest, errors.Join(errs...)
+	}
+
+	order := []int{best}
+	for i := range m.sources {
+		if i != best {
+			order = append(order, i)
+		}
+	}
+	return order, latest, nil
+}
+
+func (m *multi) GetLatestVersion() (string, string, error) {
+	order, _, err := m.order()
+	if err != nil {
+		return "", "", err
+	}
+	p := m.sources[order[0]]
+	return p.GetLatestVersion()
+}
+
+// Fetch tries the sources until one of them succeeds, the one with the
+// latest version first. Specific versions are tried in priority order.
+func (m *multi) Fetch(opts *FetchOpts) (*File, error) {
+	order := make([]int, len(m.sources))
+	for i := range order {
+		order[i] = i
+	}
+	if opts.Version == "" {
+		var err error
+		if order, _, err = m.order(); err != nil {
+			return nil, err
+		}
+	}
+
+	errs := []error{}
+	for _, i := range order {
+		f, err := m.sources[i].Fetch(opts)
+		if err == nil {
+			f.Source = m.urls[i]
+			return f, nil
+		}
+		log.WithField("url", m.urls[i]).WithError(err).Warn("Error fetching, trying the next source")
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+// ListVersions lists the versions of the first source that succeeds
+func (m *multi) ListVersions(limit int) ([]*Release, error) {
+	errs := []error{}
+	for i, p := range m.sources {
+		releases, err := p.ListVersions(limit)
+		if err == nil {
+			return releases, nil
+		}
+		log.WithField("url", m.urls[i]).WithError(err).Warn("Error listing the versions")
+		errs = append(errs, err)
+	}
+	return nil, errors.Join(errs...)
+}
+
+func (m *multi) GetID() string {
+	return m.sources[0].GetID()
+}
+
+// Capabilities are the ones of every source, any of them might be used
+func (m *multi) Capabilities() Capabilities {
+	c := m.sources[0].Capabilities()
+	for _, p := range m.sources[1:] {
+		c = c.Intersect(p.Capabilities())
+	}
+	return c
+}
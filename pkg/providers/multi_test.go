@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"errors"
+	"testing"
+)
+
+type fakeSource struct {
+	version string
+	err     error
+	fetched bool
+}
+
+func (f *fakeSource) Fetch(opts *FetchOpts) (*File, error) {
+	f.fetched = true
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &File{Version: f.version}, nil
+}
+
+func (f *fakeSource) GetLatestVersion() (string, string, error) {
+	return f.version, "", f.err
+}
+
+func (f *fakeSource) ListVersions(limit int) ([]*Release, error) {
+	return []*Release{{Version: f.version}}, f.err
+}
+
+func (f *fakeSource) GetID() string {
+	return "fake"
+}
+
+func (f *fakeSource) Capabilities() Capabilities {
+	return NewCapabilities(CapListVersions)
+}
+
+func TestMulti(t *testing.T) {
+	broken := errors.New("broken")
+	cases := []struct {
+		name     string
+		strategy string
+		sources  []*fakeSource
+		version  string
+		source   string
+	}{
+		{"newest", "", []*fakeSource{{version: "v1.2.3"}, {version: "1.2.4"}}, "1.2.4", "b"},
+		{"tie keeps priority", StrategyNewest, []*fakeSource{{version: "v1.2.4"}, {version: "1.2.4"}}, "v1.2.4", "a"},
+		{"priority", StrategyPriority, []*fakeSource{{version: "v1.2.3"}, {version: "1.2.4"}}, "v1.2.3", "a"},
+		{"primary down", StrategyPriority, []*fakeSource{{err: broken}, {version: "1.2.4"}}, "1.2.4", "b"},
+		// the latest version is known but downloading it fails
+		{"fallback on fetch", "", []*fakeSource{{version: "1.0.0"}, {version: "2.0.0"}}, "1.0.0", "a"},
+	}
+
+	for _, c := range cases {
+		m := &multi{urls: []string{"a", "b"}, strategy: c.strategy}
+		for _, s := range c.sources {
+			m.sources = append(m.sources, s)
+		}
+		if c.name == "fallback on fetch" {
+			m.sources[1] = &failingFetch{c.sources[1]}
+		}
+
+		f, err := m.Fetch(&FetchOpts{})
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if f.Version != c.version || f.Source != c.source {
+			t.Errorf("%s: expected %s from %s, got %s from %s", c.name, c.version, c.source, f.Version, f.Source)
+		}
+	}
+
+	m := &multi{urls: []string{"a", "b"}, sources: []Provider{&fakeSource{err: broken}, &fakeSource{err: broken}}}
+	if _, _, err := m.GetLatestVersion(); !errors.Is(err, broken) {
+		t.Errorf("expected the errors of every source, got %v", err)
+	}
+
+	if _, err := New("github.com/foo/bar", &Opts{Sources: []string{"https://dl.example.com/bar-{version}"}, Strategy: "random"}); err == nil {
+		t.Error("expected an invalid strategy to be rejected")
+	}
+}
+
+// failingFetch knows the latest version but can't download it
+type failingFetch struct {
+	*fakeSource
+}
+
+func (f *failingFetch) Fetch(opts *FetchOpts) (*File, error) {
+	return nil, errors.New("download failed")
+}
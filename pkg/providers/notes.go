@@ -0,0 +1,33 @@
+package providers
+
+// ReleaseNotes are the notes published along a release
+type ReleaseNotes struct {
+	Version string
+	// Body is the markdown of the notes
+	Body string
+	// URL is the page of the release, where
+	// the notes can be read in full
+	URL string
+}
+
+// notesWalker collects the notes of the releases after from
+// up to to, walking the releases newest first
+type notesWalker struct {
+	from, to string
+	notes    []*ReleaseNotes
+}
+
+// add collects the notes of the release if it's in the range, the
+// intermediate prereleases are left out. It returns false once from
+// is reached and there's nothing left to collect
+func (w *notesWalker) add(n *ReleaseNotes, prerelease bool) bool {
+	switch {
+	case n.Version == w.from:
+		return false
+	case n.Version == w.to:
+		w.notes = append(w.notes, n)
+	case len(w.notes) > 0 && !prerelease:
+		w.notes = append(w.notes, n)
+	}
+	return true
+}
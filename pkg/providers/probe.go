@@ -0,0 +1,177 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+const (
+	// maxProbes bounds the number of requests done
+	// when probing for new versions
+	maxProbes = 20
+	// probeInterval rate-limits the probing requests
+	// so download servers are not hammered
+	probeInterval = 500 * time.Millisecond
+)
+
+var probeVersionRe = regexp.MustCompile(`^(v?)(\d+)\.(\d+)(?:\.(\d+))?$`)
+
+// prober discovers new versions of a binary whose download server
+// has no version endpoint by requesting the URLs of the candidate
+// next versions.
+type prober struct {
+	client   *http.Client
+	headers  map[string]string
+	levels   []string
+	max      int
+	interval time.Duration
+}
+
+// newProber validates the strategy, a comma separated list
+// of the version components to increment (major, minor, patch)
+func newProber(strategy string, client *http.Client) (*prober, error) {
+	requested := map[string]bool{}
+	for _, s := range strings.Split(strategy, ",") {
+		s = strings.TrimSpace(s)
+		switch s {
+		case "major", "minor", "patch":
+			requested[s] = true
+		default:
+			return nil, fmt.Errorf("invalid version probe increment %q, must be a list of major, minor or patch", s)
+		}
+	}
+
+	p := &prober{client: client, max: maxProbes, interval: probeInterval}
+	// bigger increments are probed first so we can jump ahead
+	for _, level := range []string{"major", "minor", "patch"} {
+		if requested[level] {
+			p.levels = append(p.levels, level)
+		}
+	}
+	return p, nil
+}
+
+// bump increments the given component of the version, it
+// returns false if the version doesn't have it
+func bump(version, level string) (string, bool) {
+	m := probeVersionRe.FindStringSubmatch(version)
+	if m == nil {
+		return "", false
+	}
+	major, _ := strconv.Atoi(m[2])
+	minor, _ := strconv.Atoi(m[3])
+	switch level {
+	case "major":
+		major, minor = major+1, 0
+	case "minor":
+		minor++
+	}
+
+	if m[4] == "" {
+		if level == "patch" {
+			return "", false
+		}
+		return fmt.Sprintf("%s%d.%d", m[1], major, minor), true
+	}
+
+	patch, _ := strconv.Atoi(m[4])
+	if level == "patch" {
+		patch++
+	} else {
+		patch = 0
+	}
+	return fmt.Sprintf("%s%d.%d.%d", m[1], major, minor, patch), true
+}
+
+// latest returns the highest version reachable from
+// current whose download URL exists
+func (p *prober) latest(current, urlTemplate string) (string, error) {
+	if !probeVersionRe.MatchString(current) {
+		return "", fmt.Errorf("can't probe versions from %q, it needs to look like 1.2.3", current)
+	}
+
+	attempts := 0
+	for {
+		found := false
+		for _, level := range p.levels {
+			next, ok := bump(current, level)
+			if !ok {
+				continue
+			}
+			if attempts >= p.max {
+				log.WithField("attempts", attempts).WithField("version", current).Warn("Stopped probing versions, using the latest found")
+				return current, nil
+			}
+			if attempts > 0 {
+				time.Sleep(p.interval)
+			}
+			attempts++
+
+			exists, err := p.exists(strings.ReplaceAll(urlTemplate, "{version}", next))
+			if err != nil {
+				return "", err
+			}
+			if exists {
+				current, found = next, true
+				break
+			}
+		}
+		if !found {
+			return current, nil
+		}
+	}
+}
+
+// exists checks if the URL can be downloaded
+func (p *prober) exists(u string) (bool, error) {
+	log.Debugf("Probing %s", u)
+	req, err := p.newRequest(http.MethodHead, u)
+	if err != nil {
+		return false, err
+	}
+	res, err := p.client.Do(req)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+
+	if res.StatusCode == http.StatusMethodNotAllowed {
+		// some servers don't support HEAD requests
+		req, err := p.newRequest(http.MethodGet, u)
+		if err != nil {
+			return false, err
+		}
+		req.Header.Set("Range", "bytes=0-0")
+		res, err = p.client.Do(req)
+		if err != nil {
+			return false, err
+		}
+		res.Body.Close()
+	}
+
+	switch {
+	case res.StatusCode >= 200 && res.StatusCode < 300:
+		return true, nil
+	case res.StatusCode == http.StatusNotFound || res.StatusCode == http.StatusGone || res.StatusCode == http.StatusForbidden:
+		return false, nil
+	default:
+		return false, fmt.Errorf("%d response when probing %s", res.StatusCode, u)
+	}
+}
+
+func (p *prober) newRequest(method, u string) (*http.Request, error) {
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range p.headers {
+		req.Header.Set(name, value)
+	}
+	return req, nil
+}
@@ -0,0 +1,90 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBump(t *testing.T) {
+	cases := []struct {
+		in, level string
+		out       string
+		ok        bool
+	}{
+		{"1.2.3", "patch", "1.2.4", true},
+		{"v1.2.3", "minor", "v1.3.0", true},
+		{"1.2.3", "major", "2.0.0", true},
+		{"1.22", "minor", "1.23", true},
+		{"1.22", "patch", "", false},
+		{"latest", "patch", "", false},
+	}
+
+	for _, c := range cases {
+		out, ok := bump(c.in, c.level)
+		if out != c.out || ok != c.ok {
+			t.Errorf("bump(%s, %s): expected %s (%v), got %s (%v)", c.in, c.level, c.out, c.ok, out, ok)
+		}
+	}
+}
+
+func TestProberLatest(t *testing.T) {
+	published := map[string]bool{
+		"/v1.2.3/tool": true,
+		"/v1.2.4/tool": true,
+		"/v1.3.0/tool": true,
+		"/v1.3.1/tool": true,
+		"/v1.5.0/tool": true,
+	}
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Method == http.MethodHead && r.URL.Path == "/v1.3.1/tool" {
+			// some servers don't support HEAD requests
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		if !published[r.URL.Path] {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		strategy string
+		max      int
+		out      string
+	}{
+		{"patch", maxProbes, "v1.2.4"},
+		{"patch,minor", maxProbes, "v1.3.1"},
+		{"minor", maxProbes, "v1.3.0"},
+		{"major", maxProbes, "v1.2.3"},
+		// v1.3.0, v1.4.0 and v1.3.1 would be needed to reach v1.3.1
+		{"patch,minor", 2, "v1.3.0"},
+	}
+
+	for _, c := range cases {
+		p, err := newProber(c.strategy, srv.Client())
+		if err != nil {
+			t.Fatal(err)
+		}
+		p.interval = 0
+		p.max = c.max
+
+		requests = 0
+		v, err := p.latest("v1.2.3", srv.URL+"/{version}/tool")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != c.out {
+			t.Errorf("strategy %s: expected %s, got %s", c.strategy, c.out, v)
+		}
+		if requests > c.max+1 {
+			t.Errorf("strategy %s: %d requests exceed the %d probes limit", c.strategy, requests, c.max)
+		}
+	}
+
+	if _, err := newProber("patch,build", nil); err == nil {
+		t.Error("expected an error for an invalid increment")
+	}
+}
@@ -8,16 +8,88 @@ import (
 	"net/url"
 	"regexp"
 	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/assets"
+	"github.com/marcosnils/bin/pkg/config"
+	"github.com/marcosnils/bin/pkg/prompt"
 )
 
 var ErrInvalidProvider = errors.New("invalid provider")
 
+// Asset hint policies define what happens when the asset
+// hint of a binary doesn't match any of the release assets
+const (
+	// AssetHintWarn logs a warning and considers every asset
+	AssetHintWarn = "warn"
+	// AssetHintFail aborts the operation
+	AssetHintFail = "fail"
+	// AssetHintFallback considers every asset and records
+	// that the hint was bypassed
+	AssetHintFallback = "fallback"
+)
+
+// ResolveAssetHintPolicy returns the asset hint policy applied for
+// the configured one, along with its origin. Without a configured
+// policy a different flavor is never silently installed when nobody
+// is there to pick it: it fails when not interactive and warns otherwise.
+func ResolveAssetHintPolicy(policy string) (string, string) {
+	if policy != "" {
+		return policy, config.OriginEntry
+	}
+	if !prompt.IsInteractive() {
+		return AssetHintFail, config.OriginDefault
+	}
+	return AssetHintWarn, config.OriginDefault
+}
+
 type File struct {
 	Data        io.Reader
 	Name        string
 	Version     string
 	Length      int64
 	PackagePath string
+	// AssetDigest identifies the content of the remote asset
+	// the file comes from, if the provider supports it
+	AssetDigest string
+	// AssetHintBypassed is set when the asset hint didn't match
+	// any asset and was ignored per the fallback policy
+	AssetHintBypassed bool
+	// Source is the URL of the source which served the
+	// file when the binary has several of them
+	Source string
+	// SelectedAsset is the pattern of the asset picked by
+	// the user, if it was asked to
+	SelectedAsset string
+	// VerifiedWith is the algorithm of the checksum which
+	// verified the asset, empty if it wasn't verified
+	VerifiedWith string
+	// Asset and AssetSize are the name and size of the
+	// downloaded release asset, if any
+	Asset     string
+	AssetSize int64
+	// AssetSHA256 is the sha256 digest of the downloaded asset
+	// and AssetURL the URL it was downloaded from
+	AssetSHA256 string
+	AssetURL    string
+	// Emulated is the architecture of the asset when the
+	// host runs it through emulation (i.e. Rosetta)
+	Emulated string
+	// OtherEntries are the paths, inside the archive, of the other
+	// files picked with FetchOpts.Entries or SelectMultiple
+	OtherEntries []string
+	// Extras are the shell completions and man pages
+	// shipped in the archive along the binary
+	Extras []*assets.Extra
+	// Downloaded is the number of bytes downloaded to fetch the file
+	Downloaded int64
+	// Entry is the metadata of the archive entry the
+	// file was extracted from, if any
+	Entry *assets.EntryMeta
+	// SignedBy is the id of the OpenPGP key which signed SignedFile,
+	// either the asset or the checksum file which verified it
+	SignedBy   string
+	SignedFile string
 }
 
 func (f *File) Hash() ([]byte, error) {
@@ -34,6 +106,125 @@ type FetchOpts struct {
 	PackagePath    string
 	SkipPatchCheck bool
 	Version        string
+	// Formats is the preference order of the asset formats,
+	// see assets.DefaultFormats
+	Formats []string
+	// SelectedAsset is the pattern of the asset
+	// previously picked by the user
+	SelectedAsset string
+	// SideFiles keeps the checksums, signatures, SBOMs
+	// and source archives among the candidates
+	SideFiles bool
+	// RequireChecksum fails when the asset can't be verified
+	// with a checksum published along it
+	RequireChecksum bool
+	// Files fetches a data file or a font instead of an
+	// executable, the assets don't need to match the platform
+	Files bool
+	// Entries fetches the files of the archive with these names
+	// and SelectMultiple lets the user pick several of them. The
+	// first one is fetched, the others are listed in OtherEntries
+	Entries        []string
+	SelectMultiple bool
+	// Completions and Manpages fetch the shell completions
+	// and man pages shipped along the binary, see File.Extras
+	Completions bool
+	Manpages    bool
+
+	// Mirrors are URL templates of mirrors of the assets, see
+	// assets.FilterOpts.Mirrors. The {asset} placeholder is resolved
+	// by the filter, the others by the provider
+	Mirrors     []string
+	MirrorFirst bool
+
+	// SigningKeys and RequireSignature verify the signatures of
+	// the checksum files and of the assets, see assets.FilterOpts
+	SigningKeys      []string
+	RequireSignature bool
+
+	// Relax loosens the requirements of the asset selection,
+	// see assets.Relaxation
+	Relax assets.Relaxation
+
+	// AssetRewrites rename the candidate assets of the
+	// versions they apply to before they're scored
+	AssetRewrites []*config.AssetRewrite
+
+	// BuildFromSource builds the Go modules whose releases have
+	// no asset for the platform, from MainPackage when it's set
+	BuildFromSource bool
+	MainPackage     string
+
+	// Cache shares the downloaded assets between the
+	// binaries fetched in the same run
+	Cache *assets.DownloadCache
+	// Releases shares the releases between the binaries
+	// installed from the same one, when they're updated
+	Releases *ReleaseCache
+}
+
+// Opts holds the binary specific settings used
+// to build a provider
+type Opts struct {
+	// Provider forces the use of a specific provider
+	Provider   string
+	VersionURL string
+	// VersionRegex extracts the version from the body of the
+	// version URL using its first capture group
+	VersionRegex string
+	// VersionJSONPath extracts the version from the JSON body
+	// of the version URL, i.e. `[0].version`
+	VersionJSONPath string
+	// VersionCmd prints the version on stdout instead of the
+	// version URL, VersionCmdShell runs it with `sh -c`
+	VersionCmd      string
+	VersionCmdShell bool
+
+	// Scrape looks for the versions in the links of the page at the
+	// URL, which can be restricted to the ones matching LinkRegex
+	Scrape    bool
+	LinkRegex string
+
+	// Sources are other places the binary is published, in priority
+	// order after the URL. Strategy chooses between them.
+	Sources  []string
+	Strategy string
+
+	// TagPrefix and TagPattern restrict the releases considered
+	// by the provider to the ones whose tag matches them. This
+	// is needed for monorepos which release several components
+	TagPrefix  string
+	TagPattern string
+
+	// Version is the currently installed version, if any
+	Version string
+	// VersionProbe enables the discovery of new versions by probing
+	// the download URL of the next ones. It lists the version
+	// components to increment (i.e. `patch,minor`)
+	VersionProbe string
+
+	// Asset selects the release assets to consider, it can be an
+	// exact name, a glob or a regex wrapped in slashes
+	Asset string
+	// AssetHintPolicy defines what to do when the asset hint
+	// (i.e. from a download URL) doesn't match any asset
+	AssetHintPolicy string
+
+	// Headers are sent with the requests of the generic provider.
+	// Values reference the credentials from the environment, i.e.
+	// `Bearer ${TOKEN}`
+	Headers map[string]string
+	// BasicAuth holds the `user:password` credentials of the
+	// generic provider, referenced from the environment
+	BasicAuth string
+
+	// MinTLSVersion overrides the minimum TLS version (i.e. 1.0 for
+	// legacy servers) for the hosts of the URL and the version URL
+	MinTLSVersion string
+
+	// Settings resolves the ambient configuration of the providers.
+	// The environment is used if it's not set
+	Settings *Settings
 }
 
 type Provider interface {
@@ -44,8 +235,99 @@ type Provider interface {
 	// latest version for this binary
 	GetLatestVersion() (string, string, error)
 
+	// ListVersions returns up to limit available versions, newest
+	// first. A limit lower than 1 returns every version
+	ListVersions(limit int) ([]*Release, error)
+
 	// GetID returns the unique identiifer of this provider
 	GetID() string
+
+	// Capabilities returns what the provider supports
+	// for this binary, see Capability
+	Capabilities() Capabilities
+}
+
+// Release describes an available version of a binary. Providers
+// fill the fields they know about
+type Release struct {
+	Version     string
+	URL         string
+	PublishedAt time.Time
+	Prerelease  bool
+}
+
+// AssetDigester is implemented by providers which are able to
+// detect when the assets of an already released version get
+// re-published (i.e. rolling `nightly` tags)
+type AssetDigester interface {
+	// GetAssetDigests returns the digests of the assets
+	// currently published for the given version
+	GetAssetDigests(version string) ([]string, error)
+}
+
+// ReleaseNoter is implemented by providers publishing notes
+// along the releases, see CapReleaseNotes
+type ReleaseNoter interface {
+	// ReleaseNotes returns the notes of the releases after from up to
+	// to, newest first. Only the notes of to are returned when from is
+	// empty or isn't older than to
+	ReleaseNotes(from, to string) ([]*ReleaseNotes, error)
+}
+
+// MultiSourcer is implemented by the binaries published in
+// several places, so the sources can be compared
+type MultiSourcer interface {
+	// LatestBySource returns the latest version of every source
+	LatestBySource() []*SourceLatest
+}
+
+// AssetLister is implemented by providers able to list the
+// assets of a release without downloading them
+type AssetLister interface {
+	// ListAssets returns the names of the assets of the release of
+	// the given version, the latest one when it's empty
+	ListAssets(version string) ([]string, error)
+}
+
+// Describer is implemented by providers with details about where
+// they get the binary from, i.e. the repository of GitHub releases
+type Describer interface {
+	// Describe returns the details by name (i.e. owner, repo)
+	Describe() map[string]string
+}
+
+// Sourcer is implemented by providers able to identify where
+// the releases come from (i.e. a repository) regardless of the
+// asset selected, so the binaries installed from the same source
+// are only resolved once in batch operations
+type Sourcer interface {
+	// GetSource returns the identifier of the releases source
+	GetSource() string
+}
+
+// rewritesFor returns the asset rewrites applying to the version
+func rewritesFor(rewrites []*config.AssetRewrite, version string) []*config.AssetRewrite {
+	var res []*config.AssetRewrite
+	for _, r := range rewrites {
+		if r.AppliesTo(version) {
+			res = append(res, r)
+		}
+	}
+	return res
+}
+
+// rollingTags are tag names which usually get their
+// assets overwritten on every build
+var rollingTags = []string{"nightly", "latest", "edge", "continuous"}
+
+// IsRollingTag reports whether the tag is known to be re-published
+func IsRollingTag(tag string) bool {
+	for _, t := range rollingTags {
+		if strings.EqualFold(tag, t) {
+			return true
+		}
+	}
+	return false
 }
 
 var (
@@ -54,19 +336,56 @@ var (
 	goinstallUrlPrefix = regexp.MustCompile("^goinstall://")
 )
 
-func New(u, provider, versionURL string) (Provider, error) {
+func New(u string, opts *Opts) (Provider, error) {
+	if opts == nil {
+		opts = &Opts{}
+	}
+	provider := opts.Provider
+	settings := opts.Settings
+	if settings == nil {
+		settings = NewSettings(false, nil)
+	}
+
+	if len(opts.Sources) > 0 {
+		return newMulti(u, opts, settings)
+	}
+
+	if IsLocal(u) || provider == "local" {
+		return newLocal(u)
+	}
+
+	if pattern, h := settings.handlerFor(u); h != nil && (provider == "" || provider == "handler") {
+		return newHandler(u, pattern, h, opts)
+	}
+
 	if dockerUrlPrefix.MatchString(u) {
-		return newDocker(u)
+		return newDocker(u, settings)
 	}
 	if goinstallUrlPrefix.MatchString(u) || provider == "goinstall" {
-		return newGoInstall(u)
+		return newGoInstall(u, settings)
 	}
 	if !httpUrlPrefix.MatchString(u) {
 		u = fmt.Sprintf("https://%s", u)
 	}
 
-	if strings.Contains(u, "{version}") {
-		return newGeneric(u, versionURL)
+	if opts.MinTLSVersion != "" {
+		for _, h := range []string{u, opts.VersionURL} {
+			hu, err := url.Parse(h)
+			if err != nil || hu.Hostname() == "" {
+				continue
+			}
+			if err := settings.setHostMinTLSVersion(hu.Hostname(), opts.MinTLSVersion); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if provider == staticSiteID {
+		return newStaticSite(u, opts, settings)
+	}
+
+	if IsTemplate(u) {
+		return newGeneric(u, opts, settings)
 	}
 
 	purl, err := url.Parse(u)
@@ -74,17 +393,37 @@ func New(u, provider, versionURL string) (Provider, error) {
 		return nil, err
 	}
 
-	if strings.Contains(purl.Host, "github") || provider == "github" {
-		return newGitHub(purl)
+	if isGitHub(purl, provider) || settings.gitHubEnterpriseHost(purl.Hostname()) {
+		// unknown GitHub-like hosts might as well be GitLab instances
+		if provider == "" && settings.detectForge(purl) == forgeGitLab {
+			return newGitLab(purl, settings)
+		}
+		if purl, err = normalizeGitHubURL(purl, settings.gitHubPathPrefix(purl.Hostname())); err != nil {
+			return nil, err
+		}
+		tf, err := NewTagFilter(opts.TagPrefix, opts.TagPattern)
+		if err != nil {
+			return nil, err
+		}
+		switch opts.AssetHintPolicy {
+		case "", AssetHintWarn, AssetHintFail, AssetHintFallback:
+		default:
+			return nil, fmt.Errorf("invalid asset hint policy %q, must be one of %s, %s or %s", opts.AssetHintPolicy, AssetHintWarn, AssetHintFail, AssetHintFallback)
+		}
+		return newGitHub(purl, tf, opts.Asset, opts.AssetHintPolicy, settings)
 	}
 
 	if strings.Contains(purl.Host, "gitlab") || provider == "gitlab" {
-		return newGitLab(purl)
+		return newGitLab(purl, settings)
 	}
 
 	if strings.Contains(purl.Host, "releases.hashicorp.com") || provider == "hashicorp" {
-		return newHashiCorp(purl)
+		return newHashiCorp(purl, settings)
+	}
+
+	if provider == "direct" || (provider == "" && isDirect(purl, opts, settings)) {
+		return newDirect(purl, opts, settings)
 	}
 
-	return newGeneric(purl.String(), versionURL)
+	return newGeneric(purl.String(), opts, settings)
 }
@@ -0,0 +1,275 @@
+package providers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/marcosnils/bin/pkg/log"
+	"golang.org/x/net/html"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+// linkVersionRe finds a version in the name of a link, only the usual
+// pre-release suffixes are considered so the platform isn't part of it
+var linkVersionRe = regexp.MustCompile(`(?i)v?\d+\.\d+(?:\.\d+)*(?:-(?:rc|alpha|beta|pre|preview|dev)\.?\d*)?`)
+
+// scraper is the mode of the generic provider for download pages and
+// directory listings without any API. The links of the page are
+// grouped by the version found in their name and the assets of the
+// highest one go through the regular asset filter.
+type scraper struct {
+	page    *url.URL
+	linkRe  *regexp.Regexp
+	client  *http.Client
+	headers map[string]string
+}
+
+// link is a downloadable file of the page
+type link struct {
+	name    string
+	url     string
+	version *version.Version
+}
+
+func newScraper(u string, opts *Opts, s *Settings) (Provider, error) {
+	page, err := url.Parse(u)
+	if err != nil {
+		return nil, err
+	}
+	sc := &scraper{page: page, client: s.HTTPClient()}
+	if sc.headers, err = resolveHeaders(s, opts.Headers, opts.BasicAuth); err != nil {
+		return nil, err
+	}
+	if opts.LinkRegex != "" {
+		if sc.linkRe, err = regexp.Compile(opts.LinkRegex); err != nil {
+			return nil, fmt.Errorf("invalid link regex %q: %w", opts.LinkRegex, err)
+		}
+	}
+	return sc, nil
+}
+
+// links returns the links of the page which match the link regex
+// and have a version, resolved against the page URL
+func (s *scraper) links() ([]*link, error) {
+	log.Debugf("Scraping links from %s", s.page)
+	req, err := http.NewRequest(http.MethodGet, s.page.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range s.headers {
+		req.Header.Set(name, value)
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%d response when fetching %s", res.StatusCode, s.page)
+	}
+	return parseLinks(res.Body, s.page, s.linkRe)
+}
+
+func parseLinks(r io.Reader, page *url.URL, linkRe *regexp.Regexp) ([]*link, error) {
+	files, err := fileLinks(r, page)
+	if err != nil {
+		return nil, err
+	}
+	links := []*link{}
+	for _, u := range files {
+		if l := newLink(path.Base(u.Path), u.String(), linkRe); l != nil {
+			links = append(links, l)
+		}
+	}
+	return links, nil
+}
+
+// fileLinks returns the links of the page to files, resolved
+// against the page URL, without directories, parent links
+// and sorting links
+func fileLinks(r io.Reader, page *url.URL) ([]*url.URL, error) {
+	links := []*url.URL{}
+	seen := map[string]bool{}
+	z := html.NewTokenizer(r)
+	for {
+		switch z.Next() {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return links, nil
+			}
+			return nil, z.Err()
+		case html.StartTagToken, html.SelfClosingTagToken:
+			t := z.Token()
+			if t.Data != "a" {
+				continue
+			}
+			for _, a := range t.Attr {
+				if a.Key != "href" {
+					continue
+				}
+				ref, err := url.Parse(strings.TrimSpace(a.Val))
+				if err != nil {
+					continue
+				}
+				u := page.ResolveReference(ref)
+				u.Fragment = ""
+				if strings.HasSuffix(u.Path, "/") || u.RawQuery != "" || seen[u.String()] {
+					continue
+				}
+				seen[u.String()] = true
+				links = append(links, u)
+			}
+		}
+	}
+}
+
+// newLink returns the link if it matches the regex and has a version,
+// the first capture group of the regex is used as version if any
+func newLink(name, u string, linkRe *regexp.Regexp) *link {
+	raw := linkVersionRe.FindString(name)
+	if linkRe != nil {
+		m := linkRe.FindStringSubmatch(u)
+		if m == nil {
+			return nil
+		}
+		if len(m) > 1 && m[1] != "" {
+			raw = m[1]
+		}
+	}
+	v, err := version.NewVersion(raw)
+	if err != nil {
+		return nil
+	}
+	return &link{name: name, url: u, version: v}
+}
+
+// versions groups the links by version, highest first
+func versions(links []*link) ([]string, map[string][]*link) {
+	byVersion := map[string][]*link{}
+	order := []*link{}
+	for _, l := range links {
+		v := l.version.Original()
+		if _, ok := byVersion[v]; !ok {
+			order = append(order, l)
+		}
+		byVersion[v] = append(byVersion[v], l)
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		return order[i].version.GreaterThan(order[j].version)
+	})
+	res := make([]string, 0, len(order))
+	for _, l := range order {
+		res = append(res, l.version.Original())
+	}
+	return res, byVersion
+}
+
+// latest returns the highest stable version, or
+// the highest pre-release if there are no stable ones
+func latest(vs []string, byVersion map[string][]*link) (string, error) {
+	if len(vs) == 0 {
+		return "", fmt.Errorf("no versioned link found")
+	}
+	for _, v := range vs {
+		if byVersion[v][0].version.Prerelease() == "" {
+			return v, nil
+		}
+	}
+	return vs[0], nil
+}
+
+func (s *scraper) Fetch(opts *FetchOpts) (*File, error) {
+	links, err := s.links()
+	if err != nil {
+		return nil, err
+	}
+	vs, byVersion := versions(links)
+
+	v := opts.Version
+	if v == "" {
+		if v, err = latest(vs, byVersion); err != nil {
+			return nil, fmt.Errorf("%w in %s", err, s.page)
+		}
+	}
+	vLinks, ok := byVersion[v]
+	if !ok {
+		return nil, fmt.Errorf("version %s not found in %s", v, s.page)
+	}
+
+	candidates := make([]*assets.Asset, 0, len(vLinks))
+	for _, l := range vLinks {
+		candidates = append(candidates, &assets.Asset{Name: l.name, URL: l.url, NameFromURL: true})
+	}
+
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, HTTPClient: s.client, Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, v), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax, Rewrites: rewritesFor(opts.AssetRewrites, v)})
+	gf, err := f.FilterAssets(s.name(), candidates)
+	if err != nil {
+		return nil, err
+	}
+	gf.ExtraHeaders = s.headers
+
+	outFile, err := f.ProcessURL(gf)
+	if err != nil {
+		return nil, err
+	}
+
+	file := &File{Data: outFile.Source, Name: outFile.Name, Version: v, PackagePath: outFile.PackagePath, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}
+	if f.Picked() {
+		file.SelectedAsset = assets.SelectionPattern(gf.Name, v)
+	}
+	return file, nil
+}
+
+// name guesses the name of the binary from the page URL
+func (s *scraper) name() string {
+	return path.Base(strings.TrimSuffix(s.page.Path, "/"))
+}
+
+func (s *scraper) GetLatestVersion() (string, string, error) {
+	links, err := s.links()
+	if err != nil {
+		return "", "", err
+	}
+	v, err := latest(versions(links))
+	if err != nil {
+		return "", "", fmt.Errorf("%w in %s", err, s.page)
+	}
+	return v, s.page.String(), nil
+}
+
+// ListVersions returns the versions found in the page
+func (s *scraper) ListVersions(limit int) ([]*Release, error) {
+	links, err := s.links()
+	if err != nil {
+		return nil, err
+	}
+	vs, byVersion := versions(links)
+	releases := []*Release{}
+	for _, v := range vs {
+		if limit > 0 && len(releases) >= limit {
+			break
+		}
+		releases = append(releases, &Release{
+			Version:    v,
+			URL:        s.page.String(),
+			Prerelease: byVersion[v][0].version.Prerelease() != "",
+		})
+	}
+	return releases, nil
+}
+
+func (s *scraper) GetID() string {
+	return "generic"
+}
+
+func (s *scraper) Capabilities() Capabilities {
+	return NewCapabilities(CapListVersions, CapChecksums, CapAssetSizes, CapPrereleases)
+}
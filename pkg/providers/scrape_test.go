@@ -0,0 +1,78 @@
+package providers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScraper(t *testing.T) {
+	srv := httptest.NewServer(http.FileServer(http.Dir("testdata")))
+	defer srv.Close()
+	page := srv.URL + "/listing.html"
+
+	p, err := New(page, &Opts{Scrape: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, u, err := p.GetLatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "1.10.0" || u != page {
+		t.Errorf("expected the latest stable version 1.10.0, got %s (%s)", v, u)
+	}
+
+	releases, err := p.ListVersions(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		version    string
+		prerelease bool
+	}{{"1.11.0-rc1", true}, {"1.10.0", false}, {"1.9.2", false}}
+	if len(releases) != len(expected) {
+		t.Fatalf("expected %d versions, got %d", len(expected), len(releases))
+	}
+	for i, e := range expected {
+		if releases[i].Version != e.version || releases[i].Prerelease != e.prerelease {
+			t.Errorf("%d: expected %s, got %#v", i, e.version, releases[i])
+		}
+	}
+
+	s := p.(*scraper)
+	links, err := s.links()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, byVersion := versions(links)
+	got := map[string]bool{}
+	for _, l := range byVersion["1.10.0"] {
+		got[l.url] = true
+	}
+	for _, want := range []string{
+		srv.URL + "/tool-1.10.0-linux-amd64.tar.gz",
+		srv.URL + "/tool-1.10.0-darwin-arm64.tar.gz",
+		srv.URL + "/tool-1.10.0-linux-amd64.tar.gz.sha256",
+	} {
+		if !got[want] {
+			t.Errorf("expected the relative link to be resolved to %s, got %v", want, got)
+		}
+	}
+
+	// the capture group of the link regex is the version
+	p, err = New(page, &Opts{Scrape: true, LinkRegex: `tool-([0-9.]+)-linux`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if releases, err = p.ListVersions(0); err != nil {
+		t.Fatal(err)
+	}
+	if len(releases) != 2 || releases[0].Version != "1.10.0" {
+		t.Errorf("expected the links to be filtered, got %d versions", len(releases))
+	}
+
+	if _, err := New("https://dl.example.com/tool-{version}.tar.gz", &Opts{Scrape: true}); err == nil {
+		t.Error("expected scraping to be rejected with placeholders")
+	}
+}
@@ -0,0 +1,61 @@
+package providers
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// SearchResult is a repository found by Search
+type SearchResult struct {
+	FullName    string `json:"full_name"`
+	Description string `json:"description"`
+	Stars       int    `json:"stars"`
+	URL         string `json:"url"`
+	// Release is the tag of the latest release,
+	// empty when the repository has none
+	Release string `json:"release"`
+}
+
+// Search searches the repositories of the GitHub forge at host, github.com
+// when it's empty, matching the query, most starred first. The releases
+// of the results are checked too, with a request per repository.
+func (s *Settings) Search(host, query string, limit int) ([]*SearchResult, error) {
+	u := &url.URL{Scheme: "https", Host: host}
+	if host == "" {
+		u.Host = "github.com"
+	}
+	forge := s.detectForge(u)
+	if forge == forgeGitLab {
+		return nil, fmt.Errorf("searching %s isn't supported, it's a GitLab forge", host)
+	}
+	client, err := s.gitHubAPIClient(u, forge)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Searching the repositories of %s matching %q", u.Host, query)
+	found, _, err := client.Search.Repositories(context.TODO(), query, &github.SearchOptions{Sort: "stars", ListOptions: github.ListOptions{PerPage: limit}})
+	if err != nil {
+		return nil, err
+	}
+
+	res := []*SearchResult{}
+	for _, r := range found.Repositories {
+		if len(res) == limit {
+			break
+		}
+		sr := &SearchResult{FullName: r.GetFullName(), Description: r.GetDescription(), Stars: r.GetStargazersCount(), URL: r.GetHTMLURL()}
+		releases, _, err := client.Repositories.ListReleases(context.TODO(), r.GetOwner().GetLogin(), r.GetName(), &github.ListOptions{PerPage: 1})
+		if err != nil {
+			log.Debugf("Unable to get the releases of %s: %v", sr.FullName, err)
+		} else if len(releases) > 0 {
+			sr.Release = releases[0].GetTagName()
+		}
+		res = append(res, sr)
+	}
+	return res, nil
+}
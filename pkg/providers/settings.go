@@ -0,0 +1,255 @@
+package providers
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/marcosnils/bin/pkg/config"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// Settings resolves the ambient configuration (tokens, enterprise
+// endpoints, proxies, ...) used by the providers. Every environment
+// access of the providers goes through it so it can be ignored
+// in pure mode. Values are resolved, in order of precedence, from:
+//   - the explicit settings (i.e. --env flags or Set)
+//   - the environment, unless in pure mode
+//   - the configuration file
+//
+// The providers resolve their credentials on every request,
+// so the settings can be changed while they're in use.
+type Settings struct {
+	pure bool
+
+	mu         sync.RWMutex
+	explicit   map[string]string
+	configured map[string]string
+
+	trace *tracer
+	tls   *tlsPolicy
+	// intercepted are the hosts served in-process
+	intercepted map[string]http.Handler
+
+	clientOnce sync.Once
+	client     *http.Client
+
+	cacheDir     string
+	gitHubOnce   sync.Once
+	gitHubClient *http.Client
+
+	// forges and the GitHub API clients are resolved per host
+	forgesMu      sync.Mutex
+	forges        map[string]string
+	gitHubClients map[string]*github.Client
+
+	// handlers are the external commands of the configuration
+	// the URLs are delegated to, by scheme or host
+	handlers map[string]*config.Handler
+}
+
+// NewSettings returns the settings resolver. Explicit values (i.e. set
+// through flags) always take precedence over the environment, which
+// is completely ignored when pure is set.
+func NewSettings(pure bool, explicit map[string]string) *Settings {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.TLSClientConfig = &tls.Config{MinVersion: defaultMinTLSVersion}
+	return &Settings{pure: pure, explicit: explicit, tls: &tlsPolicy{
+		base:       t,
+		min:        defaultMinTLSVersion,
+		hosts:      map[string]uint16{},
+		transports: map[string]*http.Transport{},
+	}}
+}
+
+// Get returns the value of the given setting
+func (s *Settings) Get(key string) string {
+	v, _ := s.Lookup(key)
+	return v
+}
+
+// Lookup returns the value of the given setting along with its
+// origin: a flag, the environment or the configuration. The
+// origin is the default one when the setting isn't set.
+func (s *Settings) Lookup(key string) (value, origin string) {
+	if s == nil {
+		if v := os.Getenv(key); v != "" {
+			return v, config.OriginEnv
+		}
+		return "", config.OriginDefault
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if v, ok := s.explicit[key]; ok {
+		return v, config.OriginFlag
+	}
+	if !s.pure {
+		if v := os.Getenv(key); v != "" {
+			return v, config.OriginEnv
+		}
+	}
+	if v, ok := s.configured[key]; ok {
+		return v, config.OriginConfig
+	}
+	return "", config.OriginDefault
+}
+
+// Set explicitly sets a setting, i.e. a refreshed token
+func (s *Settings) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	explicit := make(map[string]string, len(s.explicit)+1)
+	for k, v := range s.explicit {
+		explicit[k] = v
+	}
+	explicit[key] = value
+	s.explicit = explicit
+}
+
+// Configure sets the values from the configuration
+// file, they have the lowest precedence
+func (s *Settings) Configure(values map[string]string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.configured = values
+}
+
+// Pure reports whether the environment is ignored
+func (s *Settings) Pure() bool {
+	return s != nil && s.pure
+}
+
+// get returns the first non empty value of the given settings
+func (s *Settings) get(keys ...string) string {
+	v, _, _ := s.LookupFirst(keys...)
+	return v
+}
+
+// LookupFirst returns the first non empty value of the given
+// settings along with its key and origin
+func (s *Settings) LookupFirst(keys ...string) (value, key, origin string) {
+	for _, k := range keys {
+		if v, o := s.Lookup(k); v != "" {
+			return v, k, o
+		}
+	}
+	return "", "", config.OriginDefault
+}
+
+// TokenKeys returns the settings the token of the provider is read
+// from for the given host, in order of precedence. It's empty for
+// the providers without tokens. GitHub hosts which haven't been
+// detected yet are assumed to be github.com.
+func (s *Settings) TokenKeys(provider, host string) []string {
+	switch provider {
+	case "github":
+		forge, _ := s.knownForge(host)
+		return s.gitHubTokenKeys(forge, host)
+	case "gitlab":
+		return []string{fmt.Sprintf("GITLAB_TOKEN_%s", strings.ReplaceAll(host, `.`, "_")), "GITLAB_TOKEN"}
+	}
+	return nil
+}
+
+// Trace logs every request done by the providers to w. Credentials
+// are redacted and the bodies of the API and version endpoints are
+// included, up to maxBody bytes, if bodies is set. It must be called
+// before the HTTP client is used.
+func (s *Settings) Trace(w io.Writer, bodies bool, maxBody int) {
+	s.trace = &tracer{w: w, bodies: bodies, maxBody: maxBody}
+}
+
+// Intercept serves the requests to the host with h instead of the
+// network. It must be called before the HTTP client is used.
+func (s *Settings) Intercept(host string, h http.Handler) {
+	if s.intercepted == nil {
+		s.intercepted = map[string]http.Handler{}
+	}
+	s.intercepted[strings.ToLower(host)] = h
+}
+
+// SetMinTLSVersion sets the minimum TLS version of every host without
+// a specific policy. It must be called before the HTTP client is used.
+func (s *Settings) SetMinTLSVersion(v string) error {
+	min, err := ParseTLSVersion(v)
+	if err != nil {
+		return err
+	}
+	s.tls.min = min
+	s.tls.base.TLSClientConfig.MinVersion = min
+	return nil
+}
+
+// setHostMinTLSVersion sets the minimum TLS version of a single host
+func (s *Settings) setHostMinTLSVersion(host, v string) error {
+	min, err := ParseTLSVersion(v)
+	if err != nil {
+		return err
+	}
+	s.tls.setHost(host, min)
+	return nil
+}
+
+// SetCacheDir sets the directory where the responses of the
+// GitHub API are cached. It must be called before the GitHub
+// client is used.
+func (s *Settings) SetCacheDir(dir string) {
+	s.cacheDir = dir
+}
+
+// gitHub returns the client shared by every request to the
+// GitHub API, whichever provider does it
+func (s *Settings) gitHub() *http.Client {
+	newClient := func(dir string) *http.Client {
+		return &http.Client{Transport: &gitHubTransport{
+			next:  s.HTTPClient().Transport,
+			token: func() string { return s.get("GITHUB_AUTH_TOKEN", "GITHUB_TOKEN") },
+			etags: newETagCache(dir),
+			sleep: time.Sleep,
+		}}
+	}
+	if s == nil {
+		return newClient("")
+	}
+	s.gitHubOnce.Do(func() {
+		s.gitHubClient = newClient(s.cacheDir)
+	})
+	return s.gitHubClient
+}
+
+// HTTPClient returns the client to be used for every request
+// done by the providers. Proxies are resolved from the settings
+// instead of the environment.
+func (s *Settings) HTTPClient() *http.Client {
+	if s == nil {
+		return http.DefaultClient
+	}
+	s.clientOnce.Do(func() {
+		proxy := (&httpproxy.Config{
+			HTTPProxy:  s.get("HTTP_PROXY", "http_proxy"),
+			HTTPSProxy: s.get("HTTPS_PROXY", "https_proxy"),
+			NoProxy:    s.get("NO_PROXY", "no_proxy"),
+		}).ProxyFunc()
+
+		s.tls.base.Proxy = func(r *http.Request) (*url.URL, error) {
+			return proxy(r.URL)
+		}
+		var t http.RoundTripper = s.tls
+		if len(s.intercepted) > 0 {
+			t = &interceptor{next: s.tls, handlers: s.intercepted}
+		}
+		s.client = &http.Client{Transport: t}
+		if s.trace != nil {
+			s.trace.next = t
+			s.client.Transport = s.trace
+		}
+	})
+	return s.client
+}
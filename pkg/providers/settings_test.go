@@ -0,0 +1,256 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"testing"
+
+	"github.com/google/go-github/v31/github"
+)
+
+var pollutedEnv = map[string]string{
+	"GITHUB_AUTH_TOKEN":       "gh-auth-token",
+	"GITHUB_TOKEN":            "gh-token",
+	"GHES_BASE_URL":           "https://ghes.example.com/api/v3/",
+	"GHES_UPLOAD_URL":         "https://ghes.example.com/api/uploads/",
+	"GHES_AUTH_TOKEN":         "ghes-token",
+	"GITLAB_TOKEN":            "gl-token",
+	"GITLAB_TOKEN_gitlab_com": "gl-host-token",
+	"HTTPS_PROXY":             "http://proxy.example.com:3128",
+	"https_proxy":             "http://proxy.example.com:3128",
+	"HTTP_PROXY":              "http://proxy.example.com:3128",
+}
+
+type resolution struct {
+	githubToken   string
+	githubBaseURL string
+	gitlabToken   string
+	proxy         string
+}
+
+func resolve(t *testing.T, s *Settings) resolution {
+	t.Helper()
+	gh, err := New("https://github.com/owner/repo", &Opts{Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	gl, err := New("https://gitlab.com/owner/repo", &Opts{Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := resolution{
+		githubToken:   gh.(*gitHub).token(),
+		githubBaseURL: gh.(*gitHub).client.BaseURL.String(),
+		gitlabToken:   gl.(*gitLab).token(),
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "https://github.com", nil)
+	proxy, err := s.HTTPClient().Transport.(*tlsPolicy).base.Proxy(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proxy != nil {
+		r.proxy = proxy.String()
+	}
+	return r
+}
+
+func TestPureSettingsIgnoreEnvironment(t *testing.T) {
+	explicit := map[string]string{"GITHUB_TOKEN": "explicit-token"}
+
+	clean := resolve(t, NewSettings(true, explicit))
+
+	for k, v := range pollutedEnv {
+		t.Setenv(k, v)
+	}
+
+	polluted := resolve(t, NewSettings(true, explicit))
+	if clean != polluted {
+		t.Fatalf("pure resolution depends on the environment: %+v != %+v", clean, polluted)
+	}
+	if polluted.githubToken != "explicit-token" {
+		t.Fatalf("expected explicit token to be used, got %q", polluted.githubToken)
+	}
+
+	impure := resolve(t, NewSettings(false, nil))
+	if impure == polluted {
+		t.Fatal("expected the environment to be honored outside of pure mode")
+	}
+	if impure.gitlabToken != "gl-host-token" {
+		t.Fatalf("expected host specific gitlab token, got %q", impure.gitlabToken)
+	}
+}
+
+func TestSettingsPrecedence(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "env-token")
+	t.Setenv("GITLAB_TOKEN", "")
+
+	s := NewSettings(false, map[string]string{"GHES_BASE_URL": "explicit-url"})
+	s.Configure(map[string]string{"GITHUB_TOKEN": "config-token", "GITLAB_TOKEN": "config-gl-token", "GHES_BASE_URL": "config-url"})
+	for k, want := range map[string]string{"GHES_BASE_URL": "explicit-url", "GITHUB_TOKEN": "env-token", "GITLAB_TOKEN": "config-gl-token"} {
+		if v := s.Get(k); v != want {
+			t.Errorf("%s: expected %q, got %q", k, want, v)
+		}
+	}
+
+	for k, want := range map[string]string{"GHES_BASE_URL": "flag", "GITHUB_TOKEN": "env", "GITLAB_TOKEN": "config", "GHES_UPLOAD_URL": "default"} {
+		if _, origin := s.Lookup(k); origin != want {
+			t.Errorf("%s: expected origin %q, got %q", k, want, origin)
+		}
+	}
+
+	pure := NewSettings(true, nil)
+	pure.Configure(map[string]string{"GITHUB_TOKEN": "config-token"})
+	if v := pure.Get("GITHUB_TOKEN"); v != "config-token" {
+		t.Errorf("expected the configuration to be honored in pure mode, got %q", v)
+	}
+}
+
+func TestSettingsTokenRefresh(t *testing.T) {
+	var auth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		fmt.Fprint(w, `{"tag_name": "v1.0.0", "html_url": "https://ghes.example.com/owner/repo/releases/tag/v1.0.0"}`)
+	}))
+	defer srv.Close()
+
+	s := NewSettings(true, map[string]string{
+		"GHES_BASE_URL":   srv.URL + "/api/v3/",
+		"GHES_UPLOAD_URL": srv.URL + "/api/uploads/",
+		"GHES_AUTH_TOKEN": "old-token",
+	})
+	p, err := New(srv.URL+"/owner/repo", &Opts{Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, token := range []string{"old-token", "new-token"} {
+		s.Set("GHES_AUTH_TOKEN", token)
+		if _, _, err := p.GetLatestVersion(); err != nil {
+			t.Fatal(err)
+		}
+		if auth != "Bearer "+token {
+			t.Errorf("expected the request to use %s, got %q", token, auth)
+		}
+	}
+}
+
+// TestGitHubPerHost checks the releases of repositories of github.com,
+// the configured GitHub Enterprise Server and an undeclared one with
+// the same settings, like `bin update` does
+func TestGitHubPerHost(t *testing.T) {
+	server := func(prefix, token string, probes *int) *httptest.Server {
+		mux := http.NewServeMux()
+		mux.HandleFunc(prefix+"/meta", func(w http.ResponseWriter, r *http.Request) {
+			*probes++
+			fmt.Fprint(w, `{}`)
+		})
+		mux.HandleFunc(prefix+"/repos/owner/", func(w http.ResponseWriter, r *http.Request) {
+			if auth := r.Header.Get("Authorization"); auth != "Bearer "+token {
+				t.Errorf("%s: expected %s to be used, got %q", r.URL, token, auth)
+			}
+			fmt.Fprintf(w, `{"tag_name": "%s"}`, token)
+		})
+		srv := httptest.NewServer(mux)
+		t.Cleanup(srv.Close)
+		return srv
+	}
+
+	var probes int
+	public := server("", "public-token", &probes)
+	ghes := server("/api/v3", "ghes-token", &probes)
+	detected := server("/api/v3", "detected-token", &probes)
+
+	defer func(u string) { gitHubPublicAPI = u }(gitHubPublicAPI)
+	gitHubPublicAPI = public.URL + "/"
+
+	du, _ := url.Parse(detected.URL)
+	detectedURL := "http://localhost:" + du.Port()
+	s := NewSettings(true, map[string]string{
+		"GITHUB_TOKEN":           "public-token",
+		"GHES_BASE_URL":          ghes.URL + "/api/v3/",
+		"GHES_UPLOAD_URL":        ghes.URL + "/api/uploads/",
+		"GHES_AUTH_TOKEN":        "ghes-token",
+		"GITHUB_TOKEN_localhost": "detected-token",
+	})
+
+	clients := map[string]*github.Client{}
+	for _, tc := range []struct {
+		url, provider, host, want string
+	}{
+		{"https://github.com/owner/a", "", "github.com", "public-token"},
+		{ghes.URL + "/owner/b", "", ghes.URL, "ghes-token"},
+		{detectedURL + "/owner/c", "github", detectedURL, "detected-token"},
+		{"https://github.com/owner/d", "", "github.com", "public-token"},
+		{ghes.URL + "/owner/e", "", ghes.URL, "ghes-token"},
+		{detectedURL + "/owner/f", "github", detectedURL, "detected-token"},
+	} {
+		p, err := New(tc.url, &Opts{Provider: tc.provider, Settings: s})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, ok := p.(*gitHub)
+		if !ok {
+			t.Fatalf("%s: expected the github provider, got %s", tc.url, p.GetID())
+		}
+		if c, ok := clients[tc.host]; ok && c != g.client {
+			t.Errorf("%s: expected the client of %s to be reused", tc.url, tc.host)
+		}
+		clients[tc.host] = g.client
+
+		v, _, err := p.GetLatestVersion()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != tc.want {
+			t.Errorf("%s: expected the release from the %s server, got %s", tc.url, tc.want, v)
+		}
+	}
+	if len(clients) != 3 {
+		t.Errorf("expected a client per host, got %d", len(clients))
+	}
+	if probes != 1 {
+		t.Errorf("expected the undeclared host to be probed once, got %d", probes)
+	}
+}
+
+// TestGitHubDetectedHostToken checks the token of the configured GitHub
+// Enterprise Server isn't sent to another host detected as one
+func TestGitHubDetectedHostToken(t *testing.T) {
+	ghes := httptest.NewServer(http.NotFoundHandler())
+	defer ghes.Close()
+	var auth []string
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v3/meta", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{}`)
+	})
+	mux.HandleFunc("/api/v3/repos/owner/", func(w http.ResponseWriter, r *http.Request) {
+		auth = append(auth, r.Header.Get("Authorization"))
+		fmt.Fprint(w, `{"tag_name": "v1.0.0"}`)
+	})
+	foreign := httptest.NewServer(mux)
+	defer foreign.Close()
+
+	u, _ := url.Parse(foreign.URL)
+	s := NewSettings(true, map[string]string{
+		"GHES_BASE_URL":   ghes.URL + "/api/v3/",
+		"GHES_AUTH_TOKEN": "ghes-token",
+	})
+	p, err := New("http://localhost:"+u.Port()+"/owner/repo", &Opts{Provider: "github", Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := p.GetLatestVersion(); err != nil {
+		t.Fatal(err)
+	}
+	if len(auth) == 0 || slices.ContainsFunc(auth, func(a string) bool { return a != "" }) {
+		t.Errorf("expected no Authorization header, got %q", auth)
+	}
+	if keys := s.TokenKeys("github", "localhost"); slices.Contains(keys, "GHES_AUTH_TOKEN") {
+		t.Errorf("expected the detected host not to read GHES_AUTH_TOKEN, got %v", keys)
+	}
+}
@@ -0,0 +1,43 @@
+package providers
+
+import (
+	"fmt"
+	"strings"
+)
+
+// forges maps the forges supported by shorthands
+// to their repository and release URL formats
+var forges = map[string]struct{ repo, release string }{
+	"github": {"https://github.com/%s", "https://github.com/%s/releases/tag/%s"},
+	"gitlab": {"https://gitlab.com/%s", "https://gitlab.com/%s/-/releases/%s"},
+}
+
+// ExpandShorthand expands `owner/repo` and `owner/repo@version`
+// shorthands into the canonical URL of the given forge (github by
+// default). Only bare strings containing exactly one slash and no scheme
+// are considered shorthands, anything else is returned unchanged.
+func ExpandShorthand(u, forge string) (string, error) {
+	if strings.Contains(u, "://") || strings.Count(u, "/") != 1 ||
+		strings.HasPrefix(u, ".") || strings.HasPrefix(u, "~") {
+		return u, nil
+	}
+
+	repo, version, hasVersion := strings.Cut(u, "@")
+	owner, name, _ := strings.Cut(repo, "/")
+	if owner == "" || name == "" || (hasVersion && version == "") {
+		return u, nil
+	}
+
+	if forge == "" {
+		forge = "github"
+	}
+	f, ok := forges[forge]
+	if !ok {
+		return "", fmt.Errorf("unsupported default forge %q for shorthand %s", forge, u)
+	}
+
+	if hasVersion {
+		return fmt.Sprintf(f.release, repo, version), nil
+	}
+	return fmt.Sprintf(f.repo, repo), nil
+}
@@ -0,0 +1,39 @@
+package providers
+
+import "testing"
+
+func TestExpandShorthand(t *testing.T) {
+	cases := []struct {
+		in, forge string
+		out       string
+	}{
+		{"junegunn/fzf", "", "https://github.com/junegunn/fzf"},
+		{"junegunn/fzf@0.46.0", "", "https://github.com/junegunn/fzf/releases/tag/0.46.0"},
+		{"junegunn/fzf@0.46.0", "github", "https://github.com/junegunn/fzf/releases/tag/0.46.0"},
+		{"gitlab-org/cli", "gitlab", "https://gitlab.com/gitlab-org/cli"},
+		{"gitlab-org/cli@v1.36.0", "gitlab", "https://gitlab.com/gitlab-org/cli/-/releases/v1.36.0"},
+		// not shorthands
+		{"github.com/junegunn/fzf", "", "github.com/junegunn/fzf"},
+		{"https://github.com/junegunn/fzf", "", "https://github.com/junegunn/fzf"},
+		{"docker://hashicorp/terraform", "", "docker://hashicorp/terraform"},
+		{"goinstall://github.com/jrhouston/tfk8s@v0.1.8", "", "goinstall://github.com/jrhouston/tfk8s@v0.1.8"},
+		{"./tool", "", "./tool"},
+		{"/tool", "", "/tool"},
+		{"junegunn/", "", "junegunn/"},
+		{"junegunn/fzf@", "", "junegunn/fzf@"},
+	}
+
+	for _, c := range cases {
+		out, err := ExpandShorthand(c.in, c.forge)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if out != c.out {
+			t.Errorf("expanding %s: expected %s, got %s", c.in, c.out, out)
+		}
+	}
+
+	if _, err := ExpandShorthand("owner/repo", "sourcehut"); err == nil {
+		t.Error("expected an error for an unsupported forge")
+	}
+}
@@ -0,0 +1,154 @@
+package providers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/google/go-github/v31/github"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// lookGo finds the Go toolchain building from source
+var lookGo = func() (string, error) { return exec.LookPath("go") }
+
+// buildFromSource builds the main package of the Go module of the
+// release with the local toolchain, for the platforms without an
+// asset. The main package is mainPkg, relative to the module root,
+// or cmd/<repo> when it exists and the module root otherwise.
+func (g *gitHub) buildFromSource(release *github.RepositoryRelease, mainPkg string) (*File, error) {
+	tag := release.GetTagName()
+	goBin, err := lookGo()
+	if err != nil {
+		return nil, fmt.Errorf("no asset of %s/%s %s matches %s/%s and building it from source needs a Go toolchain: install Go (https://go.dev/dl) or pick an asset with --all", g.owner, g.repo, tag, runtime.GOOS, runtime.GOARCH)
+	}
+	log.WithField("repo", g.owner+"/"+g.repo).WithField("version", tag).Warn("No asset matches the platform, building from source")
+
+	dir, err := os.MkdirTemp("", "bin-build-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	src := filepath.Join(dir, "src")
+	if err := g.downloadSource(tag, src); err != nil {
+		return nil, fmt.Errorf("error downloading the source of %s/%s %s: %w", g.owner, g.repo, tag, err)
+	}
+	if _, err := os.Stat(filepath.Join(src, "go.mod")); err != nil {
+		return nil, fmt.Errorf("no asset of %s/%s %s matches %s/%s and it can't be built from source, it isn't a Go module", g.owner, g.repo, tag, runtime.GOOS, runtime.GOARCH)
+	}
+	if mainPkg == "" {
+		mainPkg = "."
+		if fi, err := os.Stat(filepath.Join(src, "cmd", g.repo)); err == nil && fi.IsDir() {
+			mainPkg = "./cmd/" + g.repo
+		}
+	}
+	if !strings.HasPrefix(mainPkg, ".") {
+		mainPkg = "./" + mainPkg
+	}
+
+	name := g.repo
+	if runtime.GOOS == "windows" {
+		name += ".exe"
+	}
+	out := filepath.Join(dir, name)
+	cmd := exec.Command(goBin, "build", "-trimpath", "-o", out, mainPkg)
+	cmd.Dir = src
+	log.Debugf("Running %v in %s", cmd, src)
+	// stdout holds the --json outputs, the output of the
+	// build is only shown when it fails
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("error building %s of %s/%s %s: %w: %s", mainPkg, g.owner, g.repo, tag, err, tail(strings.TrimSpace(string(output)), handlerStderrSize))
+	}
+
+	// read before the build directory is removed
+	b, err := os.ReadFile(out)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Data: bytes.NewReader(b), Name: name, Version: tag}, nil
+}
+
+// downloadSource extracts the source archive of the tag into dir
+func (g *gitHub) downloadSource(tag, dir string) error {
+	u, _, err := g.client.Repositories.GetArchiveLink(context.TODO(), g.owner, g.repo, github.Tarball, &github.RepositoryContentGetOptions{Ref: tag}, true)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return err
+	}
+	if token := g.token(); token != "" {
+		req.Header.Set("Authorization", fmt.Sprintf("token %s", token))
+	}
+	res, err := g.downloadClient().Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%d response downloading %s", res.StatusCode, u.Redacted())
+	}
+	return extractSource(res.Body, dir)
+}
+
+// extractSource extracts the tar.gz archive into dir without its
+// top directory, named after the repository and the commit
+func extractSource(r io.Reader, dir string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, rel, ok := strings.Cut(h.Name, "/")
+		if !ok || rel == "" {
+			continue
+		}
+		path := filepath.Join(dir, filepath.FromSlash(rel))
+		if !strings.HasPrefix(path, filepath.Clean(dir)+string(filepath.Separator)) {
+			return fmt.Errorf("the archive entry %s is outside of the archive", h.Name)
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o644)
+			if err != nil {
+				return err
+			}
+			_, err = io.Copy(f, tr)
+			if cerr := f.Close(); err == nil {
+				err = cerr
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package providers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// sourceArchive returns the tar.gz of files under the top
+// directory GitHub names after the repository and the commit
+func sourceArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: "acme-tool-abc123/" + name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(tw, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return b.Bytes()
+}
+
+func TestGitHubBuildFromSource(t *testing.T) {
+	if _, err := exec.LookPath("go"); err != nil {
+		t.Skip("no Go toolchain")
+	}
+	t.Setenv("GOTOOLCHAIN", "local")
+	t.Setenv("GOFLAGS", "")
+
+	archive := sourceArchive(t, map[string]string{
+		"go.mod":           "module example.com/acme/tool\n\ngo 1.21\n",
+		"cmd/tool/main.go": "package main\n\nfunc main() { println(\"tool\") }\n",
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/repos/acme/tool/releases/latest", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, `{"id":1,"tag_name":"v1.0.0","assets":[
+			{"name":"tool_plan9_mips.tar.gz","url":"http://%[1]s/assets/1"},
+			{"name":"tool_aix_ppc64.tar.gz","url":"http://%[1]s/assets/2"}
+		]}`, r.Host)
+	})
+	mux.HandleFunc("/repos/acme/tool/releases/1", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":1,"tag_name":"v1.0.0"}`)
+	})
+	mux.HandleFunc("/repos/acme/tool/tarball/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://"+r.Host+"/codeload/acme/tool/v1.0.0", http.StatusFound)
+	})
+	mux.HandleFunc("/codeload/acme/tool/v1.0.0", func(w http.ResponseWriter, r *http.Request) {
+		w.Write(archive)
+	})
+
+	newGitHub := func() *gitHub {
+		g := newTestGitHub(t, mux, nil)
+		g.owner, g.repo = "acme", "tool"
+		g.token = func() string { return "" }
+		return g
+	}
+
+	f, err := newGitHub().Fetch(&FetchOpts{BuildFromSource: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if f.Version != "v1.0.0" {
+		t.Fatalf("expected the tag to be the version, got %s", f.Version)
+	}
+	b, err := io.ReadAll(f.Data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) == 0 {
+		t.Fatal("expected the built binary")
+	}
+
+	if _, err := newGitHub().Fetch(&FetchOpts{BuildFromSource: true, MainPackage: "cmd/missing"}); err == nil {
+		t.Fatal("expected the build of a missing main package to fail")
+	}
+
+	defer func(l func() (string, error)) { lookGo = l }(lookGo)
+	lookGo = func() (string, error) { return "", exec.ErrNotFound }
+	if _, err := newGitHub().Fetch(&FetchOpts{BuildFromSource: true}); err == nil || !strings.Contains(err.Error(), "needs a Go toolchain") {
+		t.Fatalf("expected the missing toolchain to be explained, got %v", err)
+	}
+}
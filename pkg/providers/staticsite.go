@@ -0,0 +1,183 @@
+package providers
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/marcosnils/bin/pkg/log"
+
+	"github.com/marcosnils/bin/pkg/assets"
+)
+
+const staticSiteID = "static-site"
+
+// staticSitePointer is the pointer file read at the root of the
+// site when no version URL is configured
+const staticSitePointer = "stable.txt"
+
+// staticSiteChecksums are the checksum files looked for next
+// to the file of a template, {asset} is its name
+var staticSiteChecksums = []string{"{asset}.sha256", "{asset}.sha256sum", "SHA256SUMS", "sha256sums.txt", "checksums.txt"}
+
+// staticSite is the provider of the projects publishing every version
+// in its own directory of a static site along a pointer file holding
+// the current version, i.e. https://dl.example.com/tool/stable.txt and
+// https://dl.example.com/tool/v1.2.3/{tool_linux_amd64.tar.gz,SHA256SUMS}.
+// The URL is the template of the version directory, whose listing gives
+// the candidates, or of the file itself when the server doesn't list
+// directories. Either way the checksum files of the directory verify
+// the asset.
+type staticSite struct {
+	// generic reads the version from the pointer file
+	*generic
+	// root is the part of the URL before the version
+	root string
+}
+
+func newStaticSite(u string, opts *Opts, s *Settings) (Provider, error) {
+	if opts.Scrape || opts.VersionProbe != "" || opts.VersionCmd != "" {
+		return nil, fmt.Errorf("the %s provider can't be used with scraping, version probing or a version command", staticSiteID)
+	}
+	i := strings.Index(u, "{version}")
+	if i < 0 {
+		return nil, fmt.Errorf("the %s provider requires a {version} placeholder in the URL, i.e. https://dl.example.com/tool/{version}/", staticSiteID)
+	}
+	root := u[:strings.LastIndex(u[:i], "/")+1]
+
+	versionURL := opts.VersionURL
+	if versionURL == "" {
+		versionURL = root + staticSitePointer
+	}
+	gopts := *opts
+	gopts.VersionURL = versionURL
+	p, err := newGeneric(u, &gopts, s)
+	if err != nil {
+		return nil, err
+	}
+	return &staticSite{generic: p.(*generic), root: root}, nil
+}
+
+func (s *staticSite) Fetch(opts *FetchOpts) (*File, error) {
+	version := opts.Version
+	if version == "" {
+		var err error
+		if version, _, err = s.GetLatestVersion(); err != nil {
+			return nil, err
+		}
+	}
+
+	u := expandTemplate(s.url, version)
+	var candidates []*assets.Asset
+	var err error
+	if strings.HasSuffix(u, "/") {
+		candidates, err = s.listing(u)
+	} else {
+		candidates, err = s.templated(u)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f := assets.NewFilter(&assets.FilterOpts{SkipScoring: opts.All, PackagePath: opts.PackagePath, SkipPathCheck: opts.SkipPatchCheck, PackageName: opts.PackageName, HTTPClient: s.client, Cache: opts.Cache, Formats: opts.Formats, Selected: opts.SelectedAsset, SideFiles: opts.SideFiles, RequireChecksum: opts.RequireChecksum, Files: opts.Files, Entries: opts.Entries, SelectMultiple: opts.SelectMultiple, Completions: opts.Completions, Manpages: opts.Manpages, Mirrors: expandMirrors(opts.Mirrors, version), MirrorFirst: opts.MirrorFirst, SigningKeys: opts.SigningKeys, RequireSignature: opts.RequireSignature, Relax: opts.Relax, Rewrites: rewritesFor(opts.AssetRewrites, version)})
+	gf, err := f.FilterAssets(s.name(), candidates)
+	if err != nil {
+		return nil, err
+	}
+	gf.ExtraHeaders = s.headers
+
+	outFile, err := f.ProcessURL(gf)
+	if err != nil {
+		return nil, err
+	}
+	if f.Verified() == "" {
+		log.WithField("asset", gf.Name).WithField("url", path.Dir(gf.URL)).Warn("No checksum found, the asset couldn't be verified")
+	}
+
+	file := &File{Data: outFile.Source, Name: outFile.Name, Version: version, PackagePath: outFile.PackagePath, VerifiedWith: f.Verified(), Asset: outFile.Asset, AssetSize: outFile.AssetSize, AssetSHA256: outFile.AssetSHA256, AssetURL: outFile.AssetURL, Emulated: f.Emulated(), OtherEntries: f.OtherEntries(), Extras: f.Extras(), Downloaded: f.Downloaded(), Entry: f.Entry(), SignedBy: f.SignedBy(), SignedFile: f.SignedFile()}
+	if f.Picked() {
+		file.SelectedAsset = assets.SelectionPattern(gf.Name, version)
+	}
+	return file, nil
+}
+
+// listing returns the files of the version directory
+func (s *staticSite) listing(dir string) ([]*assets.Asset, error) {
+	log.Debugf("Listing the files of %s", dir)
+	req, err := s.newRequest(http.MethodGet, dir)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%d response when listing %s, use the URL of the file with placeholders if the server doesn't list directories", res.StatusCode, dir)
+	}
+	page, err := url.Parse(dir)
+	if err != nil {
+		return nil, err
+	}
+	links, err := fileLinks(res.Body, page)
+	if err != nil {
+		return nil, err
+	}
+	candidates := []*assets.Asset{}
+	for _, l := range links {
+		candidates = append(candidates, &assets.Asset{Name: path.Base(l.Path), URL: l.String(), NameFromURL: true})
+	}
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no file found in the listing of %s", dir)
+	}
+	return candidates, nil
+}
+
+// templated returns the file of the template along
+// the checksum files found in its directory
+func (s *staticSite) templated(u string) ([]*assets.Asset, error) {
+	dir, name := u[:strings.LastIndex(u, "/")+1], path.Base(u)
+	candidates := []*assets.Asset{{Name: name, URL: u, NameFromURL: true}}
+	for _, c := range staticSiteChecksums {
+		c = strings.ReplaceAll(c, "{asset}", name)
+		if s.exists(dir + c) {
+			candidates = append(candidates, &assets.Asset{Name: c, URL: dir + c, NameFromURL: true})
+		}
+	}
+	return candidates, nil
+}
+
+// exists checks whether the server has the file
+func (s *staticSite) exists(u string) bool {
+	req, err := s.newRequest(http.MethodHead, u)
+	if err != nil {
+		return false
+	}
+	res, err := s.client.Do(req)
+	if err != nil {
+		return false
+	}
+	res.Body.Close()
+	return res.StatusCode == http.StatusOK
+}
+
+// name guesses the name of the binary from the root of the site
+func (s *staticSite) name() string {
+	pu, err := url.Parse(s.root)
+	if err != nil {
+		return ""
+	}
+	return path.Base(strings.TrimSuffix(pu.Path, "/"))
+}
+
+func (s *staticSite) GetID() string {
+	return staticSiteID
+}
+
+// Capabilities of the static-site provider, it only knows the latest version
+func (s *staticSite) Capabilities() Capabilities {
+	return NewCapabilities(CapChecksums, CapAssetSizes)
+}
@@ -0,0 +1,106 @@
+package providers
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// TestStaticSite fetches from servers laid out like the
+// download sites of real tools using per-version directories
+func TestStaticSite(t *testing.T) {
+	bin := []byte("#!/bin/sh\necho tool\n")
+	var tgz bytes.Buffer
+	gw := gzip.NewWriter(&tgz)
+	tw := tar.NewWriter(gw)
+	if err := tw.WriteHeader(&tar.Header{Name: "node", Mode: 0o755, Size: int64(len(bin)), Typeflag: tar.TypeReg}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(bin)
+	tw.Close()
+	gw.Close()
+
+	nodeAsset := fmt.Sprintf("node-v20.11.0-%s-%s.tar.gz", runtime.GOOS, runtime.GOARCH)
+	files := map[string]string{
+		// dl.k8s.io: stable.txt at the root and a digest only
+		// checksum file next to every binary, no listing
+		"/release/stable.txt": "v1.30.2\n",
+		"/release/v1.30.2/bin/" + runtime.GOOS + "/" + runtime.GOARCH + "/kubectl":        string(bin),
+		"/release/v1.30.2/bin/" + runtime.GOOS + "/" + runtime.GOARCH + "/kubectl.sha256": fmt.Sprintf("%x", sha256.Sum256(bin)),
+		"/tampered/stable.txt": "v1.30.2\n",
+		"/tampered/v1.30.2/bin/" + runtime.GOOS + "/" + runtime.GOARCH + "/kubectl":        string(bin),
+		"/tampered/v1.30.2/bin/" + runtime.GOOS + "/" + runtime.GOARCH + "/kubectl.sha256": strings.Repeat("0", 64),
+
+		// nodejs.org/dist: a JSON index of the versions and
+		// a listing of every version with its SHASUMS256.txt
+		"/dist/index.json": `[{"version": "v20.11.0"}, {"version": "v20.10.0"}]`,
+		"/dist/v20.11.0/": `<html><body><a href="../">../</a>
+<a href="` + nodeAsset + `">` + nodeAsset + `</a>
+<a href="node-v20.11.0-aix-ppc64.tar.gz">node-v20.11.0-aix-ppc64.tar.gz</a>
+<a href="SHASUMS256.txt">SHASUMS256.txt</a>
+</body></html>`,
+		"/dist/v20.11.0/" + nodeAsset:                   tgz.String(),
+		"/dist/v20.11.0/node-v20.11.0-aix-ppc64.tar.gz": tgz.String(),
+		"/dist/v20.11.0/SHASUMS256.txt":                 fmt.Sprintf("%x  node-v20.11.0-aix-ppc64.tar.gz\n%x  %s\n", sha256.Sum256(tgz.Bytes()), sha256.Sum256(tgz.Bytes()), nodeAsset),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := files[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer srv.Close()
+
+	cases := []struct {
+		name    string
+		url     string
+		opts    *Opts
+		version string
+		err     string
+	}{
+		{"kubectl", srv.URL + "/release/{version}/bin/{os}/{arch}/kubectl", &Opts{}, "v1.30.2", ""},
+		{"node", srv.URL + "/dist/{version}/", &Opts{VersionURL: srv.URL + "/dist/index.json", VersionJSONPath: "[0].version"}, "v20.11.0", ""},
+		{"tampered", srv.URL + "/tampered/{version}/bin/{os}/{arch}/kubectl", &Opts{}, "", "checksum"},
+		{"no listing", srv.URL + "/release/{version}/bin/", &Opts{}, "", "doesn't list directories"},
+	}
+	for _, c := range cases {
+		c.opts.Provider = staticSiteID
+		p, err := New(c.url, c.opts)
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if p.GetID() != staticSiteID {
+			t.Fatalf("%s: expected the %s provider, got %s", c.name, staticSiteID, p.GetID())
+		}
+		f, err := p.Fetch(&FetchOpts{})
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: expected an error containing %q, got %v", c.name, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", c.name, err)
+		}
+		if f.Version != c.version || f.VerifiedWith != "sha256" {
+			t.Errorf("%s: expected version %s verified with sha256, got %s verified with %q", c.name, c.version, f.Version, f.VerifiedWith)
+		}
+		if b, _ := io.ReadAll(f.Data); !bytes.Equal(b, bin) {
+			t.Errorf("%s: unexpected content %q", c.name, b)
+		}
+	}
+
+	if _, err := New("https://dl.example.com/tool/stable/", &Opts{Provider: staticSiteID}); err == nil {
+		t.Error("expected an error without a {version} placeholder")
+	}
+}
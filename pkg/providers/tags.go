@@ -0,0 +1,67 @@
+package providers
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// TagFilter selects the release tags that belong to a single
+// component of a repository. Monorepos usually tag their releases
+// as `component/v1.2.3` or `cli-v0.9.0`, so the "latest" release
+// of the repository might belong to a different component.
+type TagFilter struct {
+	prefix  string
+	pattern *regexp.Regexp
+}
+
+// NewTagFilter returns a filter for the given prefix and pattern.
+// It returns nil if both of them are empty, meaning that every tag
+// is accepted.
+func NewTagFilter(prefix, pattern string) (*TagFilter, error) {
+	if prefix == "" && pattern == "" {
+		return nil, nil
+	}
+
+	tf := &TagFilter{prefix: prefix}
+	if pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid tag pattern %q: %w", pattern, err)
+		}
+		tf.pattern = re
+	}
+
+	return tf, nil
+}
+
+// Match checks if the tag belongs to the component selected
+// by the filter
+func (t *TagFilter) Match(tag string) bool {
+	if t == nil {
+		return true
+	}
+	if t.prefix != "" && !strings.HasPrefix(tag, t.prefix) {
+		return false
+	}
+	if t.pattern != nil && !t.pattern.MatchString(tag) {
+		return false
+	}
+	return true
+}
+
+// Version strips the component information from the tag so it
+// can be compared as a regular version. If the pattern has a
+// capture group, its first match is used as the version.
+func (t *TagFilter) Version(tag string) string {
+	if t == nil {
+		return tag
+	}
+	v := strings.TrimPrefix(tag, t.prefix)
+	if t.pattern != nil && t.pattern.NumSubexp() > 0 {
+		if m := t.pattern.FindStringSubmatch(tag); len(m) > 1 && m[1] != "" {
+			v = m[1]
+		}
+	}
+	return v
+}
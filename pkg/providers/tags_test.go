@@ -0,0 +1,41 @@
+package providers
+
+import "testing"
+
+func TestTagFilter(t *testing.T) {
+	cases := []struct {
+		name            string
+		prefix, pattern string
+		tag             string
+		match           bool
+		version         string
+	}{
+		{name: "no filter", tag: "v1.2.3", match: true, version: "v1.2.3"},
+		{name: "prefix match", prefix: "protoc-gen-openapiv2/", tag: "protoc-gen-openapiv2/v2.1.0", match: true, version: "v2.1.0"},
+		{name: "prefix mismatch", prefix: "protoc-gen-openapiv2/", tag: "v2.1.0", match: false, version: "v2.1.0"},
+		{name: "dashed prefix", prefix: "cli-", tag: "cli-v0.9.0", match: true, version: "v0.9.0"},
+		{name: "pattern match", pattern: `^cli-v\d+`, tag: "cli-v0.9.0", match: true, version: "cli-v0.9.0"},
+		{name: "pattern mismatch", pattern: `^cli-v\d+`, tag: "server-v0.9.0", match: false, version: "server-v0.9.0"},
+		{name: "pattern capture group", pattern: `^cli-(v.+)$`, tag: "cli-v0.9.0", match: true, version: "v0.9.0"},
+		{name: "prefix and pattern", prefix: "cli-", pattern: `-v1\.`, tag: "cli-v1.0.0", match: true, version: "v1.0.0"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			tf, err := NewTagFilter(c.prefix, c.pattern)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m := tf.Match(c.tag); m != c.match {
+				t.Errorf("expected match to be %v, got %v", c.match, m)
+			}
+			if v := tf.Version(c.tag); v != c.version {
+				t.Errorf("expected version %s, got %s", c.version, v)
+			}
+		})
+	}
+
+	if _, err := NewTagFilter("", "("); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
@@ -0,0 +1,74 @@
+package providers
+
+import (
+	"runtime"
+	"strings"
+)
+
+// templatePlaceholders are resolved in the URLs of the generic
+// provider, so the same configuration works on every platform
+var templatePlaceholders = []string{"{version}", "{os}", "{arch}", "{ext}", "{os_alt}", "{arch_alt}"}
+
+// osAliases and archAliases hold the names some
+// projects use instead of Go's ones
+var (
+	osAliases   = map[string]string{"darwin": "macos", "windows": "win"}
+	archAliases = map[string]string{"amd64": "x86_64", "arm64": "aarch64", "386": "i386"}
+)
+
+// goos and goarch can be changed by tests
+var goos, goarch = runtime.GOOS, runtime.GOARCH
+
+// SetPlatform resolves the placeholders of the URLs for
+// another platform, i.e. to download the assets of another machine
+func SetPlatform(os, arch string) {
+	goos, goarch = os, arch
+}
+
+// IsTemplate reports whether the URL has placeholders. Templates are
+// stored as is and resolved every time they're used
+func IsTemplate(u string) bool {
+	for _, p := range templatePlaceholders {
+		if strings.Contains(u, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandTemplate resolves the placeholders of the URL
+// for the given version and the running platform
+func expandTemplate(u, version string) string {
+	ext := "tar.gz"
+	if goos == "windows" {
+		ext = "zip"
+	}
+	return strings.NewReplacer(
+		"{version}", version,
+		"{os}", goos,
+		"{arch}", goarch,
+		"{ext}", ext,
+		"{os_alt}", alias(osAliases, goos),
+		"{arch_alt}", alias(archAliases, goarch),
+	).Replace(u)
+}
+
+// expandMirrors resolves the placeholders of the mirror
+// templates but {asset}, which is resolved once it's picked
+func expandMirrors(mirrors []string, version string) []string {
+	if len(mirrors) == 0 {
+		return nil
+	}
+	out := make([]string, len(mirrors))
+	for i, m := range mirrors {
+		out[i] = expandTemplate(m, version)
+	}
+	return out
+}
+
+func alias(aliases map[string]string, v string) string {
+	if a, ok := aliases[v]; ok {
+		return a
+	}
+	return v
+}
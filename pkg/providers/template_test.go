@@ -0,0 +1,51 @@
+package providers
+
+import "testing"
+
+func TestExpandTemplate(t *testing.T) {
+	defer func(os, arch string) { goos, goarch = os, arch }(goos, goarch)
+
+	const template = "https://dl.example.com/{version}/tool_{version}_{os}_{arch}.{ext}"
+	cases := []struct {
+		os, arch string
+		template string
+		out      string
+	}{
+		{"linux", "amd64", template, "https://dl.example.com/1.2.3/tool_1.2.3_linux_amd64.tar.gz"},
+		{"windows", "arm64", template, "https://dl.example.com/1.2.3/tool_1.2.3_windows_arm64.zip"},
+		{"darwin", "amd64", "https://dl.example.com/tool-{os_alt}-{arch_alt}", "https://dl.example.com/tool-macos-x86_64"},
+		{"linux", "arm64", "https://dl.example.com/tool-{os_alt}-{arch_alt}", "https://dl.example.com/tool-linux-aarch64"},
+	}
+
+	for _, c := range cases {
+		goos, goarch = c.os, c.arch
+		if out := expandTemplate(c.template, "1.2.3"); out != c.out {
+			t.Errorf("%s/%s: expected %s, got %s", c.os, c.arch, c.out, out)
+		}
+	}
+
+	for u, expected := range map[string]bool{
+		template:                                   true,
+		"https://dl.example.com/tool-{os}":         true,
+		"https://dl.example.com/tool-linux-amd64":  false,
+		"https://github.com/owner/repo/releases/x": false,
+	} {
+		if IsTemplate(u) != expected {
+			t.Errorf("%s: expected IsTemplate to be %v", u, expected)
+		}
+	}
+}
+
+func TestExpandMirrors(t *testing.T) {
+	defer func(os, arch string) { goos, goarch = os, arch }(goos, goarch)
+	goos, goarch = "linux", "amd64"
+
+	out := expandMirrors([]string{"https://mirror.example.com/tool/{version}/{asset}", "https://cache.example.com/{os}/{arch}/{asset}"}, "1.2.3")
+	expected := []string{"https://mirror.example.com/tool/1.2.3/{asset}", "https://cache.example.com/linux/amd64/{asset}"}
+	if len(out) != len(expected) || out[0] != expected[0] || out[1] != expected[1] {
+		t.Errorf("expected %q, got %q", expected, out)
+	}
+	if out := expandMirrors(nil, "1.2.3"); out != nil {
+		t.Errorf("expected no mirrors, got %q", out)
+	}
+}
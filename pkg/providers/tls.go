@@ -0,0 +1,109 @@
+package providers
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultMinTLSVersion is enforced for every host
+// without an explicit policy
+const defaultMinTLSVersion = tls.VersionTLS12
+
+// DefaultMinTLSVersion is the minimum TLS version
+// accepted from the hosts without a configured one
+const DefaultMinTLSVersion = "1.2"
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ParseTLSVersion parses versions like 1.2
+func ParseTLSVersion(v string) (uint16, error) {
+	tv, ok := tlsVersions[strings.TrimPrefix(strings.ToUpper(v), "TLS")]
+	if !ok {
+		return 0, fmt.Errorf("invalid TLS version %q, must be one of 1.0, 1.1, 1.2 or 1.3", v)
+	}
+	return tv, nil
+}
+
+// tlsPolicy routes the requests to a transport enforcing the
+// minimum TLS version configured for their host
+type tlsPolicy struct {
+	base *http.Transport
+	min  uint16
+
+	mu         sync.Mutex
+	hosts      map[string]uint16
+	transports map[string]*http.Transport
+}
+
+func (p *tlsPolicy) RoundTrip(r *http.Request) (*http.Response, error) {
+	t, min := p.transport(r.URL.Hostname())
+	res, err := t.RoundTrip(r)
+	if err != nil && isHandshakeError(err) {
+		return nil, legacyTLSError(r.URL.Host, min, err)
+	}
+	return res, err
+}
+
+// transport returns the transport for the host
+// along with its minimum TLS version
+func (p *tlsPolicy) transport(host string) (*http.Transport, uint16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	min, ok := p.hosts[host]
+	if !ok {
+		return p.base, p.min
+	}
+	t, ok := p.transports[host]
+	if !ok {
+		t = p.base.Clone()
+		t.TLSClientConfig = p.base.TLSClientConfig.Clone()
+		t.TLSClientConfig.MinVersion = min
+		p.transports[host] = t
+	}
+	return t, min
+}
+
+// setHost overrides the minimum TLS version of a single host
+func (p *tlsPolicy) setHost(host string, min uint16) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.hosts[host] = min
+	delete(p.transports, host)
+}
+
+func isHandshakeError(err error) bool {
+	var alert tls.AlertError
+	var rec tls.RecordHeaderError
+	return errors.As(err, &alert) || errors.As(err, &rec) ||
+		strings.Contains(err.Error(), "protocol version not supported") ||
+		strings.Contains(err.Error(), "handshake failure")
+}
+
+// legacyTLSError explains a failed handshake by checking
+// which TLS version the server actually offers
+func legacyTLSError(host string, min uint16, err error) error {
+	if !strings.Contains(host, ":") {
+		host += ":443"
+	}
+	offered := "an unknown TLS version"
+	d := &net.Dialer{Timeout: 5 * time.Second}
+	// the connection is only used to find out the version
+	// offered by the server, nothing is sent through it
+	// nolint: gosec
+	if conn, derr := tls.DialWithDialer(d, "tcp", host, &tls.Config{MinVersion: tls.VersionTLS10, InsecureSkipVerify: true}); derr == nil {
+		offered = tls.VersionName(conn.ConnectionState().Version)
+		conn.Close()
+	}
+	return fmt.Errorf("TLS handshake with %s failed, %s is required and the server offers %s. Use min_tls_version or --allow-legacy-tls for this source if it can't be upgraded: %w", host, tls.VersionName(min), offered, err)
+}
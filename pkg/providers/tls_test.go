@@ -0,0 +1,58 @@
+package providers
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLegacyTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "v1.2.3")
+	}))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS11}
+	srv.StartTLS()
+	defer srv.Close()
+
+	newSettings := func() *Settings {
+		s := NewSettings(true, nil)
+		// trust the self-signed certificate of the test server
+		s.tls.base.TLSClientConfig.InsecureSkipVerify = true
+		return s
+	}
+
+	s := newSettings()
+	p, err := New("https://dl.example.com/{version}/tool", &Opts{VersionURL: srv.URL, Settings: s})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _, err = p.GetLatestVersion()
+	if err == nil || !strings.Contains(err.Error(), "TLS 1.2 is required and the server offers TLS 1.1") {
+		t.Fatalf("expected a legacy TLS error, got %v", err)
+	}
+
+	s = newSettings()
+	p, err = New("https://dl.example.com/{version}/tool", &Opts{VersionURL: srv.URL, Settings: s, MinTLSVersion: "1.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v, _, err := p.GetLatestVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != "v1.2.3" {
+		t.Fatalf("expected v1.2.3, got %s", v)
+	}
+
+	// the override is scoped to the hosts of the binary
+	if _, min := s.tls.transport("example.com"); min != tls.VersionTLS12 {
+		t.Errorf("expected other hosts to require TLS 1.2, got %s", tls.VersionName(min))
+	}
+
+	if err := s.SetMinTLSVersion("1.4"); err == nil {
+		t.Error("expected an error for an invalid TLS version")
+	}
+}
@@ -0,0 +1,139 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// redactedHeaders hold credentials which must never be traced
+var redactedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Proxy-Authorization": true,
+	"Private-Token":       true,
+	"Job-Token":           true,
+	"Cookie":              true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// redactedParams are query parameters holding credentials, i.e.
+// the signatures of the presigned S3, GCS and Azure URLs the asset
+// downloads are redirected to. They're matched case insensitively.
+var redactedParams = []string{
+	"token", "access_token", "private_token", "client_secret", "jwt",
+	"x-amz-signature", "x-amz-credential", "x-amz-security-token",
+	"x-goog-signature", "x-goog-credential", "signature", "sig",
+}
+
+// tracer logs the requests and responses going through it
+type tracer struct {
+	next    http.RoundTripper
+	w       io.Writer
+	bodies  bool
+	maxBody int
+
+	mu sync.Mutex
+}
+
+func (t *tracer) RoundTrip(r *http.Request) (*http.Response, error) {
+	start := time.Now()
+	res, err := t.next.RoundTrip(r)
+	elapsed := time.Since(start).Round(time.Millisecond)
+
+	var b strings.Builder
+	// the redirects go to presigned URLs, none of their
+	// query values are traced
+	fmt.Fprintf(&b, "--> %s %s\n", r.Method, redactURL(r.URL, r.Response != nil))
+	writeHeaders(&b, r.Header)
+	if err != nil {
+		fmt.Fprintf(&b, "<-- error: %v (%s)\n", err, elapsed)
+		t.write(b.String())
+		return res, err
+	}
+
+	fmt.Fprintf(&b, "<-- %s (%s)\n", res.Status, elapsed)
+	writeHeaders(&b, res.Header)
+	if t.bodies && isVersionResponse(r, res) {
+		peek := make([]byte, t.maxBody+1)
+		n, _ := io.ReadFull(res.Body, peek)
+		res.Body = readCloser{io.MultiReader(bytes.NewReader(peek[:n]), res.Body), res.Body}
+		body := peek[:n]
+		if n > t.maxBody {
+			body = append(peek[:t.maxBody:t.maxBody], "..."...)
+		}
+		fmt.Fprintf(&b, "%s\n", body)
+	}
+
+	t.write(b.String())
+	return res, nil
+}
+
+func (t *tracer) write(s string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprint(t.w, s)
+}
+
+type readCloser struct {
+	io.Reader
+	io.Closer
+}
+
+func writeHeaders(b *strings.Builder, h http.Header) {
+	keys := make([]string, 0, len(h))
+	for k := range h {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := strings.Join(h[k], ", ")
+		switch {
+		case redactedHeaders[http.CanonicalHeaderKey(k)]:
+			v = "[REDACTED]"
+		case http.CanonicalHeaderKey(k) == "Location", http.CanonicalHeaderKey(k) == "Referer":
+			if u, err := url.Parse(v); err == nil {
+				v = redactURL(u, true)
+			}
+		}
+		fmt.Fprintf(b, "    %s: %s\n", k, v)
+	}
+}
+
+// redactURL returns the URL without its credentials, all
+// the values of its query are redacted when all is set
+func redactURL(u *url.URL, all bool) string {
+	ru := *u
+	if ru.User != nil {
+		ru.User = url.User("REDACTED")
+	}
+	if ru.RawQuery == "" {
+		return ru.String()
+	}
+	q := ru.Query()
+	for k := range q {
+		if all || slices.Contains(redactedParams, strings.ToLower(k)) {
+			q.Set(k, "REDACTED")
+		}
+	}
+	ru.RawQuery = q.Encode()
+	return ru.String()
+}
+
+// isVersionResponse tells API and version endpoints responses
+// apart from binary downloads, whose body is never traced
+func isVersionResponse(r *http.Request, res *http.Response) bool {
+	if r.Header.Get("Accept") == "application/octet-stream" || r.Header.Get("Range") != "" {
+		return false
+	}
+	ct, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return strings.HasPrefix(ct, "text/") || strings.HasSuffix(ct, "json") || strings.HasSuffix(ct, "xml")
+}
@@ -0,0 +1,86 @@
+package providers
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTraceHTTP(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/asset" {
+			// the redirect of the asset downloads to the storage
+			http.Redirect(w, r, "/download?X-Amz-Credential=secret-cred&X-Amz-Signature=secret-sig&response-content-type=secret-type", http.StatusFound)
+			return
+		}
+		if r.URL.Path == "/download" {
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "binary content")
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Set-Cookie", "session=secret-cookie")
+		fmt.Fprint(w, `{"tag_name":"v1.2.3","body":"a long release description"}`)
+	}))
+	defer srv.Close()
+
+	var out bytes.Buffer
+	s := NewSettings(true, nil)
+	s.Trace(&out, true, 20)
+	client := s.HTTPClient()
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/releases/latest?access_token=secret-param", nil)
+	req.Header.Set("Authorization", "token secret-token")
+	req.Header.Set("Private-Token", "secret-gitlab")
+	res, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if !strings.HasSuffix(string(body), `description"}`) {
+		t.Errorf("the traced body must be left untouched, got %s", body)
+	}
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/download", nil)
+	req.Header.Set("Accept", "application/octet-stream")
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	req, _ = http.NewRequest(http.MethodGet, srv.URL+"/asset?sig=secret-azure", nil)
+	req.Header.Set("Accept", "application/octet-stream")
+	res, err = client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	trace := out.String()
+	if strings.Contains(trace, "secret") {
+		t.Errorf("credentials leaked in the trace:\n%s", trace)
+	}
+	for _, expected := range []string{
+		"--> GET " + srv.URL + "/releases/latest?access_token=REDACTED",
+		"Authorization: [REDACTED]",
+		"<-- 200 OK",
+		`{"tag_name":"v1.2.3"...`,
+		"--> GET " + srv.URL + "/download",
+		"--> GET " + srv.URL + "/asset?sig=REDACTED",
+		"Location: /download?X-Amz-Credential=REDACTED&X-Amz-Signature=REDACTED&response-content-type=REDACTED",
+		"--> GET " + srv.URL + "/download?X-Amz-Credential=REDACTED&X-Amz-Signature=REDACTED&response-content-type=REDACTED",
+	} {
+		if !strings.Contains(trace, expected) {
+			t.Errorf("expected %q in the trace:\n%s", expected, trace)
+		}
+	}
+	if strings.Contains(trace, "binary content") {
+		t.Errorf("download bodies must not be traced:\n%s", trace)
+	}
+}
@@ -0,0 +1,41 @@
+// Package schema holds the JSON schemas of the machine-readable
+// outputs of the commands (`--json`). Consumers can rely on their
+// shape as long as the schema version doesn't change: adding optional
+// fields is fine, anything else requires bumping Version.
+package schema
+
+import (
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Version is the version of the schemas, it's included
+// as `schema_version` in every JSON output
+const Version = 1
+
+//go:embed schemas/*.json
+var schemas embed.FS
+
+// Commands returns the commands whose
+// JSON output is described by a schema
+func Commands() []string {
+	entries, _ := schemas.ReadDir("schemas")
+	commands := make([]string, 0, len(entries))
+	for _, e := range entries {
+		commands = append(commands, strings.TrimSuffix(e.Name(), ".json"))
+	}
+	sort.Strings(commands)
+	return commands
+}
+
+// Get returns the schema of the JSON output of the command
+func Get(command string) ([]byte, error) {
+	b, err := schemas.ReadFile(path.Join("schemas", command+".json"))
+	if err != nil {
+		return nil, fmt.Errorf("no schema for %q, available ones are: %s", command, strings.Join(Commands(), ", "))
+	}
+	return b, nil
+}
@@ -0,0 +1,32 @@
+package schema
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestGet(t *testing.T) {
+	for _, c := range Commands() {
+		b, err := Get(c)
+		if err != nil {
+			t.Fatalf("getting the schema of %s: %v", c, err)
+		}
+		var s struct {
+			Properties struct {
+				SchemaVersion struct {
+					Const int `json:"const"`
+				} `json:"schema_version"`
+			} `json:"properties"`
+		}
+		if err := json.Unmarshal(b, &s); err != nil {
+			t.Fatalf("parsing the schema of %s: %v", c, err)
+		}
+		if s.Properties.SchemaVersion.Const != Version {
+			t.Fatalf("expected the schema of %s to require schema_version %d, got %d", c, Version, s.Properties.SchemaVersion.Const)
+		}
+	}
+
+	if _, err := Get("nope"); err == nil {
+		t.Fatal("expected an error for an unknown command")
+	}
+}
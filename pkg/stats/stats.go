@@ -0,0 +1,160 @@
+// Package stats records locally how often the managed binaries are
+// executed, nothing is ever transmitted. A shim placed at the path of
+// the binary appends a byte to a counter file and execs the real one,
+// so the size of the counter is the number of runs and its
+// modification time the last one.
+package stats
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// marker identifies the shims written by bin
+const marker = "# bin stats shim"
+
+// realDir is where the real binaries are moved, next to the shim
+// so the move is a rename and argv[0] keeps the same base name
+const realDir = ".bin-real"
+
+// Shims installs the exec counting shims, the counters
+// are stored in dir
+type Shims struct {
+	dir string
+}
+
+// Usage is the recorded usage of a binary
+type Usage struct {
+	Runs int64
+	// LastRun is the time of the last execution or, if the binary
+	// was never run, the time the shim was installed
+	LastRun time.Time
+}
+
+// New returns the shims recording their counters in dir
+func New(dir string) *Shims {
+	return &Shims{dir: dir}
+}
+
+// RealPath returns where the real binary of the shim at path lives
+func RealPath(path string) string {
+	return filepath.Join(filepath.Dir(path), realDir, filepath.Base(path))
+}
+
+// IsShim checks if the file at path is a shim
+func IsShim(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+	head := make([]byte, 64)
+	n, _ := f.Read(head)
+	return bytes.Contains(head[:n], []byte(marker))
+}
+
+// Resolve returns the path of the real binary if path is a shim
+func Resolve(path string) string {
+	if IsShim(path) {
+		return RealPath(path)
+	}
+	return path
+}
+
+// counter returns the counter file of the binary at path, the
+// hash of the path avoids collisions between directories
+func (s *Shims) counter(path string) string {
+	h := sha256.Sum256([]byte(path))
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%x", filepath.Base(path), h[:4]))
+}
+
+// Enable moves the binary at path out of the way and installs a
+// shim counting its executions in its place
+func (s *Shims) Enable(path string) error {
+	if runtime.GOOS == "windows" {
+		return errors.New("usage statistics are not supported on windows")
+	}
+	if IsShim(path) {
+		return nil
+	}
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return err
+	}
+	counter := s.counter(path)
+	f, err := os.OpenFile(counter, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+
+	real := RealPath(path)
+	if err := os.MkdirAll(filepath.Dir(real), 0o755); err != nil {
+		return err
+	}
+	if err := os.Rename(path, real); err != nil {
+		return err
+	}
+
+	// exec replaces the shell so the arguments, exit code and
+	// signals are the ones of the real binary
+	shim := fmt.Sprintf("#!/bin/sh\n%s\nprintf . >> %s 2>/dev/null\nexec %s \"$@\"\n", marker, quote(counter), quote(real))
+	tmp := path + ".bin-shim"
+	if err := os.WriteFile(tmp, []byte(shim), 0o755); err != nil {
+		return errors.Join(err, os.Rename(real, path))
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Join(err, os.Remove(tmp), os.Rename(real, path))
+	}
+	return nil
+}
+
+// Disable moves the real binary back in place of the shim
+func (s *Shims) Disable(path string) error {
+	if !IsShim(path) {
+		return nil
+	}
+	if err := os.Rename(RealPath(path), path); err != nil {
+		return err
+	}
+	// the directory is shared with the other binaries
+	_ = os.Remove(filepath.Dir(RealPath(path)))
+	return nil
+}
+
+// Remove deletes the shim of the binary at path, if any, with
+// its real binary
+func (s *Shims) Remove(path string) error {
+	if !IsShim(path) {
+		return nil
+	}
+	if err := os.Remove(RealPath(path)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	_ = os.Remove(filepath.Dir(RealPath(path)))
+	return os.Remove(s.counter(path))
+}
+
+// Usage returns the recorded usage of the binary at path,
+// ok is false if it has no shim
+func (s *Shims) Usage(path string) (u Usage, ok bool, err error) {
+	if !IsShim(path) {
+		return u, false, nil
+	}
+	fi, err := os.Stat(s.counter(path))
+	if err != nil {
+		return u, false, err
+	}
+	return Usage{Runs: fi.Size(), LastRun: fi.ModTime()}, true, nil
+}
+
+// quote returns s as a single quoted shell word
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
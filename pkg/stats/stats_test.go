@@ -0,0 +1,61 @@
+package stats
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestShims(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shims are not supported on windows")
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bin", "it's")
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	real := "#!/bin/sh\necho \"$(basename \"$0\")|$1|$2\"\nexit 3\n"
+	if err := os.WriteFile(path, []byte(real), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	s := New(filepath.Join(dir, "stats"))
+	if err := s.Enable(path); err != nil {
+		t.Fatal(err)
+	}
+	if !IsShim(path) {
+		t.Fatal("expected a shim to be installed")
+	}
+	if u, ok, err := s.Usage(path); err != nil || !ok || u.Runs != 0 {
+		t.Fatalf("expected no runs, got %v %v %v", u, ok, err)
+	}
+
+	for i := 0; i < 2; i++ {
+		out, err := exec.Command(path, "a b", "").Output()
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) || exitErr.ExitCode() != 3 {
+			t.Fatalf("expected exit code 3, got %v", err)
+		}
+		if string(out) != "it's|a b|\n" {
+			t.Fatalf("unexpected output %q", out)
+		}
+	}
+	if u, _, _ := s.Usage(path); u.Runs != 2 {
+		t.Errorf("expected 2 runs, got %d", u.Runs)
+	}
+
+	if err := s.Disable(path); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil || string(b) != real {
+		t.Fatalf("expected the real binary to be restored, got %q %v", b, err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(path), realDir)); !os.IsNotExist(err) {
+		t.Errorf("expected %s to be removed", realDir)
+	}
+}
@@ -0,0 +1,280 @@
+// Package store keeps the installed versions of the binaries side by
+// side, as <dir>/<name>-<hash>/<version>/<name>, and points the path of
+// the binary to the active one. The hash is of the path of the binary,
+// so the binaries with the same name installed in different directories
+// don't share their versions. The path is a symlink into the store which
+// is flipped atomically, so the previous version stays untouched until
+// the new one is complete. Without symlinks (i.e. on windows) the active
+// version is copied in place and a sidecar file records which one it is.
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/marcosnils/bin/pkg/journal"
+	"github.com/marcosnils/bin/pkg/log"
+)
+
+// DefaultKeep is the number of versions kept per binary
+const DefaultKeep = 2
+
+// metaFile is written next to the stored versions, see Record
+const metaFile = ".bin-version.json"
+
+// symlinks is false where the symlinks aren't reliable, it's
+// a variable so the tests can run the copies on any platform
+var symlinks = runtime.GOOS != "windows"
+
+// Store keeps the versions of the binaries in dir, the copies
+// made without symlinks are journaled in journalDir
+type Store struct {
+	dir        string
+	journalDir string
+	keep       int
+}
+
+// sidecar records the version copied at the path of a binary
+type sidecar struct {
+	Version string `json:"version"`
+	Path    string `json:"path"`
+}
+
+// New returns the store in dir keeping the given number of
+// versions per binary, DefaultKeep when it's not positive
+func New(dir, journalDir string, keep int) *Store {
+	if keep <= 0 {
+		keep = DefaultKeep
+	}
+	return &Store{dir: dir, journalDir: journalDir, keep: keep}
+}
+
+// Path returns where the given version of the binary at path is stored
+func (s *Store) Path(path, version string) string {
+	return filepath.Join(s.entryDir(path), dirName(version), filepath.Base(path))
+}
+
+// entryDir returns the directory of the versions of the binary at path,
+// it's named after the binary followed by a hash of its path
+func (s *Store) entryDir(path string) string {
+	h := sha256.Sum256([]byte(filepath.Clean(path)))
+	return filepath.Join(s.dir, fmt.Sprintf("%s-%s", filepath.Base(path), hex.EncodeToString(h[:])[:12]))
+}
+
+// dirName returns the directory of the version, the
+// separators of the tags (i.e. cli/v1.2.0) are replaced
+func dirName(version string) string {
+	if version == "" {
+		return "unversioned"
+	}
+	return strings.NewReplacer("/", "_", `\`, "_", ":", "_").Replace(version)
+}
+
+// sidecarPath returns the metadata file of the copy at path
+func sidecarPath(path string) string {
+	return filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.bin-store.json", filepath.Base(path)))
+}
+
+// Activate points path to the stored version at src. The symlink is
+// created next to path and renamed over it, when it can't be created
+// the file is copied in place instead.
+func (s *Store) Activate(src, path string) error {
+	if symlinks {
+		tmp := filepath.Join(filepath.Dir(path), fmt.Sprintf(".%s.bin-%d", filepath.Base(path), time.Now().UnixNano()))
+		err := os.Symlink(src, tmp)
+		if err == nil {
+			if err := os.Rename(tmp, path); err != nil {
+				return errors.Join(err, os.Remove(tmp))
+			}
+			if err := os.Remove(sidecarPath(path)); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}
+		log.Debugf("Copying %s, it can't be symlinked: %v", path, err)
+	}
+	return s.copy(src, path)
+}
+
+// copy replaces the file at path with the one at src
+// and records it in the sidecar file
+func (s *Store) copy(src, path string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	tx, err := journal.Begin(s.journalDir)
+	if err != nil {
+		return err
+	}
+	out, err := tx.Stage(path, 0o755)
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	_, err = io.Copy(out, in)
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(sidecar{Version: filepath.Base(filepath.Dir(src)), Path: src})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(sidecarPath(path), b, 0o644)
+}
+
+// Active returns the stored version path points to, ok is false
+// when it's not a symlink into the store nor a recorded copy
+func (s *Store) Active(path string) (src string, ok bool) {
+	if target, err := os.Readlink(path); err == nil {
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(filepath.Dir(path), target)
+		}
+		return target, s.contains(target)
+	}
+	b, err := os.ReadFile(sidecarPath(path))
+	if err != nil {
+		return "", false
+	}
+	var sc sidecar
+	if err := json.Unmarshal(b, &sc); err != nil {
+		return "", false
+	}
+	return sc.Path, s.contains(sc.Path)
+}
+
+// contains checks if p is a binary of the store
+func (s *Store) contains(p string) bool {
+	rel, err := filepath.Rel(s.dir, p)
+	return err == nil && len(strings.Split(rel, string(filepath.Separator))) == 3 && !strings.HasPrefix(rel, "..")
+}
+
+// Version is a stored version of a binary
+type Version struct {
+	Version string
+	Path    string
+	// StoredAt is when the version was last installed
+	StoredAt time.Time
+	// Meta is what was recorded along the version, if any
+	Meta json.RawMessage
+}
+
+// Record writes metadata along the version stored at path
+func (s *Store) Record(path string, meta any) error {
+	b, err := json.Marshal(meta)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(filepath.Dir(path), metaFile), b, 0o644)
+}
+
+// Versions returns the stored versions of the binary at path, newest first
+func (s *Store) Versions(path string) ([]Version, error) {
+	dir, name := s.entryDir(path), filepath.Base(path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	res := []Version{}
+	for _, e := range entries {
+		p := filepath.Join(dir, e.Name(), name)
+		fi, err := os.Stat(p)
+		if !e.IsDir() || err != nil {
+			continue
+		}
+		meta, _ := os.ReadFile(filepath.Join(filepath.Dir(p), metaFile))
+		res = append(res, Version{Version: e.Name(), Path: p, StoredAt: fi.ModTime(), Meta: meta})
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if !res[i].StoredAt.Equal(res[j].StoredAt) {
+			return res[i].StoredAt.After(res[j].StoredAt)
+		}
+		return res[i].Version > res[j].Version
+	})
+	return res, nil
+}
+
+// Prune removes the versions of the binary at path beyond the ones
+// kept, the active one, which target points to, is never removed
+func (s *Store) Prune(path, target string) error {
+	versions, err := s.Versions(path)
+	if err != nil {
+		return err
+	}
+	active, _ := s.Active(target)
+	kept := 0
+	var errs []error
+	for _, v := range versions {
+		if v.Path == active || kept < s.keep {
+			kept++
+			continue
+		}
+		log.Debugf("Removing version %s of %s from the store", v.Version, path)
+		errs = append(errs, os.RemoveAll(filepath.Dir(v.Path)))
+	}
+	return errors.Join(errs...)
+}
+
+// Move moves the stored versions of the binary at from to the ones of
+// the binary at to, renaming them after it, once the binary was moved.
+// It returns where active, the version from pointed to, is stored once
+// moved. Nothing is moved when active isn't one of the versions of from.
+func (s *Store) Move(from, to, active string) (string, error) {
+	src, dst := s.entryDir(from), s.entryDir(to)
+	rel, err := filepath.Rel(src, active)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return active, nil
+	}
+	if _, err := os.Stat(dst); err == nil {
+		return "", fmt.Errorf("the store already has versions of %s", to)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return "", err
+	}
+	name := filepath.Base(to)
+	if old := filepath.Base(from); old != name {
+		entries, err := os.ReadDir(dst)
+		if err != nil {
+			return "", err
+		}
+		for _, e := range entries {
+			if err := os.Rename(filepath.Join(dst, e.Name(), old), filepath.Join(dst, e.Name(), name)); err != nil && !os.IsNotExist(err) {
+				return "", err
+			}
+		}
+	}
+	if err := os.Remove(sidecarPath(from)); err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	return filepath.Join(dst, filepath.Dir(rel), name), nil
+}
+
+// Remove deletes the stored versions of the binary at
+// path along with the sidecar file of the copy at target
+func (s *Store) Remove(path, target string) error {
+	if err := os.Remove(sidecarPath(target)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.RemoveAll(s.entryDir(path))
+}
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopies(t *testing.T) {
+	defer func(s bool) { symlinks = s }(symlinks)
+	symlinks = false
+
+	tmp := t.TempDir()
+	s := New(filepath.Join(tmp, "store"), filepath.Join(tmp, "journal"), 1)
+	path := filepath.Join(tmp, "tool")
+
+	for _, v := range []string{"v1.0.0", "cli/v1.1.0"} {
+		src := s.Path(path, v)
+		if err := os.MkdirAll(filepath.Dir(src), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(src, []byte(v), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Activate(src, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if b, _ := os.ReadFile(path); string(b) != "cli/v1.1.0" {
+		t.Fatalf("expected the last version to be copied, got %q", b)
+	}
+	if fi, err := os.Lstat(path); err != nil || !fi.Mode().IsRegular() {
+		t.Fatalf("expected a copy, got %v, %v", fi, err)
+	}
+	active, ok := s.Active(path)
+	if !ok || active != filepath.Join(s.entryDir(path), "cli_v1.1.0", "tool") {
+		t.Fatalf("expected the sidecar to record the active version, got %s", active)
+	}
+
+	if err := s.Prune(path, path); err != nil {
+		t.Fatal(err)
+	}
+	versions, err := s.Versions(path)
+	if err != nil || len(versions) != 1 || versions[0].Path != active {
+		t.Fatalf("expected only the active version to be kept, got %+v, %v", versions, err)
+	}
+
+	// the versions follow the binary when it's renamed
+	renamed := filepath.Join(tmp, "tl")
+	if err := os.Rename(path, renamed); err != nil {
+		t.Fatal(err)
+	}
+	moved, err := s.Move(path, renamed, active)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Activate(moved, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if a, ok := s.Active(renamed); !ok || a != filepath.Join(s.entryDir(renamed), "cli_v1.1.0", "tl") {
+		t.Fatalf("expected the moved version to be active, got %s", a)
+	}
+	if _, ok := s.Active(path); ok {
+		t.Fatal("expected the sidecar of the previous path to be removed")
+	}
+
+	if err := s.Remove(renamed, renamed); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Active(renamed); ok {
+		t.Fatal("expected the sidecar to be removed")
+	}
+}
+
+func TestSameName(t *testing.T) {
+	tmp := t.TempDir()
+	s := New(filepath.Join(tmp, "store"), filepath.Join(tmp, "journal"), 1)
+	a, b := filepath.Join(tmp, "a", "tool"), filepath.Join(tmp, "b", "tool")
+	for _, p := range []string{a, b} {
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		for _, v := range []string{"v1.0.0", "v1.1.0"} {
+			src := s.Path(p, v)
+			if err := os.MkdirAll(filepath.Dir(src), 0o755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(src, []byte(p+v), 0o755); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := s.Activate(s.Path(a, "v1.1.0"), a); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Activate(s.Path(b, "v1.0.0"), b); err != nil {
+		t.Fatal(err)
+	}
+
+	// each binary keeps its own versions, the active
+	// version of one isn't pruned along the other
+	if err := s.Prune(a, a); err != nil {
+		t.Fatal(err)
+	}
+	if versions, err := s.Versions(b); err != nil || len(versions) != 2 {
+		t.Fatalf("expected the versions of %s to be left alone, got %+v, %v", b, versions, err)
+	}
+	if versions, err := s.Versions(a); err != nil || len(versions) != 1 || versions[0].Version != "v1.1.0" {
+		t.Fatalf("expected only the active version of %s to be kept, got %+v, %v", a, versions, err)
+	}
+
+	if err := s.Remove(a, a); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Active(b); !ok {
+		t.Fatalf("expected the versions of %s to be kept", b)
+	}
+}
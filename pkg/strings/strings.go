@@ -10,3 +10,37 @@ func ContainsAny(s string, v []string) bool {
 	}
 	return false
 }
+
+// Closest returns the candidate with the smallest edit
+// distance to s, or an empty string if there are none
+func Closest(s string, candidates []string) string {
+	closest, best := "", -1
+	for _, c := range candidates {
+		if d := Distance(strings.ToLower(s), strings.ToLower(c)); best < 0 || d < best {
+			closest, best = c, d
+		}
+	}
+	return closest
+}
+
+// Distance returns the Levenshtein distance between a and b
+func Distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
@@ -0,0 +1,32 @@
+package strings
+
+import "testing"
+
+func TestDistance(t *testing.T) {
+	cases := []struct {
+		a, b string
+		out  int
+	}{
+		{"", "", 0},
+		{"abc", "", 3},
+		{"kitten", "sitting", 3},
+		{"tool_linux_amd64", "tool_linux_amd64", 0},
+		{"tool_linux_amd64", "tool_linux_arm64", 2},
+	}
+
+	for _, c := range cases {
+		if d := Distance(c.a, c.b); d != c.out {
+			t.Errorf("distance between %q and %q: expected %d, got %d", c.a, c.b, c.out, d)
+		}
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"tool_1.2.0_linux_amd64.tar.gz", "tool_1.2.0_darwin_amd64.tar.gz", "checksums.txt"}
+	if c := Closest("tool_1.1.0_linux_amd64.tar.gz", candidates); c != candidates[0] {
+		t.Fatalf("expected %s, got %s", candidates[0], c)
+	}
+	if c := Closest("tool", nil); c != "" {
+		t.Fatalf("expected no match, got %s", c)
+	}
+}